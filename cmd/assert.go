@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// Assertion flags
+var (
+	assertEndpoint     string
+	assertCallTimeout  time.Duration
+	assertStrictSchema bool
+)
+
+// newAssertCmd creates the assert command
+func newAssertCmd() *cobra.Command {
+	assertCmd := &cobra.Command{
+		Use:   "assert <expectation-file>...",
+		Short: "Call tools and check their results against expectation files",
+		Long: `Connects to the server, calls the tool of every case in the expectation
+files and checks each result with JSONPath matchers, for validating MCP
+servers in CI pipelines.
+
+Every case is run even if an earlier one fails. The exit code is 0 if all
+cases pass, 9 if any check fails, and the usual connection exit codes if the
+server cannot be reached.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runAssert,
+	}
+	assertCmd.Flags().StringVar(&assertEndpoint, "endpoint", "http://localhost:8090/mcp", "MCP endpoint URL (must end with /mcp)")
+	assertCmd.Flags().DurationVar(&assertCallTimeout, "call-timeout", 0, "Deadline of each tool call (0 disables)")
+	assertCmd.Flags().BoolVar(&assertStrictSchema, "strict-schema", false, "Fail cases whose arguments or structured results do not match the tool's declared schemas")
+	assertCmd.Flags().BoolVar(&verbose, "verbose", false, "Log the requests and responses")
+	assertCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return assertCmd
+}
+
+// runAssert loads the expectation files, runs their cases and prints the
+// results
+func runAssert(cmd *cobra.Command, args []string) error {
+	if err := validateEndpoint(assertEndpoint); err != nil {
+		return err
+	}
+
+	var cases []agent.AssertionCase
+	for _, path := range args {
+		suite, err := agent.LoadAssertionSuite(path)
+		if err != nil {
+			return err
+		}
+		cases = append(cases, suite.Cases...)
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	setupSignalHandler(cancel, false)
+
+	logger := agent.NewLogger(verbose, !noColor, false)
+	clientLogger := agent.NewLoggerWithWriter(false, false, false, io.Discard)
+	if verbose {
+		clientLogger = logger
+	}
+
+	client := agent.NewClient(agent.ClientConfig{
+		Endpoint:             assertEndpoint,
+		Transport:            transportStreamableHTTP,
+		Logger:               clientLogger,
+		Version:              version,
+		NotificationOverflow: agent.OverflowDropNewest,
+		CallTimeout:          assertCallTimeout,
+		StrictSchema:         assertStrictSchema,
+	})
+	if err := client.Run(ctx); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", assertEndpoint, err)
+	}
+	defer func() { _ = client.Close() }()
+
+	results := client.RunAssertions(ctx, cases)
+	agent.WriteAssertionResults(cmd.OutOrStdout(), results)
+	return agent.AssertionsError(results, len(cases))
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// benchThreshold is the minimum relative median change reported as a
+// regression or improvement
+var benchThreshold float64
+
+// Load test flags
+var (
+	benchEndpoint    string
+	benchTool        string
+	benchArgs        string
+	benchConcurrency int
+	benchConnections int
+	benchDuration    time.Duration
+	benchRequests    int
+	benchReportFile  string
+	benchThrottle    bool
+)
+
+// newBenchCmd creates the bench command group
+func newBenchCmd() *cobra.Command {
+	benchCmd := &cobra.Command{
+		Use:   "bench --tool <name>",
+		Short: "Load test a tool and work with benchmark reports",
+		Long: `Calls a tool concurrently for a fixed duration and reports throughput, the
+latency distribution of successful calls and a breakdown of errors by kind.
+
+Workers are spread round-robin over a pool of connections, each with its own
+MCP session. By default every worker gets its own connection; use
+--connections to let several workers share one session.
+
+With --report, the measurements are also written as a benchmark report that
+'bench compare' can diff against another run.`,
+		Args: cobra.NoArgs,
+		RunE: runBenchLoad,
+	}
+	benchCmd.Flags().StringVar(&benchEndpoint, "endpoint", "http://localhost:8090/mcp", "MCP endpoint URL (must end with /mcp)")
+	benchCmd.Flags().StringVar(&benchTool, "tool", "", "Name of the tool to call")
+	benchCmd.Flags().StringVar(&benchArgs, "args", "{}", "Tool arguments as a JSON object, passed to every call")
+	benchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 10, "Number of workers calling the tool in parallel")
+	benchCmd.Flags().IntVar(&benchConnections, "connections", 0, "Number of connections the workers share (0 opens one per worker)")
+	benchCmd.Flags().DurationVar(&benchDuration, "duration", 30*time.Second, "How long to start new calls")
+	benchCmd.Flags().IntVar(&benchRequests, "requests", 0, "Stop after this many calls, even if --duration has not elapsed (0 disables)")
+	benchCmd.Flags().BoolVar(&benchThrottle, "throttle", false, "Hold calls back to stay under the rate limit announced by the server's X-RateLimit-* and Retry-After headers")
+	benchCmd.Flags().StringVar(&benchReportFile, "report", "", "Write the latencies to this file as a benchmark report for 'bench compare'")
+	benchCmd.Flags().BoolVar(&verbose, "verbose", false, "Log the requests of the pooled connections")
+	benchCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	addProfilingFlags(benchCmd.Flags())
+	_ = benchCmd.MarkFlagRequired("tool")
+
+	compareCmd := &cobra.Command{
+		Use:   "compare <old.json> <new.json>",
+		Short: "Compare two benchmark reports",
+		Long: `Computes per-operation latency deltas between a baseline and a candidate
+benchmark report and renders them as a Markdown table.
+
+Operations whose median latency changed by at least --threshold and whose
+mean difference is statistically significant (Welch's t-test, 95%) are
+flagged as regressions or improvements; everything else is reported as
+unchanged.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runBenchCompare,
+	}
+	compareCmd.Flags().Float64Var(&benchThreshold, "threshold", 0.05, "Minimum relative change of the median latency to report (0.05 = 5%)")
+
+	benchCmd.AddCommand(compareCmd)
+	return benchCmd
+}
+
+// runBenchCompare loads both reports and prints the comparison table
+func runBenchCompare(cmd *cobra.Command, args []string) error {
+	baseline, err := agent.LoadBenchReport(args[0])
+	if err != nil {
+		return err
+	}
+	candidate, err := agent.LoadBenchReport(args[1])
+	if err != nil {
+		return err
+	}
+
+	deltas := agent.CompareBenchReports(baseline, candidate, benchThreshold)
+	if len(deltas) == 0 {
+		return fmt.Errorf("no operations found in %s or %s", args[0], args[1])
+	}
+
+	agent.WriteBenchComparison(cmd.OutOrStdout(), deltas)
+	return nil
+}
+
+// runBenchLoad opens the connection pool, runs the load test and prints the
+// results
+func runBenchLoad(cmd *cobra.Command, args []string) error {
+	if err := validateEndpoint(benchEndpoint); err != nil {
+		return err
+	}
+	if benchConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if benchConnections < 0 {
+		return fmt.Errorf("--connections must not be negative")
+	}
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(benchArgs), &toolArgs); err != nil {
+		return fmt.Errorf("invalid --args (must be a JSON object): %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	setupSignalHandler(cancel, false)
+
+	logger := agent.NewLogger(verbose, !noColor, false)
+
+	stopProfiling, err := startProfiling(logger)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
+	connections := benchConnections
+	if connections == 0 || connections > benchConcurrency {
+		connections = benchConcurrency
+	}
+
+	// Per-request logging of the pooled connections would drown the report
+	connLogger := agent.NewLoggerWithWriter(false, false, false, io.Discard)
+	if verbose {
+		connLogger = logger
+	}
+
+	logger.Info("Opening %d connection(s) to %s...", connections, benchEndpoint)
+	pool, err := agent.NewClientPool(ctx, agent.ClientConfig{
+		Endpoint:      benchEndpoint,
+		Transport:     transportStreamableHTTP,
+		Logger:        connLogger,
+		Version:       version,
+		NoInitialList: true,
+	}, connections)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	logger.Info("Calling %s from %d worker(s) for %s...", benchTool, benchConcurrency, benchDuration)
+	result, err := agent.RunLoadTest(ctx, pool.Clients(), agent.LoadTestConfig{
+		Tool:        benchTool,
+		Arguments:   toolArgs,
+		Concurrency: benchConcurrency,
+		Duration:    benchDuration,
+		MaxRequests: benchRequests,
+		Throttle:    benchThrottle,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(cmd.OutOrStdout())
+	agent.WriteLoadTestResult(cmd.OutOrStdout(), result)
+
+	if benchReportFile != "" {
+		report := result.BenchReport(benchEndpoint, pool.Clients()[0].ServerInfo().Version)
+		if err := agent.SaveBenchReport(benchReportFile, report); err != nil {
+			return err
+		}
+		logger.Success("Benchmark report written to %s", benchReportFile)
+	}
+	return nil
+}
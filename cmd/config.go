@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// appliedConfig records which flags were set from the environment and the
+// config file, to report once the logger exists
+type appliedConfig struct {
+	file     string
+	profile  string
+	fromFile []string
+	fromEnv  []string
+	// exposesSecret is set if the file provided credentials while other
+	// users can read it
+	exposesSecret bool
+}
+
+// applyConfig sets the flags not given on the command line from, in order
+// of precedence, MCP_DEBUG_* environment variables, the --profile section of
+// the config file and the file's top-level settings
+func applyConfig(cmd *cobra.Command) (*appliedConfig, error) {
+	flags := cmd.Flags()
+	applied := &appliedConfig{}
+
+	var envErr error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || flag.Name == "help" || envErr != nil {
+			return
+		}
+		value, ok := os.LookupEnv(agent.ConfigEnvName(flag.Name))
+		if !ok {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			envErr = fmt.Errorf("invalid %s: %w", agent.ConfigEnvName(flag.Name), err)
+			return
+		}
+		applied.fromEnv = append(applied.fromEnv, flag.Name)
+	})
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	path := configPath
+	if path == "" {
+		path = agent.DefaultConfigPath()
+	}
+	file, err := agent.LoadConfigFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && configPath == "":
+		// Without a config file only the environment applies
+		if profileName != "" {
+			return nil, fmt.Errorf("profile %q not found: there is no config file at %s", profileName, path)
+		}
+		return applied, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	settings, err := file.Resolve(profileName)
+	if err != nil {
+		return nil, err
+	}
+	applied.file, applied.profile = path, profileName
+	applied.exposesSecret = file.ExposesSecret(settings)
+
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		flag := flags.Lookup(name)
+		switch {
+		case name == "config" || name == "profile":
+			return nil, fmt.Errorf("config file %s: %s cannot be set in the config file", path, name)
+		case flag == nil && cmd.Root().Flags().Lookup(name) != nil:
+			// A setting of another mode that this command does not accept
+			continue
+		case flag == nil:
+			return nil, fmt.Errorf("config file %s: unknown setting %q (settings are flag names without the leading --)", path, name)
+		case flag.Changed:
+			continue
+		}
+		for _, value := range settings[name] {
+			if err := flags.Set(name, value); err != nil {
+				return nil, fmt.Errorf("config file %s: invalid %s: %w", path, name, err)
+			}
+		}
+		applied.fromFile = append(applied.fromFile, name)
+	}
+	return applied, nil
+}
+
+// onCommandLine reports whether the flag was given on the command line
+// rather than set from the environment or the config file
+func (a *appliedConfig) onCommandLine(cmd *cobra.Command, name string) bool {
+	return cmd.Flags().Changed(name) && !slices.Contains(a.fromEnv, name) && !slices.Contains(a.fromFile, name)
+}
+
+// log reports where settings came from. Only flag names are logged, never
+// their values.
+func (a *appliedConfig) log(logger *agent.Logger) {
+	if a.exposesSecret {
+		logger.Warning("Config file %s holds credentials but other users can read it; restrict it with: chmod 600 %s", a.file, a.file)
+	}
+	if len(a.fromFile) > 0 {
+		source := a.file
+		if a.profile != "" {
+			source += " (profile " + a.profile + ")"
+		}
+		logger.InfoVerbose("Settings from %s: %s", source, strings.Join(a.fromFile, ", "))
+	}
+	if len(a.fromEnv) > 0 {
+		logger.InfoVerbose("Settings from %s* environment variables: %s", agent.ConfigEnvPrefix, strings.Join(a.fromEnv, ", "))
+	}
+}
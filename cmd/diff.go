@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Server comparison flags
+var (
+	diffEndpointA string
+	diffEndpointB string
+	diffCallsFile string
+	diffJSON      bool
+)
+
+// serverDiff makes runMCPDebug compare two servers instead of connecting to
+// --endpoint; nil in the other modes
+var serverDiff *agent.SnapshotSuite
+
+// diffExcludedFlags are the root flags that do not apply to the diff
+// command, which connects to its own two endpoints
+var diffExcludedFlags = map[string]bool{
+	"endpoint":     true,
+	"resume":       true,
+	"session-file": true,
+}
+
+// newDiffCmd creates the diff command. It accepts the connection flags of
+// the root command, which apply to both servers.
+func newDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff --endpoint-a <url> --endpoint-b <url>",
+		Short: "Compare two MCP servers",
+		Long: `Connects to two MCP servers and compares their capabilities, instructions
+and the definitions of their tools, resources, resource templates and
+prompts, down to the properties of the tools' input schemas. Server A is
+the baseline, e.g. the current release, and B the candidate.
+
+With --calls, the tool calls of a snapshot file (see 'snapshot record') are
+replayed against both servers and their results compared, with the paths in
+its "ignore" lists masked. Recorded results in the file are not used.
+
+The exit code is 0 if the servers do not differ and 1 otherwise.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, endpoint := range []string{diffEndpointA, diffEndpointB} {
+				if err := validateEndpoint(endpoint); err != nil {
+					return err
+				}
+			}
+			serverDiff = &agent.SnapshotSuite{}
+			if diffCallsFile != "" {
+				suite, err := agent.LoadSnapshotSuite(diffCallsFile)
+				if err != nil {
+					return err
+				}
+				serverDiff = suite
+			}
+			if !cmd.Flags().Changed("log-level") {
+				logLevel = "warn"
+			}
+			noInitialList = true
+			cmd.SilenceUsage = true
+			return runMCPDebug(cmd, nil)
+		},
+	}
+	diffCmd.Flags().StringVar(&diffEndpointA, "endpoint-a", "", "MCP endpoint URL of the baseline server (must end with /mcp)")
+	diffCmd.Flags().StringVar(&diffEndpointB, "endpoint-b", "", "MCP endpoint URL of the server compared with it (must end with /mcp)")
+	diffCmd.Flags().StringVar(&diffCallsFile, "calls", "", "Snapshot file whose tool calls are replayed against both servers")
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Print the comparison as JSON")
+	_ = diffCmd.MarkFlagRequired("endpoint-a")
+	_ = diffCmd.MarkFlagRequired("endpoint-b")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || diffExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		diffCmd.Flags().AddFlag(flag)
+	})
+	return diffCmd
+}
+
+// runServerDiff connects to both endpoints with the configuration of the
+// root command, compares the servers and prints the comparison
+func runServerDiff(ctx context.Context, cfg agent.ClientConfig, out io.Writer) error {
+	// Both clients share the configuration, so nothing is recorded per
+	// endpoint
+	cfg.SessionFile = ""
+	cfg.ResumeSessions = false
+	cfg.CapabilityHistoryFile = ""
+
+	var clients []*agent.Client
+	for _, endpoint := range []string{diffEndpointA, diffEndpointB} {
+		cfg.Endpoint = endpoint
+		client := agent.NewClient(cfg)
+		if err := client.Run(ctx); err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+		}
+		defer func() { _ = client.Close() }()
+		clients = append(clients, client)
+	}
+
+	var calls *agent.SnapshotSuite
+	if len(serverDiff.Cases) > 0 {
+		calls = serverDiff
+	}
+	comparison, err := agent.CompareServers(ctx, clients[0], clients[1], calls)
+	if err != nil {
+		return err
+	}
+	if diffJSON {
+		if err := writeJSON(out, comparison); err != nil {
+			return err
+		}
+	} else {
+		agent.WriteServerComparison(out, comparison)
+	}
+	if comparison.Differs() {
+		return errors.New("the servers differ")
+	}
+	return nil
+}
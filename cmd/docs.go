@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// docsOut is the file the docs command writes to
+var docsOut string
+
+// newDocsCmd creates the docs command, which accepts the connection flags
+// of the root command
+func newDocsCmd() *cobra.Command {
+	docsCmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate Markdown documentation of the connected server",
+		Long: `Connects to the MCP server, lists its tools, resources, resource templates
+and prompts and writes human-readable Markdown documentation of them.
+
+Tool arguments and structured results are rendered as tables with their type,
+whether they are required and their description, including allowed values and
+defaults. Nested properties are listed as parent.child and the properties of
+array items as list[].child.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				inventory, err := client.Inventory(ctx)
+				if err != nil {
+					return err
+				}
+				if docsOut == "" {
+					agent.WriteMarkdownDocs(out, inventory)
+					return nil
+				}
+				file, err := os.Create(docsOut)
+				if err != nil {
+					return err
+				}
+				agent.WriteMarkdownDocs(file, inventory)
+				return file.Close()
+			})
+		},
+	}
+	docsCmd.Flags().StringVar(&docsOut, "out", "", "Write the documentation to this file instead of stdout")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		docsCmd.Flags().AddFlag(flag)
+	})
+	return docsCmd
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Export flags
+var (
+	exportFormat string
+	exportOut    string
+)
+
+// newExportCmd creates the export command with its tools subcommand, which
+// accepts the connection flags of the root command
+func newExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the connected server's definitions for other tooling",
+	}
+
+	toolsCmd := &cobra.Command{
+		Use:   "tools",
+		Short: "Export the tool definitions as an OpenAPI document or a JSON Schema bundle",
+		Long: `Connects to the MCP server, lists its tools and writes their definitions in a
+format documentation and SDK generators understand:
+
+  openapi     An OpenAPI 3.1 document. Each tool is a POST operation on
+              /tools/<tool> whose request body is the tool's arguments and
+              whose response is its structured result, if it declares an
+              output schema. This describes the tools for generators; the
+              server does not serve these paths.
+  jsonschema  A JSON Schema 2020-12 bundle with the input and output schema
+              of each tool in $defs.
+
+Schemas are named <tool>.input and <tool>.output, with characters other than
+letters, digits, '.', '-' and '_' replaced by '_'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(agent.ExportFormats, exportFormat) {
+				return fmt.Errorf("unknown --format %q (must be one of: %s)", exportFormat, strings.Join(agent.ExportFormats, ", "))
+			}
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				list, err := client.ListCapabilities(ctx, "tools")
+				if err != nil {
+					return err
+				}
+				doc, err := agent.ExportTools(list.([]mcp.Tool), client.ServerInfo(), exportFormat)
+				if err != nil {
+					return err
+				}
+				if exportOut == "" {
+					return writeJSON(out, doc)
+				}
+				file, err := os.Create(exportOut)
+				if err != nil {
+					return err
+				}
+				if err := writeJSON(file, doc); err != nil {
+					_ = file.Close()
+					return err
+				}
+				return file.Close()
+			})
+		},
+	}
+	toolsCmd.Flags().StringVar(&exportFormat, "format", agent.ExportOpenAPI, "Export format: "+strings.Join(agent.ExportFormats, " or "))
+	toolsCmd.Flags().StringVar(&exportOut, "out", "", "Write the export to this file instead of stdout")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		toolsCmd.Flags().AddFlag(flag)
+	})
+	exportCmd.AddCommand(toolsCmd)
+	return exportCmd
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Fuzzing flags
+var (
+	fuzzTools      []string
+	fuzzAllTools   bool
+	fuzzTimeout    time.Duration
+	fuzzStringSize int
+	fuzzJSON       bool
+)
+
+// newFuzzCmd creates the fuzz command. It accepts the connection flags of
+// the root command.
+func newFuzzCmd() *cobra.Command {
+	fuzzCmd := &cobra.Command{
+		Use:   "fuzz",
+		Short: "Send malformed JSON-RPC messages and report how the server handles them",
+		Long: `Connects to the MCP server and posts malformed messages within the session:
+broken JSON, wrong or missing ids, versions and methods, parameters of the
+wrong type, invalid UTF-8 and oversized strings. Each response is checked
+against what the spec requires, and the server is pinged after every case.
+
+Crashes (dropped connections, 5xx responses or failed pings), hangs (no
+response within --timeout) and non-spec responses (e.g. a result for an
+invalid request) are reported as findings; the run stops once the server no
+longer answers pings.
+
+With --tool or --all-tools, tools are also called with arguments derived
+from their input schemas: required arguments missing, arguments of the wrong
+type, oversized and invalid UTF-8 strings. This really calls the tools, so
+only fuzz tools without side effects you care about. Tools returning a
+successful result for such arguments are listed as accepted, which is not a
+finding.
+
+The exit code is 0 without findings and 1 otherwise.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := agent.FuzzConfig{
+				Tools:      fuzzTools,
+				AllTools:   fuzzAllTools,
+				Timeout:    fuzzTimeout,
+				StringSize: fuzzStringSize,
+			}
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				report, err := client.RunFuzz(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				if fuzzJSON {
+					if err := writeJSON(out, report); err != nil {
+						return err
+					}
+				} else {
+					agent.WriteFuzzReport(out, report)
+				}
+				if findings := report.Findings(); findings > 0 {
+					return fmt.Errorf("%d finding(s)", findings)
+				}
+				return nil
+			})
+		},
+	}
+	fuzzCmd.Flags().StringSliceVar(&fuzzTools, "tool", nil, "Also fuzz the arguments of this tool (repeatable); calls the tool")
+	fuzzCmd.Flags().BoolVar(&fuzzAllTools, "all-tools", false, "Also fuzz the arguments of every tool; calls the tools")
+	fuzzCmd.Flags().DurationVar(&fuzzTimeout, "timeout", agent.DefaultFuzzTimeout, "Time to wait for each response before reporting a hang")
+	fuzzCmd.Flags().IntVar(&fuzzStringSize, "string-size", agent.DefaultFuzzStringSize, "Length of the oversized strings in bytes")
+	fuzzCmd.Flags().BoolVar(&fuzzJSON, "json", false, "Print the report as JSON")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		fuzzCmd.Flags().AddFlag(flag)
+	})
+	return fuzzCmd
+}
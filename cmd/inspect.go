@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// newInspectCmd creates the inspect command, which opens the REPL on a
+// capability snapshot instead of a live server
+func newInspectCmd() *cobra.Command {
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <snapshot-file>",
+		Short: "Browse a capability snapshot in the REPL without connecting",
+		Long: `Loads a file written by 'mcp-debug snapshot capabilities' into the REPL.
+list, describe, server and the other commands that read what the server
+announced work as if connected; commands that need the server, such as call,
+get or prompt, fail with "not available offline".
+
+  mcp-debug snapshot capabilities --endpoint https://mcp.example.com/mcp --out snap.json
+  mcp-debug inspect snap.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: runInspect,
+	}
+	inspectCmd.Flags().StringVar(&script, "script", "", "Run the REPL commands in this file non-interactively and exit")
+	inspectCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose logging")
+	inspectCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return inspectCmd
+}
+
+// runInspect loads the snapshot and runs the REPL or script on it
+func runInspect(cmd *cobra.Command, args []string) error {
+	snapshot, err := agent.LoadCapabilitySnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	setupSignalHandler(cancel, false)
+
+	logger := agent.NewLogger(verbose, !noColor, false)
+	logger.Info("Inspecting snapshot of %s taken %s (offline)", snapshot.Endpoint, snapshot.TakenAt.Local().Format("2006-01-02 15:04:05"))
+	client := agent.NewOfflineClient(snapshot, logger)
+	defer func() { _ = client.Close() }()
+
+	replHandler := agent.NewREPL(client, logger)
+	replHandler.SetDisplayOptions(agent.DisplayOptions{NoColor: noColor})
+	if script != "" {
+		if err := replHandler.RunScript(ctx, script); err != nil {
+			return fmt.Errorf("script failed: %w", err)
+		}
+		return nil
+	}
+	if err := replHandler.Run(ctx); err != nil {
+		return fmt.Errorf("REPL error: %w", err)
+	}
+	return nil
+}
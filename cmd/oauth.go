@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// OAuth discovery flags
+var (
+	discoverPreferredAS string
+	discoverJSON        bool
+)
+
+// newOAuthCmd creates the oauth command group
+func newOAuthCmd() *cobra.Command {
+	oauthCmd := &cobra.Command{
+		Use:   "oauth",
+		Short: "Inspect the OAuth setup of MCP servers",
+	}
+
+	discoverCmd := &cobra.Command{
+		Use:   "discover <endpoint>",
+		Short: "Run OAuth discovery and report every step without authorizing",
+		Long: `Runs only the OAuth discovery chain for an MCP endpoint: the unauthenticated
+request and its WWW-Authenticate challenge, RFC 9728 Protected Resource
+Metadata, RFC 8414 Authorization Server Metadata, and the PKCE, Dynamic Client
+Registration and Client ID Metadata Document support they advertise.
+
+Prints every probed URL with its status, the configuration a connection with
+--oauth would derive and the problems found. No client is registered and no
+authorization is requested, which separates discovery bugs from
+authorization bugs.
+
+The exit code is 0 if authorization server metadata was found, 1 otherwise.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runOAuthDiscover,
+	}
+	discoverCmd.Flags().StringVar(&discoverPreferredAS, "oauth-preferred-auth-server", "", "Preferred authorization server URL when multiple are available")
+	discoverCmd.Flags().BoolVar(&discoverJSON, "json", false, "Print the report as JSON")
+
+	oauthCmd.AddCommand(discoverCmd)
+	return oauthCmd
+}
+
+// runOAuthDiscover runs OAuth discovery for the endpoint and prints the
+// report
+func runOAuthDiscover(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	setupSignalHandler(cancel, true)
+
+	report, err := agent.DiscoverOAuth(ctx, args[0], discoverPreferredAS)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	if discoverJSON {
+		if err := writeJSON(cmd.OutOrStdout(), report); err != nil {
+			return err
+		}
+	} else {
+		agent.WriteDiscoveryReport(cmd.OutOrStdout(), report)
+	}
+	return report.Err()
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// oneShot runs the single operation of the call, list and get commands
+// once the client is connected; nil in the other modes
+var oneShot func(ctx context.Context, client *agent.Client, out io.Writer) error
+
+// oneShotArgs holds the --args of the call command
+var oneShotArgs string
+
+// oneShotExcludedFlags are the root flags selecting other modes or only
+// affecting the REPL, which do not apply to one-shot commands
+var oneShotExcludedFlags = map[string]bool{
+	"max-display-bytes": true,
+	"display-format":    true,
+	"save-binary":       true,
+	"image-preview":     true,
+}
+
+// newOneShotCmds creates the call, list and get commands. They accept the
+// connection flags of the root command.
+func newOneShotCmds() []*cobra.Command {
+	callCmd := &cobra.Command{
+		Use:   "call <tool>",
+		Short: "Call a tool and print its result as JSON",
+		Long: `Connects to the MCP server, calls a tool with the --args JSON object, prints
+the result as JSON and exits.
+
+The exit code is 0 on success and 6 if the tool reported an error; see the
+documentation for the other exit codes. Logs go to stderr at the warn level
+unless --log-level or --verbose says otherwise.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var toolArgs map[string]any
+			if err := json.Unmarshal([]byte(oneShotArgs), &toolArgs); err != nil {
+				return fmt.Errorf("invalid --args (must be a JSON object): %w", err)
+			}
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				result, err := client.CallTool(ctx, args[0], toolArgs)
+				if err != nil {
+					return err
+				}
+				if err := writeJSON(out, result); err != nil {
+					return err
+				}
+				return agent.ToolResultError(result)
+			})
+		},
+	}
+	callCmd.Flags().StringVar(&oneShotArgs, "args", "{}", "Tool arguments as a JSON object")
+
+	listCmd := &cobra.Command{
+		Use:       "list <" + strings.Join(agent.ListKinds, "|") + ">",
+		Short:     "Print the server's tools, resources, templates or prompts as JSON",
+		Long:      `Connects to the MCP server, prints one of its lists as a JSON array and exits.`,
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: agent.ListKinds,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				list, err := client.ListCapabilities(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return writeJSON(out, list)
+			})
+		},
+	}
+
+	getCmd := &cobra.Command{
+		Use:   "get <uri>",
+		Short: "Read a resource and print the result as JSON",
+		Long: `Connects to the MCP server, reads a resource, prints the result with its
+contents as JSON and exits. Blob contents stay base64 encoded.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				result, err := client.GetResource(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return writeJSON(out, result)
+			})
+		},
+	}
+
+	cmds := []*cobra.Command{callCmd, listCmd, getCmd}
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		for _, cmd := range cmds {
+			cmd.Flags().AddFlag(flag)
+		}
+	})
+	return cmds
+}
+
+// runOneShot connects to the server and runs op. Logs go to stderr so the
+// output on stdout can be piped, and only warnings are logged by default.
+func runOneShot(cmd *cobra.Command, op func(ctx context.Context, client *agent.Client, out io.Writer) error) error {
+	oneShot = op
+	if !cmd.Flags().Changed("log-level") {
+		logLevel = "warn"
+	}
+	// Only the list an operation needs is fetched
+	if !cmd.Flags().Changed("no-initial-list") {
+		noInitialList = true
+	}
+	cmd.SilenceUsage = true
+	return runMCPDebug(cmd, nil)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// Profile flags
+var (
+	profileEndpoint     string
+	profileTransport    string
+	profileOAuth        bool
+	profileClientID     string
+	profileScopes       []string
+	profileScopeMode    string
+	profilePreferredAS  string
+	profileForceReplace bool
+)
+
+// newProfileCmd creates the profile command group
+func newProfileCmd() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage named server profiles in the config file",
+		Long: `Saves the connection settings of servers you debug regularly as named
+profiles in the config file. 'mcp-debug --profile <name>' restores them, and
+flags given on the command line still override the saved values.`,
+	}
+	profileCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file holding the profiles (default: ~/.config/mcp-debug/config.yaml)")
+
+	addCmd := &cobra.Command{
+		Use:   "add <name> --endpoint <url>",
+		Short: "Save a server profile",
+		Long: `Saves the endpoint, transport and OAuth settings of a server as a profile.
+Any OAuth setting enables OAuth for the profile.
+
+Client secrets are not saved; provide them with MCP_DEBUG_OAUTH_CLIENT_SECRET.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runProfileAdd,
+	}
+	addCmd.Flags().StringVar(&profileEndpoint, "endpoint", "", "MCP endpoint URL (must end with /mcp)")
+	addCmd.Flags().StringVar(&profileTransport, "transport", transportStreamableHTTP, "Transport protocol (streamable-http only)")
+	addCmd.Flags().BoolVar(&profileOAuth, "oauth", false, "Enable OAuth authentication")
+	addCmd.Flags().StringVar(&profileClientID, "oauth-client-id", "", "OAuth client ID (Dynamic Client Registration is used if not set)")
+	addCmd.Flags().StringSliceVar(&profileScopes, "oauth-scopes", nil, "OAuth scopes to request")
+	addCmd.Flags().StringVar(&profileScopeMode, "oauth-scope-mode", "", "Scope selection mode: 'auto' or 'manual' (use --oauth-scopes only)")
+	addCmd.Flags().StringVar(&profilePreferredAS, "oauth-preferred-auth-server", "", "Preferred authorization server URL when multiple are available")
+	addCmd.Flags().BoolVar(&profileForceReplace, "force", false, "Replace an existing profile of the same name")
+	_ = addCmd.MarkFlagRequired("endpoint")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the saved profiles",
+		Args:  cobra.NoArgs,
+		RunE:  runProfileList,
+	}
+
+	removeCmd := &cobra.Command{
+		Use:     "remove <name>",
+		Aliases: []string{"rm"},
+		Short:   "Remove a saved profile",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runProfileRemove,
+	}
+
+	profileCmd.AddCommand(addCmd, listCmd, removeCmd)
+	return profileCmd
+}
+
+// profileConfigPath returns the config file the profile commands work on
+func profileConfigPath() string {
+	if configPath != "" {
+		return configPath
+	}
+	return agent.DefaultConfigPath()
+}
+
+// loadProfileConfig loads the config file, or returns an empty one if it
+// does not exist yet
+func loadProfileConfig() (*agent.ConfigFile, error) {
+	path := profileConfigPath()
+	file, err := agent.LoadConfigFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return agent.NewConfigFile(path), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file: %w", err)
+	}
+	return file, nil
+}
+
+// runProfileAdd validates the profile settings and saves them
+func runProfileAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validateEndpoint(profileEndpoint); err != nil {
+		return err
+	}
+	if profileTransport != transportStreamableHTTP {
+		return fmt.Errorf("unsupported transport '%s' (only streamable-http is supported)", profileTransport)
+	}
+	if profileScopeMode != "" && profileScopeMode != agent.ScopeModeAuto && profileScopeMode != agent.ScopeModeManual {
+		return fmt.Errorf("invalid --oauth-scope-mode '%s' (must be '%s' or '%s')", profileScopeMode, agent.ScopeModeAuto, agent.ScopeModeManual)
+	}
+
+	file, err := loadProfileConfig()
+	if err != nil {
+		return err
+	}
+	if _, exists := file.Profiles[name]; exists && !profileForceReplace {
+		return fmt.Errorf("profile %q already exists in %s (use --force to replace it)", name, file.Path)
+	}
+
+	settings := agent.Settings{
+		"endpoint":  {profileEndpoint},
+		"transport": {profileTransport},
+	}
+	if profileClientID != "" {
+		settings["oauth-client-id"] = []string{profileClientID}
+	}
+	if len(profileScopes) > 0 {
+		settings["oauth-scopes"] = profileScopes
+	}
+	if profileScopeMode != "" {
+		settings["oauth-scope-mode"] = []string{profileScopeMode}
+	}
+	if profilePreferredAS != "" {
+		settings["oauth-preferred-auth-server"] = []string{profilePreferredAS}
+	}
+	if profileOAuth || len(settings) > 2 { // any OAuth setting
+		settings["oauth"] = []string{"true"}
+	}
+
+	file.SetProfile(name, settings)
+	if err := file.Save(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Saved profile %s to %s\n", name, file.Path)
+	return nil
+}
+
+// runProfileList prints the saved profiles with their settings
+func runProfileList(cmd *cobra.Command, args []string) error {
+	file, err := loadProfileConfig()
+	if err != nil {
+		return err
+	}
+	names := file.ProfileNames()
+	if len(names) == 0 {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "No profiles in %s\n", file.Path)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tENDPOINT\tSETTINGS")
+	for _, name := range names {
+		settings := file.Profiles[name]
+		endpoint := strings.Join(settings["endpoint"], ",")
+		if endpoint == "" {
+			endpoint = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, endpoint, formatProfileSettings(settings))
+	}
+	return w.Flush()
+}
+
+// formatProfileSettings renders the settings other than the endpoint as
+// key=value pairs sorted by key, repeated values joined with commas
+func formatProfileSettings(settings map[string][]string) string {
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		if key == "endpoint" {
+			continue
+		}
+		pairs = append(pairs, key+"="+strings.Join(settings[key], ","))
+	}
+	if len(pairs) == 0 {
+		return "-"
+	}
+	return strings.Join(pairs, " ")
+}
+
+// runProfileRemove removes a profile from the config file
+func runProfileRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	file, err := loadProfileConfig()
+	if err != nil {
+		return err
+	}
+	if !file.RemoveProfile(name) {
+		return fmt.Errorf("unknown profile %q in %s", name, file.Path)
+	}
+	if err := file.Save(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Removed profile %s from %s\n", name, file.Path)
+	return nil
+}
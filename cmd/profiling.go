@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+	"github.com/spf13/pflag"
+)
+
+var (
+	pprofAddr   string
+	cpuProfile  string
+	heapProfile string
+)
+
+// addProfilingFlags registers the profiling flags. Only commands that call
+// startProfiling register them, so the others reject them instead of
+// ignoring them.
+func addProfilingFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&pprofAddr, "pprof-addr", "", "Expose net/http/pprof on this address (e.g. localhost:6060); empty disables")
+	flags.StringVar(&cpuProfile, "cpu-profile", "", "Write a CPU profile covering the whole run to this file")
+	flags.StringVar(&heapProfile, "heap-profile", "", "Write a heap profile to this file when the run ends")
+}
+
+// startProfiling starts the pprof HTTP endpoint and CPU profile capture as
+// configured by the profiling flags. The returned function stops CPU
+// profiling, writes the heap profile and shuts the endpoint down; it must be
+// called once the profiled work is done.
+func startProfiling(logger *agent.Logger) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on pprof address %s: %w", pprofAddr, err)
+		}
+
+		server := &http.Server{
+			Handler:           newPprofMux(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("pprof server error: %v", err)
+			}
+		}()
+		logger.Info("pprof endpoint listening on http://%s/debug/pprof/", listener.Addr())
+		stops = append(stops, func() { _ = server.Close() })
+	}
+
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		logger.Info("Capturing CPU profile to %s", cpuProfile)
+		stops = append(stops, func() {
+			runtimepprof.StopCPUProfile()
+			_ = f.Close()
+		})
+	}
+
+	if heapProfile != "" {
+		stops = append(stops, func() {
+			if err := writeHeapProfile(heapProfile); err != nil {
+				logger.Error("%v", err)
+				return
+			}
+			logger.Info("Heap profile written to %s", heapProfile)
+		})
+	}
+
+	return stop, nil
+}
+
+// newPprofMux registers the net/http/pprof handlers on a dedicated mux so
+// they are never exposed by the MCP server's own HTTP listener
+func newPprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// writeHeapProfile writes an up-to-date heap profile to path
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// Collect garbage first so the profile reflects live objects
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// protocolMatrix holds the versions probed by the protocol-versions command;
+// nil in the other modes
+var protocolMatrix []string
+
+// protocolMatrixJSON prints the protocol-versions report as JSON
+var protocolMatrixJSON bool
+
+// protocolExcludedFlags are the root flags that do not apply to the
+// protocol-versions command, which only initializes sessions
+var protocolExcludedFlags = map[string]bool{
+	"protocol-version": true,
+	"no-initial-list":  true,
+	"resume":           true,
+	"session-file":     true,
+}
+
+// newProtocolVersionsCmd creates the protocol-versions command. It accepts
+// the connection flags of the root command.
+func newProtocolVersionsCmd() *cobra.Command {
+	protocolCmd := &cobra.Command{
+		Use:   "protocol-versions [version...]",
+		Short: "Report which MCP protocol versions the server accepts",
+		Long: `Initializes a separate session with the MCP server for each protocol version
+and reports whether the server accepted it, answered with another version
+(which the session would then use), answered with a version mcp-debug does
+not speak, or failed the initialize request.
+
+Without arguments, every version mcp-debug speaks is probed. Pass other
+versions, including made-up ones, to check how the server handles versions
+it does not know.
+
+The exit code is 0 if at least one session could be initialized, 1 otherwise.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			protocolMatrix = args
+			if len(protocolMatrix) == 0 {
+				protocolMatrix = agent.ProtocolVersions()
+			}
+			cmd.SilenceUsage = true
+			return runMCPDebug(cmd, nil)
+		},
+	}
+	protocolCmd.Flags().BoolVar(&protocolMatrixJSON, "json", false, "Print the report as JSON")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || protocolExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		protocolCmd.Flags().AddFlag(flag)
+	})
+	return protocolCmd
+}
+
+// runProtocolMatrix probes the protocolMatrix versions with the connection
+// configuration of the root command and prints the report. The sessions
+// only log with --verbose, so the report is not buried in connection logs.
+func runProtocolMatrix(ctx context.Context, cfg agent.ClientConfig, out io.Writer) error {
+	if !verbose {
+		cfg.Logger = agent.NewLoggerWithWriter(false, false, false, io.Discard)
+	}
+	probes := agent.ProbeProtocolVersions(ctx, cfg, protocolMatrix)
+
+	if protocolMatrixJSON {
+		if err := writeJSON(out, probes); err != nil {
+			return err
+		}
+	} else {
+		agent.WriteProtocolVersionReport(out, probes)
+	}
+	for _, probe := range probes {
+		if probe.Status == agent.ProtocolAccepted || probe.Status == agent.ProtocolCountered {
+			return nil
+		}
+	}
+	return errors.New("no session could be initialized with the requested protocol versions")
+}
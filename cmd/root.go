@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -26,15 +29,83 @@ var (
 	timeout         time.Duration
 	verbose         bool
 	noColor         bool
+	noPager         bool
 	jsonRPC         bool
+	outputFormat    string
+	logLevel        string
+	logFile         string
+	logFileLevel    string
+	logFileMaxSize  int
+	logFileBackups  int
+	redactPatterns  []string
+	noRedact        bool
 	repl            bool
+	script          string
 	mcpServer       bool
 	transport       string
 	serverTransport string
 	listenAddr      string
+	serverIssuer    string
+	serverResource  string
+	serverScopes    []string
+	chaos           agent.FaultConfig
+	chaosEnabled    bool
+	listDebounce    time.Duration
+	announceCaps    []string
+	protocolVersion string
+	pingInterval    time.Duration
+	pingFailures    int
+	reconnectTries  int
+	reconnectDelay  time.Duration
+	reconnectMax    time.Duration
+	retryAttempts   int
+	retryDelay      time.Duration
+	retryMaxDelay   time.Duration
+	retryStatuses   []int
+	resumeSessions  bool
+	sessionFile     string
+	cacheTTL        time.Duration
+	callTimeout     time.Duration
+	maxListPages    int
+	strictSchema    bool
+	capHistoryFile  string
+	noInitialList   bool
+	requestMeta     []string
+	customHeaders   []string
+	tlsConfig       agent.TLSConfig
+	metadataNets    agent.MetadataNetworkPolicy
+	metadataCache   agent.MetadataCacheConfig
+	configPath      string
+	profileName     string
+	proxyURL        string
+	kubeForward     agent.KubePortForwardConfig
+	sshTunnel       agent.SSHTunnelConfig
+	dockerServer    agent.DockerConfig
+	cookies         []string
+	maxInFlight     int
+	resourceMemMax  int64
+	resourceETag    bool
+	maxDisplay      int
+	foldLines       int
+	displayFormat   string
+	saveBinary      bool
+	imagePreview    string
+	notifyBuffer    int
+	notifyOverflow  string
+	logCompact      bool
+	logMaxPayload   int
+	logSampleRate   int
+	harFile         string
+	otelEndpoint    string
+	samplingMode    string
+	samplingReply   string
+	samplingModel   string
+	elicitMode      string
+	elicitAnswers   string
 
 	// OAuth flags
 	oauthEnabled           bool
+	oauthFlow              string
 	oauthClientID          string
 	oauthClientSecret      string
 	oauthScopes            []string
@@ -53,6 +124,16 @@ var (
 	oauthStepUpPrompt      bool
 	oauthClientIDMetaURL   string
 	oauthDisableCIMD       bool
+	oauthRevokeOnExit      bool
+	oauthStepMode          bool
+	oauthManualCode        bool
+	oauthCallbackPorts     string
+	oauthJAR               bool
+	oauthJARPrint          bool
+	oauthTokenAuthMethod   string
+	oauthSigningKey        string
+	oauthRefreshBefore     time.Duration
+	oauthTokenLogInterval  time.Duration
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -67,6 +148,7 @@ inspect available tools, resources, and prompts, and execute them interactively.
 The tool supports multiple modes:
 - Normal mode (default): Connect and wait for notifications
 - REPL mode (--repl): Interactive exploration and execution
+- Script mode (--script): Run a file of REPL commands with assertions, e.g. as a smoke test
 - MCP Server mode (--mcp-server): Act as an MCP server for integration with AI assistants
 
 The agent connects to an MCP server as a client agent,
@@ -94,11 +176,46 @@ By default, it connects to http://localhost:8090/mcp. You can override this with
 	RunE: runMCPDebug,
 }
 
+// Process exit codes, chosen by the kind of error that terminated the run
+const (
+	exitCodeError      = 1
+	exitCodeAuth       = 3
+	exitCodeTransport  = 4
+	exitCodeProtocol   = 5
+	exitCodeToolFailed = 6
+	exitCodeTimeout    = 7
+	exitCodeSchema     = 8
+	exitCodeAssertion  = 9
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCodeForError(err))
+	}
+}
+
+// exitCodeForError maps errors from the agent's error taxonomy to exit codes
+// so scripts can distinguish authentication, connectivity and server failures
+func exitCodeForError(err error) int {
+	switch agent.ErrorKind(err) {
+	case agent.ErrAuthRequired, agent.ErrInsufficientScope:
+		return exitCodeAuth
+	case agent.ErrTransportClosed:
+		return exitCodeTransport
+	case agent.ErrProtocol:
+		return exitCodeProtocol
+	case agent.ErrToolFailed:
+		return exitCodeToolFailed
+	case agent.ErrTimeout:
+		return exitCodeTimeout
+	case agent.ErrSchemaViolation:
+		return exitCodeSchema
+	case agent.ErrAssertionFailed:
+		return exitCodeAssertion
+	default:
+		return exitCodeError
 	}
 }
 
@@ -114,15 +231,111 @@ func init() {
 	rootCmd.Flags().StringVar(&transport, "transport", transportStreamableHTTP, "Transport protocol to use for client connections (streamable-http only)")
 	rootCmd.Flags().StringVar(&serverTransport, "server-transport", "stdio", "Transport protocol for the MCP server itself (stdio, streamable-http)")
 	rootCmd.Flags().StringVar(&listenAddr, "listen-addr", ":8899", "Listen address for streamable-http server (path is fixed to /mcp)")
+	rootCmd.Flags().StringVar(&serverIssuer, "server-oauth-issuer", "", "Require bearer tokens from this authorization server on the streamable-http MCP server and serve RFC 9728 protected resource metadata")
+	rootCmd.Flags().StringVar(&serverResource, "server-oauth-resource", "", "Resource URI tokens must be issued for (default: http://localhost:<port>/mcp from --listen-addr)")
+	rootCmd.Flags().StringSliceVar(&serverScopes, "server-oauth-scopes", []string{}, "Scopes a bearer token must grant to access the MCP server")
+	rootCmd.Flags().BoolVar(&chaosEnabled, "chaos", false, "Inject faults into the streamable-http MCP server and register the configure_faults tool (implied by any --chaos-* flag)")
+	rootCmd.Flags().DurationVar(&chaos.Latency, "chaos-latency", 0, "Maximum random delay added before each request to the MCP server is handled")
+	rootCmd.Flags().Float64Var(&chaos.DropNotifications, "chaos-drop-notifications", 0, "Rate (0-1) of notifications the MCP server does not send")
+	rootCmd.Flags().Float64Var(&chaos.Malformed, "chaos-malformed", 0, "Rate (0-1) of JSON-RPC responses the MCP server corrupts")
+	rootCmd.Flags().Float64Var(&chaos.Truncate, "chaos-truncate", 0, "Rate (0-1) of responses and SSE streams the MCP server cuts off halfway")
+	rootCmd.Flags().Float64Var(&chaos.HTTPErrors, "chaos-http-errors", 0, "Rate (0-1) of requests that start a burst of HTTP errors")
+	rootCmd.Flags().IntVar(&chaos.ErrorBurst, "chaos-error-burst", 1, "Number of consecutive requests an HTTP error burst fails")
+	rootCmd.Flags().IntSliceVar(&chaos.ErrorStatuses, "chaos-error-statuses", []int{401, 403, 500}, "HTTP statuses an error burst picks from")
+	rootCmd.Flags().Float64Var(&chaos.ConnectionReset, "chaos-reset", 0, "Rate (0-1) of requests whose connection is reset without a response")
+	rootCmd.Flags().Int64Var(&chaos.Seed, "chaos-seed", 0, "Seed for the injected faults, to reproduce a run (default: random)")
 	rootCmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Timeout for waiting for notifications")
 	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose logging (show keepalive messages)")
 	rootCmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	rootCmd.Flags().BoolVar(&jsonRPC, "json-rpc", false, "Enable full JSON-RPC message logging")
+	rootCmd.Flags().StringVar(&outputFormat, "output", string(agent.OutputText), "Log output format: 'text' (human-readable) or 'json' (one JSON object per line, including JSON-RPC payloads)")
+	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Minimum level logged to the terminal: trace, debug, info, warn or error (--verbose lowers it to debug; trace logs full JSON-RPC messages)")
+	rootCmd.Flags().StringVar(&logFile, "log-file", "", "Also write the log to this file, rotated by size")
+	rootCmd.Flags().StringVar(&logFileLevel, "log-file-level", "trace", "Minimum level written to --log-file")
+	rootCmd.Flags().IntVar(&logFileMaxSize, "log-file-max-size", 100, "Size in MB at which --log-file is rotated (0 disables rotation)")
+	rootCmd.Flags().IntVar(&logFileBackups, "log-file-max-backups", 5, "Number of rotated log files kept")
+	rootCmd.Flags().StringArrayVar(&redactPatterns, "redact", nil, "Regular expression of further field names whose values are masked in the log, in addition to tokens, secrets and authorization codes (repeatable)")
+	rootCmd.Flags().BoolVar(&noRedact, "no-redact", false, "Log credentials unmasked")
 	rootCmd.Flags().BoolVar(&repl, "repl", false, "Start interactive REPL mode")
 	rootCmd.Flags().BoolVar(&mcpServer, "mcp-server", false, "Run as MCP server (stdio transport)")
+	rootCmd.Flags().StringVar(&script, "script", "", "Run the REPL commands in this file non-interactively and exit; fails on the first failing command or assertion")
+	rootCmd.Flags().StringArrayVar(&announceCaps, "announce-capabilities", []string{}, "Client capabilities to announce during initialize (sampling, roots, elicitation, experimental:<name>[=<json-object>]), comma-separated or repeated")
+	rootCmd.Flags().StringVar(&protocolVersion, "protocol-version", agent.DefaultProtocolVersion, "MCP protocol version requested in initialize ("+strings.Join(agent.ProtocolVersions(), ", ")+"); the server may answer with another one")
+	rootCmd.Flags().DurationVar(&pingInterval, "ping-interval", 0, "Interval for client-initiated MCP pings used to detect dead connections (0 disables)")
+	rootCmd.Flags().IntVar(&foldLines, "fold-lines", agent.DefaultFoldLines, "Height in lines above which the REPL folds nested JSON objects and arrays of results and tool schemas; 'expand <path>' shows them (0 disables)")
+	rootCmd.Flags().IntVar(&pingFailures, "ping-failure-threshold", agent.DefaultPingFailureThreshold, "Consecutive failed pings before the connection is treated as lost and re-established")
+	rootCmd.Flags().IntVar(&reconnectTries, "reconnect-max-attempts", agent.DefaultReconnectMaxAttempts, "Attempts to re-establish a lost connection before giving up (-1 retries until interrupted)")
+	rootCmd.Flags().DurationVar(&reconnectDelay, "reconnect-initial-delay", agent.DefaultReconnectInitialDelay, "Wait after the first failed reconnect attempt, doubled after each further failure")
+	rootCmd.Flags().DurationVar(&reconnectMax, "reconnect-max-delay", agent.DefaultReconnectMaxDelay, "Maximum wait between reconnect attempts")
+	rootCmd.Flags().IntVar(&retryAttempts, "http-retry-max-attempts", agent.DefaultHTTPRetryMaxAttempts, "Attempts of an HTTP request failing with a --http-retry-on status or, for GET requests, without a response (1 disables retries)")
+	rootCmd.Flags().DurationVar(&retryDelay, "http-retry-initial-delay", agent.DefaultHTTPRetryInitialDelay, "Wait after the first failed HTTP attempt, doubled after each further failure unless the server sends Retry-After")
+	rootCmd.Flags().DurationVar(&retryMaxDelay, "http-retry-max-delay", agent.DefaultHTTPRetryMaxDelay, "Maximum wait between HTTP attempts; a longer Retry-After ends the retries")
+	rootCmd.Flags().IntSliceVar(&retryStatuses, "http-retry-on", agent.DefaultHTTPRetryStatuses, "HTTP statuses that are retried; POST requests are only retried on 429 and 503")
+	rootCmd.Flags().BoolVar(&resumeSessions, "resume", false, "Resume the session after a lost connection, sending Last-Event-ID so the server can replay missed events")
+	rootCmd.Flags().StringVar(&sessionFile, "session-file", "", "Save the session ID and last event ID to this file and resume that session on the next run (implies --resume)")
+	rootCmd.Flags().BoolVar(&noInitialList, "no-initial-list", false, "Skip listing tools, resources and prompts after initialize; each list is fetched on first use")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 0, "Maximum age of cached tool/resource/prompt lists before they are re-listed (0 disables expiry)")
+	rootCmd.Flags().IntVar(&maxListPages, "max-list-pages", agent.DefaultMaxListPages, "Maximum number of pages followed when listing tools, resources, templates or prompts")
+	rootCmd.Flags().BoolVar(&strictSchema, "strict-schema", false, "Fail tool calls whose arguments or structured results do not match the tool's declared schemas instead of only warning")
+	rootCmd.Flags().StringVar(&capHistoryFile, "capability-history", "", "Append tool/resource/prompt list snapshots to this file so the history command shows changes across sessions")
+	rootCmd.Flags().DurationVar(&callTimeout, "call-timeout", 0, "Deadline of each tool call; a call without a response by then is cancelled (0 disables)")
+	rootCmd.Flags().BoolVar(&logCompact, "log-compact", false, "Log JSON-RPC payloads as single-line JSON (cheaper than pretty-printing)")
+	rootCmd.Flags().IntVar(&logMaxPayload, "log-max-payload", 0, "Truncate logged JSON-RPC payloads whose compact JSON is longer than this many bytes (0 disables)")
+	rootCmd.Flags().IntVar(&logSampleRate, "log-sample-rate", 1, "Log the JSON-RPC payload of only every Nth message; others are not serialized")
+	rootCmd.Flags().StringVar(&harFile, "har-file", "", "Record the HTTP traffic of the session and write it to this HAR file on exit")
+	rootCmd.Flags().StringVar(&otelEndpoint, "otel-endpoint", "", "Record the HTTP traffic of the session and export it on exit as OTLP spans to this OTLP/HTTP collector (e.g. http://localhost:4318)")
+	rootCmd.Flags().StringVar(&samplingMode, "sampling", string(agent.SamplingOff), "Answer sampling/createMessage requests from the server: "+strings.Join(agent.SamplingModes, ", ")+" ('interactive' prompts for each response, 'auto' uses --sampling-response)")
+	rootCmd.Flags().StringVar(&samplingReply, "sampling-response", agent.DefaultSamplingResponse, "Go template of the response sent in --sampling=auto mode; fields: .LastMessage, .Messages (.Role, .Text), .SystemPrompt, .MaxTokens")
+	rootCmd.Flags().StringVar(&samplingModel, "sampling-model", agent.DefaultSamplingModel, "Model name reported in sampling responses")
+	rootCmd.Flags().StringVar(&elicitMode, "elicitation", string(agent.ElicitationOff), "Answer elicitation/create requests from the server: "+strings.Join(agent.ElicitationModes, ", ")+" ('interactive' asks for each field, 'auto' uses --elicitation-answers)")
+	rootCmd.Flags().StringVar(&elicitAnswers, "elicitation-answers", "", "JSON file of canned elicitation answers for non-interactive runs; implies --elicitation=auto")
+	rootCmd.Flags().StringArrayVar(&customHeaders, "header", []string{}, "HTTP header to send to the MCP server as 'Name: value', e.g. an API key required by a gateway (repeatable)")
+	rootCmd.Flags().StringVar(&configPath, "config", "", "Config file of flag settings and profiles (default: ~/.config/mcp-debug/config.yaml)")
+	rootCmd.Flags().StringVar(&profileName, "profile", "", "Profile of the config file to apply on top of its top-level settings")
+	rootCmd.Flags().StringVar(&proxyURL, "proxy", "", "Proxy for all outbound requests (http://, https://, socks5:// or socks5h://host:port); defaults to HTTPS_PROXY/HTTP_PROXY/NO_PROXY")
+	rootCmd.Flags().StringVar(&sshTunnel.Destination, "ssh", "", "Reach the MCP server through an SSH tunnel via this jump host, as [user@]host[:port]")
+	rootCmd.Flags().StringVar(&sshTunnel.IdentityFile, "ssh-identity", "", "Private key for --ssh, tried after the SSH agent's keys (default: ~/.ssh/id_ed25519, id_ecdsa, id_rsa)")
+	rootCmd.Flags().StringVar(&sshTunnel.KnownHostsFile, "ssh-known-hosts", "", "Known hosts file verifying the --ssh jump host (default: ~/.ssh/known_hosts)")
+	rootCmd.Flags().StringVar(&dockerServer.Image, "docker-image", "", "Run the MCP server from this Docker image and connect to its published port; --endpoint supplies the scheme and path")
+	rootCmd.Flags().IntVar(&dockerServer.Port, "docker-port", 0, "Container port the --docker-image server listens on (default: the only port the image exposes)")
+	rootCmd.Flags().StringArrayVar(&dockerServer.RunArgs, "docker-arg", nil, "Extra docker run option for --docker-image, e.g. --docker-arg=--volume=/data:/data (repeatable)")
+	rootCmd.Flags().StringArrayVar(&dockerServer.Env, "env", []string{}, "Environment variable of the --docker-image server as KEY=VAL; a bare KEY passes mcp-debug's value (repeatable)")
+	rootCmd.Flags().StringVar(&dockerServer.EnvFile, "env-file", "", "File of KEY=VAL lines to set in the --docker-image server's environment; --env overrides it")
+	rootCmd.Flags().BoolVar(&dockerServer.NoInheritEnv, "no-inherit-env", false, "Do not pass mcp-debug's environment to the --docker-image server: bare KEY entries are an error or stay unset")
+	rootCmd.Flags().StringVar(&kubeForward.Service, "kube-service", "", "Port-forward to this Kubernetes service with kubectl and connect through it; --endpoint supplies the path and must use http")
+	rootCmd.Flags().StringVar(&kubeForward.Namespace, "kube-namespace", "", "Namespace of --kube-service (default: the namespace of the kubeconfig context)")
+	rootCmd.Flags().StringVar(&kubeForward.Context, "kube-context", "", "Kubeconfig context of --kube-service (default: the current context)")
+	rootCmd.Flags().IntVar(&kubeForward.Port, "kube-port", 0, "Port of --kube-service to forward to (default: the service's first port)")
+	rootCmd.Flags().BoolVar(&metadataNets.DenyPrivate, "metadata-deny-private", false, "Refuse metadata discovery and OAuth requests to loopback, private and link-local addresses (SSRF protection against untrusted servers)")
+	rootCmd.Flags().StringArrayVar(&metadataNets.Deny, "metadata-deny-cidr", []string{}, "Further network (CIDR or address) metadata discovery and OAuth requests may not connect to (repeatable)")
+	rootCmd.Flags().StringArrayVar(&metadataNets.Allow, "metadata-allow-cidr", []string{}, "Network (CIDR or address) exempt from --metadata-deny-private and --metadata-deny-cidr, e.g. the cluster service network (repeatable)")
+	rootCmd.Flags().BoolVar(&metadataCache.Disabled, "no-metadata-cache", false, "Fetch protected resource and authorization server metadata on every connection instead of reusing it")
+	rootCmd.Flags().DurationVar(&metadataCache.TTL, "metadata-cache-ttl", agent.DefaultMetadataCacheTTL, "Maximum time discovered metadata is reused; a shorter Cache-Control max-age takes precedence")
+	rootCmd.Flags().StringVar(&metadataCache.File, "metadata-cache-file", "", "Persist the metadata cache in this file so later runs reuse it")
+	rootCmd.Flags().StringVar(&tlsConfig.CAFile, "tls-ca", "", "PEM file of CA certificates to trust in addition to the system's, for servers behind a private PKI")
+	rootCmd.Flags().StringVar(&tlsConfig.CertFile, "tls-cert", "", "PEM client certificate presented to servers requiring mutual TLS (requires --tls-key)")
+	rootCmd.Flags().StringVar(&tlsConfig.KeyFile, "tls-key", "", "PEM private key of the --tls-cert client certificate")
+	rootCmd.Flags().BoolVar(&tlsConfig.Insecure, "tls-insecure", false, "Skip verification of server certificates (insecure, for self-signed test servers only)")
+	rootCmd.Flags().StringArrayVar(&cookies, "cookie", []string{}, "Cookie to send to the MCP server as name=value; several may be separated by semicolons (repeatable)")
+	rootCmd.Flags().StringArrayVar(&requestMeta, "meta", []string{}, "_meta field to attach to outgoing requests as key=value; JSON values are decoded (repeatable)")
+	rootCmd.Flags().IntVar(&maxInFlight, "max-in-flight", 0, "Maximum number of concurrent requests to the server; extra requests are queued in FIFO order (0 means unlimited)")
+	rootCmd.Flags().IntVar(&notifyBuffer, "notification-buffer", agent.DefaultNotificationBufferSize, "Number of server notifications queued for processing before the overflow policy applies")
+	rootCmd.Flags().StringVar(&notifyOverflow, "notification-overflow", string(agent.OverflowBlock), "What to do when the notification buffer is full: "+strings.Join(agent.NotificationOverflowPolicies, ", "))
+	rootCmd.Flags().Int64Var(&resourceMemMax, "resource-memory-limit", agent.DefaultResourceMemoryLimit, "Resource size in bytes above which the REPL 'get' command streams to a temporary file instead of keeping the resource in memory (0 disables)")
+	rootCmd.Flags().BoolVar(&resourceETag, "resource-etag-meta", false, "Send the etag of a resource read back as _meta.ifNoneMatch on 'get --if-changed' (a non-standard convention)")
+	rootCmd.Flags().BoolVar(&noPager, "no-pager", false, "Print REPL output taller than the terminal directly instead of through $PAGER or the internal pager")
+	rootCmd.Flags().IntVar(&maxDisplay, "max-display-bytes", agent.DefaultMaxDisplayBytes, "Size in bytes above which the REPL truncates tool, resource and prompt results; 'show last' prints them in full (0 disables)")
+	rootCmd.Flags().StringVar(&displayFormat, "display-format", string(agent.DisplayPretty), "How the REPL renders JSON results: "+strings.Join(agent.DisplayFormats, ", "))
+	rootCmd.Flags().BoolVar(&saveBinary, "save-binary", false, "Write images, audio and blobs in REPL results to temporary files and show their paths")
+	rootCmd.Flags().StringVar(&imagePreview, "image-preview", string(agent.PreviewAuto), "Inline preview of images in REPL results: "+strings.Join(agent.ImagePreviews, ", ")+" (auto detects iTerm2, WezTerm and kitty)")
+	rootCmd.Flags().DurationVar(&listDebounce, "list-changed-debounce", agent.DefaultListChangedDebounce, "Quiet time after the latest list_changed notification before the one refresh of a burst (0 disables)")
+
+	// Profiling flags
+	addProfilingFlags(rootCmd.Flags())
 
 	// OAuth flags
 	rootCmd.Flags().BoolVar(&oauthEnabled, "oauth", false, "Enable OAuth authentication for connecting to protected MCP servers")
+	rootCmd.Flags().StringVar(&oauthFlow, "oauth-flow", agent.OAuthFlowAuthorizationCode, "OAuth flow: 'authorization-code' (browser with localhost callback) or 'device' (RFC 8628 device code, for headless machines)")
 	rootCmd.Flags().StringVar(&oauthClientID, "oauth-client-id", "", "OAuth client ID (optional - will use Dynamic Client Registration if not provided)")
 	rootCmd.Flags().StringVar(&oauthClientSecret, "oauth-client-secret", "", "OAuth client secret (optional)")
 	rootCmd.Flags().StringSliceVar(&oauthScopes, "oauth-scopes", []string{}, "OAuth scopes to request (optional, used with --oauth-scope-mode=manual)")
@@ -140,23 +353,51 @@ func init() {
 	rootCmd.Flags().IntVar(&oauthStepUpMaxRetries, "oauth-step-up-max-retries", 2, "Maximum number of step-up authorization retry attempts")
 	rootCmd.Flags().BoolVar(&oauthStepUpPrompt, "oauth-step-up-prompt", false, "Prompt user before requesting additional scopes during step-up authorization")
 	rootCmd.Flags().StringVar(&oauthClientIDMetaURL, "oauth-client-id-metadata-url", "", "HTTPS URL hosting Client ID Metadata Document (enables CIMD support)")
+	rootCmd.Flags().BoolVar(&oauthRevokeOnExit, "oauth-revoke-on-exit", false, "Revoke the access and refresh tokens (RFC 7009) when mcp-debug exits")
+	rootCmd.Flags().BoolVar(&oauthManualCode, "oauth-manual-code", false, "Print the authorization URL and read the pasted redirect URL or code instead of opening a browser and a local callback port")
+	rootCmd.Flags().StringVar(&oauthCallbackPorts, "oauth-callback-port-range", "", "Port range like 8000-8100; the OAuth callback server listens on its first free port instead of the redirect URL's")
+	rootCmd.Flags().BoolVar(&oauthJAR, "oauth-jar", false, "Send the authorization request as a request object signed with a generated key (JAR, RFC 9101)")
+	rootCmd.Flags().BoolVar(&oauthJARPrint, "oauth-jar-print", false, "Print the claims and the JWT of each request object (requires --oauth-jar)")
+	rootCmd.Flags().StringVar(&oauthTokenAuthMethod, "oauth-token-auth-method", "", "Token endpoint authentication: 'tls_client_auth' or 'self_signed_tls_client_auth' with the --tls-cert client certificate (RFC 8705), or 'private_key_jwt' with a signed client assertion (RFC 7523)")
+	rootCmd.Flags().StringVar(&oauthSigningKey, "oauth-signing-key", "", "PEM private key (RSA or EC) signing client assertions and request objects; a key is generated for the session if not set")
+	rootCmd.Flags().DurationVar(&oauthRefreshBefore, "oauth-refresh-before", time.Minute, "Refresh the access token this long before it expires (0 refreshes only once it has expired)")
+	rootCmd.Flags().DurationVar(&oauthTokenLogInterval, "oauth-token-log-interval", 0, "Log the remaining lifetime of the access token at this interval (0 disables)")
+	rootCmd.Flags().BoolVar(&oauthStepMode, "oauth-step-mode", false, "Show every request of the OAuth flow before it is sent and wait for confirmation")
 	rootCmd.Flags().BoolVar(&oauthDisableCIMD, "oauth-disable-cimd", false, "Disable Client ID Metadata Documents (falls back to DCR or manual registration)")
 
 	// Add subcommands
 	rootCmd.AddCommand(newSelfUpdateCmd())
+	rootCmd.AddCommand(newBenchCmd())
+	rootCmd.AddCommand(newAssertCmd())
+	rootCmd.AddCommand(newProfileCmd())
+	rootCmd.AddCommand(newWebCmd())
+	rootCmd.AddCommand(newOneShotCmds()...)
+	rootCmd.AddCommand(newOAuthCmd())
+	rootCmd.AddCommand(newProtocolVersionsCmd())
+	rootCmd.AddCommand(newFuzzCmd())
+	rootCmd.AddCommand(newSnapshotCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newDocsCmd())
+	rootCmd.AddCommand(newInspectCmd())
 
 	// Mark flags as mutually exclusive
-	rootCmd.MarkFlagsMutuallyExclusive("repl", "mcp-server")
+	rootCmd.MarkFlagsMutuallyExclusive("repl", "mcp-server", "script")
 }
 
 // validateTransport validates the transport configuration
 func validateTransport() error {
-	if transport == transportStreamableHTTP && !strings.HasSuffix(endpoint, "/mcp") {
-		return fmt.Errorf("endpoint '%s' must end with /mcp for streamable-http transport", endpoint)
-	}
 	if transport != transportStreamableHTTP {
 		return fmt.Errorf("unsupported transport '%s' (only streamable-http is supported)", transport)
 	}
+	return validateEndpoint(endpoint)
+}
+
+// validateEndpoint checks that a streamable-http endpoint ends with /mcp
+func validateEndpoint(endpoint string) error {
+	if !strings.HasSuffix(endpoint, "/mcp") {
+		return fmt.Errorf("endpoint '%s' must end with /mcp for streamable-http transport", endpoint)
+	}
 	return nil
 }
 
@@ -175,37 +416,48 @@ func setupSignalHandler(cancel context.CancelFunc, silent bool) {
 }
 
 // buildOAuthConfig creates an OAuth configuration from CLI flags
-func buildOAuthConfig(cmd *cobra.Command, logger *agent.Logger) (*agent.OAuthConfig, error) {
+func buildOAuthConfig(cmd *cobra.Command, applied *appliedConfig, logger *agent.Logger) (*agent.OAuthConfig, error) {
 	if !oauthEnabled {
 		return nil, nil
 	}
 
 	// Security warning: Check if client secret was passed via CLI flag
-	if oauthClientSecret != "" && cmd.Flags().Changed("oauth-client-secret") {
+	if oauthClientSecret != "" && applied.onCommandLine(cmd, "oauth-client-secret") {
 		logger.Warning("Security Warning: Client secret passed via CLI flag is visible in process listings")
-		logger.Info("Consider using environment variables instead: export OAUTH_CLIENT_SECRET=\"...\"")
+		logger.Info("Consider using the environment or the config file instead: export %s=\"...\"", agent.ConfigEnvName("oauth-client-secret"))
 	}
 
 	config := &agent.OAuthConfig{
-		Enabled:              true,
-		ClientID:             oauthClientID,
-		ClientSecret:         oauthClientSecret,
-		Scopes:               oauthScopes,
-		ScopeSelectionMode:   oauthScopeMode,
-		RedirectURL:          oauthRedirectURL,
-		UsePKCE:              oauthUsePKCE,
-		AuthorizationTimeout: oauthTimeout,
-		UseOIDC:              oauthUseOIDC,
-		RegistrationToken:    oauthRegistrationToken,
-		ResourceURI:          oauthResourceURI,
-		SkipResourceParam:    oauthSkipResource,
-		SkipResourceMetadata: oauthSkipResourceMeta,
-		PreferredAuthServer:  oauthPreferredAuthSrv,
-		EnableStepUpAuth:     !oauthDisableStepUp,
-		StepUpMaxRetries:     oauthStepUpMaxRetries,
-		StepUpUserPrompt:     oauthStepUpPrompt,
-		ClientIDMetadataURL:  oauthClientIDMetaURL,
-		DisableCIMD:          oauthDisableCIMD,
+		Enabled:                 true,
+		Flow:                    oauthFlow,
+		ClientID:                oauthClientID,
+		ClientSecret:            oauthClientSecret,
+		Scopes:                  oauthScopes,
+		ScopeSelectionMode:      oauthScopeMode,
+		RedirectURL:             oauthRedirectURL,
+		UsePKCE:                 oauthUsePKCE,
+		AuthorizationTimeout:    oauthTimeout,
+		UseOIDC:                 oauthUseOIDC,
+		RegistrationToken:       oauthRegistrationToken,
+		ResourceURI:             oauthResourceURI,
+		SkipResourceParam:       oauthSkipResource,
+		SkipResourceMetadata:    oauthSkipResourceMeta,
+		PreferredAuthServer:     oauthPreferredAuthSrv,
+		EnableStepUpAuth:        !oauthDisableStepUp,
+		StepUpMaxRetries:        oauthStepUpMaxRetries,
+		StepUpUserPrompt:        oauthStepUpPrompt,
+		ClientIDMetadataURL:     oauthClientIDMetaURL,
+		DisableCIMD:             oauthDisableCIMD,
+		RevokeOnExit:            oauthRevokeOnExit,
+		StepMode:                oauthStepMode,
+		ManualCode:              oauthManualCode,
+		CallbackPortRange:       oauthCallbackPorts,
+		JAR:                     oauthJAR,
+		PrintRequestObject:      oauthJARPrint,
+		TokenEndpointAuthMethod: oauthTokenAuthMethod,
+		SigningKeyFile:          oauthSigningKey,
+		RefreshBefore:           oauthRefreshBefore,
+		TokenLogInterval:        oauthTokenLogInterval,
 	}
 
 	config = config.WithDefaults()
@@ -223,12 +475,57 @@ func buildOAuthConfig(cmd *cobra.Command, logger *agent.Logger) (*agent.OAuthCon
 	return config, nil
 }
 
+// buildElicitationConfig creates the elicitation configuration from CLI flags
+func buildElicitationConfig(cmd *cobra.Command) (agent.ElicitationConfig, error) {
+	mode, err := agent.ParseElicitationMode(elicitMode)
+	if err != nil {
+		return agent.ElicitationConfig{}, err
+	}
+	if elicitAnswers == "" {
+		if mode == agent.ElicitationInteractive && mcpServer && serverTransport == "stdio" {
+			return agent.ElicitationConfig{}, fmt.Errorf("--elicitation=interactive cannot read input from stdin while it carries the MCP server protocol")
+		}
+		return agent.ElicitationConfig{Mode: mode}, nil
+	}
+
+	if !cmd.Flags().Changed("elicitation") {
+		mode = agent.ElicitationAuto
+	}
+	if mode != agent.ElicitationAuto {
+		return agent.ElicitationConfig{}, fmt.Errorf("--elicitation-answers requires --elicitation=auto")
+	}
+	answers, err := agent.LoadElicitationAnswers(elicitAnswers)
+	if err != nil {
+		return agent.ElicitationConfig{}, err
+	}
+	return agent.ElicitationConfig{Mode: mode, Answers: answers}, nil
+}
+
 // runMCPServer runs the agent in MCP server mode
-func runMCPServer(ctx context.Context, client *agent.Client, logger *agent.Logger) error {
-	server, err := agent.NewMCPServer(client, serverTransport, logger, false)
+func runMCPServer(ctx context.Context, client *agent.Client, logger *agent.Logger, sessionLog *agent.SessionLog) error {
+	server, err := agent.NewMCPServer(client, serverTransport, logger, sessionLog, false)
 	if err != nil {
 		return fmt.Errorf("failed to create MCP server: %w", err)
 	}
+	if serverIssuer != "" {
+		resource := serverResource
+		if resource == "" {
+			resource = defaultServerResource(listenAddr)
+		}
+		err := server.RequireBearerTokens(agent.ServerAuthConfig{
+			Issuer:   serverIssuer,
+			Resource: resource,
+			Scopes:   serverScopes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if chaosEnabled {
+		if err := server.InjectFaults(chaos); err != nil {
+			return err
+		}
+	}
 
 	logger.Info("Starting mcp-debug MCP server (transport: %s)...", serverTransport)
 	if serverTransport == transportStreamableHTTP {
@@ -245,6 +542,54 @@ func runMCPServer(ctx context.Context, client *agent.Client, logger *agent.Logge
 	return nil
 }
 
+// defaultServerResource derives the resource URI of the MCP server mode's
+// endpoint from its listen address, e.g. http://localhost:8899/mcp
+func defaultServerResource(addr string) string {
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://localhost" + addr + "/mcp"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/mcp"
+}
+
+// revokeTokensOnExit revokes the session's OAuth tokens. It uses its own
+// context because the run context is usually cancelled by then.
+func revokeTokensOnExit(client *agent.Client, logger *agent.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger.Info("Revoking OAuth tokens...")
+	if err := client.RevokeTokens(ctx); err != nil {
+		logger.Warning("%v", err)
+	}
+}
+
+// exportTraffic writes the recorded traffic to the HAR file and the OTLP
+// collector. It uses its own context because the run context is usually
+// cancelled by then.
+func exportTraffic(traffic *agent.TrafficRecorder, logger *agent.Logger) {
+	if harFile != "" {
+		if err := traffic.WriteHAR(harFile, version); err != nil {
+			logger.Warning("%v", err)
+		} else {
+			logger.Success("HTTP traffic written to %s", harFile)
+		}
+	}
+	if otelEndpoint != "" {
+		if err := traffic.ExportOTLP(context.Background(), otelEndpoint, version); err != nil {
+			logger.Warning("%v", err)
+		} else {
+			logger.Success("Spans exported to %s", otelEndpoint)
+		}
+	}
+}
+
 // runNormalMode runs the agent in normal (listen) mode
 func runNormalMode(ctx context.Context, client *agent.Client, logger *agent.Logger) error {
 	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, timeout)
@@ -260,40 +605,312 @@ func runNormalMode(ctx context.Context, client *agent.Client, logger *agent.Logg
 	return nil
 }
 
+// chaosFlagChanged reports whether a --chaos-* flag was set, which implies
+// --chaos
+func chaosFlagChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{
+		"chaos-latency", "chaos-drop-notifications", "chaos-malformed", "chaos-truncate",
+		"chaos-http-errors", "chaos-error-burst", "chaos-error-statuses", "chaos-reset", "chaos-seed",
+	} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
 func runMCPDebug(cmd *cobra.Command, args []string) error {
+	applied, err := applyConfig(cmd)
+	if err != nil {
+		return err
+	}
 	if err := validateTransport(); err != nil {
 		return err
 	}
+	if chaosFlagChanged(cmd) {
+		chaosEnabled = true
+	}
+
+	announceCaps = agent.SplitClientCapabilities(announceCaps)
+	if err := agent.ValidateClientCapabilities(announceCaps); err != nil {
+		return err
+	}
+	metaFields, err := agent.ParseMetaEntries(requestMeta)
+	if err != nil {
+		return err
+	}
+	headers, err := agent.ParseHeaders(customHeaders)
+	if err != nil {
+		return err
+	}
+	parsedCookies, err := agent.ParseCookies(cookies)
+	if err != nil {
+		return err
+	}
+	overflowPolicy, err := agent.ParseNotificationOverflowPolicy(notifyOverflow)
+	if err != nil {
+		return err
+	}
+	format, err := agent.ParseOutputFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	display, err := agent.ParseDisplayFormat(displayFormat)
+	if err != nil {
+		return err
+	}
+	preview, err := agent.ParseImagePreview(imagePreview)
+	if err != nil {
+		return err
+	}
+	displayOptions := agent.DisplayOptions{
+		MaxBytes:     maxDisplay,
+		Format:       display,
+		SaveBinary:   saveBinary,
+		ImagePreview: preview,
+		NoColor:      noColor,
+		NoPager:      noPager,
+		FoldLines:    foldLines,
+	}
+	consoleLevel, err := agent.ParseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	if verbose && consoleLevel > agent.LevelDebug {
+		consoleLevel = agent.LevelDebug
+	}
+	fileLevel, err := agent.ParseLogLevel(logFileLevel)
+	if err != nil {
+		return fmt.Errorf("--log-file-level: %w", err)
+	}
+	redactor, err := agent.NewRedactor(redactPatterns)
+	if err != nil {
+		return err
+	}
+	if noRedact {
+		redactor = nil
+	}
+	sampling, err := agent.ParseSamplingMode(samplingMode)
+	if err != nil {
+		return err
+	}
+	if _, err := agent.ParseSamplingTemplate(samplingReply); err != nil {
+		return err
+	}
+	if sampling == agent.SamplingInteractive && mcpServer && serverTransport == "stdio" {
+		return fmt.Errorf("--sampling=interactive cannot read responses from stdin while it carries the MCP server protocol")
+	}
+	elicitation, err := buildElicitationConfig(cmd)
+	if err != nil {
+		return err
+	}
 
 	ctx, cancel := context.WithCancel(cmd.Context())
 	defer cancel()
 
 	setupSignalHandler(cancel, mcpServer)
 
+	payloadOptions := agent.PayloadLogOptions{
+		Compact:    logCompact,
+		MaxBytes:   logMaxPayload,
+		SampleRate: logSampleRate,
+	}
 	logger := agent.NewLogger(verbose, !noColor, jsonRPC)
+	logger.SetLevel(consoleLevel)
+	logger.SetRedactor(redactor)
+	logger.SetOutputFormat(format)
+	logger.SetPayloadOptions(payloadOptions)
+
+	// The log file gets its own level so a full trace can be captured
+	// without flooding the terminal
+	if logFile != "" {
+		file, err := agent.OpenLogFile(logFile, int64(logFileMaxSize)<<20, logFileBackups)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		fileLogger := agent.NewLoggerWithWriter(false, false, jsonRPC, file)
+		fileLogger.SetLevel(fileLevel)
+		fileLogger.SetRedactor(redactor)
+		fileLogger.SetOutputFormat(format)
+		fileLogger.SetPayloadOptions(payloadOptions)
+		logger.SetTee(fileLogger)
+	}
+	applied.log(logger)
+
+	// One-shot commands print their result on stdout, so scripts can pipe it
+	if oneShot != nil || protocolMatrix != nil || serverDiff != nil {
+		logger.SetWriter(os.Stderr)
+	}
+
+	// In MCP server mode, keep the latest log lines for get_session_log;
+	// the web inspector streams them to the browser
+	var sessionLog *agent.SessionLog
+	if mcpServer || webMode {
+		sessionLog = agent.NewSessionLog(agent.DefaultSessionLogLines)
+		logger.SetWriter(io.MultiWriter(os.Stdout, sessionLog))
+	}
+
+	stopProfiling, err := startProfiling(logger)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
 
-	oauthConfig, err := buildOAuthConfig(cmd, logger)
+	if err := agent.ConfigureTLS(tlsConfig, logger); err != nil {
+		return err
+	}
+	if err := agent.ConfigureProxy(proxyURL, logger); err != nil {
+		return err
+	}
+	if err := agent.ConfigureMetadataNetworks(metadataNets, logger); err != nil {
+		return err
+	}
+	// The REPL history belongs to the endpoint as given, not the local
+	// address of a port-forward or container
+	historyFile := agent.DefaultHistoryFile(profileName, endpoint)
+	if kubeForward.Service != "" {
+		forward, err := agent.StartPortForward(ctx, kubeForward, logger)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = forward.Close() }()
+		if endpoint, err = agent.ForwardedEndpoint(endpoint, forward.Addr); err != nil {
+			return err
+		}
+	} else if kubeForward.Namespace != "" || kubeForward.Context != "" || kubeForward.Port != 0 {
+		return fmt.Errorf("--kube-namespace, --kube-context and --kube-port require --kube-service")
+	}
+	if sshTunnel.Destination != "" {
+		if kubeForward.Service != "" {
+			return fmt.Errorf("--ssh cannot be combined with --kube-service")
+		}
+		tunnel, err := agent.ConfigureSSHTunnel(ctx, sshTunnel, endpoint, logger)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tunnel.Close() }()
+	} else if sshTunnel.IdentityFile != "" || sshTunnel.KnownHostsFile != "" {
+		return fmt.Errorf("--ssh-identity and --ssh-known-hosts require --ssh")
+	}
+	if dockerServer.Image != "" {
+		if kubeForward.Service != "" || sshTunnel.Destination != "" {
+			return fmt.Errorf("--docker-image cannot be combined with --kube-service or --ssh")
+		}
+		container, containerEndpoint, err := agent.StartDockerContainer(ctx, dockerServer, endpoint, logger)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = container.Close() }()
+		endpoint = containerEndpoint
+	} else if dockerServer.Port != 0 || len(dockerServer.RunArgs) > 0 {
+		return fmt.Errorf("--docker-port and --docker-arg require --docker-image")
+	} else if len(dockerServer.Env) > 0 || dockerServer.EnvFile != "" || dockerServer.NoInheritEnv {
+		// The container is the only server mcp-debug starts
+		return fmt.Errorf("--env, --env-file and --no-inherit-env require --docker-image")
+	}
+	agent.ConfigureMetadataCache(metadataCache, logger)
+
+	oauthConfig, err := buildOAuthConfig(cmd, applied, logger)
 	if err != nil {
 		return err
 	}
 
-	client := agent.NewClient(agent.ClientConfig{
-		Endpoint:    endpoint,
-		Transport:   transport,
-		Logger:      logger,
-		OAuthConfig: oauthConfig,
-		Version:     version,
-	})
+	var traffic *agent.TrafficRecorder
+	if harFile != "" || otelEndpoint != "" {
+		traffic = agent.NewTrafficRecorder()
+		traffic.SetRedactor(redactor)
+		defer exportTraffic(traffic, logger)
+	}
+
+	clientConfig := agent.ClientConfig{
+		Endpoint:        endpoint,
+		Transport:       transport,
+		Logger:          logger,
+		OAuthConfig:     oauthConfig,
+		Version:         version,
+		ProtocolVersion: protocolVersion,
+
+		ListChangedDebounce:   listDebounce,
+		AnnouncedCapabilities: announceCaps,
+		PingInterval:          pingInterval,
+		PingFailureThreshold:  pingFailures,
+		ReconnectMaxAttempts:  reconnectTries,
+		ReconnectInitialDelay: reconnectDelay,
+		ReconnectMaxDelay:     reconnectMax,
+		HTTPRetryMaxAttempts:  retryAttempts,
+		HTTPRetryInitialDelay: retryDelay,
+		HTTPRetryMaxDelay:     retryMaxDelay,
+		HTTPRetryStatuses:     retryStatuses,
+		ResumeSessions:        resumeSessions,
+		SessionFile:           sessionFile,
+		CacheTTL:              cacheTTL,
+		CallTimeout:           callTimeout,
+		MaxListPages:          maxListPages,
+		StrictSchema:          strictSchema,
+		CapabilityHistoryFile: capHistoryFile,
+
+		RequestMeta:            metaFields,
+		Headers:                headers,
+		Cookies:                parsedCookies,
+		MaxInFlight:            maxInFlight,
+		ResourceMemoryLimit:    resourceMemMax,
+		ResourceETagMeta:       resourceETag,
+		NotificationBufferSize: notifyBuffer,
+		NotificationOverflow:   overflowPolicy,
+		NoInitialList:          noInitialList,
+		Traffic:                traffic,
+		Sampling: agent.SamplingConfig{
+			Mode:     sampling,
+			Response: samplingReply,
+			Model:    samplingModel,
+		},
+		Elicitation: elicitation,
+	}
+	if protocolMatrix != nil {
+		return runProtocolMatrix(ctx, clientConfig, cmd.OutOrStdout())
+	}
+	if serverDiff != nil {
+		return runServerDiff(ctx, clientConfig, cmd.OutOrStdout())
+	}
+	if !slices.Contains(agent.ProtocolVersions(), protocolVersion) {
+		logger.Warning("Requesting protocol version %s, which mcp-debug does not speak; the server must answer with one of %s", protocolVersion, strings.Join(agent.ProtocolVersions(), ", "))
+	}
+
+	client := agent.NewClient(clientConfig)
 	if err := client.Run(ctx); err != nil {
 		return fmt.Errorf("failed to connect client: %w", err)
 	}
+	if oauthConfig != nil && oauthConfig.RevokeOnExit {
+		defer revokeTokensOnExit(client, logger)
+	}
+
+	if oneShot != nil {
+		return oneShot(ctx, client, cmd.OutOrStdout())
+	}
 
 	if mcpServer {
-		return runMCPServer(ctx, client, logger)
+		return runMCPServer(ctx, client, logger, sessionLog)
+	}
+
+	if webMode {
+		return agent.NewWebUI(client, logger, sessionLog).Start(ctx, webListen)
+	}
+
+	if script != "" {
+		scriptREPL := agent.NewREPL(client, logger)
+		scriptREPL.SetDisplayOptions(displayOptions)
+		if err := scriptREPL.RunScript(ctx, script); err != nil {
+			return fmt.Errorf("script failed: %w", err)
+		}
+		return nil
 	}
 
 	if repl {
 		replHandler := agent.NewREPL(client, logger)
+		replHandler.SetDisplayOptions(displayOptions)
+		replHandler.SetHistoryFile(historyFile)
 		if err := replHandler.Run(ctx); err != nil {
 			return fmt.Errorf("REPL error: %w", err)
 		}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/mcp-debug/internal/agent"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// snapshotCapabilitiesOut is the file snapshot capabilities writes to
+var snapshotCapabilitiesOut string
+
+// newSnapshotCmd creates the snapshot command with its record, verify and
+// capabilities subcommands. They accept the connection flags of the root
+// command.
+func newSnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Record tool results and verify them later (golden files)",
+		Long: `Snapshot files list tool calls together with the results recorded for them.
+'snapshot record' calls the tools and stores their results in the file;
+'snapshot verify' calls them again and reports where the results differ,
+for using recorded results as golden files in CI pipelines.
+
+Values selected by the JSONPaths in "ignore", such as timestamps or request
+ids, are replaced with "<ignored>" before results are stored or compared.`,
+	}
+
+	recordCmd := &cobra.Command{
+		Use:   "record <snapshot-file>",
+		Short: "Call the tools of a snapshot file and store their results in it",
+		Long: `Calls the tool of every case in the snapshot file and writes the results
+back into the file, replacing results recorded before. Tool errors are
+recorded like any other result. If a call fails, the file is left unchanged.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			suite, err := agent.LoadSnapshotSuite(args[0])
+			if err != nil {
+				return err
+			}
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				if err := client.RecordSnapshots(ctx, suite); err != nil {
+					return err
+				}
+				if err := agent.SaveSnapshotSuite(args[0], suite); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(out, "Recorded %d snapshot(s) in %s\n", len(suite.Cases), args[0])
+				return nil
+			})
+		},
+	}
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify <snapshot-file>...",
+		Short: "Call the tools of snapshot files and compare the results with the recorded ones",
+		Long: `Calls the tool of every case in the snapshot files and compares the result
+with the recorded one, listing each difference with its JSONPath.
+
+Every case is run even if an earlier one fails. The exit code is 0 if all
+results match, 9 if any differs, and the usual connection exit codes if the
+server cannot be reached.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var suites []*agent.SnapshotSuite
+			for _, path := range args {
+				suite, err := agent.LoadSnapshotSuite(path)
+				if err != nil {
+					return err
+				}
+				suites = append(suites, suite)
+			}
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				var results []agent.SnapshotResult
+				total := 0
+				for _, suite := range suites {
+					results = append(results, client.VerifySnapshots(ctx, suite)...)
+					total += len(suite.Cases)
+				}
+				agent.WriteSnapshotResults(out, results)
+				return agent.SnapshotsError(results, total)
+			})
+		},
+	}
+
+	capabilitiesCmd := &cobra.Command{
+		Use:   "capabilities",
+		Short: "Save the server's capabilities, tools, resources and prompts for offline inspection",
+		Long: `Connects to the MCP server and writes what it announces and lists to a JSON
+file: its identity and instructions, its capabilities, and all tools,
+resources, resource templates and prompts. 'mcp-debug inspect <file>' loads
+the file into the REPL without a connection, so the server's surface can be
+reviewed without network access.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOneShot(cmd, func(ctx context.Context, client *agent.Client, out io.Writer) error {
+				snapshot, err := client.CapabilitySnapshot(ctx)
+				if err != nil {
+					return err
+				}
+				if snapshotCapabilitiesOut == "" {
+					return writeJSON(out, snapshot)
+				}
+				return agent.SaveCapabilitySnapshot(snapshotCapabilitiesOut, snapshot)
+			})
+		},
+	}
+	capabilitiesCmd.Flags().StringVar(&snapshotCapabilitiesOut, "out", "", "Write the snapshot to this file instead of stdout")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if webExcludedFlags[flag.Name] || oneShotExcludedFlags[flag.Name] || isChaosFlag(flag.Name) {
+			return
+		}
+		recordCmd.Flags().AddFlag(flag)
+		verifyCmd.Flags().AddFlag(flag)
+		capabilitiesCmd.Flags().AddFlag(flag)
+	})
+	snapshotCmd.AddCommand(recordCmd, verifyCmd, capabilitiesCmd)
+	return snapshotCmd
+}
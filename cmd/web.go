@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// webMode serves the web inspector instead of running another mode
+	webMode   bool
+	webListen string
+)
+
+// webExcludedFlags are the root flags selecting other modes or configuring
+// the MCP server mode, which do not apply to the web inspector
+var webExcludedFlags = map[string]bool{
+	"repl":                  true,
+	"script":                true,
+	"mcp-server":            true,
+	"timeout":               true,
+	"server-transport":      true,
+	"listen-addr":           true,
+	"server-oauth-issuer":   true,
+	"server-oauth-resource": true,
+	"server-oauth-scopes":   true,
+}
+
+// newWebCmd creates the web command. It accepts the connection flags of
+// the root command.
+func newWebCmd() *cobra.Command {
+	webCmd := &cobra.Command{
+		Use:   "web",
+		Short: "Inspect the server in a browser",
+		Long: `Connects to the MCP server and serves a web UI showing the live message log,
+the server's tools, resources and prompts, and a form to call tools. Useful
+for demoing an MCP server to teammates who do not use the CLI.
+
+JSON-RPC messages are logged unless --json-rpc=false is given. Anyone who can
+reach the listen address can call tools with your credentials, so it binds to
+localhost unless --listen says otherwise.`,
+		Args: cobra.NoArgs,
+		RunE: runWeb,
+	}
+	webCmd.Flags().StringVar(&webListen, "listen", "localhost:8080", "Address to serve the web UI on")
+
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !webExcludedFlags[flag.Name] && !isChaosFlag(flag.Name) {
+			webCmd.Flags().AddFlag(flag)
+		}
+	})
+	return webCmd
+}
+
+// isChaosFlag reports whether the flag configures fault injection in the
+// MCP server mode
+func isChaosFlag(name string) bool {
+	return name == "chaos" || strings.HasPrefix(name, "chaos-")
+}
+
+// runWeb connects to the server and serves the web inspector
+func runWeb(cmd *cobra.Command, args []string) error {
+	webMode = true
+	// The message log is the main view of the inspector
+	if !cmd.Flags().Changed("json-rpc") {
+		jsonRPC = true
+	}
+	return runMCPDebug(cmd, args)
+}
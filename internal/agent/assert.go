@@ -0,0 +1,379 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// AssertionSuite is the expectation file read by `assert`: tool calls with
+// the checks their results must pass
+type AssertionSuite struct {
+	Cases []AssertionCase `json:"cases"`
+}
+
+// AssertionCase calls one tool and checks its result
+type AssertionCase struct {
+	// Name identifies the case in the report; defaults to the tool name and
+	// position
+	Name string `json:"name,omitempty"`
+	// Tool is the name of the tool to call
+	Tool string `json:"tool"`
+	// Arguments are passed to the tool
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// ToolError expects the tool to report an error (isError). Without it,
+	// a result with isError fails the case.
+	ToolError bool `json:"toolError,omitempty"`
+	// Expect are the checks run against the result
+	Expect []AssertionMatcher `json:"expect,omitempty"`
+}
+
+// AssertionMatcher checks the values a JSONPath selects in the tool result,
+// e.g. $.structuredContent.total or $.content[0].text. Every selected value
+// must pass every check that is set.
+type AssertionMatcher struct {
+	Path string `json:"path"`
+	// Exists checks whether the path selects anything
+	Exists *bool `json:"exists,omitempty"`
+	// Equals and NotEquals compare with a JSON value
+	Equals    json.RawMessage `json:"equals,omitempty"`
+	NotEquals json.RawMessage `json:"notEquals,omitempty"`
+	// Contains checks for a substring of a string or an element of an array
+	Contains json.RawMessage `json:"contains,omitempty"`
+	// Matches is a regular expression a string must match
+	Matches string `json:"matches,omitempty"`
+	// Length is the length of a string, array or object
+	Length *int `json:"length,omitempty"`
+	// Type is the JSON type: null, boolean, number, integer, string, array
+	// or object
+	Type string `json:"type,omitempty"`
+	// GreaterThan, AtLeast, LessThan and AtMost bound a number
+	GreaterThan *float64 `json:"gt,omitempty"`
+	AtLeast     *float64 `json:"gte,omitempty"`
+	LessThan    *float64 `json:"lt,omitempty"`
+	AtMost      *float64 `json:"lte,omitempty"`
+}
+
+// LoadAssertionSuite reads and validates an expectation file
+func LoadAssertionSuite(path string) (*AssertionSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expectation file: %w", err)
+	}
+
+	var suite AssertionSuite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse expectation file %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("expectation file %s has no cases", path)
+	}
+	for i := range suite.Cases {
+		tc := &suite.Cases[i]
+		if tc.Tool == "" {
+			return nil, fmt.Errorf("%s: case %d has no tool", path, i+1)
+		}
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("%s #%d", tc.Tool, i+1)
+		}
+		for _, m := range tc.Expect {
+			if err := m.validate(); err != nil {
+				return nil, fmt.Errorf("%s: case %q: %w", path, tc.Name, err)
+			}
+		}
+	}
+	return &suite, nil
+}
+
+// validate reports matchers that could never run
+func (m AssertionMatcher) validate() error {
+	if _, err := parseJSONPath(m.Path); err != nil {
+		return err
+	}
+	if m.Matches != "" {
+		if _, err := regexp.Compile(m.Matches); err != nil {
+			return fmt.Errorf("%s: invalid regular expression: %w", m.Path, err)
+		}
+	}
+	switch m.Type {
+	case "", "null", "boolean", "number", "integer", "string", "array", "object":
+	default:
+		return fmt.Errorf("%s: unknown type %q", m.Path, m.Type)
+	}
+	if m.Exists == nil && !m.hasValueChecks() {
+		return fmt.Errorf("%s: no check given", m.Path)
+	}
+	return nil
+}
+
+// hasValueChecks reports whether any check other than exists is set
+func (m AssertionMatcher) hasValueChecks() bool {
+	return len(m.Equals) > 0 || len(m.NotEquals) > 0 || len(m.Contains) > 0 || m.Matches != "" ||
+		m.Length != nil || m.Type != "" || m.GreaterThan != nil || m.AtLeast != nil || m.LessThan != nil || m.AtMost != nil
+}
+
+// check runs the matcher against a decoded tool result and returns the
+// failures, each prefixed with the path
+func (m AssertionMatcher) check(doc any) []string {
+	steps, err := parseJSONPath(m.Path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	values := evaluateJSONPath(doc, steps)
+
+	if m.Exists != nil && *m.Exists != (len(values) > 0) {
+		if *m.Exists {
+			return []string{fmt.Sprintf("%s: selects nothing", m.Path)}
+		}
+		return []string{fmt.Sprintf("%s: exists, got %s", m.Path, compactJSON(values[0]))}
+	}
+	if !m.hasValueChecks() {
+		return nil
+	}
+	if len(values) == 0 {
+		return []string{fmt.Sprintf("%s: selects nothing", m.Path)}
+	}
+
+	var failures []string
+	for _, value := range values {
+		if msg := m.checkValue(value); msg != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", m.Path, msg))
+		}
+	}
+	return failures
+}
+
+// checkValue returns why value fails the matcher, or "" if it passes
+func (m AssertionMatcher) checkValue(value any) string {
+	got := compactJSON(value)
+
+	if len(m.Equals) > 0 {
+		if want, err := decodeJSONValue(m.Equals); err != nil || !reflect.DeepEqual(value, want) {
+			return fmt.Sprintf("got %s, want %s", got, string(m.Equals))
+		}
+	}
+	if len(m.NotEquals) > 0 {
+		if unwanted, err := decodeJSONValue(m.NotEquals); err == nil && reflect.DeepEqual(value, unwanted) {
+			return fmt.Sprintf("got %s, want anything else", got)
+		}
+	}
+	if len(m.Contains) > 0 {
+		if msg := checkContains(value, m.Contains); msg != "" {
+			return msg
+		}
+	}
+	if m.Matches != "" {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("got %s, want a string matching %q", got, m.Matches)
+		}
+		if re := regexp.MustCompile(m.Matches); !re.MatchString(s) {
+			return fmt.Sprintf("got %s, want a match of %q", got, m.Matches)
+		}
+	}
+	if m.Length != nil {
+		length, ok := jsonLength(value)
+		if !ok {
+			return fmt.Sprintf("got %s, want a string, array or object of length %d", got, *m.Length)
+		}
+		if length != *m.Length {
+			return fmt.Sprintf("got length %d, want %d", length, *m.Length)
+		}
+	}
+	if m.Type != "" && !hasJSONType(value, m.Type) {
+		return fmt.Sprintf("got %s, want type %s", got, m.Type)
+	}
+	return m.checkBounds(value)
+}
+
+// checkBounds applies the numeric comparisons
+func (m AssertionMatcher) checkBounds(value any) string {
+	bounds := []struct {
+		limit *float64
+		op    string
+		ok    func(v, limit float64) bool
+	}{
+		{m.GreaterThan, ">", func(v, limit float64) bool { return v > limit }},
+		{m.AtLeast, ">=", func(v, limit float64) bool { return v >= limit }},
+		{m.LessThan, "<", func(v, limit float64) bool { return v < limit }},
+		{m.AtMost, "<=", func(v, limit float64) bool { return v <= limit }},
+	}
+	for _, bound := range bounds {
+		if bound.limit == nil {
+			continue
+		}
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Sprintf("got %s, want a number %s %g", compactJSON(value), bound.op, *bound.limit)
+		}
+		if !bound.ok(v, *bound.limit) {
+			return fmt.Sprintf("got %g, want %s %g", v, bound.op, *bound.limit)
+		}
+	}
+	return ""
+}
+
+// checkContains checks for a substring of a string or an element of an array
+func checkContains(value any, raw json.RawMessage) string {
+	want, err := decodeJSONValue(raw)
+	if err != nil {
+		return fmt.Sprintf("invalid contains value: %v", err)
+	}
+	switch v := value.(type) {
+	case string:
+		if s, ok := want.(string); ok && strings.Contains(v, s) {
+			return ""
+		}
+	case []any:
+		for _, element := range v {
+			if reflect.DeepEqual(element, want) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("got %s, want it to contain %s", compactJSON(value), string(raw))
+}
+
+// decodeJSONValue decodes a JSON value the way tool results are decoded
+func decodeJSONValue(raw json.RawMessage) (any, error) {
+	var value any
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+// jsonLength returns the length of a string (in characters), array or object
+func jsonLength(value any) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), true
+	case []any:
+		return len(v), true
+	case map[string]any:
+		return len(v), true
+	}
+	return 0, false
+}
+
+// hasJSONType reports whether a decoded value has the named JSON type
+func hasJSONType(value any, name string) bool {
+	switch v := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case float64:
+		return name == "number" || (name == "integer" && v == math.Trunc(v))
+	case string:
+		return name == "string"
+	case []any:
+		return name == "array"
+	case map[string]any:
+		return name == "object"
+	}
+	return false
+}
+
+// AssertionResult is the outcome of one assertion case
+type AssertionResult struct {
+	Case     AssertionCase
+	Failures []string
+	Duration time.Duration
+}
+
+// Passed reports whether every check of the case passed
+func (r AssertionResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// RunAssertions calls the tool of each case in order and checks its result.
+// A failed call fails its case without stopping the run, unless ctx ends.
+func (c *Client) RunAssertions(ctx context.Context, cases []AssertionCase) []AssertionResult {
+	results := make([]AssertionResult, 0, len(cases))
+	for _, tc := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		failures := c.runAssertionCase(ctx, tc)
+		results = append(results, AssertionResult{Case: tc, Failures: failures, Duration: time.Since(start)})
+	}
+	return results
+}
+
+// runAssertionCase calls the case's tool and returns the failed checks
+func (c *Client) runAssertionCase(ctx context.Context, tc AssertionCase) []string {
+	result, err := c.CallTool(ctx, tc.Tool, tc.Arguments)
+	if err != nil {
+		return []string{fmt.Sprintf("call failed: %v", err)}
+	}
+
+	var failures []string
+	switch {
+	case result.IsError && !tc.ToolError:
+		failures = append(failures, fmt.Sprintf("unexpected tool error: %v", ToolResultError(result)))
+	case !result.IsError && tc.ToolError:
+		failures = append(failures, "expected the tool to report an error")
+	}
+
+	doc, err := decodeToolResult(result)
+	if err != nil {
+		return append(failures, fmt.Sprintf("cannot decode the result: %v", err))
+	}
+	for _, m := range tc.Expect {
+		failures = append(failures, m.check(doc)...)
+	}
+	return failures
+}
+
+// decodeToolResult converts a tool result into the generic JSON form that
+// assertion paths select from
+func decodeToolResult(result *mcp.CallToolResult) (any, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONValue(data)
+}
+
+// WriteAssertionResults prints one line per case, the failures of failed
+// cases and a summary
+func WriteAssertionResults(w io.Writer, results []AssertionResult) {
+	passed := 0
+	for _, r := range results {
+		status := "FAIL"
+		if r.Passed() {
+			status = "PASS"
+			passed++
+		}
+		_, _ = fmt.Fprintf(w, "%s  %s (%s)\n", status, r.Case.Name, r.Duration.Round(time.Millisecond))
+		for _, failure := range r.Failures {
+			_, _ = fmt.Fprintf(w, "      %s\n", failure)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d passed, %d failed\n", passed, len(results)-passed)
+}
+
+// AssertionsError returns an ErrAssertionFailed error if any case failed or
+// did not run, nil otherwise
+func AssertionsError(results []AssertionResult, total int) error {
+	failed := total - len(results)
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return withKind(ErrAssertionFailed, fmt.Errorf("%d of %d assertion case(s) failed", failed, total))
+}
@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a parsed JSONPath: a member name, an array index
+// or a wildcard over all members or elements
+type pathStep struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the JSONPath subset used by assertions: a leading $
+// followed by .name, ['name'], [n] (negative n counts from the end), [*]
+// and .* steps
+func parseJSONPath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid path %q: must start with $", path)
+	}
+
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: empty member name", path)
+			}
+			steps = append(steps, pathStep{name: name, wildcard: name == "*"})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", path)
+			}
+			step, err := parseBracketStep(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// parseBracketStep parses the inside of [...]
+func parseBracketStep(inner string) (pathStep, error) {
+	if inner == "*" {
+		return pathStep{wildcard: true}, nil
+	}
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return pathStep{name: inner[1 : len(inner)-1]}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return pathStep{}, fmt.Errorf("invalid index [%s]", inner)
+	}
+	return pathStep{index: index, isIndex: true}, nil
+}
+
+// evaluateJSONPath returns the values the steps select in a decoded JSON
+// document; missing members and out-of-range indexes select nothing
+func evaluateJSONPath(doc any, steps []pathStep) []any {
+	values := []any{doc}
+	for _, step := range steps {
+		var next []any
+		for _, value := range values {
+			next = append(next, step.apply(value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+// apply returns the values a step selects in value
+func (s pathStep) apply(value any) []any {
+	switch v := value.(type) {
+	case map[string]any:
+		if s.wildcard {
+			values := make([]any, 0, len(v))
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				values = append(values, v[key])
+			}
+			return values
+		}
+		if member, ok := v[s.name]; ok && !s.isIndex {
+			return []any{member}
+		}
+	case []any:
+		if s.wildcard {
+			return v
+		}
+		if !s.isIndex {
+			return nil
+		}
+		index := s.index
+		if index < 0 {
+			index += len(v)
+		}
+		if index >= 0 && index < len(v) {
+			return []any{v[index]}
+		}
+	}
+	return nil
+}
+
+// replaceJSONPath returns a copy of doc in which the values the steps select
+// are replaced with replacement; doc itself is not modified
+func replaceJSONPath(doc any, steps []pathStep, replacement any) any {
+	if len(steps) == 0 {
+		return replacement
+	}
+	step, rest := steps[0], steps[1:]
+	switch v := doc.(type) {
+	case map[string]any:
+		replaced := maps.Clone(v)
+		for key, member := range v {
+			if step.wildcard || (!step.isIndex && key == step.name) {
+				replaced[key] = replaceJSONPath(member, rest, replacement)
+			}
+		}
+		return replaced
+	case []any:
+		if !step.wildcard && !step.isIndex {
+			return doc
+		}
+		replaced := slices.Clone(v)
+		for i, element := range v {
+			index := step.index
+			if index < 0 {
+				index += len(v)
+			}
+			if step.wildcard || i == index {
+				replaced[i] = replaceJSONPath(element, rest, replacement)
+			}
+		}
+		return replaced
+	}
+	return doc
+}
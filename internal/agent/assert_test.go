@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestJSONPath(t *testing.T) {
+	doc, err := decodeJSONValue([]byte(`{"content":[{"type":"text","text":"a"},{"type":"text","text":"b"}],"structuredContent":{"odd key":1,"n":2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"$", "[" + compactJSON(doc) + "]"},
+		{"$.content[1].text", `["b"]`},
+		{"$.content[-1].type", `["text"]`},
+		{"$.content[*].text", `["a","b"]`},
+		{"$.structuredContent['odd key']", `[1]`},
+		{`$.structuredContent.*`, `[2,1]`},
+		{"$.content[5].text", `null`},
+		{"$.missing.deeper", `null`},
+	} {
+		steps, err := parseJSONPath(tc.path)
+		if err != nil {
+			t.Errorf("parseJSONPath(%q): %v", tc.path, err)
+			continue
+		}
+		if got := compactJSON(evaluateJSONPath(doc, steps)); got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.path, got, tc.want)
+		}
+	}
+
+	for _, path := range []string{"content", "$.", "$[1", "$[x]", "$..a"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("parseJSONPath(%q): expected error", path)
+		}
+	}
+}
+
+func TestAssertionMatchers(t *testing.T) {
+	doc, _ := decodeJSONValue([]byte(`{"total":3,"name":"widget","tags":["a","b"],"empty":null}`))
+	yes, no := true, false
+	three, one := 3.0, 1.0
+	length := 2
+
+	for _, tc := range []struct {
+		matcher AssertionMatcher
+		failure string
+	}{
+		{AssertionMatcher{Path: "$.total", Equals: []byte("3")}, ""},
+		{AssertionMatcher{Path: "$.total", Equals: []byte("4")}, "$.total: got 3, want 4"},
+		{AssertionMatcher{Path: "$.name", NotEquals: []byte(`"widget"`)}, `$.name: got "widget", want anything else`},
+		{AssertionMatcher{Path: "$.name", Contains: []byte(`"idg"`)}, ""},
+		{AssertionMatcher{Path: "$.tags", Contains: []byte(`"c"`)}, `$.tags: got ["a","b"], want it to contain "c"`},
+		{AssertionMatcher{Path: "$.name", Matches: "^w.*t$"}, ""},
+		{AssertionMatcher{Path: "$.total", Matches: "3"}, `$.total: got 3, want a string matching "3"`},
+		{AssertionMatcher{Path: "$.tags", Length: &length}, ""},
+		{AssertionMatcher{Path: "$.name", Length: &length}, "$.name: got length 6, want 2"},
+		{AssertionMatcher{Path: "$.total", Type: "integer"}, ""},
+		{AssertionMatcher{Path: "$.empty", Type: "object"}, "$.empty: got null, want type object"},
+		{AssertionMatcher{Path: "$.total", AtLeast: &three, GreaterThan: &one}, ""},
+		{AssertionMatcher{Path: "$.total", LessThan: &three}, "$.total: got 3, want < 3"},
+		{AssertionMatcher{Path: "$.empty", Exists: &yes}, ""},
+		{AssertionMatcher{Path: "$.missing", Exists: &no}, ""},
+		{AssertionMatcher{Path: "$.name", Exists: &no}, `$.name: exists, got "widget"`},
+		{AssertionMatcher{Path: "$.missing", Equals: []byte("1")}, "$.missing: selects nothing"},
+		{AssertionMatcher{Path: "$.tags[*]", Type: "string"}, ""},
+	} {
+		if got := strings.Join(tc.matcher.check(doc), "; "); got != tc.failure {
+			t.Errorf("%+v: got %q, want %q", tc.matcher, got, tc.failure)
+		}
+	}
+}
+
+func writeExpectations(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "expect.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write expectations: %v", err)
+	}
+	return path
+}
+
+func TestLoadAssertionSuite(t *testing.T) {
+	suite, err := LoadAssertionSuite(writeExpectations(t, `{"cases":[{"tool":"echo","expect":[{"path":"$.isError","equals":false}]}]}`))
+	if err != nil {
+		t.Fatalf("LoadAssertionSuite: %v", err)
+	}
+	if suite.Cases[0].Name != "echo #1" {
+		t.Errorf("default name = %q", suite.Cases[0].Name)
+	}
+
+	for content, want := range map[string]string{
+		`{"cases":[]}`:              "has no cases",
+		`{"cases":[{"expect":[]}]}`: "case 1 has no tool",
+		`{"cases":[{"tool":"echo","expect":[{"path":"$.a"}]}]}`:               "$.a: no check given",
+		`{"cases":[{"tool":"echo","expect":[{"path":"a","exists":true}]}]}`:   "must start with $",
+		`{"cases":[{"tool":"echo","expect":[{"path":"$.a","matches":"("}]}]}`: "invalid regular expression",
+		`{"cases":[{"tool":"echo","expect":[{"path":"$.a","type":"int"}]}]}`:  `unknown type "int"`,
+	} {
+		if _, err := LoadAssertionSuite(writeExpectations(t, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestRunAssertions(t *testing.T) {
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		switch req.Params.Name {
+		case "echo":
+			return mcp.NewToolResultText(fmt.Sprint(req.GetArguments()["message"])), nil
+		case "fail":
+			return mcp.NewToolResultError("boom"), nil
+		}
+		return nil, mcp.ErrMethodNotFound
+	}}
+	c := newStubbedClient(t, stub)
+
+	suite, err := LoadAssertionSuite(writeExpectations(t, `{"cases":[
+		{"name":"echo passes","tool":"echo","arguments":{"message":"hi"},"expect":[{"path":"$.content[0].text","equals":"hi"}]},
+		{"name":"echo mismatch","tool":"echo","arguments":{"message":"hi"},"expect":[{"path":"$.content[0].text","equals":"bye"}]},
+		{"name":"expected tool error","tool":"fail","toolError":true,"expect":[{"path":"$.content[0].text","contains":"boom"}]},
+		{"name":"unexpected tool error","tool":"fail"},
+		{"name":"call error","tool":"missing"}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadAssertionSuite: %v", err)
+	}
+
+	results := c.RunAssertions(context.Background(), suite.Cases)
+	var report strings.Builder
+	WriteAssertionResults(&report, results)
+
+	for _, want := range []string{
+		"PASS  echo passes",
+		"FAIL  echo mismatch",
+		`      $.content[0].text: got "hi", want "bye"`,
+		"PASS  expected tool error",
+		"FAIL  unexpected tool error",
+		"unexpected tool error: tool failed: boom",
+		"FAIL  call error",
+		"call failed:",
+		"2 passed, 3 failed",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, report.String())
+		}
+	}
+
+	err = AssertionsError(results, len(suite.Cases))
+	if !errors.Is(err, ErrAssertionFailed) || err.Error() != "3 of 5 assertion case(s) failed" {
+		t.Errorf("AssertionsError = %v", err)
+	}
+	if err := AssertionsError(results[:1], 1); err != nil {
+		t.Errorf("AssertionsError for passing cases = %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BenchReport is the JSON document written by a benchmark run and read by
+// `bench compare`
+type BenchReport struct {
+	// Endpoint is the MCP server the benchmark ran against
+	Endpoint string `json:"endpoint,omitempty"`
+	// ServerVersion is the version the server reported during initialize
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// StartedAt is when the benchmark began
+	StartedAt time.Time `json:"startedAt"`
+	// Operations holds the measured latencies per operation
+	Operations []BenchOperation `json:"operations"`
+}
+
+// BenchOperation holds the measurements of one benchmarked operation
+type BenchOperation struct {
+	// Name identifies the operation, e.g. "tools/call echo"
+	Name string `json:"name"`
+	// SamplesMs are the latencies of successful requests in milliseconds
+	SamplesMs []float64 `json:"samplesMs"`
+	// Errors counts failed requests
+	Errors int `json:"errors,omitempty"`
+}
+
+// LoadBenchReport reads a benchmark report from a JSON file
+func LoadBenchReport(path string) (*BenchReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read benchmark report: %w", err)
+	}
+
+	var report BenchReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse benchmark report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// SaveBenchReport writes a benchmark report to a JSON file
+func SaveBenchReport(path string, report *BenchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode benchmark report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write benchmark report: %w", err)
+	}
+	return nil
+}
+
+// LatencySummary holds descriptive statistics of a latency sample in milliseconds
+type LatencySummary struct {
+	Count  int
+	Mean   float64
+	StdDev float64
+	P50    float64
+	P95    float64
+	P99    float64
+}
+
+// SummarizeLatencies computes descriptive statistics of samples
+func SummarizeLatencies(samples []float64) LatencySummary {
+	summary := LatencySummary{Count: len(samples)}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, s := range sorted {
+		sum += s
+	}
+	summary.Mean = sum / float64(len(sorted))
+
+	if len(sorted) > 1 {
+		var sq float64
+		for _, s := range sorted {
+			sq += (s - summary.Mean) * (s - summary.Mean)
+		}
+		summary.StdDev = math.Sqrt(sq / float64(len(sorted)-1))
+	}
+
+	summary.P50 = percentile(sorted, 50)
+	summary.P95 = percentile(sorted, 95)
+	summary.P99 = percentile(sorted, 99)
+	return summary
+}
+
+// percentile returns the p-th percentile of sorted samples using linear
+// interpolation between closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// BenchVerdict classifies the change of an operation between two reports
+type BenchVerdict string
+
+const (
+	VerdictRegression  BenchVerdict = "regression"
+	VerdictImprovement BenchVerdict = "improvement"
+	VerdictUnchanged   BenchVerdict = "unchanged"
+	VerdictAdded       BenchVerdict = "added"
+	VerdictRemoved     BenchVerdict = "removed"
+)
+
+// BenchDelta compares one operation between a baseline and a candidate report
+type BenchDelta struct {
+	Name string
+	Old  LatencySummary
+	New  LatencySummary
+	// P50Change and P95Change are relative changes, e.g. 0.1 for +10%
+	P50Change float64
+	P95Change float64
+	// TStat is Welch's t statistic of the mean difference
+	TStat float64
+	// Significant is true when |TStat| exceeds the 95% confidence bound
+	Significant bool
+	Verdict     BenchVerdict
+}
+
+// welchSignificance is the |t| above which a mean difference is treated as
+// significant; the normal approximation of a two-sided 95% interval
+const welchSignificance = 1.96
+
+// CompareBenchReports computes per-operation latency deltas. Changes of the
+// median smaller than threshold (e.g. 0.05 for 5%) or not statistically
+// significant are reported as unchanged.
+func CompareBenchReports(baseline, candidate *BenchReport, threshold float64) []BenchDelta {
+	oldOps := make(map[string]BenchOperation, len(baseline.Operations))
+	for _, op := range baseline.Operations {
+		oldOps[op.Name] = op
+	}
+	newOps := make(map[string]BenchOperation, len(candidate.Operations))
+	for _, op := range candidate.Operations {
+		newOps[op.Name] = op
+	}
+
+	names := make([]string, 0, len(oldOps)+len(newOps))
+	for name := range oldOps {
+		names = append(names, name)
+	}
+	for name := range newOps {
+		if _, exists := oldOps[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	deltas := make([]BenchDelta, 0, len(names))
+	for _, name := range names {
+		oldOp, inOld := oldOps[name]
+		newOp, inNew := newOps[name]
+
+		delta := BenchDelta{
+			Name: name,
+			Old:  SummarizeLatencies(oldOp.SamplesMs),
+			New:  SummarizeLatencies(newOp.SamplesMs),
+		}
+		switch {
+		case !inOld:
+			delta.Verdict = VerdictAdded
+		case !inNew:
+			delta.Verdict = VerdictRemoved
+		default:
+			delta.P50Change = relativeChange(delta.Old.P50, delta.New.P50)
+			delta.P95Change = relativeChange(delta.Old.P95, delta.New.P95)
+			delta.TStat = welchT(delta.Old, delta.New)
+			delta.Significant = math.Abs(delta.TStat) >= welchSignificance
+			delta.Verdict = VerdictUnchanged
+			if delta.Significant && math.Abs(delta.P50Change) >= threshold {
+				if delta.P50Change > 0 {
+					delta.Verdict = VerdictRegression
+				} else {
+					delta.Verdict = VerdictImprovement
+				}
+			}
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+// relativeChange returns (after-before)/before, or 0 if before is zero
+func relativeChange(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before
+}
+
+// welchT returns Welch's t statistic for the difference of the means of two
+// samples, or 0 if either sample is too small to estimate its variance
+func welchT(before, after LatencySummary) float64 {
+	if before.Count < 2 || after.Count < 2 {
+		return 0
+	}
+	se := math.Sqrt(before.StdDev*before.StdDev/float64(before.Count) + after.StdDev*after.StdDev/float64(after.Count))
+	if se == 0 {
+		if after.Mean == before.Mean {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, after.Mean-before.Mean)))
+	}
+	return (after.Mean - before.Mean) / se
+}
+
+// WriteBenchComparison renders deltas as a Markdown table suitable for
+// release notes, followed by a one-line summary
+func WriteBenchComparison(w io.Writer, deltas []BenchDelta) {
+	_, _ = fmt.Fprintln(w, "| Operation | p50 old | p50 new | Δ p50 | p95 old | p95 new | Δ p95 | Result |")
+	_, _ = fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---|")
+
+	counts := make(map[BenchVerdict]int)
+	for _, d := range deltas {
+		counts[d.Verdict]++
+
+		result := string(d.Verdict)
+		switch d.Verdict {
+		case VerdictRegression, VerdictImprovement:
+			result = fmt.Sprintf("**%s** (t=%.1f)", d.Verdict, d.TStat)
+		case VerdictUnchanged:
+			if d.Old.Count < 2 || d.New.Count < 2 {
+				result = "insufficient samples"
+			} else if !d.Significant {
+				result = "unchanged (within noise)"
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			d.Name,
+			formatLatency(d.Old, d.Old.P50), formatLatency(d.New, d.New.P50), formatChange(d, d.P50Change),
+			formatLatency(d.Old, d.Old.P95), formatLatency(d.New, d.New.P95), formatChange(d, d.P95Change),
+			result,
+		)
+	}
+
+	var parts []string
+	for _, v := range []BenchVerdict{VerdictRegression, VerdictImprovement, VerdictUnchanged, VerdictAdded, VerdictRemoved} {
+		if counts[v] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[v], v))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d operations compared: %s\n", len(deltas), strings.Join(parts, ", "))
+}
+
+// formatLatency renders a latency of a summary, or a dash for an empty sample
+func formatLatency(s LatencySummary, v float64) string {
+	if s.Count == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%.2fms", v)
+}
+
+// formatChange renders a relative change, or a dash for operations present
+// in only one report
+func formatChange(d BenchDelta, change float64) string {
+	if d.Verdict == VerdictAdded || d.Verdict == VerdictRemoved {
+		return "–"
+	}
+	return fmt.Sprintf("%+.1f%%", change*100)
+}
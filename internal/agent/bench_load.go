@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadTestConfig configures a load test of one tool
+type LoadTestConfig struct {
+	// Tool is the name of the tool to call
+	Tool string
+	// Arguments are passed unchanged to every call
+	Arguments map[string]any
+	// Concurrency is the number of workers calling the tool in parallel
+	Concurrency int
+	// Duration is how long new calls are started; calls in flight when it
+	// elapses are allowed to finish
+	Duration time.Duration
+	// MaxRequests stops the test after this many calls; zero means no limit
+	MaxRequests int
+	// Throttle holds calls back to stay under the rate limit the server
+	// announces, instead of measuring how it rejects the excess
+	Throttle bool
+}
+
+// LoadTestError counts the failed calls of one error kind
+type LoadTestError struct {
+	// Kind is the taxonomy sentinel's message, e.g. "tool failed", or
+	// "other" for unclassified errors
+	Kind  string
+	Count int
+	// Example is the message of the first error of this kind
+	Example string
+}
+
+// LoadTestResult holds the measurements of a load test
+type LoadTestResult struct {
+	Tool        string
+	Concurrency int
+	Connections int
+	StartedAt   time.Time
+	// Elapsed is the wall time from the first call until the last finished
+	Elapsed time.Duration
+	// Requests counts all completed calls, including failed ones
+	Requests int
+	// SamplesMs are the latencies of successful calls in milliseconds
+	SamplesMs []float64
+	// Errors breaks failed calls down by kind, most frequent first
+	Errors []LoadTestError
+	// RateLimited counts the 429 responses during the test, including
+	// those of attempts that were retried
+	RateLimited int
+	// Throttled is the total time workers waited for the rate limit
+	Throttled time.Duration
+}
+
+// Failed returns the number of failed calls
+func (r *LoadTestResult) Failed() int {
+	failed := 0
+	for _, e := range r.Errors {
+		failed += e.Count
+	}
+	return failed
+}
+
+// Throughput returns the completed calls per second
+func (r *LoadTestResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// BenchReport converts the result into a report for `bench compare`
+func (r *LoadTestResult) BenchReport(endpoint, serverVersion string) *BenchReport {
+	return &BenchReport{
+		Endpoint:      endpoint,
+		ServerVersion: serverVersion,
+		StartedAt:     r.StartedAt,
+		Operations: []BenchOperation{{
+			Name:      "tools/call " + r.Tool,
+			SamplesMs: r.SamplesMs,
+			Errors:    r.Failed(),
+		}},
+	}
+}
+
+// loadTestCollector gathers the outcomes reported by the workers
+type loadTestCollector struct {
+	mu        sync.Mutex
+	requests  int
+	samplesMs []float64
+	errors    map[string]*LoadTestError
+	throttled time.Duration
+}
+
+// wait records the time a worker waited for the rate limit
+func (c *loadTestCollector) wait(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.throttled += d
+}
+
+// record adds the outcome of one call
+func (c *loadTestCollector) record(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests++
+	if err == nil {
+		c.samplesMs = append(c.samplesMs, milliseconds(latency))
+		return
+	}
+
+	kind := "other"
+	if sentinel := ErrorKind(err); sentinel != nil {
+		kind = sentinel.Error()
+	}
+	if c.errors[kind] == nil {
+		c.errors[kind] = &LoadTestError{Kind: kind, Example: err.Error()}
+	}
+	c.errors[kind].Count++
+}
+
+// RunLoadTest calls a tool from cfg.Concurrency workers until cfg.Duration
+// has elapsed or cfg.MaxRequests calls were started. Workers are assigned to
+// the clients round-robin, so several workers may share one session.
+// Cancelling ctx stops the test early; calls aborted by the cancellation are
+// not counted.
+func RunLoadTest(ctx context.Context, clients []*Client, cfg LoadTestConfig) (*LoadTestResult, error) {
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no connections to run the load test on")
+	}
+	if cfg.Concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", cfg.Concurrency)
+	}
+	if cfg.Duration <= 0 && cfg.MaxRequests <= 0 {
+		return nil, fmt.Errorf("either a duration or a maximum number of requests is required")
+	}
+
+	collector := &loadTestCollector{errors: make(map[string]*LoadTestError)}
+	limitedBefore := rateLimitedResponses(clients)
+	started := time.Now()
+	var deadline time.Time
+	if cfg.Duration > 0 {
+		deadline = started.Add(cfg.Duration)
+	}
+
+	// issued hands out call tickets so MaxRequests is honored across workers
+	var issuedMu sync.Mutex
+	issued := 0
+	nextCall := func() bool {
+		if ctx.Err() != nil || (!deadline.IsZero() && !time.Now().Before(deadline)) {
+			return false
+		}
+		issuedMu.Lock()
+		defer issuedMu.Unlock()
+		if cfg.MaxRequests > 0 && issued >= cfg.MaxRequests {
+			return false
+		}
+		issued++
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < cfg.Concurrency; worker++ {
+		client := clients[worker%len(clients)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nextCall() {
+				if cfg.Throttle {
+					wait, err := client.WaitRateLimit(ctx)
+					if err != nil {
+						return
+					}
+					collector.wait(wait)
+				}
+				callStart := time.Now()
+				result, err := client.CallTool(ctx, cfg.Tool, cfg.Arguments)
+				latency := time.Since(callStart)
+				if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+					return
+				}
+				if err == nil {
+					err = ToolResultError(result)
+				}
+				collector.record(latency, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := &LoadTestResult{
+		Tool:        cfg.Tool,
+		Concurrency: cfg.Concurrency,
+		Connections: min(len(clients), cfg.Concurrency),
+		StartedAt:   started,
+		Elapsed:     time.Since(started),
+		Requests:    collector.requests,
+		SamplesMs:   collector.samplesMs,
+		RateLimited: rateLimitedResponses(clients) - limitedBefore,
+		Throttled:   collector.throttled,
+	}
+	for _, e := range collector.errors {
+		result.Errors = append(result.Errors, *e)
+	}
+	sort.Slice(result.Errors, func(i, j int) bool {
+		if result.Errors[i].Count != result.Errors[j].Count {
+			return result.Errors[i].Count > result.Errors[j].Count
+		}
+		return result.Errors[i].Kind < result.Errors[j].Kind
+	})
+	return result, nil
+}
+
+// rateLimitedResponses counts the 429 responses the clients received so
+// far; clients of a pool share their rate limit tracker
+func rateLimitedResponses(clients []*Client) int {
+	seen := make(map[*rateLimitTracker]bool)
+	limited := 0
+	for _, client := range clients {
+		if client.rateLimits == nil || seen[client.rateLimits] {
+			continue
+		}
+		seen[client.rateLimits] = true
+		limited += client.rateLimits.snapshot().Limited
+	}
+	return limited
+}
+
+// WriteLoadTestResult renders throughput, latency distribution and error
+// breakdown of a load test
+func WriteLoadTestResult(w io.Writer, r *LoadTestResult) {
+	summary := SummarizeLatencies(r.SamplesMs)
+
+	_, _ = fmt.Fprintf(w, "Tool:         %s\n", r.Tool)
+	_, _ = fmt.Fprintf(w, "Workers:      %d over %d connection(s)\n", r.Concurrency, r.Connections)
+	_, _ = fmt.Fprintf(w, "Elapsed:      %s\n", r.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "Requests:     %d (%d succeeded, %d failed)\n", r.Requests, summary.Count, r.Failed())
+	_, _ = fmt.Fprintf(w, "Throughput:   %.1f req/s\n", r.Throughput())
+	if r.RateLimited > 0 {
+		_, _ = fmt.Fprintf(w, "Rate limited: %d response(s) with status 429\n", r.RateLimited)
+	}
+	if r.Throttled > 0 {
+		_, _ = fmt.Fprintf(w, "Throttled:    %s waiting for the rate limit\n", r.Throttled.Round(time.Millisecond))
+	}
+
+	if summary.Count > 0 {
+		_, _ = fmt.Fprintln(w, "\nLatency of successful calls:")
+		_, _ = fmt.Fprintf(w, "  min   %10.2fms\n", slices.Min(r.SamplesMs))
+		_, _ = fmt.Fprintf(w, "  mean  %10.2fms (stddev %.2fms)\n", summary.Mean, summary.StdDev)
+		_, _ = fmt.Fprintf(w, "  p50   %10.2fms\n", summary.P50)
+		_, _ = fmt.Fprintf(w, "  p95   %10.2fms\n", summary.P95)
+		_, _ = fmt.Fprintf(w, "  p99   %10.2fms\n", summary.P99)
+		_, _ = fmt.Fprintf(w, "  max   %10.2fms\n", slices.Max(r.SamplesMs))
+	}
+
+	if len(r.Errors) > 0 {
+		_, _ = fmt.Fprintln(w, "\nErrors:")
+		for _, e := range r.Errors {
+			_, _ = fmt.Fprintf(w, "  %-20s %6d  e.g. %s\n", e.Kind, e.Count, e.Example)
+		}
+	}
+}
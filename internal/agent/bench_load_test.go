@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRunLoadTest(t *testing.T) {
+	var calls atomic.Int64
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		switch calls.Add(1) % 5 {
+		case 0:
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{mcp.NewTextContent("boom")}}, nil
+		case 1:
+			return nil, errors.New("unexpected")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}}
+	clients := []*Client{newStubbedClient(t, stub), newStubbedClient(t, stub)}
+
+	result, err := RunLoadTest(context.Background(), clients, LoadTestConfig{
+		Tool:        "echo",
+		Concurrency: 4,
+		Duration:    time.Minute,
+		MaxRequests: 50,
+	})
+	if err != nil {
+		t.Fatalf("RunLoadTest: %v", err)
+	}
+
+	if result.Requests != 50 || calls.Load() != 50 {
+		t.Errorf("requests = %d, calls = %d, want 50", result.Requests, calls.Load())
+	}
+	if len(result.SamplesMs) != 30 || result.Failed() != 20 {
+		t.Errorf("succeeded = %d, failed = %d, want 30 and 20", len(result.SamplesMs), result.Failed())
+	}
+	if len(result.Errors) != 2 {
+		t.Fatalf("errors = %+v, want two kinds", result.Errors)
+	}
+	for _, e := range result.Errors {
+		if e.Count != 10 || (e.Kind != ErrToolFailed.Error() && e.Kind != "other") {
+			t.Errorf("unexpected error breakdown entry: %+v", e)
+		}
+	}
+	if result.Connections != 2 {
+		t.Errorf("connections = %d, want 2", result.Connections)
+	}
+
+	report := result.BenchReport("http://localhost/mcp", "1.0")
+	if len(report.Operations) != 1 || report.Operations[0].Name != "tools/call echo" || report.Operations[0].Errors != 20 {
+		t.Errorf("unexpected report: %+v", report.Operations)
+	}
+
+	var out bytes.Buffer
+	WriteLoadTestResult(&out, result)
+	for _, want := range []string{"Requests:     50 (30 succeeded, 20 failed)", "p95", "tool failed", "boom"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunLoadTestDuration(t *testing.T) {
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		time.Sleep(time.Millisecond)
+		return mcp.NewToolResultText("ok"), nil
+	}}
+
+	result, err := RunLoadTest(context.Background(), []*Client{newStubbedClient(t, stub)}, LoadTestConfig{
+		Tool:        "echo",
+		Concurrency: 2,
+		Duration:    50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RunLoadTest: %v", err)
+	}
+	if result.Requests == 0 || result.Failed() != 0 {
+		t.Errorf("requests = %d, failed = %d", result.Requests, result.Failed())
+	}
+	if result.Throughput() <= 0 {
+		t.Errorf("throughput = %v", result.Throughput())
+	}
+}
+
+func TestRunLoadTestValidation(t *testing.T) {
+	client := newStubbedClient(t, &stubMCPClient{})
+	cases := []LoadTestConfig{
+		{Tool: "echo", Concurrency: 0, Duration: time.Second},
+		{Tool: "echo", Concurrency: 1},
+	}
+	for _, cfg := range cases {
+		if _, err := RunLoadTest(context.Background(), []*Client{client}, cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+	if _, err := RunLoadTest(context.Background(), nil, LoadTestConfig{Tool: "echo", Concurrency: 1, Duration: time.Second}); err == nil {
+		t.Error("expected error without connections")
+	}
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeLatencies(t *testing.T) {
+	s := SummarizeLatencies([]float64{5, 1, 3, 2, 4})
+	if s.Count != 5 || s.Mean != 3 || s.P50 != 3 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	if math.Abs(s.StdDev-math.Sqrt(2.5)) > 1e-9 {
+		t.Errorf("StdDev = %v", s.StdDev)
+	}
+	if s.P95 != 4.8 {
+		t.Errorf("P95 = %v, want 4.8", s.P95)
+	}
+
+	if empty := SummarizeLatencies(nil); empty.Count != 0 || empty.P50 != 0 {
+		t.Errorf("unexpected summary of empty sample: %+v", empty)
+	}
+}
+
+func TestCompareBenchReports(t *testing.T) {
+	jitter := func(base float64) []float64 {
+		samples := make([]float64, 50)
+		for i := range samples {
+			samples[i] = base + float64(i%5)
+		}
+		return samples
+	}
+
+	baseline := &BenchReport{Operations: []BenchOperation{
+		{Name: "tools/call slow", SamplesMs: jitter(100)},
+		{Name: "tools/call fast", SamplesMs: jitter(100)},
+		{Name: "tools/list", SamplesMs: jitter(10)},
+		{Name: "prompts/get", SamplesMs: jitter(5)},
+	}}
+	candidate := &BenchReport{Operations: []BenchOperation{
+		{Name: "tools/call slow", SamplesMs: jitter(150)},
+		{Name: "tools/call fast", SamplesMs: jitter(50)},
+		{Name: "tools/list", SamplesMs: jitter(10.1)},
+		{Name: "resources/read", SamplesMs: jitter(7)},
+	}}
+
+	deltas := CompareBenchReports(baseline, candidate, 0.05)
+	verdicts := make(map[string]BenchVerdict)
+	for _, d := range deltas {
+		verdicts[d.Name] = d.Verdict
+	}
+
+	want := map[string]BenchVerdict{
+		"tools/call slow": VerdictRegression,
+		"tools/call fast": VerdictImprovement,
+		"tools/list":      VerdictUnchanged,
+		"prompts/get":     VerdictRemoved,
+		"resources/read":  VerdictAdded,
+	}
+	for name, verdict := range want {
+		if verdicts[name] != verdict {
+			t.Errorf("%s: verdict = %q, want %q", name, verdicts[name], verdict)
+		}
+	}
+
+	var buf bytes.Buffer
+	WriteBenchComparison(&buf, deltas)
+	output := buf.String()
+	for _, substr := range []string{"| tools/call slow |", "+49.0%", "**regression**", "5 operations compared"} {
+		if !strings.Contains(output, substr) {
+			t.Errorf("expected output to contain %q:\n%s", substr, output)
+		}
+	}
+}
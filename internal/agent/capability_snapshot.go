@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrOffline is returned for requests that need a live server when the
+// client serves a capability snapshot
+var ErrOffline = errors.New("not available offline: the snapshot only holds the server's lists")
+
+// CapabilitySnapshot is the file of `snapshot capabilities` and `inspect`:
+// what a server announced and listed at one point in time
+type CapabilitySnapshot struct {
+	Endpoint     string                 `json:"endpoint"`
+	TakenAt      time.Time              `json:"takenAt"`
+	Capabilities mcp.ServerCapabilities `json:"capabilities"`
+	ServerInventory
+}
+
+// CapabilitySnapshot lists everything the connected server offers for
+// inspecting it offline later
+func (c *Client) CapabilitySnapshot(ctx context.Context) (*CapabilitySnapshot, error) {
+	inventory, err := c.Inventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &CapabilitySnapshot{
+		Endpoint:        c.currentEndpoint(),
+		TakenAt:         time.Now().UTC(),
+		ServerInventory: *inventory,
+	}
+	c.mu.RLock()
+	if c.serverCapabilities != nil {
+		snapshot.Capabilities = *c.serverCapabilities
+	}
+	c.mu.RUnlock()
+	return snapshot, nil
+}
+
+// SaveCapabilitySnapshot writes a capability snapshot file
+func SaveCapabilitySnapshot(path string, snapshot *CapabilitySnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode capability snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write capability snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadCapabilitySnapshot reads a capability snapshot file
+func LoadCapabilitySnapshot(path string) (*CapabilitySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capability snapshot: %w", err)
+	}
+	var snapshot CapabilitySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse capability snapshot %s: %w", path, err)
+	}
+	if snapshot.Server.Name == "" && snapshot.Server.ProtocolVersion == "" {
+		return nil, fmt.Errorf("%s is not a capability snapshot: it has no server", path)
+	}
+	return &snapshot, nil
+}
+
+// NewOfflineClient returns a client that serves the lists of a capability
+// snapshot instead of connecting, so the REPL can list and describe a
+// server without network access. Requests that need the server, such as
+// tool calls or resource reads, fail with ErrOffline.
+func NewOfflineClient(snapshot *CapabilitySnapshot, logger *Logger) *Client {
+	c := NewClient(ClientConfig{
+		Endpoint:      snapshot.Endpoint,
+		Transport:     "snapshot",
+		Logger:        logger,
+		NoInitialList: true,
+	})
+	c.offline = true
+	c.setMCPClient(&offlineMCPClient{snapshot: snapshot})
+
+	c.mu.Lock()
+	capabilities := snapshot.Capabilities
+	c.serverCapabilities = &capabilities
+	c.serverInfo = snapshot.Server
+	c.toolCache = append([]mcp.Tool{}, snapshot.Tools...)
+	c.resourceCache = append([]mcp.Resource{}, snapshot.Resources...)
+	c.templateCache = append([]mcp.ResourceTemplate{}, snapshot.Templates...)
+	c.promptCache = append([]mcp.Prompt{}, snapshot.Prompts...)
+	for _, cache := range []string{cacheTools, cacheResources, cachePrompts} {
+		c.markFetched(cache)
+	}
+	c.mu.Unlock()
+	return c
+}
+
+// offlineMCPClient answers list requests from a capability snapshot and
+// fails everything else with ErrOffline
+type offlineMCPClient struct {
+	snapshot *CapabilitySnapshot
+}
+
+func (o *offlineMCPClient) Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return nil, ErrOffline
+}
+
+func (o *offlineMCPClient) Ping(ctx context.Context) error {
+	return ErrOffline
+}
+
+func (o *offlineMCPClient) ListResourcesByPage(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	return &mcp.ListResourcesResult{Resources: o.snapshot.Resources}, nil
+}
+
+func (o *offlineMCPClient) ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	return o.ListResourcesByPage(ctx, request)
+}
+
+func (o *offlineMCPClient) ListResourceTemplatesByPage(ctx context.Context, request mcp.ListResourceTemplatesRequest) (*mcp.ListResourceTemplatesResult, error) {
+	return &mcp.ListResourceTemplatesResult{ResourceTemplates: o.snapshot.Templates}, nil
+}
+
+func (o *offlineMCPClient) ListResourceTemplates(ctx context.Context, request mcp.ListResourceTemplatesRequest) (*mcp.ListResourceTemplatesResult, error) {
+	return o.ListResourceTemplatesByPage(ctx, request)
+}
+
+func (o *offlineMCPClient) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	return nil, ErrOffline
+}
+
+func (o *offlineMCPClient) Subscribe(ctx context.Context, request mcp.SubscribeRequest) error {
+	return ErrOffline
+}
+
+func (o *offlineMCPClient) Unsubscribe(ctx context.Context, request mcp.UnsubscribeRequest) error {
+	return ErrOffline
+}
+
+func (o *offlineMCPClient) ListPromptsByPage(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	return &mcp.ListPromptsResult{Prompts: o.snapshot.Prompts}, nil
+}
+
+func (o *offlineMCPClient) ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	return o.ListPromptsByPage(ctx, request)
+}
+
+func (o *offlineMCPClient) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return nil, ErrOffline
+}
+
+func (o *offlineMCPClient) ListToolsByPage(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return &mcp.ListToolsResult{Tools: o.snapshot.Tools}, nil
+}
+
+func (o *offlineMCPClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	return o.ListToolsByPage(ctx, request)
+}
+
+func (o *offlineMCPClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return nil, ErrOffline
+}
+
+func (o *offlineMCPClient) SetLevel(ctx context.Context, request mcp.SetLevelRequest) error {
+	return ErrOffline
+}
+
+func (o *offlineMCPClient) Complete(ctx context.Context, request mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	return nil, ErrOffline
+}
+
+func (o *offlineMCPClient) Close() error {
+	return nil
+}
+
+func (o *offlineMCPClient) OnNotification(handler func(notification mcp.JSONRPCNotification)) {}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestCapabilitySnapshotOffline(t *testing.T) {
+	s := server.NewMCPServer("snapshotted", "1.0.0", server.WithInstructions("Use echo."))
+	s.AddTool(mcp.NewTool("echo", mcp.WithString("message", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(req.GetString("message", "")), nil
+		})
+	s.AddResource(mcp.NewResource("file:///config", "config"),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	live := NewClient(ClientConfig{
+		Endpoint:      ts.URL + "/mcp",
+		Transport:     "streamable-http",
+		Logger:        logger,
+		NoInitialList: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := live.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = live.Close() }()
+
+	snapshot, err := live.CapabilitySnapshot(ctx)
+	if err != nil {
+		t.Fatalf("CapabilitySnapshot: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := SaveCapabilitySnapshot(path, snapshot); err != nil {
+		t.Fatalf("SaveCapabilitySnapshot: %v", err)
+	}
+	loaded, err := LoadCapabilitySnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadCapabilitySnapshot: %v", err)
+	}
+
+	offline := NewOfflineClient(loaded, logger)
+	if info := offline.ServerInfo(); info.Name != "snapshotted" || info.Instructions != "Use echo." {
+		t.Errorf("unexpected server info %+v", info)
+	}
+	if !offline.ServerSupportsTools() || !offline.ServerSupportsResources() || offline.ServerSupportsPrompts() {
+		t.Error("offline client should announce the snapshot's capabilities")
+	}
+	tools, err := offline.ListCapabilities(ctx, "tools")
+	if err != nil {
+		t.Fatalf("ListCapabilities: %v", err)
+	}
+	if list := tools.([]mcp.Tool); len(list) != 1 || list[0].Name != "echo" || list[0].InputSchema.Required[0] != "message" {
+		t.Errorf("unexpected tools %+v", list)
+	}
+	if err := offline.RefreshCaches(ctx); err != nil {
+		t.Errorf("RefreshCaches should re-list from the snapshot: %v", err)
+	}
+	resources, _ := offline.ListCapabilities(ctx, "resources")
+	if list := resources.([]mcp.Resource); len(list) != 1 || list[0].URI != "file:///config" {
+		t.Errorf("unexpected resources %+v", list)
+	}
+
+	if _, err := offline.CallTool(ctx, "echo", map[string]any{"message": "hi"}); !errors.Is(err, ErrOffline) {
+		t.Errorf("CallTool error = %v, want ErrOffline", err)
+	}
+	if err := offline.Reconnect(ctx); !errors.Is(err, ErrOffline) {
+		t.Errorf("Reconnect error = %v, want ErrOffline", err)
+	}
+}
+
+func TestLoadCapabilitySnapshotRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suite.json")
+	if err := SaveSnapshotSuite(path, &SnapshotSuite{Cases: []SnapshotCase{{Tool: "echo"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCapabilitySnapshot(path); err == nil {
+		t.Error("expected a tool snapshot file to be rejected")
+	}
+}
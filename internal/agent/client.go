@@ -2,33 +2,184 @@ package agent
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // Client represents an MCP agent client
 type Client struct {
-	endpoint           string
-	transport          string
-	logger             *Logger
+	endpoint  string
+	transport string
+	logger    *Logger
+	// client is the mcp-go client of the current session, replaced by
+	// reconnects that may run on the keepalive goroutine; read it with
+	// mcpClient
 	client             client.MCPClient
+	clientMu           sync.RWMutex
 	toolCache          []mcp.Tool
 	resourceCache      []mcp.Resource
 	promptCache        []mcp.Prompt
+	templateCache      []mcp.ResourceTemplate
 	mu                 sync.RWMutex
 	notificationChan   chan mcp.JSONRPCNotification
 	serverCapabilities *mcp.ServerCapabilities
+	serverInfo         ServerInfo
 	oauthConfig        *OAuthConfig
 	version            string
 	resourceURI        string // RFC 8707 resource URI for OAuth flows
+
+	// oauthTokenStore holds the token of the current OAuth session,
+	// requestedScopes the scopes asked for when it was authorized and
+	// resourceScopes those advertised by the protected resource metadata
+	oauthTokenStore client.TokenStore
+	requestedScopes []string
+	resourceScopes  []string
+	// oauthHandler is mcp-go's OAuth handler of the current session, used to
+	// reach the authorization server's revocation and introspection endpoints
+	oauthHandler *transport.OAuthHandler
+	// oauthStepper pauses the OAuth flow before each request in step mode;
+	// nil otherwise
+	oauthStepper *oauthStepper
+	// oauthReadLine reads the pasted authorization code in manual code
+	// mode; nil reads standard input
+	oauthReadLine lineReader
+	// oauthSigner signs request objects and client assertions; loaded from
+	// --oauth-signing-key or generated on first use
+	oauthSigner *jwtSigner
+	// tokenLifecycle tracks the proactive refresh of the access token
+	tokenLifecycle tokenLifecycle
+	// authServerCandidates are the authorization servers of the protected
+	// resource in the order they are tried
+	authServerCandidates []string
+	// authServers records which of them were tried and why they failed
+	authServers authServerFallback
+	// registration is the dynamically registered client, kept for RFC 7592
+	// management
+	registration clientRegistrationStore
+
+	// notificationOverflow decides what happens when notificationChan is full
+	notificationOverflow NotificationOverflowPolicy
+	notificationCounters notificationCounters
+
+	// listChangedDebouncer coalesces bursts of list_changed notifications
+	listChangedDebouncer *listChangedDebouncer
+	// onListRefreshed is invoked after a list_changed notification has been
+	// turned into a refreshed cache (used by the REPL to rebuild completions)
+	onListRefreshed func(method string)
+
+	// announcedCapabilities are the client capability names sent in initialize
+	announcedCapabilities []string
+
+	// pingInterval enables client-initiated keepalive pings when > 0
+	pingInterval         time.Duration
+	pingFailureThreshold int
+	pingTracker          pingTracker
+	// uptime records when the connection and the current session were
+	// established, for the health report
+	uptime uptimeTracker
+
+	// cacheTTL is how long cached lists are trusted before being re-listed;
+	// zero means they only change on list_changed or manual refresh
+	cacheTTL       time.Duration
+	cacheFetchedAt cacheTimestamps
+	// cacheLoaded records which caches have been listed at least once
+	cacheLoaded map[string]bool
+	// noInitialList skips listing after initialize; caches are populated on
+	// first use instead
+	noInitialList bool
+	// offline serves a capability snapshot instead of connecting; see
+	// NewOfflineClient
+	offline bool
+
+	// requestMetaFields is attached as _meta to outgoing requests
+	requestMetaFields map[string]any
+
+	// requestLimiter bounds concurrent in-flight requests
+	requestLimiter *requestLimiter
+
+	// progress routes notifications/progress to streaming tool calls
+	progress progressRouter
+	// inflight tracks the tool calls that can be cancelled
+	inflight inflightCalls
+	// callTimeout is the default deadline of tool calls; zero means none
+	callTimeout time.Duration
+	// maxListPages bounds the pages followed by list requests
+	maxListPages int
+	// schemas validates tool arguments and structured results
+	schemas schemaValidator
+	// strictSchema fails tool calls whose arguments or results do not match
+	// the declared schemas instead of only warning
+	strictSchema bool
+	// history keeps the timeline of tool, resource and prompt lists
+	history *capabilityHistory
+
+	// resourceMemoryLimit is the decoded resource size above which
+	// DownloadResource streams to disk instead of memory; zero disables the
+	// cutoff
+	resourceMemoryLimit int64
+
+	// resourceVersions records the digest of each resource as last read by
+	// ReadResourceIfChanged, so repeated reads can report "unchanged"
+	resourceVersions map[string]ResourceVersion
+	// resourceETagMeta sends the etag of the previous read back in _meta
+	resourceETagMeta bool
+
+	// traffic records HTTP exchanges for export; nil disables recording
+	traffic *TrafficRecorder
+	// sessions tracks the session and event IDs to resume; nil when
+	// session resumption is off
+	sessions *sessionTracker
+
+	// latency collects per-method request statistics
+	latency latencyTracker
+	// exchanges keeps the latest requests and responses for correlation
+	exchanges exchangeHistory
+
+	// sampling answers sampling/createMessage requests; nil when sampling
+	// is off
+	sampling *samplingHandler
+	// elicitation answers elicitation/create requests; nil when elicitation
+	// is off
+	elicitation *elicitationHandler
+
+	// config is the configuration the client was created from, used to
+	// derive further connections with the same settings
+	config ClientConfig
+
+	// subscriptions tracks resource URIs subscribed to, so they can be
+	// re-established after a reconnect
+	subscriptions map[string]struct{}
+
+	// reconnectPolicy paces the attempts to re-establish a lost connection
+	reconnectPolicy reconnectPolicy
+
+	// retryPolicy paces the attempts of HTTP requests failing transiently;
+	// nil disables retries
+	retryPolicy *retryPolicy
+
+	// rateLimits records the rate limiting announced by the server
+	rateLimits *rateLimitTracker
+
+	// reconnectMu guards reconnecting, the automatic reconnect in progress
+	// that concurrent callers wait for instead of starting their own
+	reconnectMu  sync.Mutex
+	reconnecting *reconnectAttempt
+	// onReconnected is invoked after an automatic reconnect restored the
+	// session (used by the REPL to show a banner)
+	onReconnected func(ReconnectReport)
 }
 
 // ClientConfig holds configuration for creating a new Client
@@ -38,10 +189,170 @@ type ClientConfig struct {
 	Logger      *Logger
 	OAuthConfig *OAuthConfig
 	Version     string
+
+	// ProtocolVersion is the MCP protocol version requested in initialize
+	// (default: DefaultProtocolVersion). The server may answer with another
+	// version, which the session then uses if mcp-debug speaks it.
+	ProtocolVersion string
+
+	// ListChangedDebounce is how long list_changed notifications must be
+	// quiet before a burst of them re-lists once. Zero disables coalescing
+	// (every notification re-lists).
+	ListChangedDebounce time.Duration
+
+	// AnnouncedCapabilities lists the client capabilities to advertise during
+	// initialize (sampling, roots, elicitation, experimental:<name>).
+	// Empty announces no capabilities.
+	AnnouncedCapabilities []string
+
+	// PingInterval is the interval between client-initiated ping requests.
+	// Zero disables the keepalive.
+	PingInterval time.Duration
+
+	// PingFailureThreshold is the number of consecutive failed pings that
+	// trigger a reconnect (default: DefaultPingFailureThreshold)
+	PingFailureThreshold int
+
+	// ReconnectMaxAttempts bounds the attempts to re-establish a lost
+	// connection (default: DefaultReconnectMaxAttempts). Negative retries
+	// until the context ends.
+	ReconnectMaxAttempts int
+
+	// ReconnectInitialDelay is the wait after the first failed reconnect
+	// attempt, doubled after each further failure up to ReconnectMaxDelay
+	// (defaults: DefaultReconnectInitialDelay, DefaultReconnectMaxDelay)
+	ReconnectInitialDelay time.Duration
+	ReconnectMaxDelay     time.Duration
+
+	// HTTPRetryMaxAttempts bounds the attempts of an HTTP request failing
+	// with one of HTTPRetryStatuses, or of a GET request failing without a
+	// response (default: DefaultHTTPRetryMaxAttempts). One disables retries.
+	// MCP requests are retried whatever their method, OAuth requests only
+	// if idempotent, such as metadata fetches.
+	HTTPRetryMaxAttempts int
+
+	// HTTPRetryInitialDelay is the wait after the first failed attempt,
+	// doubled after each further failure up to HTTPRetryMaxDelay, unless
+	// the response has a Retry-After header (defaults:
+	// DefaultHTTPRetryInitialDelay, DefaultHTTPRetryMaxDelay)
+	HTTPRetryInitialDelay time.Duration
+	HTTPRetryMaxDelay     time.Duration
+
+	// HTTPRetryStatuses are the retried status codes (default:
+	// DefaultHTTPRetryStatuses)
+	HTTPRetryStatuses []int
+
+	// CacheTTL is the maximum age of the cached tool, resource and prompt
+	// lists before they are re-listed on access. Zero disables expiry.
+	CacheTTL time.Duration
+
+	// RequestMeta is attached as _meta to every outgoing request that
+	// supports it, for exercising vendor-specific extensions
+	RequestMeta map[string]any
+
+	// MaxInFlight limits the number of concurrent requests sent to the
+	// server; further requests wait in FIFO order. Zero means unlimited.
+	MaxInFlight int
+
+	// NotificationBufferSize is the capacity of the notification buffer
+	// (default: DefaultNotificationBufferSize)
+	NotificationBufferSize int
+
+	// NotificationOverflow is the policy applied when the notification
+	// buffer is full (default: OverflowBlock)
+	NotificationOverflow NotificationOverflowPolicy
+
+	// ResourceMemoryLimit is the decoded size in bytes above which
+	// DownloadResource streams a resource to a temporary file rather than
+	// keeping it in memory. Zero disables it.
+	ResourceMemoryLimit int64
+
+	// ResourceETagMeta sends the etag a resource read carried in _meta back
+	// as _meta.ifNoneMatch when the resource is read again with
+	// ReadResourceIfChanged. This is a convention of some servers, not part
+	// of MCP.
+	ResourceETagMeta bool
+
+	// NoInitialList skips listing tools, resources and prompts after
+	// initialize. Each list is fetched the first time it is needed.
+	NoInitialList bool
+
+	// Traffic, if set, records the HTTP exchanges with the server and the
+	// OAuth endpoints for export as HAR or OTLP spans
+	Traffic *TrafficRecorder
+
+	// Sampling configures how sampling/createMessage requests from the
+	// server are answered. The zero value leaves sampling off.
+	Sampling SamplingConfig
+
+	// Elicitation configures how elicitation/create requests from the
+	// server are answered. The zero value leaves elicitation off.
+	Elicitation ElicitationConfig
+
+	// CallTimeout is the deadline of each tool call unless the call's
+	// context sets its own with WithCallTimeout. Zero leaves tool calls
+	// bounded only by the caller's context.
+	CallTimeout time.Duration
+
+	// MaxListPages is the maximum number of pages followed when listing
+	// tools, resources, templates or prompts (default: DefaultMaxListPages)
+	MaxListPages int
+
+	// StrictSchema fails tool calls whose arguments or structured results
+	// do not match the tool's declared schemas with ErrSchemaViolation.
+	// Without it, mismatches are logged as warnings.
+	StrictSchema bool
+
+	// Headers and Cookies are added to every request to the MCP server,
+	// e.g. API keys or tenant headers required by a gateway. They are not
+	// sent to other hosts such as the authorization server.
+	Headers http.Header
+	Cookies []*http.Cookie
+
+	// ResumeSessions resumes the streamable-http session after a lost
+	// connection instead of starting a new one, sending Last-Event-ID so
+	// the server can replay the events missed in between
+	ResumeSessions bool
+
+	// SessionFile persists the session ID and last event ID, so a later run
+	// resumes the session too. Setting it implies ResumeSessions.
+	SessionFile string
+
+	// CapabilityHistoryFile is a JSON lines file the tool, resource and
+	// prompt list snapshots are appended to, so the history command also
+	// shows changes since earlier sessions with the same endpoint. Empty
+	// keeps the history in memory only.
+	CapabilityHistoryFile string
+
+	// TokenStore holds the OAuth token. Clients given the same store share
+	// one OAuth session, so only the first one authorizes. Nil keeps the
+	// token in memory for this client alone.
+	TokenStore client.TokenStore
 }
 
 // NewClient creates a new agent client from a configuration
 func NewClient(cfg ClientConfig) *Client {
+	bufferSize := cfg.NotificationBufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultNotificationBufferSize
+	}
+	overflow := cfg.NotificationOverflow
+	if overflow == "" {
+		overflow = OverflowBlock
+	}
+	maxListPages := cfg.MaxListPages
+	if maxListPages <= 0 {
+		maxListPages = DefaultMaxListPages
+	}
+	var sessions *sessionTracker
+	if cfg.ResumeSessions || cfg.SessionFile != "" {
+		sessions = newSessionTracker(cfg.Endpoint, cfg.SessionFile, cfg.Logger)
+	}
+	var stepper *oauthStepper
+	if cfg.OAuthConfig != nil && cfg.OAuthConfig.Enabled && cfg.OAuthConfig.StepMode {
+		stepper = newOAuthStepper()
+	}
+
 	return &Client{
 		endpoint:         cfg.Endpoint,
 		transport:        cfg.Transport,
@@ -49,49 +360,216 @@ func NewClient(cfg ClientConfig) *Client {
 		toolCache:        []mcp.Tool{},
 		resourceCache:    []mcp.Resource{},
 		promptCache:      []mcp.Prompt{},
-		notificationChan: make(chan mcp.JSONRPCNotification, 10),
+		templateCache:    []mcp.ResourceTemplate{},
+		notificationChan: make(chan mcp.JSONRPCNotification, bufferSize),
 		oauthConfig:      cfg.OAuthConfig,
+		oauthStepper:     stepper,
 		version:          cfg.Version,
+
+		notificationOverflow: overflow,
+
+		listChangedDebouncer:  newListChangedDebouncer(cfg.ListChangedDebounce),
+		announcedCapabilities: cfg.AnnouncedCapabilities,
+		pingInterval:          cfg.PingInterval,
+		pingFailureThreshold:  cfg.PingFailureThreshold,
+		cacheTTL:              cfg.CacheTTL,
+
+		requestMetaFields:   cfg.RequestMeta,
+		requestLimiter:      newRequestLimiter(cfg.MaxInFlight),
+		resourceMemoryLimit: cfg.ResourceMemoryLimit,
+		resourceETagMeta:    cfg.ResourceETagMeta,
+		noInitialList:       cfg.NoInitialList,
+		traffic:             cfg.Traffic,
+		sessions:            sessions,
+		callTimeout:         cfg.CallTimeout,
+		maxListPages:        maxListPages,
+		strictSchema:        cfg.StrictSchema,
+		history:             newCapabilityHistory(cfg.Endpoint, cfg.CapabilityHistoryFile),
+		reconnectPolicy:     newReconnectPolicy(cfg),
+		retryPolicy:         newRetryPolicy(cfg),
+		rateLimits:          newRateLimitTracker(cfg.Logger),
+		config:              cfg,
 	}
 }
 
 // Run executes the agent workflow
 func (c *Client) Run(ctx context.Context) error {
-	return c.connectAndInitialize(ctx)
+	if err := c.connect(ctx); err != nil {
+		return classifyError(err)
+	}
+	c.uptime.connected()
+
+	c.startKeepalive(ctx)
+	c.startTokenLifecycle(ctx)
+	return nil
+}
+
+// Close ends the session with the server, unless it is saved to a session
+// file to be resumed later. Background work started with the context passed
+// to Run stops when that context is cancelled.
+func (c *Client) Close() error {
+	c.listChangedDebouncer.stop()
+	c.stopResumedListener()
+	mcpClient := c.mcpClient()
+	if mcpClient == nil {
+		return nil
+	}
+	if c.config.SessionFile != "" {
+		c.closeKeepingSession()
+		return nil
+	}
+	return mcpClient.Close()
+}
+
+// mcpClient returns the mcp-go client of the current session
+func (c *Client) mcpClient() client.MCPClient {
+	c.clientMu.RLock()
+	defer c.clientMu.RUnlock()
+	return c.client
+}
+
+// setMCPClient makes mcpClient the client of the current session
+func (c *Client) setMCPClient(mcpClient client.MCPClient) {
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	c.client = mcpClient
 }
 
+// Reconnect closes the current connection and establishes a new session
 func (c *Client) Reconnect(ctx context.Context) error {
+	_, err := c.reconnect(ctx)
+	return err
+}
+
+// reconnect establishes a new session, re-lists and re-subscribes. It
+// returns the subscriptions that could not be re-established.
+func (c *Client) reconnect(ctx context.Context) ([]string, error) {
 	c.logger.Info("Attempting to reconnect to MCP server...")
-	if c.client != nil {
-		_ = c.client.Close() // Explicitly ignore close error during reconnect
+
+	// Remember what the server looked like so changes made during the outage
+	// can be reported once the fresh lists are in
+	before := c.snapshotSurface()
+
+	c.stopResumedListener()
+	if previous := c.mcpClient(); previous != nil && c.sessions != nil {
+		c.closeKeepingSession()
+	} else if previous != nil {
+		_ = previous.Close() // Explicitly ignore close error during reconnect
 	}
-	return c.connectAndInitialize(ctx)
+	if err := c.connect(ctx); err != nil {
+		return nil, classifyError(err)
+	}
+	return c.reconnected(ctx, before), nil
 }
 
-func (c *Client) connectAndInitialize(ctx context.Context) error {
-	c.logger.Info("Connecting to MCP server at %s using %s transport...", c.endpoint, c.transport)
+// reconnected re-lists and re-subscribes once a new session is established,
+// showing what changed since before. It returns the subscriptions that
+// could not be re-established.
+func (c *Client) reconnected(ctx context.Context, before surfaceSnapshot) []string {
+	c.uptime.reconnected()
+
+	if c.noInitialList {
+		// Nothing was re-listed; the diff is shown when each list is next used
+		c.InvalidateCaches()
+	} else {
+		c.showReconnectDiff(before)
+	}
+	failed := c.resubscribe(ctx)
+
+	if c.onListRefreshed != nil {
+		c.onListRefreshed("")
+	}
+	return failed
+}
+
+// connectSession connects to the server and initializes a new session, or
+// restores resume instead if it is not nil
+func (c *Client) connectSession(ctx context.Context, resume *sessionState) error {
+	c.logger.Info("Connecting to MCP server at %s using %s transport...", c.currentEndpoint(), c.transport)
+	if len(c.config.Headers) > 0 || len(c.config.Cookies) > 0 {
+		c.logger.Info("Sending %d custom header(s) and %d cookie(s)", len(c.config.Headers), len(c.config.Cookies))
+	}
 
 	var mcpClient *client.Client
 	var err error
 
-	// Handle OAuth authentication if enabled
-	if c.oauthConfig != nil && c.oauthConfig.Enabled {
+	var clientOptions []client.ClientOption
+	if c.sampling == nil {
+		if c.sampling, err = newSamplingHandler(c.config.Sampling, c.logger); err != nil {
+			return err
+		}
+	}
+	if c.sampling != nil {
+		// Registering the handler also announces the sampling capability
+		clientOptions = append(clientOptions, client.WithSamplingHandler(c.sampling))
+	}
+	if c.elicitation == nil {
+		c.elicitation = newElicitationHandler(c.config.Elicitation, c.logger)
+	}
+	if c.elicitation != nil {
+		clientOptions = append(clientOptions, client.WithElicitationHandler(c.elicitation))
+	}
+
+	// The transport logs through slog.Default otherwise, bypassing our
+	// output format; its messages are debug output here
+	httpOptions := []transport.StreamableHTTPCOption{
+		transport.WithHTTPLogger(slog.New(slog.NewTextHandler(c.logger, nil))),
+		transport.WithHTTPBasicClient(&http.Client{Transport: c.httpClients().mcpTransport()}),
+	}
+	if resume != nil {
+		httpOptions = append(httpOptions, transport.WithSession(resume.SessionID))
+		clientOptions = append(clientOptions, client.WithSession())
+	}
+	if c.sampling != nil || c.elicitation != nil {
+		// Some servers send their requests on the standalone GET stream
+		// rather than on the response stream of the request in flight
+		httpOptions = append(httpOptions, transport.WithContinuousListening())
+	}
+
+	// Handle OAuth authentication if enabled. StepUpScopes may swap the
+	// settings while a reconnect runs, so the connection uses one snapshot.
+	if configured := c.oauthSettings(); configured != nil && configured.Enabled {
 		// Apply defaults before validation
-		c.oauthConfig = c.oauthConfig.WithDefaults()
+		oauthConfig := configured.WithDefaults()
 
-		if err := c.oauthConfig.Validate(); err != nil {
+		if err := oauthConfig.Validate(); err != nil {
 			return fmt.Errorf("invalid OAuth configuration: %w", err)
 		}
 
 		c.logger.Info("OAuth authentication enabled")
 
+		// mTLS client authentication needs the certificate before anything
+		// is registered or exchanged
+		var clientCert *x509.Certificate
+		if isMTLSAuthMethod(oauthConfig.TokenEndpointAuthMethod) {
+			if clientCert = oauthClientCertificate(); clientCert == nil {
+				return fmt.Errorf("token endpoint authentication with %s requires a client certificate (--tls-cert and --tls-key)", oauthConfig.TokenEndpointAuthMethod)
+			}
+		}
+
+		// The redirect URL is registered and sent with the authorization
+		// request, so the callback port is picked before either
+		if oauthConfig.CallbackPortRange != "" && !oauthConfig.ManualCode && oauthConfig.Flow == OAuthFlowAuthorizationCode {
+			redirectURL, err := redirectURLWithFreePort(oauthConfig.RedirectURL, oauthConfig.CallbackPortRange)
+			if err != nil {
+				return err
+			}
+			oauthConfig.RedirectURL = redirectURL
+			c.logger.Info("Using OAuth callback %s", redirectURL)
+		}
+		// The authorization flows read the defaults and the callback port
+		c.swapOAuthSettings(configured, oauthConfig)
+
 		// Store discovered metadata for scope selection
 		var discoveredMetadata *ProtectedResourceMetadata
+		// authServerMetadataURL points mcp-go at the selected authorization
+		// server; empty leaves the discovery to mcp-go
+		var authServerMetadataURL string
 
 		// Attempt RFC 9728 Protected Resource Metadata discovery (proactive)
-		if !c.oauthConfig.SkipResourceMetadata {
+		if !oauthConfig.SkipResourceMetadata {
 			c.logger.Info("Attempting RFC 9728 Protected Resource Metadata discovery...")
-			metadata, err := discoverProtectedResourceMetadata(ctx, c.endpoint, nil, c.logger)
+			metadata, err := discoverProtectedResourceMetadata(withHTTPClients(ctx, c.httpClients()), c.currentEndpoint(), nil, c.logger)
 			if err != nil {
 				c.logger.Warning("Protected Resource Metadata discovery failed: %v", err)
 				c.logger.Info("Falling back to standard OAuth discovery (via mcp-go library)")
@@ -99,15 +577,23 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 				c.logger.Success("Protected Resource Metadata discovered")
 				discoveredMetadata = metadata
 
-				// Select authorization server
-				authServer, err := selectAuthorizationServer(metadata, c.oauthConfig.PreferredAuthServer)
-				if err != nil {
+				// Select authorization server, falling back to the next one
+				// listed when discovery fails
+				candidates, err := orderAuthServers(metadata, oauthConfig.PreferredAuthServer)
+				c.authServerCandidates = candidates
+				switch {
+				case err != nil:
 					c.logger.Warning("Failed to select authorization server: %v", err)
-				} else {
-					c.logger.Info("Using authorization server: %s", authServer)
-					// Note: mcp-go doesn't currently support setting a custom authorization server URL
-					// This would need to be added to the library for full RFC 9728 support
-					// For now, we log the discovery but rely on mcp-go's built-in discovery
+				case oauthConfig.SkipAuthServerDiscovery:
+					c.logger.Info("Using authorization server: %s", candidates[0])
+				default:
+					authServer, metadataURL, err := c.selectAuthServerWithFallback(withHTTPClients(ctx, c.httpClients()), candidates)
+					if err != nil {
+						c.logger.Warning("%v, relying on mcp-go's discovery", err)
+					} else {
+						c.logger.Info("Using authorization server: %s", authServer)
+						authServerMetadataURL = metadataURL
+					}
 				}
 
 				// Log discovered scopes for scope selection strategy
@@ -119,8 +605,12 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 			c.logger.Warning("RFC 9728 Protected Resource Metadata discovery disabled")
 		}
 
-		// Create token store for mcp-go
-		tokenStore := client.NewMemoryTokenStore()
+		// Create token store for mcp-go, unless the session is shared
+		tokenStore := c.config.TokenStore
+		if tokenStore == nil {
+			tokenStore = client.NewMemoryTokenStore()
+		}
+		c.oauthTokenStore = tokenStore
 
 		// Derive or use configured resource URI for RFC 8707
 		// Priority order:
@@ -128,22 +618,22 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		// 2. Resource from Protected Resource Metadata (RFC 9728)
 		// 3. Derived from endpoint URL
 		var resourceURI string
-		if c.oauthConfig.ResourceURI != "" {
-			resourceURI = c.oauthConfig.ResourceURI
+		if oauthConfig.ResourceURI != "" {
+			resourceURI = oauthConfig.ResourceURI
 			c.logger.Info("Using configured resource URI: %s", resourceURI)
 		} else if discoveredMetadata != nil && discoveredMetadata.Resource != "" {
 			resourceURI = discoveredMetadata.Resource
 			c.logger.Info("Using resource URI from Protected Resource Metadata: %s", resourceURI)
-		} else if !c.oauthConfig.SkipResourceParam {
+		} else if !oauthConfig.SkipResourceParam {
 			var err error
-			resourceURI, err = deriveResourceURI(c.endpoint)
+			resourceURI, err = deriveResourceURI(c.currentEndpoint())
 			if err != nil {
 				return fmt.Errorf("failed to derive resource URI: %w", err)
 			}
 			c.logger.Info("Derived resource URI from endpoint: %s", resourceURI)
 		}
 
-		if c.oauthConfig.SkipResourceParam {
+		if oauthConfig.SkipResourceParam {
 			c.logger.Warning("RFC 8707 resource parameter disabled - this weakens token security")
 		}
 
@@ -153,10 +643,15 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		// Select scopes using MCP spec priority order
 		// Note: WWW-Authenticate challenge is not available during proactive connection
 		// Priority 1 (challenge scopes) will be available during step-up authorization (future)
-		selectedScopes := selectScopes(c.oauthConfig, nil, discoveredMetadata, c.logger)
+		selectedScopes := selectScopes(oauthConfig, nil, discoveredMetadata, c.logger)
+		c.requestedScopes = selectedScopes
+		c.resourceScopes = nil
+		if discoveredMetadata != nil {
+			c.resourceScopes = discoveredMetadata.ScopesSupported
+		}
 
 		// Log scope selection for security audit
-		if c.oauthConfig.ScopeSelectionMode == ScopeModeManual {
+		if oauthConfig.ScopeSelectionMode == ScopeModeManual {
 			c.logger.Info("Scope selection mode: manual")
 			c.logger.Info("Requested scopes (manual): %v", selectedScopes)
 		} else {
@@ -177,12 +672,12 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		// Priority 2: Client ID Metadata Documents (CIMD)
 		// Priority 3: Dynamic Client Registration (handled by mcp-go if ClientID is empty)
 		// Priority 4: Manual configuration (handled by mcp-go error flow)
-		clientID := c.oauthConfig.ClientID
+		clientID := oauthConfig.ClientID
 
-		if clientID == "" && !c.oauthConfig.DisableCIMD {
+		if clientID == "" && !oauthConfig.DisableCIMD {
 			// Use CIMD (Client ID Metadata Documents) approach
 			// If an explicit CIMD URL is provided, use it; otherwise use the default
-			cimdURL := c.oauthConfig.ClientIDMetadataURL
+			cimdURL := oauthConfig.ClientIDMetadataURL
 			if cimdURL == "" {
 				// Use the default mcp-debug client metadata URL hosted on GitHub Pages
 				// The AS will fetch our client metadata from this URL
@@ -206,30 +701,52 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		// Create mcp-go OAuth config
 		mcpOAuthConfig := client.OAuthConfig{
 			ClientID:     clientID,
-			ClientSecret: c.oauthConfig.ClientSecret,
-			RedirectURI:  c.oauthConfig.RedirectURL,
+			ClientSecret: oauthConfig.ClientSecret,
+			RedirectURI:  oauthConfig.RedirectURL,
 			Scopes:       selectedScopes,
 			TokenStore:   tokenStore,
-			PKCEEnabled:  c.oauthConfig.UsePKCE,
+			PKCEEnabled:  oauthConfig.UsePKCE,
+
+			AuthServerMetadataURL: authServerMetadataURL,
+		}
+
+		// Build HTTP client with custom round trippers on top of the
+		// connection's OAuth transport, so token and registration requests
+		// reuse connections
+		clients := c.httpClients()
+		if c.oauthStepper != nil {
+			c.logger.Info("OAuth step mode enabled - each OAuth request is shown and needs confirmation")
 		}
+		transport := clients.oauthTransport()
+		transport = &registrationRecorder{base: transport, store: &c.registration, logger: c.logger}
 
-		// Build HTTP client with custom round trippers
-		transport := http.DefaultTransport
+		if clientCert != nil {
+			c.logger.Info("Authenticating at the token endpoint with %s (subject %s)", oauthConfig.TokenEndpointAuthMethod, clientCert.Subject)
+			transport = newMTLSClientAuthRoundTripper(oauthConfig.TokenEndpointAuthMethod, clientCert, transport, c.logger)
+		}
+		if oauthConfig.TokenEndpointAuthMethod == TokenAuthMethodPrivateKeyJWT {
+			signer, err := c.oauthSigningKey()
+			if err != nil {
+				return fmt.Errorf("failed to load the client assertion key: %w", err)
+			}
+			c.logger.Info("Authenticating at the token endpoint with %s (%s, kid %s)", TokenAuthMethodPrivateKeyJWT, signer.alg(), signer.kid)
+			transport = newClientAssertionRoundTripper(signer, transport, c.logger)
+		}
 
 		// Add registration token round tripper if needed
-		if c.oauthConfig.RegistrationToken != "" {
+		if oauthConfig.RegistrationToken != "" {
 			c.logger.Info("Registration access token provided for Dynamic Client Registration")
 			c.logger.Info("Security: Token will only be sent over HTTPS to prevent credential exposure")
-			transport = newRegistrationTokenRoundTripper(c.oauthConfig.RegistrationToken, transport, c.logger)
+			transport = newRegistrationTokenRoundTripper(oauthConfig.RegistrationToken, transport, c.logger)
 		}
 
 		// Add resource parameter round tripper (RFC 8707)
-		if !c.oauthConfig.SkipResourceParam && resourceURI != "" {
-			transport = newResourceRoundTripper(resourceURI, c.oauthConfig.SkipResourceParam, transport, c.logger)
+		if !oauthConfig.SkipResourceParam && resourceURI != "" {
+			transport = newResourceRoundTripper(resourceURI, oauthConfig.SkipResourceParam, transport, c.logger)
 		}
 
 		// Add step-up authorization round tripper for handling insufficient_scope errors
-		if c.oauthConfig.EnableStepUpAuth {
+		if oauthConfig.EnableStepUpAuth {
 			c.logger.Info("Step-up authorization enabled (will detect insufficient_scope errors)")
 
 			// Create reauthorization function that will be called when step-up is needed
@@ -246,19 +763,17 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 				return fmt.Errorf("step-up authorization required - please restart with scopes: %v", newScopes)
 			}
 
-			transport = newStepUpRoundTripper(c.oauthConfig, transport, c.logger, reauthorizeFunc)
+			transport = newStepUpRoundTripper(oauthConfig, transport, c.logger, reauthorizeFunc)
 		}
 
 		// Create HTTP client with all round trippers
-		if transport != http.DefaultTransport {
-			httpClient := &http.Client{
-				Transport: transport,
-			}
-			mcpOAuthConfig.HTTPClient = httpClient
+		mcpOAuthConfig.HTTPClient = &http.Client{
+			Timeout:   oauthRequestTimeout,
+			Transport: clients.cached(clients.retried(clients.record(transport), true)),
 		}
 
 		// Create OAuth client using mcp-go's native support
-		mcpClient, err = client.NewOAuthStreamableHttpClient(c.endpoint, mcpOAuthConfig)
+		mcpClient, err = newStreamableHTTPClient(c.currentEndpoint(), &mcpOAuthConfig, httpOptions, clientOptions, &c.exchanges)
 		if err != nil {
 			return fmt.Errorf("failed to create OAuth client: %w", err)
 		}
@@ -266,34 +781,63 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		c.logger.Info("Token refresh will be handled automatically by the OAuth client when tokens expire")
 	} else {
 		// Create regular non-OAuth client
-		mcpClient, err = client.NewStreamableHttpClient(c.endpoint)
+		mcpClient, err = newStreamableHTTPClient(c.currentEndpoint(), nil, httpOptions, clientOptions, &c.exchanges)
 		if err != nil {
 			return fmt.Errorf("failed to create streamable HTTP client: %w", err)
 		}
 	}
 
-	c.client = mcpClient
+	if httpTransport, ok := mcpClient.GetTransport().(*requestTrackingTransport); ok {
+		c.oauthHandler = httpTransport.GetOAuthHandler()
+	}
+	// Other goroutines, such as the keepalive or background calls, reach
+	// the new client only once its session is initialized; the failed one
+	// is handed over for retryWithNextAuthServer to close
+	failed := func(err error) error {
+		c.setMCPClient(mcpClient)
+		return c.retryWithNextAuthServer(ctx, resume, err)
+	}
 
 	// Start the transport with OAuth retry support
 	if err := c.executeWithOAuthRetry(ctx, "start client", func() error {
 		return mcpClient.Start(ctx)
 	}); err != nil {
-		return err
+		return failed(err)
 	}
 
 	// Set up notification handler
 	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
-		select {
-		case c.notificationChan <- notification:
-		case <-ctx.Done():
+		// Partial results for a streaming call are rendered by its handler
+		// as they arrive instead of queueing behind other notifications
+		if c.progress.dispatch(notification) {
+			return
 		}
+
+		c.enqueueNotification(ctx, notification)
 	})
 
-	// Initialize the session with OAuth retry support
-	if err := c.executeWithOAuthRetry(ctx, "initialization", func() error {
-		return c.initialize(ctx)
-	}); err != nil {
-		return err
+	if resume != nil {
+		// A resumed session is ready at once; restoring it pings through
+		// the new client
+		c.setMCPClient(mcpClient)
+		if err := c.executeWithOAuthRetry(ctx, "session resumption", func() error {
+			return c.restoreSession(ctx, resume)
+		}); err != nil {
+			return c.retryWithNextAuthServer(ctx, resume, err)
+		}
+	} else {
+		if err := c.executeWithOAuthRetry(ctx, "initialization", func() error {
+			// Initialize the session with OAuth retry support
+			return c.initialize(ctx, mcpClient)
+		}); err != nil {
+			return failed(err)
+		}
+		c.setMCPClient(mcpClient)
+	}
+
+	if c.noInitialList {
+		c.logger.Info("Skipping initial listing; tools, resources and prompts are listed on first use")
+		return nil
 	}
 
 	// List capabilities conditionally based on what the server supports
@@ -309,6 +853,7 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 		if err := c.listResources(ctx, true); err != nil {
 			return fmt.Errorf("initial resource listing failed: %w", err)
 		}
+		c.refreshResourceTemplates(ctx)
 	} else {
 		c.logger.Info("Server does not support resources capability")
 	}
@@ -324,6 +869,21 @@ func (c *Client) connectAndInitialize(ctx context.Context) error {
 	return nil
 }
 
+// newStreamableHTTPClient creates an mcp-go client over a streamable-http
+// transport, authenticating with oauthConfig unless it is nil. Unlike
+// client.NewStreamableHttpClient it accepts client options, which register
+// the handlers for server-to-client requests.
+func newStreamableHTTPClient(endpoint string, oauthConfig *client.OAuthConfig, httpOptions []transport.StreamableHTTPCOption, clientOptions []client.ClientOption, exchanges *exchangeHistory) (*client.Client, error) {
+	if oauthConfig != nil {
+		httpOptions = append(slices.Clone(httpOptions), transport.WithHTTPOAuth(*oauthConfig))
+	}
+	httpTransport, err := transport.NewStreamableHTTP(endpoint, httpOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewClient(&requestTrackingTransport{httpTransport, exchanges}, clientOptions...), nil
+}
+
 func (c *Client) Listen(ctx context.Context) error {
 	// Wait for notifications
 	c.logger.Info("Waiting for notifications (press Ctrl+C to exit)...")
@@ -331,6 +891,7 @@ func (c *Client) Listen(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
+			c.listChangedDebouncer.stop()
 			c.logger.Info("Shutting down...")
 			return nil
 
@@ -342,60 +903,75 @@ func (c *Client) Listen(ctx context.Context) error {
 	}
 }
 
-// initialize performs the MCP protocol handshake
-func (c *Client) initialize(ctx context.Context) error {
+// initialize performs the MCP protocol handshake over mcpClient, before
+// it becomes the client of the session
+func (c *Client) initialize(ctx context.Context, mcpClient client.MCPClient) error {
+	capabilities, err := c.clientCapabilities()
+	if err != nil {
+		return fmt.Errorf("invalid client capabilities: %w", err)
+	}
+	if !isEmptyClientCapabilities(capabilities) {
+		c.logger.Info("Announcing client capabilities: %s", describeClientCapabilities(capabilities))
+	}
+
 	req := mcp.InitializeRequest{
 		Params: struct {
 			ProtocolVersion string                 `json:"protocolVersion"`
 			Capabilities    mcp.ClientCapabilities `json:"capabilities"`
 			ClientInfo      mcp.Implementation     `json:"clientInfo"`
 		}{
-			ProtocolVersion: "2024-11-05",
+			ProtocolVersion: c.requestedProtocolVersion(),
 			ClientInfo: mcp.Implementation{
 				Name:    "mcp-debug-agent",
 				Version: "1.0.0",
 			},
-			Capabilities: mcp.ClientCapabilities{},
+			Capabilities: capabilities,
 		},
 	}
 
 	// Log request
-	c.logger.Request("initialize", req.Params)
+	ctx = c.withExchange(ctx, methodInitialize)
+	c.logRequest(ctx, methodInitialize, req.Params)
 
 	// Send request
-	result, err := c.client.Initialize(ctx, req)
+	var result *mcp.InitializeResult
+	err = c.timeRequest(methodInitialize, func() error {
+		var err error
+		result, err = mcpClient.Initialize(ctx, req)
+		return err
+	})
 	if err != nil {
+		err = explainVersionError(err)
 		c.logger.Error("Initialize failed: %v", err)
 		return err
 	}
 
 	// Log response
-	c.logger.Response("initialize", result)
+	c.logResponse(ctx, methodInitialize, result)
+	c.checkNegotiatedVersion(result.ProtocolVersion)
 
 	// Store server capabilities for conditional feature usage
 	c.mu.Lock()
 	c.serverCapabilities = &result.Capabilities
 	c.mu.Unlock()
 
+	c.storeServerInfo(result)
+	if c.sessions != nil {
+		c.sessions.initialized(result)
+	}
+
 	return nil
 }
 
 // listTools lists all available tools
 func (c *Client) listTools(ctx context.Context, initial bool) error {
-	req := mcp.ListToolsRequest{}
-
-	// Log request
-	c.logger.Request("tools/list", req.Params)
-
-	// Send request
-	result, err := c.client.ListTools(ctx, req)
+	// Follow the cursors so large servers are not truncated to one page
+	tools, err := collectPages(ctx, c, "tools/list", c.toolsPage)
 	if err != nil {
 		c.logger.Error("ListTools failed: %v", err)
 		return err
 	}
-
-	// Log response
-	c.logger.Response("tools/list", result)
+	c.recordHistory(historyTools, listItems(tools, func(t mcp.Tool) string { return t.Name }))
 
 	// Compare with cache if not initial
 	if !initial {
@@ -404,14 +980,16 @@ func (c *Client) listTools(ctx context.Context, initial bool) error {
 		c.mu.RUnlock()
 
 		c.mu.Lock()
-		c.toolCache = result.Tools
+		c.toolCache = tools
+		c.markFetched(cacheTools)
 		c.mu.Unlock()
 
 		// Show differences
-		c.showToolDiff(oldTools, result.Tools)
+		c.showToolDiff(oldTools, tools)
 	} else {
 		c.mu.Lock()
-		c.toolCache = result.Tools
+		c.toolCache = tools
+		c.markFetched(cacheTools)
 		c.mu.Unlock()
 	}
 
@@ -420,20 +998,13 @@ func (c *Client) listTools(ctx context.Context, initial bool) error {
 
 // listResources lists all available resources
 func (c *Client) listResources(ctx context.Context, initial bool) error {
-	req := mcp.ListResourcesRequest{}
-
-	// Log request
-	c.logger.Request("resources/list", req.Params)
-
-	// Send request
-	result, err := c.client.ListResources(ctx, req)
+	// Follow the cursors so large servers are not truncated to one page
+	resources, err := collectPages(ctx, c, "resources/list", c.resourcesPage)
 	if err != nil {
 		c.logger.Error("ListResources failed: %v", err)
 		return err
 	}
-
-	// Log response
-	c.logger.Response("resources/list", result)
+	c.recordHistory(historyResources, listItems(resources, func(r mcp.Resource) string { return r.URI }))
 
 	// Compare with cache if not initial
 	if !initial {
@@ -442,14 +1013,16 @@ func (c *Client) listResources(ctx context.Context, initial bool) error {
 		c.mu.RUnlock()
 
 		c.mu.Lock()
-		c.resourceCache = result.Resources
+		c.resourceCache = resources
+		c.markFetched(cacheResources)
 		c.mu.Unlock()
 
 		// Show differences
-		c.showResourceDiff(oldResources, result.Resources)
+		c.showResourceDiff(oldResources, resources)
 	} else {
 		c.mu.Lock()
-		c.resourceCache = result.Resources
+		c.resourceCache = resources
+		c.markFetched(cacheResources)
 		c.mu.Unlock()
 	}
 
@@ -458,20 +1031,13 @@ func (c *Client) listResources(ctx context.Context, initial bool) error {
 
 // listPrompts lists all available prompts
 func (c *Client) listPrompts(ctx context.Context, initial bool) error {
-	req := mcp.ListPromptsRequest{}
-
-	// Log request
-	c.logger.Request("prompts/list", req.Params)
-
-	// Send request
-	result, err := c.client.ListPrompts(ctx, req)
+	// Follow the cursors so large servers are not truncated to one page
+	prompts, err := collectPages(ctx, c, "prompts/list", c.promptsPage)
 	if err != nil {
 		c.logger.Error("ListPrompts failed: %v", err)
 		return err
 	}
-
-	// Log response
-	c.logger.Response("prompts/list", result)
+	c.recordHistory(historyPrompts, listItems(prompts, func(p mcp.Prompt) string { return p.Name }))
 
 	// Compare with cache if not initial
 	if !initial {
@@ -480,14 +1046,16 @@ func (c *Client) listPrompts(ctx context.Context, initial bool) error {
 		c.mu.RUnlock()
 
 		c.mu.Lock()
-		c.promptCache = result.Prompts
+		c.promptCache = prompts
+		c.markFetched(cachePrompts)
 		c.mu.Unlock()
 
 		// Show differences
-		c.showPromptDiff(oldPrompts, result.Prompts)
+		c.showPromptDiff(oldPrompts, prompts)
 	} else {
 		c.mu.Lock()
-		c.promptCache = result.Prompts
+		c.promptCache = prompts
+		c.markFetched(cachePrompts)
 		c.mu.Unlock()
 	}
 
@@ -497,24 +1065,34 @@ func (c *Client) listPrompts(ctx context.Context, initial bool) error {
 // handleNotification processes incoming notifications
 func (c *Client) handleNotification(ctx context.Context, notification mcp.JSONRPCNotification) error {
 	// Log the notification
-	c.logger.Notification(notification.Method, notification.Params)
+	c.logger.CorrelatedNotification(c.exchanges.notificationCorrelation(notification), notification.Method, notification.Params)
+	if len(notification.Params.Meta) > 0 {
+		c.logServerMeta(notification.Method, &mcp.Meta{AdditionalFields: notification.Params.Meta})
+	}
 
-	// Handle specific notifications only if the server supports the corresponding capability
 	switch notification.Method {
-	case notificationToolsListChanged:
-		if c.ServerSupportsTools() {
-			return c.listTools(ctx, false)
+	case notificationToolsListChanged,
+		notificationResourcesListChanged,
+		notificationPromptsListChanged:
+		if !c.listChangedDebouncer.enabled() {
+			return c.refreshList(ctx, notification.Method)
 		}
 
-	case notificationResourcesListChanged:
-		if c.ServerSupportsResources() {
-			return c.listResources(ctx, false)
-		}
+		method := notification.Method
+		c.listChangedDebouncer.trigger(method, func(coalesced int) {
+			if ctx.Err() != nil {
+				return
+			}
+			if coalesced > 0 {
+				c.logger.Info("Coalesced %d additional %s notification(s) into a single refresh", coalesced, method)
+			}
+			if err := c.refreshList(ctx, method); err != nil {
+				c.logger.Error("Failed to handle notification: %v", err)
+			}
+		})
 
-	case notificationPromptsListChanged:
-		if c.ServerSupportsPrompts() {
-			return c.listPrompts(ctx, false)
-		}
+	case notificationMessage:
+		c.handleLoggingMessage(notification)
 
 	default:
 		// Unknown notification type
@@ -523,6 +1101,52 @@ func (c *Client) handleNotification(ctx context.Context, notification mcp.JSONRP
 	return nil
 }
 
+// refreshList re-lists the capability named by a list_changed notification,
+// but only if the server supports the corresponding capability
+func (c *Client) refreshList(ctx context.Context, method string) error {
+	// A list that was never fetched has nothing to diff against; it is
+	// listed when first used
+	if cache := listChangedCache(method); cache != "" && c.awaitsFirstListing(cache) {
+		c.logger.Debug("Ignoring %s for %s not yet listed", method, cache)
+		return nil
+	}
+
+	var err error
+	switch method {
+	case notificationToolsListChanged:
+		if !c.ServerSupportsTools() {
+			return nil
+		}
+		err = c.listTools(ctx, false)
+	case notificationResourcesListChanged:
+		if !c.ServerSupportsResources() {
+			return nil
+		}
+		err = c.listResources(ctx, false)
+		if err == nil {
+			c.refreshResourceTemplates(ctx)
+		}
+	case notificationPromptsListChanged:
+		if !c.ServerSupportsPrompts() {
+			return nil
+		}
+		err = c.listPrompts(ctx, false)
+	default:
+		return nil
+	}
+
+	if err == nil && c.onListRefreshed != nil {
+		c.onListRefreshed(method)
+	}
+	return err
+}
+
+// SuppressedRefreshes returns the number of list_changed notifications that
+// were folded into an already pending refresh instead of triggering their own
+func (c *Client) SuppressedRefreshes() int64 {
+	return c.listChangedDebouncer.suppressedCount()
+}
+
 // showToolDiff displays the differences between old and new tool lists
 func (c *Client) showToolDiff(oldTools, newTools []mcp.Tool) {
 	// Create maps for easier comparison
@@ -540,13 +1164,19 @@ func (c *Client) showToolDiff(oldTools, newTools []mcp.Tool) {
 	var added []string
 	var removed []string
 	var unchanged []string
+	modified := make(map[string][]string)
 
-	// Find added and unchanged
-	for name := range newMap {
-		if _, exists := oldMap[name]; exists {
-			unchanged = append(unchanged, name)
-		} else {
+	// Find added, modified and unchanged
+	for name, tool := range newMap {
+		oldTool, exists := oldMap[name]
+		if !exists {
 			added = append(added, name)
+			continue
+		}
+		if changes := toolChanges(toolDefinition(oldTool), toolDefinition(tool)); len(changes) > 0 {
+			modified[name] = changes
+		} else {
+			unchanged = append(unchanged, name)
 		}
 	}
 
@@ -558,7 +1188,7 @@ func (c *Client) showToolDiff(oldTools, newTools []mcp.Tool) {
 	}
 
 	// Display changes
-	if len(added) > 0 || len(removed) > 0 {
+	if len(added) > 0 || len(removed) > 0 || len(modified) > 0 {
 		c.logger.Info("Tool changes detected:")
 		for _, name := range unchanged {
 			c.logger.Success("  ✓ Unchanged: %s", name)
@@ -566,6 +1196,12 @@ func (c *Client) showToolDiff(oldTools, newTools []mcp.Tool) {
 		for _, name := range added {
 			c.logger.Success("  + Added: %s", name)
 		}
+		for _, name := range slices.Sorted(maps.Keys(modified)) {
+			c.logger.Warning("  ~ Modified: %s", name)
+			for _, change := range modified[name] {
+				c.logger.Warning("      %s", change)
+			}
+		}
 		for _, name := range removed {
 			c.logger.Error("  - Removed: %s", name)
 		}
@@ -714,6 +1350,11 @@ func (c *Client) handleOAuthAuthorization(ctx context.Context, authErr error) er
 	if oauthHandler == nil {
 		return fmt.Errorf("no OAuth handler available in error")
 	}
+	ctx = withHTTPClients(ctx, c.httpClients())
+
+	if c.oauthSettings().Flow == OAuthFlowDevice {
+		return c.handleDeviceAuthorizationFlow(ctx, oauthHandler)
+	}
 
 	// Use our wrapper that provides better UX while leveraging mcp-go's OAuth handler
 	return c.handleMCPOAuthFlow(ctx, oauthHandler)
@@ -732,7 +1373,7 @@ func (c *Client) executeWithOAuthRetry(ctx context.Context, operation string, fn
 		c.logger.Info("This may indicate token expiration - automatic refresh or re-authorization will be attempted")
 
 		if authErr := c.handleOAuthAuthorization(ctx, err); authErr != nil {
-			return fmt.Errorf("OAuth authorization failed: %w", authErr)
+			return fmt.Errorf("%w: %w", errOAuthAuthorizationFailed, authErr)
 		}
 
 		c.logger.Success("OAuth token refreshed/renewed successfully")
@@ -747,29 +1388,8 @@ func (c *Client) executeWithOAuthRetry(ctx context.Context, operation string, fn
 	return fmt.Errorf("%s failed: %w", operation, err)
 }
 
+// shouldReconnect reports whether err indicates a lost connection that a
+// reconnect may recover from
 func shouldReconnect(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	// Check for context cancellation, which can happen on disconnect
-	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		return true
-	}
-
-	var netErr net.Error
-	if errors.As(err, &netErr) && netErr.Timeout() {
-		return true
-	}
-
-	errMsg := strings.ToLower(err.Error())
-	if strings.Contains(errMsg, "connection refused") ||
-		strings.Contains(errMsg, "connection reset by peer") ||
-		strings.Contains(errMsg, "transport is closing") ||
-		strings.Contains(errMsg, "broken pipe") ||
-		strings.Contains(errMsg, "unexpected eof") {
-		return true
-	}
-
-	return false
+	return errors.Is(classifyError(err), ErrTransportClosed)
 }
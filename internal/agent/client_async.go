@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolCallHandle is a tool call started with CallToolAsync; safe for
+// concurrent use
+type ToolCallHandle struct {
+	// Tool is the name of the called tool
+	Tool string
+	// Started is when the call was issued
+	Started time.Time
+
+	done     chan struct{}
+	result   *mcp.CallToolResult
+	err      error
+	finished time.Time
+}
+
+// CallToolAsync starts a tool call like CallToolStreaming and returns a
+// handle without waiting for the result, so several calls can overlap on the
+// same session. The calls share the --max-in-flight limit and are listed by
+// InFlightRequests until they complete; cancelling ctx aborts the call.
+func (c *Client) CallToolAsync(ctx context.Context, name string, args map[string]interface{}, onProgress ProgressHandler) *ToolCallHandle {
+	call := &ToolCallHandle{Tool: name, Started: time.Now(), done: make(chan struct{})}
+	go func() {
+		defer close(call.done)
+		call.result, call.err = c.CallToolStreaming(ctx, name, args, onProgress)
+		call.finished = time.Now()
+	}()
+	return call
+}
+
+// Done returns a channel that is closed once the call has completed
+func (call *ToolCallHandle) Done() <-chan struct{} {
+	return call.done
+}
+
+// Finished reports whether the call has completed
+func (call *ToolCallHandle) Finished() bool {
+	select {
+	case <-call.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait waits for the call to complete and returns its result. It returns
+// ctx's error if ctx is done first; the call keeps running in that case.
+func (call *ToolCallHandle) Wait(ctx context.Context) (*mcp.CallToolResult, error) {
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Elapsed returns how long the call took, or has been running so far
+func (call *ToolCallHandle) Elapsed() time.Duration {
+	if call.Finished() {
+		return call.finished.Sub(call.Started)
+	}
+	return time.Since(call.Started)
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newOverlappingStub returns a stub whose tool calls only complete once n
+// calls are in progress at the same time
+func newOverlappingStub(n int) *stubMCPClient {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	return &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		wg.Done()
+		wg.Wait()
+		if req.Params.Name == "fail" {
+			return nil, errors.New("tool exploded")
+		}
+		return mcp.NewToolResultText("result of " + req.Params.Name), nil
+	}}
+}
+
+func TestCallToolAsyncOverlaps(t *testing.T) {
+	c := newStubbedClient(t, newOverlappingStub(2))
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	first := c.CallToolAsync(ctx, "first", nil, nil)
+	second := c.CallToolAsync(ctx, "second", nil, nil)
+	if first.Tool != "first" || first.Started.IsZero() {
+		t.Errorf("unexpected handle %+v", first)
+	}
+
+	for _, call := range []*ToolCallHandle{second, first} {
+		result, err := call.Wait(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", call.Tool, err)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; text != "result of "+call.Tool {
+			t.Errorf("%s returned %q", call.Tool, text)
+		}
+		if !call.Finished() {
+			t.Errorf("%s: expected the call to be finished", call.Tool)
+		}
+	}
+}
+
+func TestCallToolAsyncWaitContext(t *testing.T) {
+	release := make(chan struct{})
+	c := newStubbedClient(t, &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-release
+		return mcp.NewToolResultText("ok"), nil
+	}})
+	call := c.CallToolAsync(context.Background(), "slow", nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := call.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+	if call.Finished() {
+		t.Error("expected the call to keep running")
+	}
+
+	close(release)
+	<-call.Done()
+	if _, err := call.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// OAuthEnabled reports whether the client authenticates with OAuth
+func (c *Client) OAuthEnabled() bool {
+	oauthConfig := c.oauthSettings()
+	return oauthConfig != nil && oauthConfig.Enabled
+}
+
+// oauthSettings returns the OAuth configuration. It is replaced by modified
+// copies rather than changed in place, so the returned settings stay
+// consistent while a connection attempt uses them.
+func (c *Client) oauthSettings() *OAuthConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.oauthConfig
+}
+
+// swapOAuthSettings replaces the OAuth configuration with updated unless it
+// was replaced since previous was read
+func (c *Client) swapOAuthSettings(previous, updated *OAuthConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.oauthConfig == previous {
+		c.oauthConfig = updated
+	}
+}
+
+// TokenScopes returns the scopes of the current OAuth access token. If the
+// authorization server did not report the granted scopes in its token
+// response, the scopes that were requested are returned and granted is false.
+func (c *Client) TokenScopes(ctx context.Context) (scopes []string, granted bool, err error) {
+	if !c.OAuthEnabled() {
+		return nil, false, errors.New("OAuth is not enabled")
+	}
+	if c.oauthTokenStore == nil {
+		return nil, false, errors.New("no OAuth session established")
+	}
+
+	token, err := c.oauthTokenStore.GetToken(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("no access token available: %w", err)
+	}
+	if token.Scope != "" {
+		return strings.Fields(token.Scope), true, nil
+	}
+	return slices.Clone(c.requestedScopes), false, nil
+}
+
+// StepUpScopes manually performs step-up authorization: it re-authorizes with
+// the current scopes plus the given ones and reconnects with the new token.
+// This is the same escalation the step-up round tripper performs reactively
+// on insufficient_scope responses. On failure the previous scopes are
+// restored. It returns the scopes requested for the new token.
+func (c *Client) StepUpScopes(ctx context.Context, scopes []string) ([]string, error) {
+	current, _, err := c.TokenScopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// SECURITY: Apply the same checks as server-initiated step-up
+	if err := validateRequestedScopes(scopes); err != nil {
+		return nil, fmt.Errorf("scope validation failed: %w", err)
+	}
+
+	merged := slices.Clone(current)
+	for _, scope := range scopes {
+		if !slices.Contains(merged, scope) {
+			merged = append(merged, scope)
+		}
+	}
+	if len(merged) == len(current) {
+		c.logger.Info("Requested scopes are already part of the current token")
+		return current, nil
+	}
+
+	c.logger.Info("AUDIT: Manual step-up authorization requested")
+	c.logger.Info("AUDIT: Requested additional scopes: %v", scopes)
+
+	// Request exactly the merged scopes; auto mode would re-select them from
+	// the server metadata and drop the additions
+	c.mu.Lock()
+	previous := c.oauthConfig
+	stepUp := *previous
+	stepUp.Scopes = merged
+	stepUp.ScopeSelectionMode = ScopeModeManual
+	c.oauthConfig = &stepUp
+	c.mu.Unlock()
+
+	if err := c.Reconnect(ctx); err != nil {
+		c.mu.Lock()
+		c.oauthConfig = previous
+		c.mu.Unlock()
+		return nil, newStepUpError(fmt.Errorf("step-up re-authorization failed: %w", err))
+	}
+
+	c.logger.Success("Additional permissions granted")
+	return merged, nil
+}
+
+// ScopeReport compares the scopes advertised, requested and granted for the
+// OAuth session
+type ScopeReport struct {
+	// Resource are the scopes_supported of the protected resource metadata
+	Resource []string
+	// AuthServer are the scopes_supported of the authorization server
+	// metadata; AuthServerErr is set if the metadata was unavailable
+	AuthServer    []string
+	AuthServerErr error
+	// Requested are the scopes asked for in the authorization request
+	Requested []string
+	// Granted are the scopes of the token response; GrantedReported is
+	// false if the authorization server did not include them
+	Granted         []string
+	GrantedReported bool
+	// Mismatches describe where the lists disagree
+	Mismatches []string
+}
+
+// ScopeReport gathers the scopes advertised by the resource and the
+// authorization server, the requested scopes and the granted ones
+func (c *Client) ScopeReport(ctx context.Context) (*ScopeReport, error) {
+	if !c.OAuthEnabled() {
+		return nil, errors.New("OAuth is not enabled")
+	}
+	if c.oauthTokenStore == nil {
+		return nil, errors.New("no OAuth session established")
+	}
+
+	report := &ScopeReport{
+		Resource:  slices.Clone(c.resourceScopes),
+		Requested: slices.Clone(c.requestedScopes),
+	}
+	if c.oauthHandler != nil {
+		metadata, err := c.oauthHandler.GetServerMetadata(ctx)
+		if err != nil {
+			report.AuthServerErr = err
+		} else {
+			report.AuthServer = metadata.ScopesSupported
+		}
+	} else {
+		report.AuthServerErr = errors.New("no OAuth handler")
+	}
+	if token, err := c.oauthTokenStore.GetToken(ctx); err == nil && token.Scope != "" {
+		report.Granted = strings.Fields(token.Scope)
+		report.GrantedReported = true
+	}
+	report.Mismatches = scopeMismatches(report)
+	return report, nil
+}
+
+// scopeMismatches lists the scopes that one side has and another lacks.
+// Lists that were not advertised or reported are not compared.
+func scopeMismatches(report *ScopeReport) []string {
+	var mismatches []string
+	compare := func(scopes, against []string, format string) {
+		if len(against) == 0 {
+			return
+		}
+		if missing := scopesMissing(scopes, against); len(missing) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf(format, formatScopeList(missing)))
+		}
+	}
+	compare(report.Resource, report.AuthServer, "Advertised by the resource but not by the authorization server: %s")
+	compare(report.Requested, report.Resource, "Requested but not advertised by the resource: %s")
+	compare(report.Requested, report.AuthServer, "Requested but not advertised by the authorization server: %s")
+	if report.GrantedReported {
+		compare(report.Requested, report.Granted, "Requested but not granted: %s")
+		if missing := scopesMissing(report.Granted, report.Requested); len(missing) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf("Granted but not requested: %s", formatScopeList(missing)))
+		}
+	}
+	return mismatches
+}
+
+// scopesMissing returns the scopes that are not in against
+func scopesMissing(scopes, against []string) []string {
+	var missing []string
+	for _, scope := range scopes {
+		if !slices.Contains(against, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
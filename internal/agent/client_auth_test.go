@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+)
+
+func newOAuthStubbedClient(t *testing.T, scope string) *Client {
+	t.Helper()
+
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.oauthConfig = &OAuthConfig{Enabled: true}
+	c.requestedScopes = []string{"read"}
+	c.oauthTokenStore = client.NewMemoryTokenStore()
+	if err := c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "t", Scope: scope}); err != nil {
+		t.Fatalf("SaveToken failed: %v", err)
+	}
+	return c
+}
+
+func TestTokenScopes(t *testing.T) {
+	t.Run("granted scopes from the token response", func(t *testing.T) {
+		c := newOAuthStubbedClient(t, "read write")
+		scopes, granted, err := c.TokenScopes(t.Context())
+		if err != nil || !granted || !slices.Equal(scopes, []string{"read", "write"}) {
+			t.Errorf("got scopes=%v granted=%v err=%v", scopes, granted, err)
+		}
+	})
+
+	t.Run("falls back to requested scopes", func(t *testing.T) {
+		c := newOAuthStubbedClient(t, "")
+		scopes, granted, err := c.TokenScopes(t.Context())
+		if err != nil || granted || !slices.Equal(scopes, []string{"read"}) {
+			t.Errorf("got scopes=%v granted=%v err=%v", scopes, granted, err)
+		}
+	})
+
+	t.Run("requires OAuth", func(t *testing.T) {
+		c := newStubbedClient(t, &stubMCPClient{})
+		if _, _, err := c.TokenScopes(t.Context()); err == nil {
+			t.Error("expected error without OAuth")
+		}
+	})
+}
+
+func TestStepUpScopes(t *testing.T) {
+	t.Run("already granted scopes do not re-authorize", func(t *testing.T) {
+		c := newOAuthStubbedClient(t, "read write")
+		scopes, err := c.StepUpScopes(t.Context(), []string{"write"})
+		if err != nil || !slices.Equal(scopes, []string{"read", "write"}) {
+			t.Errorf("got scopes=%v err=%v", scopes, err)
+		}
+		if c.oauthConfig.ScopeSelectionMode == ScopeModeManual {
+			t.Error("expected configuration to be left untouched")
+		}
+	})
+
+	t.Run("failed step-up restores the settings", func(t *testing.T) {
+		c := newOAuthStubbedClient(t, "read")
+		c.endpoint = "http://127.0.0.1:1/mcp"
+		previous := c.oauthSettings()
+
+		// Keepalive and reconnects read the settings concurrently
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range 100 {
+				_ = c.OAuthEnabled()
+			}
+		}()
+		if _, err := c.StepUpScopes(t.Context(), []string{"write"}); err == nil {
+			t.Error("expected the reconnect to fail")
+		}
+		<-done
+		if c.oauthSettings() != previous {
+			t.Error("expected the previous settings to be restored")
+		}
+	})
+
+	t.Run("suspicious scopes are rejected", func(t *testing.T) {
+		c := newOAuthStubbedClient(t, "read")
+		if _, err := c.StepUpScopes(t.Context(), []string{"admin\x00"}); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+}
+
+func TestScopeReport(t *testing.T) {
+	c := newOAuthStubbedClient(t, "read admin")
+	c.resourceScopes = []string{"read", "write"}
+	c.requestedScopes = []string{"read", "write"}
+
+	report, err := c.ScopeReport(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.GrantedReported || !slices.Equal(report.Granted, []string{"read", "admin"}) || report.AuthServerErr == nil {
+		t.Errorf("unexpected report %+v", report)
+	}
+	want := []string{"Requested but not granted: write", "Granted but not requested: admin"}
+	if !slices.Equal(report.Mismatches, want) {
+		t.Errorf("got mismatches %q, want %q", report.Mismatches, want)
+	}
+}
+
+func TestScopeMismatches(t *testing.T) {
+	report := &ScopeReport{
+		Resource:   []string{"read", "write"},
+		AuthServer: []string{"read", "openid"},
+		Requested:  []string{"read", "write", "extra"},
+	}
+	want := []string{
+		"Advertised by the resource but not by the authorization server: write",
+		"Requested but not advertised by the resource: extra",
+		"Requested but not advertised by the authorization server: write, extra",
+	}
+	if got := scopeMismatches(report); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := scopeMismatches(&ScopeReport{Requested: []string{"read"}}); len(got) != 0 {
+		t.Errorf("expected lists that were not advertised to be skipped, got %q", got)
+	}
+}
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Cache names used by RefreshCache and the REPL refresh command
+const (
+	cacheTools     = "tools"
+	cacheResources = "resources"
+	cachePrompts   = "prompts"
+)
+
+// cacheTimestamps records when each capability list was last fetched.
+// A zero time means the cache is invalid and must be re-listed.
+type cacheTimestamps struct {
+	tools     time.Time
+	resources time.Time
+	prompts   time.Time
+}
+
+// markFetched records that the named cache was just populated.
+// Callers must hold c.mu.
+func (c *Client) markFetched(cache string) {
+	if c.cacheLoaded == nil {
+		c.cacheLoaded = make(map[string]bool)
+	}
+	c.cacheLoaded[cache] = true
+
+	now := time.Now()
+	switch cache {
+	case cacheTools:
+		c.cacheFetchedAt.tools = now
+	case cacheResources:
+		c.cacheFetchedAt.resources = now
+	case cachePrompts:
+		c.cacheFetchedAt.prompts = now
+	}
+}
+
+// InvalidateCaches marks the tool, resource and prompt caches as stale so the
+// next access re-lists them from the server
+func (c *Client) InvalidateCaches() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cacheFetchedAt = cacheTimestamps{}
+}
+
+// CacheAge returns how long ago the named cache was fetched, and false if the
+// cache is invalid or the name is unknown
+func (c *Client) CacheAge(cache string) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var fetchedAt time.Time
+	switch cache {
+	case cacheTools:
+		fetchedAt = c.cacheFetchedAt.tools
+	case cacheResources:
+		fetchedAt = c.cacheFetchedAt.resources
+	case cachePrompts:
+		fetchedAt = c.cacheFetchedAt.prompts
+	}
+	if fetchedAt.IsZero() {
+		return 0, false
+	}
+	return time.Since(fetchedAt), true
+}
+
+// isCacheStale reports whether the named cache has been invalidated or has
+// outlived the configured TTL
+func (c *Client) isCacheStale(cache string) bool {
+	age, valid := c.CacheAge(cache)
+	if !valid {
+		return true
+	}
+	return c.cacheTTL > 0 && age > c.cacheTTL
+}
+
+// awaitsFirstListing reports whether the named cache was skipped by
+// --no-initial-list and has not been listed since. Unlike the fetch
+// timestamps this survives invalidation, so a lazily populated cache is not
+// reported as entirely "added".
+func (c *Client) awaitsFirstListing(cache string) bool {
+	if !c.noInitialList {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.cacheLoaded[cache]
+}
+
+// listChangedCache maps a list_changed notification to the cache it affects
+func listChangedCache(method string) string {
+	switch method {
+	case notificationToolsListChanged:
+		return cacheTools
+	case notificationResourcesListChanged:
+		return cacheResources
+	case notificationPromptsListChanged:
+		return cachePrompts
+	}
+	return ""
+}
+
+// RefreshCache forces a re-list of the named cache ("tools", "resources" or
+// "prompts"), showing any differences against the previous contents. A cache
+// that has never been listed is populated without a diff.
+func (c *Client) RefreshCache(ctx context.Context, cache string) error {
+	switch cache {
+	case cacheTools, cacheResources, cachePrompts:
+	default:
+		return fmt.Errorf("unknown cache: %s (must be %s, %s or %s)", cache, cacheTools, cacheResources, cachePrompts)
+	}
+	if !c.supportsCache(cache) {
+		return nil
+	}
+
+	initial := c.awaitsFirstListing(cache)
+	if initial {
+		c.logger.Info("Listing %s on first use", cache)
+	}
+
+	switch cache {
+	case cacheTools:
+		return classifyError(c.listTools(ctx, initial))
+	case cacheResources:
+		if err := c.listResources(ctx, initial); err != nil {
+			return classifyError(err)
+		}
+		c.refreshResourceTemplates(ctx)
+		return nil
+	default:
+		return classifyError(c.listPrompts(ctx, initial))
+	}
+}
+
+// RefreshCaches forces a re-list of every cache the server supports
+func (c *Client) RefreshCaches(ctx context.Context) error {
+	for _, cache := range []string{cacheTools, cacheResources, cachePrompts} {
+		if err := c.RefreshCache(ctx, cache); err != nil {
+			return fmt.Errorf("failed to refresh %s: %w", cache, err)
+		}
+	}
+	return nil
+}
+
+// refreshStaleCaches re-lists the named caches (all of them if none are
+// given) that were invalidated, have outlived the TTL or were never listed.
+// Servers that never send list_changed would otherwise leave stale data in
+// place until the next reconnect. It reports whether any cache was re-listed.
+func (c *Client) refreshStaleCaches(ctx context.Context, caches ...string) (bool, error) {
+	if len(caches) == 0 {
+		caches = []string{cacheTools, cacheResources, cachePrompts}
+	}
+
+	refreshed := false
+	for _, cache := range caches {
+		if !c.isCacheStale(cache) || !c.supportsCache(cache) {
+			continue
+		}
+		c.logger.Debug("Cache for %s is stale, re-listing", cache)
+		if err := c.RefreshCache(ctx, cache); err != nil {
+			return refreshed, fmt.Errorf("failed to refresh %s: %w", cache, err)
+		}
+		refreshed = true
+	}
+	return refreshed, nil
+}
+
+// supportsCache reports whether the server advertised the capability backing
+// the named cache
+func (c *Client) supportsCache(cache string) bool {
+	switch cache {
+	case cacheTools:
+		return c.ServerSupportsTools()
+	case cacheResources:
+		return c.ServerSupportsResources()
+	case cachePrompts:
+		return c.ServerSupportsPrompts()
+	}
+	return false
+}
+
+// ListKinds lists the capability lists ListCapabilities returns
+var ListKinds = []string{cacheTools, cacheResources, "templates", cachePrompts}
+
+// ListCapabilities returns the server's tools, resources, resource templates
+// or prompts, listing them first if the cache is stale or was never filled.
+// The list is empty, not nil, if the server lacks the capability.
+func (c *Client) ListCapabilities(ctx context.Context, kind string) (any, error) {
+	cache := kind
+	if kind == "templates" {
+		// Templates are listed with the resources
+		cache = cacheResources
+	}
+	switch cache {
+	case cacheTools, cacheResources, cachePrompts:
+	default:
+		return nil, fmt.Errorf("unknown list: %s (must be one of: %s)", kind, strings.Join(ListKinds, ", "))
+	}
+	if _, err := c.refreshStaleCaches(ctx, cache); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch kind {
+	case cacheTools:
+		return append([]mcp.Tool{}, c.toolCache...), nil
+	case cacheResources:
+		return append([]mcp.Resource{}, c.resourceCache...), nil
+	case "templates":
+		return append([]mcp.ResourceTemplate{}, c.templateCache...), nil
+	default:
+		return append([]mcp.Prompt{}, c.promptCache...), nil
+	}
+}
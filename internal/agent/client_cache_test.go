@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newToolsCacheClient(t *testing.T, stub *stubMCPClient, ttl time.Duration) *Client {
+	t.Helper()
+
+	c := newStubbedClient(t, stub)
+	c.cacheTTL = ttl
+	c.serverCapabilities = &mcp.ServerCapabilities{}
+	c.serverCapabilities.Tools = &struct {
+		ListChanged bool `json:"listChanged,omitempty"`
+	}{}
+
+	if err := c.listTools(t.Context(), true); err != nil {
+		t.Fatalf("initial listTools failed: %v", err)
+	}
+	return c
+}
+
+func TestRefreshStaleCaches(t *testing.T) {
+	t.Run("fresh cache is not re-listed", func(t *testing.T) {
+		stub := &stubMCPClient{tools: []mcp.Tool{{Name: "a"}}}
+		c := newToolsCacheClient(t, stub, time.Hour)
+
+		refreshed, err := c.refreshStaleCaches(t.Context())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if refreshed || stub.listToolsCalls != 1 {
+			t.Errorf("expected no re-list, got refreshed=%v calls=%d", refreshed, stub.listToolsCalls)
+		}
+	})
+
+	t.Run("expired cache is re-listed", func(t *testing.T) {
+		stub := &stubMCPClient{tools: []mcp.Tool{{Name: "a"}}}
+		c := newToolsCacheClient(t, stub, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		stub.tools = []mcp.Tool{{Name: "a"}, {Name: "b"}}
+		refreshed, err := c.refreshStaleCaches(t.Context())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !refreshed || stub.listToolsCalls != 2 {
+			t.Errorf("expected re-list, got refreshed=%v calls=%d", refreshed, stub.listToolsCalls)
+		}
+		if len(c.toolCache) != 2 {
+			t.Errorf("expected 2 cached tools, got %d", len(c.toolCache))
+		}
+	})
+
+	t.Run("zero TTL never expires", func(t *testing.T) {
+		stub := &stubMCPClient{}
+		c := newToolsCacheClient(t, stub, 0)
+		time.Sleep(time.Millisecond)
+
+		if refreshed, _ := c.refreshStaleCaches(t.Context()); refreshed {
+			t.Error("expected no re-list with TTL disabled")
+		}
+	})
+
+	t.Run("invalidated cache is re-listed", func(t *testing.T) {
+		stub := &stubMCPClient{}
+		c := newToolsCacheClient(t, stub, 0)
+
+		c.InvalidateCaches()
+		if _, valid := c.CacheAge(cacheTools); valid {
+			t.Error("expected cache to be invalid after InvalidateCaches")
+		}
+		if refreshed, _ := c.refreshStaleCaches(t.Context()); !refreshed {
+			t.Error("expected re-list after invalidation")
+		}
+		if _, valid := c.CacheAge(cacheTools); !valid {
+			t.Error("expected cache to be valid after re-list")
+		}
+	})
+
+	t.Run("unsupported capabilities are skipped", func(t *testing.T) {
+		stub := &stubMCPClient{}
+		c := newToolsCacheClient(t, stub, 0)
+
+		// Resources and prompts were never fetched but are not advertised
+		if refreshed, err := c.refreshStaleCaches(t.Context()); refreshed || err != nil {
+			t.Errorf("expected nothing to refresh, got refreshed=%v err=%v", refreshed, err)
+		}
+	})
+}
+
+func TestRefreshCacheUnknownName(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	if err := c.RefreshCache(t.Context(), "widgets"); err == nil {
+		t.Error("expected error for unknown cache name")
+	}
+}
+
+func TestLazyInitialListing(t *testing.T) {
+	stub := &stubMCPClient{tools: []mcp.Tool{{Name: "a"}}}
+	c := newStubbedClient(t, stub)
+	c.noInitialList = true
+	c.serverCapabilities = &mcp.ServerCapabilities{}
+	c.serverCapabilities.Tools = &struct {
+		ListChanged bool `json:"listChanged,omitempty"`
+	}{}
+
+	// list_changed for a list never fetched has nothing to diff against
+	if err := c.refreshList(t.Context(), notificationToolsListChanged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stub.listToolsCalls != 0 {
+		t.Errorf("expected no listing before first use, got %d calls", stub.listToolsCalls)
+	}
+
+	// Refreshing another cache must not list tools
+	if _, err := c.refreshStaleCaches(t.Context(), cachePrompts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stub.listToolsCalls != 0 {
+		t.Errorf("expected tools to stay unlisted, got %d calls", stub.listToolsCalls)
+	}
+
+	refreshed, err := c.refreshStaleCaches(t.Context(), cacheTools)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !refreshed || stub.listToolsCalls != 1 || len(c.toolCache) != 1 {
+		t.Errorf("expected first use to list tools, got refreshed=%v calls=%d cached=%d", refreshed, stub.listToolsCalls, len(c.toolCache))
+	}
+	if c.awaitsFirstListing(cacheTools) {
+		t.Error("expected tools to be marked as listed")
+	}
+
+	// Once listed, invalidation leads to a regular diffing re-list
+	c.InvalidateCaches()
+	if c.awaitsFirstListing(cacheTools) {
+		t.Error("expected invalidation to keep the listed marker")
+	}
+}
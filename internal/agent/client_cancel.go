@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// errRequestCancelled is the cause of a request cancelled with CancelRequest
+var errRequestCancelled = errors.New("request cancelled")
+
+// InFlightRequest describes a request that is still awaiting its response
+type InFlightRequest struct {
+	// ID is the JSON-RPC id of the request, empty until it has been sent
+	ID string
+	// Method is the MCP method, e.g. tools/call
+	Method string
+	// Target names what the request operates on, e.g. the tool name
+	Target string
+	// Started is when the request was issued
+	Started time.Time
+	// Progress is the latest progress update, if any was received
+	Progress *ToolProgress
+}
+
+// inflightCall tracks one cancellable request
+type inflightCall struct {
+	method  string
+	target  string
+	started time.Time
+	cancel  context.CancelCauseFunc
+
+	mu       sync.Mutex
+	id       mcp.RequestId
+	progress *ToolProgress
+}
+
+// setID records the JSON-RPC id; retries after a reconnect get a new one
+func (call *inflightCall) setID(id mcp.RequestId) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	call.id = id
+}
+
+// requestID returns the JSON-RPC id of the request
+func (call *inflightCall) requestID() mcp.RequestId {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	return call.id
+}
+
+// setProgress records the latest progress update
+func (call *inflightCall) setProgress(update ToolProgress) {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	call.progress = &update
+}
+
+// snapshot returns the public view of the call
+func (call *inflightCall) snapshot() InFlightRequest {
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	req := InFlightRequest{
+		Method:   call.method,
+		Target:   call.target,
+		Started:  call.started,
+		Progress: call.progress,
+	}
+	if !call.id.IsNil() {
+		req.ID = fmt.Sprint(call.id.Value())
+	}
+	return req
+}
+
+// inflightCalls is the set of cancellable requests of a client; safe for
+// concurrent use
+type inflightCalls struct {
+	mu    sync.Mutex
+	calls map[*inflightCall]struct{}
+}
+
+// requestIDRecorderKey is the context key of the function receiving the
+// JSON-RPC id of the request sent with that context
+type requestIDRecorderKey struct{}
+
+// start registers a request and returns the context to send it with. The
+// context is cancelled by CancelRequest and reports the request id to the
+// call; done must be called once the request has completed.
+func (f *inflightCalls) start(ctx context.Context, method, target string) (context.Context, *inflightCall, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	call := &inflightCall{method: method, target: target, started: time.Now(), cancel: cancel}
+	ctx = context.WithValue(ctx, requestIDRecorderKey{}, call.setID)
+
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[*inflightCall]struct{})
+	}
+	f.calls[call] = struct{}{}
+	f.mu.Unlock()
+
+	return ctx, call, func() {
+		f.mu.Lock()
+		delete(f.calls, call)
+		f.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// find returns the call with the given JSON-RPC id
+func (f *inflightCalls) find(id string) *inflightCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for call := range f.calls {
+		if call.snapshot().ID == id {
+			return call
+		}
+	}
+	return nil
+}
+
+// list returns the calls in the order they were started
+func (f *inflightCalls) list() []InFlightRequest {
+	f.mu.Lock()
+	requests := make([]InFlightRequest, 0, len(f.calls))
+	for call := range f.calls {
+		requests = append(requests, call.snapshot())
+	}
+	f.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Started.Before(requests[j].Started)
+	})
+	return requests
+}
+
+// InFlightRequests lists the tool calls still awaiting their response
+func (c *Client) InFlightRequests() []InFlightRequest {
+	return c.inflight.list()
+}
+
+// CancelRequest aborts the in-flight request with the given JSON-RPC id: the
+// server is sent notifications/cancelled with reason, and the pending call
+// returns immediately instead of waiting for a response that may never come.
+func (c *Client) CancelRequest(ctx context.Context, id, reason string) error {
+	call := c.inflight.find(id)
+	if call == nil {
+		return fmt.Errorf("no request in flight with id %s", id)
+	}
+	// The response is unused from now on, even if the server never learns
+	// about the cancellation. Cancelling first also keeps the server's reply
+	// to the cancellation from being returned as the call's result.
+	call.cancel(errRequestCancelled)
+	return c.notifyCancelled(ctx, call.requestID(), reason)
+}
+
+// notifyCancelled tells the server that the response to a request is no
+// longer wanted
+func (c *Client) notifyCancelled(ctx context.Context, requestID mcp.RequestId, reason string) error {
+	if requestID.IsNil() {
+		return fmt.Errorf("request has not been sent yet")
+	}
+	corr := c.exchanges.requestCorrelation(fmt.Sprint(requestID.Value()))
+	c.logger.CorrelatedRequest(corr, string(mcp.MethodNotificationCancelled), mcp.CancelledNotificationParams{RequestId: requestID, Reason: reason})
+
+	sender, ok := c.mcpClient().(interface{ GetTransport() transport.Interface })
+	if !ok {
+		return fmt.Errorf("transport does not support sending notifications")
+	}
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: string(mcp.MethodNotificationCancelled),
+			Params: mcp.NotificationParams{AdditionalFields: map[string]any{
+				"requestId": requestID.Value(),
+				"reason":    reason,
+			}},
+		},
+	}
+	if err := sender.GetTransport().SendNotification(ctx, notification); err != nil {
+		return fmt.Errorf("failed to send cancellation: %w", classifyError(err))
+	}
+	return nil
+}
+
+// requestTrackingTransport reports the JSON-RPC id of each request to the
+// in-flight call it belongs to, since mcp-go allocates ids internally, and
+// records the requests and responses for correlation
+type requestTrackingTransport struct {
+	*transport.StreamableHTTP
+	exchanges *exchangeHistory
+}
+
+// SendRequest implements transport.Interface
+func (t *requestTrackingTransport) SendRequest(ctx context.Context, request transport.JSONRPCRequest) (*transport.JSONRPCResponse, error) {
+	if setID, ok := ctx.Value(requestIDRecorderKey{}).(func(mcp.RequestId)); ok {
+		setID(request.ID)
+	}
+	if t.exchanges == nil {
+		return t.StreamableHTTP.SendRequest(ctx, request)
+	}
+	ex := t.exchanges.sent(ctx, request)
+	response, err := t.StreamableHTTP.SendRequest(ctx, request)
+	t.exchanges.received(ex, response, err)
+	return response, err
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestFormatToolProgress(t *testing.T) {
+	for _, tc := range []struct {
+		update ToolProgress
+		want   string
+	}{
+		{ToolProgress{Progress: 3}, "progress 3"},
+		{ToolProgress{Progress: 3, Message: "chunk"}, "chunk"},
+		{ToolProgress{Progress: 5, Total: 10, Message: "halfway"}, "[" + strings.Repeat("#", 15) + strings.Repeat("-", 15) + "]  50% (5/10) halfway"},
+		{ToolProgress{Progress: 12, Total: 10}, "[" + strings.Repeat("#", 30) + "] 100% (12/10)"},
+	} {
+		if got := formatToolProgress(tc.update); got != tc.want {
+			t.Errorf("formatToolProgress(%+v) = %q, want %q", tc.update, got, tc.want)
+		}
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	serverCancelled := make(chan struct{})
+	mcpServer := server.NewMCPServer("cancel-test", "1.0.0", server.WithToolCapabilities(false))
+	mcpServer.AddTool(mcp.NewTool("slow"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		_ = mcpServer.SendNotificationToClient(ctx, string(mcp.MethodNotificationProgress), map[string]any{
+			"progressToken": req.Params.Meta.ProgressToken,
+			"progress":      1,
+			"total":         4,
+		})
+		select {
+		case <-ctx.Done():
+			close(serverCancelled)
+			return nil, ctx.Err()
+		case <-time.After(testTimeoutLong):
+			return mcp.NewToolResultText("finished"), nil
+		}
+	})
+	ts := server.NewTestStreamableHTTPServer(mcpServer)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := client.CancelRequest(ctx, "1", ""); err == nil {
+		t.Error("expected error cancelling an unknown request")
+	}
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := client.CallToolStreaming(ctx, "slow", nil, func(ToolProgress) {})
+		callErr <- err
+	}()
+
+	var inflight InFlightRequest
+	for inflight.Progress == nil {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("call never reported progress: %+v", client.InFlightRequests())
+		case <-time.After(10 * time.Millisecond):
+		}
+		if requests := client.InFlightRequests(); len(requests) == 1 {
+			inflight = requests[0]
+		}
+	}
+	if inflight.ID == "" || inflight.Method != "tools/call" || inflight.Target != "slow" || *inflight.Progress != (ToolProgress{Progress: 1, Total: 4}) {
+		t.Errorf("in-flight request = %+v", inflight)
+	}
+
+	if err := client.CancelRequest(ctx, inflight.ID, "test"); err != nil {
+		t.Fatalf("CancelRequest: %v", err)
+	}
+	if err := <-callErr; !errors.Is(err, errRequestCancelled) {
+		t.Errorf("call error = %v, want request cancelled", err)
+	}
+	select {
+	case <-serverCancelled:
+	case <-ctx.Done():
+		t.Fatal("server did not see the cancellation")
+	}
+	if requests := client.InFlightRequests(); len(requests) != 0 {
+		t.Errorf("requests still in flight: %+v", requests)
+	}
+}
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Client capability names accepted by --announce-capabilities
+const (
+	capabilitySampling    = "sampling"
+	capabilityRoots       = "roots"
+	capabilityElicitation = "elicitation"
+
+	// capabilityExperimentalPrefix announces an experimental capability,
+	// e.g. "experimental:my-feature" or, with settings,
+	// `experimental:my-feature={"level":2}`
+	capabilityExperimentalPrefix = "experimental:"
+)
+
+// SupportedClientCapabilities lists the capability names that can be announced
+// during initialize, in the order they are documented.
+var SupportedClientCapabilities = []string{
+	capabilitySampling,
+	capabilityRoots,
+	capabilityElicitation,
+	capabilityExperimentalPrefix + "<name>[=<json-object>]",
+}
+
+// buildClientCapabilities translates a list of capability names into the
+// ClientCapabilities announced in the initialize request.
+//
+// Announcing a capability only changes what the server is told; mcp-debug does
+// not necessarily answer the corresponding server-to-client requests.
+func buildClientCapabilities(names []string) (mcp.ClientCapabilities, error) {
+	caps := mcp.ClientCapabilities{}
+
+	for _, raw := range names {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" {
+			continue
+		}
+
+		switch {
+		case name == capabilitySampling:
+			caps.Sampling = &mcp.SamplingCapability{}
+		case name == capabilityRoots:
+			caps.Roots = &struct {
+				ListChanged bool `json:"listChanged,omitempty"`
+			}{ListChanged: true}
+		case name == capabilityElicitation:
+			caps.Elicitation = &mcp.ElicitationCapability{}
+		case strings.HasPrefix(name, capabilityExperimentalPrefix):
+			// Keep the original casing of experimental capability names
+			expName, settings, err := parseExperimentalCapability(strings.TrimSpace(raw)[len(capabilityExperimentalPrefix):])
+			if err != nil {
+				return mcp.ClientCapabilities{}, err
+			}
+			if caps.Experimental == nil {
+				caps.Experimental = make(map[string]any)
+			}
+			caps.Experimental[expName] = settings
+		default:
+			return mcp.ClientCapabilities{}, fmt.Errorf("unknown client capability: %s (supported: %s)", raw, strings.Join(SupportedClientCapabilities, ", "))
+		}
+	}
+
+	return caps, nil
+}
+
+// parseExperimentalCapability parses the name[=json-object] part of an
+// experimental capability. Without a value the capability is announced with
+// an empty settings object.
+func parseExperimentalCapability(entry string) (string, map[string]any, error) {
+	name, value, _ := strings.Cut(entry, "=")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("experimental capability requires a name (e.g. %sfeature)", capabilityExperimentalPrefix)
+	}
+
+	settings := map[string]any{}
+	if strings.TrimSpace(value) != "" {
+		if err := json.Unmarshal([]byte(value), &settings); err != nil {
+			return "", nil, fmt.Errorf("experimental capability %s: settings must be a JSON object: %w", name, err)
+		}
+	}
+	return name, settings, nil
+}
+
+// clientCapabilityName identifies the capability an entry announces: the
+// lower-case name of a standard capability, or experimental:<name> without
+// the settings
+func clientCapabilityName(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(strings.ToLower(entry), capabilityExperimentalPrefix) {
+		name, _, _ := strings.Cut(entry[len(capabilityExperimentalPrefix):], "=")
+		return capabilityExperimentalPrefix + strings.TrimSpace(name)
+	}
+	return strings.ToLower(entry)
+}
+
+// SplitClientCapabilities splits comma-separated capability lists into
+// names. Commas inside the JSON settings of an experimental capability do not
+// separate entries.
+func SplitClientCapabilities(values []string) []string {
+	var names []string
+	add := func(name string) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	for _, value := range values {
+		depth, start := 0, 0
+		inString, escaped := false, false
+		for i := 0; i < len(value); i++ {
+			switch ch := value[i]; {
+			case escaped:
+				escaped = false
+			case inString:
+				if ch == '\\' {
+					escaped = true
+				} else if ch == '"' {
+					inString = false
+				}
+			case ch == '"':
+				inString = true
+			case ch == '{' || ch == '[':
+				depth++
+			case ch == '}' || ch == ']':
+				depth--
+			case ch == ',' && depth <= 0:
+				add(value[start:i])
+				start = i + 1
+			}
+		}
+		add(value[start:])
+	}
+	return names
+}
+
+// ValidateClientCapabilities checks that all capability names are recognised
+func ValidateClientCapabilities(names []string) error {
+	_, err := buildClientCapabilities(names)
+	return err
+}
+
+// describeClientCapabilities returns a short human-readable summary of the
+// capabilities being announced
+func describeClientCapabilities(caps mcp.ClientCapabilities) string {
+	var names []string
+	if caps.Sampling != nil {
+		names = append(names, capabilitySampling)
+	}
+	if caps.Roots != nil {
+		names = append(names, capabilityRoots)
+	}
+	if caps.Elicitation != nil {
+		names = append(names, capabilityElicitation)
+	}
+
+	var experimental []string
+	for name := range caps.Experimental {
+		experimental = append(experimental, capabilityExperimentalPrefix+name)
+	}
+	sort.Strings(experimental)
+	names = append(names, experimental...)
+
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
+
+// isEmptyClientCapabilities reports whether no capability is announced
+func isEmptyClientCapabilities(caps mcp.ClientCapabilities) bool {
+	return caps.Sampling == nil && caps.Roots == nil && caps.Elicitation == nil && len(caps.Experimental) == 0
+}
+
+// clientCapabilities returns the capabilities announced in initialize: the
+// configured names, and sampling and elicitation if mcp-debug answers those
+// requests
+func (c *Client) clientCapabilities() (mcp.ClientCapabilities, error) {
+	caps, err := buildClientCapabilities(c.AnnouncedCapabilities())
+	if err != nil {
+		return mcp.ClientCapabilities{}, err
+	}
+	if c.sampling != nil {
+		caps.Sampling = &mcp.SamplingCapability{}
+	}
+	if c.elicitation != nil {
+		caps.Elicitation = &mcp.ElicitationCapability{}
+	}
+	return caps, nil
+}
+
+// AnnouncedCapabilities returns the capability names announced in
+// initialize, as given to --announce-capabilities or
+// SetAnnouncedCapabilities
+func (c *Client) AnnouncedCapabilities() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.announcedCapabilities)
+}
+
+// SetAnnouncedCapabilities replaces the announced capability names and
+// starts a new session announcing them, since capabilities are fixed for the
+// lifetime of a session. Sampling and elicitation stay announced while
+// mcp-debug answers those requests.
+func (c *Client) SetAnnouncedCapabilities(ctx context.Context, names []string) error {
+	if err := ValidateClientCapabilities(names); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.announcedCapabilities = slices.Clone(names)
+	c.mu.Unlock()
+
+	// A resumed session would keep the capabilities it was initialized with
+	if previous := c.mcpClient(); c.sessions != nil && previous != nil {
+		c.stopResumedListener()
+		_ = previous.Close()
+		c.sessions.forget()
+	}
+	return c.Reconnect(ctx)
+}
+
+// ClientCapabilityStatus describes one announced client capability
+type ClientCapabilityStatus struct {
+	Name string
+	// Settings are the settings announced for an experimental capability
+	Settings map[string]any
+	// Answered is set if mcp-debug answers the server's requests for the
+	// capability; otherwise they fail with an error
+	Answered bool
+}
+
+// ClientCapabilityStatuses lists the capabilities announced in initialize
+func (c *Client) ClientCapabilityStatuses() ([]ClientCapabilityStatus, error) {
+	caps, err := c.clientCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []ClientCapabilityStatus
+	if caps.Sampling != nil {
+		statuses = append(statuses, ClientCapabilityStatus{Name: capabilitySampling, Answered: c.sampling != nil})
+	}
+	if caps.Roots != nil {
+		statuses = append(statuses, ClientCapabilityStatus{Name: capabilityRoots})
+	}
+	if caps.Elicitation != nil {
+		statuses = append(statuses, ClientCapabilityStatus{Name: capabilityElicitation, Answered: c.elicitation != nil})
+	}
+	var experimental []string
+	for name := range caps.Experimental {
+		experimental = append(experimental, name)
+	}
+	sort.Strings(experimental)
+	for _, name := range experimental {
+		status := ClientCapabilityStatus{Name: capabilityExperimentalPrefix + name}
+		if settings, ok := caps.Experimental[name].(map[string]any); ok && len(settings) > 0 {
+			status.Settings = settings
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestBuildClientCapabilities(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           []string
+		wantErr         bool
+		wantSampling    bool
+		wantRoots       bool
+		wantElicitation bool
+		wantExperiment  []string
+	}{
+		{
+			name:  "empty",
+			input: nil,
+		},
+		{
+			name:         "sampling and roots",
+			input:        []string{"sampling", "roots"},
+			wantSampling: true,
+			wantRoots:    true,
+		},
+		{
+			name:            "case and whitespace insensitive",
+			input:           []string{" Elicitation ", ""},
+			wantElicitation: true,
+		},
+		{
+			name:           "experimental entries",
+			input:          []string{"experimental:featureA", "experimental:featureB"},
+			wantExperiment: []string{"featureA", "featureB"},
+		},
+		{
+			name:           "experimental with settings",
+			input:          []string{`experimental:featureA={"level":2}`, "experimental:featureB="},
+			wantExperiment: []string{"featureA", "featureB"},
+		},
+		{
+			name:    "experimental settings not an object",
+			input:   []string{"experimental:featureC=[1,2]"},
+			wantErr: true,
+		},
+		{
+			name:    "experimental without name",
+			input:   []string{"experimental:"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown capability",
+			input:   []string{"telepathy"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps, err := buildClientCapabilities(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if (caps.Sampling != nil) != tt.wantSampling {
+				t.Errorf("sampling announced = %v, want %v", caps.Sampling != nil, tt.wantSampling)
+			}
+			if (caps.Roots != nil) != tt.wantRoots {
+				t.Errorf("roots announced = %v, want %v", caps.Roots != nil, tt.wantRoots)
+			}
+			if (caps.Elicitation != nil) != tt.wantElicitation {
+				t.Errorf("elicitation announced = %v, want %v", caps.Elicitation != nil, tt.wantElicitation)
+			}
+			if len(caps.Experimental) != len(tt.wantExperiment) {
+				t.Errorf("expected %d experimental entries, got %d", len(tt.wantExperiment), len(caps.Experimental))
+			}
+			for _, name := range tt.wantExperiment {
+				if _, ok := caps.Experimental[name]; !ok {
+					t.Errorf("expected experimental capability %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitClientCapabilities(t *testing.T) {
+	got := SplitClientCapabilities([]string{
+		"sampling, roots",
+		`experimental:a={"x":[1,2],"y":"c,d\"}"}`,
+		",elicitation,",
+	})
+	want := []string{"sampling", "roots", `experimental:a={"x":[1,2],"y":"c,d\"}"}`, "elicitation"}
+	if !slices.Equal(got, want) {
+		t.Errorf("SplitClientCapabilities() = %q, want %q", got, want)
+	}
+	if _, err := buildClientCapabilities(got); err != nil {
+		t.Errorf("split capabilities do not parse: %v", err)
+	}
+}
+
+func TestDescribeClientCapabilities(t *testing.T) {
+	caps, err := buildClientCapabilities([]string{"experimental:b", "roots", "experimental:a", "sampling"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "sampling, roots, experimental:a, experimental:b"
+	if got := describeClientCapabilities(caps); got != want {
+		t.Errorf("describeClientCapabilities() = %q, want %q", got, want)
+	}
+
+	empty, _ := buildClientCapabilities(nil)
+	if got := describeClientCapabilities(empty); got != "(none)" {
+		t.Errorf("expected (none) for empty capabilities, got %q", got)
+	}
+}
+
+func TestSetAnnouncedCapabilities(t *testing.T) {
+	var mu sync.Mutex
+	var announced []mcp.ClientCapabilities
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, req *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		announced = append(announced, req.Params.Capabilities)
+	})
+	ts := server.NewTestStreamableHTTPServer(server.NewMCPServer("capabilities", "1.0.0", server.WithHooks(hooks)))
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:              ts.URL + "/mcp",
+		Transport:             "streamable-http",
+		Logger:                NewLoggerWithWriter(false, false, false, io.Discard),
+		AnnouncedCapabilities: []string{"sampling"},
+		ResumeSessions:        true,
+		NoInitialList:         true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if err := client.SetAnnouncedCapabilities(ctx, []string{"roots", "experimental:demo"}); err != nil {
+		t.Fatalf("SetAnnouncedCapabilities: %v", err)
+	}
+	if err := client.SetAnnouncedCapabilities(ctx, []string{"bogus"}); err == nil || !strings.Contains(err.Error(), "unknown client capability") {
+		t.Errorf("expected an unknown capability to be rejected, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(announced) != 2 {
+		t.Fatalf("expected a new session to be initialized, got %d initializes", len(announced))
+	}
+	if got := describeClientCapabilities(announced[0]); got != "sampling" {
+		t.Errorf("first session announced %s", got)
+	}
+	if got := describeClientCapabilities(announced[1]); got != "roots, experimental:demo" {
+		t.Errorf("second session announced %s", got)
+	}
+	if got := client.AnnouncedCapabilities(); strings.Join(got, ",") != "roots,experimental:demo" {
+		t.Errorf("AnnouncedCapabilities() = %v", got)
+	}
+}
+
+func TestREPLCapabilities(t *testing.T) {
+	ts := server.NewTestStreamableHTTPServer(server.NewMCPServer("capabilities", "1.0.0"))
+	defer ts.Close()
+	c := NewClient(ClientConfig{
+		Endpoint:              ts.URL + "/mcp",
+		Transport:             "streamable-http",
+		Logger:                NewLoggerWithWriter(false, false, false, io.Discard),
+		AnnouncedCapabilities: []string{"roots"},
+		Elicitation:           ElicitationConfig{Mode: ElicitationAuto},
+		NoInitialList:         true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+	r := NewREPL(c, c.logger)
+
+	output, err := captureStdout(func() error { return r.handleCapabilities(ctx, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"roots          announced only", "elicitation    requests are answered"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	for _, tt := range []struct {
+		args   []string
+		want   string
+		listed string
+	}{
+		{[]string{"add", "sampling,experimental:x"}, "roots,sampling,experimental:x", "experimental:x\n"},
+		{[]string{"add", `experimental:x={"level":2}`}, `roots,sampling,experimental:x={"level":2}`, `experimental:x {"level":2}`},
+		{[]string{"remove", "roots"}, `sampling,experimental:x={"level":2}`, ""},
+		{[]string{"remove", "experimental:x"}, "sampling", ""},
+		{[]string{"set", "none"}, "", "elicitation    requests are answered"},
+	} {
+		output, err := captureStdout(func() error { return r.handleCapabilities(ctx, tt.args) })
+		if err != nil {
+			t.Fatalf("capabilities %v: %v", tt.args, err)
+		}
+		if got := strings.Join(c.AnnouncedCapabilities(), ","); got != tt.want {
+			t.Errorf("capabilities %v announced %q, want %q", tt.args, got, tt.want)
+		}
+		if !strings.Contains(output, tt.listed) {
+			t.Errorf("capabilities %v listed %q, want it to contain %q", tt.args, output, tt.listed)
+		}
+	}
+	if err := r.handleCapabilities(ctx, []string{"toggle"}); err == nil || err.Error() != capabilitiesUsage {
+		t.Errorf("expected the usage, got %v", err)
+	}
+}
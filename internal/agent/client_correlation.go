@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxExchanges bounds the requests kept for the trace command
+const maxExchanges = 200
+
+// Exchange is a JSON-RPC request sent to the server and its response
+type Exchange struct {
+	// ID is the short correlation ID shown in the log
+	ID string
+	// RequestID is the JSON-RPC id; empty until the request was sent
+	RequestID string
+	Method    string
+	// Sent is when the request was sent; zero until then
+	Sent time.Time
+	// Elapsed is the time from sending the request to its response
+	Elapsed time.Duration
+	// Done is set once the response or a transport error was received
+	Done     bool
+	Request  json.RawMessage
+	Response json.RawMessage
+	// Err is the transport error, if the request failed without a response
+	Err string
+}
+
+// exchange is the record of one request, shared by the context it is sent
+// with and the history; guarded by the history's mutex
+type exchange struct {
+	Exchange
+	progressToken string
+}
+
+// exchangeHistory keeps the latest requests sent to the server; safe for
+// concurrent use
+type exchangeHistory struct {
+	mu        sync.Mutex
+	exchanges []*exchange
+}
+
+// exchangeKey is the context key of the exchange a request is sent as
+type exchangeKey struct{}
+
+// newCorrelationID returns a short random hex ID
+func newCorrelationID() string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// withExchange returns ctx carrying a new exchange for a request of method,
+// so the transport records the request under the correlation ID that
+// logRequest and logResponse show
+func (c *Client) withExchange(ctx context.Context, method string) context.Context {
+	ex := &exchange{Exchange: Exchange{ID: newCorrelationID(), Method: method}}
+	return context.WithValue(ctx, exchangeKey{}, ex)
+}
+
+// exchangeFromContext returns the exchange of ctx, if any
+func exchangeFromContext(ctx context.Context) *exchange {
+	ex, _ := ctx.Value(exchangeKey{}).(*exchange)
+	return ex
+}
+
+// logRequest logs a request with the correlation ID of the exchange in ctx
+func (c *Client) logRequest(ctx context.Context, method string, params interface{}) {
+	var corr Correlation
+	if ex := exchangeFromContext(ctx); ex != nil {
+		corr.ID = ex.ID
+	}
+	c.logger.CorrelatedRequest(corr, method, params)
+}
+
+// logResponse logs a response with the correlation ID and round-trip time
+// of the exchange in ctx
+func (c *Client) logResponse(ctx context.Context, method string, result interface{}) {
+	var corr Correlation
+	if ex := exchangeFromContext(ctx); ex != nil {
+		corr = c.exchanges.correlation(ex)
+	}
+	c.logger.CorrelatedResponse(corr, method, result)
+}
+
+// correlation returns the correlation of a response to ex
+func (h *exchangeHistory) correlation(ex *exchange) Correlation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Correlation{ID: ex.ID, Method: ex.Method, Elapsed: ex.Elapsed}
+}
+
+// sent records that request is being sent. The exchange of ctx is used if
+// it was created for the request's method, since a context may outlive its
+// request, e.g. while reconnecting; otherwise a new one is recorded.
+func (h *exchangeHistory) sent(ctx context.Context, request transport.JSONRPCRequest) *exchange {
+	ex := exchangeFromContext(ctx)
+	if ex == nil || ex.Method != request.Method {
+		ex = &exchange{Exchange: Exchange{ID: newCorrelationID(), Method: request.Method}}
+	}
+	raw, _ := json.Marshal(request)
+	token := progressTokenOf(raw)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// A retry sends the same exchange again
+	for i, recorded := range h.exchanges {
+		if recorded == ex {
+			h.exchanges = append(h.exchanges[:i], h.exchanges[i+1:]...)
+			break
+		}
+	}
+	ex.RequestID = fmt.Sprint(request.ID.Value())
+	ex.Sent = time.Now()
+	ex.Elapsed = 0
+	ex.Done = false
+	ex.Request = raw
+	ex.Response = nil
+	ex.Err = ""
+	ex.progressToken = token
+	h.exchanges = append(h.exchanges, ex)
+	if len(h.exchanges) > maxExchanges {
+		h.exchanges = h.exchanges[len(h.exchanges)-maxExchanges:]
+	}
+	return ex
+}
+
+// received records the response to ex or the error sending it
+func (h *exchangeHistory) received(ex *exchange, response *transport.JSONRPCResponse, err error) {
+	var raw json.RawMessage
+	if response != nil {
+		raw, _ = json.Marshal(response)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ex.Elapsed = time.Since(ex.Sent)
+	ex.Done = true
+	ex.Response = raw
+	if err != nil {
+		ex.Err = err.Error()
+	}
+}
+
+// progressTokenOf returns the progress token in the _meta of a request
+func progressTokenOf(raw []byte) string {
+	var request struct {
+		Params struct {
+			Meta struct {
+				ProgressToken any `json:"progressToken"`
+			} `json:"_meta"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(raw, &request) != nil || request.Params.Meta.ProgressToken == nil {
+		return ""
+	}
+	return fmt.Sprint(request.Params.Meta.ProgressToken)
+}
+
+// notificationCorrelation links a progress or cancellation notification to
+// the request it refers to
+func (h *exchangeHistory) notificationCorrelation(notification mcp.JSONRPCNotification) Correlation {
+	var match func(*exchange) bool
+	switch notification.Method {
+	case string(mcp.MethodNotificationProgress):
+		token, ok := notification.Params.AdditionalFields["progressToken"]
+		if !ok {
+			return Correlation{}
+		}
+		match = func(ex *exchange) bool { return ex.progressToken == fmt.Sprint(token) }
+	case string(mcp.MethodNotificationCancelled):
+		id, ok := notification.Params.AdditionalFields["requestId"]
+		if !ok {
+			return Correlation{}
+		}
+		return h.requestCorrelation(fmt.Sprint(id))
+	default:
+		return Correlation{}
+	}
+	return h.latestCorrelation(match)
+}
+
+// requestCorrelation links a message to the request with a JSON-RPC id
+func (h *exchangeHistory) requestCorrelation(requestID string) Correlation {
+	return h.latestCorrelation(func(ex *exchange) bool { return ex.RequestID == requestID })
+}
+
+// latestCorrelation returns the correlation to the latest matching
+// exchange, with the time elapsed since it was sent
+func (h *exchangeHistory) latestCorrelation(match func(*exchange) bool) Correlation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.exchanges) - 1; i >= 0; i-- {
+		if ex := h.exchanges[i]; match(ex) {
+			return Correlation{ID: ex.ID, Method: ex.Method, Elapsed: time.Since(ex.Sent)}
+		}
+	}
+	return Correlation{}
+}
+
+// find returns the latest exchange with the correlation ID or, failing
+// that, the JSON-RPC id
+func (h *exchangeHistory) find(id string) (Exchange, bool) {
+	id = strings.TrimPrefix(id, "#")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.exchanges) - 1; i >= 0; i-- {
+		if h.exchanges[i].ID == id {
+			return h.exchanges[i].Exchange, true
+		}
+	}
+	for i := len(h.exchanges) - 1; i >= 0; i-- {
+		if h.exchanges[i].RequestID == id {
+			return h.exchanges[i].Exchange, true
+		}
+	}
+	return Exchange{}, false
+}
+
+// recent returns up to n of the latest exchanges, oldest first
+func (h *exchangeHistory) recent(n int) []Exchange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	start := max(len(h.exchanges)-n, 0)
+	out := make([]Exchange, 0, len(h.exchanges)-start)
+	for _, ex := range h.exchanges[start:] {
+		out = append(out, ex.Exchange)
+	}
+	return out
+}
+
+// FindExchange returns the request with the given correlation ID (with or
+// without the leading #) or JSON-RPC id, and its response
+func (c *Client) FindExchange(id string) (Exchange, bool) {
+	return c.exchanges.find(id)
+}
+
+// RecentExchanges returns up to n of the latest requests, oldest first
+func (c *Client) RecentExchanges(n int) []Exchange {
+	return c.exchanges.recent(n)
+}
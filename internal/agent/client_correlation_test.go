@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestClientCorrelatesExchanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, buf)
+	c := NewClient(ClientConfig{
+		Endpoint:  newUpstreamServer(t),
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	buf.Reset()
+	if _, err := c.CallTool(ctx, "echo", map[string]any{"message": "hi"}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+
+	// The request and its response carry the same correlation ID
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	request := regexp.MustCompile(`Sending request: tools/call \[#([0-9a-f]{6})\]`).FindStringSubmatch(lines[0])
+	if request == nil {
+		t.Fatalf("request line without correlation ID: %q", lines[0])
+	}
+	response := regexp.MustCompile(`Received response for: tools/call \[#` + request[1] + ` tools/call \d`).FindString(buf.String())
+	if response == "" {
+		t.Errorf("response not correlated with #%s:\n%s", request[1], buf.String())
+	}
+
+	ex, ok := c.FindExchange("#" + request[1])
+	if !ok {
+		t.Fatalf("exchange #%s not recorded", request[1])
+	}
+	if ex.Method != "tools/call" || !ex.Done || ex.Elapsed <= 0 || ex.RequestID == "" {
+		t.Errorf("unexpected exchange %+v", ex)
+	}
+	if !strings.Contains(string(ex.Request), `"message":"hi"`) || !strings.Contains(string(ex.Response), `"text":"hi"`) {
+		t.Errorf("request %s, response %s", ex.Request, ex.Response)
+	}
+	if byRequestID, ok := c.FindExchange(ex.RequestID); !ok || byRequestID.ID != ex.ID {
+		t.Errorf("FindExchange(%s) = %+v, %v", ex.RequestID, byRequestID, ok)
+	}
+
+	recent := c.RecentExchanges(2)
+	if len(recent) != 2 || recent[1].ID != ex.ID || recent[0].Method != "prompts/list" {
+		t.Errorf("RecentExchanges = %+v", recent)
+	}
+}
+
+func TestExchangeHistoryCorrelatesNotifications(t *testing.T) {
+	var history exchangeHistory
+	ctx := context.WithValue(context.Background(), exchangeKey{}, &exchange{Exchange: Exchange{ID: "abc123", Method: "tools/call"}})
+	request := mcp.CallToolRequest{}
+	request.Params.Meta = &mcp.Meta{ProgressToken: "token-1"}
+	ex := history.sent(ctx, transportRequest(7, "tools/call", request.Params))
+	if ex.ID != "abc123" || ex.RequestID != "7" {
+		t.Fatalf("sent exchange %+v", ex.Exchange)
+	}
+
+	progress := mcp.JSONRPCNotification{Notification: mcp.Notification{
+		Method: string(mcp.MethodNotificationProgress),
+		Params: mcp.NotificationParams{AdditionalFields: map[string]any{"progressToken": "token-1", "progress": 1}},
+	}}
+	time.Sleep(time.Millisecond)
+	if corr := history.notificationCorrelation(progress); corr.ID != "abc123" || corr.Method != "tools/call" || corr.Elapsed <= 0 {
+		t.Errorf("progress correlation = %+v", corr)
+	}
+
+	cancelled := mcp.JSONRPCNotification{Notification: mcp.Notification{
+		Method: string(mcp.MethodNotificationCancelled),
+		Params: mcp.NotificationParams{AdditionalFields: map[string]any{"requestId": float64(7)}},
+	}}
+	if corr := history.notificationCorrelation(cancelled); corr.ID != "abc123" {
+		t.Errorf("cancellation correlation = %+v", corr)
+	}
+
+	// A context reused for a request of another method gets a new exchange
+	other := history.sent(ctx, transportRequest(8, methodPing, nil))
+	if other.ID == "abc123" {
+		t.Error("ping recorded under the tool call's correlation ID")
+	}
+}
+
+func TestCorrelationSuffix(t *testing.T) {
+	tests := []struct {
+		corr Correlation
+		want string
+	}{
+		{Correlation{}, ""},
+		{Correlation{ID: "abc123"}, " [#abc123]"},
+		{Correlation{ID: "abc123", Method: "tools/call", Elapsed: 1234567 * time.Nanosecond}, " [#abc123 tools/call 1ms]"},
+		{Correlation{ID: "abc123", Method: methodPing, Elapsed: 250 * time.Microsecond}, " [#abc123 ping 250µs]"},
+	}
+	for _, tt := range tests {
+		if got := tt.corr.suffix(); got != tt.want {
+			t.Errorf("suffix(%+v) = %q, want %q", tt.corr, got, tt.want)
+		}
+	}
+}
+
+// transportRequest builds a request as mcp-go passes it to the transport
+func transportRequest(id int64, method string, params any) transport.JSONRPCRequest {
+	return transport.JSONRPCRequest{JSONRPC: mcp.JSONRPC_VERSION, ID: mcp.NewRequestId(id), Method: method, Params: params}
+}
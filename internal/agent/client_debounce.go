@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultListChangedDebounce is the default quiet time after which a burst
+// of list_changed notifications is folded into a single re-list.
+const DefaultListChangedDebounce = 500 * time.Millisecond
+
+// listChangedDebouncer coalesces repeated triggers for the same key. The
+// first trigger schedules the callback; every further trigger before it
+// fires is counted as suppressed, folded into it and pushes it back by the
+// window, so the callback runs once the triggers have been quiet for a
+// whole window.
+type listChangedDebouncer struct {
+	window     time.Duration
+	mu         sync.Mutex
+	pending    map[string]*pendingRefresh
+	suppressed int64
+}
+
+// pendingRefresh tracks a scheduled refresh and how many triggers it absorbed
+type pendingRefresh struct {
+	timer     *time.Timer
+	coalesced int
+}
+
+// newListChangedDebouncer creates a debouncer with the given window.
+// A window of zero or less disables coalescing.
+func newListChangedDebouncer(window time.Duration) *listChangedDebouncer {
+	return &listChangedDebouncer{
+		window:  window,
+		pending: make(map[string]*pendingRefresh),
+	}
+}
+
+// enabled reports whether coalescing is active
+func (d *listChangedDebouncer) enabled() bool {
+	return d != nil && d.window > 0
+}
+
+// trigger schedules fn for key to run after the window. If a refresh is
+// already pending, the trigger is suppressed and the pending refresh is
+// rescheduled to run a window from now. fn receives the number of triggers
+// that were folded into this refresh (not counting the first one).
+func (d *listChangedDebouncer) trigger(key string, fn func(coalesced int)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if p, exists := d.pending[key]; exists {
+		p.coalesced++
+		d.suppressed++
+		// A timer that already fired is waiting for mu; its refresh runs
+		// after this trigger and covers it
+		if p.timer.Stop() {
+			p.timer.Reset(d.window)
+		}
+		return
+	}
+
+	p := &pendingRefresh{}
+	p.timer = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		coalesced := p.coalesced
+		delete(d.pending, key)
+		d.mu.Unlock()
+
+		fn(coalesced)
+	})
+	d.pending[key] = p
+}
+
+// suppressedCount returns the total number of triggers suppressed so far
+func (d *listChangedDebouncer) suppressedCount() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.suppressed
+}
+
+// stop cancels all pending refreshes
+func (d *listChangedDebouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, p := range d.pending {
+		p.timer.Stop()
+		delete(d.pending, key)
+	}
+}
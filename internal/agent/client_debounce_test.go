@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListChangedDebouncer(t *testing.T) {
+	t.Run("coalesces burst into single refresh", func(t *testing.T) {
+		d := newListChangedDebouncer(50 * time.Millisecond)
+
+		var mu sync.Mutex
+		calls := 0
+		lastCoalesced := -1
+		done := make(chan struct{}, 1)
+
+		for i := 0; i < 5; i++ {
+			d.trigger(notificationToolsListChanged, func(coalesced int) {
+				mu.Lock()
+				calls++
+				lastCoalesced = coalesced
+				mu.Unlock()
+				done <- struct{}{}
+			})
+		}
+
+		select {
+		case <-done:
+		case <-time.After(testTimeoutNormal):
+			t.Fatal("debounced refresh never fired")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if calls != 1 {
+			t.Errorf("expected 1 refresh, got %d", calls)
+		}
+		if lastCoalesced != 4 {
+			t.Errorf("expected 4 coalesced triggers, got %d", lastCoalesced)
+		}
+		if got := d.suppressedCount(); got != 4 {
+			t.Errorf("expected suppressed count 4, got %d", got)
+		}
+	})
+
+	t.Run("each trigger postpones the refresh", func(t *testing.T) {
+		window := 200 * time.Millisecond
+		d := newListChangedDebouncer(window)
+
+		fired := make(chan int, 1)
+		refresh := func(coalesced int) { fired <- coalesced }
+		d.trigger(notificationToolsListChanged, refresh)
+		// The triggers span more than a window, but none is a window apart
+		for i := 0; i < 5; i++ {
+			time.Sleep(window / 4)
+			d.trigger(notificationToolsListChanged, refresh)
+		}
+
+		select {
+		case <-fired:
+			t.Fatal("refresh fired while triggers kept arriving")
+		default:
+		}
+		select {
+		case coalesced := <-fired:
+			if coalesced != 5 {
+				t.Errorf("expected 5 coalesced triggers, got %d", coalesced)
+			}
+		case <-time.After(testTimeoutNormal):
+			t.Fatal("debounced refresh never fired")
+		}
+	})
+
+	t.Run("keys are debounced independently", func(t *testing.T) {
+		d := newListChangedDebouncer(20 * time.Millisecond)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		d.trigger(notificationToolsListChanged, func(int) { wg.Done() })
+		d.trigger(notificationPromptsListChanged, func(int) { wg.Done() })
+
+		waitCh := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(waitCh)
+		}()
+
+		select {
+		case <-waitCh:
+		case <-time.After(testTimeoutNormal):
+			t.Fatal("expected both keys to refresh")
+		}
+
+		if got := d.suppressedCount(); got != 0 {
+			t.Errorf("expected no suppressed triggers, got %d", got)
+		}
+	})
+
+	t.Run("stop cancels pending refreshes", func(t *testing.T) {
+		d := newListChangedDebouncer(20 * time.Millisecond)
+
+		fired := make(chan struct{}, 1)
+		d.trigger(notificationResourcesListChanged, func(int) { fired <- struct{}{} })
+		d.stop()
+
+		select {
+		case <-fired:
+			t.Error("refresh fired after stop")
+		case <-time.After(testDelayLong):
+		}
+	})
+
+	t.Run("zero window is disabled", func(t *testing.T) {
+		if newListChangedDebouncer(0).enabled() {
+			t.Error("expected zero window to disable debouncing")
+		}
+		var d *listChangedDebouncer
+		if d.enabled() {
+			t.Error("expected nil debouncer to be disabled")
+		}
+	})
+}
@@ -0,0 +1,408 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultResourceMemoryLimit is the decoded resource size above which a read
+// is streamed to a file instead of being kept in memory
+const DefaultResourceMemoryLimit int64 = 16 << 20
+
+// DownloadProgressHandler receives the number of response bytes received so
+// far and the size of the response, or -1 if the server did not announce it
+type DownloadProgressHandler func(received, total int64)
+
+// ResourceDownload is a streamed resource read. Contents within the client's
+// memory limit are kept in Result; larger contents are written to the file
+// at Path instead, and Result is nil.
+type ResourceDownload struct {
+	Result *mcp.ReadResourceResult
+	Path   string
+	// Size is the decoded size of the contents in bytes
+	Size int64
+}
+
+// downloadRequestIDs numbers the resources/read requests sent outside
+// mcp-go, apart from the IDs mcp-go assigns
+var downloadRequestIDs atomic.Int64
+
+// ResourceMemoryLimit returns the decoded size above which resource reads
+// are streamed to a file; zero means resources are always kept in memory
+func (c *Client) ResourceMemoryLimit() int64 {
+	return c.resourceMemoryLimit
+}
+
+// SaveResource reads a resource and writes its decoded contents to path as
+// the response arrives, so the resource is never held in memory; blobs are
+// base64 decoded on the way. onProgress, if set, follows the bytes received.
+// A partially written file is removed on failure. It returns the number of
+// bytes written.
+func (c *Client) SaveResource(ctx context.Context, uri, path string, onProgress DownloadProgressHandler) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	sink := &fileContentsSink{w: bufio.NewWriter(f)}
+	meta, err := c.streamResource(ctx, uri, sink, onProgress)
+	if err == nil {
+		c.logger.Debug("resources/read of %s streamed %d bytes to %s", uri, sink.written, path)
+		c.logServerMeta("resources/read", meta)
+	}
+	if errors.Is(err, errNotStreamable) {
+		// Without a streamable HTTP session, read through mcp-go
+		var result *mcp.ReadResourceResult
+		if result, err = c.GetResource(ctx, uri); err == nil {
+			_, err = writeResourceContents(sink, result.Contents)
+		}
+	}
+	if err == nil {
+		err = sink.w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return sink.written, err
+	}
+	return sink.written, nil
+}
+
+// DownloadResource reads a resource as the response arrives. Contents are
+// kept in memory up to the client's memory limit; beyond it, they are
+// written to a temporary file instead, which the caller removes when done.
+// onProgress, if set, follows the bytes received.
+func (c *Client) DownloadResource(ctx context.Context, uri string, onProgress DownloadProgressHandler) (*ResourceDownload, error) {
+	sink := &memoryContentsSink{limit: c.resourceMemoryLimit}
+	meta, err := c.streamResource(ctx, uri, sink, onProgress)
+	if errors.Is(err, errNotStreamable) {
+		result, err := c.GetResource(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		return &ResourceDownload{Result: result, Size: ResourceContentsSize(result.Contents)}, nil
+	}
+	if err == nil {
+		err = sink.finish()
+	}
+	if err != nil {
+		sink.discard()
+		return nil, err
+	}
+
+	download := &ResourceDownload{Path: sink.path, Size: sink.size}
+	if sink.path == "" {
+		download.Result = &mcp.ReadResourceResult{Contents: sink.contents()}
+		download.Result.Meta = meta
+		c.logResponse(ctx, "resources/read", download.Result)
+	} else {
+		c.logger.Debug("resources/read of %s streamed %d bytes to %s", uri, sink.size, sink.path)
+	}
+	c.logServerMeta("resources/read", meta)
+	return download, nil
+}
+
+// streamResource sends resources/read within the session and passes the
+// decoded contents to sink as the response arrives. It returns the _meta of
+// the result, or an error wrapping errNotStreamable if the client has no
+// streamable HTTP session to send it in.
+func (c *Client) streamResource(ctx context.Context, uri string, sink resourceContentsSink, onProgress DownloadProgressHandler) (*mcp.Meta, error) {
+	poster, err := c.sessionPoster("streaming a resource")
+	if err != nil {
+		return nil, err
+	}
+
+	params := mcp.ReadResourceParams{URI: uri, Meta: c.requestMeta()}
+	body, err := json.Marshal(mcp.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(fmt.Sprintf("mcp-debug-read-%d", downloadRequestIDs.Add(1))),
+		Request: mcp.Request{Method: string(mcp.MethodResourcesRead)},
+		Params:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx = c.withExchange(ctx, "resources/read")
+	c.logRequest(ctx, "resources/read", params)
+
+	var meta *mcp.Meta
+	err = c.withRequestSlot(ctx, "resources/read", func() error {
+		resp, err := poster.do(ctx, body)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		meta, err = decodeResourceResponse(resp, sink, onProgress)
+		return err
+	})
+	return meta, classifyError(err)
+}
+
+// decodeResourceResponse decodes the response to a streamed resources/read,
+// which is a JSON document or an SSE stream whose events before the response
+// may be notifications or requests
+func decodeResourceResponse(resp *http.Response, sink resourceContentsSink, onProgress DownloadProgressHandler) (*mcp.Meta, error) {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, transport.ErrUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, transport.ErrSessionTerminated
+	case resp.StatusCode != http.StatusOK:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("resources/read failed with HTTP %d: %s", resp.StatusCode, orNone(strings.TrimSpace(string(detail))))
+	}
+
+	var body io.Reader = resp.Body
+	if onProgress != nil {
+		body = &progressReader{r: body, total: resp.ContentLength, onProgress: onProgress}
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		decoder := newResourceResponseDecoder(body, sink)
+		if err := decoder.decodeMessage(); err != nil {
+			return nil, err
+		}
+		return decoder.response()
+	}
+
+	events := newSSEDataReader(body)
+	decoder := newResourceResponseDecoder(events, sink)
+	for {
+		if err := decoder.decodeMessage(); err != nil {
+			if events.streamEnded {
+				return nil, fmt.Errorf("resources/read stream ended without a response: %w", err)
+			}
+			return nil, err
+		}
+		if decoder.method == "" {
+			return decoder.response()
+		}
+		// A notification or server request sent ahead of the response
+		if err := events.nextEvent(); err != nil {
+			return nil, fmt.Errorf("resources/read stream ended without a response: %w", io.ErrUnexpectedEOF)
+		}
+		decoder.r.Reset(events)
+	}
+}
+
+// progressReader reports the bytes read through it
+type progressReader struct {
+	r          io.Reader
+	read       int64
+	total      int64
+	onProgress DownloadProgressHandler
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.onProgress(p.read, p.total)
+	}
+	return n, err
+}
+
+// fileContentsSink writes all content items to one file
+type fileContentsSink struct {
+	w       *bufio.Writer
+	written int64
+}
+
+func (s *fileContentsSink) Write(b []byte) (int, error) {
+	n, err := s.w.Write(b)
+	s.written += int64(n)
+	return n, err
+}
+
+func (s *fileContentsSink) endContent(streamedContent) error {
+	return nil
+}
+
+// memoryContentsSink keeps content items in memory until their total size
+// exceeds limit, then moves them to a temporary file and writes the rest
+// there
+type memoryContentsSink struct {
+	limit int64
+	size  int64
+
+	items   []streamedContent
+	buffers []*bytes.Buffer
+	current bytes.Buffer
+
+	// file and path are set once the contents were moved to a file
+	file *os.File
+	w    *bufio.Writer
+	path string
+}
+
+func (s *memoryContentsSink) Write(b []byte) (int, error) {
+	s.size += int64(len(b))
+	if s.file == nil && s.limit > 0 && s.size > s.limit {
+		if err := s.spill(); err != nil {
+			return 0, err
+		}
+	}
+	if s.file != nil {
+		return s.w.Write(b)
+	}
+	return s.current.Write(b)
+}
+
+func (s *memoryContentsSink) endContent(item streamedContent) error {
+	if s.file != nil {
+		return nil
+	}
+	s.items = append(s.items, item)
+	s.buffers = append(s.buffers, bytes.NewBuffer(s.current.Bytes()))
+	s.current = bytes.Buffer{}
+	return nil
+}
+
+// spill moves the contents kept so far to a temporary file
+func (s *memoryContentsSink) spill() error {
+	f, err := os.CreateTemp("", "mcp-debug-resource-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	s.file, s.path, s.w = f, f.Name(), bufio.NewWriter(f)
+	for _, buf := range append(s.buffers, &s.current) {
+		if _, err := buf.WriteTo(s.w); err != nil {
+			return err
+		}
+	}
+	s.items, s.buffers = nil, nil
+	s.current = bytes.Buffer{}
+	return nil
+}
+
+// finish completes the temporary file, if there is one
+func (s *memoryContentsSink) finish() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// discard removes the temporary file of a failed read
+func (s *memoryContentsSink) discard() {
+	if s.file != nil {
+		_ = s.file.Close()
+		_ = os.Remove(s.path)
+	}
+}
+
+// contents returns the content items kept in memory
+func (s *memoryContentsSink) contents() []mcp.ResourceContents {
+	contents := make([]mcp.ResourceContents, 0, len(s.items))
+	for i, item := range s.items {
+		if item.Blob {
+			contents = append(contents, mcp.BlobResourceContents{
+				URI:      item.URI,
+				MIMEType: item.MIMEType,
+				Blob:     base64.StdEncoding.EncodeToString(s.buffers[i].Bytes()),
+			})
+		} else {
+			contents = append(contents, mcp.TextResourceContents{
+				URI:      item.URI,
+				MIMEType: item.MIMEType,
+				Text:     s.buffers[i].String(),
+			})
+		}
+	}
+	return contents
+}
+
+// SaveResourceContents writes the decoded contents of a resource read result
+// to path. A partially written file is removed on failure.
+func SaveResourceContents(result *mcp.ReadResourceResult, path string) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	written, err := writeResourceContents(f, result.Contents)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return written, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return written, nil
+}
+
+// ResourceContentsSize returns the decoded size in bytes of all contents of a
+// resource read result
+func ResourceContentsSize(contents []mcp.ResourceContents) int64 {
+	var total int64
+	for _, content := range contents {
+		if text, ok := mcp.AsTextResourceContents(content); ok {
+			total += int64(len(text.Text))
+		} else if blob, ok := mcp.AsBlobResourceContents(content); ok {
+			total += decodedBlobSize(blob.Blob)
+		}
+	}
+	return total
+}
+
+// decodedBlobSize returns the number of bytes a padded base64 string decodes to
+func decodedBlobSize(blob string) int64 {
+	size := int64(base64.StdEncoding.DecodedLen(len(blob)))
+	return size - int64(len(blob)-len(strings.TrimRight(blob, "=")))
+}
+
+// writeResourceContents writes every content item to w, decoding blobs on
+// the way
+func writeResourceContents(w io.Writer, contents []mcp.ResourceContents) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	for _, content := range contents {
+		var src io.Reader
+		if text, ok := mcp.AsTextResourceContents(content); ok {
+			src = strings.NewReader(text.Text)
+		} else if blob, ok := mcp.AsBlobResourceContents(content); ok {
+			src = base64.NewDecoder(base64.StdEncoding, strings.NewReader(blob.Blob))
+		} else {
+			continue
+		}
+
+		if _, err := io.Copy(cw, src); err != nil {
+			return cw.written, err
+		}
+	}
+	return cw.written, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestSaveResourceContents(t *testing.T) {
+	blob := bytes.Repeat([]byte{0x00, 0xff, 0x10}, 1<<20)
+	result := &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContents{
+			mcp.TextResourceContents{URI: "file:///a", Text: "header\n"},
+			mcp.BlobResourceContents{URI: "file:///a", Blob: base64.StdEncoding.EncodeToString(blob)},
+		},
+	}
+
+	wantSize := int64(len("header\n") + len(blob))
+	if got := ResourceContentsSize(result.Contents); got != wantSize {
+		t.Fatalf("ResourceContentsSize() = %d, want %d", got, wantSize)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	written, err := SaveResourceContents(result, path)
+	if err != nil {
+		t.Fatalf("SaveResourceContents: %v", err)
+	}
+	if written != wantSize {
+		t.Errorf("written = %d, want %d", written, wantSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if !bytes.Equal(data, append([]byte("header\n"), blob...)) {
+		t.Error("saved contents do not match the resource")
+	}
+}
+
+func TestSaveResourceContentsRemovesPartialFile(t *testing.T) {
+	result := &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContents{
+			mcp.BlobResourceContents{URI: "file:///bad", Blob: "not base64!"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	if _, err := SaveResourceContents(result, path); err == nil {
+		t.Fatal("expected error for invalid blob")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected partial file to be removed, stat err = %v", err)
+	}
+}
+
+// newBlobServer returns an MCP endpoint with a text resource and a blob
+// resource of the given contents
+func newBlobServer(t *testing.T, blob []byte) string {
+	t.Helper()
+
+	upstream := server.NewMCPServer("blobs", "1.0.0", server.WithResourceCapabilities(false, false))
+	upstream.AddResource(mcp.NewResource("file:///blob", "blob", mcp.WithMIMEType("application/octet-stream")),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: "file:///blob", MIMEType: "text/plain", Text: "héader \"quoted\" \U0001F600\n"},
+				mcp.BlobResourceContents{URI: "file:///blob", MIMEType: "application/octet-stream", Blob: base64.StdEncoding.EncodeToString(blob)},
+			}, nil
+		})
+	ts := server.NewTestStreamableHTTPServer(upstream)
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func TestStreamResource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	blob := bytes.Repeat([]byte{0x00, 0xff, 0x10, 0x7f}, 64<<10)
+	want := append([]byte("héader \"quoted\" \U0001F600\n"), blob...)
+	c := NewClient(ClientConfig{
+		Endpoint:            newBlobServer(t, blob),
+		Transport:           "streamable-http",
+		Logger:              NewLoggerWithWriter(false, false, false, io.Discard),
+		ResourceMemoryLimit: 64 << 10,
+	})
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	t.Run("saves to a file with progress", func(t *testing.T) {
+		var updates int
+		var received int64
+		path := filepath.Join(t.TempDir(), "out.bin")
+		written, err := c.SaveResource(ctx, "file:///blob", path, func(n, total int64) {
+			updates++
+			received = n
+		})
+		if err != nil {
+			t.Fatalf("SaveResource: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if written != int64(len(want)) || !bytes.Equal(data, want) {
+			t.Errorf("saved %d bytes that do not match the resource", written)
+		}
+		if updates < 2 || received < int64(len(blob)) {
+			t.Errorf("expected progress while receiving, got %d updates up to %d bytes", updates, received)
+		}
+	})
+
+	t.Run("spills beyond the memory limit", func(t *testing.T) {
+		download, err := c.DownloadResource(ctx, "file:///blob", nil)
+		if err != nil {
+			t.Fatalf("DownloadResource: %v", err)
+		}
+		t.Cleanup(func() { _ = os.Remove(download.Path) })
+		if download.Result != nil || download.Path == "" {
+			t.Fatalf("expected the resource in a file, got %+v", download)
+		}
+		data, _ := os.ReadFile(download.Path)
+		if download.Size != int64(len(want)) || !bytes.Equal(data, want) {
+			t.Errorf("spilled %d bytes that do not match the resource", download.Size)
+		}
+	})
+
+	t.Run("keeps small resources in memory", func(t *testing.T) {
+		c.resourceMemoryLimit = 0
+		defer func() { c.resourceMemoryLimit = 64 << 10 }()
+		download, err := c.DownloadResource(ctx, "file:///blob", nil)
+		if err != nil {
+			t.Fatalf("DownloadResource: %v", err)
+		}
+		if download.Path != "" || download.Result == nil || len(download.Result.Contents) != 2 {
+			t.Fatalf("expected the resource in memory, got %+v", download)
+		}
+		text, ok := mcp.AsTextResourceContents(download.Result.Contents[0])
+		if !ok || text.Text != "héader \"quoted\" \U0001F600\n" || text.MIMEType != "text/plain" {
+			t.Errorf("unexpected text contents %+v", download.Result.Contents[0])
+		}
+		blobContents, ok := mcp.AsBlobResourceContents(download.Result.Contents[1])
+		if !ok || blobContents.Blob != base64.StdEncoding.EncodeToString(blob) {
+			t.Error("blob contents do not match the resource")
+		}
+	})
+}
+
+func TestDecodeResourceResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:        "json with fields after the contents",
+			contentType: "application/json",
+			body:        `{"jsonrpc":"2.0","result":{"contents":[{"text":"a\n\u00e9\ud83d\ude00\/","uri":"x"},{"blob":"aGk=","mimeType":"b"}],"_meta":{"k":[1,{"n":null}]}},"id":"r"}`,
+			want:        "a\né\U0001F600/hi",
+		},
+		{
+			name:        "sse with a notification ahead",
+			contentType: "text/event-stream",
+			body: "id: 1\nevent: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"contents\":[{\"text\":\"no\"}]}}\n\n" +
+				": comment\ndata: {\"jsonrpc\":\"2.0\",\"id\":\"r\",\r\ndata: \"result\":{\"contents\":[{\"text\":\"yes\"}]}}\r\n\r\n",
+			want: "yes",
+		},
+		{
+			name:        "json-rpc error",
+			contentType: "application/json",
+			body:        `{"jsonrpc":"2.0","id":"r","error":{"code":-32002,"message":"Resource not found"}}`,
+			wantErr:     "Resource not found",
+		},
+		{
+			name:        "sse without a response",
+			contentType: "text/event-stream",
+			body:        "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\"}\n\n",
+			wantErr:     "ended without a response",
+		},
+		{
+			name:        "truncated blob",
+			contentType: "application/json",
+			body:        `{"jsonrpc":"2.0","id":"r","result":{"contents":[{"blob":"aGk"}]}}`,
+			wantErr:     "truncated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{tt.contentType}},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			var out bytes.Buffer
+			sink := &fileContentsSink{w: bufio.NewWriter(&out)}
+			_, err := decodeResourceResponse(resp, sink, nil)
+			_ = sink.w.Flush()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("contents = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ElicitationMode selects how elicitation/create requests from the server
+// are answered
+type ElicitationMode string
+
+const (
+	// ElicitationOff does not offer elicitation; the server's requests fail
+	ElicitationOff ElicitationMode = "off"
+	// ElicitationInteractive asks the user for every requested field
+	ElicitationInteractive ElicitationMode = "interactive"
+	// ElicitationAuto answers from configured answers without asking
+	ElicitationAuto ElicitationMode = "auto"
+)
+
+// ElicitationModes lists the accepted elicitation mode names
+var ElicitationModes = []string{
+	string(ElicitationOff),
+	string(ElicitationInteractive),
+	string(ElicitationAuto),
+}
+
+// ParseElicitationMode validates an elicitation mode name
+func ParseElicitationMode(mode string) (ElicitationMode, error) {
+	normalized := strings.ToLower(strings.TrimSpace(mode))
+	for _, m := range ElicitationModes {
+		if m == normalized {
+			return ElicitationMode(m), nil
+		}
+	}
+	return "", fmt.Errorf("invalid elicitation mode: %s (must be one of: %s)", mode, strings.Join(ElicitationModes, ", "))
+}
+
+// maxElicitationAttempts bounds how often an invalid field value is asked
+// for again before the request is cancelled
+const maxElicitationAttempts = 3
+
+// ElicitationConfig configures the answers to elicitation requests
+type ElicitationConfig struct {
+	Mode ElicitationMode
+	// Answers are the canned answers of ElicitationAuto mode, tried in order
+	Answers []ElicitationAnswer
+}
+
+// ElicitationAnswer is a canned answer to the elicitation requests whose
+// message contains Match
+type ElicitationAnswer struct {
+	// Match is a case-insensitive substring of the request message; empty
+	// matches every request
+	Match string `json:"match,omitempty"`
+	// Action is accept, decline or cancel
+	Action mcp.ElicitationResponseAction `json:"action"`
+	// Content is sent with accepted form requests. Fields it leaves out are
+	// filled from the defaults of the requested schema.
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// LoadElicitationAnswers reads canned answers from a JSON file holding an
+// array of ElicitationAnswer objects
+func LoadElicitationAnswers(path string) ([]ElicitationAnswer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elicitation answers: %w", err)
+	}
+
+	var answers []ElicitationAnswer
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("failed to parse elicitation answers %s: %w", path, err)
+	}
+	for i, answer := range answers {
+		switch answer.Action {
+		case mcp.ElicitationResponseActionAccept, mcp.ElicitationResponseActionDecline, mcp.ElicitationResponseActionCancel:
+		default:
+			return nil, fmt.Errorf("elicitation answer %d: invalid action %q (must be accept, decline or cancel)", i+1, answer.Action)
+		}
+	}
+	return answers, nil
+}
+
+// elicitationField is one property of a requested schema. MCP restricts
+// requested schemas to flat objects of primitive properties.
+type elicitationField struct {
+	Name        string
+	Title       string
+	Description string
+	Type        string
+	Enum        []string
+	Default     any
+	Required    bool
+}
+
+// elicitationFields extracts the properties of a requested schema, sorted by
+// name with required fields first
+func elicitationFields(schema any) []elicitationField {
+	var parsed struct {
+		Properties map[string]struct {
+			Type        string `json:"type"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Enum        []any  `json:"enum"`
+			Default     any    `json:"default"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil || json.Unmarshal(raw, &parsed) != nil {
+		return nil
+	}
+
+	fields := make([]elicitationField, 0, len(parsed.Properties))
+	for name, prop := range parsed.Properties {
+		field := elicitationField{
+			Name:        name,
+			Title:       prop.Title,
+			Description: prop.Description,
+			Type:        prop.Type,
+			Default:     prop.Default,
+			Required:    slices.Contains(parsed.Required, name),
+		}
+		for _, value := range prop.Enum {
+			field.Enum = append(field.Enum, fmt.Sprint(value))
+		}
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Required != fields[j].Required {
+			return fields[i].Required
+		}
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// parseValue converts user input into a value of the field's type
+func (f elicitationField) parseValue(input string) (any, error) {
+	if len(f.Enum) > 0 && !slices.Contains(f.Enum, input) {
+		return nil, fmt.Errorf("must be one of: %s", strings.Join(f.Enum, ", "))
+	}
+
+	switch f.Type {
+	case "number":
+		v, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return v, nil
+	case "integer":
+		v, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return v, nil
+	case "boolean":
+		switch strings.ToLower(input) {
+		case "y", "yes", "true", "1":
+			return true, nil
+		case "n", "no", "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be yes or no")
+	}
+	return input, nil
+}
+
+// describe renders the field for a prompt, e.g. "age (integer, required)"
+func (f elicitationField) describe() string {
+	details := []string{f.Type}
+	if len(f.Enum) > 0 {
+		details = []string{strings.Join(f.Enum, "|")}
+	}
+	if f.Required {
+		details = append(details, "required")
+	}
+	if f.Default != nil {
+		details = append(details, fmt.Sprintf("default %v", f.Default))
+	}
+
+	label := f.Name
+	if f.Title != "" && f.Title != f.Name {
+		label = fmt.Sprintf("%s [%s]", f.Title, f.Name)
+	}
+	return fmt.Sprintf("%s (%s)", label, strings.Join(details, ", "))
+}
+
+// elicitationHandler answers elicitation/create requests; it implements
+// mcp-go's client.ElicitationHandler
+type elicitationHandler struct {
+	mode    ElicitationMode
+	answers []ElicitationAnswer
+	logger  *Logger
+
+	mu sync.Mutex
+	// readLine is used in interactive mode; the REPL replaces the default
+	// stdin reader with its line editor
+	readLine lineReader
+	// out receives the description of interactive requests
+	out io.Writer
+}
+
+// newElicitationHandler creates the handler for cfg, or returns nil if
+// elicitation is off
+func newElicitationHandler(cfg ElicitationConfig, logger *Logger) *elicitationHandler {
+	if cfg.Mode == "" || cfg.Mode == ElicitationOff {
+		return nil
+	}
+	return &elicitationHandler{
+		mode:     cfg.Mode,
+		answers:  cfg.Answers,
+		logger:   logger,
+		readLine: stdinLineReader(),
+		out:      os.Stdout,
+	}
+}
+
+// setLineReader replaces the interactive input
+func (h *elicitationHandler) setLineReader(readLine lineReader, out io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readLine = readLine
+	h.out = out
+}
+
+// Elicit implements client.ElicitationHandler
+func (h *elicitationHandler) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	h.logger.ServerRequest(string(mcp.MethodElicitationCreate), request.Params)
+
+	var response mcp.ElicitationResponse
+	var err error
+	if h.mode == ElicitationInteractive {
+		// One prompt at a time; concurrent requests wait their turn
+		h.mu.Lock()
+		response, err = h.ask(ctx, request.Params)
+		h.mu.Unlock()
+	} else {
+		response = h.answer(request.Params)
+	}
+	if err != nil {
+		h.logger.Warning("Elicitation request not answered: %v", err)
+		return nil, err
+	}
+
+	result := &mcp.ElicitationResult{ElicitationResponse: response}
+	h.logger.ServerRequestReply(string(mcp.MethodElicitationCreate), result)
+	return result, nil
+}
+
+// answer picks the first canned answer matching the request. Without a
+// match, the request is accepted with the schema defaults if they cover
+// every required field, and declined otherwise.
+func (h *elicitationHandler) answer(params mcp.ElicitationParams) mcp.ElicitationResponse {
+	fields := elicitationFields(params.RequestedSchema)
+	message := strings.ToLower(params.Message)
+
+	for _, answer := range h.answers {
+		if !strings.Contains(message, strings.ToLower(answer.Match)) {
+			continue
+		}
+		if answer.Action != mcp.ElicitationResponseActionAccept || params.Mode == mcp.ElicitationModeURL {
+			return mcp.ElicitationResponse{Action: answer.Action}
+		}
+		return mcp.ElicitationResponse{Action: answer.Action, Content: withSchemaDefaults(answer.Content, fields)}
+	}
+
+	if params.Mode == mcp.ElicitationModeURL {
+		return mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}
+	}
+	content := withSchemaDefaults(nil, fields)
+	for _, field := range fields {
+		if _, ok := content[field.Name]; field.Required && !ok {
+			return mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}
+		}
+	}
+	return mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionAccept, Content: content}
+}
+
+// withSchemaDefaults copies content and adds the default of every field it
+// does not set
+func withSchemaDefaults(content map[string]any, fields []elicitationField) map[string]any {
+	merged := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if field.Default != nil {
+			merged[field.Name] = field.Default
+		}
+	}
+	for name, value := range content {
+		merged[name] = value
+	}
+	return merged
+}
+
+// ask shows the request and reads the user's decision and field values
+func (h *elicitationHandler) ask(ctx context.Context, params mcp.ElicitationParams) (mcp.ElicitationResponse, error) {
+	cancel := mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionCancel}
+
+	_, _ = fmt.Fprintf(h.out, "\nThe server requests input (elicitation/create):\n  %s\n", params.Message)
+	if params.Mode == mcp.ElicitationModeURL {
+		_, _ = fmt.Fprintf(h.out, "  Open this URL to continue: %s\n", params.URL)
+	}
+
+	action, err := h.askAction(ctx)
+	if errors.Is(err, errInputInterrupted) {
+		return cancel, nil
+	}
+	if err != nil || action != mcp.ElicitationResponseActionAccept || params.Mode == mcp.ElicitationModeURL {
+		return mcp.ElicitationResponse{Action: action}, err
+	}
+
+	content := make(map[string]any)
+	for _, field := range elicitationFields(params.RequestedSchema) {
+		if field.Description != "" {
+			_, _ = fmt.Fprintf(h.out, "  %s\n", field.Description)
+		}
+		value, ok, err := h.askField(ctx, field)
+		if errors.Is(err, errInputInterrupted) {
+			return cancel, nil
+		}
+		if err != nil {
+			return cancel, err
+		}
+		if ok {
+			content[field.Name] = value
+		}
+	}
+	return mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionAccept, Content: content}, nil
+}
+
+// askAction asks whether to accept, decline or cancel the request
+func (h *elicitationHandler) askAction(ctx context.Context) (mcp.ElicitationResponseAction, error) {
+	for attempt := 0; attempt < maxElicitationAttempts; attempt++ {
+		input, err := h.readLine(ctx, "[a]ccept, [d]ecline or [c]ancel? ")
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "a", "accept", "":
+			return mcp.ElicitationResponseActionAccept, nil
+		case "d", "decline":
+			return mcp.ElicitationResponseActionDecline, nil
+		case "c", "cancel":
+			return mcp.ElicitationResponseActionCancel, nil
+		}
+	}
+	return mcp.ElicitationResponseActionCancel, nil
+}
+
+// askField reads the value of one field. An empty input takes the default,
+// or omits an optional field without one; ok is false when omitted.
+func (h *elicitationHandler) askField(ctx context.Context, field elicitationField) (value any, ok bool, err error) {
+	for attempt := 0; attempt < maxElicitationAttempts; attempt++ {
+		input, err := h.readLine(ctx, field.describe()+": ")
+		if err != nil {
+			return nil, false, err
+		}
+		input = strings.TrimSpace(input)
+
+		if input == "" {
+			if field.Default != nil {
+				return field.Default, true, nil
+			}
+			if !field.Required {
+				return nil, false, nil
+			}
+			_, _ = fmt.Fprintf(h.out, "  %s is required\n", field.Name)
+			continue
+		}
+
+		value, err := field.parseValue(input)
+		if err == nil {
+			return value, true, nil
+		}
+		_, _ = fmt.Fprintf(h.out, "  invalid value: %v\n", err)
+	}
+	return nil, false, fmt.Errorf("no valid value for %s after %d attempts", field.Name, maxElicitationAttempts)
+}
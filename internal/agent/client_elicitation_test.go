@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// deployParams is a form elicitation asking for a deployment confirmation
+func deployParams() mcp.ElicitationParams {
+	return mcp.ElicitationParams{
+		Message: "Confirm the deployment",
+		RequestedSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"replicas":    map[string]any{"type": "integer", "default": 2},
+				"environment": map[string]any{"type": "string", "enum": []any{"staging", "production"}},
+				"notify":      map[string]any{"type": "boolean", "description": "Send a notification"},
+			},
+			"required": []any{"environment"},
+		},
+	}
+}
+
+// scriptedInput returns a lineReader answering with lines in order
+func scriptedInput(t *testing.T, lines ...string) lineReader {
+	return func(ctx context.Context, prompt string) (string, error) {
+		if len(lines) == 0 {
+			t.Fatalf("unexpected prompt %q", prompt)
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	}
+}
+
+func TestElicitationFields(t *testing.T) {
+	fields := elicitationFields(deployParams().RequestedSchema)
+
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	if want := []string{"environment", "notify", "replicas"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("fields = %v, want required first, then by name: %v", names, want)
+	}
+	if !fields[0].Required || fields[0].describe() != "environment (staging|production, required)" {
+		t.Errorf("environment field: %+v, %q", fields[0], fields[0].describe())
+	}
+
+	for _, tc := range []struct {
+		field elicitationField
+		input string
+		want  any
+	}{
+		{elicitationField{Type: "integer"}, "3", int64(3)},
+		{elicitationField{Type: "number"}, "1.5", 1.5},
+		{elicitationField{Type: "boolean"}, "yes", true},
+		{elicitationField{Type: "string", Enum: []string{"a", "b"}}, "b", "b"},
+	} {
+		got, err := tc.field.parseValue(tc.input)
+		if err != nil || got != tc.want {
+			t.Errorf("parseValue(%q) = %v, %v; want %v", tc.input, got, err, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		field elicitationField
+		input string
+	}{
+		{elicitationField{Type: "integer"}, "three"},
+		{elicitationField{Type: "boolean"}, "maybe"},
+		{elicitationField{Type: "string", Enum: []string{"a"}}, "c"},
+	} {
+		if _, err := tc.field.parseValue(tc.input); err == nil {
+			t.Errorf("parseValue(%q) for %s: expected error", tc.input, tc.field.Type)
+		}
+	}
+}
+
+func TestElicitationAutoAnswers(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	h := newElicitationHandler(ElicitationConfig{Mode: ElicitationAuto, Answers: []ElicitationAnswer{
+		{Match: "DEPLOYMENT", Action: mcp.ElicitationResponseActionAccept, Content: map[string]any{"environment": "staging"}},
+		{Match: "delete", Action: mcp.ElicitationResponseActionDecline},
+	}}, logger)
+
+	got := h.answer(deployParams())
+	want := mcp.ElicitationResponse{
+		Action:  mcp.ElicitationResponseActionAccept,
+		Content: map[string]any{"environment": "staging", "replicas": float64(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matching answer = %+v, want %+v", got, want)
+	}
+
+	if got := h.answer(mcp.ElicitationParams{Message: "Delete everything?"}); got.Action != mcp.ElicitationResponseActionDecline {
+		t.Errorf("second rule: %+v", got)
+	}
+
+	// Without a matching rule, defaults are sent if they satisfy the schema
+	unmatched := deployParams()
+	unmatched.Message = "Something else"
+	if got := h.answer(unmatched); got.Action != mcp.ElicitationResponseActionDecline {
+		t.Errorf("required field without default: %+v, want decline", got)
+	}
+	optional := mcp.ElicitationParams{Message: "Tune", RequestedSchema: map[string]any{
+		"properties": map[string]any{"level": map[string]any{"type": "integer", "default": 1}},
+	}}
+	if got := h.answer(optional); got.Action != mcp.ElicitationResponseActionAccept || !reflect.DeepEqual(got.Content, map[string]any{"level": float64(1)}) {
+		t.Errorf("defaults only: %+v", got)
+	}
+}
+
+func TestElicitationInteractive(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	h := newElicitationHandler(ElicitationConfig{Mode: ElicitationInteractive}, logger)
+
+	var shown strings.Builder
+	// accept; environment invalid then valid; notify skipped; replicas default
+	h.setLineReader(scriptedInput(t, "a", "dev", "production", "", ""), &shown)
+	result, err := h.Elicit(context.Background(), mcp.ElicitationRequest{Params: deployParams()})
+	if err != nil {
+		t.Fatalf("Elicit: %v", err)
+	}
+	want := map[string]any{"environment": "production", "replicas": float64(2)}
+	if result.Action != mcp.ElicitationResponseActionAccept || !reflect.DeepEqual(result.Content, want) {
+		t.Errorf("result = %+v, want accept with %v", result.ElicitationResponse, want)
+	}
+	for _, text := range []string{"Confirm the deployment", "invalid value", "Send a notification"} {
+		if !strings.Contains(shown.String(), text) {
+			t.Errorf("output missing %q:\n%s", text, shown.String())
+		}
+	}
+
+	h.setLineReader(scriptedInput(t, "d"), io.Discard)
+	if result, _ := h.Elicit(context.Background(), mcp.ElicitationRequest{Params: deployParams()}); result.Action != mcp.ElicitationResponseActionDecline {
+		t.Errorf("declined: %+v", result)
+	}
+
+	h.setLineReader(func(ctx context.Context, prompt string) (string, error) {
+		return "", errInputInterrupted
+	}, io.Discard)
+	if result, _ := h.Elicit(context.Background(), mcp.ElicitationRequest{Params: deployParams()}); result.Action != mcp.ElicitationResponseActionCancel {
+		t.Errorf("interrupted: %+v", result)
+	}
+}
+
+func TestLoadElicitationAnswers(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "answers.json")
+	if err := os.WriteFile(valid, []byte(`[{"match":"deploy","action":"accept","content":{"ok":true}},{"action":"cancel"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	answers, err := LoadElicitationAnswers(valid)
+	if err != nil || len(answers) != 2 || answers[0].Content["ok"] != true {
+		t.Errorf("answers = %+v, err = %v", answers, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`[{"action":"maybe"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadElicitationAnswers(invalid); err == nil {
+		t.Error("expected error for invalid action")
+	}
+}
+
+func TestClientAnswersElicitationDuringToolCall(t *testing.T) {
+	mcpServer := server.NewMCPServer("elicitation-test", "1.0.0", server.WithToolCapabilities(false), server.WithElicitation())
+	mcpServer.AddTool(mcp.NewTool("deploy"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := mcpServer.RequestElicitation(ctx, mcp.ElicitationRequest{Params: deployParams()})
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		content, _ := result.Content.(map[string]any)
+		return mcp.NewToolResultText(string(result.Action) + " " + content["environment"].(string)), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(mcpServer)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+		Elicitation: ElicitationConfig{Mode: ElicitationAuto, Answers: []ElicitationAnswer{
+			{Action: mcp.ElicitationResponseActionAccept, Content: map[string]any{"environment": "staging"}},
+		}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	result, err := client.CallTool(ctx, "deploy", nil)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if err := ToolResultError(result); err != nil {
+		t.Fatalf("tool failed: %v", err)
+	}
+	if got := result.Content[0].(mcp.TextContent).Text; got != "accept staging" {
+		t.Errorf("tool result = %q", got)
+	}
+}
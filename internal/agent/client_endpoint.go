@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+)
+
+// ChangeEndpoint ends the session with the current server and connects to
+// endpoint instead, with a fresh OAuth flow if OAuth is enabled. The
+// caches, subscriptions and statistics are kept, so the changes show up
+// like those after a reconnect. If connecting fails, the client keeps the
+// new endpoint and Reconnect retries it. Custom headers and cookies stay
+// scoped to the origin of the configured endpoint.
+func (c *Client) ChangeEndpoint(ctx context.Context, endpoint string) error {
+	if c.offline {
+		return ErrOffline
+	}
+	if err := validateEndpoint(endpoint); err != nil {
+		return err
+	}
+
+	// Automatic reconnects, like the keepalive's once the old session is
+	// closed, wait for the switch instead of connecting on their own
+	attempt, err := c.beginReconnectAttempt(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.changeEndpoint(ctx, endpoint)
+	c.endReconnectAttempt(attempt, err)
+	return err
+}
+
+// changeEndpoint switches to endpoint while ChangeEndpoint holds the
+// reconnect attempt
+func (c *Client) changeEndpoint(ctx context.Context, endpoint string) error {
+	c.logger.Info("Changing endpoint from %s to %s...", c.currentEndpoint(), endpoint)
+	before := c.snapshotSurface()
+
+	// The old server's session cannot be resumed at the new endpoint, so it
+	// is ended rather than kept
+	c.stopResumedListener()
+	if previous := c.mcpClient(); previous != nil {
+		_ = previous.Close()
+	}
+	c.mu.Lock()
+	c.endpoint = endpoint
+	c.mu.Unlock()
+	if c.sessions != nil {
+		c.sessions.moved(endpoint)
+	}
+	if (len(c.config.Headers) > 0 || len(c.config.Cookies) > 0) && originOf(endpoint) != originOf(c.config.Endpoint) {
+		c.logger.Info("Custom headers and cookies are only sent to %s", originOf(c.config.Endpoint))
+	}
+	// A configured resource indicator names the old server; the new one is
+	// discovered or derived from the endpoint
+	c.mu.Lock()
+	if c.oauthConfig != nil && c.oauthConfig.ResourceURI != "" {
+		oauthConfig := *c.oauthConfig
+		oauthConfig.ResourceURI = ""
+		c.oauthConfig = &oauthConfig
+	}
+	c.mu.Unlock()
+
+	if err := c.connect(ctx); err != nil {
+		return classifyError(err)
+	}
+	c.reconnected(ctx, before)
+	return nil
+}
+
+// currentEndpoint returns the endpoint of the server, which ChangeEndpoint
+// may replace
+func (c *Client) currentEndpoint() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.endpoint
+}
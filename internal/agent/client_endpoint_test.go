@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestChangeEndpoint(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	newServer := func(name, tool string) string {
+		s := server.NewMCPServer(name, "1.0.0")
+		s.AddTool(mcp.NewTool(tool), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+		ts := server.NewTestStreamableHTTPServer(s)
+		t.Cleanup(ts.Close)
+		return ts.URL + "/mcp"
+	}
+	oldURL, newURL := newServer("old", "before"), newServer("new", "after")
+
+	c := NewClient(ClientConfig{
+		Endpoint:       oldURL,
+		Transport:      "streamable-http",
+		Logger:         NewLoggerWithWriter(false, false, false, io.Discard),
+		ResumeSessions: true,
+	})
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if err := c.ChangeEndpoint(ctx, "localhost:8090"); err == nil {
+		t.Error("expected an endpoint without scheme to be rejected")
+	}
+	if c.currentEndpoint() != oldURL {
+		t.Errorf("a rejected endpoint should not be kept, got %s", c.currentEndpoint())
+	}
+
+	if err := c.ChangeEndpoint(ctx, newURL); err != nil {
+		t.Fatalf("ChangeEndpoint: %v", err)
+	}
+	if info := c.ServerInfo(); info.Name != "new" {
+		t.Errorf("expected to be connected to the new server, got %+v", info)
+	}
+	// Custom headers stay scoped to the configured endpoint
+	if c.config.Endpoint != oldURL {
+		t.Errorf("expected the configured endpoint to be kept, got %s", c.config.Endpoint)
+	}
+	c.mu.RLock()
+	tools := c.toolCache
+	c.mu.RUnlock()
+	if len(tools) != 1 || tools[0].Name != "after" {
+		t.Errorf("expected the new server's tools, got %+v", tools)
+	}
+	if state := c.sessions.resumable(); state == nil || state.Endpoint != newURL {
+		t.Errorf("expected the new server's session to be tracked, got %+v", state)
+	}
+	result, err := c.CallTool(ctx, "after", nil)
+	if err != nil || result.Content[0].(mcp.TextContent).Text != "new" {
+		t.Errorf("CallTool = %+v, %v", result, err)
+	}
+}
+
+func TestChangeEndpointWaitsForReconnect(t *testing.T) {
+	fs := newFlakyServer(t)
+	c := newFlakyClient(t, fs, 1)
+	other := newFlakyServer(t)
+
+	// An automatic reconnect is in progress
+	running, err := c.beginReconnectAttempt(t.Context())
+	if err != nil {
+		t.Fatalf("beginReconnectAttempt: %v", err)
+	}
+	changed := make(chan error, 1)
+	go func() { changed <- c.ChangeEndpoint(t.Context(), other.url) }()
+
+	select {
+	case err := <-changed:
+		t.Fatalf("ChangeEndpoint did not wait for the reconnect in progress: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if c.currentEndpoint() != fs.url {
+		t.Errorf("endpoint changed during the reconnect: %s", c.currentEndpoint())
+	}
+
+	c.endReconnectAttempt(running, nil)
+	if err := <-changed; err != nil {
+		t.Fatalf("ChangeEndpoint: %v", err)
+	}
+	if c.currentEndpoint() != other.url {
+		t.Errorf("endpoint = %s, want %s", c.currentEndpoint(), other.url)
+	}
+}
+
+func TestChangeEndpointOffline(t *testing.T) {
+	c := NewOfflineClient(&CapabilitySnapshot{ServerInventory: ServerInventory{Server: ServerInfo{Name: "snapshotted"}}}, NewLoggerWithWriter(false, false, false, io.Discard))
+	if err := c.ChangeEndpoint(context.Background(), "http://localhost:8090/mcp"); !errors.Is(err, ErrOffline) {
+		t.Errorf("ChangeEndpoint error = %v, want ErrOffline", err)
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// reservedHeaders are managed by the transport and cannot be set with
+// --header
+var reservedHeaders = []string{
+	"Host",
+	"Content-Length",
+	transport.HeaderKeySessionID,
+	transport.HeaderKeyProtocolVersion,
+}
+
+// ParseHeaders parses "Name: value" entries into headers sent to the MCP
+// server. Repeating a name sends the header with several values.
+func ParseHeaders(entries []string) (http.Header, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	headers := make(http.Header, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q (expected 'Name: value')", entry)
+		}
+		for _, reserved := range reservedHeaders {
+			if strings.EqualFold(name, reserved) {
+				return nil, fmt.Errorf("header %s is set by the transport and cannot be overridden", reserved)
+			}
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// ParseCookies parses "name=value" entries into cookies sent to the MCP
+// server. An entry may hold several cookies separated by semicolons, as in
+// a Cookie header.
+func ParseCookies(entries []string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for _, entry := range entries {
+		parsed, err := http.ParseCookie(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie %q (expected name=value): %w", entry, err)
+		}
+		cookies = append(cookies, parsed...)
+	}
+	return cookies, nil
+}
+
+// headerRoundTripper adds custom headers and cookies to the requests to the
+// MCP server's origin. Requests to other hosts, like the authorization
+// server's, do not get them, so gateway API keys are not leaked.
+type headerRoundTripper struct {
+	base    http.RoundTripper
+	origin  string
+	headers http.Header
+	cookies []*http.Cookie
+}
+
+// newHeaderRoundTripper creates a headerRoundTripper for the origin of
+// endpoint
+func newHeaderRoundTripper(endpoint string, headers http.Header, cookies []*http.Cookie, base http.RoundTripper) *headerRoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &headerRoundTripper{
+		base:    base,
+		origin:  originOf(endpoint),
+		headers: headers,
+		cookies: cookies,
+	}
+}
+
+// RoundTrip implements http.RoundTripper. Headers the request already has,
+// like the Authorization header of an OAuth session, are kept.
+func (t *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.origin == "" || originOf(req.URL.String()) != t.origin {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	for _, cookie := range t.cookies {
+		req.AddCookie(cookie)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// originOf returns the scheme and host of rawURL, or "" if it does not parse
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// customHeaders wraps base in a headerRoundTripper if custom headers or
+// cookies are configured. They are scoped to the configured endpoint, so
+// ChangeEndpoint does not carry them to another server.
+func (c *Client) customHeaders(base http.RoundTripper) http.RoundTripper {
+	if len(c.config.Headers) == 0 && len(c.config.Cookies) == 0 {
+		return base
+	}
+	return newHeaderRoundTripper(c.config.Endpoint, c.config.Headers, c.config.Cookies, base)
+}
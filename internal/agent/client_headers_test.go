@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders([]string{
+		"X-Api-Key: secret",
+		"x-tenant:acme",
+		"X-Tenant: other",
+		"X-Empty:",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := http.Header{
+		"X-Api-Key": {"secret"},
+		"X-Tenant":  {"acme", "other"},
+		"X-Empty":   {""},
+	}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("ParseHeaders() = %v, want %v", headers, want)
+	}
+
+	for _, bad := range []string{"novalue", ": x", "X Api: x", "Mcp-Session-Id: abc", "host: example.com"} {
+		if _, err := ParseHeaders([]string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseCookies(t *testing.T) {
+	cookies, err := ParseCookies([]string{"session=abc", "a=1; b=2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, cookie := range cookies {
+		got = append(got, cookie.String())
+	}
+	if want := []string{"session=abc", "a=1", "b=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCookies() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseCookies([]string{"novalue"}); err == nil {
+		t.Error("expected error for a cookie without value")
+	}
+}
+
+func TestHeaderRoundTripper(t *testing.T) {
+	var received http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer ts.Close()
+
+	headers := http.Header{"X-Api-Key": {"secret"}, "Authorization": {"Bearer static"}}
+	cookies := []*http.Cookie{{Name: "session", Value: "abc"}}
+	rt := newHeaderRoundTripper(ts.URL+"/mcp", headers, cookies, nil)
+
+	send := func(url string, header http.Header) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	send(ts.URL+"/.well-known/oauth-protected-resource", nil)
+	if received.Get("X-Api-Key") != "secret" || received.Get("Authorization") != "Bearer static" || received.Get("Cookie") != "session=abc" {
+		t.Errorf("headers not added: %v", received)
+	}
+
+	// A header set by the client itself, like an OAuth token, wins
+	send(ts.URL+"/mcp", http.Header{"Authorization": {"Bearer oauth"}})
+	if got := received.Get("Authorization"); got != "Bearer oauth" {
+		t.Errorf("Authorization = %q, want the request's own", got)
+	}
+
+	// Other origins, like the authorization server, get nothing
+	rt = newHeaderRoundTripper("https://mcp.example.com/mcp", headers, cookies, nil)
+	send(ts.URL+"/token", nil)
+	if received.Get("X-Api-Key") != "" || received.Get("Cookie") != "" {
+		t.Errorf("headers leaked to another origin: %v", received)
+	}
+}
+
+func TestClientCustomHeaders(t *testing.T) {
+	upstream := server.NewStreamableHTTPServer(server.NewMCPServer("gateway", "1.0.0"))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The gateway in front of the server wants an API key and a tenant
+		if r.Header.Get("X-Api-Key") != "secret" {
+			http.Error(w, "missing API key", http.StatusUnauthorized)
+			return
+		}
+		if cookie, err := r.Cookie("tenant"); err != nil || cookie.Value != "acme" {
+			http.Error(w, "missing tenant", http.StatusForbidden)
+			return
+		}
+		upstream.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+		Headers:   http.Header{"X-Api-Key": {"secret"}},
+		Cookies:   []*http.Cookie{{Name: "tenant", Value: "acme"}},
+	})
+	if err := c.Run(t.Context()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = c.Close() }()
+	if _, err := c.Ping(t.Context()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+// HealthStatus summarises the state of the connection
+type HealthStatus string
+
+const (
+	// HealthHealthy means the last ping, if any, succeeded
+	HealthHealthy HealthStatus = "healthy"
+	// HealthDegraded means recent pings failed but the failure threshold
+	// for reconnecting has not been reached
+	HealthDegraded HealthStatus = "degraded"
+	// HealthReconnecting means the connection was lost and is being
+	// re-established
+	HealthReconnecting HealthStatus = "reconnecting"
+	// HealthDisconnected means no connection has been established
+	HealthDisconnected HealthStatus = "disconnected"
+)
+
+// ConnectionHealth describes the connection to the server
+type ConnectionHealth struct {
+	Status HealthStatus
+	// Uptime is the time since the first connection was established
+	Uptime time.Duration
+	// SessionAge is the time since the current session was established,
+	// which differs from Uptime after a reconnect
+	SessionAge time.Duration
+	// Reconnects counts the sessions re-established after the first
+	Reconnects int
+	// PingInterval is the keepalive interval; zero if pings are only sent
+	// on request
+	PingInterval time.Duration
+	Ping         PingStats
+	// PendingRequests is the number of requests awaiting a response and
+	// QueuedRequests those waiting for an in-flight slot
+	PendingRequests int
+	QueuedRequests  int
+}
+
+// uptimeTracker records when the connection and the current session were
+// established; safe for concurrent use
+type uptimeTracker struct {
+	mu             sync.Mutex
+	connectedAt    time.Time
+	sessionStarted time.Time
+	reconnects     int
+}
+
+// connected records the first connection
+func (t *uptimeTracker) connected() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.connectedAt = time.Now()
+	t.sessionStarted = t.connectedAt
+}
+
+// reconnected records a session re-established after the first
+func (t *uptimeTracker) reconnected() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if t.connectedAt.IsZero() {
+		t.connectedAt = now
+	}
+	t.sessionStarted = now
+	t.reconnects++
+}
+
+// Health returns a snapshot of the connection's health
+func (c *Client) Health() ConnectionHealth {
+	c.uptime.mu.Lock()
+	connectedAt, sessionStarted, reconnects := c.uptime.connectedAt, c.uptime.sessionStarted, c.uptime.reconnects
+	c.uptime.mu.Unlock()
+
+	queue := c.RequestQueueStats()
+	health := ConnectionHealth{
+		Reconnects:      reconnects,
+		PingInterval:    c.pingInterval,
+		Ping:            c.PingStats(),
+		PendingRequests: queue.InFlight,
+		QueuedRequests:  queue.Queued,
+	}
+	if !connectedAt.IsZero() {
+		health.Uptime = time.Since(connectedAt)
+		health.SessionAge = time.Since(sessionStarted)
+	}
+
+	c.reconnectMu.Lock()
+	reconnecting := c.reconnecting != nil
+	c.reconnectMu.Unlock()
+
+	switch {
+	case reconnecting:
+		health.Status = HealthReconnecting
+	case connectedAt.IsZero():
+		health.Status = HealthDisconnected
+	case health.Ping.ConsecutiveFailures > 0:
+		health.Status = HealthDegraded
+	default:
+		health.Status = HealthHealthy
+	}
+	return health
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientHealth(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+
+	if health := c.Health(); health.Status != HealthDisconnected || health.Uptime != 0 {
+		t.Errorf("before connecting: %+v", health)
+	}
+
+	c.uptime.connected()
+	c.pingTracker.record(10*time.Millisecond, nil)
+	c.pingTracker.record(30*time.Millisecond, nil)
+	health := c.Health()
+	if health.Status != HealthHealthy || health.Uptime <= 0 || health.Reconnects != 0 {
+		t.Errorf("after connecting: %+v", health)
+	}
+	if health.Ping.LastRTT != 30*time.Millisecond || health.Ping.AvgRTT != 20*time.Millisecond {
+		t.Errorf("RTT = %v, average %v", health.Ping.LastRTT, health.Ping.AvgRTT)
+	}
+
+	c.pingTracker.record(0, errors.New("timeout"))
+	if health := c.Health(); health.Status != HealthDegraded || health.Ping.AvgRTT != 20*time.Millisecond {
+		t.Errorf("after a failed ping: %+v", health)
+	}
+
+	c.reconnectMu.Lock()
+	c.reconnecting = &reconnectAttempt{done: make(chan struct{})}
+	c.reconnectMu.Unlock()
+	if health := c.Health(); health.Status != HealthReconnecting {
+		t.Errorf("while reconnecting: status %s", health.Status)
+	}
+	c.reconnectMu.Lock()
+	c.reconnecting = nil
+	c.reconnectMu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	c.uptime.reconnected()
+	c.pingTracker.resetFailures()
+	health = c.Health()
+	if health.Status != HealthHealthy || health.Reconnects != 1 || health.SessionAge >= health.Uptime {
+		t.Errorf("after reconnecting: %+v", health)
+	}
+}
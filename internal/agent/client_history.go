@@ -0,0 +1,288 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxHistorySnapshots bounds the snapshots kept in memory per list kind; the
+// oldest are dropped first
+const maxHistorySnapshots = 100
+
+// History list kinds
+const (
+	historyTools     = "tools"
+	historyResources = "resources"
+	historyPrompts   = "prompts"
+)
+
+// listSnapshot is the content of one list as fetched from the server: the
+// JSON definition of each entry keyed by name (URI for resources). It is
+// also the line format of the capability history file.
+type listSnapshot struct {
+	Time     time.Time         `json:"time"`
+	Endpoint string            `json:"endpoint"`
+	Kind     string            `json:"kind"`
+	Items    map[string]string `json:"items"`
+}
+
+// HistoryChange is how an entry differs from the previous snapshot
+type HistoryChange string
+
+const (
+	HistoryAppeared    HistoryChange = "appeared"
+	HistoryDisappeared HistoryChange = "disappeared"
+	HistoryChanged     HistoryChange = "changed"
+)
+
+// HistoryEvent is one change of a tool, resource or prompt between two
+// consecutive snapshots of its list
+type HistoryEvent struct {
+	Time   time.Time
+	Name   string
+	Change HistoryChange
+	// Fields are the top-level fields of a changed definition that differ,
+	// e.g. description or inputSchema
+	Fields []string
+	// Details describe the changes of a tool's definition down to its
+	// input schema properties, e.g. "~ count: string → integer"
+	Details []string
+}
+
+// capabilityHistory keeps a timeline of list snapshots, storing a snapshot
+// only when the list differs from the previous one. With a file, snapshots
+// are appended as JSON lines and earlier sessions with the same endpoint are
+// loaded on first use, so changes between sessions are detected too.
+type capabilityHistory struct {
+	endpoint string
+	file     string
+
+	mu        sync.Mutex
+	loaded    bool
+	snapshots map[string][]listSnapshot
+	// onChange is called with the events of each snapshot that differs from
+	// a previous one; nil if nobody listens
+	onChange func(kind string, events []HistoryEvent)
+}
+
+// newCapabilityHistory creates the history of one endpoint; file may be
+// empty to keep it in memory only
+func newCapabilityHistory(endpoint, file string) *capabilityHistory {
+	return &capabilityHistory{
+		endpoint:  endpoint,
+		file:      file,
+		snapshots: make(map[string][]listSnapshot),
+	}
+}
+
+// record adds a snapshot of a list if it differs from the previous one and
+// reports whether it did. The snapshot is kept in memory even if writing it
+// to the file fails.
+func (h *capabilityHistory) record(kind string, items map[string]string, now time.Time) (bool, error) {
+	h.mu.Lock()
+	loadErr := h.loadLocked()
+
+	previous := h.snapshots[kind]
+	if len(previous) > 0 && maps.Equal(previous[len(previous)-1].Items, items) {
+		h.mu.Unlock()
+		return false, loadErr
+	}
+
+	snapshot := listSnapshot{Time: now, Endpoint: h.endpoint, Kind: kind, Items: items}
+	h.append(snapshot)
+	err := errors.Join(loadErr, h.persist(snapshot))
+	onChange := h.onChange
+	h.mu.Unlock()
+
+	// The listener runs unlocked so it may read the history
+	if onChange != nil && len(previous) > 0 {
+		last := previous[len(previous)-1]
+		onChange(kind, historyEvents([]listSnapshot{last, snapshot}))
+	}
+	return true, err
+}
+
+// setOnChange sets the listener for snapshots that differ from a previous
+// one
+func (h *capabilityHistory) setOnChange(onChange func(kind string, events []HistoryEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onChange = onChange
+}
+
+// append adds a snapshot in memory, dropping the oldest beyond the limit
+func (h *capabilityHistory) append(snapshot listSnapshot) {
+	snapshots := append(h.snapshots[snapshot.Kind], snapshot)
+	if len(snapshots) > maxHistorySnapshots {
+		snapshots = snapshots[len(snapshots)-maxHistorySnapshots:]
+	}
+	h.snapshots[snapshot.Kind] = snapshots
+}
+
+// loadLocked reads the snapshots of this endpoint from the history file
+// once; a missing file is an empty history
+func (h *capabilityHistory) loadLocked() error {
+	if h.loaded || h.file == "" {
+		return nil
+	}
+	h.loaded = true
+
+	f, err := os.Open(h.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read capability history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var snapshot listSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return fmt.Errorf("failed to parse capability history %s:%d: %w", h.file, line, err)
+		}
+		if snapshot.Endpoint == h.endpoint {
+			h.append(snapshot)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read capability history: %w", err)
+	}
+	return nil
+}
+
+// persist appends a snapshot to the history file
+func (h *capabilityHistory) persist(snapshot listSnapshot) error {
+	if h.file == "" {
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write capability history: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write capability history: %w", err)
+	}
+	return nil
+}
+
+// timeline returns the snapshots of a list kind, oldest first
+func (h *capabilityHistory) timeline(kind string) []listSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.snapshots[kind])
+}
+
+// historyEvents lists the changes between consecutive snapshots, oldest
+// first. Entries of the first snapshot are not events; they are the
+// baseline.
+func historyEvents(snapshots []listSnapshot) []HistoryEvent {
+	var events []HistoryEvent
+	for i := 1; i < len(snapshots); i++ {
+		before, after := snapshots[i-1].Items, snapshots[i].Items
+		at := snapshots[i].Time
+
+		for _, name := range slices.Sorted(maps.Keys(after)) {
+			old, existed := before[name]
+			switch {
+			case !existed:
+				events = append(events, HistoryEvent{Time: at, Name: name, Change: HistoryAppeared})
+			case old != after[name]:
+				event := HistoryEvent{Time: at, Name: name, Change: HistoryChanged, Fields: changedFields(old, after[name])}
+				if snapshots[i].Kind == historyTools {
+					event.Details = toolChanges(decodeDefinition(old), decodeDefinition(after[name]))
+				}
+				events = append(events, event)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(before)) {
+			if _, exists := after[name]; !exists {
+				events = append(events, HistoryEvent{Time: at, Name: name, Change: HistoryDisappeared})
+			}
+		}
+	}
+	return events
+}
+
+// changedFields returns the top-level fields that differ between two JSON
+// definitions
+func changedFields(before, after string) []string {
+	var old, updated map[string]any
+	if json.Unmarshal([]byte(before), &old) != nil || json.Unmarshal([]byte(after), &updated) != nil {
+		return nil
+	}
+
+	var fields []string
+	for key := range maps.Keys(old) {
+		if !reflect.DeepEqual(old[key], updated[key]) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range maps.Keys(updated) {
+		if _, ok := old[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// decodeDefinition decodes an encoded snapshot entry
+func decodeDefinition(encoded string) map[string]any {
+	var definition map[string]any
+	_ = json.Unmarshal([]byte(encoded), &definition)
+	return definition
+}
+
+// listItems encodes list entries for a snapshot, keyed by name
+func listItems[T any](entries []T, key func(T) string) map[string]string {
+	items := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		items[key(entry)] = encodeForDiff(entry)
+	}
+	return items
+}
+
+// recordHistory adds a snapshot of a list that was just fetched to the
+// capability history
+func (c *Client) recordHistory(kind string, items map[string]string) {
+	if c.history == nil {
+		return
+	}
+	if _, err := c.history.record(kind, items, time.Now()); err != nil {
+		c.logger.Warning("Capability history: %v", err)
+	}
+}
+
+// CapabilityHistory returns the snapshots of a list kind (tools, resources
+// or prompts) recorded so far and the changes between them, oldest first
+func (c *Client) CapabilityHistory(kind string) (snapshots int, since time.Time, events []HistoryEvent) {
+	if c.history == nil {
+		return 0, time.Time{}, nil
+	}
+	timeline := c.history.timeline(kind)
+	if len(timeline) == 0 {
+		return 0, time.Time{}, nil
+	}
+	return len(timeline), timeline[0].Time, historyEvents(timeline)
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCapabilityHistory(t *testing.T) {
+	stub := &stubMCPClient{tools: []mcp.Tool{
+		{Name: "echo", Description: "Echo a message"},
+		{Name: "legacy"},
+	}}
+	c := newToolsCacheClient(t, stub, 0)
+
+	// Re-listing an unchanged list records nothing
+	if err := c.listTools(t.Context(), false); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	if snapshots, _, events := c.CapabilityHistory(historyTools); snapshots != 1 || len(events) != 0 {
+		t.Fatalf("expected one snapshot without events, got %d snapshots and %v", snapshots, events)
+	}
+
+	stub.tools = []mcp.Tool{
+		{Name: "echo", Description: "Echo a message back"},
+		{Name: "search"},
+	}
+	if err := c.listTools(t.Context(), false); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+
+	snapshots, _, events := c.CapabilityHistory(historyTools)
+	if snapshots != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", snapshots)
+	}
+	got := make([]string, 0, len(events))
+	for _, event := range events {
+		got = append(got, event.Name+" "+string(event.Change)+" "+strings.Join(event.Fields, ","))
+	}
+	want := []string{"echo changed description", "search appeared ", "legacy disappeared "}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if !slices.Equal(events[0].Details, []string{"description changed"}) {
+		t.Errorf("unexpected details of echo: %q", events[0].Details)
+	}
+
+	if snapshots, _, _ := c.CapabilityHistory(historyPrompts); snapshots != 0 {
+		t.Errorf("expected no prompt snapshots, got %d", snapshots)
+	}
+}
+
+func TestCapabilityHistoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first := newCapabilityHistory("http://a/mcp", file)
+	if _, err := first.record(historyTools, map[string]string{"echo": `{"name":"echo"}`}, start); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	other := newCapabilityHistory("http://b/mcp", file)
+	if _, err := other.record(historyTools, map[string]string{"other": `{"name":"other"}`}, start); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	// A later session with the same endpoint continues the timeline
+	second := newCapabilityHistory("http://a/mcp", file)
+	recorded, err := second.record(historyTools, map[string]string{"echo": `{"name":"echo"}`}, start.Add(time.Hour))
+	if err != nil || recorded {
+		t.Fatalf("expected unchanged list to be skipped, got recorded=%v err=%v", recorded, err)
+	}
+	if _, err := second.record(historyTools, map[string]string{}, start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	timeline := second.timeline(historyTools)
+	if len(timeline) != 2 || !timeline[0].Time.Equal(start) {
+		t.Fatalf("expected the earlier session's snapshot first, got %+v", timeline)
+	}
+	events := historyEvents(timeline)
+	if len(events) != 1 || events[0].Name != "echo" || events[0].Change != HistoryDisappeared {
+		t.Errorf("expected echo to disappear, got %+v", events)
+	}
+}
+
+func TestCapabilityHistoryLimit(t *testing.T) {
+	h := newCapabilityHistory("http://a/mcp", "")
+	start := time.Now()
+	for i := range maxHistorySnapshots + 5 {
+		items := map[string]string{"tool": strings.Repeat("x", i)}
+		if _, err := h.record(historyTools, items, start.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+	timeline := h.timeline(historyTools)
+	if len(timeline) != maxHistorySnapshots {
+		t.Fatalf("expected %d snapshots, got %d", maxHistorySnapshots, len(timeline))
+	}
+	if !timeline[0].Time.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("expected the oldest snapshots to be dropped, first is %v", timeline[0].Time)
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	before := `{"name":"echo","description":"a","inputSchema":{"type":"object"}}`
+	after := `{"name":"echo","inputSchema":{"type":"object","required":["x"]},"annotations":{"readOnlyHint":true}}`
+	if got, want := changedFields(before, after), []string{"annotations", "description", "inputSchema"}; !slices.Equal(got, want) {
+		t.Errorf("changedFields = %q, want %q", got, want)
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	events := []HistoryEvent{
+		{Time: at, Name: "echo", Change: HistoryChanged, Fields: []string{"description", "inputSchema"}},
+		{Time: at, Name: "search", Change: HistoryAppeared},
+	}
+
+	var out strings.Builder
+	writeHistory(&out, historyTools, "", 2, at, events)
+	want := "Changes to tools since 2026-01-02 03:04:05 (2 snapshot(s)):\n" +
+		"  2026-01-02 03:04:05  ~ echo changed: description, inputSchema\n" +
+		"  2026-01-02 03:04:05  + search appeared\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	writeHistory(&out, historyTools, "legacy", 2, at, events)
+	if !strings.HasPrefix(out.String(), "No changes to legacy since") {
+		t.Errorf("unexpected output for unchanged entry: %q", out.String())
+	}
+
+	out.Reset()
+	writeHistory(&out, historyPrompts, "", 0, time.Time{}, nil)
+	if out.String() != "No prompts have been listed yet.\n" {
+		t.Errorf("unexpected output without snapshots: %q", out.String())
+	}
+}
@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// Request timeouts by purpose. Requests to the MCP endpoint have none, as
+// their streams stay open for the session.
+const (
+	// metadataRequestTimeout bounds protected resource, authorization
+	// server and client ID metadata requests and JWKS fetches
+	metadataRequestTimeout = 10 * time.Second
+	// oauthRequestTimeout bounds token, registration, device, revocation
+	// and introspection requests
+	oauthRequestTimeout = 30 * time.Second
+)
+
+// userAgent identifies mcp-debug in OAuth and discovery requests
+const userAgent = "mcp-debug/1.0"
+
+// httpClients builds the HTTP clients of a connection. All of them use a
+// shared transport, and with it the proxy, TLS and network settings, and
+// get their timeout, user agent, custom headers, step mode, exchange logging,
+// traffic recording, retries and metadata caching here rather than at each
+// call site.
+type httpClients struct {
+	// headers adds the custom headers and cookies for the origin of the MCP
+	// endpoint; nil for none
+	headers func(http.RoundTripper) http.RoundTripper
+	// stepper pauses requests in step mode; nil otherwise
+	stepper *oauthStepper
+	// logger logs the OAuth exchanges marked with withOAuthExchangeLog; nil
+	// disables exchange logging
+	logger *Logger
+	// traffic records the exchanges; nil disables recording
+	traffic *TrafficRecorder
+	// sessions tracks the session and event IDs of MCP requests; nil when
+	// session resumption is off
+	sessions *sessionTracker
+	// metadata answers repeated metadata requests; nil fetches every time
+	metadata *metadataCache
+	// retry repeats requests failing transiently; nil disables retries
+	retry *retryPolicy
+	// rateLimits records the rate limiting of MCP responses; nil for none
+	rateLimits *rateLimitTracker
+}
+
+// defaultHTTPClients serve requests made outside a connection, such as the
+// discovery report and the JWKS fetches of server mode. They bypass the
+// metadata cache, so the discovery report always shows the live documents.
+var defaultHTTPClients = &httpClients{}
+
+// httpClients returns the HTTP client factory of the connection
+func (c *Client) httpClients() *httpClients {
+	return &httpClients{
+		headers:  c.customHeaders,
+		stepper:  c.oauthStepper,
+		logger:   c.logger,
+		traffic:  c.traffic,
+		sessions: c.sessions,
+		metadata: sharedMetadataCache,
+		retry:    c.retryPolicy,
+
+		rateLimits: c.rateLimits,
+	}
+}
+
+// oauthTransport returns the round tripper of OAuth and discovery requests.
+// Step mode sits innermost, so it shows requests as they are sent. The
+// custom headers reach a protected resource metadata endpoint behind the
+// same gateway as the MCP server.
+func (h *httpClients) oauthTransport() http.RoundTripper {
+	var rt http.RoundTripper = sharedOAuthTransport
+	if h.stepper != nil {
+		rt = h.stepper.RoundTripper(rt)
+	}
+	rt = &userAgentRoundTripper{base: rt}
+	if h.logger != nil {
+		rt = &exchangeLogRoundTripper{base: rt, logger: h.logger}
+	}
+	if h.headers != nil {
+		rt = h.headers(rt)
+	}
+	return rt
+}
+
+// mcpTransport returns the round tripper of requests to the MCP endpoint
+func (h *httpClients) mcpTransport() http.RoundTripper {
+	var rt http.RoundTripper = sharedMCPTransport
+	if h.stepper != nil {
+		rt = h.stepper.MCPRoundTripper(rt)
+	}
+	if h.headers != nil {
+		rt = h.headers(rt)
+	}
+	rt = h.record(rt)
+	// Inside the retries, so every rejected attempt is counted
+	if h.rateLimits != nil {
+		rt = &rateLimitRoundTripper{base: rt, tracker: h.rateLimits}
+	}
+	rt = h.retried(rt, false)
+	if h.sessions != nil {
+		rt = h.sessions.roundTripper(rt)
+	}
+	return rt
+}
+
+// record wraps rt in traffic recording if it is enabled
+func (h *httpClients) record(rt http.RoundTripper) http.RoundTripper {
+	if h.traffic == nil {
+		return rt
+	}
+	return h.traffic.RoundTripper(rt)
+}
+
+// retried wraps rt in the retry policy if there is one. It goes outside
+// traffic recording, so every attempt is recorded. idempotentOnly limits
+// retries to GET and HEAD requests.
+func (h *httpClients) retried(rt http.RoundTripper, idempotentOnly bool) http.RoundTripper {
+	if h.retry == nil {
+		return rt
+	}
+	return &retryRoundTripper{base: rt, policy: h.retry, logger: h.logger, idempotentOnly: idempotentOnly}
+}
+
+// cached wraps rt in the metadata cache if it is enabled. It goes outside
+// traffic recording, so only requests that reach the network are recorded.
+func (h *httpClients) cached(rt http.RoundTripper) http.RoundTripper {
+	if h.metadata == nil {
+		return rt
+	}
+	return &metadataCacheRoundTripper{base: rt, cache: h.metadata, logger: h.logger}
+}
+
+// oauthClient returns a client for OAuth and discovery requests
+func (h *httpClients) oauthClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: h.cached(h.retried(h.record(h.oauthTransport()), true)),
+	}
+}
+
+// httpClientsKey is the context key of the HTTP clients of a connection
+type httpClientsKey struct{}
+
+// withHTTPClients returns a context whose OAuth and discovery requests made
+// outside mcp-go's handler use the clients of h; nil leaves ctx unchanged
+func withHTTPClients(ctx context.Context, h *httpClients) context.Context {
+	if h == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, httpClientsKey{}, h)
+}
+
+// httpClientFor returns a client for OAuth and discovery requests made with
+// ctx: one of the connection ctx belongs to, or a default one
+func httpClientFor(ctx context.Context, timeout time.Duration) *http.Client {
+	h, ok := ctx.Value(httpClientsKey{}).(*httpClients)
+	if !ok {
+		h = defaultHTTPClients
+	}
+	return h.oauthClient(timeout)
+}
+
+// userAgentRoundTripper sets the user agent of requests that have none
+type userAgentRoundTripper struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// sessionPoster posts raw JSON-RPC messages within a client's session,
+// outside mcp-go, for callers that need the response as it arrives
+type sessionPoster struct {
+	client          *http.Client
+	endpoint        string
+	sessionID       string
+	protocolVersion string
+	authorization   func(ctx context.Context) (string, error)
+}
+
+// sessionPoster returns a poster for the client's session. It shares the
+// transport of the MCP requests without retries and session tracking, as a
+// raw message is sent exactly once and its responses are not part of the
+// session's event stream. purpose names the caller in errors.
+func (c *Client) sessionPoster(purpose string) (*sessionPoster, error) {
+	mcpClient, ok := c.mcpClient().(*client.Client)
+	if !ok {
+		return nil, fmt.Errorf("%s %w", purpose, errNotStreamable)
+	}
+	tracking, ok := mcpClient.GetTransport().(*requestTrackingTransport)
+	if !ok {
+		return nil, fmt.Errorf("%s %w", purpose, errNotStreamable)
+	}
+
+	clients := c.httpClients()
+	clients.retry = nil
+	clients.sessions = nil
+	poster := &sessionPoster{
+		client:          &http.Client{Transport: clients.mcpTransport()},
+		endpoint:        c.currentEndpoint(),
+		sessionID:       tracking.GetSessionId(),
+		protocolVersion: c.ServerInfo().ProtocolVersion,
+	}
+	if c.oauthHandler != nil {
+		poster.authorization = c.oauthHandler.GetAuthorizationHeader
+	}
+	return poster, nil
+}
+
+// do posts body with the session's headers. The caller closes the body of
+// the response, which may be a JSON document or an SSE stream.
+func (s *sessionPoster) do(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if s.sessionID != "" {
+		req.Header.Set(transport.HeaderKeySessionID, s.sessionID)
+	}
+	if s.protocolVersion != "" {
+		req.Header.Set(transport.HeaderKeyProtocolVersion, s.protocolVersion)
+	}
+	if s.authorization != nil {
+		authorization, err := s.authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", authorization)
+	}
+	return s.client.Do(req)
+}
+
+// errNotStreamable is returned by sessionPoster for clients that do not
+// speak streamable HTTP, such as test stubs
+var errNotStreamable = errors.New("needs a connected streamable HTTP client")
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientsApplyConnectionSettings(t *testing.T) {
+	var gotKey, gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey, gotAgent = r.Header.Get("X-Api-Key"), r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resource":"https://example.com","authorization_servers":["https://auth.example.com"]}`)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	c := NewClient(ClientConfig{
+		Endpoint:  server.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, &logs),
+		Headers:   http.Header{"X-Api-Key": {"k"}},
+	})
+	ctx := withOAuthExchangeLog(withHTTPClients(t.Context(), c.httpClients()))
+
+	if _, err := fetchProtectedResourceMetadata(ctx, server.URL+"/.well-known/oauth-protected-resource"); err != nil {
+		t.Fatal(err)
+	}
+	if gotKey != "k" || gotAgent != userAgent {
+		t.Errorf("expected the custom header and user agent on discovery, got %q and %q", gotKey, gotAgent)
+	}
+	if !strings.Contains(logs.String(), "GET /.well-known/oauth-protected-resource HTTP/1.1") {
+		t.Errorf("expected the discovery exchange to be logged:\n%s", logs.String())
+	}
+}
+
+func TestHTTPClientForDefaults(t *testing.T) {
+	var gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	client := httpClientFor(t.Context(), metadataRequestTimeout)
+	if client.Timeout != metadataRequestTimeout {
+		t.Errorf("got timeout %v", client.Timeout)
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "custom/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if gotAgent != "custom/1.0" {
+		t.Errorf("expected an explicit user agent to be kept, got %q", gotAgent)
+	}
+}
+
+func TestHTTPClientsUseMetadataCache(t *testing.T) {
+	server, requests := newMetadataTestServer(t, "")
+	t.Cleanup(func() { sharedMetadataCache = nil })
+	ConfigureMetadataCache(MetadataCacheConfig{TTL: time.Minute}, NewLogger(false, false, false))
+
+	c := NewClient(ClientConfig{Endpoint: server.URL + "/mcp", Transport: "streamable-http", Logger: NewLogger(false, false, false)})
+	metadataURL := server.URL + "/.well-known/oauth-protected-resource"
+	for range 2 {
+		if _, err := fetchProtectedResourceMetadata(withHTTPClients(t.Context(), c.httpClients()), metadataURL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the connection to reuse the cached metadata, got %d requests", n)
+	}
+
+	// The discovery report shows the live documents
+	if _, err := fetchProtectedResourceMetadata(t.Context(), metadataURL); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected the default clients to bypass the cache, got %d requests", n)
+	}
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultHTTPRetryMaxAttempts is the number of attempts of an HTTP
+	// request failing with a transient error, including the first
+	DefaultHTTPRetryMaxAttempts = 3
+	// DefaultHTTPRetryInitialDelay is the wait after the first failed attempt
+	DefaultHTTPRetryInitialDelay = 500 * time.Millisecond
+	// DefaultHTTPRetryMaxDelay caps the exponentially growing wait between
+	// attempts and the Retry-After the client is willing to honor
+	DefaultHTTPRetryMaxDelay = 10 * time.Second
+)
+
+// DefaultHTTPRetryStatuses are the status codes of rate limiting and
+// unavailable gateways or servers that are retried
+var DefaultHTTPRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// unprocessedStatuses are the retried status codes that tell the request
+// was not processed. A 502 or 504 may come from a gateway that already
+// forwarded a tools/call, so other requests than GET and HEAD are only
+// retried on these.
+var unprocessedStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusServiceUnavailable,
+}
+
+// retryPolicy paces the attempts of HTTP requests failing transiently
+type retryPolicy struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	statuses     []int
+}
+
+// newRetryPolicy applies the defaults to the configured policy; nil
+// disables retries
+func newRetryPolicy(cfg ClientConfig) *retryPolicy {
+	p := &retryPolicy{
+		maxAttempts:  cfg.HTTPRetryMaxAttempts,
+		initialDelay: cfg.HTTPRetryInitialDelay,
+		maxDelay:     cfg.HTTPRetryMaxDelay,
+		statuses:     cfg.HTTPRetryStatuses,
+	}
+	if p.maxAttempts == 0 {
+		p.maxAttempts = DefaultHTTPRetryMaxAttempts
+	}
+	if p.maxAttempts <= 1 {
+		return nil
+	}
+	if p.initialDelay <= 0 {
+		p.initialDelay = DefaultHTTPRetryInitialDelay
+	}
+	if p.maxDelay <= 0 {
+		p.maxDelay = DefaultHTTPRetryMaxDelay
+	}
+	if p.maxDelay < p.initialDelay {
+		p.maxDelay = p.initialDelay
+	}
+	if p.statuses == nil {
+		p.statuses = DefaultHTTPRetryStatuses
+	}
+	return p
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date, and returns false if there is none
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryRoundTripper repeats requests failing with one of the retried status
+// codes, and idempotent requests failing without a response. Requests with
+// other methods are repeated only for unprocessedStatuses, so a tool with
+// side effects does not run twice. Each failed attempt is logged.
+type retryRoundTripper struct {
+	base   http.RoundTripper
+	policy *retryPolicy
+	logger *Logger
+	// idempotentOnly restricts retries to GET and HEAD requests, for OAuth
+	// requests that must not be repeated such as token requests
+	idempotentOnly bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	// A body that cannot be replayed leaves a single attempt
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if (t.idempotentOnly && !idempotent) || !replayable {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.policy.maxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := exponentialBackoff(t.policy.initialDelay, t.policy.maxDelay, attempt)
+		var failure string
+		switch {
+		case err != nil && idempotent:
+			failure = err.Error()
+		case err != nil:
+			return nil, err
+		case slices.Contains(t.policy.statuses, resp.StatusCode) && (idempotent || slices.Contains(unprocessedStatuses, resp.StatusCode)):
+			failure = fmt.Sprintf("status %d", resp.StatusCode)
+			if after, ok := retryAfter(resp.Header, time.Now()); ok {
+				if after > t.policy.maxDelay {
+					t.logger.Warning("%s %s: %s with Retry-After %s exceeding the maximum retry delay, giving up", req.Method, req.URL.Redacted(), failure, after)
+					return resp, nil
+				}
+				wait = after
+			}
+		default:
+			return resp, nil
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxOAuthResponseSize))
+			_ = resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, errors.Join(fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), failure), bodyErr)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		t.logger.Warning("%s %s: %s (attempt %d/%d, retrying in %v)", req.Method, req.URL.Redacted(), failure, attempt, t.policy.maxAttempts, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
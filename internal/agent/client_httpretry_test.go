@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	p := newRetryPolicy(ClientConfig{})
+	if p == nil || p.maxAttempts != DefaultHTTPRetryMaxAttempts || p.initialDelay != DefaultHTTPRetryInitialDelay || p.maxDelay != DefaultHTTPRetryMaxDelay || len(p.statuses) != len(DefaultHTTPRetryStatuses) {
+		t.Errorf("unexpected defaults %+v", p)
+	}
+	if p := newRetryPolicy(ClientConfig{HTTPRetryMaxAttempts: 1}); p != nil {
+		t.Errorf("expected a single attempt to disable retries, got %+v", p)
+	}
+	if p := newRetryPolicy(ClientConfig{HTTPRetryInitialDelay: time.Minute, HTTPRetryMaxDelay: time.Second}); p.maxDelay != time.Minute {
+		t.Errorf("expected the maximum delay to be raised to the initial delay, got %v", p.maxDelay)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Wed, 01 Jan 2025 12:00:05 GMT": 5 * time.Second,
+		"Wed, 01 Jan 2025 11:00:00 GMT": 0,
+	} {
+		got, ok := retryAfter(http.Header{"Retry-After": {value}}, now)
+		if !ok || got != want {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v", value, got, ok, want)
+		}
+	}
+	for _, value := range []string{"", "soon"} {
+		if _, ok := retryAfter(http.Header{"Retry-After": {value}}, now); ok {
+			t.Errorf("expected no delay for %q", value)
+		}
+	}
+}
+
+// newRetryTestClient returns a client retrying with short delays through a
+// logger writing to logs
+func newRetryTestClient(logs *bytes.Buffer, idempotentOnly bool) *http.Client {
+	policy := &retryPolicy{maxAttempts: 3, initialDelay: time.Millisecond, maxDelay: 50 * time.Millisecond, statuses: DefaultHTTPRetryStatuses}
+	return &http.Client{Transport: &retryRoundTripper{
+		base:           http.DefaultTransport,
+		policy:         policy,
+		logger:         NewLoggerWithWriter(false, false, false, logs),
+		idempotentOnly: idempotentOnly,
+	}}
+}
+
+func TestRetryRoundTripperReplaysBody(t *testing.T) {
+	var attempts atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	resp, err := newRetryTestClient(&logs, false).Post(server.URL+"/mcp", "application/json", strings.NewReader(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || attempts.Load() != 3 {
+		t.Fatalf("expected success on the third attempt, got %d after %d", resp.StatusCode, attempts.Load())
+	}
+	for i, body := range bodies {
+		if body != `{"id":1}` {
+			t.Errorf("attempt %d sent body %q", i+1, body)
+		}
+	}
+	for _, want := range []string{"POST " + server.URL + "/mcp: status 503 (attempt 1/3, retrying in 0s)", "(attempt 2/3"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log missing %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestRetryRoundTripperGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := newRetryTestClient(&logs, false)
+	for path, want := range map[string]int32{
+		"/gateway": 3, // every attempt used
+		"/limited": 1, // Retry-After beyond the maximum delay
+		"/broken":  1, // not a retried status
+	} {
+		attempts.Store(0)
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if attempts.Load() != want {
+			t.Errorf("%s: got %d attempts, want %d", path, attempts.Load(), want)
+		}
+	}
+	if !strings.Contains(logs.String(), "Retry-After 1m0s exceeding the maximum retry delay") {
+		t.Errorf("expected the ignored Retry-After to be logged:\n%s", logs.String())
+	}
+
+	// A gateway error may come after the upstream ran the call
+	attempts.Store(0)
+	resp, err := client.Post(server.URL+"/gateway", "application/json", strings.NewReader(`{"method":"tools/call"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if attempts.Load() != 1 {
+		t.Errorf("expected a POST failing with 502 not to be repeated, got %d attempts", attempts.Load())
+	}
+
+	// OAuth requests that are not idempotent are sent once
+	attempts.Store(0)
+	resp, err = newRetryTestClient(&logs, true).Post(server.URL+"/token", "application/x-www-form-urlencoded", strings.NewReader("grant_type=authorization_code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if attempts.Load() != 1 {
+		t.Errorf("expected the token request not to be repeated, got %d attempts", attempts.Load())
+	}
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// lineReader reads one line of user input after showing prompt. It is used
+// to answer server requests (sampling, elicitation) interactively.
+type lineReader func(ctx context.Context, prompt string) (string, error)
+
+// errInputInterrupted is returned by a lineReader when the user pressed
+// Ctrl+C; the server request is then declined or cancelled
+var errInputInterrupted = errors.New("input interrupted")
+
+// stdinLineReader reads lines from standard input, for modes without a line
+// editor. All handlers share one reader so buffered input is not lost.
+var stdinLineReader = sync.OnceValue(func() lineReader {
+	reader := bufio.NewReader(os.Stdin)
+	return func(ctx context.Context, prompt string) (string, error) {
+		fmt.Print(prompt)
+
+		lines := make(chan string, 1)
+		errs := make(chan error, 1)
+		go func() {
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				errs <- err
+				return
+			}
+			lines <- strings.TrimRight(line, "\r\n")
+		}()
+
+		select {
+		case line := <-lines:
+			return line, nil
+		case err := <-errs:
+			return "", fmt.Errorf("failed to read input: %w", err)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+})
+
+// setInputReader replaces how server requests are answered interactively;
+// the REPL passes its line editor
+func (c *Client) setInputReader(readLine lineReader, out io.Writer) {
+	if c.sampling != nil {
+		c.sampling.setLineReader(readLine, out)
+	}
+	if c.elicitation != nil {
+		c.elicitation.setLineReader(readLine, out)
+	}
+	if c.oauthStepper != nil {
+		c.oauthStepper.setLineReader(readLine, out)
+	}
+	c.mu.Lock()
+	c.oauthReadLine = readLine
+	c.mu.Unlock()
+}
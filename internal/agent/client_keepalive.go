@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultPingFailureThreshold is the number of consecutive failed pings after
+// which the connection is considered dead and a reconnect is attempted
+const DefaultPingFailureThreshold = 3
+
+// PingStats summarises client-initiated ping activity
+type PingStats struct {
+	// LastRTT is the round-trip time of the most recent successful ping
+	LastRTT time.Duration
+	// AvgRTT is the mean round-trip time of the successful pings
+	AvgRTT time.Duration
+	// LastPingAt is when the most recent ping (successful or not) completed
+	LastPingAt time.Time
+	// LastError is the error of the most recent ping, if it failed
+	LastError error
+	// ConsecutiveFailures counts failed pings since the last success
+	ConsecutiveFailures int
+	// TotalPings is the number of pings sent
+	TotalPings int
+	// TotalFailures is the number of pings that failed
+	TotalFailures int
+}
+
+// pingTracker records ping results; safe for concurrent use
+type pingTracker struct {
+	mu    sync.Mutex
+	stats PingStats
+	// totalRTT sums the round-trip times of the successful pings
+	totalRTT time.Duration
+}
+
+// record stores the outcome of a single ping and returns the updated
+// consecutive failure count
+func (t *pingTracker) record(rtt time.Duration, err error) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.TotalPings++
+	t.stats.LastPingAt = time.Now()
+	t.stats.LastError = err
+	if err != nil {
+		t.stats.TotalFailures++
+		t.stats.ConsecutiveFailures++
+	} else {
+		t.stats.LastRTT = rtt
+		t.stats.ConsecutiveFailures = 0
+		t.totalRTT += rtt
+		t.stats.AvgRTT = t.totalRTT / time.Duration(t.stats.TotalPings-t.stats.TotalFailures)
+	}
+	return t.stats.ConsecutiveFailures
+}
+
+// resetFailures clears the consecutive failure count (e.g. after reconnecting)
+func (t *pingTracker) resetFailures() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.ConsecutiveFailures = 0
+}
+
+// snapshot returns a copy of the current stats
+func (t *pingTracker) snapshot() PingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// Ping sends an MCP ping request and returns the measured round-trip time
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	ctx = c.withExchange(ctx, methodPing)
+	c.logRequest(ctx, methodPing, nil)
+
+	start := time.Now()
+	err := c.timeRequest(methodPing, func() error {
+		return c.mcpClient().Ping(ctx)
+	})
+	rtt := time.Since(start)
+
+	c.pingTracker.record(rtt, err)
+	if err != nil {
+		c.logger.Error("Ping failed: %v", err)
+		return 0, classifyError(err)
+	}
+
+	c.logResponse(ctx, methodPing, nil)
+	c.logger.Debug("Ping RTT: %v", rtt)
+	return rtt, nil
+}
+
+// PingStats returns a snapshot of client-initiated ping statistics
+func (c *Client) PingStats() PingStats {
+	return c.pingTracker.snapshot()
+}
+
+// startKeepalive pings the server at the configured interval until ctx is done.
+// After pingFailureThreshold consecutive failures the client reconnects.
+func (c *Client) startKeepalive(ctx context.Context) {
+	if c.pingInterval <= 0 {
+		return
+	}
+
+	threshold := c.pingFailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultPingFailureThreshold
+	}
+
+	c.logger.Info("Client keepalive enabled (ping every %v, reconnect after %d failures)", c.pingInterval, threshold)
+
+	go func() {
+		ticker := time.NewTicker(c.pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.keepaliveTick(ctx, threshold); err != nil {
+					c.logger.Error("Keepalive: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// keepaliveTick sends one ping and reconnects once the failure threshold is reached
+func (c *Client) keepaliveTick(ctx context.Context, threshold int) error {
+	// Bound each ping so a half-dead connection cannot stall the loop
+	pingCtx, cancel := context.WithTimeout(ctx, c.pingInterval)
+	defer cancel()
+
+	if _, err := c.Ping(pingCtx); err == nil {
+		return nil
+	}
+
+	failures := c.pingTracker.snapshot().ConsecutiveFailures
+	if failures < threshold || ctx.Err() != nil {
+		return nil
+	}
+
+	c.logger.Warning("%d consecutive pings failed, treating connection as lost", failures)
+	c.pingTracker.resetFailures()
+	if err := c.reconnectWithBackoff(ctx); err != nil {
+		return fmt.Errorf("reconnect after failed pings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPingTracker(t *testing.T) {
+	var tracker pingTracker
+
+	if got := tracker.record(10*time.Millisecond, nil); got != 0 {
+		t.Errorf("expected 0 consecutive failures after success, got %d", got)
+	}
+	tracker.record(0, errors.New("timeout"))
+	if got := tracker.record(0, errors.New("timeout")); got != 2 {
+		t.Errorf("expected 2 consecutive failures, got %d", got)
+	}
+
+	stats := tracker.snapshot()
+	if stats.TotalPings != 3 {
+		t.Errorf("expected 3 pings, got %d", stats.TotalPings)
+	}
+	if stats.TotalFailures != 2 {
+		t.Errorf("expected 2 failures, got %d", stats.TotalFailures)
+	}
+	if stats.LastRTT != 10*time.Millisecond {
+		t.Errorf("expected last RTT to be kept from last success, got %v", stats.LastRTT)
+	}
+	if stats.LastError == nil {
+		t.Error("expected last error to be recorded")
+	}
+
+	tracker.resetFailures()
+	if got := tracker.snapshot().ConsecutiveFailures; got != 0 {
+		t.Errorf("expected failures reset, got %d", got)
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	t.Run("success records RTT", func(t *testing.T) {
+		stub := &stubMCPClient{}
+		c := newStubbedClient(t, stub)
+
+		if _, err := c.Ping(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		stats := c.PingStats()
+		if stats.TotalPings != 1 || stats.ConsecutiveFailures != 0 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("failure below threshold does not reconnect", func(t *testing.T) {
+		stub := &stubMCPClient{pingErr: errors.New("connection reset by peer")}
+		c := newStubbedClient(t, stub)
+		c.pingInterval = testTimeoutNormal
+
+		if err := c.keepaliveTick(t.Context(), 3); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := c.PingStats().ConsecutiveFailures; got != 1 {
+			t.Errorf("expected 1 consecutive failure, got %d", got)
+		}
+	})
+}
+
+func TestStartKeepaliveDisabled(t *testing.T) {
+	stub := &stubMCPClient{}
+	c := newStubbedClient(t, stub)
+
+	// Zero interval must not start a goroutine or ping
+	c.startKeepalive(t.Context())
+	time.Sleep(testDelayLong)
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.pingCalls != 0 {
+		t.Errorf("expected no pings with keepalive disabled, got %d", stub.pingCalls)
+	}
+}
+
+func TestPingWhileClientReplaced(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+
+	// A keepalive reconnect swaps the client while callers keep using it;
+	// the race detector catches unguarded access
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			c.setMCPClient(&stubMCPClient{})
+		}
+	}()
+	for range 100 {
+		if _, err := c.Ping(t.Context()); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	}
+	<-done
+}
+
+func TestKeepaliveReconnectWhilePinging(t *testing.T) {
+	fs := newFlakyServer(t)
+	c := newFlakyClient(t, fs, 20)
+
+	reconnected := make(chan ReconnectReport, 1)
+	c.onReconnected = func(report ReconnectReport) { reconnected <- report }
+	c.pingInterval = 10 * time.Millisecond
+	c.pingFailureThreshold = 1
+
+	// The keepalive replaces the client while the caller keeps pinging
+	fs.down.Store(true)
+	go func() {
+		for {
+			c.reconnectMu.Lock()
+			reconnecting := c.reconnecting != nil
+			c.reconnectMu.Unlock()
+			if reconnecting {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		fs.down.Store(false)
+	}()
+	c.startKeepalive(t.Context())
+
+	deadline := time.After(10 * time.Second)
+	for {
+		_, _ = c.Ping(t.Context())
+		select {
+		case <-reconnected:
+			if _, err := c.Ping(t.Context()); err != nil {
+				t.Fatalf("Ping after the keepalive reconnected: %v", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("the keepalive did not reconnect")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
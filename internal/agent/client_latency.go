@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the latencies kept per method; percentiles of
+// long sessions are computed over the most recent samples
+const maxLatencySamples = 10000
+
+// MethodStats summarises the requests of one JSON-RPC method since the
+// client was created
+type MethodStats struct {
+	Method string `json:"method"`
+	// Count is the number of requests sent, including failed ones
+	Count int `json:"count"`
+	// Errors is the number of requests that failed
+	Errors int `json:"errors"`
+	// ErrorRate is Errors divided by Count
+	ErrorRate float64 `json:"errorRate"`
+	// P50Ms, P95Ms and P99Ms are latency percentiles in milliseconds
+	P50Ms float64 `json:"p50Ms"`
+	P95Ms float64 `json:"p95Ms"`
+	P99Ms float64 `json:"p99Ms"`
+}
+
+// methodLatency holds the measurements of one method
+type methodLatency struct {
+	count  int
+	errors int
+	// samplesMs is a ring buffer of the latest latencies in milliseconds
+	samplesMs []float64
+	next      int
+}
+
+// latencyTracker records request latencies per method
+type latencyTracker struct {
+	mu      sync.Mutex
+	methods map[string]*methodLatency
+}
+
+// record adds one request to the statistics of method
+func (t *latencyTracker) record(method string, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.methods == nil {
+		t.methods = make(map[string]*methodLatency)
+	}
+	m := t.methods[method]
+	if m == nil {
+		m = &methodLatency{}
+		t.methods[method] = m
+	}
+
+	m.count++
+	if failed {
+		m.errors++
+	}
+	sample := float64(latency) / float64(time.Millisecond)
+	if len(m.samplesMs) < maxLatencySamples {
+		m.samplesMs = append(m.samplesMs, sample)
+	} else {
+		m.samplesMs[m.next] = sample
+		m.next = (m.next + 1) % maxLatencySamples
+	}
+}
+
+// snapshot summarises every method, sorted by name
+func (t *latencyTracker) snapshot() []MethodStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := make([]MethodStats, 0, len(t.methods))
+	for method, m := range t.methods {
+		summary := SummarizeLatencies(m.samplesMs)
+		stats = append(stats, MethodStats{
+			Method:    method,
+			Count:     m.count,
+			Errors:    m.errors,
+			ErrorRate: float64(m.errors) / float64(m.count),
+			P50Ms:     summary.P50,
+			P95Ms:     summary.P95,
+			P99Ms:     summary.P99,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+// MethodStats returns latency and error statistics per JSON-RPC method
+func (c *Client) MethodStats() []MethodStats {
+	return c.latency.snapshot()
+}
+
+// timeRequest runs fn, which sends one request of method, and records its
+// latency and outcome
+func (c *Client) timeRequest(method string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	c.latency.record(method, time.Since(start), err != nil)
+	return err
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	var tracker latencyTracker
+	for i := 1; i <= 100; i++ {
+		tracker.record("tools/call", time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	tracker.record("ping", time.Millisecond, false)
+
+	stats := tracker.snapshot()
+	if len(stats) != 2 || stats[0].Method != "ping" || stats[1].Method != "tools/call" {
+		t.Fatalf("expected stats sorted by method, got %+v", stats)
+	}
+
+	call := stats[1]
+	if call.Count != 100 || call.Errors != 10 || call.ErrorRate != 0.1 {
+		t.Errorf("unexpected counts: %+v", call)
+	}
+	if call.P50Ms < 50 || call.P50Ms > 51 || call.P99Ms < 99 || call.P99Ms > 100 {
+		t.Errorf("unexpected percentiles: %+v", call)
+	}
+}
+
+func TestLatencyTrackerBoundsSamples(t *testing.T) {
+	var tracker latencyTracker
+	for i := 0; i < maxLatencySamples+10; i++ {
+		tracker.record("ping", time.Millisecond, false)
+	}
+
+	if got := len(tracker.methods["ping"].samplesMs); got != maxLatencySamples {
+		t.Errorf("expected %d samples to be kept, got %d", maxLatencySamples, got)
+	}
+	if got := tracker.snapshot()[0].Count; got != maxLatencySamples+10 {
+		t.Errorf("expected every request to be counted, got %d", got)
+	}
+}
+
+func TestClientMethodStats(t *testing.T) {
+	stub := &stubMCPClient{pingErr: errors.New("boom")}
+	c := newStubbedClient(t, stub)
+
+	if err := c.listTools(context.Background(), true); err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	_, _ = c.Ping(context.Background())
+
+	stats := c.MethodStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 methods, got %+v", stats)
+	}
+	if stats[0].Method != methodPing || stats[0].Errors != 1 {
+		t.Errorf("expected the failed ping to be counted, got %+v", stats[0])
+	}
+	if stats[1].Method != "tools/list" || stats[1].Count != 1 || stats[1].Errors != 0 {
+		t.Errorf("unexpected tools/list stats: %+v", stats[1])
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// LoggingLevels lists the RFC 5424 severities accepted by logging/setLevel,
+// from least to most severe
+var LoggingLevels = []string{
+	string(mcp.LoggingLevelDebug),
+	string(mcp.LoggingLevelInfo),
+	string(mcp.LoggingLevelNotice),
+	string(mcp.LoggingLevelWarning),
+	string(mcp.LoggingLevelError),
+	string(mcp.LoggingLevelCritical),
+	string(mcp.LoggingLevelAlert),
+	string(mcp.LoggingLevelEmergency),
+}
+
+// ServerSupportsLogging reports whether the server advertised the logging capability
+func (c *Client) ServerSupportsLogging() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverCapabilities != nil && c.serverCapabilities.Logging != nil
+}
+
+// SetLogLevel asks the server to send log messages at the given level and above
+func (c *Client) SetLogLevel(ctx context.Context, level string) error {
+	parsed, err := parseLoggingLevel(level)
+	if err != nil {
+		return err
+	}
+
+	req := mcp.SetLevelRequest{
+		Params: mcp.SetLevelParams{
+			Level: parsed,
+		},
+	}
+
+	ctx = c.withExchange(ctx, methodLoggingSetLevel)
+	c.logRequest(ctx, methodLoggingSetLevel, req.Params)
+
+	if err := c.timeRequest(methodLoggingSetLevel, func() error {
+		return c.mcpClient().SetLevel(ctx, req)
+	}); err != nil {
+		c.logger.Error("SetLevel failed: %v", err)
+		return classifyError(err)
+	}
+
+	c.logResponse(ctx, methodLoggingSetLevel, nil)
+	return nil
+}
+
+// parseLoggingLevel validates a logging level name
+func parseLoggingLevel(level string) (mcp.LoggingLevel, error) {
+	normalized := strings.ToLower(strings.TrimSpace(level))
+	for _, l := range LoggingLevels {
+		if l == normalized {
+			return mcp.LoggingLevel(l), nil
+		}
+	}
+	return "", fmt.Errorf("invalid log level: %s (must be one of: %s)", level, strings.Join(LoggingLevels, ", "))
+}
+
+// handleLoggingMessage renders a notifications/message notification
+func (c *Client) handleLoggingMessage(notification mcp.JSONRPCNotification) {
+	fields := notification.Params.AdditionalFields
+
+	level, _ := fields["level"].(string)
+	loggerName, _ := fields["logger"].(string)
+
+	c.logger.ServerLog(level, loggerName, formatLogData(fields["data"]))
+}
+
+// formatLogData renders the data field of a log message: strings are shown
+// as-is, anything else as compact JSON
+func formatLogData(data interface{}) string {
+	switch v := data.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%+v", v)
+		}
+		return string(b)
+	}
+}
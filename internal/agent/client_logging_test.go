@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseLoggingLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    mcp.LoggingLevel
+		wantErr bool
+	}{
+		{input: "debug", want: mcp.LoggingLevelDebug},
+		{input: "WARNING", want: mcp.LoggingLevelWarning},
+		{input: " emergency ", want: mcp.LoggingLevelEmergency},
+		{input: "verbose", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLoggingLevel(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseLoggingLevel(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLogData(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{name: "nil", data: nil, want: ""},
+		{name: "string", data: "plain message", want: "plain message"},
+		{name: "object", data: map[string]interface{}{"k": "v"}, want: `{"k":"v"}`},
+		{name: "number", data: 42.0, want: "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLogData(tt.data); got != tt.want {
+				t.Errorf("formatLogData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleLoggingMessageNotification(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, buf)
+	client := NewClient(ClientConfig{
+		Endpoint:  "test://endpoint",
+		Transport: "streamable-http",
+		Logger:    logger,
+		Version:   "test",
+	})
+
+	notification := mcp.JSONRPCNotification{
+		Notification: mcp.Notification{
+			Method: notificationMessage,
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]any{
+					"level":  "notice",
+					"logger": "scheduler",
+					"data":   "job finished",
+				},
+			},
+		},
+	}
+
+	if err := client.handleNotification(t.Context(), notification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "[server:NOTICE] (scheduler) job finished") {
+		t.Errorf("expected rendered server log line, got %q", output)
+	}
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -9,30 +10,84 @@ import (
 
 // CallTool executes a tool with the given arguments, with reconnection logic.
 func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	return c.CallToolStreaming(ctx, name, args, nil)
+}
+
+// CallToolStreaming executes a tool like CallTool, but requests progress
+// notifications and passes each partial update to onProgress as it arrives,
+// so long-running tools can be observed before the final result. A nil
+// onProgress behaves exactly like CallTool.
+func (c *Client) CallToolStreaming(ctx context.Context, name string, args map[string]interface{}, onProgress ProgressHandler) (*mcp.CallToolResult, error) {
 	req := mcp.CallToolRequest{
 		Params: mcp.CallToolParams{
 			Name:      name,
 			Arguments: args,
+			Meta:      c.requestMeta(),
 		},
 	}
 
-	c.logger.Request("tools/call", req.Params)
+	tool, known := c.findTool(name)
+	if known {
+		if err := c.validateToolArguments(tool, args); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, timeout, cancel := c.withCallDeadline(ctx)
+	defer cancel()
+	ctx, call, finished := c.inflight.start(ctx, "tools/call", name)
+	defer finished()
+
+	if onProgress != nil {
+		token, done := c.progress.register(func(update ToolProgress) {
+			call.setProgress(update)
+			onProgress(update)
+		})
+		defer done()
+		if req.Params.Meta == nil {
+			req.Params.Meta = &mcp.Meta{}
+		}
+		req.Params.Meta.ProgressToken = token
+	}
+
+	ctx = c.withExchange(ctx, "tools/call")
+	c.logRequest(ctx, "tools/call", req.Params)
 
 	const maxRetries = 1
 	var result *mcp.CallToolResult
 	var err error
 
 	for i := 0; i <= maxRetries; i++ {
-		result, err = c.client.CallTool(ctx, req)
+		err = c.withRequestSlot(ctx, "tools/call", func() error {
+			var callErr error
+			result, callErr = c.mcpClient().CallTool(ctx, req)
+			return callErr
+		})
 		if err == nil {
-			c.logger.Response("tools/call", result)
+			c.logResponse(ctx, "tools/call", result)
+			c.logServerMeta("tools/call", result.Meta)
+			if known {
+				if err := c.validateToolResult(tool, result); err != nil {
+					return nil, err
+				}
+			}
 			return result, nil // Success
 		}
 
+		// A cancelled or timed out call is not a lost connection
+		if cause := context.Cause(ctx); errors.Is(cause, errRequestCancelled) {
+			err = cause
+			break
+		}
+		if timeoutErr := callTimeoutError(ctx, name, timeout); timeoutErr != nil {
+			c.abandonTimedOutCall(ctx, call)
+			err = timeoutErr
+			break
+		}
 		if shouldReconnect(err) {
 			if i < maxRetries {
 				c.logger.Error("Connection lost during tool call. Attempting to reconnect...")
-				if reconnErr := c.Reconnect(ctx); reconnErr != nil {
+				if reconnErr := c.reconnectWithBackoff(ctx); reconnErr != nil {
 					err = fmt.Errorf("failed to reconnect: %w", reconnErr)
 					break // Don't retry if reconnect fails
 				}
@@ -45,33 +100,51 @@ func (c *Client) CallTool(ctx context.Context, name string, args map[string]inte
 	}
 
 	c.logger.Error("CallTool failed: %v", err)
-	return nil, err
+	return nil, classifyError(err)
 }
 
 // GetResource retrieves a resource by URI, with reconnection logic.
 func (c *Client) GetResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	ctx = c.withExchange(ctx, "resources/read")
+	result, err := c.readResource(ctx, uri, c.requestMeta())
+	if err != nil {
+		return nil, err
+	}
+
+	c.logResponse(ctx, "resources/read", result)
+	c.logServerMeta("resources/read", result.Meta)
+	return result, nil
+}
+
+// readResource sends resources/read with reconnection logic. The response is
+// left for the caller to log, so unchanged re-reads can be logged briefly.
+func (c *Client) readResource(ctx context.Context, uri string, meta *mcp.Meta) (*mcp.ReadResourceResult, error) {
 	req := mcp.ReadResourceRequest{
 		Params: mcp.ReadResourceParams{
-			URI: uri,
+			URI:  uri,
+			Meta: meta,
 		},
 	}
-	c.logger.Request("resources/read", req.Params)
+	c.logRequest(ctx, "resources/read", req.Params)
 
 	const maxRetries = 1
 	var result *mcp.ReadResourceResult
 	var err error
 
 	for i := 0; i <= maxRetries; i++ {
-		result, err = c.client.ReadResource(ctx, req)
+		err = c.withRequestSlot(ctx, "resources/read", func() error {
+			var callErr error
+			result, callErr = c.mcpClient().ReadResource(ctx, req)
+			return callErr
+		})
 		if err == nil {
-			c.logger.Response("resources/read", result)
 			return result, nil // Success
 		}
 
 		if shouldReconnect(err) {
 			if i < maxRetries {
 				c.logger.Error("Connection lost during resource fetch. Attempting to reconnect...")
-				if reconnErr := c.Reconnect(ctx); reconnErr != nil {
+				if reconnErr := c.reconnectWithBackoff(ctx); reconnErr != nil {
 					err = fmt.Errorf("failed to reconnect: %w", reconnErr)
 					break // Don't retry if reconnect fails
 				}
@@ -84,7 +157,7 @@ func (c *Client) GetResource(ctx context.Context, uri string) (*mcp.ReadResource
 	}
 
 	c.logger.Error("ReadResource failed: %v", err)
-	return nil, err
+	return nil, classifyError(err)
 }
 
 // GetPrompt retrieves a prompt with arguments, with reconnection logic.
@@ -93,25 +166,32 @@ func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]str
 		Params: mcp.GetPromptParams{
 			Name:      name,
 			Arguments: args,
+			Meta:      c.requestMeta(),
 		},
 	}
-	c.logger.Request("prompts/get", req.Params)
+	ctx = c.withExchange(ctx, "prompts/get")
+	c.logRequest(ctx, "prompts/get", req.Params)
 
 	const maxRetries = 1
 	var result *mcp.GetPromptResult
 	var err error
 
 	for i := 0; i <= maxRetries; i++ {
-		result, err = c.client.GetPrompt(ctx, req)
+		err = c.withRequestSlot(ctx, "prompts/get", func() error {
+			var callErr error
+			result, callErr = c.mcpClient().GetPrompt(ctx, req)
+			return callErr
+		})
 		if err == nil {
-			c.logger.Response("prompts/get", result)
+			c.logResponse(ctx, "prompts/get", result)
+			c.logServerMeta("prompts/get", result.Meta)
 			return result, nil // Success
 		}
 
 		if shouldReconnect(err) {
 			if i < maxRetries {
 				c.logger.Error("Connection lost during prompt fetch. Attempting to reconnect...")
-				if reconnErr := c.Reconnect(ctx); reconnErr != nil {
+				if reconnErr := c.reconnectWithBackoff(ctx); reconnErr != nil {
 					err = fmt.Errorf("failed to reconnect: %w", reconnErr)
 					break // Don't retry if reconnect fails
 				}
@@ -124,5 +204,5 @@ func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]str
 	}
 
 	c.logger.Error("GetPrompt failed: %v", err)
-	return nil, err
+	return nil, classifyError(err)
 }
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ParseMetaEntries parses key=value pairs into a _meta map. Values that are
+// valid JSON are decoded (so numbers, booleans and objects keep their type);
+// anything else is sent as a plain string.
+func ParseMetaEntries(entries []string) (map[string]any, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	fields := make(map[string]any, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid _meta entry %q (expected key=value)", entry)
+		}
+
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			decoded = value
+		}
+		fields[key] = decoded
+	}
+	return fields, nil
+}
+
+// requestMeta returns the configured _meta for outgoing requests, or nil if
+// none is configured. Each call returns a fresh copy so callers may add
+// fields without affecting other requests.
+func (c *Client) requestMeta() *mcp.Meta {
+	if len(c.requestMetaFields) == 0 {
+		return nil
+	}
+	return &mcp.Meta{AdditionalFields: maps.Clone(c.requestMetaFields)}
+}
+
+// logServerMeta surfaces _meta attached by the server to a response, which
+// is otherwise only visible in verbose JSON output
+func (c *Client) logServerMeta(method string, meta *mcp.Meta) {
+	if meta == nil || (meta.ProgressToken == nil && len(meta.AdditionalFields) == 0) {
+		return
+	}
+
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	c.logger.Info("Server _meta on %s: %s", method, string(b))
+}
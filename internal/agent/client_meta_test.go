@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetaEntries(t *testing.T) {
+	fields, err := ParseMetaEntries([]string{
+		"vendor/trace=abc",
+		"count=3",
+		"flags={\"a\":true}",
+		"empty=",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]any{
+		"vendor/trace": "abc",
+		"count":        float64(3),
+		"flags":        map[string]any{"a": true},
+		"empty":        "",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("ParseMetaEntries() = %v, want %v", fields, want)
+	}
+
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, err := ParseMetaEntries([]string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestRequestMetaIsCopied(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	if c.requestMeta() != nil {
+		t.Error("expected nil _meta when none is configured")
+	}
+
+	c.requestMetaFields = map[string]any{"k": "v"}
+	meta := c.requestMeta()
+	meta.AdditionalFields["extra"] = 1
+
+	if _, leaked := c.requestMetaFields["extra"]; leaked {
+		t.Error("modifying a request's _meta must not affect the configured fields")
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultNotificationBufferSize is the number of notifications queued for
+// the listener before the overflow policy applies
+const DefaultNotificationBufferSize = 10
+
+// NotificationOverflowPolicy decides what happens to a notification that
+// arrives while the notification buffer is full
+type NotificationOverflowPolicy string
+
+const (
+	// OverflowBlock waits until the listener has room, applying back-pressure
+	// to the transport
+	OverflowBlock NotificationOverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued notification to make room
+	OverflowDropOldest NotificationOverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the notification that just arrived
+	OverflowDropNewest NotificationOverflowPolicy = "drop-newest"
+)
+
+// NotificationOverflowPolicies lists the accepted overflow policy names
+var NotificationOverflowPolicies = []string{
+	string(OverflowBlock),
+	string(OverflowDropOldest),
+	string(OverflowDropNewest),
+}
+
+// ParseNotificationOverflowPolicy validates an overflow policy name
+func ParseNotificationOverflowPolicy(policy string) (NotificationOverflowPolicy, error) {
+	normalized := strings.ToLower(strings.TrimSpace(policy))
+	for _, p := range NotificationOverflowPolicies {
+		if p == normalized {
+			return NotificationOverflowPolicy(p), nil
+		}
+	}
+	return "", fmt.Errorf("invalid notification overflow policy: %s (must be one of: %s)", policy, strings.Join(NotificationOverflowPolicies, ", "))
+}
+
+// NotificationStats summarises notification delivery to the listener
+type NotificationStats struct {
+	// BufferSize is the capacity of the notification buffer
+	BufferSize int
+	// Policy is the overflow policy in effect
+	Policy NotificationOverflowPolicy
+	// Received counts notifications handed to the buffer
+	Received int64
+	// Dropped counts notifications discarded because the buffer was full
+	Dropped int64
+	// Queued is the number of notifications currently waiting
+	Queued int
+}
+
+// notificationCounters tracks buffer activity; safe for concurrent use
+type notificationCounters struct {
+	received atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NotificationStats returns a snapshot of notification buffer statistics
+func (c *Client) NotificationStats() NotificationStats {
+	return NotificationStats{
+		BufferSize: cap(c.notificationChan),
+		Policy:     c.notificationOverflow,
+		Received:   c.notificationCounters.received.Load(),
+		Dropped:    c.notificationCounters.dropped.Load(),
+		Queued:     len(c.notificationChan),
+	}
+}
+
+// enqueueNotification hands a notification to the listener, applying the
+// overflow policy when the buffer is full
+func (c *Client) enqueueNotification(ctx context.Context, notification mcp.JSONRPCNotification) {
+	c.notificationCounters.received.Add(1)
+
+	switch c.notificationOverflow {
+	case OverflowDropNewest:
+		select {
+		case c.notificationChan <- notification:
+		default:
+			c.recordDroppedNotification(notification)
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case c.notificationChan <- notification:
+				return
+			default:
+			}
+			// Make room by discarding the oldest entry; the listener may have
+			// drained it concurrently, in which case the next send succeeds
+			select {
+			case oldest := <-c.notificationChan:
+				c.recordDroppedNotification(oldest)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case c.notificationChan <- notification:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// recordDroppedNotification counts and reports a discarded notification
+func (c *Client) recordDroppedNotification(notification mcp.JSONRPCNotification) {
+	dropped := c.notificationCounters.dropped.Add(1)
+	c.logger.WarningVerbose("Notification buffer full, dropped %s (%d dropped so far)", notification.Method, dropped)
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"io"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newNotification(method string) mcp.JSONRPCNotification {
+	return mcp.JSONRPCNotification{Notification: mcp.Notification{Method: method}}
+}
+
+func TestParseNotificationOverflowPolicy(t *testing.T) {
+	for _, name := range []string{"block", "Drop-Oldest", " drop-newest "} {
+		if _, err := ParseNotificationOverflowPolicy(name); err != nil {
+			t.Errorf("unexpected error for %q: %v", name, err)
+		}
+	}
+	if _, err := ParseNotificationOverflowPolicy("discard"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestNotificationOverflow(t *testing.T) {
+	tests := []struct {
+		policy NotificationOverflowPolicy
+		want   []string
+	}{
+		{policy: OverflowDropNewest, want: []string{"a", "b"}},
+		{policy: OverflowDropOldest, want: []string{"c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c := NewClient(ClientConfig{
+				Logger:                 NewLoggerWithWriter(false, false, false, io.Discard),
+				NotificationBufferSize: 2,
+				NotificationOverflow:   tt.policy,
+			})
+
+			for _, method := range []string{"a", "b", "c", "d"} {
+				c.enqueueNotification(t.Context(), newNotification(method))
+			}
+
+			stats := c.NotificationStats()
+			if stats.Received != 4 || stats.Dropped != 2 || stats.Queued != 2 || stats.BufferSize != 2 {
+				t.Errorf("unexpected stats: %+v", stats)
+			}
+
+			for _, want := range tt.want {
+				if got := (<-c.notificationChan).Method; got != want {
+					t.Errorf("got %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotificationBlockPolicyDefaults(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	stats := c.NotificationStats()
+	if stats.Policy != OverflowBlock || stats.BufferSize != DefaultNotificationBufferSize {
+		t.Errorf("unexpected defaults: %+v", stats)
+	}
+}
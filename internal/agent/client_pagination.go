@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultMaxListPages is the number of pages followed when listing tools,
+// resources, templates or prompts; it stops servers that keep returning
+// cursors from stalling the client
+const DefaultMaxListPages = 100
+
+// pageFetcher sends one page request of a list method and returns its items
+// with the cursor of the next page, empty on the last page
+type pageFetcher[T any] func(ctx context.Context, cursor mcp.Cursor) ([]T, mcp.Cursor, error)
+
+// collectPages follows the cursors of a list method from the first page
+// until the server returns no cursor or maxListPages pages were fetched
+func collectPages[T any](ctx context.Context, c *Client, method string, fetch pageFetcher[T]) ([]T, error) {
+	all := []T{}
+	var cursor mcp.Cursor
+	for page := 1; ; page++ {
+		items, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		if next == "" {
+			if page > 1 {
+				c.logger.Info("%s: fetched %d entries across %d pages", method, len(all), page)
+			}
+			return all, nil
+		}
+		if page >= c.maxListPages {
+			c.logger.Warning("%s: stopped after %d pages, the list is truncated to %d entries (raise --max-list-pages to fetch more)", method, page, len(all))
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+// fetchPageNumber returns page (1-based) of a list method by following the
+// cursors of the pages before it, and reports whether more pages follow
+func fetchPageNumber[T any](ctx context.Context, page int, fetch pageFetcher[T]) ([]T, bool, error) {
+	if page < 1 {
+		return nil, false, fmt.Errorf("invalid page %d: pages are numbered from 1", page)
+	}
+
+	var cursor mcp.Cursor
+	for current := 1; ; current++ {
+		items, next, err := fetch(ctx, cursor)
+		if err != nil {
+			return nil, false, err
+		}
+		if current == page {
+			return items, next != "", nil
+		}
+		if next == "" {
+			return nil, false, fmt.Errorf("page %d does not exist: the server returned %d page(s)", page, current)
+		}
+		cursor = next
+	}
+}
+
+// fetchListPage sends one page request of method with logging and request
+// slot accounting
+func fetchListPage[R any](ctx context.Context, c *Client, method string, params mcp.PaginatedParams, send func(context.Context) (*R, error)) (*R, error) {
+	ctx = c.withExchange(ctx, method)
+	c.logRequest(ctx, method, params)
+
+	var result *R
+	err := c.withRequestSlot(ctx, method, func() error {
+		var err error
+		result, err = send(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logResponse(ctx, method, result)
+	return result, nil
+}
+
+// toolsPage fetches one page of tools/list
+func (c *Client) toolsPage(ctx context.Context, cursor mcp.Cursor) ([]mcp.Tool, mcp.Cursor, error) {
+	req := mcp.ListToolsRequest{}
+	req.Params.Meta = c.requestMeta()
+	req.Params.Cursor = cursor
+
+	result, err := fetchListPage(ctx, c, "tools/list", req.Params, func(ctx context.Context) (*mcp.ListToolsResult, error) {
+		return c.mcpClient().ListToolsByPage(ctx, req)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logServerMeta("tools/list", result.Meta)
+	return result.Tools, result.NextCursor, nil
+}
+
+// resourcesPage fetches one page of resources/list
+func (c *Client) resourcesPage(ctx context.Context, cursor mcp.Cursor) ([]mcp.Resource, mcp.Cursor, error) {
+	req := mcp.ListResourcesRequest{}
+	req.Params.Meta = c.requestMeta()
+	req.Params.Cursor = cursor
+
+	result, err := fetchListPage(ctx, c, "resources/list", req.Params, func(ctx context.Context) (*mcp.ListResourcesResult, error) {
+		return c.mcpClient().ListResourcesByPage(ctx, req)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logServerMeta("resources/list", result.Meta)
+	return result.Resources, result.NextCursor, nil
+}
+
+// resourceTemplatesPage fetches one page of resources/templates/list
+func (c *Client) resourceTemplatesPage(ctx context.Context, cursor mcp.Cursor) ([]mcp.ResourceTemplate, mcp.Cursor, error) {
+	req := mcp.ListResourceTemplatesRequest{}
+	req.Params.Meta = c.requestMeta()
+	req.Params.Cursor = cursor
+
+	result, err := fetchListPage(ctx, c, methodResourcesTemplatesList, req.Params, func(ctx context.Context) (*mcp.ListResourceTemplatesResult, error) {
+		return c.mcpClient().ListResourceTemplatesByPage(ctx, req)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logServerMeta(methodResourcesTemplatesList, result.Meta)
+	return result.ResourceTemplates, result.NextCursor, nil
+}
+
+// promptsPage fetches one page of prompts/list
+func (c *Client) promptsPage(ctx context.Context, cursor mcp.Cursor) ([]mcp.Prompt, mcp.Cursor, error) {
+	req := mcp.ListPromptsRequest{}
+	req.Params.Meta = c.requestMeta()
+	req.Params.Cursor = cursor
+
+	result, err := fetchListPage(ctx, c, "prompts/list", req.Params, func(ctx context.Context) (*mcp.ListPromptsResult, error) {
+		return c.mcpClient().ListPromptsByPage(ctx, req)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logServerMeta("prompts/list", result.Meta)
+	return result.Prompts, result.NextCursor, nil
+}
+
+// ListToolsPage fetches a single page (1-based) of tools/list without
+// touching the tool cache, and reports whether more pages follow
+func (c *Client) ListToolsPage(ctx context.Context, page int) ([]mcp.Tool, bool, error) {
+	tools, more, err := fetchPageNumber(ctx, page, c.toolsPage)
+	return tools, more, classifyError(err)
+}
+
+// ListResourcesPage fetches a single page (1-based) of resources/list
+// without touching the resource cache, and reports whether more pages follow
+func (c *Client) ListResourcesPage(ctx context.Context, page int) ([]mcp.Resource, bool, error) {
+	resources, more, err := fetchPageNumber(ctx, page, c.resourcesPage)
+	return resources, more, classifyError(err)
+}
+
+// ListResourceTemplatesPage fetches a single page (1-based) of
+// resources/templates/list without touching the template cache, and reports
+// whether more pages follow
+func (c *Client) ListResourceTemplatesPage(ctx context.Context, page int) ([]mcp.ResourceTemplate, bool, error) {
+	templates, more, err := fetchPageNumber(ctx, page, c.resourceTemplatesPage)
+	return templates, more, classifyError(err)
+}
+
+// ListPromptsPage fetches a single page (1-based) of prompts/list without
+// touching the prompt cache, and reports whether more pages follow
+func (c *Client) ListPromptsPage(ctx context.Context, page int) ([]mcp.Prompt, bool, error) {
+	prompts, more, err := fetchPageNumber(ctx, page, c.promptsPage)
+	return prompts, more, classifyError(err)
+}
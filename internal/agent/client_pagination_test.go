@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// pagedToolsStub serves tools/list in pages of pageSize, using the offset of
+// the next page as cursor
+type pagedToolsStub struct {
+	stubMCPClient
+	pageSize int
+	pages    int
+}
+
+func (s *pagedToolsStub) ListToolsByPage(ctx context.Context, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	s.pages++
+	start := 0
+	if req.Params.Cursor != "" {
+		start, _ = strconv.Atoi(string(req.Params.Cursor))
+	}
+	end := min(start+s.pageSize, len(s.tools))
+
+	result := &mcp.ListToolsResult{Tools: s.tools[start:end]}
+	if end < len(s.tools) {
+		result.NextCursor = mcp.Cursor(strconv.Itoa(end))
+	}
+	return result, nil
+}
+
+func newPagedToolsStub(count, pageSize int) *pagedToolsStub {
+	stub := &pagedToolsStub{pageSize: pageSize}
+	for i := range count {
+		stub.tools = append(stub.tools, mcp.NewTool(fmt.Sprintf("tool-%03d", i)))
+	}
+	return stub
+}
+
+func TestListToolsFollowsPages(t *testing.T) {
+	stub := newPagedToolsStub(250, 100)
+	c := newStubbedClient(t, stub)
+
+	if err := c.listTools(context.Background(), true); err != nil {
+		t.Fatalf("listTools: %v", err)
+	}
+	if len(c.toolCache) != 250 || stub.pages != 3 {
+		t.Errorf("cached %d tools from %d pages, want 250 from 3", len(c.toolCache), stub.pages)
+	}
+	if last := c.toolCache[249].Name; last != "tool-249" {
+		t.Errorf("last tool = %s", last)
+	}
+
+	// The page limit truncates instead of following cursors forever
+	stub.pages = 0
+	c.maxListPages = 2
+	if err := c.listTools(context.Background(), true); err != nil {
+		t.Fatalf("listTools: %v", err)
+	}
+	if len(c.toolCache) != 200 || stub.pages != 2 {
+		t.Errorf("cached %d tools from %d pages, want 200 from 2", len(c.toolCache), stub.pages)
+	}
+}
+
+func TestListToolsPage(t *testing.T) {
+	c := newStubbedClient(t, newPagedToolsStub(250, 100))
+	ctx := context.Background()
+
+	tools, more, err := c.ListToolsPage(ctx, 2)
+	if err != nil || !more || len(tools) != 100 || tools[0].Name != "tool-100" {
+		t.Errorf("page 2 = %d tools starting %v, more=%v, err=%v", len(tools), tools, more, err)
+	}
+	tools, more, err = c.ListToolsPage(ctx, 3)
+	if err != nil || more || len(tools) != 50 {
+		t.Errorf("page 3 = %d tools, more=%v, err=%v", len(tools), more, err)
+	}
+	if _, _, err := c.ListToolsPage(ctx, 4); err == nil {
+		t.Error("expected error for a page past the end")
+	}
+	if _, _, err := c.ListToolsPage(ctx, 0); err == nil {
+		t.Error("expected error for page 0")
+	}
+}
+
+func TestClientListsAllPagesFromServer(t *testing.T) {
+	mcpServer := server.NewMCPServer("pagination-test", "1.0.0", server.WithToolCapabilities(false), server.WithPaginationLimit(2))
+	for i := range 5 {
+		mcpServer.AddTool(mcp.NewTool(fmt.Sprintf("tool-%d", i)), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	}
+	ts := server.NewTestStreamableHTTPServer(mcpServer)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if len(client.toolCache) != 5 {
+		t.Errorf("cached %d tools, want all 5", len(client.toolCache))
+	}
+	tools, more, err := client.ListToolsPage(ctx, 3)
+	if err != nil || more || len(tools) != 1 {
+		t.Errorf("page 3 = %v, more=%v, err=%v", tools, more, err)
+	}
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+)
+
+// ClientPool is a fixed set of independent sessions with one server, used to
+// spread concurrent load over several connections
+type ClientPool struct {
+	clients []*Client
+	cancel  context.CancelFunc
+}
+
+// NewClientPool opens size connections with the given configuration. If any
+// connection fails, the ones already open are closed again.
+func NewClientPool(ctx context.Context, cfg ClientConfig, size int) (*ClientPool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("pool size must be at least 1, got %d", size)
+	}
+	// Nobody consumes the notifications of pooled connections; dropping
+	// them keeps a chatty server from stalling the transport
+	cfg.NotificationOverflow = OverflowDropNewest
+	// Identical connections would append the same snapshots many times
+	cfg.CapabilityHistoryFile = ""
+	// and would all resume the one saved session
+	cfg.SessionFile = ""
+
+	poolCtx, cancel := context.WithCancel(ctx)
+	pool := &ClientPool{cancel: cancel}
+	var rateLimits *rateLimitTracker
+	for i := 0; i < size; i++ {
+		client := NewClient(cfg)
+		// The server's quota applies to all connections together
+		if rateLimits == nil {
+			rateLimits = client.rateLimits
+		} else {
+			client.rateLimits = rateLimits
+		}
+		if err := client.Run(poolCtx); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to open connection %d of %d: %w", i+1, size, err)
+		}
+		pool.clients = append(pool.clients, client)
+	}
+	return pool, nil
+}
+
+// Clients returns the connections of the pool
+func (p *ClientPool) Clients() []*Client {
+	return p.clients
+}
+
+// Close ends every session of the pool
+func (p *ClientPool) Close() {
+	p.cancel()
+	for _, client := range p.clients {
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultProtocolVersion is the MCP protocol version requested in initialize
+// unless ClientConfig.ProtocolVersion says otherwise
+const DefaultProtocolVersion = "2024-11-05"
+
+// ProtocolVersions returns the protocol versions mcp-debug speaks, newest
+// first. The server may answer initialize with any of them.
+func ProtocolVersions() []string {
+	return slices.Clone(mcp.ValidProtocolVersions)
+}
+
+// requestedProtocolVersion returns the protocol version sent in initialize
+func (c *Client) requestedProtocolVersion() string {
+	if c.config.ProtocolVersion != "" {
+		return c.config.ProtocolVersion
+	}
+	return DefaultProtocolVersion
+}
+
+// checkNegotiatedVersion logs the protocol version the server chose in
+// response to the requested one
+func (c *Client) checkNegotiatedVersion(negotiated string) {
+	requested := c.requestedProtocolVersion()
+	if negotiated == requested {
+		c.logger.Info("Negotiated protocol version %s", negotiated)
+		return
+	}
+	c.logger.Warning("Server negotiated protocol version %s instead of the requested %s", negotiated, requested)
+}
+
+// explainVersionError adds the supported versions to an error caused by the
+// server answering initialize with a version mcp-debug does not speak
+func explainVersionError(err error) error {
+	var versionErr mcp.UnsupportedProtocolVersionError
+	if !errors.As(err, &versionErr) {
+		return err
+	}
+	return fmt.Errorf("server answered with protocol version %q, but mcp-debug only speaks %s: %w",
+		versionErr.Version, strings.Join(mcp.ValidProtocolVersions, ", "), err)
+}
+
+// ProtocolVersionStatus is how a server responded to a requested protocol
+// version
+type ProtocolVersionStatus string
+
+const (
+	// ProtocolAccepted means the server agreed to the requested version
+	ProtocolAccepted ProtocolVersionStatus = "accepted"
+	// ProtocolCountered means the server answered with another version that
+	// mcp-debug speaks, so the session uses that one
+	ProtocolCountered ProtocolVersionStatus = "countered"
+	// ProtocolUnsupported means the server answered with a version that
+	// mcp-debug does not speak
+	ProtocolUnsupported ProtocolVersionStatus = "unsupported"
+	// ProtocolRejected means initialize failed, e.g. with a JSON-RPC error
+	ProtocolRejected ProtocolVersionStatus = "rejected"
+)
+
+// ProtocolVersionProbe is the outcome of initializing a session with one
+// requested protocol version
+type ProtocolVersionProbe struct {
+	Requested  string                `json:"requested"`
+	Negotiated string                `json:"negotiated,omitempty"`
+	Status     ProtocolVersionStatus `json:"status"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// ProbeProtocolVersions initializes a separate session for each version in
+// turn and reports which versions the server accepts, which it answers with
+// another version, and which fail. Each session is closed again right away.
+// The sessions share one OAuth token, so authorization happens at most once.
+func ProbeProtocolVersions(ctx context.Context, cfg ClientConfig, versions []string) []ProtocolVersionProbe {
+	// Probes only initialize; nothing is listed, recorded or resumed
+	cfg.NoInitialList = true
+	cfg.CapabilityHistoryFile = ""
+	cfg.SessionFile = ""
+	cfg.ResumeSessions = false
+	if cfg.TokenStore == nil {
+		cfg.TokenStore = client.NewMemoryTokenStore()
+	}
+
+	probes := make([]ProtocolVersionProbe, 0, len(versions))
+	for _, version := range versions {
+		if ctx.Err() != nil {
+			break
+		}
+		cfg.ProtocolVersion = version
+		probes = append(probes, probeProtocolVersion(ctx, cfg))
+	}
+	return probes
+}
+
+// probeProtocolVersion initializes one session with cfg.ProtocolVersion
+func probeProtocolVersion(ctx context.Context, cfg ClientConfig) ProtocolVersionProbe {
+	probe := ProtocolVersionProbe{Requested: cfg.ProtocolVersion}
+	c := NewClient(cfg)
+	defer func() { _ = c.Close() }()
+
+	err := c.connect(ctx)
+	var versionErr mcp.UnsupportedProtocolVersionError
+	switch {
+	case errors.As(err, &versionErr):
+		probe.Status = ProtocolUnsupported
+		probe.Negotiated = versionErr.Version
+		probe.Error = err.Error()
+	case err != nil:
+		probe.Status = ProtocolRejected
+		probe.Error = err.Error()
+	default:
+		probe.Negotiated = c.ServerInfo().ProtocolVersion
+		probe.Status = ProtocolAccepted
+		if probe.Negotiated != probe.Requested {
+			probe.Status = ProtocolCountered
+		}
+	}
+	return probe
+}
+
+// WriteProtocolVersionReport prints the probes as a table
+func WriteProtocolVersionReport(w io.Writer, probes []ProtocolVersionProbe) {
+	_, _ = fmt.Fprintf(w, "%-12s %-12s %s\n", "REQUESTED", "NEGOTIATED", "STATUS")
+	for _, probe := range probes {
+		negotiated := probe.Negotiated
+		if negotiated == "" {
+			negotiated = "-"
+		}
+		line := fmt.Sprintf("%-12s %-12s %s", probe.Requested, negotiated, probe.Status)
+		if probe.Status == ProtocolRejected {
+			line += ": " + probe.Error
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+}
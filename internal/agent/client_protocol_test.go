@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newVersionTestServer returns an MCP endpoint that accepts 2025-06-18,
+// answers 2025-03-26 with 2025-06-18, answers 2024-11-05 with a version
+// nobody speaks and rejects anything else with a JSON-RPC error
+func newVersionTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(versionTestHandler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// versionTestHandler serves the endpoint of newVersionTestServer
+var versionTestHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"params"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if r.Method != http.MethodPost || json.Unmarshal(body, &msg) != nil || msg.Method != methodInitialize {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	response := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+	answer := map[string]string{"2025-06-18": "2025-06-18", "2025-03-26": "2025-06-18", "2024-11-05": "0.1"}[msg.Params.ProtocolVersion]
+	if answer == "" {
+		response["error"] = map[string]any{"code": mcp.INVALID_PARAMS, "message": "Unsupported protocol version"}
+	} else {
+		response["result"] = map[string]any{
+			"protocolVersion": answer,
+			"capabilities":    map[string]any{},
+			"serverInfo":      map[string]any{"name": "versioned", "version": "1.0.0"},
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+})
+
+func TestProbeProtocolVersions(t *testing.T) {
+	server := newVersionTestServer(t)
+	probes := ProbeProtocolVersions(t.Context(), ClientConfig{
+		Endpoint:             server.URL + "/mcp",
+		Transport:            "streamable-http",
+		Logger:               NewLoggerWithWriter(false, false, false, io.Discard),
+		HTTPRetryMaxAttempts: 1,
+	}, []string{"2025-06-18", "2025-03-26", "2024-11-05", "2099-01-01"})
+
+	want := []ProtocolVersionProbe{
+		{Requested: "2025-06-18", Negotiated: "2025-06-18", Status: ProtocolAccepted},
+		{Requested: "2025-03-26", Negotiated: "2025-06-18", Status: ProtocolCountered},
+		{Requested: "2024-11-05", Negotiated: "0.1", Status: ProtocolUnsupported},
+		{Requested: "2099-01-01", Status: ProtocolRejected},
+	}
+	if len(probes) != len(want) {
+		t.Fatalf("got %d probes, want %d: %+v", len(probes), len(want), probes)
+	}
+	for i, probe := range probes {
+		errorText := probe.Error
+		probe.Error = ""
+		if probe != want[i] {
+			t.Errorf("probe %d = %+v, want %+v", i, probe, want[i])
+		}
+		if (errorText != "") != (i >= 2) {
+			t.Errorf("probe %d: unexpected error %q", i, errorText)
+		}
+	}
+	if !strings.Contains(probes[2].Error, "mcp-debug only speaks") || !strings.Contains(probes[3].Error, "Unsupported protocol version") {
+		t.Errorf("unexpected errors %q and %q", probes[2].Error, probes[3].Error)
+	}
+
+	var out bytes.Buffer
+	WriteProtocolVersionReport(&out, probes)
+	for _, want := range []string{
+		"2025-06-18   2025-06-18   accepted",
+		"2025-03-26   2025-06-18   countered",
+		"2024-11-05   0.1          unsupported\n",
+		"2099-01-01   -            rejected: ",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestProbeProtocolVersionsShareOAuthSession(t *testing.T) {
+	var mu sync.Mutex
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mu.Lock()
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			mu.Unlock()
+		}
+		versionTestHandler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	store := client.NewMemoryTokenStore()
+	if err := store.SaveToken(t.Context(), &client.Token{
+		AccessToken: "shared-token",
+		TokenType:   "Bearer",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	oauth := DefaultOAuthConfig()
+	oauth.Enabled = true
+	oauth.ClientID = "probe-client"
+	probes := ProbeProtocolVersions(t.Context(), ClientConfig{
+		Endpoint:             server.URL + "/mcp",
+		Transport:            "streamable-http",
+		Logger:               NewLoggerWithWriter(false, false, false, io.Discard),
+		OAuthConfig:          oauth,
+		HTTPRetryMaxAttempts: 1,
+		TokenStore:           store,
+	}, []string{"2025-06-18", "2025-03-26"})
+
+	for _, probe := range probes {
+		if probe.Status != ProtocolAccepted && probe.Status != ProtocolCountered {
+			t.Errorf("probe %s = %+v", probe.Requested, probe)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authorizations) < len(probes) {
+		t.Fatalf("server saw %d requests, want at least %d", len(authorizations), len(probes))
+	}
+	for i, authorization := range authorizations {
+		if authorization != "Bearer shared-token" {
+			t.Errorf("request %d authorized with %q", i, authorization)
+		}
+	}
+}
+
+func TestInitializeRequestsProtocolVersion(t *testing.T) {
+	var logs bytes.Buffer
+	stub := &stubMCPClient{initResult: &mcp.InitializeResult{ProtocolVersion: "2025-03-26"}}
+	c := newStubbedClient(t, stub)
+	c.logger = NewLoggerWithWriter(false, false, false, &logs)
+	c.config.ProtocolVersion = "2025-06-18"
+
+	if err := c.initialize(t.Context(), c.mcpClient()); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if want := "Server negotiated protocol version 2025-03-26 instead of the requested 2025-06-18"; !strings.Contains(logs.String(), want) {
+		t.Errorf("log missing %q:\n%s", want, logs.String())
+	}
+
+	// Without a configured version the default is requested and echoed
+	c = newStubbedClient(t, &stubMCPClient{})
+	if err := c.initialize(t.Context(), c.mcpClient()); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if got := c.ServerInfo().ProtocolVersion; got != DefaultProtocolVersion {
+		t.Errorf("expected %s to be requested, got %s", DefaultProtocolVersion, got)
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// RequestQueueStats summarises client-side request queuing
+type RequestQueueStats struct {
+	// MaxInFlight is the configured limit (0 means unlimited)
+	MaxInFlight int
+	// InFlight is the number of requests currently sent and awaiting a response
+	InFlight int
+	// Queued is the number of requests currently waiting for a free slot
+	Queued int
+	// PeakQueued is the highest queue depth observed
+	PeakQueued int
+	// TotalQueued counts requests that had to wait for a slot
+	TotalQueued int
+	// TotalWait is the cumulative time requests spent waiting in the queue
+	TotalWait time.Duration
+}
+
+// requestLimiter bounds the number of concurrent in-flight requests. Waiters
+// are served strictly in arrival order so no request starves under load.
+type requestLimiter struct {
+	max int
+
+	mu      sync.Mutex
+	waiters list.List // of chan struct{}
+	stats   RequestQueueStats
+}
+
+// newRequestLimiter creates a limiter; max <= 0 disables limiting
+func newRequestLimiter(max int) *requestLimiter {
+	if max < 0 {
+		max = 0
+	}
+	return &requestLimiter{max: max, stats: RequestQueueStats{MaxInFlight: max}}
+}
+
+// acquire waits for a free slot in FIFO order and returns a function that
+// releases it. It returns ctx's error if ctx is done before a slot frees up.
+func (l *requestLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	if l.max == 0 || (l.stats.InFlight < l.max && l.waiters.Len() == 0) {
+		l.stats.InFlight++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.stats.Queued++
+	l.stats.TotalQueued++
+	if l.stats.Queued > l.stats.PeakQueued {
+		l.stats.PeakQueued = l.stats.Queued
+	}
+	l.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		// release handed its slot to us and already counted it as in flight
+		l.mu.Lock()
+		l.stats.TotalWait += time.Since(start)
+		l.mu.Unlock()
+		return l.release, nil
+
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ready:
+			// The slot was handed over while we were giving up; pass it on
+			l.stats.TotalWait += time.Since(start)
+			l.releaseLocked()
+		default:
+			l.waiters.Remove(elem)
+			l.stats.Queued--
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot, handing it directly to the oldest waiter if any
+func (l *requestLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *requestLimiter) releaseLocked() {
+	front := l.waiters.Front()
+	if front == nil {
+		l.stats.InFlight--
+		return
+	}
+
+	// Transfer the slot: InFlight stays the same
+	l.waiters.Remove(front)
+	l.stats.Queued--
+	close(front.Value.(chan struct{}))
+}
+
+// snapshot returns a copy of the current stats
+func (l *requestLimiter) snapshot() RequestQueueStats {
+	if l == nil {
+		return RequestQueueStats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// RequestQueueStats returns a snapshot of client-side request queuing metrics
+func (c *Client) RequestQueueStats() RequestQueueStats {
+	return c.requestLimiter.snapshot()
+}
+
+// withRequestSlot runs fn, which sends one request of method, once an
+// in-flight slot is available. Its latency excludes the time spent queuing.
+func (c *Client) withRequestSlot(ctx context.Context, method string, fn func() error) error {
+	release, err := c.requestLimiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.timeRequest(method, fn)
+}
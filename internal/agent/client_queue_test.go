@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiterUnlimited(t *testing.T) {
+	l := newRequestLimiter(0)
+	for i := 0; i < 5; i++ {
+		if _, err := l.acquire(t.Context()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if stats := l.snapshot(); stats.InFlight != 5 || stats.TotalQueued != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRequestLimiterFIFO(t *testing.T) {
+	l := newRequestLimiter(1)
+
+	release, err := l.acquire(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			rel, err := l.acquire(t.Context())
+			if err != nil {
+				t.Errorf("waiter %d: %v", n, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, n)
+			mu.Unlock()
+			rel()
+		}(i)
+
+		// Wait until the waiter is queued so arrival order is deterministic
+		deadline := time.Now().Add(testTimeoutNormal)
+		for l.snapshot().Queued != i+1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("waiter %d was not queued", i)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if stats := l.snapshot(); stats.InFlight != 1 || stats.PeakQueued != 3 {
+		t.Errorf("unexpected stats while saturated: %+v", stats)
+	}
+
+	release()
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("expected FIFO order [0 1 2], got %v", order)
+	}
+	if stats := l.snapshot(); stats.InFlight != 0 || stats.Queued != 0 || stats.TotalQueued != 3 {
+		t.Errorf("unexpected final stats: %+v", stats)
+	}
+}
+
+func TestRequestLimiterCancel(t *testing.T) {
+	l := newRequestLimiter(1)
+	release, _ := l.acquire(t.Context())
+
+	ctx, cancel := context.WithTimeout(t.Context(), testDelayLong)
+	defer cancel()
+
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if stats := l.snapshot(); stats.Queued != 0 || stats.InFlight != 1 {
+		t.Errorf("cancelled waiter must leave the queue: %+v", stats)
+	}
+
+	release()
+	if stats := l.snapshot(); stats.InFlight != 0 {
+		t.Errorf("expected slot to be free, got %+v", stats)
+	}
+}
@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitStats summarises the rate limiting announced by the MCP server
+// in its responses
+type RateLimitStats struct {
+	// Limited counts the 429 Too Many Requests responses
+	Limited int
+	// LastRetryAfter is the Retry-After of the last 429 response
+	LastRetryAfter time.Duration
+	// Limit and Remaining are the last announced request quota and what is
+	// left of it; -1 if the server never sent them
+	Limit     int
+	Remaining int
+	// Reset is when the current quota window ends; zero if unknown
+	Reset time.Time
+	// BlockedUntil is when the server is expected to accept requests again
+	// after a 429 response or an exhausted quota; zero if not blocked
+	BlockedUntil time.Time
+	// Throttled is the total time WaitRateLimit held requests back
+	Throttled time.Duration
+}
+
+// Observed reports whether the server sent any rate limit information
+func (s RateLimitStats) Observed() bool {
+	return s.Limited > 0 || s.Limit >= 0 || s.Remaining >= 0
+}
+
+// rateLimitHeaderPrefixes are the prefixes of the rate limit headers in
+// use: the common X-RateLimit-*, the IETF draft RateLimit-* and X-Rate-Limit-*
+var rateLimitHeaderPrefixes = []string{"X-RateLimit-", "RateLimit-", "X-Rate-Limit-"}
+
+// rateLimitHeader returns the leading integer of the first rate limit
+// header with the given suffix, e.g. "Remaining". Draft headers may carry
+// a policy after the value, as in "100, 100;w=60".
+func rateLimitHeader(header http.Header, suffix string) (int64, bool) {
+	for _, prefix := range rateLimitHeaderPrefixes {
+		value := header.Get(prefix + suffix)
+		if value == "" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ",")
+		value, _, _ = strings.Cut(value, ";")
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// rateLimitReset interprets a reset header, which servers send either as
+// seconds until the window ends or as a Unix timestamp
+func rateLimitReset(header http.Header, now time.Time) (time.Time, bool) {
+	n, ok := rateLimitHeader(header, "Reset")
+	if !ok {
+		return time.Time{}, false
+	}
+	// Larger values than a year of seconds are timestamps
+	if n > 365*24*60*60 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
+
+// rateLimitTracker records the rate limiting of a connection and paces
+// requests to stay under it; safe for concurrent use
+type rateLimitTracker struct {
+	mu     sync.Mutex
+	stats  RateLimitStats
+	logger *Logger
+	now    func() time.Time
+	// nextSlot is the earliest time the next throttled request may start
+	nextSlot time.Time
+	// budget is the remaining quota less the requests reserved since the
+	// server last announced it
+	budget int
+}
+
+// newRateLimitTracker returns a tracker logging to logger
+func newRateLimitTracker(logger *Logger) *rateLimitTracker {
+	return &rateLimitTracker{
+		stats:  RateLimitStats{Limit: -1, Remaining: -1},
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// observe records the rate limit headers and 429 status of a response
+func (t *rateLimitTracker) observe(req *http.Request, resp *http.Response) {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	limit, hasLimit := rateLimitHeader(resp.Header, "Limit")
+	remaining, hasRemaining := rateLimitHeader(resp.Header, "Remaining")
+	if !limited && !hasLimit && !hasRemaining {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if hasLimit {
+		t.stats.Limit = int(limit)
+	}
+	if hasRemaining {
+		t.stats.Remaining = int(remaining)
+		t.budget = int(remaining)
+	}
+	reset, hasReset := rateLimitReset(resp.Header, now)
+	if hasReset {
+		t.stats.Reset = reset
+	}
+
+	quota := t.describeLocked(now)
+	switch {
+	case limited:
+		t.stats.Limited++
+		if after, ok := retryAfter(resp.Header, now); ok {
+			t.stats.LastRetryAfter = after
+			t.stats.BlockedUntil = now.Add(after)
+			quota = append([]string{fmt.Sprintf("retry after %s", after)}, quota...)
+		} else if hasReset {
+			t.stats.BlockedUntil = reset
+		}
+		t.logger.RateLimit(LevelWarn, "429 Too Many Requests for %s %s: %s", req.Method, req.URL.Redacted(), joinOrNone(quota))
+	case t.stats.Remaining == 0 && hasReset:
+		t.stats.BlockedUntil = reset
+		t.logger.RateLimit(LevelWarn, "Quota exhausted: %s", joinOrNone(quota))
+	default:
+		t.logger.RateLimit(LevelDebug, "%s %s: %s", req.Method, req.URL.Redacted(), joinOrNone(quota))
+	}
+}
+
+// describeLocked lists the known quota values; the caller holds mu
+func (t *rateLimitTracker) describeLocked(now time.Time) []string {
+	var parts []string
+	if t.stats.Limit >= 0 {
+		parts = append(parts, fmt.Sprintf("limit %d", t.stats.Limit))
+	}
+	if t.stats.Remaining >= 0 {
+		parts = append(parts, fmt.Sprintf("remaining %d", t.stats.Remaining))
+	}
+	if t.stats.Reset.After(now) {
+		parts = append(parts, fmt.Sprintf("resets in %s", t.stats.Reset.Sub(now).Round(time.Second)))
+	}
+	return parts
+}
+
+// joinOrNone joins the parts of a log message, or says there are none
+func joinOrNone(parts []string) string {
+	if len(parts) == 0 {
+		return "no rate limit headers"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reserve returns how long the next request should wait: until a 429 or
+// an exhausted quota is over, and spread evenly over the rest of the quota
+// window otherwise
+func (t *rateLimitTracker) reserve() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+
+	slot := now
+	if t.nextSlot.After(slot) {
+		slot = t.nextSlot
+	}
+	if t.stats.BlockedUntil.After(slot) {
+		slot = t.stats.BlockedUntil
+	}
+	var interval time.Duration
+	if t.budget > 0 && t.stats.Reset.After(slot) {
+		interval = t.stats.Reset.Sub(slot) / time.Duration(t.budget)
+		t.budget--
+	}
+	t.nextSlot = slot.Add(interval)
+
+	wait := slot.Sub(now)
+	t.stats.Throttled += wait
+	return wait
+}
+
+// snapshot returns a copy of the statistics
+func (t *rateLimitTracker) snapshot() RateLimitStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// rateLimitRoundTripper records the rate limiting announced in responses
+type rateLimitRoundTripper struct {
+	base    http.RoundTripper
+	tracker *rateLimitTracker
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.tracker.observe(req, resp)
+	}
+	return resp, err
+}
+
+// RateLimitStats returns the rate limiting observed on the connection
+func (c *Client) RateLimitStats() RateLimitStats {
+	if c.rateLimits == nil {
+		return RateLimitStats{Limit: -1, Remaining: -1}
+	}
+	return c.rateLimits.snapshot()
+}
+
+// WaitRateLimit waits until the next request is expected to stay under
+// the server's rate limit and returns how long it waited. Operations that
+// send many requests, such as load tests, call it before each request.
+func (c *Client) WaitRateLimit(ctx context.Context) (time.Duration, error) {
+	if c.rateLimits == nil {
+		return 0, nil
+	}
+	wait := c.rateLimits.reserve()
+	if wait <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("RateLimit-Limit", "100, 100;w=60")
+	header.Set("X-RateLimit-Remaining", "7")
+	if n, ok := rateLimitHeader(header, "Limit"); !ok || n != 100 {
+		t.Errorf("limit = %d, %v; want 100", n, ok)
+	}
+	if n, ok := rateLimitHeader(header, "Remaining"); !ok || n != 7 {
+		t.Errorf("remaining = %d, %v; want 7", n, ok)
+	}
+	if _, ok := rateLimitHeader(header, "Reset"); ok {
+		t.Error("expected no reset")
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	header.Set("X-RateLimit-Reset", "30")
+	if reset, _ := rateLimitReset(header, now); !reset.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("expected a delta reset 30s ahead, got %v", reset)
+	}
+	header.Set("X-RateLimit-Reset", "1700000060")
+	if reset, _ := rateLimitReset(header, now); !reset.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected a timestamp reset a minute ahead, got %v", reset)
+	}
+}
+
+func TestRateLimitTrackerObserve(t *testing.T) {
+	var logs bytes.Buffer
+	tracker := newRateLimitTracker(NewLoggerWithWriter(false, false, false, &logs))
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	req := httptest.NewRequest(http.MethodPost, "https://mcp.example.com/mcp", nil)
+
+	tracker.observe(req, &http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	if tracker.snapshot().Observed() {
+		t.Error("expected a response without rate limit headers to be ignored")
+	}
+
+	tracker.observe(req, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{
+		"Retry-After":           {"2"},
+		"X-Ratelimit-Limit":     {"10"},
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {"5"},
+	}})
+	stats := tracker.snapshot()
+	if stats.Limited != 1 || stats.LastRetryAfter != 2*time.Second || stats.Limit != 10 || stats.Remaining != 0 || !stats.BlockedUntil.Equal(now.Add(2*time.Second)) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	want := "[rate-limit] 429 Too Many Requests for POST https://mcp.example.com/mcp: retry after 2s, limit 10, remaining 0, resets in 5s"
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("log missing %q:\n%s", want, logs.String())
+	}
+}
+
+func TestRateLimitLogJSON(t *testing.T) {
+	var logs bytes.Buffer
+	logger := NewLoggerWithWriter(false, false, false, &logs)
+	logger.SetOutputFormat(OutputJSON)
+	logger.RateLimit(LevelWarn, "Quota exhausted: limit 10")
+
+	var entry logEntry
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Type != entryRateLimit || entry.Level != "warning" || entry.Message != "Quota exhausted: limit 10" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestRateLimitTrackerReserve(t *testing.T) {
+	tracker := newRateLimitTracker(NewLoggerWithWriter(false, false, false, &bytes.Buffer{}))
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	if wait := tracker.reserve(); wait != 0 {
+		t.Errorf("expected no wait without rate limit information, got %v", wait)
+	}
+
+	// Four requests left in the next four seconds are spread evenly
+	tracker.budget = 4
+	tracker.stats.Reset = now.Add(4 * time.Second)
+	for i, want := range []time.Duration{0, time.Second, 2 * time.Second} {
+		if wait := tracker.reserve(); wait != want {
+			t.Errorf("reservation %d waits %v, want %v", i, wait, want)
+		}
+	}
+
+	// A 429 holds requests back until its Retry-After has passed
+	tracker.nextSlot = time.Time{}
+	tracker.budget = 0
+	tracker.stats.BlockedUntil = now.Add(10 * time.Second)
+	if wait := tracker.reserve(); wait != 10*time.Second {
+		t.Errorf("expected to wait for the blocked period, got %v", wait)
+	}
+	if throttled := tracker.snapshot().Throttled; throttled != 13*time.Second {
+		t.Errorf("throttled = %v, want 13s", throttled)
+	}
+}
+
+func TestRunLoadTestThrottle(t *testing.T) {
+	var calls atomic.Int64
+	var c *Client
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if calls.Add(1) == 1 {
+			// The first call is rejected with a Retry-After of one second
+			httpReq := httptest.NewRequest(http.MethodPost, "https://mcp.example.com/mcp", nil)
+			c.rateLimits.observe(httpReq, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}})
+			return nil, errors.New("request failed with status 429")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}}
+	c = newStubbedClient(t, stub)
+
+	result, err := RunLoadTest(t.Context(), []*Client{c}, LoadTestConfig{
+		Tool:        "echo",
+		Concurrency: 1,
+		MaxRequests: 2,
+		Throttle:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RateLimited != 1 || result.Throttled < 900*time.Millisecond {
+		t.Errorf("expected the second call to wait for the Retry-After, got %d rate limited and %v throttled", result.RateLimited, result.Throttled)
+	}
+
+	var out bytes.Buffer
+	WriteLoadTestResult(&out, result)
+	for _, want := range []string{"Rate limited: 1 response(s) with status 429", "Throttled:    "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
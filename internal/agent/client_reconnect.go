@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultReconnectMaxAttempts is the number of attempts to re-establish
+	// a lost connection before giving up
+	DefaultReconnectMaxAttempts = 8
+	// DefaultReconnectInitialDelay is the wait after the first failed
+	// reconnect attempt
+	DefaultReconnectInitialDelay = 500 * time.Millisecond
+	// DefaultReconnectMaxDelay caps the exponentially growing wait between
+	// reconnect attempts
+	DefaultReconnectMaxDelay = 30 * time.Second
+)
+
+// surfaceSnapshot captures the server's advertised tools, resources and
+// prompts, keyed by name (or URI) with their JSON encoding as value so that
+// definition changes are detected as well as additions and removals
+type surfaceSnapshot struct {
+	tools        map[string]string
+	resources    map[string]string
+	prompts      map[string]string
+	instructions string
+}
+
+// surfaceDiff lists what changed for one kind of capability between two snapshots
+type surfaceDiff struct {
+	kind    string
+	added   []string
+	removed []string
+	changed []string
+}
+
+// empty reports whether the diff contains no changes
+func (d surfaceDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+// snapshotSurface captures the current caches
+func (c *Client) snapshotSurface() surfaceSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := surfaceSnapshot{
+		tools:     make(map[string]string, len(c.toolCache)),
+		resources: make(map[string]string, len(c.resourceCache)),
+		prompts:   make(map[string]string, len(c.promptCache)),
+
+		instructions: c.serverInfo.Instructions,
+	}
+	for _, tool := range c.toolCache {
+		snap.tools[tool.Name] = encodeForDiff(tool)
+	}
+	for _, resource := range c.resourceCache {
+		snap.resources[resource.URI] = encodeForDiff(resource)
+	}
+	for _, prompt := range c.promptCache {
+		snap.prompts[prompt.Name] = encodeForDiff(prompt)
+	}
+	return snap
+}
+
+// encodeForDiff returns a stable encoding of v used to detect definition changes
+func encodeForDiff(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// diffSurfaces compares two snapshots and returns one diff per capability kind
+func diffSurfaces(before, after surfaceSnapshot) []surfaceDiff {
+	return []surfaceDiff{
+		diffEntries("Tools", before.tools, after.tools),
+		diffEntries("Resources", before.resources, after.resources),
+		diffEntries("Prompts", before.prompts, after.prompts),
+	}
+}
+
+// diffEntries compares two keyed encodings
+func diffEntries(kind string, before, after map[string]string) surfaceDiff {
+	diff := surfaceDiff{kind: kind}
+	for key, encoded := range after {
+		old, exists := before[key]
+		switch {
+		case !exists:
+			diff.added = append(diff.added, key)
+		case old != encoded:
+			diff.changed = append(diff.changed, key)
+		}
+	}
+	for key := range before {
+		if _, exists := after[key]; !exists {
+			diff.removed = append(diff.removed, key)
+		}
+	}
+	sort.Strings(diff.added)
+	sort.Strings(diff.removed)
+	sort.Strings(diff.changed)
+	return diff
+}
+
+// showReconnectDiff displays a consolidated diff between the state before the
+// disconnect and the freshly listed state, so changes made during the outage
+// are not silently missed
+func (c *Client) showReconnectDiff(before surfaceSnapshot) {
+	after := c.snapshotSurface()
+	diffs := diffSurfaces(before, after)
+
+	instructionsChanged := before.instructions != after.instructions
+	changed := instructionsChanged
+	for _, diff := range diffs {
+		if !diff.empty() {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		c.logger.Info("No changes since disconnect")
+		return
+	}
+
+	c.logger.Info("Changes since disconnect:")
+	if instructionsChanged {
+		c.logger.Warning("  ~ Server instructions changed")
+	}
+	for _, diff := range diffs {
+		if diff.empty() {
+			continue
+		}
+		c.logger.Info("  %s:", diff.kind)
+		for _, name := range diff.added {
+			c.logger.Success("    + Added: %s", name)
+		}
+		for _, name := range diff.changed {
+			c.logger.Warning("    ~ Changed: %s", name)
+		}
+		for _, name := range diff.removed {
+			c.logger.Error("    - Removed: %s", name)
+		}
+	}
+}
+
+// reconnectPolicy paces the attempts to re-establish a lost connection
+type reconnectPolicy struct {
+	// maxAttempts is negative to retry until the context ends
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+}
+
+// newReconnectPolicy applies the defaults to the configured policy
+func newReconnectPolicy(cfg ClientConfig) reconnectPolicy {
+	p := reconnectPolicy{
+		maxAttempts:  cfg.ReconnectMaxAttempts,
+		initialDelay: cfg.ReconnectInitialDelay,
+		maxDelay:     cfg.ReconnectMaxDelay,
+	}
+	if p.maxAttempts == 0 {
+		p.maxAttempts = DefaultReconnectMaxAttempts
+	}
+	if p.initialDelay <= 0 {
+		p.initialDelay = DefaultReconnectInitialDelay
+	}
+	if p.maxDelay <= 0 {
+		p.maxDelay = DefaultReconnectMaxDelay
+	}
+	if p.maxDelay < p.initialDelay {
+		p.maxDelay = p.initialDelay
+	}
+	return p
+}
+
+// delay returns the wait after the given number of failed attempts
+func (p reconnectPolicy) delay(failures int) time.Duration {
+	return exponentialBackoff(p.initialDelay, p.maxDelay, failures)
+}
+
+// exponentialBackoff returns the wait after the given number of failed
+// attempts: the initial delay doubled per further failure, capped at
+// maxDelay, with "equal jitter" so clients that lost the same server do
+// not retry in step
+func exponentialBackoff(initialDelay, maxDelay time.Duration, failures int) time.Duration {
+	d := initialDelay
+	for i := 1; i < failures && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(d-half)+1))
+}
+
+// ReconnectReport describes a session restored by an automatic reconnect
+type ReconnectReport struct {
+	// Attempts is the number of reconnect attempts it took
+	Attempts int
+	// Downtime is the time from starting to reconnect until the session
+	// was restored
+	Downtime time.Duration
+	// Resumed is set when the previous session was resumed rather than
+	// replaced by a new one
+	Resumed bool
+	// Subscriptions is the number of resource subscriptions re-established
+	// and FailedSubscriptions those that could not be
+	Subscriptions       int
+	FailedSubscriptions []string
+}
+
+// reconnectAttempt is an automatic reconnect in progress
+type reconnectAttempt struct {
+	done chan struct{}
+	err  error
+}
+
+// reconnectWithBackoff re-establishes a lost connection, retrying with
+// exponential backoff until the policy gives up. Callers that lose the
+// connection while a reconnect is in progress wait for it instead of
+// starting another.
+func (c *Client) reconnectWithBackoff(ctx context.Context) error {
+	c.reconnectMu.Lock()
+	if attempt := c.reconnecting; attempt != nil {
+		c.reconnectMu.Unlock()
+		select {
+		case <-attempt.done:
+			return attempt.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	attempt := &reconnectAttempt{done: make(chan struct{})}
+	c.reconnecting = attempt
+	c.reconnectMu.Unlock()
+
+	c.endReconnectAttempt(attempt, c.reconnectLoop(ctx))
+	return attempt.err
+}
+
+// beginReconnectAttempt waits for the reconnect in progress, if any, and
+// registers a new one that automatic reconnects wait for until
+// endReconnectAttempt
+func (c *Client) beginReconnectAttempt(ctx context.Context) (*reconnectAttempt, error) {
+	for {
+		c.reconnectMu.Lock()
+		running := c.reconnecting
+		if running == nil {
+			attempt := &reconnectAttempt{done: make(chan struct{})}
+			c.reconnecting = attempt
+			c.reconnectMu.Unlock()
+			return attempt, nil
+		}
+		c.reconnectMu.Unlock()
+
+		select {
+		case <-running.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// endReconnectAttempt finishes attempt with err, releasing its waiters
+func (c *Client) endReconnectAttempt(attempt *reconnectAttempt, err error) {
+	attempt.err = err
+	c.reconnectMu.Lock()
+	c.reconnecting = nil
+	c.reconnectMu.Unlock()
+	close(attempt.done)
+}
+
+// reconnectLoop makes the reconnect attempts of reconnectWithBackoff
+func (c *Client) reconnectLoop(ctx context.Context) error {
+	policy := c.reconnectPolicy
+	start := time.Now()
+	sessionID := c.trackedSessionID()
+
+	var err error
+	for attempt := 1; policy.maxAttempts < 0 || attempt <= policy.maxAttempts; attempt++ {
+		if attempt > 1 {
+			wait := policy.delay(attempt - 1)
+			c.logger.Warning("Reconnect attempt %d failed: %v (retrying in %v)", attempt-1, err, wait.Round(time.Millisecond))
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+
+		var failed []string
+		failed, err = c.reconnect(ctx)
+		if err == nil {
+			report := ReconnectReport{
+				Attempts:            attempt,
+				Downtime:            time.Since(start),
+				Resumed:             sessionID != "" && c.trackedSessionID() == sessionID,
+				Subscriptions:       len(c.Subscriptions()) - len(failed),
+				FailedSubscriptions: failed,
+			}
+			if c.onReconnected != nil {
+				c.onReconnected(report)
+			} else {
+				c.logger.Success("Connection restored after %d attempt(s) in %v", report.Attempts, report.Downtime.Round(time.Millisecond))
+			}
+			return nil
+		}
+		if !retryReconnect(ctx, err) {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up after %d reconnect attempts: %w", policy.maxAttempts, err)
+}
+
+// retryReconnect reports whether a failed reconnect attempt is worth
+// repeating: authorization needs the user and a cancelled context ends the
+// session anyway
+func retryReconnect(ctx context.Context, err error) bool {
+	return ctx.Err() == nil &&
+		!errors.Is(err, ErrAuthRequired) &&
+		!errors.Is(err, ErrInsufficientScope)
+}
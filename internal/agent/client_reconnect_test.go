@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestDiffSurfaces(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.toolCache = []mcp.Tool{
+		{Name: "keep"},
+		{Name: "edit", Description: "old"},
+		{Name: "drop"},
+	}
+	c.promptCache = []mcp.Prompt{{Name: "greeting"}}
+	before := c.snapshotSurface()
+
+	c.toolCache = []mcp.Tool{
+		{Name: "keep"},
+		{Name: "edit", Description: "new"},
+		{Name: "fresh"},
+	}
+	after := c.snapshotSurface()
+
+	diffs := diffSurfaces(before, after)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %d", len(diffs))
+	}
+
+	tools := diffs[0]
+	if !reflect.DeepEqual(tools.added, []string{"fresh"}) {
+		t.Errorf("added = %v", tools.added)
+	}
+	if !reflect.DeepEqual(tools.removed, []string{"drop"}) {
+		t.Errorf("removed = %v", tools.removed)
+	}
+	if !reflect.DeepEqual(tools.changed, []string{"edit"}) {
+		t.Errorf("changed = %v", tools.changed)
+	}
+
+	if !diffs[1].empty() || !diffs[2].empty() {
+		t.Errorf("expected resources and prompts unchanged, got %+v %+v", diffs[1], diffs[2])
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	stub := &stubMCPClient{}
+	c := newStubbedClient(t, stub)
+
+	for _, uri := range []string{"file:///b", "file:///a"} {
+		if err := c.SubscribeResource(t.Context(), uri); err != nil {
+			t.Fatalf("subscribe %s: %v", uri, err)
+		}
+	}
+
+	stub.subscribed = nil
+	c.resubscribe(t.Context())
+
+	want := []string{"file:///a", "file:///b"}
+	if !reflect.DeepEqual(stub.subscribed, want) {
+		t.Errorf("resubscribed = %v, want %v", stub.subscribed, want)
+	}
+
+	// Failed re-subscriptions stay tracked so a later reconnect retries them
+	stub.subscribeErr = errors.New("connection reset by peer")
+	c.resubscribe(t.Context())
+	if got := c.Subscriptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriptions = %v, want %v", got, want)
+	}
+}
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	policy := newReconnectPolicy(ClientConfig{ReconnectInitialDelay: 100 * time.Millisecond, ReconnectMaxDelay: time.Second})
+
+	bounds := []struct {
+		failures int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{4, 400 * time.Millisecond, 800 * time.Millisecond},
+		{20, 500 * time.Millisecond, time.Second},
+	}
+	for _, b := range bounds {
+		for range 20 {
+			if d := policy.delay(b.failures); d < b.min || d > b.max {
+				t.Fatalf("delay(%d) = %v, want between %v and %v", b.failures, d, b.min, b.max)
+			}
+		}
+	}
+
+	defaults := newReconnectPolicy(ClientConfig{})
+	if defaults.maxAttempts != DefaultReconnectMaxAttempts || defaults.initialDelay != DefaultReconnectInitialDelay || defaults.maxDelay != DefaultReconnectMaxDelay {
+		t.Errorf("unexpected defaults %+v", defaults)
+	}
+}
+
+// flakyServer is an MCP server with a subscribable resource that answers
+// 503 while down
+type flakyServer struct {
+	url  string
+	down atomic.Bool
+	// rejected counts the requests answered with 503
+	rejected atomic.Int32
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	t.Helper()
+
+	upstream := server.NewMCPServer("flaky", "1.0.0", server.WithResourceCapabilities(true, false))
+	upstream.AddResource(mcp.NewResource("docs://status", "status"),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: "docs://status", Text: "ok"}}, nil
+		})
+	streamable := server.NewStreamableHTTPServer(upstream)
+
+	fs := &flakyServer{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fs.down.Load() {
+			fs.rejected.Add(1)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		streamable.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	fs.url = ts.URL + "/mcp"
+	return fs
+}
+
+func newFlakyClient(t *testing.T, fs *flakyServer, maxAttempts int) *Client {
+	t.Helper()
+
+	c := NewClient(ClientConfig{
+		Endpoint:              fs.url,
+		Transport:             "streamable-http",
+		Logger:                NewLoggerWithWriter(false, false, false, io.Discard),
+		ReconnectMaxAttempts:  maxAttempts,
+		ReconnectInitialDelay: 5 * time.Millisecond,
+		ReconnectMaxDelay:     20 * time.Millisecond,
+		// Rejected attempts must reach the reconnect loop
+		HTTPRetryMaxAttempts: 1,
+	})
+	if err := c.Run(t.Context()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestReconnectWithBackoff(t *testing.T) {
+	fs := newFlakyServer(t)
+	c := newFlakyClient(t, fs, 20)
+	if err := c.SubscribeResource(t.Context(), "docs://status"); err != nil {
+		t.Fatalf("SubscribeResource: %v", err)
+	}
+
+	var mu sync.Mutex
+	var reports []ReconnectReport
+	c.onReconnected = func(report ReconnectReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, report)
+	}
+
+	// The server comes back after rejecting a few attempts
+	fs.down.Store(true)
+	go func() {
+		for fs.rejected.Load() < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		fs.down.Store(false)
+	}()
+
+	// Concurrent callers share one reconnect
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.reconnectWithBackoff(t.Context())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("reconnectWithBackoff: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 1 {
+		t.Fatalf("expected one restored session, got %+v", reports)
+	}
+	if reports[0].Attempts < 2 || reports[0].Subscriptions != 1 || len(reports[0].FailedSubscriptions) != 0 {
+		t.Errorf("unexpected report %+v", reports[0])
+	}
+	if _, err := c.GetResource(t.Context(), "docs://status"); err != nil {
+		t.Errorf("GetResource after reconnecting: %v", err)
+	}
+}
+
+func TestReconnectWithBackoffGivesUp(t *testing.T) {
+	fs := newFlakyServer(t)
+	c := newFlakyClient(t, fs, 3)
+
+	fs.down.Store(true)
+	err := c.reconnectWithBackoff(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "gave up after 3 reconnect attempts") {
+		t.Fatalf("expected to give up, got %v", err)
+	}
+}
@@ -0,0 +1,656 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxStreamedFieldSize bounds the JSON values of a streamed resources/read
+// response that are kept whole: ids, errors, _meta and the URI and MIME type
+// of each content item. Only text and blob contents may be larger.
+const maxStreamedFieldSize = 1 << 20
+
+// resourceContentsSink receives the decoded contents of a streamed
+// resources/read response, one content item after the other
+type resourceContentsSink interface {
+	// Write receives decoded bytes of the current content item
+	io.Writer
+	// endContent completes the current content item. Its URI and MIME type
+	// are only known now, as they may follow the contents in the JSON.
+	endContent(item streamedContent) error
+}
+
+// streamedContent describes a content item of a streamed resource
+type streamedContent struct {
+	URI      string
+	MIMEType string
+	// Blob is set for base64 encoded contents
+	Blob bool
+}
+
+// resourceResponseDecoder decodes a JSON-RPC response to resources/read as
+// it is read, passing the text and blob contents to a sink without holding
+// them in memory
+type resourceResponseDecoder struct {
+	r    *bufio.Reader
+	sink resourceContentsSink
+
+	// id, rpcErr and meta are the raw id, error and result _meta of the
+	// message
+	id     json.RawMessage
+	rpcErr json.RawMessage
+	meta   json.RawMessage
+	// method is set if the message is a request or notification rather than
+	// a response
+	method string
+	// hasResult is set once the result of a response was decoded
+	hasResult bool
+	// chunk buffers decoded string bytes on their way to the sink
+	chunk []byte
+}
+
+// newResourceResponseDecoder returns a decoder of the JSON-RPC messages read
+// from r
+func newResourceResponseDecoder(r io.Reader, sink resourceContentsSink) *resourceResponseDecoder {
+	return &resourceResponseDecoder{r: bufio.NewReaderSize(r, 64*1024), sink: sink}
+}
+
+// decodeMessage decodes one JSON-RPC message. Only the contents of a result
+// reach the sink; requests and notifications are read past.
+func (d *resourceResponseDecoder) decodeMessage() error {
+	d.id, d.rpcErr, d.meta, d.method, d.hasResult = nil, nil, nil, "", false
+	return d.object(func(key string) error {
+		switch key {
+		case "id":
+			return d.capture(&d.id)
+		case "method":
+			return d.smallString(&d.method)
+		case "error":
+			return d.capture(&d.rpcErr)
+		case "result":
+			d.hasResult = true
+			return d.result()
+		}
+		return d.skip()
+	})
+}
+
+// response returns the error of a decoded response, converted like mcp-go
+// does, and the result _meta
+func (d *resourceResponseDecoder) response() (*mcp.Meta, error) {
+	if d.rpcErr != nil {
+		var details mcp.JSONRPCErrorDetails
+		if err := json.Unmarshal(d.rpcErr, &details); err != nil {
+			return nil, fmt.Errorf("invalid error in resources/read response: %w", err)
+		}
+		return nil, details.AsError()
+	}
+	if !d.hasResult {
+		return nil, errors.New("resources/read response has neither result nor error")
+	}
+	if d.meta == nil {
+		return nil, nil
+	}
+	meta := &mcp.Meta{}
+	if err := json.Unmarshal(d.meta, meta); err != nil {
+		return nil, fmt.Errorf("invalid _meta in resources/read response: %w", err)
+	}
+	return meta, nil
+}
+
+// result decodes the result object of resources/read
+func (d *resourceResponseDecoder) result() error {
+	return d.object(func(key string) error {
+		switch key {
+		case "contents":
+			return d.array(d.content)
+		case "_meta":
+			return d.capture(&d.meta)
+		}
+		return d.skip()
+	})
+}
+
+// content decodes one content item, streaming its text or blob to the sink
+func (d *resourceResponseDecoder) content() error {
+	var item streamedContent
+	err := d.object(func(key string) error {
+		switch key {
+		case "uri":
+			return d.smallString(&item.URI)
+		case "mimeType":
+			return d.smallString(&item.MIMEType)
+		case "text":
+			return d.stringTo(d.sink)
+		case "blob":
+			item.Blob = true
+			blob := &base64StreamWriter{w: d.sink}
+			if err := d.stringTo(blob); err != nil {
+				return err
+			}
+			return blob.Close()
+		}
+		return d.skip()
+	})
+	if err != nil {
+		return err
+	}
+	return d.sink.endContent(item)
+}
+
+// object decodes a JSON object, calling field for each key with the reader
+// positioned at its value
+func (d *resourceResponseDecoder) object(field func(key string) error) error {
+	if err := d.expect('{'); err != nil {
+		return err
+	}
+	first := true
+	for {
+		c, err := d.next()
+		if err != nil {
+			return err
+		}
+		if c == '}' && first {
+			return nil
+		}
+		if !first {
+			if c == '}' {
+				return nil
+			}
+			if c != ',' {
+				return fmt.Errorf("invalid JSON: expected ',' or '}', got %q", c)
+			}
+			if c, err = d.next(); err != nil {
+				return err
+			}
+		}
+		first = false
+		if c != '"' {
+			return fmt.Errorf("invalid JSON: expected object key, got %q", c)
+		}
+		var key string
+		if err := d.smallStringBody(&key); err != nil {
+			return err
+		}
+		if err := d.expect(':'); err != nil {
+			return err
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+}
+
+// array decodes a JSON array, calling element with the reader positioned at
+// each element
+func (d *resourceResponseDecoder) array(element func() error) error {
+	if err := d.expect('['); err != nil {
+		return err
+	}
+	c, err := d.next()
+	if err != nil {
+		return err
+	}
+	if c == ']' {
+		return nil
+	}
+	_ = d.r.UnreadByte()
+	for {
+		if err := element(); err != nil {
+			return err
+		}
+		c, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case ']':
+			return nil
+		case ',':
+		default:
+			return fmt.Errorf("invalid JSON: expected ',' or ']', got %q", c)
+		}
+	}
+}
+
+// smallString decodes a JSON string bounded by maxStreamedFieldSize
+func (d *resourceResponseDecoder) smallString(s *string) error {
+	if err := d.expect('"'); err != nil {
+		return err
+	}
+	return d.smallStringBody(s)
+}
+
+// smallStringBody decodes the rest of a string whose opening quote was read
+func (d *resourceResponseDecoder) smallStringBody(s *string) error {
+	var buf limitedBuffer
+	if err := d.stringBodyTo(&buf); err != nil {
+		return err
+	}
+	*s = buf.String()
+	return nil
+}
+
+// stringTo decodes a JSON string into w
+func (d *resourceResponseDecoder) stringTo(w io.Writer) error {
+	if err := d.expect('"'); err != nil {
+		return err
+	}
+	return d.stringBodyTo(w)
+}
+
+// stringBodyTo decodes the rest of a string whose opening quote was read
+// into w, in chunks
+func (d *resourceResponseDecoder) stringBodyTo(w io.Writer) error {
+	if d.chunk == nil {
+		d.chunk = make([]byte, 0, 32*1024)
+	}
+	chunk := d.chunk[:0]
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		_, err := w.Write(chunk)
+		chunk = chunk[:0]
+		return err
+	}
+	for {
+		if len(chunk) > cap(chunk)-2*utf8.UTFMax {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch {
+		case c == '"':
+			return flush()
+		case c == '\\':
+			if chunk, err = d.escape(chunk); err != nil {
+				return err
+			}
+		case c < 0x20:
+			return fmt.Errorf("invalid JSON: control character %q in string", c)
+		default:
+			chunk = append(chunk, c)
+		}
+	}
+}
+
+// escape decodes the escape sequence after a backslash and appends it
+func (d *resourceResponseDecoder) escape(chunk []byte) ([]byte, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return chunk, unexpectedEOF(err)
+	}
+	switch c {
+	case '"', '\\', '/':
+		return append(chunk, c), nil
+	case 'b':
+		return append(chunk, '\b'), nil
+	case 'f':
+		return append(chunk, '\f'), nil
+	case 'n':
+		return append(chunk, '\n'), nil
+	case 'r':
+		return append(chunk, '\r'), nil
+	case 't':
+		return append(chunk, '\t'), nil
+	case 'u':
+		r, err := d.hex4()
+		if err != nil {
+			return chunk, err
+		}
+		if utf16.IsSurrogate(r) {
+			// A surrogate pair is two escapes in a row; a lone surrogate
+			// becomes the replacement character, as with encoding/json
+			if next, err := d.r.Peek(2); err == nil && string(next) == `\u` {
+				_, _ = d.r.Discard(2)
+				low, err := d.hex4()
+				if err != nil {
+					return chunk, err
+				}
+				if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+					return utf8.AppendRune(chunk, pair), nil
+				}
+				chunk = utf8.AppendRune(chunk, utf8.RuneError)
+				r = low
+				if utf16.IsSurrogate(r) {
+					r = utf8.RuneError
+				}
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		return utf8.AppendRune(chunk, r), nil
+	}
+	return chunk, fmt.Errorf("invalid JSON: escape sequence \\%c", c)
+}
+
+// hex4 decodes the four hex digits of a \u escape
+func (d *resourceResponseDecoder) hex4() (rune, error) {
+	var r rune
+	for range 4 {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, fmt.Errorf("invalid JSON: \\u escape with %q", c)
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, nil
+}
+
+// capture keeps the raw JSON of the next value, bounded by
+// maxStreamedFieldSize
+func (d *resourceResponseDecoder) capture(raw *json.RawMessage) error {
+	var buf limitedBuffer
+	if err := d.copyValue(&buf); err != nil {
+		return err
+	}
+	*raw = bytes.Clone(buf.Bytes())
+	return nil
+}
+
+// skip reads past the next value
+func (d *resourceResponseDecoder) skip() error {
+	return d.copyValue(io.Discard)
+}
+
+// copyValue copies the raw JSON of the next value to w
+func (d *resourceResponseDecoder) copyValue(w io.Writer) error {
+	c, err := d.next()
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	if err := d.copyFrom(out, c); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// copyFrom copies the value starting with c to w
+func (d *resourceResponseDecoder) copyFrom(w *bufio.Writer, c byte) error {
+	_ = w.WriteByte(c)
+	switch c {
+	case '"':
+		escaped := false
+		for {
+			c, err := d.r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			_ = w.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				return nil
+			}
+		}
+	case '{', '[':
+		closing := byte('}')
+		if c == '[' {
+			closing = ']'
+		}
+		for {
+			c, err := d.next()
+			if err != nil {
+				return err
+			}
+			if c == closing {
+				return w.WriteByte(c)
+			}
+			if c == ',' || c == ':' {
+				_ = w.WriteByte(c)
+				continue
+			}
+			if err := d.copyFrom(w, c); err != nil {
+				return err
+			}
+		}
+	}
+	// A number or literal runs up to the next delimiter
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if bytes.IndexByte([]byte(",}] \t\r\n"), c) >= 0 {
+			return d.r.UnreadByte()
+		}
+		if err := w.WriteByte(c); err != nil {
+			return err
+		}
+	}
+}
+
+// expect reads the next non-space byte and checks that it is want
+func (d *resourceResponseDecoder) expect(want byte) error {
+	c, err := d.next()
+	if err != nil {
+		return err
+	}
+	if c != want {
+		return fmt.Errorf("invalid JSON: expected %q, got %q", want, c)
+	}
+	return nil
+}
+
+// next returns the next byte that is not white space
+func (d *resourceResponseDecoder) next() (byte, error) {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, nil
+	}
+}
+
+// unexpectedEOF turns the end of the input inside a value into
+// io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// limitedBuffer is a buffer failing writes beyond maxStreamedFieldSize
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxStreamedFieldSize {
+		return 0, fmt.Errorf("resources/read response has a field larger than %d bytes", maxStreamedFieldSize)
+	}
+	return b.Buffer.Write(p)
+}
+
+// base64StreamWriter decodes standard base64 written to it in pieces of
+// any length
+type base64StreamWriter struct {
+	w       io.Writer
+	pending []byte
+	decoded []byte
+}
+
+func (b *base64StreamWriter) Write(p []byte) (int, error) {
+	b.pending = append(b.pending, p...)
+	whole := len(b.pending) / 4 * 4
+	if whole == 0 {
+		return len(p), nil
+	}
+	if cap(b.decoded) < whole/4*3 {
+		b.decoded = make([]byte, whole/4*3)
+	}
+	n, err := base64.StdEncoding.Decode(b.decoded[:whole/4*3], b.pending[:whole])
+	if err != nil {
+		return 0, fmt.Errorf("invalid base64 in blob: %w", err)
+	}
+	b.pending = append(b.pending[:0], b.pending[whole:]...)
+	if _, err := b.w.Write(b.decoded[:n]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close fails if the blob ended within a group of four characters
+func (b *base64StreamWriter) Close() error {
+	if len(b.pending) > 0 {
+		return errors.New("invalid base64 in blob: truncated")
+	}
+	return nil
+}
+
+// sseDataReader reads the data of one server-sent event at a time, with
+// the data lines of an event joined by newlines like the sseParser does, but
+// without holding an event in memory whole. Read returns io.EOF at the end
+// of each event; nextEvent moves on to the next one.
+type sseDataReader struct {
+	r *bufio.Reader
+	// inValue is set while the rest of a data line is to be read
+	inValue bool
+	// sawData is set once the current event has a data line
+	sawData bool
+	// eventEnded is set at the end of the current event
+	eventEnded bool
+	// streamEnded is set at the end of the stream
+	streamEnded bool
+}
+
+// newSSEDataReader returns a reader of the events of the stream r
+func newSSEDataReader(r io.Reader) *sseDataReader {
+	return &sseDataReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Read implements io.Reader for the data of the current event
+func (s *sseDataReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if s.eventEnded || s.streamEnded {
+			break
+		}
+		if s.inValue {
+			if s.r.Buffered() == 0 {
+				if _, err := s.r.Peek(1); err != nil {
+					s.streamEnded = true
+					continue
+				}
+			}
+			chunk, _ := s.r.Peek(min(s.r.Buffered(), len(p)-n))
+			consumed := len(chunk)
+			if end := bytes.IndexByte(chunk, '\n'); end >= 0 {
+				chunk, consumed = chunk[:end], end+1
+				s.inValue = false
+			}
+			n += copy(p[n:], chunk)
+			_, _ = s.r.Discard(consumed)
+			continue
+		}
+
+		name, lineEnded, err := s.fieldName()
+		if err != nil {
+			s.streamEnded = true
+			continue
+		}
+		switch {
+		case name == "" && lineEnded:
+			// An empty line ends an event; events without data are skipped
+			s.eventEnded = s.sawData
+		case name == "data":
+			if s.sawData {
+				p[n] = '\n'
+				n++
+			}
+			s.sawData = true
+			if !lineEnded {
+				if next, err := s.r.Peek(1); err == nil && next[0] == ' ' {
+					_, _ = s.r.Discard(1)
+				}
+				s.inValue = true
+			}
+		case !lineEnded:
+			if err := s.skipLine(); err != nil {
+				s.streamEnded = true
+			}
+		}
+	}
+	if n == 0 && (s.eventEnded || s.streamEnded) {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// fieldName reads the field name at the start of a line, up to the colon
+// or the end of the line
+func (s *sseDataReader) fieldName() (string, bool, error) {
+	var name []byte
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return "", false, err
+		}
+		switch c {
+		case ':':
+			return string(name), false, nil
+		case '\n':
+			return string(bytes.TrimSuffix(name, []byte("\r"))), true, nil
+		}
+		if len(name) < len("data")+1 {
+			name = append(name, c)
+		}
+	}
+}
+
+// skipLine reads past the rest of a line
+func (s *sseDataReader) skipLine() error {
+	for {
+		_, err := s.r.ReadSlice('\n')
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return err
+		}
+	}
+}
+
+// nextEvent reads past the rest of the current event and moves to the
+// next one. It returns io.EOF at the end of the stream.
+func (s *sseDataReader) nextEvent() error {
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		return err
+	}
+	if s.streamEnded {
+		return io.EOF
+	}
+	s.inValue, s.sawData, s.eventEnded = false, false, false
+	return nil
+}
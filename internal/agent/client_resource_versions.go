@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// _meta keys of the conditional read convention. MCP has no native
+// conditional resources/read; a server that attaches an etag to its results
+// may honour it when sent back and reply with notModified and no contents.
+// The convention is not standard, so the etag is only sent back with
+// ClientConfig.ResourceETagMeta.
+const (
+	metaETag        = "etag"
+	metaIfNoneMatch = "ifNoneMatch"
+	metaNotModified = "notModified"
+)
+
+// ResourceVersion identifies the contents of a resource as last read
+type ResourceVersion struct {
+	// Digest is the hex encoded SHA-256 of the resource contents
+	Digest string
+	// Size is the decoded size of the contents in bytes
+	Size int64
+	// ETag is the entity tag the server attached in _meta, if any
+	ETag string
+}
+
+// ResourceVersion returns the version of a resource as last read with
+// ReadResourceIfChanged, and false if it has not been read that way in this
+// session
+func (c *Client) ResourceVersion(uri string) (ResourceVersion, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	version, ok := c.resourceVersions[uri]
+	return version, ok
+}
+
+// ReadResourceIfChanged re-reads a resource and reports whether its contents
+// differ from the previous ReadResourceIfChanged of it; reads with
+// GetResource do not count. The contents are compared by digest. With
+// ClientConfig.ResourceETagMeta, an etag the previous read carried is sent as
+// _meta.ifNoneMatch, and a reply with _meta.notModified is treated as
+// unchanged; the returned result is nil in that case. Unchanged reads are
+// logged as a single line rather than the full response.
+func (c *Client) ReadResourceIfChanged(ctx context.Context, uri string) (*mcp.ReadResourceResult, bool, error) {
+	previous, seen := c.ResourceVersion(uri)
+	conditional := c.resourceETagMeta && seen && previous.ETag != ""
+
+	meta := c.requestMeta()
+	if conditional {
+		if meta == nil {
+			meta = &mcp.Meta{}
+		}
+		if meta.AdditionalFields == nil {
+			meta.AdditionalFields = make(map[string]any)
+		}
+		meta.AdditionalFields[metaIfNoneMatch] = previous.ETag
+	}
+
+	ctx = c.withExchange(ctx, "resources/read")
+	result, err := c.readResource(ctx, uri, meta)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if conditional && metaFlag(result.Meta, metaNotModified) {
+		c.logger.Success("Resource %s not modified (etag %s)", uri, previous.ETag)
+		return nil, false, nil
+	}
+
+	version := newResourceVersion(result)
+	c.storeResourceVersion(uri, version)
+	if seen && version.Digest == previous.Digest {
+		c.logger.Success("Resource %s unchanged (%d bytes, sha256 %s)", uri, version.Size, version.ShortDigest())
+		return result, false, nil
+	}
+
+	c.logResponse(ctx, "resources/read", result)
+	c.logServerMeta("resources/read", result.Meta)
+	return result, true, nil
+}
+
+// ShortDigest returns an abbreviated digest for display
+func (v ResourceVersion) ShortDigest() string {
+	if len(v.Digest) > 12 {
+		return v.Digest[:12]
+	}
+	return v.Digest
+}
+
+// storeResourceVersion saves the version of a resource
+func (c *Client) storeResourceVersion(uri string, version ResourceVersion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resourceVersions == nil {
+		c.resourceVersions = make(map[string]ResourceVersion)
+	}
+	c.resourceVersions[uri] = version
+}
+
+// newResourceVersion digests the contents of a resource read result. Each
+// item's URI and MIME type are included so that relabelled contents count as
+// a change.
+func newResourceVersion(result *mcp.ReadResourceResult) ResourceVersion {
+	h := sha256.New()
+	for _, content := range result.Contents {
+		if text, ok := mcp.AsTextResourceContents(content); ok {
+			writeDigestFields(h, "text", text.URI, text.MIMEType, text.Text)
+		} else if blob, ok := mcp.AsBlobResourceContents(content); ok {
+			writeDigestFields(h, "blob", blob.URI, blob.MIMEType, blob.Blob)
+		}
+	}
+
+	version := ResourceVersion{
+		Digest: hex.EncodeToString(h.Sum(nil)),
+		Size:   ResourceContentsSize(result.Contents),
+	}
+	if result.Meta != nil {
+		if etag, ok := result.Meta.AdditionalFields[metaETag].(string); ok {
+			version.ETag = etag
+		}
+	}
+	return version
+}
+
+// writeDigestFields writes NUL-terminated fields so that field boundaries
+// cannot be shifted without changing the digest
+func writeDigestFields(w io.Writer, fields ...string) {
+	for _, field := range fields {
+		_, _ = io.WriteString(w, field)
+		_, _ = w.Write([]byte{0})
+	}
+}
+
+// metaFlag reports whether a boolean _meta field is set to true
+func metaFlag(meta *mcp.Meta, key string) bool {
+	if meta == nil {
+		return false
+	}
+	flag, _ := meta.AdditionalFields[key].(bool)
+	return flag
+}
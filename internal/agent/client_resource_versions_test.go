@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func textResult(text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{Contents: []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: "file:///a", MIMEType: "text/plain", Text: text},
+	}}
+}
+
+func TestReadResourceIfChanged(t *testing.T) {
+	t.Run("compares content digests", func(t *testing.T) {
+		body := "v1"
+		stub := &stubMCPClient{readResult: func(mcp.ReadResourceRequest) *mcp.ReadResourceResult {
+			return textResult(body)
+		}}
+		c := newStubbedClient(t, stub)
+
+		if _, changed, err := c.ReadResourceIfChanged(t.Context(), "file:///a"); err != nil || !changed {
+			t.Fatalf("expected first read to be a change, got changed=%v err=%v", changed, err)
+		}
+		result, changed, err := c.ReadResourceIfChanged(t.Context(), "file:///a")
+		if err != nil || changed || result == nil {
+			t.Fatalf("expected unchanged re-read with result, got changed=%v result=%v err=%v", changed, result, err)
+		}
+
+		body = "v2"
+		if _, changed, _ := c.ReadResourceIfChanged(t.Context(), "file:///a"); !changed {
+			t.Error("expected modified contents to be reported as changed")
+		}
+		if version, ok := c.ResourceVersion("file:///a"); !ok || version.Size != 2 {
+			t.Errorf("unexpected version %+v", version)
+		}
+	})
+
+	t.Run("sends etag back and honours notModified", func(t *testing.T) {
+		stub := &stubMCPClient{readResult: func(req mcp.ReadResourceRequest) *mcp.ReadResourceResult {
+			if req.Params.Meta != nil && req.Params.Meta.AdditionalFields[metaIfNoneMatch] == "abc" {
+				return &mcp.ReadResourceResult{Result: mcp.Result{Meta: &mcp.Meta{
+					AdditionalFields: map[string]any{metaNotModified: true},
+				}}}
+			}
+			result := textResult("large body")
+			result.Meta = &mcp.Meta{AdditionalFields: map[string]any{metaETag: "abc"}}
+			return result
+		}}
+		c := newStubbedClient(t, stub)
+		c.resourceETagMeta = true
+
+		if _, changed, _ := c.ReadResourceIfChanged(t.Context(), "file:///a"); !changed {
+			t.Fatal("expected first read to be a change")
+		}
+		result, changed, err := c.ReadResourceIfChanged(t.Context(), "file:///a")
+		if err != nil || changed || result != nil {
+			t.Fatalf("expected not modified without contents, got changed=%v result=%v err=%v", changed, result, err)
+		}
+		if len(stub.readReqs) != 2 || stub.readReqs[0].Params.Meta != nil {
+			t.Errorf("expected only the second request to be conditional, got %+v", stub.readReqs)
+		}
+	})
+
+	t.Run("etag is not sent without opting in", func(t *testing.T) {
+		stub := &stubMCPClient{readResult: func(mcp.ReadResourceRequest) *mcp.ReadResourceResult {
+			result := textResult("body")
+			result.Meta = &mcp.Meta{AdditionalFields: map[string]any{metaETag: "abc"}}
+			return result
+		}}
+		c := newStubbedClient(t, stub)
+
+		for range 2 {
+			if _, _, err := c.ReadResourceIfChanged(t.Context(), "file:///a"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		for i, req := range stub.readReqs {
+			if req.Params.Meta != nil {
+				t.Errorf("request %d carried _meta %+v", i, req.Params.Meta)
+			}
+		}
+	})
+
+	t.Run("GetResource does not count as a previous read", func(t *testing.T) {
+		stub := &stubMCPClient{readResult: func(mcp.ReadResourceRequest) *mcp.ReadResourceResult {
+			return textResult("same")
+		}}
+		c := newStubbedClient(t, stub)
+
+		if _, err := c.GetResource(t.Context(), "file:///a"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, changed, _ := c.ReadResourceIfChanged(t.Context(), "file:///a"); !changed {
+			t.Error("expected the first conditional read after GetResource to be a change")
+		}
+	})
+}
+
+func TestGetShowsUnchangedResourceAgain(t *testing.T) {
+	body := "v1"
+	stub := &stubMCPClient{readResult: func(mcp.ReadResourceRequest) *mcp.ReadResourceResult {
+		return textResult(body)
+	}}
+	c := newStubbedClient(t, stub)
+	c.serverCapabilities = &mcp.ServerCapabilities{Resources: &struct {
+		Subscribe   bool `json:"subscribe,omitempty"`
+		ListChanged bool `json:"listChanged,omitempty"`
+	}{}}
+	c.resourceCache = []mcp.Resource{{URI: "file:///a", MIMEType: "text/plain"}}
+	r := &REPL{client: c}
+
+	target := filepath.Join(t.TempDir(), "a.txt")
+	if err := r.handleGetResource(t.Context(), "file:///a", target); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"v1", "v2"} {
+		body = want
+		if err := r.handleGetResource(t.Context(), "file:///a", ""); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := r.display.last.save(&out); err != nil || out.String() != want {
+			t.Errorf("last result = %q, %v; want %q", out.String(), err, want)
+		}
+	}
+	if len(stub.readReqs) != 3 {
+		t.Errorf("expected every get to read the resource, got %d reads", len(stub.readReqs))
+	}
+}
+
+func TestResourceVersionDigestIncludesMIMEType(t *testing.T) {
+	a := newResourceVersion(textResult("x"))
+	relabelled := textResult("x")
+	relabelled.Contents[0] = mcp.TextResourceContents{URI: "file:///a", MIMEType: "application/json", Text: "x"}
+	if b := newResourceVersion(relabelled); a.Digest == b.Digest {
+		t.Error("expected MIME type change to alter the digest")
+	}
+}
@@ -0,0 +1,548 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// headerLastEventID asks the server to replay the events after the
+	// given one (streamable-http resumability)
+	headerLastEventID = "Last-Event-ID"
+	// maxSeenEventIDs bounds the event IDs remembered to detect events the
+	// server sends twice
+	maxSeenEventIDs = 1000
+	// resumeListenRetry is the wait before the resumed GET stream is
+	// reopened after it ended
+	resumeListenRetry = time.Second
+	// replayCaughtUp is how long a read of a resumed stream has to wait for
+	// the server before the stream counts as caught up: the backlog is
+	// sent at once, later events are fresh
+	replayCaughtUp = 100 * time.Millisecond
+)
+
+// sessionState is the streamable-http session a reconnect or a later run
+// resumes. It is also the format of the session file.
+type sessionState struct {
+	Endpoint  string `json:"endpoint"`
+	SessionID string `json:"sessionId"`
+	// LastEventID is the ID of the last SSE event received on any stream
+	LastEventID string `json:"lastEventId,omitempty"`
+	// Initialize is the server's initialize result, restored instead of
+	// initializing again
+	Initialize *mcp.InitializeResult `json:"initialize,omitempty"`
+	SavedAt    time.Time             `json:"savedAt"`
+}
+
+// sessionTracker records the session ID and the last SSE event ID of the
+// client's HTTP traffic, sends Last-Event-ID when a stream is reopened and
+// logs which events were replayed. It sits in the client's HTTP stack and
+// outlives reconnects.
+type sessionTracker struct {
+	logger *Logger
+	// path is the session file; empty keeps the state in memory
+	path string
+
+	mu    sync.Mutex
+	state sessionState
+	// seen holds the latest event IDs, oldest first, to detect duplicates
+	seen    []string
+	seenSet map[string]struct{}
+	// keepSession swallows the DELETE that ends the session on close, so
+	// the session can be resumed
+	keepSession bool
+	// stopListening ends the GET stream of a resumed session
+	stopListening context.CancelFunc
+}
+
+// newSessionTracker creates the tracker of a client, loading the state of
+// endpoint from path if there is one
+func newSessionTracker(endpoint, path string, logger *Logger) *sessionTracker {
+	t := &sessionTracker{
+		logger:  logger,
+		path:    path,
+		state:   sessionState{Endpoint: endpoint},
+		seenSet: make(map[string]struct{}),
+	}
+	if path == "" {
+		return t
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t
+	}
+	var saved sessionState
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	switch {
+	case err != nil:
+		logger.Warning("Ignoring session file %s: %v", path, err)
+	case saved.Endpoint != endpoint:
+		logger.Warning("Ignoring session file %s: it belongs to %s", path, saved.Endpoint)
+	default:
+		t.state = saved
+	}
+	return t
+}
+
+// roundTripper returns a round tripper tracking the requests sent to next
+func (t *sessionTracker) roundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &sessionTrackingRoundTripper{tracker: t, next: next}
+}
+
+// resumable returns the session to resume, or nil if there is none
+func (t *sessionTracker) resumable() *sessionState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state.SessionID == "" || t.state.Initialize == nil {
+		return nil
+	}
+	state := t.state
+	return &state
+}
+
+// setKeepSession controls whether closing the client ends the session
+func (t *sessionTracker) setKeepSession(keep bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keepSession = keep
+}
+
+// initialized records the initialize result of the current session
+func (t *sessionTracker) initialized(result *mcp.InitializeResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Initialize = result
+	t.saveLocked()
+}
+
+// forget drops the tracked session, so the next connection starts a new one
+func (t *sessionTracker) forget() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = sessionState{Endpoint: t.state.Endpoint}
+	t.saveLocked()
+}
+
+// moved drops the tracked session and tracks the sessions of endpoint
+// from now on
+func (t *sessionTracker) moved(endpoint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = sessionState{Endpoint: endpoint}
+	t.saveLocked()
+}
+
+// sessionTrackingRoundTripper feeds the exchanges of one connection to
+// the session tracker
+type sessionTrackingRoundTripper struct {
+	tracker *sessionTracker
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *sessionTrackingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := rt.tracker
+	t.mu.Lock()
+	keep := t.keepSession
+	lastEventID := t.state.LastEventID
+	t.mu.Unlock()
+
+	if req.Method == http.MethodDelete && keep {
+		t.logger.Info("Keeping session %s open for resumption", req.Header.Get(transport.HeaderKeySessionID))
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Status:     "204 No Content",
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	// A reopened GET stream picks up where the last one stopped
+	if req.Method == http.MethodGet && req.Header.Get(headerLastEventID) == "" && lastEventID != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(headerLastEventID, lastEventID)
+	}
+	resumed := req.Header.Get(headerLastEventID) != ""
+	if resumed {
+		t.logger.Info("Resuming stream after event %s", req.Header.Get(headerLastEventID))
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID := resp.Header.Get(transport.HeaderKeySessionID); sessionID != "" {
+		t.sessionStarted(sessionID)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body := &eventTrackingBody{ReadCloser: resp.Body, replaying: resumed}
+		body.parser = &sseParser{emit: func(id, data string) { t.observe(id, data, body.replaying) }}
+		resp.Body = body
+	}
+	return resp, nil
+}
+
+// sessionStarted records the session ID the server assigned; a new session
+// starts without events
+func (t *sessionTracker) sessionStarted(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if sessionID == t.state.SessionID {
+		return
+	}
+	t.state = sessionState{Endpoint: t.state.Endpoint, SessionID: sessionID}
+	t.seen = nil
+	clear(t.seenSet)
+	t.saveLocked()
+}
+
+// observe records an SSE event and logs whether it was replayed
+func (t *sessionTracker) observe(id, data string, replayed bool) {
+	message := describeSSEMessage(data)
+
+	t.mu.Lock()
+	_, duplicate := t.seenSet[id]
+	if id != "" && !duplicate {
+		t.seen = append(t.seen, id)
+		t.seenSet[id] = struct{}{}
+		if len(t.seen) > maxSeenEventIDs {
+			delete(t.seenSet, t.seen[0])
+			t.seen = t.seen[1:]
+		}
+	}
+	if id != "" {
+		t.state.LastEventID = id
+		t.saveLocked()
+	}
+	t.mu.Unlock()
+
+	switch {
+	case id != "" && duplicate:
+		t.logger.Warning("Event %s received twice (%s): the server replayed an event delivered before", id, message)
+	case replayed:
+		t.logger.Info("↺ Replayed event %s: %s", orNone(id), message)
+	default:
+		t.logger.Debug("Fresh event %s: %s", orNone(id), message)
+	}
+}
+
+// saveLocked writes the state to the session file; the caller holds mu
+func (t *sessionTracker) saveLocked() {
+	if t.path == "" {
+		return
+	}
+	t.state.SavedAt = time.Now()
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(t.path, data, 0o600)
+	}
+	if err != nil {
+		t.logger.Warning("Failed to save session file %s: %v", t.path, err)
+	}
+}
+
+// describeSSEMessage names the JSON-RPC message of an event for the log
+func describeSSEMessage(data string) string {
+	if data == "" {
+		return "no data"
+	}
+	var message struct {
+		ID     *mcp.RequestId `json:"id"`
+		Method string         `json:"method"`
+	}
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		return "not JSON-RPC"
+	}
+	switch {
+	case message.Method != "" && message.ID == nil:
+		return message.Method
+	case message.Method != "":
+		return fmt.Sprintf("%s request %v", message.Method, message.ID.Value())
+	case message.ID != nil:
+		return fmt.Sprintf("response to request %v", message.ID.Value())
+	}
+	return "unknown message"
+}
+
+// orNone returns s, or "(none)" if it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// eventTrackingBody feeds an SSE response body to a parser as it is read
+type eventTrackingBody struct {
+	io.ReadCloser
+	parser *sseParser
+	// replaying is set while a resumed stream delivers its backlog, until
+	// a read has to wait for the server
+	replaying bool
+}
+
+func (b *eventTrackingBody) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := b.ReadCloser.Read(p)
+	if b.replaying && time.Since(start) > replayCaughtUp {
+		b.replaying = false
+	}
+	if n > 0 {
+		b.parser.feed(p[:n])
+	}
+	return n, err
+}
+
+// sseParser splits an SSE stream into events
+type sseParser struct {
+	emit    func(id, data string)
+	partial []byte
+	id      string
+	data    []string
+}
+
+// feed parses the next bytes of the stream
+func (p *sseParser) feed(b []byte) {
+	p.partial = append(p.partial, b...)
+	for {
+		end := bytes.IndexByte(p.partial, '\n')
+		if end < 0 {
+			return
+		}
+		line := strings.TrimSuffix(string(p.partial[:end]), "\r")
+		p.partial = p.partial[end+1:]
+		p.line(line)
+	}
+}
+
+// line handles one line of the stream; an empty line ends the event
+func (p *sseParser) line(line string) {
+	if line == "" {
+		if p.id != "" || len(p.data) > 0 {
+			p.emit(p.id, strings.Join(p.data, "\n"))
+		}
+		p.id, p.data = "", nil
+		return
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "id":
+		p.id = value
+	case "data":
+		p.data = append(p.data, value)
+	}
+}
+
+// connect resumes the tracked session if there is one, falling back to a
+// new session if the server no longer knows it
+func (c *Client) connect(ctx context.Context) error {
+	if c.offline {
+		return ErrOffline
+	}
+	c.authServers.reset()
+	if c.sessions != nil {
+		if state := c.sessions.resumable(); state != nil {
+			err := c.resumeSession(ctx, state)
+			if err == nil {
+				return nil
+			}
+			c.logger.Warning("Could not resume session %s, starting a new one: %v", state.SessionID, err)
+		}
+	}
+	return c.connectSession(ctx, nil)
+}
+
+// trackedSessionID returns the ID of the current session, or "" if session
+// resumption is off
+func (c *Client) trackedSessionID() string {
+	if c.sessions == nil {
+		return ""
+	}
+	c.sessions.mu.Lock()
+	defer c.sessions.mu.Unlock()
+	return c.sessions.state.SessionID
+}
+
+// closeKeepingSession closes the connection without ending the session on
+// the server, so it can be resumed
+func (c *Client) closeKeepingSession() {
+	c.sessions.setKeepSession(true)
+	defer c.sessions.setKeepSession(false)
+	_ = c.mcpClient().Close()
+}
+
+// resumeSession connects to a session saved earlier instead of starting a
+// new one and reopens its stream, so the server can replay missed events
+func (c *Client) resumeSession(ctx context.Context, state *sessionState) error {
+	c.logger.Info("Resuming session %s (last event %s)...", state.SessionID, orNone(state.LastEventID))
+	if err := c.connectSession(ctx, state); err != nil {
+		if c.mcpClient() != nil {
+			c.closeKeepingSession()
+		}
+		return err
+	}
+	c.listenResumed()
+	c.logger.Success("Resumed session %s", state.SessionID)
+	return nil
+}
+
+// restoreSession takes the place of initialize for a resumed session
+func (c *Client) restoreSession(ctx context.Context, state *sessionState) error {
+	if mcpClient, ok := c.mcpClient().(*client.Client); ok {
+		if tracking, ok := mcpClient.GetTransport().(*requestTrackingTransport); ok {
+			tracking.SetProtocolVersion(state.Initialize.ProtocolVersion)
+		}
+	}
+	c.mu.Lock()
+	capabilities := state.Initialize.Capabilities
+	c.serverCapabilities = &capabilities
+	c.mu.Unlock()
+	c.storeServerInfo(state.Initialize)
+
+	// The server may have ended the session in the meantime
+	if _, err := c.Ping(ctx); err != nil {
+		return fmt.Errorf("session %s is no longer usable: %w", state.SessionID, err)
+	}
+	return nil
+}
+
+// listenResumed opens the standalone GET stream of a resumed session until
+// the client reconnects or closes. mcp-go only opens it after initialize,
+// so replayed events would otherwise never be requested.
+func (c *Client) listenResumed() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.sessions.mu.Lock()
+	c.sessions.stopListening = cancel
+	c.sessions.mu.Unlock()
+
+	go func() {
+		for {
+			err := c.readResumedStream(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, transport.ErrGetMethodNotAllowed) || errors.Is(err, transport.ErrSessionTerminated) {
+				c.logger.Warning("Resumed stream closed: %v", err)
+				return
+			}
+			if err != nil {
+				c.logger.Debug("Resumed stream ended: %v", err)
+			}
+			select {
+			case <-time.After(resumeListenRetry):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stopResumedListener ends the GET stream opened by listenResumed
+func (c *Client) stopResumedListener() {
+	if c.sessions == nil {
+		return
+	}
+	c.sessions.mu.Lock()
+	stop := c.sessions.stopListening
+	c.sessions.stopListening = nil
+	c.sessions.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// readResumedStream reads the GET stream once. Notifications are handled
+// like any other; replayed responses and requests have nobody waiting for
+// them any more and are only reported.
+func (c *Client) readResumedStream(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.currentEndpoint(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if state := c.sessions.resumable(); state != nil {
+		req.Header.Set(transport.HeaderKeySessionID, state.SessionID)
+		req.Header.Set(transport.HeaderKeyProtocolVersion, state.Initialize.ProtocolVersion)
+	}
+	if c.oauthHandler != nil {
+		authorization, err := c.oauthHandler.GetAuthorizationHeader(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := (&http.Client{Transport: c.httpClients().mcpTransport()}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed:
+		return transport.ErrGetMethodNotAllowed
+	case resp.StatusCode == http.StatusNotFound:
+		return transport.ErrSessionTerminated
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	parser := &sseParser{emit: func(id, data string) { c.dispatchResumedEvent(ctx, data) }}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		parser.feed(buf[:n])
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dispatchResumedEvent handles a message of the resumed stream
+func (c *Client) dispatchResumedEvent(ctx context.Context, data string) {
+	if data == "" {
+		return
+	}
+	var message struct {
+		ID     *mcp.RequestId `json:"id"`
+		Method string         `json:"method"`
+	}
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		return
+	}
+	switch {
+	case message.Method != "" && message.ID == nil:
+		var notification mcp.JSONRPCNotification
+		if err := json.Unmarshal([]byte(data), &notification); err == nil {
+			c.enqueueNotification(ctx, notification)
+		}
+	case message.Method != "":
+		c.logger.Warning("Not answering replayed %s request %v", message.Method, message.ID.Value())
+	default:
+		c.logger.Warning("Replayed response to request %v arrived after its caller gave up", message.ID.Value())
+	}
+}
@@ -0,0 +1,268 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestSSEParser(t *testing.T) {
+	type event struct{ id, data string }
+	var events []event
+	p := &sseParser{emit: func(id, data string) { events = append(events, event{id, data}) }}
+
+	stream := "id: 1\r\ndata: {\"a\":1}\r\n\r\n: comment\nevent: message\nid: 2\ndata: line one\ndata: line two\n\ndata: no id\n\n"
+	for _, chunk := range []string{stream[:7], stream[7:30], stream[30:]} {
+		p.feed([]byte(chunk))
+	}
+
+	want := []event{{"1", `{"a":1}`}, {"2", "line one\nline two"}, {"", "no id"}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestSessionTracker(t *testing.T) {
+	var deletes atomic.Int32
+	var lastEventID atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			deletes.Add(1)
+			return
+		case http.MethodGet:
+			lastEventID.Store(r.Header.Get(headerLastEventID))
+		}
+		w.Header().Set(transport.HeaderKeySessionID, "session-1")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "id: 41\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\"}\n\nid: 42\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n\n")
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	log := NewSessionLog(0)
+	tracker := newSessionTracker(ts.URL, path, NewLoggerWithWriter(true, false, false, log))
+	httpClient := &http.Client{Transport: tracker.roundTripper(nil)}
+
+	do := func(method string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL, nil)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	do(http.MethodPost)
+	tracker.initialized(&mcp.InitializeResult{ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION})
+	state := tracker.resumable()
+	if state == nil || state.SessionID != "session-1" || state.LastEventID != "42" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if fresh, _ := log.Tail(0, "Fresh event 42: response to request 1"); len(fresh) != 1 {
+		t.Errorf("expected the event to be logged as fresh, got %q", fresh)
+	}
+
+	// A reopened stream asks for the events after the last one; the server
+	// resending one already received is reported
+	do(http.MethodGet)
+	if got := lastEventID.Load(); got != "42" {
+		t.Errorf("Last-Event-ID = %v, want 42", got)
+	}
+	if twice, _ := log.Tail(0, "Event 42 received twice"); len(twice) != 1 {
+		t.Errorf("expected a duplicate warning, got %q", twice)
+	}
+	if replayed, _ := log.Tail(0, "Replayed event"); len(replayed) != 0 {
+		t.Errorf("events already received reported as replayed: %q", replayed)
+	}
+
+	tracker.setKeepSession(true)
+	do(http.MethodDelete)
+	tracker.setKeepSession(false)
+	if deletes.Load() != 0 {
+		t.Error("expected the DELETE to be held back while keeping the session")
+	}
+	do(http.MethodDelete)
+	if deletes.Load() != 1 {
+		t.Error("expected the DELETE to reach the server")
+	}
+
+	loaded := newSessionTracker(ts.URL, path, NewLoggerWithWriter(false, false, false, io.Discard))
+	if got := loaded.resumable(); got == nil || got.SessionID != "session-1" || got.LastEventID != "42" {
+		t.Errorf("state loaded from the session file = %+v", got)
+	}
+	if other := newSessionTracker("http://other/mcp", path, NewLoggerWithWriter(false, false, false, io.Discard)); other.resumable() != nil {
+		t.Error("expected the session of another endpoint to be ignored")
+	}
+}
+
+// resumeServer serves an MCP server whose standalone GET stream sends event
+// 1 and, when resumed after it, replays event 2
+type resumeServer struct {
+	url         string
+	initializes atomic.Int32
+	// lastEventIDs receives the Last-Event-ID of each GET stream
+	lastEventIDs chan string
+}
+
+func newResumeServer(t *testing.T) *resumeServer {
+	t.Helper()
+
+	streamable := server.NewStreamableHTTPServer(server.NewMCPServer("resumable", "1.0.0", server.WithToolCapabilities(false)), server.WithStateful(true))
+	rs := &resumeServer{lastEventIDs: make(chan string, 10)}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lastID := r.Header.Get(headerLastEventID)
+			rs.lastEventIDs <- lastID
+			next := 1
+			if lastID == "1" {
+				next = 2
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = fmt.Fprintf(w, "id: %d\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\",\"data\":\"event %d\"}}\n\n", next, next)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), `"method":"initialize"`) {
+				rs.initializes.Add(1)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		streamable.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	rs.url = ts.URL + "/mcp"
+	return rs
+}
+
+// nextLastEventID waits for the next GET stream and returns its Last-Event-ID
+func (rs *resumeServer) nextLastEventID(t *testing.T) string {
+	t.Helper()
+	select {
+	case id := <-rs.lastEventIDs:
+		return id
+	case <-time.After(testTimeoutLong):
+		t.Fatal("timed out waiting for the GET stream")
+		return ""
+	}
+}
+
+func TestResumeSession(t *testing.T) {
+	rs := newResumeServer(t)
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	// The first run listens on the GET stream (sampling turns it on) and
+	// receives event 1
+	first := NewClient(ClientConfig{
+		Endpoint:    rs.url,
+		Transport:   "streamable-http",
+		Logger:      NewLoggerWithWriter(false, false, false, io.Discard),
+		SessionFile: path,
+		Sampling:    SamplingConfig{Mode: SamplingAuto, Response: "ok"},
+	})
+	if err := first.Run(t.Context()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if id := rs.nextLastEventID(t); id != "" {
+		t.Fatalf("first stream sent Last-Event-ID %q", id)
+	}
+	waitForNotification(t, first, "event 1")
+	_ = first.Close()
+
+	var saved sessionState
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil || saved.SessionID == "" || saved.LastEventID != "1" {
+		t.Fatalf("unexpected session file %s: %v", data, err)
+	}
+
+	// The next run resumes the session without initializing and gets the
+	// missed event replayed
+	log := NewSessionLog(0)
+	second := NewClient(ClientConfig{
+		Endpoint:    rs.url,
+		Transport:   "streamable-http",
+		Logger:      NewLoggerWithWriter(false, false, false, log),
+		SessionFile: path,
+	})
+	if err := second.Run(t.Context()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if id := rs.nextLastEventID(t); id != "1" {
+		t.Errorf("resumed stream sent Last-Event-ID %q, want 1", id)
+	}
+	waitForNotification(t, second, "event 2")
+	if n := rs.initializes.Load(); n != 1 {
+		t.Errorf("expected one initialize, got %d", n)
+	}
+	if replayed, _ := log.Tail(0, "Replayed event 2: notifications/message"); len(replayed) != 1 {
+		t.Errorf("expected the replayed event to be logged, got %q", replayed)
+	}
+	_ = second.Close()
+
+	// A session the server has ended is replaced by a new one
+	req, _ := http.NewRequest(http.MethodDelete, rs.url, nil)
+	req.Header.Set(transport.HeaderKeySessionID, saved.SessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	third := NewClient(ClientConfig{
+		Endpoint:    rs.url,
+		Transport:   "streamable-http",
+		Logger:      NewLoggerWithWriter(false, false, false, io.Discard),
+		SessionFile: path,
+	})
+	if err := third.Run(t.Context()); err != nil {
+		t.Fatalf("Run after the session ended: %v", err)
+	}
+	defer func() { _ = third.Close() }()
+	if n := rs.initializes.Load(); n != 2 {
+		t.Errorf("expected a new session to be initialized, got %d initializes", n)
+	}
+	if state := third.sessions.resumable(); state == nil || state.SessionID == saved.SessionID {
+		t.Errorf("expected a new session, got %+v", state)
+	}
+}
+
+// waitForNotification waits for a notifications/message carrying data
+func waitForNotification(t *testing.T, c *Client, data string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), testTimeoutLong)
+	defer cancel()
+	for {
+		select {
+		case notification := <-c.notificationChan:
+			if notification.Params.AdditionalFields["data"] == data {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for notification %q", data)
+		}
+	}
+}
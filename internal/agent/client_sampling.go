@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// SamplingMode selects how sampling/createMessage requests from the server
+// are answered
+type SamplingMode string
+
+const (
+	// SamplingOff does not offer sampling; the server's requests fail
+	SamplingOff SamplingMode = "off"
+	// SamplingInteractive shows each request and lets the user type the
+	// response
+	SamplingInteractive SamplingMode = "interactive"
+	// SamplingAuto answers every request from the response template
+	SamplingAuto SamplingMode = "auto"
+)
+
+// SamplingModes lists the accepted sampling mode names
+var SamplingModes = []string{
+	string(SamplingOff),
+	string(SamplingInteractive),
+	string(SamplingAuto),
+}
+
+// ParseSamplingMode validates a sampling mode name
+func ParseSamplingMode(mode string) (SamplingMode, error) {
+	normalized := strings.ToLower(strings.TrimSpace(mode))
+	for _, m := range SamplingModes {
+		if m == normalized {
+			return SamplingMode(m), nil
+		}
+	}
+	return "", fmt.Errorf("invalid sampling mode: %s (must be one of: %s)", mode, strings.Join(SamplingModes, ", "))
+}
+
+const (
+	// DefaultSamplingResponse is the response template of SamplingAuto
+	DefaultSamplingResponse = "mcp-debug canned response to: {{.LastMessage}}"
+	// DefaultSamplingModel is the model name reported in sampling responses
+	DefaultSamplingModel = "mcp-debug"
+)
+
+// errSamplingDeclined is returned to the server when the user declines to
+// answer a sampling request
+var errSamplingDeclined = errors.New("user declined the sampling request")
+
+// SamplingConfig configures the answers to sampling requests
+type SamplingConfig struct {
+	Mode SamplingMode
+	// Response is a text/template rendered as the response in SamplingAuto
+	// mode (default: DefaultSamplingResponse). See samplingTemplateData for
+	// the available fields.
+	Response string
+	// Model is reported as the model that produced the response
+	// (default: DefaultSamplingModel)
+	Model string
+}
+
+// ParseSamplingTemplate checks that a response template is valid
+func ParseSamplingTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("sampling").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sampling response template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// samplingTemplateData is the data a response template is rendered with
+type samplingTemplateData struct {
+	// Messages are the conversation messages of the request
+	Messages []samplingTemplateMessage
+	// LastMessage is the text of the last message
+	LastMessage  string
+	SystemPrompt string
+	MaxTokens    int
+}
+
+// samplingTemplateMessage is one conversation message of a request
+type samplingTemplateMessage struct {
+	Role string
+	Text string
+}
+
+// samplingHandler answers sampling/createMessage requests; it implements
+// mcp-go's client.SamplingHandler
+type samplingHandler struct {
+	mode     SamplingMode
+	model    string
+	template *template.Template
+	logger   *Logger
+
+	mu sync.Mutex
+	// readLine is used in interactive mode; the REPL replaces the default
+	// stdin reader with its line editor
+	readLine lineReader
+	// out receives the description of interactive requests
+	out io.Writer
+}
+
+// newSamplingHandler creates the handler for cfg, or returns nil if
+// sampling is off
+func newSamplingHandler(cfg SamplingConfig, logger *Logger) (*samplingHandler, error) {
+	if cfg.Mode == "" || cfg.Mode == SamplingOff {
+		return nil, nil
+	}
+
+	response := cfg.Response
+	if response == "" {
+		response = DefaultSamplingResponse
+	}
+	tmpl, err := ParseSamplingTemplate(response)
+	if err != nil {
+		return nil, err
+	}
+	model := cfg.Model
+	if model == "" {
+		model = DefaultSamplingModel
+	}
+
+	return &samplingHandler{
+		mode:     cfg.Mode,
+		model:    model,
+		template: tmpl,
+		logger:   logger,
+		readLine: stdinLineReader(),
+		out:      os.Stdout,
+	}, nil
+}
+
+// setLineReader replaces the interactive input
+func (h *samplingHandler) setLineReader(readLine lineReader, out io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readLine = readLine
+	h.out = out
+}
+
+// CreateMessage implements client.SamplingHandler
+func (h *samplingHandler) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	h.logger.ServerRequest(string(mcp.MethodSamplingCreateMessage), request.CreateMessageParams)
+
+	var text string
+	var err error
+	if h.mode == SamplingInteractive {
+		// One prompt at a time; concurrent requests wait their turn
+		h.mu.Lock()
+		describeSamplingRequest(h.out, request.CreateMessageParams)
+		text, err = h.readLine(ctx, "sampling> ")
+		h.mu.Unlock()
+		if errors.Is(err, errInputInterrupted) || (err == nil && strings.TrimSpace(text) == "") {
+			err = errSamplingDeclined
+		}
+	} else {
+		text, err = h.render(request.CreateMessageParams)
+	}
+	if err != nil {
+		h.logger.Warning("Sampling request not answered: %v", err)
+		return nil, err
+	}
+
+	result := &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(text),
+		},
+		Model:      h.model,
+		StopReason: "endTurn",
+	}
+	h.logger.ServerRequestReply(string(mcp.MethodSamplingCreateMessage), result)
+	return result, nil
+}
+
+// render fills the response template with the request
+func (h *samplingHandler) render(params mcp.CreateMessageParams) (string, error) {
+	data := samplingTemplateData{
+		SystemPrompt: params.SystemPrompt,
+		MaxTokens:    params.MaxTokens,
+	}
+	for _, msg := range params.Messages {
+		data.Messages = append(data.Messages, samplingTemplateMessage{
+			Role: string(msg.Role),
+			Text: samplingContentText(msg.Content),
+		})
+	}
+	if n := len(data.Messages); n > 0 {
+		data.LastMessage = data.Messages[n-1].Text
+	}
+
+	var b strings.Builder
+	if err := h.template.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render sampling response: %w", err)
+	}
+	return b.String(), nil
+}
+
+// samplingContentText returns the text of a message, or a placeholder
+// naming the content type for images and audio
+func samplingContentText(content any) string {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text
+	case *mcp.TextContent:
+		return c.Text
+	case mcp.ImageContent:
+		return fmt.Sprintf("[image %s]", c.MIMEType)
+	case mcp.AudioContent:
+		return fmt.Sprintf("[audio %s]", c.MIMEType)
+	}
+	return fmt.Sprintf("[%T]", content)
+}
+
+// describeSamplingRequest renders a sampling request for the user to answer
+func describeSamplingRequest(w io.Writer, params mcp.CreateMessageParams) {
+	_, _ = fmt.Fprintln(w, "\nThe server requests a completion (sampling/createMessage):")
+	if params.SystemPrompt != "" {
+		_, _ = fmt.Fprintf(w, "  System: %s\n", params.SystemPrompt)
+	}
+	for _, msg := range params.Messages {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", msg.Role, samplingContentText(msg.Content))
+	}
+	if params.ModelPreferences != nil && len(params.ModelPreferences.Hints) > 0 {
+		hints := make([]string, 0, len(params.ModelPreferences.Hints))
+		for _, hint := range params.ModelPreferences.Hints {
+			hints = append(hints, hint.Name)
+		}
+		_, _ = fmt.Fprintf(w, "  Model hints: %s\n", strings.Join(hints, ", "))
+	}
+	_, _ = fmt.Fprintf(w, "  Max tokens: %d\n", params.MaxTokens)
+	_, _ = fmt.Fprintln(w, "Type the response (an empty line declines the request).")
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestParseSamplingMode(t *testing.T) {
+	for _, valid := range []string{"off", "Interactive", " auto "} {
+		if _, err := ParseSamplingMode(valid); err != nil {
+			t.Errorf("ParseSamplingMode(%q): %v", valid, err)
+		}
+	}
+	if _, err := ParseSamplingMode("llm"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestNewSamplingHandler(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	if h, err := newSamplingHandler(SamplingConfig{}, logger); h != nil || err != nil {
+		t.Errorf("zero config: handler = %v, err = %v; want neither", h, err)
+	}
+	if _, err := newSamplingHandler(SamplingConfig{Mode: SamplingAuto, Response: "{{.Missing"}, logger); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func samplingParams(texts ...string) mcp.CreateMessageParams {
+	params := mcp.CreateMessageParams{SystemPrompt: "be brief", MaxTokens: 42}
+	for _, text := range texts {
+		params.Messages = append(params.Messages, mcp.SamplingMessage{Role: mcp.RoleUser, Content: mcp.NewTextContent(text)})
+	}
+	return params
+}
+
+func TestSamplingHandlerAuto(t *testing.T) {
+	h, err := newSamplingHandler(SamplingConfig{
+		Mode:     SamplingAuto,
+		Response: "{{len .Messages}} messages, last {{.LastMessage}} ({{.SystemPrompt}}, {{.MaxTokens}})",
+		Model:    "canned",
+	}, NewLoggerWithWriter(false, false, false, io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := h.CreateMessage(context.Background(), mcp.CreateMessageRequest{CreateMessageParams: samplingParams("hi", "what is 2+2?")})
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if got := samplingContentText(result.Content); got != "2 messages, last what is 2+2? (be brief, 42)" {
+		t.Errorf("response = %q", got)
+	}
+	if result.Model != "canned" || result.Role != mcp.RoleAssistant {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestSamplingHandlerInteractive(t *testing.T) {
+	h, err := newSamplingHandler(SamplingConfig{Mode: SamplingInteractive}, NewLoggerWithWriter(false, false, false, io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answer := "typed by hand"
+	var shown strings.Builder
+	h.setLineReader(func(ctx context.Context, prompt string) (string, error) {
+		return answer, nil
+	}, &shown)
+
+	request := mcp.CreateMessageRequest{CreateMessageParams: samplingParams("question")}
+	result, err := h.CreateMessage(context.Background(), request)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if got := samplingContentText(result.Content); got != answer {
+		t.Errorf("response = %q, want %q", got, answer)
+	}
+	if result.Model != DefaultSamplingModel {
+		t.Errorf("model = %q", result.Model)
+	}
+
+	if !strings.Contains(shown.String(), "user: question") || !strings.Contains(shown.String(), "Max tokens: 42") {
+		t.Errorf("request not shown:\n%s", shown.String())
+	}
+
+	answer = "  "
+	if _, err := h.CreateMessage(context.Background(), request); !errors.Is(err, errSamplingDeclined) {
+		t.Errorf("empty answer: err = %v, want declined", err)
+	}
+}
+
+func TestClientAnswersSamplingDuringToolCall(t *testing.T) {
+	mcpServer := server.NewMCPServer("sampling-test", "1.0.0", server.WithToolCapabilities(false))
+	mcpServer.EnableSampling()
+	mcpServer.AddTool(mcp.NewTool("ask"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := mcpServer.RequestSampling(ctx, mcp.CreateMessageRequest{CreateMessageParams: samplingParams("ping?")})
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(samplingContentText(result.Content)), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(mcpServer)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+		Sampling:  SamplingConfig{Mode: SamplingAuto, Response: "pong to {{.LastMessage}}"},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	result, err := client.CallTool(ctx, "ask", nil)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if err := ToolResultError(result); err != nil {
+		t.Fatalf("tool failed: %v", err)
+	}
+	if got := samplingContentText(result.Content[0]); !strings.Contains(got, "pong to ping?") {
+		t.Errorf("tool result = %q", got)
+	}
+}
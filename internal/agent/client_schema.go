@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemaMessages renders validation errors
+var schemaMessages = message.NewPrinter(language.English)
+
+// schemaValidator validates tool arguments and structured results against
+// the schemas the server declares. Schemas are compiled on first use and
+// cached by their JSON, so re-listing unchanged tools does not recompile them.
+// The zero value is ready to use.
+type schemaValidator struct {
+	mu       sync.Mutex
+	compiled map[string]*compiledSchema
+}
+
+// compiledSchema is a cached compilation result; err is kept so a broken
+// schema is reported once instead of recompiled on every call
+type compiledSchema struct {
+	schema *jsonschema.Schema
+	err    error
+}
+
+// compile returns the compiled form of a schema document
+func (v *schemaValidator) compile(schemaJSON []byte) (*jsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := string(schemaJSON)
+	if cached, ok := v.compiled[key]; ok {
+		return cached.schema, cached.err
+	}
+	if v.compiled == nil {
+		v.compiled = make(map[string]*compiledSchema)
+	}
+
+	schema, err := compileSchema(schemaJSON)
+	v.compiled[key] = &compiledSchema{schema: schema, err: err}
+	return schema, err
+}
+
+// compileSchema compiles a schema document registered under a virtual URL,
+// so $ref to its own $defs resolves without loading anything
+func compileSchema(schemaJSON []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+	const url = "mem:///schema.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// validate checks value against a schema document and returns one message
+// per violation, e.g. "/count: got string, want integer". The error is set
+// when the schema itself cannot be compiled.
+func (v *schemaValidator) validate(schemaJSON []byte, value any) ([]string, error) {
+	schema, err := v.compile(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so Go values match what the server sees
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	var verr *jsonschema.ValidationError
+	if err := schema.Validate(instance); errors.As(err, &verr) {
+		return validationMessages(verr), nil
+	} else if err != nil {
+		return []string{err.Error()}, nil
+	}
+	return nil, nil
+}
+
+// validationMessages flattens a validation error into its leaf violations
+func validationMessages(verr *jsonschema.ValidationError) []string {
+	if len(verr.Causes) == 0 {
+		location := "/" + strings.Join(verr.InstanceLocation, "/")
+		return []string{fmt.Sprintf("%s: %s", location, verr.ErrorKind.LocalizedString(schemaMessages))}
+	}
+	var messages []string
+	for _, cause := range verr.Causes {
+		messages = append(messages, validationMessages(cause)...)
+	}
+	return messages
+}
+
+// inputSchemaJSON returns the input schema a tool declares, or false if it
+// declares none
+func inputSchemaJSON(tool mcp.Tool) ([]byte, bool) {
+	if len(tool.RawInputSchema) > 0 {
+		return tool.RawInputSchema, true
+	}
+	schema := tool.InputSchema
+	if schema.Type == "" && len(schema.Properties) == 0 && len(schema.Required) == 0 {
+		return nil, false
+	}
+	data, err := json.Marshal(schema)
+	return data, err == nil
+}
+
+// outputSchemaJSON returns the output schema a tool declares, or false if
+// it declares none
+func outputSchemaJSON(tool mcp.Tool) ([]byte, bool) {
+	if len(tool.RawOutputSchema) > 0 {
+		return tool.RawOutputSchema, true
+	}
+	if tool.OutputSchema.Type == "" {
+		return nil, false
+	}
+	data, err := json.Marshal(tool.OutputSchema)
+	return data, err == nil
+}
+
+// schemaViolation reports a schema mismatch. It is logged as a warning and,
+// with StrictSchema, returned as an ErrSchemaViolation error.
+func (c *Client) schemaViolation(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	c.logger.Warning("Schema mismatch: %s", msg)
+	if !c.strictSchema {
+		return nil
+	}
+	return withKind(ErrSchemaViolation, errors.New(msg))
+}
+
+// findTool returns the cached tool called name
+func (c *Client) findTool(name string) (mcp.Tool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, tool := range c.toolCache {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+// validateToolArguments checks call arguments against the tool's input
+// schema before they are sent
+func (c *Client) validateToolArguments(tool mcp.Tool, args map[string]interface{}) error {
+	schemaJSON, ok := inputSchemaJSON(tool)
+	if !ok {
+		return nil
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	violations, err := c.schemas.validate(schemaJSON, args)
+	if err != nil {
+		c.logger.Warning("Tool %s declares an input schema that cannot be compiled: %v", tool.Name, err)
+		return nil
+	}
+	if len(violations) > 0 {
+		return c.schemaViolation("arguments of %s do not match its input schema: %s", tool.Name, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// validateToolResult checks the structured content of a successful result
+// against the tool's output schema. A tool declaring an output schema must
+// return structured content.
+func (c *Client) validateToolResult(tool mcp.Tool, result *mcp.CallToolResult) error {
+	schemaJSON, ok := outputSchemaJSON(tool)
+	if !ok || result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return c.schemaViolation("%s declares an output schema but returned no structuredContent", tool.Name)
+	}
+
+	violations, err := c.schemas.validate(schemaJSON, result.StructuredContent)
+	if err != nil {
+		c.logger.Warning("Tool %s declares an output schema that cannot be compiled: %v", tool.Name, err)
+		return nil
+	}
+	if len(violations) > 0 {
+		return c.schemaViolation("structuredContent of %s does not match its output schema: %s", tool.Name, strings.Join(violations, "; "))
+	}
+	return nil
+}
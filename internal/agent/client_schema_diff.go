@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolDefinition decodes a tool into its JSON form, with the raw schemas
+// in place of the typed ones when the server sent them
+func toolDefinition(tool mcp.Tool) map[string]any {
+	return decodeDefinition(encodeForDiff(tool))
+}
+
+// toolChanges describes how a tool definition changed: its description,
+// title and annotations, and the properties of its input schema that were
+// added, removed or retyped, e.g. "+ limits.cpu (string)" or
+// "~ count: string → integer". It returns nil if the definitions match.
+func toolChanges(before, after map[string]any) []string {
+	var changes []string
+	for _, field := range []string{"title", "description", "annotations"} {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, field+" changed")
+		}
+	}
+
+	input := schemaChanges("", schemaObject(before["inputSchema"]), schemaObject(after["inputSchema"]))
+	if len(input) == 0 && !reflect.DeepEqual(before["inputSchema"], after["inputSchema"]) {
+		input = []string{"input schema changed"}
+	}
+	changes = append(changes, input...)
+
+	switch oldOutput, newOutput := before["outputSchema"], after["outputSchema"]; {
+	case oldOutput == nil && newOutput != nil:
+		changes = append(changes, "output schema added")
+	case oldOutput != nil && newOutput == nil:
+		changes = append(changes, "output schema removed")
+	case !reflect.DeepEqual(oldOutput, newOutput):
+		changes = append(changes, "output schema changed")
+	}
+
+	// Anything else, e.g. _meta, is still a change
+	if len(changes) == 0 && !reflect.DeepEqual(before, after) {
+		changes = append(changes, "definition changed")
+	}
+	return changes
+}
+
+// schemaChanges compares the properties of two object schemas, recursing
+// into nested objects ("parent.child") and array items ("list[]")
+func schemaChanges(path string, before, after map[string]any) []string {
+	oldProps, newProps := schemaObject(before["properties"]), schemaObject(after["properties"])
+	oldRequired, newRequired := requiredSet(before), requiredSet(after)
+
+	var changes []string
+	names := slices.Collect(maps.Keys(oldProps))
+	for name := range newProps {
+		if _, ok := oldProps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		propPath := name
+		if path != "" {
+			propPath = path + "." + name
+		}
+		oldProp, existed := oldProps[name]
+		newProp, exists := newProps[name]
+
+		switch {
+		case !existed:
+			change := fmt.Sprintf("+ %s (%s)", propPath, schemaTypeLabel(schemaObject(newProp)))
+			if newRequired[name] {
+				change += ", required"
+			}
+			changes = append(changes, change)
+		case !exists:
+			changes = append(changes, "- "+propPath)
+		default:
+			changes = append(changes, propertyChanges(propPath, schemaObject(oldProp), schemaObject(newProp))...)
+			if oldRequired[name] != newRequired[name] {
+				if newRequired[name] {
+					changes = append(changes, "~ "+propPath+": now required")
+				} else {
+					changes = append(changes, "~ "+propPath+": no longer required")
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// propertyChanges compares the schemas of a property present in both
+// versions
+func propertyChanges(path string, before, after map[string]any) []string {
+	oldType, newType := schemaTypeLabel(before), schemaTypeLabel(after)
+	if oldType != newType {
+		return []string{fmt.Sprintf("~ %s: %s → %s", path, oldType, newType)}
+	}
+
+	var changes []string
+	if oldEnum, newEnum := anyList(before["enum"]), anyList(after["enum"]); !reflect.DeepEqual(oldEnum, newEnum) {
+		changes = append(changes, fmt.Sprintf("~ %s: enum %s → %s", path, enumLabel(oldEnum), enumLabel(newEnum)))
+	}
+	switch oldType {
+	case "object":
+		changes = append(changes, schemaChanges(path, before, after)...)
+	case "array":
+		changes = append(changes, propertyChanges(path+"[]", schemaObject(before["items"]), schemaObject(after["items"]))...)
+	}
+	return changes
+}
+
+// schemaTypeLabel describes the declared type of a schema, e.g. "integer"
+// or "string|null"; "any" if it declares none
+func schemaTypeLabel(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			types = append(types, fmt.Sprint(v))
+		}
+		return strings.Join(types, "|")
+	}
+	return "any"
+}
+
+// enumLabel renders enum values as a|b|c, or "none" for no enum
+func enumLabel(values []any) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	labels := make([]string, 0, len(values))
+	for _, v := range values {
+		labels = append(labels, fmt.Sprint(v))
+	}
+	return strings.Join(labels, "|")
+}
+
+// requiredSet returns the required property names of an object schema
+func requiredSet(schema map[string]any) map[string]bool {
+	required := make(map[string]bool)
+	for _, name := range anyList(schema["required"]) {
+		if s, ok := name.(string); ok {
+			required[s] = true
+		}
+	}
+	return required
+}
+
+// schemaObject returns v as a JSON object, or nil if it is not one
+func schemaObject(v any) map[string]any {
+	object, _ := v.(map[string]any)
+	return object
+}
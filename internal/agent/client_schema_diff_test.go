@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestToolChanges(t *testing.T) {
+	base := `{
+		"type": "object",
+		"properties": {
+			"app": {"type": "string"},
+			"count": {"type": "string"},
+			"env": {"type": "string", "enum": ["staging", "production"]},
+			"limits": {"type": "object", "properties": {"cpu": {"type": "string"}}},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"legacy": {"type": "boolean"}
+		},
+		"required": ["app", "legacy"]
+	}`
+
+	tests := []struct {
+		name        string
+		description string
+		schema      string
+		want        []string
+	}{
+		{
+			name:   "unchanged",
+			schema: base,
+		},
+		{
+			name:        "description",
+			description: "Deploys an app",
+			schema:      base,
+			want:        []string{"description changed"},
+		},
+		{
+			name: "properties",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"app": {"type": "string"},
+					"count": {"type": "integer"},
+					"env": {"type": "string", "enum": ["staging", "production", "dev"]},
+					"limits": {"type": "object", "properties": {"cpu": {"type": "string"}, "memory": {"type": "integer"}}},
+					"tags": {"type": "array", "items": {"type": ["string", "null"]}},
+					"replicas": {"type": "integer"}
+				},
+				"required": ["app", "count", "replicas"]
+			}`,
+			want: []string{
+				"~ count: string → integer",
+				"~ count: now required",
+				"~ env: enum staging|production → staging|production|dev",
+				"- legacy",
+				"+ limits.memory (integer)",
+				"+ replicas (integer), required",
+				"~ tags[]: string → string|null",
+			},
+		},
+		{
+			name:   "other schema keywords",
+			schema: strings.Replace(base, `"type": "object",`, `"type": "object", "additionalProperties": false,`, 1),
+			want:   []string{"input schema changed"},
+		},
+	}
+
+	before := toolDefinition(mcp.NewToolWithRawSchema("deploy", "", json.RawMessage(base)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := toolDefinition(mcp.NewToolWithRawSchema("deploy", tt.description, json.RawMessage(tt.schema)))
+			if got := toolChanges(before, after); !slices.Equal(got, tt.want) {
+				t.Errorf("toolChanges =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolChangesOutputSchema(t *testing.T) {
+	before := toolDefinition(mcp.NewTool("stats"))
+	after := toolDefinition(mcp.NewTool("stats", mcp.WithOutputSchema[struct {
+		Total int `json:"total"`
+	}]()))
+	if got := toolChanges(before, after); !slices.Equal(got, []string{"output schema added"}) {
+		t.Errorf("unexpected changes: %q", got)
+	}
+}
+
+func TestShowToolDiffModified(t *testing.T) {
+	var out bytes.Buffer
+	c := NewClient(ClientConfig{
+		Endpoint:  "test://endpoint",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, &out),
+		Version:   "test",
+	})
+
+	oldTools := []mcp.Tool{mcp.NewTool("echo", mcp.WithString("message"))}
+	newTools := []mcp.Tool{mcp.NewTool("echo", mcp.WithNumber("message"))}
+	c.showToolDiff(oldTools, newTools)
+
+	for _, want := range []string{"~ Modified: echo", "~ message: string → number"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	c.showToolDiff(newTools, newTools)
+	if !strings.Contains(out.String(), "No tool changes detected") {
+		t.Errorf("expected no changes, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSchemaValidatorMessages(t *testing.T) {
+	var v schemaValidator
+	schema := []byte(`{"type":"object","properties":{"count":{"type":"integer"},"env":{"enum":["dev","prod"]}},"required":["count"]}`)
+
+	violations, err := v.validate(schema, map[string]any{"env": "test"})
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	got := strings.Join(violations, "; ")
+	if !strings.Contains(got, "missing property 'count'") || !strings.Contains(got, "/env:") {
+		t.Errorf("violations = %q", got)
+	}
+
+	if violations, err := v.validate(schema, map[string]any{"count": 2, "env": "dev"}); err != nil || len(violations) != 0 {
+		t.Errorf("valid value: violations = %q, err = %v", violations, err)
+	}
+	if _, err := v.validate([]byte(`{"type":"nonsense"}`), map[string]any{}); err == nil {
+		t.Error("expected error compiling an invalid schema")
+	}
+}
+
+func TestCallToolValidatesSchemas(t *testing.T) {
+	var calls int
+	structured := map[string]any{"total": "many"}
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultStructured(structured, "result"), nil
+	}}
+	c := newStubbedClient(t, stub)
+	c.toolCache = []mcp.Tool{mcp.NewTool("sum",
+		mcp.WithNumber("a", mcp.Required()),
+		mcp.WithOutputSchema[struct {
+			Total int `json:"total"`
+		}](),
+	)}
+	ctx := context.Background()
+
+	// Without strict checking, mismatches are only reported
+	if _, err := c.CallTool(ctx, "sum", map[string]interface{}{"a": "one"}); err != nil {
+		t.Errorf("non-strict call: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("tool called %d times, want 1", calls)
+	}
+
+	c.strictSchema = true
+	_, err := c.CallTool(ctx, "sum", map[string]interface{}{"a": "one"})
+	if !errors.Is(err, ErrSchemaViolation) || !strings.Contains(err.Error(), "arguments of sum do not match its input schema: /a:") {
+		t.Errorf("invalid arguments: err = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("call with invalid arguments was sent")
+	}
+
+	_, err = c.CallTool(ctx, "sum", map[string]interface{}{"a": 1})
+	if !errors.Is(err, ErrSchemaViolation) || !strings.Contains(err.Error(), "structuredContent of sum does not match its output schema: /total:") {
+		t.Errorf("invalid result: err = %v", err)
+	}
+
+	structured = map[string]any{"total": 1}
+	if _, err := c.CallTool(ctx, "sum", map[string]interface{}{"a": 1}); err != nil {
+		t.Errorf("valid call: %v", err)
+	}
+
+	// Tools missing from the cache are called without checks
+	if _, err := c.CallTool(ctx, "unknown", map[string]interface{}{"a": "one"}); err != nil {
+		t.Errorf("unknown tool: %v", err)
+	}
+}
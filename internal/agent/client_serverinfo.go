@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerInfo describes the connected server as reported in its initialize result
+type ServerInfo struct {
+	Name            string `json:"name"`
+	Version         string `json:"version,omitempty"`
+	ProtocolVersion string `json:"protocolVersion"`
+	// Instructions is the server's free-form usage guidance, often describing
+	// how its tools are meant to be combined
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ServerInfo returns what the server reported about itself during initialize
+func (c *Client) ServerInfo() ServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverInfo
+}
+
+// ServerInstructions returns the instructions from the initialize result, or
+// an empty string if the server did not provide any
+func (c *Client) ServerInstructions() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverInfo.Instructions
+}
+
+// storeServerInfo records the identity and instructions from an initialize result
+func (c *Client) storeServerInfo(result *mcp.InitializeResult) {
+	info := ServerInfo{
+		Name:            result.ServerInfo.Name,
+		Version:         result.ServerInfo.Version,
+		ProtocolVersion: result.ProtocolVersion,
+		Instructions:    result.Instructions,
+	}
+
+	c.mu.Lock()
+	c.serverInfo = info
+	c.mu.Unlock()
+
+	if info.Instructions != "" {
+		c.logger.Info("Server provided usage instructions (%d characters)", len(info.Instructions))
+		c.logger.Debug("Server instructions:\n%s", info.Instructions)
+	}
+}
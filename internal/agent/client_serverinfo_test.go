@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestInitializeStoresServerInfo(t *testing.T) {
+	stub := &stubMCPClient{initResult: &mcp.InitializeResult{
+		ProtocolVersion: "2025-06-18",
+		ServerInfo:      mcp.Implementation{Name: "demo", Version: "1.2.3"},
+		Instructions:    "Call login before any other tool.",
+	}}
+	c := newStubbedClient(t, stub)
+
+	if err := c.initialize(t.Context(), c.mcpClient()); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+
+	want := ServerInfo{
+		Name:            "demo",
+		Version:         "1.2.3",
+		ProtocolVersion: "2025-06-18",
+		Instructions:    "Call login before any other tool.",
+	}
+	if got := c.ServerInfo(); got != want {
+		t.Errorf("ServerInfo() = %+v, want %+v", got, want)
+	}
+	if got := c.ServerInstructions(); got != want.Instructions {
+		t.Errorf("ServerInstructions() = %q", got)
+	}
+}
+
+func TestSnapshotIncludesInstructions(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.serverInfo.Instructions = "v1"
+	before := c.snapshotSurface()
+
+	c.serverInfo.Instructions = "v2"
+	after := c.snapshotSurface()
+
+	if before.instructions == after.instructions {
+		t.Error("expected instructions change to be captured by snapshots")
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolProgress is an incremental update received while a tool call is still
+// running, delivered as notifications/progress on the call's response stream
+type ToolProgress struct {
+	// Progress is the amount of work done so far
+	Progress float64
+	// Total is the total amount of work, or 0 if unknown
+	Total float64
+	// Message is the partial output or status text sent with the update
+	Message string
+}
+
+// ProgressHandler receives incremental updates for a single request
+type ProgressHandler func(update ToolProgress)
+
+// progressRouter maps progress tokens of in-flight requests to the handler
+// that renders their updates; safe for concurrent use
+type progressRouter struct {
+	next     atomic.Int64
+	handlers sync.Map // token string -> ProgressHandler
+}
+
+// register allocates a progress token routed to handler and returns it with
+// a function that removes the route once the request has completed
+func (p *progressRouter) register(handler ProgressHandler) (string, func()) {
+	token := fmt.Sprintf("mcp-debug-%d", p.next.Add(1))
+	p.handlers.Store(token, handler)
+	return token, func() { p.handlers.Delete(token) }
+}
+
+// dispatch delivers a notifications/progress notification to the handler
+// registered for its token. It reports whether the notification was consumed.
+func (p *progressRouter) dispatch(notification mcp.JSONRPCNotification) bool {
+	if notification.Method != string(mcp.MethodNotificationProgress) {
+		return false
+	}
+
+	fields := notification.Params.AdditionalFields
+	token, ok := fields["progressToken"]
+	if !ok {
+		return false
+	}
+
+	handler, ok := p.handlers.Load(fmt.Sprint(token))
+	if !ok {
+		return false
+	}
+
+	update := ToolProgress{}
+	update.Progress, _ = fields["progress"].(float64)
+	update.Total, _ = fields["total"].(float64)
+	update.Message, _ = fields["message"].(string)
+
+	handler.(ProgressHandler)(update)
+	return true
+}
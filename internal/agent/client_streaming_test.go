@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func progressNotification(fields map[string]any) mcp.JSONRPCNotification {
+	n := mcp.JSONRPCNotification{}
+	n.Method = string(mcp.MethodNotificationProgress)
+	n.Params.AdditionalFields = fields
+	return n
+}
+
+func TestProgressRouter(t *testing.T) {
+	var router progressRouter
+	var updates []ToolProgress
+
+	token, done := router.register(func(update ToolProgress) {
+		updates = append(updates, update)
+	})
+
+	consumed := router.dispatch(progressNotification(map[string]any{
+		"progressToken": token,
+		"progress":      float64(1),
+		"total":         float64(4),
+		"message":       "first chunk",
+	}))
+	if !consumed {
+		t.Fatal("expected notification for registered token to be consumed")
+	}
+	if len(updates) != 1 || updates[0] != (ToolProgress{Progress: 1, Total: 4, Message: "first chunk"}) {
+		t.Errorf("unexpected updates: %+v", updates)
+	}
+
+	if router.dispatch(progressNotification(map[string]any{"progressToken": "someone-else", "progress": float64(1)})) {
+		t.Error("expected notification for unknown token to pass through")
+	}
+
+	other := mcp.JSONRPCNotification{}
+	other.Method = notificationToolsListChanged
+	if router.dispatch(other) {
+		t.Error("expected non-progress notification to pass through")
+	}
+
+	done()
+	if router.dispatch(progressNotification(map[string]any{"progressToken": token, "progress": float64(2)})) {
+		t.Error("expected notification after completion to pass through")
+	}
+	if len(updates) != 1 {
+		t.Errorf("expected no further updates after completion, got %d", len(updates))
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerSupportsSubscriptions reports whether the server allows subscribing
+// to resource updates
+func (c *Client) ServerSupportsSubscriptions() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverCapabilities != nil &&
+		c.serverCapabilities.Resources != nil &&
+		c.serverCapabilities.Resources.Subscribe
+}
+
+// SubscribeResource asks the server to send resources/updated notifications
+// for uri. The subscription is re-established automatically after a reconnect.
+func (c *Client) SubscribeResource(ctx context.Context, uri string) error {
+	if err := c.sendSubscribe(ctx, uri); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]struct{})
+	}
+	c.subscriptions[uri] = struct{}{}
+	c.mu.Unlock()
+	return nil
+}
+
+// UnsubscribeResource cancels a subscription created by SubscribeResource
+func (c *Client) UnsubscribeResource(ctx context.Context, uri string) error {
+	req := mcp.UnsubscribeRequest{
+		Params: mcp.UnsubscribeParams{URI: uri},
+	}
+
+	ctx = c.withExchange(ctx, methodResourcesUnsubscribe)
+	c.logRequest(ctx, methodResourcesUnsubscribe, req.Params)
+
+	if err := c.timeRequest(methodResourcesUnsubscribe, func() error {
+		return c.mcpClient().Unsubscribe(ctx, req)
+	}); err != nil {
+		c.logger.Error("Unsubscribe failed: %v", err)
+		return classifyError(err)
+	}
+
+	c.logResponse(ctx, methodResourcesUnsubscribe, nil)
+
+	c.mu.Lock()
+	delete(c.subscriptions, uri)
+	c.mu.Unlock()
+	return nil
+}
+
+// Subscriptions returns the URIs of active resource subscriptions, sorted
+func (c *Client) Subscriptions() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	uris := make([]string, 0, len(c.subscriptions))
+	for uri := range c.subscriptions {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// sendSubscribe sends a single resources/subscribe request
+func (c *Client) sendSubscribe(ctx context.Context, uri string) error {
+	req := mcp.SubscribeRequest{
+		Params: mcp.SubscribeParams{URI: uri},
+	}
+
+	ctx = c.withExchange(ctx, methodResourcesSubscribe)
+	c.logRequest(ctx, methodResourcesSubscribe, req.Params)
+
+	if err := c.timeRequest(methodResourcesSubscribe, func() error {
+		return c.mcpClient().Subscribe(ctx, req)
+	}); err != nil {
+		c.logger.Error("Subscribe failed: %v", err)
+		return classifyError(err)
+	}
+
+	c.logResponse(ctx, methodResourcesSubscribe, nil)
+	return nil
+}
+
+// resubscribe re-establishes all tracked subscriptions on a new session and
+// returns those that failed. They are kept so a later reconnect can retry
+// them.
+func (c *Client) resubscribe(ctx context.Context) []string {
+	uris := c.Subscriptions()
+	if len(uris) == 0 {
+		return nil
+	}
+
+	c.logger.Info("Re-establishing %d resource subscription(s)...", len(uris))
+	var failed []string
+	for _, uri := range uris {
+		if err := c.sendSubscribe(ctx, uri); err != nil {
+			c.logger.Warning("  ✗ %s: %v", uri, err)
+			failed = append(failed, uri)
+			continue
+		}
+		c.logger.Success("  ✓ %s", uri)
+	}
+	return failed
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// listResourceTemplates lists all available resource templates. Templates are
+// optional even for servers with the resources capability, so callers treat
+// failures as non-fatal.
+func (c *Client) listResourceTemplates(ctx context.Context) error {
+	templates, err := collectPages(ctx, c, methodResourcesTemplatesList, c.resourceTemplatesPage)
+	if err != nil {
+		c.logger.Error("ListResourceTemplates failed: %v", err)
+		return classifyError(err)
+	}
+
+	c.mu.Lock()
+	c.templateCache = templates
+	c.mu.Unlock()
+
+	return nil
+}
+
+// refreshResourceTemplates re-lists templates, logging instead of failing
+// since many servers do not implement resources/templates/list
+func (c *Client) refreshResourceTemplates(ctx context.Context) {
+	if !c.ServerSupportsResources() {
+		return
+	}
+	if err := c.listResourceTemplates(ctx); err != nil {
+		c.logger.Debug("Resource templates unavailable: %v", err)
+	}
+}
+
+// ServerSupportsCompletions reports whether the server advertised the
+// completions capability
+func (c *Client) ServerSupportsCompletions() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverCapabilities != nil && c.serverCapabilities.Completions != nil
+}
+
+// TemplateVariables returns the variable names of a resource template in the
+// order they appear
+func TemplateVariables(tmpl *mcp.ResourceTemplate) []string {
+	if tmpl == nil || tmpl.URITemplate == nil || tmpl.URITemplate.Template == nil {
+		return nil
+	}
+	return tmpl.URITemplate.Varnames()
+}
+
+// templateURI returns the raw URI template of tmpl, or "" if the server sent
+// none or one that could not be parsed
+func templateURI(tmpl *mcp.ResourceTemplate) string {
+	if tmpl == nil || tmpl.URITemplate == nil || tmpl.URITemplate.Template == nil {
+		return ""
+	}
+	return tmpl.URITemplate.Raw()
+}
+
+// MatchResourceTemplate returns the cached resource template that can expand
+// to uri, or nil if none does. Such URIs are readable even though they are
+// not listed by resources/list.
+func (c *Client) MatchResourceTemplate(uri string) *mcp.ResourceTemplate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, tmpl := range c.templateCache {
+		if tmpl.URITemplate == nil || tmpl.URITemplate.Template == nil {
+			continue
+		}
+		if tmpl.URITemplate.Match(uri) != nil {
+			return &tmpl
+		}
+	}
+	return nil
+}
+
+// ExpandResourceTemplate expands a resource template with the given variable
+// values according to RFC 6570. Variables without a value are left undefined.
+func ExpandResourceTemplate(tmpl *mcp.ResourceTemplate, values map[string]string) (string, error) {
+	if tmpl == nil || tmpl.URITemplate == nil || tmpl.URITemplate.Template == nil {
+		return "", fmt.Errorf("resource template has no URI template")
+	}
+
+	vars := uritemplate.Values{}
+	for name, value := range values {
+		vars.Set(name, uritemplate.String(value))
+	}
+
+	uri, err := tmpl.URITemplate.Expand(vars)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", tmpl.URITemplate.Raw(), err)
+	}
+	return uri, nil
+}
+
+// CompleteTemplateArgument asks the server for completions of a template
+// variable via completion/complete. resolved holds values already chosen for
+// other variables, which servers may use to narrow suggestions.
+func (c *Client) CompleteTemplateArgument(ctx context.Context, tmpl *mcp.ResourceTemplate, name, value string, resolved map[string]string) ([]string, error) {
+	if !c.ServerSupportsCompletions() {
+		return nil, nil
+	}
+
+	req := mcp.CompleteRequest{
+		Params: mcp.CompleteParams{
+			Ref: mcp.ResourceReference{
+				Type: "ref/resource",
+				URI:  templateURI(tmpl),
+			},
+			Argument: mcp.CompleteArgument{
+				Name:  name,
+				Value: value,
+			},
+			Context: mcp.CompleteContext{
+				Arguments: resolved,
+			},
+		},
+	}
+
+	ctx = c.withExchange(ctx, methodCompletionComplete)
+	c.logRequest(ctx, methodCompletionComplete, req.Params)
+
+	var result *mcp.CompleteResult
+	err := c.withRequestSlot(ctx, methodCompletionComplete, func() error {
+		var err error
+		result, err = c.mcpClient().Complete(ctx, req)
+		return err
+	})
+	if err != nil {
+		c.logger.Debug("Complete failed: %v", err)
+		return nil, classifyError(err)
+	}
+
+	c.logResponse(ctx, methodCompletionComplete, result)
+	return result.Completion.Values, nil
+}
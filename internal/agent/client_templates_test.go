@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestExpandResourceTemplate(t *testing.T) {
+	tmpl := mcp.NewResourceTemplate("repo://{owner}/{repo}/files{/path*}{?ref}", "repo-file")
+
+	if got := TemplateVariables(&tmpl); !reflect.DeepEqual(got, []string{"owner", "repo", "path", "ref"}) {
+		t.Errorf("TemplateVariables() = %v", got)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{
+			name:   "all variables",
+			values: map[string]string{"owner": "giantswarm", "repo": "mcp-debug", "path": "README.md", "ref": "main"},
+			want:   "repo://giantswarm/mcp-debug/files/README.md?ref=main",
+		},
+		{
+			name:   "optional variables omitted",
+			values: map[string]string{"owner": "giantswarm", "repo": "mcp-debug"},
+			want:   "repo://giantswarm/mcp-debug/files",
+		},
+		{
+			name:   "reserved characters are escaped",
+			values: map[string]string{"owner": "a b", "repo": "c/d"},
+			want:   "repo://a%20b/c%2Fd/files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandResourceTemplate(&tmpl, tt.values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandResourceTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ExpandResourceTemplate(&mcp.ResourceTemplate{Name: "broken"}, nil); err == nil {
+		t.Error("expected error for template without URI template")
+	}
+}
+
+func TestCompleteTemplateArgument(t *testing.T) {
+	stub := &stubMCPClient{completions: []string{"giantswarm", "github"}}
+	c := newStubbedClient(t, stub)
+	tmpl := mcp.NewResourceTemplate("repo://{owner}/{repo}", "repo")
+
+	// Without the completions capability no request is sent
+	values, err := c.CompleteTemplateArgument(t.Context(), &tmpl, "owner", "gi", nil)
+	if err != nil || values != nil {
+		t.Fatalf("expected no completions without capability, got %v, %v", values, err)
+	}
+
+	c.serverCapabilities = &mcp.ServerCapabilities{Completions: &struct{}{}}
+	resolved := map[string]string{"repo": "mcp-debug"}
+	values, err = c.CompleteTemplateArgument(t.Context(), &tmpl, "owner", "gi", resolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(values, []string{"giantswarm", "github"}) {
+		t.Errorf("completions = %v", values)
+	}
+
+	ref, ok := stub.lastCompleteReq.Params.Ref.(mcp.ResourceReference)
+	if !ok || ref.URI != "repo://{owner}/{repo}" || ref.Type != "ref/resource" {
+		t.Errorf("unexpected ref: %+v", stub.lastCompleteReq.Params.Ref)
+	}
+	if stub.lastCompleteReq.Params.Argument.Value != "gi" {
+		t.Errorf("unexpected argument: %+v", stub.lastCompleteReq.Params.Argument)
+	}
+	if !reflect.DeepEqual(stub.lastCompleteReq.Params.Context.Arguments, resolved) {
+		t.Errorf("unexpected context: %+v", stub.lastCompleteReq.Params.Context)
+	}
+}
+
+func TestMatchResourceTemplate(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.templateCache = []mcp.ResourceTemplate{
+		mcp.NewResourceTemplate("repo://{owner}/{repo}", "repo"),
+		mcp.NewResourceTemplate("user://{id}/profile", "profile"),
+	}
+
+	if tmpl := c.MatchResourceTemplate("user://42/profile"); tmpl == nil || tmpl.Name != "profile" {
+		t.Errorf("MatchResourceTemplate(user://42/profile) = %v, want profile", tmpl)
+	}
+	if tmpl := c.MatchResourceTemplate("repo://giantswarm/mcp-debug"); tmpl == nil || tmpl.Name != "repo" {
+		t.Errorf("MatchResourceTemplate(repo://...) = %v, want repo", tmpl)
+	}
+	if tmpl := c.MatchResourceTemplate("docs://readme"); tmpl != nil {
+		t.Errorf("MatchResourceTemplate(docs://readme) = %v, want nil", tmpl.Name)
+	}
+}
+
+func TestTemplatesWithoutURITemplate(t *testing.T) {
+	valid := mcp.NewResourceTemplate("file:///{path}", "file")
+	missing := mcp.ResourceTemplate{Name: "missing"}
+	unparsed := mcp.ResourceTemplate{Name: "unparsed", URITemplate: &mcp.URITemplate{}}
+
+	for _, tmpl := range []*mcp.ResourceTemplate{nil, &missing, &unparsed} {
+		if uri := templateURI(tmpl); uri != "" {
+			t.Errorf("templateURI() = %q, want empty", uri)
+		}
+	}
+	if uri := templateURI(&valid); uri != "file:///{path}" {
+		t.Errorf("templateURI() = %q", uri)
+	}
+
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.templateCache = []mcp.ResourceTemplate{valid, missing, unparsed}
+	r := &REPL{client: c}
+
+	if err := r.listTemplates(context.Background(), 0); err != nil {
+		t.Errorf("listTemplates: %v", err)
+	}
+	for _, name := range []string{"missing", "unparsed", "file:///{path}"} {
+		if err := r.describeTemplate(context.Background(), name); err != nil {
+			t.Errorf("describeTemplate(%s): %v", name, err)
+		}
+	}
+	if r.findTemplate("") != nil {
+		t.Error("an empty name must not match templates without a URI template")
+	}
+}
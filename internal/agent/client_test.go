@@ -130,12 +130,12 @@ func TestShouldReconnect(t *testing.T) {
 		{
 			name: "context canceled",
 			err:  context.Canceled,
-			want: true,
+			want: false,
 		},
 		{
 			name: "context deadline exceeded",
 			err:  context.DeadlineExceeded,
-			want: true,
+			want: false,
 		},
 		{
 			name: "connection refused",
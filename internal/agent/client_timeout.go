@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errCallTimeout is the cancellation cause of a tool call whose own deadline
+// expired, as opposed to the caller's context
+var errCallTimeout = errors.New("tool call deadline exceeded")
+
+// cancelNotificationTimeout bounds sending notifications/cancelled for a
+// timed out call
+const cancelNotificationTimeout = 5 * time.Second
+
+// callTimeoutKey is the context key of a per-call timeout override
+type callTimeoutKey struct{}
+
+// WithCallTimeout returns a context whose tool calls use timeout as their
+// deadline instead of the client's CallTimeout. Zero disables the deadline.
+func WithCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, timeout)
+}
+
+// withCallDeadline applies the tool call deadline to ctx and returns it with
+// the timeout in effect (zero if none)
+func (c *Client) withCallDeadline(ctx context.Context) (context.Context, time.Duration, context.CancelFunc) {
+	timeout := c.callTimeout
+	if override, ok := ctx.Value(callTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return ctx, 0, func() {}
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errCallTimeout)
+	return ctx, timeout, cancel
+}
+
+// callTimeoutError reports whether the call's own deadline ended ctx and
+// returns the error to report in that case
+func callTimeoutError(ctx context.Context, name string, timeout time.Duration) error {
+	if !errors.Is(context.Cause(ctx), errCallTimeout) {
+		return nil
+	}
+	return withKind(ErrTimeout, fmt.Errorf("tool call %s timed out after %s without a response from the server", name, timeout))
+}
+
+// abandonTimedOutCall tells the server to stop working on a call whose
+// deadline expired; failures are only logged since the call already failed
+func (c *Client) abandonTimedOutCall(ctx context.Context, call *inflightCall) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelNotificationTimeout)
+	defer cancel()
+	if err := c.notifyCancelled(ctx, call.requestID(), "timeout"); err != nil {
+		c.logger.Debug("Could not cancel the timed out request: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCallTimeout(t *testing.T) {
+	var calls atomic.Int32
+	delay := 50 * time.Millisecond
+	stub := &stubMCPClient{callTool: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls.Add(1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+			return mcp.NewToolResultText("done"), nil
+		}
+	}}
+	c := newStubbedClient(t, stub)
+	c.callTimeout = 10 * time.Millisecond
+
+	_, err := c.CallTool(context.Background(), "slow", nil)
+	if !errors.Is(err, ErrTimeout) || ErrorKind(err) != ErrTimeout {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if !strings.Contains(err.Error(), "slow timed out after 10ms") {
+		t.Errorf("unexpected message: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("tool called %d times, want no retry after a timeout", n)
+	}
+
+	// A per-call override replaces the client default
+	if _, err := c.CallTool(WithCallTimeout(context.Background(), time.Second), "slow", nil); err != nil {
+		t.Errorf("call with longer timeout: %v", err)
+	}
+	if _, err := c.CallTool(WithCallTimeout(context.Background(), 0), "slow", nil); err != nil {
+		t.Errorf("call without deadline: %v", err)
+	}
+
+	// The caller's own deadline is not reported as a call timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.CallTool(WithCallTimeout(ctx, time.Second), "slow", nil); err == nil || errors.Is(err, ErrTimeout) {
+		t.Errorf("caller deadline: err = %v, want a non-timeout error", err)
+	}
+}
+
+func TestParseCallTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		input   string
+		rest    string
+		timeout time.Duration
+		set     bool
+	}{
+		{"call echo {}", "call echo {}", 0, false},
+		{"call --timeout 30s echo {}", "call echo {}", 30 * time.Second, true},
+		{"call --timeout=2m echo", "call echo", 2 * time.Minute, true},
+		{"call --timeout 0 echo", "call echo", 0, true},
+	} {
+		rest, timeout, err := parseCallTimeout(strings.Fields(tc.input))
+		if err != nil {
+			t.Errorf("%q: %v", tc.input, err)
+			continue
+		}
+		if got := strings.Join(rest, " "); got != tc.rest || (timeout != nil) != tc.set || (timeout != nil && *timeout != tc.timeout) {
+			t.Errorf("%q = %q, %v; want %q, %v", tc.input, got, timeout, tc.rest, tc.timeout)
+		}
+	}
+
+	for _, input := range []string{"call --timeout", "call --timeout 30s", "call --timeout soon echo", "call --timeout=-1s echo"} {
+		if _, _, err := parseCallTimeout(strings.Fields(input)); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// sharedMCPTransport carries the requests to MCP servers. Like
+// sharedOAuthTransport it is shared by all connections, so ConfigureTLS and
+// ConfigureProxy reach every one of them.
+var sharedMCPTransport = newHTTPTransport()
+
+// newHTTPTransport creates a transport based on http.DefaultTransport, which
+// keeps its proxy-from-environment and pooling behavior, with TLS 1.2 as the
+// minimum protocol version. It is the factory of the shared transports all
+// outbound requests go through.
+func newHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	return transport
+}
+
+// sharedTransports returns the transports ConfigureTLS and ConfigureProxy
+// apply to
+func sharedTransports() []*http.Transport {
+	return []*http.Transport{sharedMCPTransport, sharedOAuthTransport}
+}
+
+// TLSConfig configures the TLS connections to MCP servers and OAuth
+// endpoints, for servers behind a private PKI or with self-signed
+// certificates
+type TLSConfig struct {
+	// CAFile is a PEM file of CA certificates trusted in addition to the
+	// system's
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key presented
+	// to servers that ask for one (mutual TLS)
+	CertFile string
+	KeyFile  string
+	// Insecure skips the verification of server certificates
+	Insecure bool
+}
+
+// enabled reports whether cfg changes anything from the defaults
+func (cfg TLSConfig) enabled() bool {
+	return cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" || cfg.Insecure
+}
+
+// build creates the tls.Config described by cfg
+func (cfg TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.Insecure, //nolint:gosec // G402: explicitly requested with --tls-insecure
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case cfg.CertFile != "" && cfg.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case cfg.CertFile != "" || cfg.KeyFile != "":
+		return nil, errors.New("a client certificate needs both --tls-cert and --tls-key")
+	}
+	return tlsConfig, nil
+}
+
+// ConfigureTLS applies cfg to every HTTP client of the package: the MCP
+// transport, metadata discovery, client ID metadata documents and the
+// token and registration requests. It must be called before connecting.
+func ConfigureTLS(cfg TLSConfig, logger *Logger) error {
+	if !cfg.enabled() {
+		return nil
+	}
+	tlsConfig, err := cfg.build()
+	if err != nil {
+		return err
+	}
+
+	for _, transport := range sharedTransports() {
+		transport.TLSClientConfig = tlsConfig.Clone()
+		transport.CloseIdleConnections()
+	}
+
+	if cfg.CAFile != "" {
+		logger.Info("Trusting the CA certificates in %s", cfg.CAFile)
+	}
+	if len(tlsConfig.Certificates) > 0 {
+		logger.Info("Presenting client certificate %s", cfg.CertFile)
+	}
+	if cfg.Insecure {
+		logger.Warning("TLS certificate verification disabled - connections can be intercepted")
+	}
+	return nil
+}
+
+// proxySchemes are the proxy URL schemes http.Transport supports
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ConfigureProxy sends every outbound request through the proxy at
+// proxyURL: MCP requests as well as discovery, CIMD and OAuth requests.
+// Without it, the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment
+// variables apply. It must be called before connecting.
+func ConfigureProxy(proxyURL string, logger *Logger) error {
+	if proxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q (expected %s://host:port)", u.Redacted(), strings.Join(proxySchemes, "|"))
+	}
+
+	for _, transport := range sharedTransports() {
+		transport.Proxy = http.ProxyURL(u)
+		transport.CloseIdleConnections()
+	}
+	logger.Info("Sending all requests through proxy %s", u.Redacted())
+	return nil
+}
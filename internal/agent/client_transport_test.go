@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"maps"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testPKI is a CA with a server and a client certificate, written as PEM
+// files
+type testPKI struct {
+	pool                                  *x509.CertPool
+	serverCert                            tls.Certificate
+	caFile, clientCertFile, clientKeyFile string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, template *x509.Certificate) ([]byte, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template.SerialNumber = big.NewInt(serial)
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der, key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	serverDER, serverKey := issue(2, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	clientDER, clientKey := issue(3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "mcp-debug"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	clientKeyDER, _ := x509.MarshalECPrivateKey(clientKey)
+
+	pki := &testPKI{
+		pool:           x509.NewCertPool(),
+		serverCert:     tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey},
+		caFile:         writePEM("ca.pem", "CERTIFICATE", caDER),
+		clientCertFile: writePEM("client.pem", "CERTIFICATE", clientDER),
+		clientKeyFile:  writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER),
+	}
+	pki.pool.AddCert(ca)
+	return pki
+}
+
+// restoreSharedTransports undoes ConfigureTLS and ConfigureProxy at the end
+// of the test
+func restoreSharedTransports(t *testing.T) {
+	t.Helper()
+	for _, transport := range sharedTransports() {
+		tlsConfig, proxy, dial := transport.TLSClientConfig, transport.Proxy, transport.DialContext
+		t.Cleanup(func() {
+			transport.TLSClientConfig, transport.Proxy, transport.DialContext = tlsConfig, proxy, dial
+			transport.CloseIdleConnections()
+		})
+	}
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	pki := newTestPKI(t)
+	notPEM := filepath.Join(t.TempDir(), "empty.pem")
+	_ = os.WriteFile(notPEM, []byte("not a certificate"), 0o600)
+
+	for name, cfg := range map[string]TLSConfig{
+		"certificate without key": {CertFile: pki.clientCertFile},
+		"key without certificate": {KeyFile: pki.clientKeyFile},
+		"missing CA file":         {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"CA file without PEM":     {CAFile: notPEM},
+		"key not matching":        {CertFile: pki.clientCertFile, KeyFile: pki.caFile},
+	} {
+		if _, err := cfg.build(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	tlsConfig, err := TLSConfig{CAFile: pki.caFile, CertFile: pki.clientCertFile, KeyFile: pki.clientKeyFile}.build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 || len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil || tlsConfig.InsecureSkipVerify {
+		t.Errorf("unexpected config %+v", tlsConfig)
+	}
+}
+
+func TestConfigureTLSMutualTLS(t *testing.T) {
+	restoreSharedTransports(t)
+	pki := newTestPKI(t)
+
+	upstream := server.NewStreamableHTTPServer(server.NewMCPServer("pki", "1.0.0"))
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", upstream)
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resource":"https://example.com","authorization_servers":["https://auth.example.com"]}`)
+	})
+	ts := httptest.NewUnstartedServer(mux)
+	// The handshakes rejected on purpose would be logged to stderr
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.pool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	connect := func() error {
+		c := NewClient(ClientConfig{Endpoint: ts.URL + "/mcp", Transport: "streamable-http", Logger: logger})
+		defer func() { _ = c.Close() }()
+		return c.Run(t.Context())
+	}
+
+	// The server's certificate is not trusted by default
+	if err := connect(); err == nil {
+		t.Fatal("expected the connection to fail without the CA")
+	}
+
+	if err := ConfigureTLS(TLSConfig{CAFile: pki.caFile}, logger); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	if err := connect(); err == nil {
+		t.Fatal("expected the connection to fail without a client certificate")
+	}
+
+	if err := ConfigureTLS(TLSConfig{CAFile: pki.caFile, CertFile: pki.clientCertFile, KeyFile: pki.clientKeyFile}, logger); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	if err := connect(); err != nil {
+		t.Fatalf("Run with mutual TLS: %v", err)
+	}
+
+	// Metadata discovery uses the same settings
+	if _, err := fetchProtectedResourceMetadata(t.Context(), ts.URL+"/.well-known/oauth-protected-resource"); err != nil {
+		t.Errorf("protected resource metadata over mutual TLS: %v", err)
+	}
+}
+
+func TestConfigureTLSInsecure(t *testing.T) {
+	restoreSharedTransports(t)
+
+	tlsServer := httptest.NewTLSServer(server.NewStreamableHTTPServer(server.NewMCPServer("self-signed", "1.0.0")))
+	defer tlsServer.Close()
+
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	if err := ConfigureTLS(TLSConfig{Insecure: true}, logger); err != nil {
+		t.Fatalf("ConfigureTLS: %v", err)
+	}
+	c := NewClient(ClientConfig{Endpoint: tlsServer.URL + "/mcp", Transport: "streamable-http", Logger: logger})
+	defer func() { _ = c.Close() }()
+	if err := c.Run(t.Context()); err != nil {
+		t.Fatalf("Run against a self-signed server: %v", err)
+	}
+}
+
+func TestConfigureProxy(t *testing.T) {
+	restoreSharedTransports(t)
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server.NewStreamableHTTPServer(server.NewMCPServer("behind-proxy", "1.0.0")))
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"resource":"https://example.com","authorization_servers":["https://auth.example.com"]}`)
+	})
+	target := httptest.NewServer(mux)
+	defer target.Close()
+
+	// A forward proxy receives the absolute URLs of the requests it relays
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		maps.Copy(w.Header(), resp.Header)
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	for _, bad := range []string{"ftp://proxy:21", "proxy:3128", "http://"} {
+		if err := ConfigureProxy(bad, logger); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+	if err := ConfigureProxy(proxy.URL, logger); err != nil {
+		t.Fatalf("ConfigureProxy: %v", err)
+	}
+
+	c := NewClient(ClientConfig{Endpoint: target.URL + "/mcp", Transport: "streamable-http", Logger: logger})
+	if err := c.Run(t.Context()); err != nil {
+		t.Fatalf("Run through the proxy: %v", err)
+	}
+	_ = c.Close()
+	if _, err := fetchProtectedResourceMetadata(t.Context(), target.URL+"/.well-known/oauth-protected-resource"); err != nil {
+		t.Fatalf("protected resource metadata through the proxy: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{"POST /mcp", "GET /.well-known/oauth-protected-resource"} {
+		if !slices.Contains(proxied, want) {
+			t.Errorf("%s did not go through the proxy; proxied %v", want, proxied)
+		}
+	}
+}
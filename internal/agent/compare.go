@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerComparison is the outcome of comparing server B against server A
+type ServerComparison struct {
+	EndpointA string     `json:"endpointA"`
+	EndpointB string     `json:"endpointB"`
+	ServerA   ServerInfo `json:"serverA"`
+	ServerB   ServerInfo `json:"serverB"`
+	// Capabilities are the server capabilities announced differently, e.g.
+	// "resources" or "logging"
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Lists compare the tools, resources, resource templates and prompts
+	Lists []ListComparison `json:"lists"`
+	// Calls compare the results of the replayed tool calls
+	Calls []CallComparison `json:"calls,omitempty"`
+}
+
+// ListComparison compares one list of both servers
+type ListComparison struct {
+	Kind string `json:"kind"`
+	// OnlyA and OnlyB are the entries only one server lists, by name (URI
+	// for resources, URI template for templates)
+	OnlyA []string `json:"onlyA,omitempty"`
+	OnlyB []string `json:"onlyB,omitempty"`
+	// Changed are the entries with different definitions
+	Changed []ListEntryChange `json:"changed,omitempty"`
+	// Same counts the entries with identical definitions
+	Same int `json:"same"`
+}
+
+// ListEntryChange is an entry both servers list with different definitions
+type ListEntryChange struct {
+	Name string `json:"name"`
+	// Fields are the top-level fields that differ, e.g. description
+	Fields []string `json:"fields"`
+	// Details describe tool changes down to the input schema properties,
+	// e.g. "~ count: string → integer"
+	Details []string `json:"details,omitempty"`
+}
+
+// CallComparison compares the results of one tool call on both servers
+type CallComparison struct {
+	Name string `json:"name"`
+	// Differences of B's result from A's, each prefixed with its path;
+	// "got" is B's value and "want" A's
+	Differences []string `json:"differences,omitempty"`
+}
+
+// Differs reports whether the servers differ in anything but their identity
+func (sc *ServerComparison) Differs() bool {
+	if len(sc.Capabilities) > 0 || sc.ServerA.Instructions != sc.ServerB.Instructions {
+		return true
+	}
+	for _, list := range sc.Lists {
+		if len(list.OnlyA) > 0 || len(list.OnlyB) > 0 || len(list.Changed) > 0 {
+			return true
+		}
+	}
+	for _, call := range sc.Calls {
+		if len(call.Differences) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareServers compares the capabilities and lists of two connected
+// servers and, if calls is not nil, replays its tool calls against both and
+// compares the results with its ignored paths masked. Differences are
+// reported relative to a, the baseline.
+func CompareServers(ctx context.Context, a, b *Client, calls *SnapshotSuite) (*ServerComparison, error) {
+	comparison := &ServerComparison{
+		EndpointA: a.endpoint,
+		EndpointB: b.endpoint,
+		ServerA:   a.ServerInfo(),
+		ServerB:   b.ServerInfo(),
+	}
+
+	a.mu.RLock()
+	capsA := encodeForDiff(a.serverCapabilities)
+	a.mu.RUnlock()
+	b.mu.RLock()
+	capsB := encodeForDiff(b.serverCapabilities)
+	b.mu.RUnlock()
+	comparison.Capabilities = changedFields(capsA, capsB)
+
+	for _, kind := range ListKinds {
+		itemsA, err := a.listSnapshotItems(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("server A: %w", err)
+		}
+		itemsB, err := b.listSnapshotItems(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("server B: %w", err)
+		}
+		comparison.Lists = append(comparison.Lists, compareLists(kind, itemsA, itemsB))
+	}
+
+	if calls != nil {
+		for i, call := range calls.Cases {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			comparison.Calls = append(comparison.Calls, CallComparison{
+				Name:        call.label(i),
+				Differences: compareCall(ctx, a, b, calls, call),
+			})
+		}
+	}
+	return comparison, nil
+}
+
+// listSnapshotItems returns the encoded entries of one of the ListKinds
+func (c *Client) listSnapshotItems(ctx context.Context, kind string) (map[string]string, error) {
+	list, err := c.ListCapabilities(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	switch entries := list.(type) {
+	case []mcp.Tool:
+		return listItems(entries, func(t mcp.Tool) string { return t.Name }), nil
+	case []mcp.Resource:
+		return listItems(entries, func(r mcp.Resource) string { return r.URI }), nil
+	case []mcp.ResourceTemplate:
+		return listItems(entries, func(t mcp.ResourceTemplate) string {
+			if t.URITemplate == nil || t.URITemplate.Template == nil {
+				return t.Name
+			}
+			return t.URITemplate.Raw()
+		}), nil
+	case []mcp.Prompt:
+		return listItems(entries, func(p mcp.Prompt) string { return p.Name }), nil
+	}
+	return nil, fmt.Errorf("unknown list: %s", kind)
+}
+
+// compareLists compares the entries of one list kind, taking the changes
+// from the capability history's comparison of consecutive snapshots
+func compareLists(kind string, itemsA, itemsB map[string]string) ListComparison {
+	comparison := ListComparison{Kind: kind}
+	events := historyEvents([]listSnapshot{{Kind: kind, Items: itemsA}, {Kind: kind, Items: itemsB}})
+	for _, event := range events {
+		switch event.Change {
+		case HistoryAppeared:
+			comparison.OnlyB = append(comparison.OnlyB, event.Name)
+		case HistoryDisappeared:
+			comparison.OnlyA = append(comparison.OnlyA, event.Name)
+		case HistoryChanged:
+			comparison.Changed = append(comparison.Changed, ListEntryChange{Name: event.Name, Fields: event.Fields, Details: event.Details})
+		}
+	}
+	comparison.Same = len(itemsA) - len(comparison.OnlyA) - len(comparison.Changed)
+	return comparison
+}
+
+// compareCall calls a tool on both servers and returns the differences of
+// B's result from A's
+func compareCall(ctx context.Context, a, b *Client, calls *SnapshotSuite, call SnapshotCase) []string {
+	resultA, errA := a.snapshotResult(ctx, calls, call)
+	resultB, errB := b.snapshotResult(ctx, calls, call)
+	switch {
+	case errA != nil && errB != nil:
+		if errA.Error() == errB.Error() {
+			return nil
+		}
+		return []string{fmt.Sprintf("A: %v; B: %v", errA, errB)}
+	case errA != nil:
+		return []string{fmt.Sprintf("A: %v; B returned a result", errA)}
+	case errB != nil:
+		return []string{fmt.Sprintf("B: %v; A returned a result", errB)}
+	}
+
+	return limitDifferences(diffJSONValues("$", resultA, resultB))
+}
+
+// WriteServerComparison prints a comparison, listing only what differs
+func WriteServerComparison(w io.Writer, sc *ServerComparison) {
+	for _, server := range []struct {
+		label, endpoint string
+		info            ServerInfo
+	}{{"A", sc.EndpointA, sc.ServerA}, {"B", sc.EndpointB, sc.ServerB}} {
+		name := strings.TrimSpace(server.info.Name + " " + server.info.Version)
+		_, _ = fmt.Fprintf(w, "%s: %s (%s, protocol %s)\n", server.label, server.endpoint, name, server.info.ProtocolVersion)
+	}
+
+	if len(sc.Capabilities) > 0 {
+		_, _ = fmt.Fprintf(w, "\nCapabilities differ: %s\n", strings.Join(sc.Capabilities, ", "))
+	}
+	if sc.ServerA.Instructions != sc.ServerB.Instructions {
+		_, _ = fmt.Fprintln(w, "\nInstructions differ")
+	}
+
+	for _, list := range sc.Lists {
+		total := list.Same + len(list.OnlyA) + len(list.OnlyB) + len(list.Changed)
+		if total == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "\n%s: %d same, %d only in A, %d only in B, %d changed\n",
+			strings.ToUpper(list.Kind[:1])+list.Kind[1:], list.Same, len(list.OnlyA), len(list.OnlyB), len(list.Changed))
+		for _, name := range list.OnlyA {
+			_, _ = fmt.Fprintf(w, "  - %s (only in A)\n", name)
+		}
+		for _, name := range list.OnlyB {
+			_, _ = fmt.Fprintf(w, "  + %s (only in B)\n", name)
+		}
+		for _, change := range list.Changed {
+			_, _ = fmt.Fprintf(w, "  ~ %s: %s\n", change.Name, strings.Join(change.Fields, ", "))
+			for _, detail := range change.Details {
+				_, _ = fmt.Fprintf(w, "      %s\n", detail)
+			}
+		}
+	}
+
+	if len(sc.Calls) > 0 {
+		_, _ = fmt.Fprintln(w, "\nCalls:")
+		for _, call := range sc.Calls {
+			if len(call.Differences) == 0 {
+				_, _ = fmt.Fprintf(w, "  SAME  %s\n", call.Name)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "  DIFF  %s\n", call.Name)
+			for _, difference := range call.Differences {
+				_, _ = fmt.Fprintf(w, "        %s\n", difference)
+			}
+		}
+	}
+
+	if !sc.Differs() {
+		_, _ = fmt.Fprintln(w, "\nNo differences")
+	}
+}
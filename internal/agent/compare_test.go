@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newComparedServer starts a server whose echo tool prefixes its message,
+// with the given type of the count argument and extra tools
+func newComparedServer(t *testing.T, prefix string, countType func(string, ...mcp.PropertyOption) mcp.ToolOption, extra ...string) *Client {
+	t.Helper()
+	s := server.NewMCPServer("compared", "1.0.0")
+	s.AddTool(mcp.NewTool("echo", mcp.WithString("message", mcp.Required()), countType("count")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(prefix + req.GetString("message", "")), nil
+		})
+	for _, name := range extra {
+		s.AddTool(mcp.NewTool(name), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	}
+	ts := server.NewTestStreamableHTTPServer(s)
+	t.Cleanup(ts.Close)
+
+	client := NewClient(ClientConfig{
+		Endpoint:      ts.URL + "/mcp",
+		Transport:     "streamable-http",
+		Logger:        NewLoggerWithWriter(false, false, false, io.Discard),
+		NoInitialList: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestCompareServers(t *testing.T) {
+	a := newComparedServer(t, "", mcp.WithString, "status")
+	b := newComparedServer(t, "echo: ", mcp.WithNumber, "deploy")
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	comparison, err := CompareServers(ctx, a, a, &SnapshotSuite{Cases: []SnapshotCase{{Tool: "echo", Arguments: map[string]any{"message": "hi"}}}})
+	if err != nil {
+		t.Fatalf("CompareServers: %v", err)
+	}
+	if comparison.Differs() {
+		t.Errorf("a server should not differ from itself: %+v", comparison)
+	}
+
+	calls := &SnapshotSuite{Cases: []SnapshotCase{
+		{Name: "echo hi", Tool: "echo", Arguments: map[string]any{"message": "hi"}},
+		{Name: "masked", Tool: "echo", Arguments: map[string]any{"message": "hi"}, Ignore: []string{"$.content[0].text"}},
+	}}
+	comparison, err = CompareServers(ctx, a, b, calls)
+	if err != nil {
+		t.Fatalf("CompareServers: %v", err)
+	}
+	if !comparison.Differs() {
+		t.Fatal("expected the servers to differ")
+	}
+	tools := comparison.Lists[0]
+	if tools.Kind != "tools" || strings.Join(tools.OnlyA, ",") != "status" || strings.Join(tools.OnlyB, ",") != "deploy" || tools.Same != 0 {
+		t.Errorf("unexpected tool comparison %+v", tools)
+	}
+	if len(tools.Changed) != 1 || tools.Changed[0].Name != "echo" || strings.Join(tools.Changed[0].Details, ",") != "~ count: string → number" {
+		t.Errorf("unexpected tool changes %+v", tools.Changed)
+	}
+	if len(comparison.Calls[0].Differences) != 1 || comparison.Calls[0].Differences[0] != `$.content[0].text: got "echo: hi", want "hi"` {
+		t.Errorf("unexpected call differences %v", comparison.Calls[0].Differences)
+	}
+	if len(comparison.Calls[1].Differences) != 0 {
+		t.Errorf("ignored paths should not differ: %v", comparison.Calls[1].Differences)
+	}
+
+	var out bytes.Buffer
+	WriteServerComparison(&out, comparison)
+	for _, want := range []string{
+		"(compared 1.0.0, protocol " + DefaultProtocolVersion + ")",
+		"Tools: 0 same, 1 only in A, 1 only in B, 1 changed\n",
+		"  - status (only in A)\n  + deploy (only in B)\n  ~ echo: inputSchema\n      ~ count: string → number\n",
+		"  DIFF  echo hi\n        $.content[0].text: got \"echo: hi\", want \"hi\"\n  SAME  masked\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Resources:") {
+		t.Errorf("empty lists should not be reported:\n%s", out.String())
+	}
+}
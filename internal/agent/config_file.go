@@ -0,0 +1,319 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnvPrefix prefixes the environment variables that set flags, e.g.
+// MCP_DEBUG_OAUTH_CLIENT_SECRET for --oauth-client-secret
+const ConfigEnvPrefix = "MCP_DEBUG_"
+
+// profilesKey holds the named profiles in the config file
+const profilesKey = "profiles"
+
+// Settings maps flag names to their values. Flags that take a list have
+// one value per entry; all others have exactly one.
+type Settings map[string][]string
+
+// ConfigFile is a YAML file of flag settings: top-level keys are flag names,
+// and the profiles key holds named sets of settings applied on top of them
+//
+//	endpoint: https://mcp.example.com/mcp
+//	verbose: true
+//	profiles:
+//	  staging:
+//	    endpoint: https://staging.example.com/mcp
+//	    oauth: true
+type ConfigFile struct {
+	Path     string
+	Settings Settings
+	Profiles map[string]Settings
+	// shared reports whether other users may read the file
+	shared bool
+	// doc is the parsed document, kept to preserve comments and layout when
+	// saving profiles
+	doc *yaml.Node
+}
+
+// NewConfigFile returns an empty config file to be created at path
+func NewConfigFile(path string) *ConfigFile {
+	return &ConfigFile{
+		Path:     path,
+		Settings: Settings{},
+		Profiles: map[string]Settings{},
+	}
+}
+
+// DefaultConfigPath returns $XDG_CONFIG_HOME/mcp-debug/config.yaml, or
+// ~/.config/mcp-debug/config.yaml if XDG_CONFIG_HOME is not set
+func DefaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "mcp-debug", "config.yaml")
+}
+
+// ConfigEnvName returns the environment variable that sets flag
+func ConfigEnvName(flag string) string {
+	return ConfigEnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// LoadConfigFile reads a config file. The error wraps os.ErrNotExist if
+// there is no file at path.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := NewConfigFile(path)
+	file.shared = runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return file, nil
+	}
+	file.doc = &doc
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: expected a mapping of flag names to values", path)
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		if key != profilesKey {
+			if file.Settings[key], err = settingValues(key, value); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+			}
+			continue
+		}
+
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("invalid config file %s: %s must map profile names to settings", path, profilesKey)
+		}
+		for j := 0; j < len(value.Content); j += 2 {
+			name, body := value.Content[j].Value, value.Content[j+1]
+			if body.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("invalid config file %s: profile %s must map flag names to values", path, name)
+			}
+			profile := Settings{}
+			for k := 0; k < len(body.Content); k += 2 {
+				key := body.Content[k].Value
+				if profile[key], err = settingValues(key, body.Content[k+1]); err != nil {
+					return nil, fmt.Errorf("invalid config file %s: profile %s: %w", path, name, err)
+				}
+			}
+			file.Profiles[name] = profile
+		}
+	}
+	return file, nil
+}
+
+// settingValues returns the values of a setting: a scalar or a list of
+// scalars
+func settingValues(key string, node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s: list entries must be plain values", key)
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s: expected a value or a list of values", key)
+}
+
+// Resolve returns the settings of the file with those of profile applied
+// on top. An empty profile returns the top-level settings.
+func (f *ConfigFile) Resolve(profile string) (Settings, error) {
+	settings := maps.Clone(f.Settings)
+	if profile == "" {
+		return settings, nil
+	}
+	overrides, ok := f.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q in %s (available: %s)", profile, f.Path, strings.Join(f.ProfileNames(), ", "))
+	}
+	maps.Copy(settings, overrides)
+	return settings, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted
+func (f *ConfigFile) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(f.Profiles))
+}
+
+// ExposesSecret reports whether settings taken from the file hold a secret
+// while other users may read it
+func (f *ConfigFile) ExposesSecret(settings Settings) bool {
+	if !f.shared {
+		return false
+	}
+	for name := range settings {
+		if isSecretSetting(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetProfile adds the profile, replacing any profile of the same name. Call
+// Save to write the file.
+func (f *ConfigFile) SetProfile(name string, settings Settings) {
+	f.Profiles[name] = settings
+
+	body := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		values := settings[key]
+		value := &yaml.Node{Kind: yaml.ScalarNode}
+		if len(values) == 1 {
+			value.Value = values[0]
+		} else {
+			value.Kind = yaml.SequenceNode
+			for _, v := range values {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+			}
+		}
+		body.Content = append(body.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+
+	profiles := f.profilesNode()
+	if i := mappingIndex(profiles, name); i >= 0 {
+		profiles.Content[i+1] = body
+		return
+	}
+	profiles.Content = append(profiles.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, body)
+}
+
+// RemoveProfile removes the profile and reports whether it existed. Call
+// Save to write the file.
+func (f *ConfigFile) RemoveProfile(name string) bool {
+	if _, ok := f.Profiles[name]; !ok {
+		return false
+	}
+	delete(f.Profiles, name)
+
+	profiles := f.profilesNode()
+	if i := mappingIndex(profiles, name); i >= 0 {
+		profiles.Content = slices.Delete(profiles.Content, i, i+2)
+	}
+	return true
+}
+
+// profilesNode returns the mapping node of the profiles, adding it to the
+// document if necessary
+func (f *ConfigFile) profilesNode() *yaml.Node {
+	if f.doc == nil {
+		f.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := f.doc.Content[0]
+	if i := mappingIndex(root, profilesKey); i >= 0 {
+		return root.Content[i+1]
+	}
+	profiles := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: profilesKey}, profiles)
+	return profiles
+}
+
+// mappingIndex returns the index of key in the content of a mapping node,
+// or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Save writes the file, keeping the comments and layout of the settings it
+// was loaded with. The file is only readable by the current user, since it
+// may hold credentials.
+func (f *ConfigFile) Save() error {
+	var buf bytes.Buffer
+	if f.doc != nil {
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(f.doc); err != nil {
+			return fmt.Errorf("failed to encode config file: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("failed to encode config file: %w", err)
+		}
+	}
+
+	dir := filepath.Dir(f.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// Write a temporary file and rename it so a failed write never leaves
+	// a truncated config file behind
+	tmp, err := os.CreateTemp(dir, ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	f.shared = false
+	return nil
+}
+
+// String renders the settings as sorted name=value pairs, with the values
+// of credentials redacted
+func (s Settings) String() string {
+	pairs := make([]string, 0, len(s))
+	for _, name := range slices.Sorted(maps.Keys(s)) {
+		value := strings.Join(s[name], ",")
+		if isSecretSetting(name) {
+			value = "[REDACTED]"
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// isSecretSetting reports whether the flag may hold a credential: client
+// secrets, tokens, and headers and cookies carrying API keys
+func isSecretSetting(name string) bool {
+	for _, marker := range []string{"secret", "token", "password", "header", "cookie"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
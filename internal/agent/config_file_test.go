@@ -0,0 +1,267 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	// WriteFile applies the umask
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatalf("failed to chmod config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+endpoint: https://mcp.example.com/mcp
+verbose: true
+header:
+  - "X-Api-Key: abc"
+  - "X-Tenant: acme"
+profiles:
+  staging:
+    endpoint: https://staging.example.com/mcp
+    oauth: true
+  local: {}
+`, 0o600)
+
+	file, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantSettings := Settings{
+		"endpoint": {"https://mcp.example.com/mcp"},
+		"verbose":  {"true"},
+		"header":   {"X-Api-Key: abc", "X-Tenant: acme"},
+	}
+	if !reflect.DeepEqual(file.Settings, wantSettings) {
+		t.Errorf("Settings = %v, want %v", file.Settings, wantSettings)
+	}
+	if names := file.ProfileNames(); !reflect.DeepEqual(names, []string{"local", "staging"}) {
+		t.Errorf("ProfileNames() = %v", names)
+	}
+
+	staging, err := file.Resolve("staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantStaging := Settings{
+		"endpoint": {"https://staging.example.com/mcp"},
+		"verbose":  {"true"},
+		"header":   {"X-Api-Key: abc", "X-Tenant: acme"},
+		"oauth":    {"true"},
+	}
+	if !reflect.DeepEqual(staging, wantStaging) {
+		t.Errorf("Resolve(staging) = %v, want %v", staging, wantStaging)
+	}
+	if file.Settings["endpoint"][0] != "https://mcp.example.com/mcp" {
+		t.Error("Resolve modified the top-level settings")
+	}
+
+	if top, err := file.Resolve(""); err != nil || !reflect.DeepEqual(top, wantSettings) {
+		t.Errorf("Resolve(\"\") = %v, %v", top, err)
+	}
+
+	_, err = file.Resolve("prod")
+	if err == nil || !strings.Contains(err.Error(), "local, staging") {
+		t.Errorf("expected an unknown profile error listing the profiles, got %v", err)
+	}
+}
+
+func TestLoadConfigFileInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not a mapping":     "- endpoint\n",
+		"nested setting":    "endpoint:\n  url: x\n",
+		"nested list entry": "header:\n  - a: b\n",
+		"profiles list":     "profiles:\n  - staging\n",
+		"profile scalar":    "profiles:\n  staging: x\n",
+		"invalid yaml":      "endpoint: [\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadConfigFile(writeConfigFile(t, content, 0o600)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	file, err := LoadConfigFile(writeConfigFile(t, "", 0o600))
+	if err != nil || len(file.Settings) != 0 {
+		t.Errorf("empty file: got %v, %v", file, err)
+	}
+
+	_, err = LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestConfigFileExposesSecret(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+
+	content := "endpoint: https://mcp.example.com/mcp\noauth-client-secret: s3cret\n"
+	shared, err := LoadConfigFile(writeConfigFile(t, content, 0o644))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	private, err := LoadConfigFile(writeConfigFile(t, content, 0o600))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !shared.ExposesSecret(shared.Settings) {
+		t.Error("expected a readable file holding a client secret to expose it")
+	}
+	if shared.ExposesSecret(Settings{"endpoint": {"x"}, "verbose": {"true"}}) {
+		t.Error("expected settings without credentials not to expose a secret")
+	}
+	if private.ExposesSecret(private.Settings) {
+		t.Error("expected a 0600 file not to expose its secret")
+	}
+	for _, name := range []string{"header", "cookie", "oauth-client-secret"} {
+		if !shared.ExposesSecret(Settings{name: {"x"}}) {
+			t.Errorf("expected %s to be treated as a credential", name)
+		}
+	}
+}
+
+func TestConfigEnvName(t *testing.T) {
+	tests := map[string]string{
+		"endpoint":            "MCP_DEBUG_ENDPOINT",
+		"oauth-client-secret": "MCP_DEBUG_OAUTH_CLIENT_SECRET",
+		"json-rpc":            "MCP_DEBUG_JSON_RPC",
+	}
+	for flag, want := range tests {
+		if got := ConfigEnvName(flag); got != want {
+			t.Errorf("ConfigEnvName(%q) = %q, want %q", flag, got, want)
+		}
+	}
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	if got, want := DefaultConfigPath(), filepath.Join("/tmp/xdg", "mcp-debug", "config.yaml"); got != want {
+		t.Errorf("DefaultConfigPath() = %q, want %q", got, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/tmp/home")
+	if got, want := DefaultConfigPath(), filepath.Join("/tmp/home", ".config", "mcp-debug", "config.yaml"); got != want {
+		t.Errorf("DefaultConfigPath() = %q, want %q", got, want)
+	}
+}
+
+func TestConfigFileSaveProfiles(t *testing.T) {
+	path := writeConfigFile(t, `# shared settings
+verbose: true # keep
+profiles:
+  old:
+    endpoint: http://old.example.com/mcp
+`, 0o644)
+
+	file, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	staging := Settings{
+		"endpoint":     {"https://staging.example.com/mcp"},
+		"oauth":        {"true"},
+		"oauth-scopes": {"mcp:tools", "mcp:read"},
+	}
+	file.SetProfile("staging", staging)
+	if !file.RemoveProfile("old") {
+		t.Error("expected RemoveProfile to find the profile")
+	}
+	if file.RemoveProfile("missing") {
+		t.Error("expected RemoveProfile to report a missing profile")
+	}
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config file: %v", err)
+	}
+	for _, want := range []string{"# shared settings", "# keep"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected the saved file to keep %q:\n%s", want, data)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat config file: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("expected the saved file to be private, got %v", perm)
+		}
+	}
+
+	reloaded, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("failed to reload config file: %v", err)
+	}
+	if names := reloaded.ProfileNames(); !reflect.DeepEqual(names, []string{"staging"}) {
+		t.Errorf("ProfileNames() = %v", names)
+	}
+	if !reflect.DeepEqual(reloaded.Profiles["staging"], staging) {
+		t.Errorf("staging = %v, want %v", reloaded.Profiles["staging"], staging)
+	}
+	if !reflect.DeepEqual(reloaded.Settings, Settings{"verbose": {"true"}}) {
+		t.Errorf("Settings = %v", reloaded.Settings)
+	}
+
+	staging["endpoint"] = []string{"https://new.example.com/mcp"}
+	reloaded.SetProfile("staging", staging)
+	if err := reloaded.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if reloaded, err = LoadConfigFile(path); err != nil || len(reloaded.Profiles) != 1 || reloaded.Profiles["staging"]["endpoint"][0] != "https://new.example.com/mcp" {
+		t.Errorf("expected the profile to be replaced, got %v (%v)", reloaded, err)
+	}
+}
+
+func TestNewConfigFileSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp-debug", "config.yaml")
+	file := NewConfigFile(path)
+	file.SetProfile("local", Settings{"endpoint": {"http://localhost:8090/mcp"}})
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	reloaded, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reloaded.Profiles["local"]["endpoint"]; !reflect.DeepEqual(got, []string{"http://localhost:8090/mcp"}) {
+		t.Errorf("endpoint = %v", got)
+	}
+}
+
+func TestSettingsString(t *testing.T) {
+	settings := Settings{
+		"oauth-scopes":        {"a", "b"},
+		"endpoint":            {"http://localhost/mcp"},
+		"oauth-client-secret": {"s3cret"},
+	}
+	want := "endpoint=http://localhost/mcp oauth-client-secret=[REDACTED] oauth-scopes=a,b"
+	if got := settings.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// DefaultConnectionName names the connection to the server given on the
+// command line
+const DefaultConnectionName = "default"
+
+// connection is one named client of a ConnectionManager
+type connection struct {
+	client *Client
+	// cancel stops the background work (keepalive, notifications) of the
+	// connection; nil for the primary connection, whose context is owned by
+	// the caller
+	cancel context.CancelFunc
+}
+
+// ConnectionManager holds several named connections to MCP servers and tracks
+// which one is current. Each connection has its own caches, and its log
+// lines are labelled with its name.
+type ConnectionManager struct {
+	mu          sync.RWMutex
+	connections map[string]*connection
+	current     string
+	// base is the configuration new connections are derived from
+	base ClientConfig
+}
+
+// NewConnectionManager creates a manager whose current connection is the
+// already running primary client
+func NewConnectionManager(primary *Client) *ConnectionManager {
+	return &ConnectionManager{
+		connections: map[string]*connection{DefaultConnectionName: {client: primary}},
+		current:     DefaultConnectionName,
+		base:        primary.config,
+	}
+}
+
+// Connect opens a new named connection to endpoint with the settings of the
+// primary connection, except for custom headers and cookies when endpoint
+// has another origin. The current connection is not changed.
+func (m *ConnectionManager) Connect(ctx context.Context, name, endpoint string) (*Client, error) {
+	if name == "" {
+		return nil, fmt.Errorf("connection name must not be empty")
+	}
+	if err := validateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if _, exists := m.connections[name]; exists {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("connection %s already exists", name)
+	}
+	// Reserve the name while connecting, which may take a while with OAuth
+	m.connections[name] = nil
+	m.mu.Unlock()
+
+	cfg := m.base
+	cfg.Endpoint = endpoint
+	if cfg.Logger != nil {
+		cfg.Logger = cfg.Logger.WithPrefix(name)
+	}
+	// The resource indicator of the primary server does not apply here
+	if cfg.OAuthConfig != nil {
+		oauthConfig := *cfg.OAuthConfig
+		oauthConfig.ResourceURI = ""
+		cfg.OAuthConfig = &oauthConfig
+	}
+	// Custom headers and cookies, such as a gateway API key or a static
+	// bearer token, are meant for the primary server's origin only
+	if originOf(endpoint) != originOf(m.base.Endpoint) && (len(cfg.Headers) > 0 || len(cfg.Cookies) > 0) {
+		cfg.Headers = nil
+		cfg.Cookies = nil
+		if cfg.Logger != nil {
+			cfg.Logger.Info("Not sending the custom headers and cookies of %s to another origin", m.base.Endpoint)
+		}
+	}
+	// The session file belongs to the primary server; other connections
+	// resume their sessions in memory only
+	if cfg.SessionFile != "" {
+		cfg.ResumeSessions = true
+		cfg.SessionFile = ""
+	}
+
+	client := NewClient(cfg)
+	connCtx, cancel := context.WithCancel(ctx)
+	if err := client.Run(connCtx); err != nil {
+		cancel()
+		m.mu.Lock()
+		delete(m.connections, name)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.connections[name] = &connection{client: client, cancel: cancel}
+	m.mu.Unlock()
+	return client, nil
+}
+
+// validateEndpoint checks that endpoint is an http or https URL
+func validateEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid endpoint %q (must be an http or https URL)", endpoint)
+	}
+	return nil
+}
+
+// Use makes the named connection current
+func (m *ConnectionManager) Use(name string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn := m.connections[name]
+	if conn == nil {
+		return nil, fmt.Errorf("unknown connection: %s", name)
+	}
+	m.current = name
+	return conn.client, nil
+}
+
+// Disconnect closes the named connection. The primary connection cannot be
+// closed. If the current connection is closed, the primary becomes current.
+func (m *ConnectionManager) Disconnect(name string) error {
+	if name == DefaultConnectionName {
+		return fmt.Errorf("the %s connection cannot be disconnected", DefaultConnectionName)
+	}
+
+	m.mu.Lock()
+	conn := m.connections[name]
+	if conn == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("unknown connection: %s", name)
+	}
+	delete(m.connections, name)
+	if m.current == name {
+		m.current = DefaultConnectionName
+	}
+	m.mu.Unlock()
+
+	conn.cancel()
+	return conn.client.Close()
+}
+
+// CloseAll closes every connection except the primary one
+func (m *ConnectionManager) CloseAll() {
+	for _, name := range m.Names() {
+		if name != DefaultConnectionName {
+			_ = m.Disconnect(name)
+		}
+	}
+}
+
+// Current returns the name and client of the current connection
+func (m *ConnectionManager) Current() (string, *Client) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current, m.connections[m.current].client
+}
+
+// Get returns the client of the named connection, or nil
+func (m *ConnectionManager) Get(name string) *Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if conn := m.connections[name]; conn != nil {
+		return conn.client
+	}
+	return nil
+}
+
+// Names returns the names of the established connections, sorted
+func (m *ConnectionManager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.connections))
+	for name, conn := range m.connections {
+		if conn != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConnectionManager(t *testing.T) {
+	primary := newStubbedClient(t, &stubMCPClient{})
+	secondary := newStubbedClient(t, &stubMCPClient{})
+	m := NewConnectionManager(primary)
+
+	if name, client := m.Current(); name != DefaultConnectionName || client != primary {
+		t.Fatalf("expected the primary connection to be current, got %s", name)
+	}
+
+	// Connect needs a server; register the second client directly
+	m.connections["staging"] = &connection{client: secondary, cancel: func() {}}
+
+	if got := fmt.Sprint(m.Names()); got != "[default staging]" {
+		t.Errorf("Names() = %s", got)
+	}
+
+	client, err := m.Use("staging")
+	if err != nil || client != secondary {
+		t.Fatalf("Use(staging) = %v, %v", client, err)
+	}
+	if _, err := m.Use("missing"); err == nil {
+		t.Error("expected an error for an unknown connection")
+	}
+
+	if err := m.Disconnect(DefaultConnectionName); err == nil {
+		t.Error("expected the default connection to be protected")
+	}
+	if err := m.Disconnect("staging"); err != nil {
+		t.Fatalf("Disconnect(staging) failed: %v", err)
+	}
+	if name, client := m.Current(); name != DefaultConnectionName || client != primary {
+		t.Errorf("expected to fall back to the default connection, got %s", name)
+	}
+	if m.Get("staging") != nil {
+		t.Error("expected the disconnected client to be gone")
+	}
+}
+
+func TestConnectionManagerConnectValidation(t *testing.T) {
+	m := NewConnectionManager(newStubbedClient(t, &stubMCPClient{}))
+
+	tests := []struct {
+		name, connName, endpoint, wantErr string
+	}{
+		{name: "empty name", connName: "", endpoint: "http://localhost:8090/mcp", wantErr: "must not be empty"},
+		{name: "duplicate name", connName: DefaultConnectionName, endpoint: "http://localhost:8090/mcp", wantErr: "already exists"},
+		{name: "bad scheme", connName: "other", endpoint: "ftp://localhost/mcp", wantErr: "invalid endpoint"},
+		{name: "no host", connName: "other", endpoint: "http:///mcp", wantErr: "invalid endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Connect(context.Background(), tt.connName, tt.endpoint)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConnectionManagerConnectScopesHeaders(t *testing.T) {
+	endpoint := newUpstreamServer(t)
+	primary := newStubbedClient(t, &stubMCPClient{})
+	primary.config.Headers = http.Header{"Authorization": {"Bearer secret"}}
+	primary.config.Cookies = []*http.Cookie{{Name: "session", Value: "secret"}}
+	m := NewConnectionManager(primary)
+	t.Cleanup(m.CloseAll)
+
+	other, err := m.Connect(t.Context(), "other", endpoint)
+	if err != nil {
+		t.Fatalf("Connect(other): %v", err)
+	}
+	if len(other.config.Headers) > 0 || len(other.config.Cookies) > 0 {
+		t.Errorf("headers and cookies of %s were passed to %s", primary.config.Endpoint, endpoint)
+	}
+
+	// Another path on the primary's origin keeps them
+	m.base.Endpoint = strings.TrimSuffix(endpoint, "/mcp") + "/other"
+	same, err := m.Connect(t.Context(), "same", endpoint)
+	if err != nil {
+		t.Fatalf("Connect(same): %v", err)
+	}
+	if same.config.Headers.Get("Authorization") == "" || len(same.config.Cookies) != 1 {
+		t.Error("expected headers and cookies to be kept for the same origin")
+	}
+}
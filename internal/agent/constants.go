@@ -7,6 +7,9 @@ const (
 	// methodInitialize is the MCP initialization method
 	methodInitialize = "initialize"
 
+	// methodPing is the MCP liveness check
+	methodPing = "ping"
+
 	// notificationToolsListChanged is sent when the server's tool list changes
 	notificationToolsListChanged = "notifications/tools/list_changed"
 
@@ -15,6 +18,41 @@ const (
 
 	// notificationPromptsListChanged is sent when the server's prompt list changes
 	notificationPromptsListChanged = "notifications/prompts/list_changed"
+
+	// notificationMessage carries a log message emitted by the server
+	notificationMessage = "notifications/message"
+
+	// methodLoggingSetLevel asks the server to adjust its minimum log level
+	methodLoggingSetLevel = "logging/setLevel"
+
+	// methodResourcesSubscribe requests resources/updated notifications for a URI
+	methodResourcesSubscribe = "resources/subscribe"
+
+	// methodResourcesUnsubscribe cancels a resource subscription
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+
+	// methodResourcesTemplatesList lists the server's RFC 6570 resource templates
+	methodResourcesTemplatesList = "resources/templates/list"
+
+	// methodCompletionComplete asks the server for argument completions
+	methodCompletionComplete = "completion/complete"
+)
+
+// serverInstructionsURI is the resource under which MCP server mode exposes
+// the upstream server's instructions
+const serverInstructionsURI = "mcp-debug://server/instructions"
+
+// Resources under which MCP server mode exposes the session's history
+const (
+	// sessionLogURI serves the kept lines of the session log
+	sessionLogURI = "mcp-debug://log/latest"
+	// jsonRPCLogURI serves the latest JSON-RPC requests and responses
+	jsonRPCLogURI = "mcp-debug://log/jsonrpc"
+	// oauthLogURI serves the session log lines about OAuth
+	oauthLogURI = "mcp-debug://log/oauth"
+	// capabilityChangesURI serves every recorded capability change; each
+	// change is also served under capabilityChangesURI/<n>
+	capabilityChangesURI = "mcp-debug://capabilities/changes"
 )
 
 // URL scheme and host constants for validation.
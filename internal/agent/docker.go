@@ -0,0 +1,328 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dockerClientEnv are the variables the docker client itself needs, kept
+// when the server does not inherit mcp-debug's environment
+var dockerClientEnv = []string{
+	"PATH", "HOME", "USER", "TMPDIR", "XDG_RUNTIME_DIR",
+	"DOCKER_HOST", "DOCKER_CONTEXT", "DOCKER_CONFIG", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY",
+}
+
+// Bounds of waiting for a container to start serving
+const (
+	// dockerReadyTimeout is the time a container has to answer HTTP
+	dockerReadyTimeout = 60 * time.Second
+	// dockerReadyInterval is the pause between readiness probes
+	dockerReadyInterval = 250 * time.Millisecond
+)
+
+// DockerConfig describes the container of an MCP server to debug. The
+// server must serve streamable-http on a container port; it is published
+// on a free port of the loopback interface.
+type DockerConfig struct {
+	// Image is the image to run
+	Image string
+	// Port is the container port the MCP server listens on; 0 uses the
+	// only port the image exposes
+	Port int
+	// RunArgs are further options of docker run, e.g. --volume
+	RunArgs []string
+	// Env are variables set in the container as KEY=VAL; a bare KEY takes
+	// its value from mcp-debug's environment
+	Env []string
+	// EnvFile is a file of KEY=VAL lines set in the container
+	EnvFile string
+	// NoInheritEnv keeps mcp-debug's environment from the container: bare
+	// keys in Env are an error and those in EnvFile stay unset
+	NoInheritEnv bool
+	// Docker is the docker binary; empty looks it up in PATH
+	Docker string
+}
+
+// DockerContainer is a running MCP server container whose output is
+// logged until it is closed
+type DockerContainer struct {
+	// ID is the container ID
+	ID string
+	// Addr is the loopback address the container port is published on
+	Addr string
+
+	docker string
+	logger *Logger
+	logs   *exec.Cmd
+	// logsDone is closed once the container's output ended, which happens
+	// when the container stops
+	logsDone chan struct{}
+
+	mu      sync.Mutex
+	closing bool
+}
+
+// StartDockerContainer runs the image, streams the container's output to
+// the log with the container as prefix and returns once the published port
+// answers HTTP requests to endpoint, which is rewritten to the published
+// address. Close stops and removes the container.
+func StartDockerContainer(ctx context.Context, cfg DockerConfig, endpoint string, logger *Logger) (*DockerContainer, string, error) {
+	if cfg.Image == "" {
+		return nil, "", errors.New("no Docker image to run")
+	}
+	docker := cfg.Docker
+	if docker == "" {
+		path, err := exec.LookPath("docker")
+		if err != nil {
+			return nil, "", fmt.Errorf("running an MCP server container requires docker: %w", err)
+		}
+		docker = path
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		var err error
+		if port, err = exposedPort(ctx, docker, cfg.Image, logger); err != nil {
+			return nil, "", err
+		}
+	}
+
+	env, envArgs, err := containerEnv(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	args := []string{"run", "--detach", "--publish", fmt.Sprintf("127.0.0.1::%d", port)}
+	args = append(args, envArgs...)
+	args = append(args, cfg.RunArgs...)
+	args = append(args, cfg.Image)
+	logger.Info("Starting container of %s with port %d published...", cfg.Image, port)
+	run := exec.CommandContext(ctx, docker, args...) //nolint:gosec // G204: docker with arguments from the command line
+	run.Env = env
+	out, err := commandOutput(run)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to start container: %w", err)
+	}
+	id := strings.TrimSpace(out)
+	c := &DockerContainer{ID: id, docker: docker, logger: logger, logsDone: make(chan struct{})}
+	c.streamLogs(logger.WithPrefix(shortContainerID(id)))
+
+	published, err := dockerOutput(ctx, docker, "port", id, fmt.Sprintf("%d/tcp", port))
+	if err != nil {
+		_ = c.Close()
+		return nil, "", fmt.Errorf("failed to look up the published port: %w", err)
+	}
+	c.Addr, _, _ = strings.Cut(strings.TrimSpace(published), "\n")
+	if endpoint, err = ForwardedEndpoint(endpoint, c.Addr); err != nil {
+		_ = c.Close()
+		return nil, "", err
+	}
+
+	if err := c.waitReady(ctx, endpoint); err != nil {
+		_ = c.Close()
+		return nil, "", err
+	}
+	logger.Info("Container %s of %s serves %s", shortContainerID(id), cfg.Image, endpoint)
+	return c, endpoint, nil
+}
+
+// containerEnv returns the environment of docker run and its options
+// setting the container's variables. The values are handed over in the
+// environment of docker run rather than on its command line, where other
+// users could read them.
+func containerEnv(cfg DockerConfig) (env, args []string, err error) {
+	if cfg.NoInheritEnv {
+		for _, key := range dockerClientEnv {
+			if value, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+value)
+			}
+		}
+	} else {
+		env = os.Environ()
+	}
+
+	if cfg.EnvFile != "" {
+		if _, err := os.Stat(cfg.EnvFile); err != nil {
+			return nil, nil, fmt.Errorf("failed to read env file: %w", err)
+		}
+		args = append(args, "--env-file", cfg.EnvFile)
+	}
+	for _, variable := range cfg.Env {
+		key, value, found := strings.Cut(variable, "=")
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, nil, fmt.Errorf("invalid environment variable %q (expected KEY=VAL)", variable)
+		}
+		if !found {
+			if cfg.NoInheritEnv {
+				return nil, nil, fmt.Errorf("environment variable %s has no value and the environment is not inherited", key)
+			}
+			if value, found = os.LookupEnv(key); !found {
+				return nil, nil, fmt.Errorf("environment variable %s is not set", key)
+			}
+		}
+		// Later values win, so --env overrides the env file
+		env = append(env, key+"="+value)
+		args = append(args, "--env", key)
+	}
+	return env, args, nil
+}
+
+// exposedPort returns the only TCP port the image exposes, pulling the
+// image if it is not present yet
+func exposedPort(ctx context.Context, docker, image string, logger *Logger) (int, error) {
+	inspect := func() (string, error) {
+		return dockerOutput(ctx, docker, "image", "inspect", "--format", "{{json .Config.ExposedPorts}}", image)
+	}
+	out, err := inspect()
+	if err != nil {
+		logger.Info("Pulling %s...", image)
+		if _, pullErr := dockerOutput(ctx, docker, "pull", image); pullErr != nil {
+			return 0, fmt.Errorf("failed to pull %s: %w", image, pullErr)
+		}
+		if out, err = inspect(); err != nil {
+			return 0, fmt.Errorf("failed to inspect %s: %w", image, err)
+		}
+	}
+
+	var exposed map[string]struct{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &exposed); err != nil {
+		return 0, fmt.Errorf("failed to read the exposed ports of %s: %w", image, err)
+	}
+	var ports []int
+	for spec := range exposed {
+		number, protocol, _ := strings.Cut(spec, "/")
+		if port, err := strconv.Atoi(number); err == nil && (protocol == "" || protocol == "tcp") {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) != 1 {
+		return 0, fmt.Errorf("%s exposes %d TCP ports; set the MCP server's port explicitly", image, len(ports))
+	}
+	return ports[0], nil
+}
+
+// streamLogs logs the container's stdout and stderr line by line
+func (c *DockerContainer) streamLogs(logger *Logger) {
+	c.logs = exec.Command(c.docker, "logs", "--follow", c.ID) //nolint:gosec // G204: docker with the ID it returned
+	output, writer := io.Pipe()
+	c.logs.Stdout = writer
+	c.logs.Stderr = writer
+	if err := c.logs.Start(); err != nil {
+		c.logger.Warning("Not showing the container's output: %v", err)
+		close(c.logsDone)
+		return
+	}
+
+	logged := make(chan struct{})
+	go func() {
+		defer close(logged)
+		scanner := bufio.NewScanner(output)
+		for scanner.Scan() {
+			logger.Info("%s", scanner.Text())
+		}
+	}()
+	go func() {
+		_ = c.logs.Wait()
+		_ = writer.Close()
+		<-logged
+		c.mu.Lock()
+		closing := c.closing
+		c.mu.Unlock()
+		if !closing {
+			c.logger.Error("Container %s stopped", shortContainerID(c.ID))
+		}
+		close(c.logsDone)
+	}()
+}
+
+// waitReady probes endpoint until the server answers, failing early if the
+// container stops
+func (c *DockerContainer) waitReady(ctx context.Context, endpoint string) error {
+	probe := &http.Client{Transport: sharedMCPTransport, Timeout: time.Second}
+	deadline := time.NewTimer(dockerReadyTimeout)
+	defer deadline.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		// Any response, even an error status, means the server listens
+		if resp, err := probe.Do(req); err == nil {
+			_ = resp.Body.Close()
+			return nil
+		}
+
+		select {
+		case <-c.logsDone:
+			return fmt.Errorf("container %s stopped before serving %s", shortContainerID(c.ID), endpoint)
+		case <-deadline.C:
+			return fmt.Errorf("container %s did not serve %s within %s", shortContainerID(c.ID), endpoint, dockerReadyTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dockerReadyInterval):
+		}
+	}
+}
+
+// Close stops and removes the container
+func (c *DockerContainer) Close() error {
+	c.mu.Lock()
+	c.closing = true
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := dockerOutput(ctx, c.docker, "rm", "--force", c.ID)
+	if c.logs != nil && c.logs.Process != nil {
+		_ = c.logs.Process.Kill()
+	}
+	<-c.logsDone
+	if err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", shortContainerID(c.ID), err)
+	}
+	c.logger.Info("Removed container %s", shortContainerID(c.ID))
+	return nil
+}
+
+// dockerOutput runs docker with mcp-debug's environment
+func dockerOutput(ctx context.Context, docker string, args ...string) (string, error) {
+	return commandOutput(exec.CommandContext(ctx, docker, args...)) //nolint:gosec // G204: docker with arguments from the command line
+}
+
+// commandOutput runs a docker command and returns its stdout, or an error
+// with its stderr
+func commandOutput(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", errors.New(strings.TrimSpace(stderr.String()))
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("docker not found: %w", err)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// shortContainerID shortens a container ID the way docker ps shows it
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker writes a docker stand-in that records its arguments, exposes
+// the given ports and publishes the container port on addr. If exits is
+// set, the container's output ends at once as if it stopped.
+func fakeDocker(t *testing.T, exposed, addr string, exits bool) (docker, argsFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker is a shell script")
+	}
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	follow := "exec sleep 60"
+	if exits {
+		follow = "exit 1"
+	}
+	script := `#!/bin/sh
+echo "$@" >> ` + argsFile + `
+case "$1" in
+image) echo '` + exposed + `' ;;
+run) echo 0123456789abcdef0123456789abcdef ;;
+port) echo ` + addr + `; echo "[::1]:1" ;;
+logs)
+	echo "server starting"
+	` + follow + ` ;;
+esac
+`
+	docker = filepath.Join(dir, "docker")
+	if err := os.WriteFile(docker, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return docker, argsFile
+}
+
+// lockedBuffer collects the log of the container's output alongside the
+// agent's own messages
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStartDockerContainer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+	docker, argsFile := fakeDocker(t, `{"8080/tcp":{}}`, addr, false)
+	var log lockedBuffer
+	logger := NewLoggerWithWriter(false, false, false, &log)
+
+	container, endpoint, err := StartDockerContainer(context.Background(), DockerConfig{
+		Image:   "example/mcp-server:1.0",
+		RunArgs: []string{"--label", "debug"},
+		Env:     []string{"DEBUG=1"},
+		Docker:  docker,
+	}, "http://localhost/mcp", logger)
+	if err != nil {
+		t.Fatalf("StartDockerContainer: %v", err)
+	}
+	if want := "http://" + addr + "/mcp"; endpoint != want {
+		t.Errorf("endpoint = %s, want %s", endpoint, want)
+	}
+	if container.ID != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("ID = %s", container.ID)
+	}
+	if err := container.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+
+	if !strings.Contains(log.String(), "server starting") {
+		t.Errorf("container output not logged:\n%s", log.String())
+	}
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		"image inspect --format {{json .Config.ExposedPorts}} example/mcp-server:1.0",
+		"run --detach --publish 127.0.0.1::8080 --env DEBUG --label debug example/mcp-server:1.0",
+		"logs --follow 0123456789abcdef0123456789abcdef",
+		"port 0123456789abcdef0123456789abcdef 8080/tcp",
+		"rm --force 0123456789abcdef0123456789abcdef",
+	}
+	// The output is followed concurrently with looking up the port
+	if len(calls) != len(want) {
+		t.Fatalf("docker calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+	for _, call := range want {
+		found := false
+		for _, got := range calls {
+			found = found || got == call
+		}
+		if !found {
+			t.Errorf("missing docker call %q in:\n%s", call, strings.Join(calls, "\n"))
+		}
+	}
+}
+
+func TestStartDockerContainerStops(t *testing.T) {
+	docker, argsFile := fakeDocker(t, `{}`, "127.0.0.1:1", true)
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	start := time.Now()
+	_, _, err := StartDockerContainer(context.Background(), DockerConfig{Image: "example/broken", Port: 3000, Docker: docker}, "http://localhost/mcp", logger)
+	if err == nil || !strings.Contains(err.Error(), "stopped before serving") {
+		t.Fatalf("expected the container to stop, got %v", err)
+	}
+	if time.Since(start) > dockerReadyTimeout/2 {
+		t.Errorf("waited %s for a stopped container", time.Since(start))
+	}
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "rm --force") {
+		t.Errorf("stopped container not removed:\n%s", data)
+	}
+}
+
+func TestExposedPort(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	tests := []struct {
+		exposed string
+		want    int
+		wantErr bool
+	}{
+		{exposed: `{"8080/tcp":{}}`, want: 8080},
+		{exposed: `{"8080/tcp":{},"53/udp":{}}`, want: 8080},
+		{exposed: `null`, wantErr: true},
+		{exposed: `{"80/tcp":{},"443/tcp":{}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		docker, _ := fakeDocker(t, tt.exposed, "127.0.0.1:1", false)
+		got, err := exposedPort(context.Background(), docker, "example/image", logger)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("exposedPort(%s) = %d, %v; want %d, error %v", tt.exposed, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestContainerEnv(t *testing.T) {
+	t.Setenv("MCP_TOKEN", "secret")
+	t.Setenv("UNRELATED", "value")
+	envFile := filepath.Join(t.TempDir(), "server.env")
+	if err := os.WriteFile(envFile, []byte("LOG_LEVEL=info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      DockerConfig
+		wantArgs string
+		wantEnv  []string
+		noEnv    []string
+		wantErr  string
+	}{
+		{
+			name:     "values and inherited keys",
+			cfg:      DockerConfig{Env: []string{"LOG_LEVEL=debug", "MCP_TOKEN"}, EnvFile: envFile},
+			wantArgs: "--env-file " + envFile + " --env LOG_LEVEL --env MCP_TOKEN",
+			wantEnv:  []string{"LOG_LEVEL=debug", "MCP_TOKEN=secret", "UNRELATED=value"},
+		},
+		{
+			name:     "not inherited",
+			cfg:      DockerConfig{Env: []string{"LOG_LEVEL=debug"}, NoInheritEnv: true},
+			wantArgs: "--env LOG_LEVEL",
+			wantEnv:  []string{"LOG_LEVEL=debug"},
+			noEnv:    []string{"MCP_TOKEN", "UNRELATED"},
+		},
+		{
+			name:    "bare key not inherited",
+			cfg:     DockerConfig{Env: []string{"MCP_TOKEN"}, NoInheritEnv: true},
+			wantErr: "no value",
+		},
+		{
+			name:    "bare key not set",
+			cfg:     DockerConfig{Env: []string{"MCP_MISSING"}},
+			wantErr: "not set",
+		},
+		{
+			name:    "invalid",
+			cfg:     DockerConfig{Env: []string{"=value"}},
+			wantErr: "invalid environment variable",
+		},
+		{
+			name:    "missing env file",
+			cfg:     DockerConfig{EnvFile: filepath.Join(t.TempDir(), "missing.env")},
+			wantErr: "failed to read env file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, args, err := containerEnv(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("containerEnv: %v", err)
+			}
+			if got := strings.Join(args, " "); got != tt.wantArgs {
+				t.Errorf("args = %s, want %s", got, tt.wantArgs)
+			}
+			// Like exec, the last value of a key wins
+			values := map[string]string{}
+			for _, variable := range env {
+				key, value, _ := strings.Cut(variable, "=")
+				values[key] = value
+			}
+			for _, variable := range tt.wantEnv {
+				key, value, _ := strings.Cut(variable, "=")
+				if values[key] != value {
+					t.Errorf("%s = %q, want %q", key, values[key], value)
+				}
+			}
+			for _, key := range tt.noEnv {
+				if _, ok := values[key]; ok {
+					t.Errorf("%s passed although the environment is not inherited", key)
+				}
+			}
+		})
+	}
+}
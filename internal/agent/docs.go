@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// markdownAnchorInvalid matches the characters GitHub drops when turning a
+// heading into an anchor
+var markdownAnchorInvalid = regexp.MustCompile(`[^\p{L}\p{N} _-]`)
+
+// WriteMarkdownDocs writes human-readable documentation of a server's tools,
+// resources, resource templates and prompts as Markdown. Tool arguments and
+// structured results are rendered as tables, with nested properties named
+// "parent.child" and array items "list[]".
+func WriteMarkdownDocs(w io.Writer, inventory *ServerInventory) {
+	info := inventory.Server
+	title := info.Name
+	if title == "" {
+		title = "MCP server"
+	}
+	if info.Version != "" {
+		title += " " + info.Version
+	}
+	_, _ = fmt.Fprintf(w, "# %s\n\n", title)
+	if info.ProtocolVersion != "" {
+		_, _ = fmt.Fprintf(w, "Protocol version: %s\n\n", info.ProtocolVersion)
+	}
+	if info.Instructions != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(info.Instructions))
+	}
+
+	sections := []struct {
+		title string
+		count int
+		write func(io.Writer, *ServerInventory)
+	}{
+		{"Tools", len(inventory.Tools), writeToolDocs},
+		{"Resources", len(inventory.Resources), writeResourceDocs},
+		{"Resource Templates", len(inventory.Templates), writeTemplateDocs},
+		{"Prompts", len(inventory.Prompts), writePromptDocs},
+	}
+	_, _ = fmt.Fprintln(w, "## Contents")
+	_, _ = fmt.Fprintln(w)
+	for _, section := range sections {
+		_, _ = fmt.Fprintf(w, "- [%s](#%s) (%d)\n", section.title, markdownAnchor(section.title), section.count)
+	}
+	for _, section := range sections {
+		_, _ = fmt.Fprintf(w, "\n## %s\n\n", section.title)
+		if section.count == 0 {
+			_, _ = fmt.Fprintln(w, "None.")
+			continue
+		}
+		section.write(w, inventory)
+	}
+}
+
+// writeToolDocs writes a subsection per tool
+func writeToolDocs(w io.Writer, inventory *ServerInventory) {
+	for i, tool := range inventory.Tools {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "### %s\n\n", tool.Name)
+		if title := toolTitle(tool); title != "" {
+			_, _ = fmt.Fprintf(w, "**%s**\n\n", title)
+		}
+		if tool.Description != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(tool.Description))
+		}
+		if hints := toolHints(tool.Annotations); len(hints) > 0 {
+			_, _ = fmt.Fprintf(w, "Hints: %s\n\n", strings.Join(hints, ", "))
+		}
+
+		_, _ = fmt.Fprintln(w, "Arguments:")
+		_, _ = fmt.Fprintln(w)
+		input, _ := exportedSchema(inputSchemaJSON(tool))
+		writeSchemaTable(w, "Argument", input)
+		if output, ok := exportedSchema(outputSchemaJSON(tool)); ok {
+			_, _ = fmt.Fprintln(w)
+			_, _ = fmt.Fprintln(w, "Structured result:")
+			_, _ = fmt.Fprintln(w)
+			writeSchemaTable(w, "Field", output)
+		}
+	}
+}
+
+// toolTitle returns the display title of a tool, if it has one
+func toolTitle(tool mcp.Tool) string {
+	if tool.Title != "" {
+		return tool.Title
+	}
+	return tool.Annotations.Title
+}
+
+// toolHints returns the behaviour hints a tool's annotations set
+func toolHints(annotations mcp.ToolAnnotation) []string {
+	var hints []string
+	for _, hint := range []struct {
+		value   *bool
+		yes, no string
+	}{
+		{annotations.ReadOnlyHint, "read-only", "not read-only"},
+		{annotations.DestructiveHint, "destructive", "not destructive"},
+		{annotations.IdempotentHint, "idempotent", "not idempotent"},
+		{annotations.OpenWorldHint, "open world", "closed world"},
+	} {
+		switch {
+		case hint.value == nil:
+		case *hint.value:
+			hints = append(hints, hint.yes)
+		default:
+			hints = append(hints, hint.no)
+		}
+	}
+	return hints
+}
+
+// writeSchemaTable writes the properties of an object schema as a table,
+// or "None." if it has none
+func writeSchemaTable(w io.Writer, heading string, schema map[string]any) {
+	properties, _ := schema["properties"].(map[string]any)
+	rows := schemaRows("", schemaArguments(properties, anyList(schema["required"])))
+	if len(rows) == 0 {
+		_, _ = fmt.Fprintln(w, "None.")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "| %s | Type | Required | Description |\n", heading)
+	_, _ = fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(w, row)
+	}
+}
+
+// schemaRows returns a table row per argument, followed by the rows of its
+// properties and items
+func schemaRows(prefix string, args []toolArgument) []string {
+	var rows []string
+	for _, arg := range args {
+		name := prefix + arg.Name
+		required := ""
+		if arg.Required {
+			required = "yes"
+		}
+		typ := arg.Type
+		if typ == "" {
+			typ = "any"
+		}
+		if arg.Items != nil && arg.Items.Type != "" {
+			typ += " of " + arg.Items.Type
+		}
+
+		description := arg.Description
+		if len(arg.Enum) > 0 {
+			values := make([]string, len(arg.Enum))
+			for i, value := range arg.Enum {
+				values[i] = "`" + compactJSON(value) + "`"
+			}
+			description = joinNonEmpty(description, "One of: "+strings.Join(values, ", "))
+		}
+		if arg.Default != nil {
+			description = joinNonEmpty(description, "Default: `"+compactJSON(arg.Default)+"`")
+		}
+
+		rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | %s |",
+			markdownCell(name), markdownCell(typ), required, markdownCell(description)))
+		rows = append(rows, schemaRows(name+".", arg.Properties)...)
+		if arg.Items != nil {
+			rows = append(rows, schemaRows(name+"[].", arg.Items.Properties)...)
+		}
+	}
+	return rows
+}
+
+// joinNonEmpty joins two lines of a table cell, skipping an empty first
+func joinNonEmpty(first, second string) string {
+	if first == "" {
+		return second
+	}
+	return first + "\n" + second
+}
+
+// writeResourceDocs writes the resources as a table
+func writeResourceDocs(w io.Writer, inventory *ServerInventory) {
+	_, _ = fmt.Fprintln(w, "| URI | Name | MIME type | Description |")
+	_, _ = fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, resource := range inventory.Resources {
+		_, _ = fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", markdownCell(resource.URI),
+			markdownCell(resource.Name), markdownCell(resource.MIMEType), markdownCell(resource.Description))
+	}
+}
+
+// writeTemplateDocs writes the resource templates as a table
+func writeTemplateDocs(w io.Writer, inventory *ServerInventory) {
+	_, _ = fmt.Fprintln(w, "| URI template | Name | MIME type | Description |")
+	_, _ = fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, template := range inventory.Templates {
+		uri := ""
+		if template.URITemplate != nil && template.URITemplate.Template != nil {
+			uri = template.URITemplate.Raw()
+		}
+		_, _ = fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", markdownCell(uri),
+			markdownCell(template.Name), markdownCell(template.MIMEType), markdownCell(template.Description))
+	}
+}
+
+// writePromptDocs writes a subsection per prompt
+func writePromptDocs(w io.Writer, inventory *ServerInventory) {
+	for i, prompt := range inventory.Prompts {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "### %s\n\n", prompt.Name)
+		if prompt.Title != "" {
+			_, _ = fmt.Fprintf(w, "**%s**\n\n", prompt.Title)
+		}
+		if prompt.Description != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(prompt.Description))
+		}
+		_, _ = fmt.Fprintln(w, "Arguments:")
+		_, _ = fmt.Fprintln(w)
+		if len(prompt.Arguments) == 0 {
+			_, _ = fmt.Fprintln(w, "None.")
+			continue
+		}
+		_, _ = fmt.Fprintln(w, "| Argument | Required | Description |")
+		_, _ = fmt.Fprintln(w, "| --- | --- | --- |")
+		for _, arg := range prompt.Arguments {
+			required := ""
+			if arg.Required {
+				required = "yes"
+			}
+			_, _ = fmt.Fprintf(w, "| `%s` | %s | %s |\n", markdownCell(arg.Name), required, markdownCell(arg.Description))
+		}
+	}
+}
+
+// markdownCell escapes text for a table cell, which cannot hold pipes or
+// line breaks
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+// markdownAnchor returns the anchor GitHub generates for a heading
+func markdownAnchor(heading string) string {
+	anchor := markdownAnchorInvalid.ReplaceAllString(strings.ToLower(heading), "")
+	return strings.ReplaceAll(anchor, " ", "-")
+}
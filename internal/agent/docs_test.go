@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestWriteMarkdownDocs(t *testing.T) {
+	s := server.NewMCPServer("docs", "2.0.0", server.WithInstructions("Deploy apps."))
+	s.AddTool(mcp.NewTool("deploy",
+		mcp.WithDescription("Deploy an app"),
+		mcp.WithString("app", mcp.Required(), mcp.Description("App name | slug")),
+		mcp.WithString("target", mcp.Enum("staging", "production"), mcp.DefaultString("staging")),
+		mcp.WithObject("limits", mcp.Properties(map[string]any{"cpu": map[string]any{"type": "string"}})),
+		mcp.WithArray("tags", mcp.WithStringItems()),
+		mcp.WithOutputSchema[struct {
+			Revision int `json:"revision"`
+		}](),
+		mcp.WithDestructiveHintAnnotation(true),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	s.AddResource(mcp.NewResource("file:///config", "config", mcp.WithMIMEType("application/json")),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		})
+	s.AddPrompt(mcp.NewPrompt("review", mcp.WithPromptDescription("Review a change"),
+		mcp.WithArgument("diff", mcp.RequiredArgument(), mcp.ArgumentDescription("The diff\nto review"))),
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return nil, nil
+		})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:      ts.URL + "/mcp",
+		Transport:     "streamable-http",
+		Logger:        NewLoggerWithWriter(false, false, false, io.Discard),
+		NoInitialList: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	inventory, err := client.Inventory(ctx)
+	if err != nil {
+		t.Fatalf("Inventory: %v", err)
+	}
+	var buf bytes.Buffer
+	WriteMarkdownDocs(&buf, inventory)
+	docs := buf.String()
+
+	for _, want := range []string{
+		"# docs 2.0.0\n",
+		"Deploy apps.\n",
+		"- [Resource Templates](#resource-templates) (0)\n",
+		"### deploy\n\nDeploy an app\n\nHints: not read-only, destructive, not idempotent, open world\n",
+		"| `app` | string | yes | App name \\| slug |\n",
+		"| `target` | string |  | One of: `\"staging\"`, `\"production\"`<br>Default: `\"staging\"` |\n",
+		"| `limits.cpu` | string |  |  |\n",
+		"| `tags` | array of string |  |  |\n",
+		"Structured result:\n\n| Field | Type | Required | Description |\n",
+		"| `revision` | integer | yes |  |\n",
+		"| `file:///config` | config | application/json |  |\n",
+		"## Resource Templates\n\nNone.\n",
+		"| `diff` | yes | The diff<br>to review |\n",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("docs do not contain %q:\n%s", want, docs)
+		}
+	}
+}
+
+func TestMarkdownAnchor(t *testing.T) {
+	for heading, want := range map[string]string{
+		"Resource Templates": "resource-templates",
+		"get_weather":        "get_weather",
+		"deploy/app (v2)":    "deployapp-v2",
+	} {
+		if got := markdownAnchor(heading); got != want {
+			t.Errorf("markdownAnchor(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
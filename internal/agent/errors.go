@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Error taxonomy for the Client API.
+//
+// Errors returned by Client methods are classified so callers can react with
+// errors.Is instead of inspecting messages. The original error is preserved,
+// so errors.Is/As against lower-level errors (e.g. mcp.ErrMethodNotFound)
+// keeps working and the message is unchanged.
+var (
+	// ErrAuthRequired indicates the server requires (re-)authorization
+	ErrAuthRequired = errors.New("authorization required")
+
+	// ErrInsufficientScope indicates the token lacks scopes required by the server
+	ErrInsufficientScope = errors.New("insufficient scope")
+
+	// ErrTransportClosed indicates the connection to the server was lost
+	ErrTransportClosed = errors.New("transport closed")
+
+	// ErrProtocol indicates the server answered with a JSON-RPC/MCP protocol error
+	ErrProtocol = errors.New("protocol error")
+
+	// ErrToolFailed indicates a tool call completed but the tool reported an error
+	ErrToolFailed = errors.New("tool failed")
+
+	// ErrTimeout indicates a tool call exceeded its deadline before the server
+	// answered
+	ErrTimeout = errors.New("timeout")
+
+	// ErrSchemaViolation indicates tool arguments or a structured result did
+	// not match the schema the tool declares (with strict schema checking)
+	ErrSchemaViolation = errors.New("schema violation")
+
+	// ErrAssertionFailed indicates a tool result did not meet the checks of
+	// an expectation file
+	ErrAssertionFailed = errors.New("assertion failed")
+)
+
+// errorKinds lists the taxonomy sentinels, most specific first
+var errorKinds = []error{
+	ErrInsufficientScope,
+	ErrAuthRequired,
+	ErrTimeout,
+	ErrTransportClosed,
+	ErrProtocol,
+	ErrSchemaViolation,
+	ErrToolFailed,
+	ErrAssertionFailed,
+}
+
+// classifiedError attaches a taxonomy sentinel to an error without altering
+// its message
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+
+func (e *classifiedError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// withKind marks err as belonging to kind
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, kind) {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+// ErrorKind returns the taxonomy sentinel err belongs to, or nil if the error
+// is not classified
+func ErrorKind(err error) error {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// classifyError attaches the matching taxonomy sentinel to err. Errors that
+// already carry a sentinel or match none are returned unchanged.
+func classifyError(err error) error {
+	if err == nil || ErrorKind(err) != nil {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up (e.g. Ctrl+C or its own deadline); neither the
+		// server nor the connection failed, and tool call timeouts are
+		// classified where they are detected
+		return err
+	case isInsufficientScopeError(err):
+		return withKind(ErrInsufficientScope, err)
+	case isAuthRequiredError(err):
+		return withKind(ErrAuthRequired, err)
+	case isTransportClosedError(err):
+		return withKind(ErrTransportClosed, err)
+	case isProtocolError(err):
+		return withKind(ErrProtocol, err)
+	}
+	return err
+}
+
+// isInsufficientScopeError reports whether err stems from an insufficient_scope
+// challenge that step-up authorization could not resolve
+func isInsufficientScopeError(err error) bool {
+	var stepUpErr *stepUpError
+	return errors.As(err, &stepUpErr)
+}
+
+// isAuthRequiredError reports whether err signals missing or rejected credentials
+func isAuthRequiredError(err error) bool {
+	return client.IsOAuthAuthorizationRequiredError(err) ||
+		errors.Is(err, transport.ErrOAuthAuthorizationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationRequired) ||
+		errors.Is(err, transport.ErrUnauthorized) ||
+		errors.Is(err, transport.ErrNoToken)
+}
+
+// isTransportClosedError reports whether err means the connection is gone and
+// a reconnect may help
+func isTransportClosedError(err error) bool {
+	if errors.Is(err, transport.ErrTransportClosed) ||
+		errors.Is(err, transport.ErrSessionTerminated) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// The HTTP transport often flattens the underlying error with %v, so fall
+	// back to the well-known messages
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "connection refused") ||
+		strings.Contains(errMsg, "connection reset by peer") ||
+		strings.Contains(errMsg, "transport is closing") ||
+		strings.Contains(errMsg, "broken pipe") ||
+		strings.Contains(errMsg, "unexpected eof")
+}
+
+// isProtocolError reports whether err is a JSON-RPC error returned by the server
+func isProtocolError(err error) bool {
+	var versionErr mcp.UnsupportedProtocolVersionError
+	return errors.As(err, &versionErr) ||
+		errors.Is(err, mcp.ErrParseError) ||
+		errors.Is(err, mcp.ErrInvalidRequest) ||
+		errors.Is(err, mcp.ErrMethodNotFound) ||
+		errors.Is(err, mcp.ErrInvalidParams) ||
+		errors.Is(err, mcp.ErrInternalError) ||
+		errors.Is(err, mcp.ErrResourceNotFound)
+}
+
+// ToolResultError returns an ErrToolFailed error if the tool call result
+// reports an error, nil otherwise
+func ToolResultError(result *mcp.CallToolResult) error {
+	if result == nil || !result.IsError {
+		return nil
+	}
+
+	var messages []string
+	for _, content := range result.Content {
+		if textContent, ok := mcp.AsTextContent(content); ok {
+			messages = append(messages, textContent.Text)
+		}
+	}
+	if len(messages) == 0 {
+		return ErrToolFailed
+	}
+	return fmt.Errorf("%w: %s", ErrToolFailed, strings.Join(messages, "; "))
+}
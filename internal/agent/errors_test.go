@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error
+	}{
+		{
+			name:     "nil",
+			err:      nil,
+			wantKind: nil,
+		},
+		{
+			name:     "unauthorized",
+			err:      transport.NewError(transport.ErrUnauthorized),
+			wantKind: ErrAuthRequired,
+		},
+		{
+			name:     "oauth authorization required",
+			err:      fmt.Errorf("start: %w", transport.ErrOAuthAuthorizationRequired),
+			wantKind: ErrAuthRequired,
+		},
+		{
+			name:     "failed step-up",
+			err:      fmt.Errorf("failed to send request: %w", newStepUpError(errors.New("user declined step-up authorization"))),
+			wantKind: ErrInsufficientScope,
+		},
+		{
+			name:     "insufficient scope message only",
+			err:      errors.New(`403: error="insufficient_scope"`),
+			wantKind: nil,
+		},
+		{
+			name:     "connection refused",
+			err:      errors.New("dial tcp: connection refused"),
+			wantKind: ErrTransportClosed,
+		},
+		{
+			name:     "session terminated",
+			err:      transport.NewError(transport.ErrSessionTerminated),
+			wantKind: ErrTransportClosed,
+		},
+		{
+			name:     "context canceled",
+			err:      fmt.Errorf("failed to send request: %w", context.Canceled),
+			wantKind: nil,
+		},
+		{
+			name:     "context deadline exceeded",
+			err:      fmt.Errorf("failed to send request: %w", context.DeadlineExceeded),
+			wantKind: nil,
+		},
+		{
+			name:     "method not found",
+			err:      fmt.Errorf("%w: tools/list", mcp.ErrMethodNotFound),
+			wantKind: ErrProtocol,
+		},
+		{
+			name:     "unsupported protocol version",
+			err:      mcp.UnsupportedProtocolVersionError{Version: "1999-01-01"},
+			wantKind: ErrProtocol,
+		},
+		{
+			name:     "already classified",
+			err:      withKind(ErrToolFailed, errors.New("boom")),
+			wantKind: ErrToolFailed,
+		},
+		{
+			name:     "unknown",
+			err:      errors.New("something odd"),
+			wantKind: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if kind := ErrorKind(got); kind != tt.wantKind {
+				t.Errorf("ErrorKind(classifyError(%v)) = %v, want %v", tt.err, kind, tt.wantKind)
+			}
+			if tt.err != nil {
+				if got.Error() != tt.err.Error() {
+					t.Errorf("classification changed message: %q -> %q", tt.err.Error(), got.Error())
+				}
+				if !errors.Is(got, tt.err) {
+					t.Error("classified error no longer matches the original error")
+				}
+			}
+		})
+	}
+}
+
+func TestToolResultError(t *testing.T) {
+	if err := ToolResultError(nil); err != nil {
+		t.Errorf("expected nil for nil result, got %v", err)
+	}
+
+	ok := mcp.NewToolResultText("fine")
+	if err := ToolResultError(ok); err != nil {
+		t.Errorf("expected nil for successful result, got %v", err)
+	}
+
+	failed := mcp.NewToolResultError("disk full")
+	err := ToolResultError(failed)
+	if !errors.Is(err, ErrToolFailed) {
+		t.Fatalf("expected ErrToolFailed, got %v", err)
+	}
+	if err.Error() != "tool failed: disk full" {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tool export formats
+const (
+	ExportOpenAPI    = "openapi"
+	ExportJSONSchema = "jsonschema"
+)
+
+// ExportFormats lists the formats ExportTools writes
+var ExportFormats = []string{ExportOpenAPI, ExportJSONSchema}
+
+// componentNameInvalid matches the characters OpenAPI does not allow in
+// component names
+var componentNameInvalid = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ExportTools converts tool definitions into an OpenAPI 3.1 document or a
+// JSON Schema 2020-12 bundle. Both hold the input and output schema of each
+// tool as "<tool>.input" and "<tool>.output"; OpenAPI also describes each
+// tool as a POST operation on /tools/<tool> taking its arguments as the
+// request body, a projection for generators rather than a real HTTP API.
+func ExportTools(tools []mcp.Tool, info ServerInfo, format string) (map[string]any, error) {
+	schemas := map[string]any{}
+	for _, tool := range tools {
+		name := componentName(tool.Name)
+		input, ok := exportedSchema(inputSchemaJSON(tool))
+		if !ok {
+			input = map[string]any{"type": "object"}
+		}
+		input = describeSchema(input, "Arguments of the "+tool.Name+" tool", tool.Description)
+		schemas[name+".input"] = withSchemaID(input, tool.Name, "input")
+		if output, ok := exportedSchema(outputSchemaJSON(tool)); ok {
+			output = describeSchema(output, "Structured result of the "+tool.Name+" tool", "")
+			schemas[name+".output"] = withSchemaID(output, tool.Name, "output")
+		}
+	}
+
+	switch format {
+	case ExportOpenAPI:
+		return toolsOpenAPI(tools, info, schemas), nil
+	case ExportJSONSchema:
+		title := "Tools"
+		if info.Name != "" {
+			title = "Tools of " + info.Name
+		}
+		return map[string]any{
+			"$schema":     "https://json-schema.org/draft/2020-12/schema",
+			"title":       title,
+			"description": "Input and output schemas of the MCP tools, one pair per tool as <tool>.input and <tool>.output",
+			"$defs":       schemas,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown export format: %s (must be one of: %s)", format, strings.Join(ExportFormats, ", "))
+}
+
+// toolsOpenAPI builds the OpenAPI document with one operation per tool
+func toolsOpenAPI(tools []mcp.Tool, info ServerInfo, schemas map[string]any) map[string]any {
+	apiInfo := map[string]any{"title": info.Name, "version": info.Version}
+	if info.Name == "" {
+		apiInfo["title"] = "MCP server"
+	}
+	if info.Version == "" {
+		apiInfo["version"] = "unknown"
+	}
+	if info.Instructions != "" {
+		apiInfo["description"] = info.Instructions
+	}
+
+	paths := map[string]any{}
+	for _, tool := range tools {
+		name := componentName(tool.Name)
+		response := map[string]any{"description": "Tool result"}
+		if _, ok := schemas[name+".output"]; ok {
+			response["content"] = map[string]any{"application/json": map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/" + name + ".output"},
+			}}
+		}
+		operation := map[string]any{
+			"operationId": name,
+			"summary":     tool.Name,
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/" + name + ".input"},
+				}},
+			},
+			"responses":  map[string]any{"200": response},
+			"x-mcp-tool": tool.Name,
+		}
+		if definition := toolDefinition(tool); definition["annotations"] != nil {
+			operation["x-mcp-annotations"] = definition["annotations"]
+		}
+		if tool.Title != "" {
+			operation["summary"] = tool.Title
+		}
+		if tool.Description != "" {
+			operation["description"] = tool.Description
+		}
+		paths["/tools/"+name] = map[string]any{"post": operation}
+	}
+
+	return map[string]any{
+		"openapi":    "3.1.0",
+		"info":       apiInfo,
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// componentName turns a tool name into a valid OpenAPI component name
+func componentName(tool string) string {
+	return componentNameInvalid.ReplaceAllString(tool, "_")
+}
+
+// exportedSchema decodes a declared schema
+func exportedSchema(schemaJSON []byte, declared bool) (map[string]any, bool) {
+	if !declared {
+		return nil, false
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil || schema == nil {
+		return nil, false
+	}
+	return schema, true
+}
+
+// describeSchema sets the title and description of a schema unless it has
+// its own
+func describeSchema(schema map[string]any, title, description string) map[string]any {
+	if _, ok := schema["title"]; !ok {
+		schema["title"] = title
+	}
+	if _, ok := schema["description"]; !ok && description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+// withSchemaID gives a schema that refers to its own definitions an $id,
+// making it an embedded resource so that "#/$defs/..." still resolves
+// against the schema rather than the bundle
+func withSchemaID(schema map[string]any, tool, kind string) map[string]any {
+	if _, ok := schema["$id"]; !ok && hasLocalRef(schema) {
+		schema["$id"] = "urn:mcp:tool:" + tool + ":" + kind
+	}
+	return schema
+}
+
+// hasLocalRef reports whether a schema contains a $ref to a fragment of
+// itself
+func hasLocalRef(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+			return true
+		}
+		for _, member := range v {
+			if hasLocalRef(member) {
+				return true
+			}
+		}
+	case []any:
+		for _, element := range v {
+			if hasLocalRef(element) {
+				return true
+			}
+		}
+	}
+	return false
+}
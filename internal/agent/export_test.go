@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// exportTestTools returns a tool with typed schemas and one with a raw
+// input schema that refers to its own definitions
+func exportTestTools() []mcp.Tool {
+	weather := mcp.NewTool("get_weather",
+		mcp.WithDescription("Current weather of a city"),
+		mcp.WithString("city", mcp.Required()),
+		mcp.WithOutputSchema[struct {
+			Celsius float64 `json:"celsius"`
+		}](),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	deploy := mcp.NewToolWithRawSchema("deploy/app", "Deploy an app", json.RawMessage(`{
+		"type": "object",
+		"properties": {"target": {"$ref": "#/$defs/target"}},
+		"required": ["target"],
+		"$defs": {"target": {"type": "string", "enum": ["staging", "production"]}}
+	}`))
+	return []mcp.Tool{weather, deploy}
+}
+
+func TestExportToolsOpenAPI(t *testing.T) {
+	doc, err := ExportTools(exportTestTools(), ServerInfo{Name: "weather", Version: "1.2.0"}, ExportOpenAPI)
+	if err != nil {
+		t.Fatalf("ExportTools: %v", err)
+	}
+	data, _ := json.Marshal(doc)
+	var api struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title, Version string
+		} `json:"info"`
+		Paths map[string]struct {
+			Post struct {
+				OperationID string         `json:"operationId"`
+				Summary     string         `json:"summary"`
+				Annotations map[string]any `json:"x-mcp-annotations"`
+				RequestBody struct {
+					Content map[string]struct {
+						Schema map[string]string `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+				Responses map[string]struct {
+					Content map[string]struct {
+						Schema map[string]string `json:"schema"`
+					} `json:"content"`
+				} `json:"responses"`
+			} `json:"post"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &api); err != nil {
+		t.Fatal(err)
+	}
+
+	if api.OpenAPI != "3.1.0" || api.Info.Title != "weather" || api.Info.Version != "1.2.0" {
+		t.Errorf("unexpected header %s %+v", api.OpenAPI, api.Info)
+	}
+	weather := api.Paths["/tools/get_weather"].Post
+	if weather.OperationID != "get_weather" || weather.Annotations["readOnlyHint"] != true {
+		t.Errorf("unexpected operation %+v", weather)
+	}
+	if ref := weather.RequestBody.Content["application/json"].Schema["$ref"]; ref != "#/components/schemas/get_weather.input" {
+		t.Errorf("unexpected request schema %s", ref)
+	}
+	if ref := weather.Responses["200"].Content["application/json"].Schema["$ref"]; ref != "#/components/schemas/get_weather.output" {
+		t.Errorf("unexpected response schema %s", ref)
+	}
+	deploy := api.Paths["/tools/deploy_app"].Post
+	if deploy.OperationID != "deploy_app" || deploy.Summary != "deploy/app" || len(deploy.Responses["200"].Content) != 0 {
+		t.Errorf("unexpected operation %+v", deploy)
+	}
+	if id := api.Components.Schemas["deploy_app.input"]["$id"]; id != "urn:mcp:tool:deploy/app:input" {
+		t.Errorf("schema with local refs got $id %v", id)
+	}
+	if _, ok := api.Components.Schemas["get_weather.input"]["$id"]; ok {
+		t.Error("schemas without local refs should not get an $id")
+	}
+}
+
+func TestExportToolsJSONSchema(t *testing.T) {
+	doc, err := ExportTools(exportTestTools(), ServerInfo{Name: "weather"}, ExportJSONSchema)
+	if err != nil {
+		t.Fatalf("ExportTools: %v", err)
+	}
+	defs := doc["$defs"].(map[string]any)
+	if len(defs) != 3 {
+		t.Errorf("expected 3 schemas, got %d", len(defs))
+	}
+
+	// The bundle compiles, and the raw schema's own $ref still resolves
+	doc["$ref"] = "#/$defs/deploy_app.input"
+	bundle, _ := json.Marshal(doc)
+	schema, err := compileSchema(bundle)
+	if err != nil {
+		t.Fatalf("bundle does not compile: %v", err)
+	}
+	if err := schema.Validate(map[string]any{"target": "staging"}); err != nil {
+		t.Errorf("valid arguments rejected: %v", err)
+	}
+	if err := schema.Validate(map[string]any{"target": "moon"}); err == nil {
+		t.Error("invalid arguments accepted")
+	}
+
+	if _, err := ExportTools(nil, ServerInfo{}, "yaml"); err == nil || !strings.Contains(err.Error(), "openapi, jsonschema") {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
@@ -0,0 +1,513 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Fuzzing defaults
+const (
+	// DefaultFuzzTimeout bounds each fuzz case and the ping after it
+	DefaultFuzzTimeout = 10 * time.Second
+	// DefaultFuzzStringSize is the length of the oversized strings
+	DefaultFuzzStringSize = 1 << 20
+	// maxFuzzResponseBytes is how much of a response is read
+	maxFuzzResponseBytes = 16 << 20
+	// maxFuzzDetailRunes shortens details, which may echo oversized input
+	maxFuzzDetailRunes = 200
+)
+
+// invalidUTF8 is spliced into messages where strings are expected
+const invalidUTF8 = "\xff\xfe\xc3("
+
+// FuzzConfig configures a fuzzing run
+type FuzzConfig struct {
+	// Tools are the tools called with mutated arguments. Fuzzing a tool
+	// calls it, so none are unless they are named or AllTools is set.
+	Tools []string
+	// AllTools fuzzes every tool the server lists
+	AllTools bool
+	// Timeout bounds each case; 0 uses DefaultFuzzTimeout
+	Timeout time.Duration
+	// StringSize is the length of oversized strings; 0 uses
+	// DefaultFuzzStringSize
+	StringSize int
+}
+
+// FuzzOutcome is how the server handled a malformed message
+type FuzzOutcome string
+
+const (
+	// FuzzOK means the server rejected the message as the spec asks, or
+	// handled input that it may accept
+	FuzzOK FuzzOutcome = "ok"
+	// FuzzAccepted means a tool returned a successful result for arguments
+	// that break its input schema. It is not counted as a finding.
+	FuzzAccepted FuzzOutcome = "accepted"
+	// FuzzNonSpec means the response is not what the spec requires, e.g. a
+	// result for an invalid request or an error without a code
+	FuzzNonSpec FuzzOutcome = "non-spec"
+	// FuzzHang means the server did not answer within the timeout
+	FuzzHang FuzzOutcome = "hang"
+	// FuzzCrash means the connection failed, the server answered with a 5xx
+	// status or it stopped answering pings afterwards
+	FuzzCrash FuzzOutcome = "crash"
+)
+
+// fuzzExpectation is the kind of response a fuzz case should get
+type fuzzExpectation int
+
+const (
+	// fuzzExpectReject requires a JSON-RPC error or a 4xx status
+	fuzzExpectReject fuzzExpectation = iota
+	// fuzzExpectNoReply is for notifications, which get no response
+	fuzzExpectNoReply
+	// fuzzExpectToolError expects a JSON-RPC error or a tool error; a
+	// successful result is reported as accepted
+	fuzzExpectToolError
+	// fuzzExpectAny accepts any well-formed response
+	fuzzExpectAny
+)
+
+// fuzzCase is one malformed message: a request envelope with broken fields,
+// or raw bytes
+type fuzzCase struct {
+	name    string
+	target  string
+	message map[string]any
+	raw     []byte
+	// hasID is set for messages with a valid id, which the response must
+	// echo; RunFuzz numbers them
+	hasID  bool
+	id     int
+	expect fuzzExpectation
+}
+
+// body returns the bytes posted for the case
+func (fc fuzzCase) body() []byte {
+	if fc.message == nil {
+		return fc.raw
+	}
+	if fc.hasID {
+		fc.message["id"] = fc.id
+	}
+	return marshalFuzzMessage(fc.message)
+}
+
+// FuzzResult is the outcome of one fuzz case
+type FuzzResult struct {
+	// Target is "protocol" or the name of the fuzzed tool
+	Target string `json:"target"`
+	// Case describes the mutation
+	Case    string      `json:"case"`
+	Outcome FuzzOutcome `json:"outcome"`
+	// Status is the HTTP status of the response; 0 if there was none
+	Status     int     `json:"status,omitempty"`
+	Detail     string  `json:"detail,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// FuzzReport holds the results of a fuzzing run
+type FuzzReport struct {
+	Endpoint string       `json:"endpoint"`
+	Results  []FuzzResult `json:"results"`
+	// Aborted explains why the run stopped early, e.g. because the server
+	// crashed
+	Aborted string `json:"aborted,omitempty"`
+}
+
+// Findings returns the number of crashes, hangs and non-spec responses
+func (r *FuzzReport) Findings() int {
+	findings := 0
+	for _, result := range r.Results {
+		switch result.Outcome {
+		case FuzzCrash, FuzzHang, FuzzNonSpec:
+			findings++
+		}
+	}
+	return findings
+}
+
+// fuzzTarget is the target of the protocol-level cases
+const fuzzTarget = "protocol"
+
+// RunFuzz sends malformed JSON-RPC messages to the server of a connected
+// client and reports how it handled each. The messages are posted within the
+// client's session, bypassing mcp-go, which would not send them. After every
+// case the server is pinged, and the run stops once it no longer answers.
+func (c *Client) RunFuzz(ctx context.Context, cfg FuzzConfig) (*FuzzReport, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultFuzzTimeout
+	}
+	if cfg.StringSize <= 0 {
+		cfg.StringSize = DefaultFuzzStringSize
+	}
+
+	cases := protocolFuzzCases(cfg.StringSize)
+	if cfg.AllTools || len(cfg.Tools) > 0 {
+		tools, err := c.ListCapabilities(ctx, cacheTools)
+		if err != nil {
+			return nil, err
+		}
+		found := map[string]bool{}
+		for _, tool := range tools.([]mcp.Tool) {
+			if cfg.AllTools || slices.Contains(cfg.Tools, tool.Name) {
+				found[tool.Name] = true
+				cases = append(cases, toolFuzzCases(tool, cfg.StringSize)...)
+			}
+		}
+		for _, name := range cfg.Tools {
+			if !found[name] {
+				return nil, fmt.Errorf("unknown tool: %s", name)
+			}
+		}
+	}
+	for i := range cases {
+		cases[i].id = i + 1
+	}
+
+	sender, err := c.sessionPoster("fuzzing")
+	if err != nil {
+		return nil, err
+	}
+	report := &FuzzReport{Endpoint: c.currentEndpoint()}
+	for _, fc := range cases {
+		if ctx.Err() != nil {
+			report.Aborted = ctx.Err().Error()
+			break
+		}
+		c.logger.Debug("Fuzzing %s: %s", fc.target, fc.name)
+		result := sender.send(ctx, fc, cfg.Timeout)
+
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		_, pingErr := c.Ping(pingCtx)
+		cancel()
+		if pingErr != nil && ctx.Err() == nil {
+			if result.Outcome != FuzzCrash {
+				result.Outcome = FuzzCrash
+				result.Detail = strings.TrimPrefix(result.Detail+"; ", "; ") + "server stopped answering pings: " + pingErr.Error()
+			}
+			report.Results = append(report.Results, result)
+			report.Aborted = fmt.Sprintf("server stopped answering after %q", fc.name)
+			break
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// send posts one case and classifies the response
+func (s *sessionPoster) send(ctx context.Context, fc fuzzCase, timeout time.Duration) FuzzResult {
+	result := FuzzResult{Target: fc.target, Case: fc.name}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	status, messages, err := s.post(ctx, fc.body())
+	result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	result.Status = status
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Outcome = FuzzHang
+		result.Detail = fmt.Sprintf("no response within %s", timeout)
+	case err != nil:
+		result.Outcome = FuzzCrash
+		result.Detail = err.Error()
+	default:
+		result.Outcome, result.Detail = classifyFuzzResponse(fc, status, messages)
+	}
+	if detail := []rune(result.Detail); len(detail) > maxFuzzDetailRunes {
+		result.Detail = string(detail[:maxFuzzDetailRunes-1]) + "…"
+	}
+	return result
+}
+
+// post sends a body and returns the status and the JSON-RPC messages of the
+// response, which may be a JSON document or an SSE stream
+func (s *sessionPoster) post(ctx context.Context, body []byte) (int, []json.RawMessage, error) {
+	resp, err := s.do(ctx, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFuzzResponseBytes))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	var messages []json.RawMessage
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		parser := &sseParser{emit: func(id, data string) {
+			messages = append(messages, json.RawMessage(data))
+		}}
+		parser.feed(data)
+		parser.line("")
+	} else if len(bytes.TrimSpace(data)) > 0 {
+		messages = append(messages, json.RawMessage(data))
+	}
+	return resp.StatusCode, messages, nil
+}
+
+// fuzzResponse is a JSON-RPC response, with the fields kept raw so missing
+// and mistyped ones can be told apart
+type fuzzResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Code    *int    `json:"code"`
+		Message *string `json:"message"`
+	} `json:"error"`
+}
+
+// classifyFuzzResponse checks a response against what the spec requires
+// for the case
+func classifyFuzzResponse(fc fuzzCase, status int, messages []json.RawMessage) (FuzzOutcome, string) {
+	switch {
+	case status >= 500:
+		return FuzzCrash, fmt.Sprintf("HTTP %d", status)
+	case status >= 400:
+		return FuzzOK, fmt.Sprintf("rejected with HTTP %d", status)
+	}
+
+	// Notifications are answered without a body; so are responses the
+	// server chose not to send, which only requests require
+	var response *fuzzResponse
+	for _, message := range messages {
+		var candidate fuzzResponse
+		if err := json.Unmarshal(message, &candidate); err != nil {
+			return FuzzNonSpec, fmt.Sprintf("response is not JSON: %v", err)
+		}
+		if candidate.Result != nil || candidate.Error != nil {
+			response = &candidate
+			break
+		}
+	}
+	if response == nil {
+		if fc.expect == fuzzExpectNoReply {
+			return FuzzOK, "no response"
+		}
+		return FuzzNonSpec, fmt.Sprintf("HTTP %d without a JSON-RPC response", status)
+	}
+
+	if response.JSONRPC != mcp.JSONRPC_VERSION {
+		return FuzzNonSpec, fmt.Sprintf("response has jsonrpc %q", response.JSONRPC)
+	}
+	if response.Result != nil && response.Error != nil {
+		return FuzzNonSpec, "response has both a result and an error"
+	}
+	if fc.hasID && !bytes.Equal(bytes.TrimSpace(response.ID), []byte(fmt.Sprint(fc.id))) {
+		return FuzzNonSpec, fmt.Sprintf("response id %s does not match request id %d", orNone(string(response.ID)), fc.id)
+	}
+	if response.Error != nil {
+		if response.Error.Code == nil || response.Error.Message == nil {
+			return FuzzNonSpec, "error response without a code or message"
+		}
+		if fc.expect == fuzzExpectNoReply {
+			return FuzzOK, fmt.Sprintf("rejected with error %d", *response.Error.Code)
+		}
+		return FuzzOK, fmt.Sprintf("error %d: %s", *response.Error.Code, *response.Error.Message)
+	}
+
+	switch fc.expect {
+	case fuzzExpectReject:
+		return FuzzNonSpec, "returned a result instead of an error"
+	case fuzzExpectNoReply:
+		return FuzzNonSpec, "answered a notification"
+	case fuzzExpectToolError:
+		var result struct {
+			IsError bool `json:"isError"`
+		}
+		if json.Unmarshal(response.Result, &result) == nil && result.IsError {
+			return FuzzOK, "tool error"
+		}
+		return FuzzAccepted, "tool returned a successful result"
+	}
+	return FuzzOK, "result"
+}
+
+// protocolFuzzCases returns the cases that break the JSON-RPC envelope or
+// the MCP request parameters
+func protocolFuzzCases(stringSize int) []fuzzCase {
+	cases := []fuzzCase{
+		requestFuzzCase("unknown method", fuzzExpectReject, map[string]any{"method": "fuzz/unknown"}),
+		requestFuzzCase("wrong jsonrpc version", fuzzExpectReject, map[string]any{"jsonrpc": "1.0", "method": mcp.MethodToolsList}),
+		requestFuzzCase("missing jsonrpc", fuzzExpectReject, map[string]any{"jsonrpc": nil, "method": mcp.MethodToolsList}),
+		requestFuzzCase("numeric method", fuzzExpectReject, map[string]any{"method": 42}),
+		requestFuzzCase("missing method", fuzzExpectReject, map[string]any{"method": nil}),
+		requestFuzzCase("oversized method", fuzzExpectReject, map[string]any{"method": strings.Repeat("x", stringSize)}),
+		requestFuzzCase("invalid UTF-8 method", fuzzExpectReject, map[string]any{"method": "tools/" + invalidUTF8}),
+		requestFuzzCase("params array", fuzzExpectReject, map[string]any{"method": mcp.MethodToolsCall, "params": []any{"fuzz"}}),
+		requestFuzzCase("params string", fuzzExpectReject, map[string]any{"method": mcp.MethodToolsCall, "params": "fuzz"}),
+		requestFuzzCase("tools/call without name", fuzzExpectReject, map[string]any{"method": mcp.MethodToolsCall, "params": map[string]any{}}),
+		requestFuzzCase("tools/call numeric name", fuzzExpectReject, map[string]any{"method": mcp.MethodToolsCall, "params": map[string]any{"name": 42}}),
+		requestFuzzCase("tools/call unknown tool", fuzzExpectToolError, map[string]any{"method": mcp.MethodToolsCall, "params": map[string]any{"name": "fuzz-unknown-tool"}}),
+		requestFuzzCase("resources/read numeric uri", fuzzExpectReject, map[string]any{"method": mcp.MethodResourcesRead, "params": map[string]any{"uri": 42}}),
+		requestFuzzCase("null id", fuzzExpectReject, map[string]any{"id": nil, "method": mcp.MethodToolsList}),
+		requestFuzzCase("boolean id", fuzzExpectReject, map[string]any{"id": true, "method": mcp.MethodToolsList}),
+		requestFuzzCase("object id", fuzzExpectReject, map[string]any{"id": map[string]any{"fuzz": 1}, "method": mcp.MethodToolsList}),
+		{name: "missing id", raw: []byte(`{"jsonrpc":"2.0","method":"tools/list"}`), expect: fuzzExpectNoReply},
+		{name: "truncated JSON", raw: []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/li`), expect: fuzzExpectReject},
+		{name: "not JSON", raw: []byte("fuzz " + invalidUTF8), expect: fuzzExpectReject},
+		{name: "JSON string", raw: []byte(`"tools/list"`), expect: fuzzExpectReject},
+		{name: "empty batch", raw: []byte(`[]`), expect: fuzzExpectReject},
+		{name: "empty body", expect: fuzzExpectReject},
+	}
+	for i := range cases {
+		cases[i].target = fuzzTarget
+	}
+	return cases
+}
+
+// requestFuzzCase builds a request from a valid envelope with the fields
+// replaced; a nil value removes the field. The request keeps a valid id
+// unless the fields replace it.
+func requestFuzzCase(name string, expect fuzzExpectation, fields map[string]any) fuzzCase {
+	_, replacesID := fields["id"]
+	fc := fuzzCase{name: name, expect: expect, hasID: !replacesID}
+	fc.message = map[string]any{"jsonrpc": mcp.JSONRPC_VERSION}
+	for key, value := range fields {
+		if value != nil {
+			fc.message[key] = value
+		} else if key != "id" {
+			delete(fc.message, key)
+		}
+	}
+	if replacesID && fields["id"] == nil {
+		fc.message["id"] = nil
+	}
+	return fc
+}
+
+// toolFuzzCases returns the cases that call a tool with arguments derived
+// from its input schema: required arguments missing, arguments of the wrong
+// type, oversized and invalid UTF-8 strings and unknown arguments
+func toolFuzzCases(tool mcp.Tool, stringSize int) []fuzzCase {
+	args := toolArguments(tool)
+	valid := map[string]any{}
+	for _, arg := range args {
+		if arg.Required {
+			valid[arg.Name] = arg.placeholder()
+		}
+	}
+	with := func(name string, value any) map[string]any {
+		arguments := make(map[string]any, len(valid)+1)
+		for key, v := range valid {
+			arguments[key] = v
+		}
+		if value == nil {
+			delete(arguments, name)
+		} else {
+			arguments[name] = value
+		}
+		return arguments
+	}
+
+	var cases []fuzzCase
+	add := func(name string, expect fuzzExpectation, arguments any) {
+		fc := requestFuzzCase(name, expect, map[string]any{
+			"method": mcp.MethodToolsCall,
+			"params": map[string]any{"name": tool.Name, "arguments": arguments},
+		})
+		fc.target = tool.Name
+		cases = append(cases, fc)
+	}
+
+	add("arguments not an object", fuzzExpectToolError, "fuzz")
+	add("unknown argument", fuzzExpectAny, with("fuzz_unknown_argument", "fuzz"))
+	for _, arg := range args {
+		if arg.Required {
+			add("missing required "+arg.Name, fuzzExpectToolError, with(arg.Name, nil))
+		}
+		if wrong := wrongTypeValue(arg.Type); wrong != nil {
+			add("wrong type "+arg.Name, fuzzExpectToolError, with(arg.Name, wrong))
+		}
+		if arg.Type == "string" && len(arg.Enum) == 0 {
+			add("oversized "+arg.Name, fuzzExpectAny, with(arg.Name, strings.Repeat("A", stringSize)))
+			add("invalid UTF-8 "+arg.Name, fuzzExpectAny, with(arg.Name, "fuzz"+invalidUTF8))
+		}
+		if len(arg.Enum) > 0 {
+			add("not in enum "+arg.Name, fuzzExpectToolError, with(arg.Name, "fuzz-not-in-enum"))
+		}
+	}
+	return cases
+}
+
+// wrongTypeValue returns a value that does not match a schema type; nil for
+// untyped arguments
+func wrongTypeValue(schemaType string) any {
+	switch schemaType {
+	case "string":
+		return 12345
+	case "integer", "number":
+		return "not a number"
+	case "boolean":
+		return "true"
+	case "array":
+		return map[string]any{"fuzz": 1}
+	case "object":
+		return []any{"fuzz"}
+	}
+	return nil
+}
+
+// marshalFuzzMessage encodes a message, keeping invalid UTF-8 in strings
+// as raw bytes; encoding/json would replace it with U+FFFD
+func marshalFuzzMessage(message map[string]any) []byte {
+	const marker = "\u0000fuzz-invalid-utf8\u0000"
+	var replace func(any) any
+	replace = func(value any) any {
+		switch v := value.(type) {
+		case string:
+			return strings.ReplaceAll(v, invalidUTF8, marker)
+		case map[string]any:
+			replaced := make(map[string]any, len(v))
+			for key, entry := range v {
+				replaced[key] = replace(entry)
+			}
+			return replaced
+		}
+		return value
+	}
+	data, _ := json.Marshal(replace(message))
+	encodedMarker, _ := json.Marshal(marker)
+	return bytes.ReplaceAll(data, bytes.Trim(encodedMarker, `"`), []byte(invalidUTF8))
+}
+
+// WriteFuzzReport prints the cases that did not pass, followed by a summary
+func WriteFuzzReport(w io.Writer, report *FuzzReport) {
+	counts := map[FuzzOutcome]int{}
+	for _, result := range report.Results {
+		counts[result.Outcome]++
+	}
+
+	if len(report.Results) > counts[FuzzOK] {
+		_, _ = fmt.Fprintf(w, "%-9s %-20s %-32s %s\n", "OUTCOME", "TARGET", "CASE", "DETAIL")
+		for _, result := range report.Results {
+			if result.Outcome != FuzzOK {
+				_, _ = fmt.Fprintf(w, "%-9s %-20s %-32s %s\n", result.Outcome, result.Target, result.Case, result.Detail)
+			}
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+
+	var parts []string
+	for _, outcome := range []FuzzOutcome{FuzzOK, FuzzAccepted, FuzzNonSpec, FuzzHang, FuzzCrash} {
+		if counts[outcome] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[outcome], outcome))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d case(s): %s\n", len(report.Results), strings.Join(parts, ", "))
+	if report.Aborted != "" {
+		_, _ = fmt.Fprintf(w, "Stopped early: %s\n", report.Aborted)
+	}
+}
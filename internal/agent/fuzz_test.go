@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fuzzResult returns the result of the named case
+func fuzzResult(t *testing.T, report *FuzzReport, target, name string) FuzzResult {
+	t.Helper()
+	for _, result := range report.Results {
+		if result.Target == target && result.Case == name {
+			return result
+		}
+	}
+	t.Fatalf("no result for %s: %s", target, name)
+	return FuzzResult{}
+}
+
+// connectFuzzClient connects a client to endpoint
+func connectFuzzClient(t *testing.T, ctx context.Context, endpoint string) *Client {
+	t.Helper()
+	c := NewClient(ClientConfig{
+		Endpoint:             endpoint,
+		Transport:            "streamable-http",
+		Logger:               NewLoggerWithWriter(false, false, false, io.Discard),
+		NoInitialList:        true,
+		HTTPRetryMaxAttempts: 1,
+	})
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestRunFuzz(t *testing.T) {
+	s := server.NewMCPServer("fuzz", "1.0.0", server.WithToolCapabilities(false))
+	s.AddTool(mcp.NewTool("echo",
+		mcp.WithString("message", mcp.Required()),
+		mcp.WithNumber("count"),
+	), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		message, err := req.RequireString("message")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(message), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	c := connectFuzzClient(t, ctx, ts.URL+"/mcp")
+
+	report, err := c.RunFuzz(ctx, FuzzConfig{})
+	if err != nil {
+		t.Fatalf("RunFuzz: %v", err)
+	}
+	for _, result := range report.Results {
+		if result.Target != fuzzTarget {
+			t.Fatalf("tools are only fuzzed when asked to, got %+v", result)
+		}
+	}
+
+	report, err = c.RunFuzz(ctx, FuzzConfig{Tools: []string{"echo"}, StringSize: 4096})
+	if err != nil {
+		t.Fatalf("RunFuzz: %v", err)
+	}
+	if report.Aborted != "" {
+		t.Fatalf("unexpected abort: %s", report.Aborted)
+	}
+	for _, want := range []struct {
+		target, name string
+		outcome      FuzzOutcome
+		detail       string
+	}{
+		{fuzzTarget, "unknown method", FuzzOK, "error -32601"},
+		{fuzzTarget, "truncated JSON", FuzzOK, "rejected with HTTP 400"},
+		{fuzzTarget, "missing id", FuzzOK, "no response"},
+		{"echo", "missing required message", FuzzOK, "tool error"},
+		{"echo", "oversized message", FuzzOK, "result"},
+		{"echo", "wrong type count", FuzzAccepted, "tool returned a successful result"},
+	} {
+		result := fuzzResult(t, report, want.target, want.name)
+		if result.Outcome != want.outcome || !strings.HasPrefix(result.Detail, want.detail) {
+			t.Errorf("%s: %s = %s (%s), want %s (%s)", want.target, want.name, result.Outcome, result.Detail, want.outcome, want.detail)
+		}
+	}
+	if result := fuzzResult(t, report, fuzzTarget, "oversized method"); len([]rune(result.Detail)) > maxFuzzDetailRunes {
+		t.Errorf("detail not shortened: %d runes", len([]rune(result.Detail)))
+	}
+
+	if _, err := c.RunFuzz(ctx, FuzzConfig{Tools: []string{"missing"}}); err == nil || err.Error() != "unknown tool: missing" {
+		t.Errorf("expected an unknown tool error, got %v", err)
+	}
+}
+
+func TestRunFuzzFindings(t *testing.T) {
+	// The server hangs on an unknown method, answers a request with the
+	// wrong jsonrpc version, and drops the connection on a request without
+	// one, after which it only answers with 503
+	var dead atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]any
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || json.Unmarshal(body, &msg) != nil {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if dead.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if _, ok := msg["id"]; !ok {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		response := map[string]any{"jsonrpc": "2.0", "id": msg["id"], "result": map[string]any{}}
+		switch {
+		case msg["method"] == "fuzz/unknown":
+			<-r.Context().Done()
+			return
+		case msg["jsonrpc"] == nil:
+			dead.Store(true)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		case msg["method"] == methodInitialize:
+			response["result"] = map[string]any{
+				"protocolVersion": DefaultProtocolVersion,
+				"capabilities":    map[string]any{},
+				"serverInfo":      map[string]any{"name": "fragile", "version": "1.0.0"},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	c := connectFuzzClient(t, ctx, ts.URL+"/mcp")
+
+	report, err := c.RunFuzz(ctx, FuzzConfig{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("RunFuzz: %v", err)
+	}
+	want := []FuzzOutcome{FuzzHang, FuzzNonSpec, FuzzCrash}
+	if len(report.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(report.Results), len(want), report.Results)
+	}
+	for i, result := range report.Results {
+		if result.Outcome != want[i] {
+			t.Errorf("%s = %s (%s), want %s", result.Case, result.Outcome, result.Detail, want[i])
+		}
+	}
+	if report.Findings() != 3 || report.Aborted != `server stopped answering after "missing jsonrpc"` {
+		t.Errorf("unexpected findings %d and abort %q", report.Findings(), report.Aborted)
+	}
+
+	var out bytes.Buffer
+	WriteFuzzReport(&out, report)
+	for _, want := range []string{
+		"hang      protocol             unknown method                   no response within 200ms",
+		"non-spec  protocol             wrong jsonrpc version            returned a result instead of an error",
+		"crash     protocol             missing jsonrpc ",
+		"3 case(s): 1 non-spec, 1 hang, 1 crash\n",
+		"Stopped early: server stopped answering after \"missing jsonrpc\"",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ServerInventory is everything a server offers: its identity and its tools,
+// resources, resource templates and prompts
+type ServerInventory struct {
+	Server    ServerInfo             `json:"server"`
+	Tools     []mcp.Tool             `json:"tools"`
+	Resources []mcp.Resource         `json:"resources"`
+	Templates []mcp.ResourceTemplate `json:"templates"`
+	Prompts   []mcp.Prompt           `json:"prompts"`
+}
+
+// Inventory lists the tools, resources, resource templates and prompts of
+// the connected server. Lists the server does not support are empty.
+func (c *Client) Inventory(ctx context.Context) (*ServerInventory, error) {
+	inventory := &ServerInventory{Server: c.ServerInfo()}
+	for _, kind := range ListKinds {
+		list, err := c.ListCapabilities(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		switch entries := list.(type) {
+		case []mcp.Tool:
+			inventory.Tools = entries
+		case []mcp.Resource:
+			inventory.Resources = entries
+		case []mcp.ResourceTemplate:
+			inventory.Templates = entries
+		case []mcp.Prompt:
+			inventory.Prompts = entries
+		}
+	}
+	return inventory, nil
+}
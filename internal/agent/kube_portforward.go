@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// portForwardReadyTimeout bounds the wait for kubectl to start forwarding
+const portForwardReadyTimeout = 30 * time.Second
+
+// forwardingLine matches the line kubectl port-forward prints once the
+// local port listens, e.g. "Forwarding from 127.0.0.1:54321 -> 8080"
+var forwardingLine = regexp.MustCompile(`^Forwarding from (127\.0\.0\.1:\d+) -> (\d+)`)
+
+// KubePortForwardConfig selects the Kubernetes service an MCP server runs
+// behind. The port-forward is run with the kubectl binary rather than
+// client-go, so kubectl reads the kubeconfig and authenticates to the
+// cluster with any credential plugin it supports.
+type KubePortForwardConfig struct {
+	// Context is the kubeconfig context; empty uses the current one
+	Context string
+	// Namespace of the service; empty uses the context's namespace
+	Namespace string
+	// Service is the name of the service to forward to
+	Service string
+	// Port is the service port; 0 uses the service's first port
+	Port int
+	// Kubectl is the kubectl binary; empty looks it up in PATH
+	Kubectl string
+}
+
+// PortForward is a running port-forward from a local port to a service
+type PortForward struct {
+	// Addr is the local address forwarded to the service
+	Addr string
+
+	cmd    *exec.Cmd
+	logger *Logger
+	done   chan struct{}
+
+	// mu guards started and closing, which tell an unexpected end of
+	// kubectl from Close
+	mu      sync.Mutex
+	started bool
+	closing bool
+}
+
+// StartPortForward forwards a free local port to the service and returns
+// once the port listens. Close stops the forward.
+func StartPortForward(ctx context.Context, cfg KubePortForwardConfig, logger *Logger) (*PortForward, error) {
+	if cfg.Service == "" {
+		return nil, errors.New("no Kubernetes service to forward to")
+	}
+	kubectl := cfg.Kubectl
+	if kubectl == "" {
+		path, err := exec.LookPath("kubectl")
+		if err != nil {
+			return nil, fmt.Errorf("port-forwarding to a Kubernetes service requires kubectl: %w", err)
+		}
+		kubectl = path
+	}
+
+	var args []string
+	if cfg.Context != "" {
+		args = append(args, "--context", cfg.Context)
+	}
+	if cfg.Namespace != "" {
+		args = append(args, "--namespace", cfg.Namespace)
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		var err error
+		if port, err = servicePort(ctx, kubectl, args, cfg.Service); err != nil {
+			return nil, err
+		}
+	}
+
+	target := "service/" + cfg.Service
+	args = append(args, "port-forward", target, fmt.Sprintf(":%d", port), "--address", "127.0.0.1")
+	cmd := exec.Command(kubectl, args...) //nolint:gosec // G204: kubectl with arguments from the command line
+	// Wait finishes copying the output before it returns, unlike with
+	// StdoutPipe
+	stdout, stdoutWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	logger.Info("Port-forwarding to %s port %d%s...", target, port, describeKubeContext(cfg))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start kubectl port-forward: %w", err)
+	}
+
+	pf := &PortForward{cmd: cmd, logger: logger, done: make(chan struct{})}
+	ready := make(chan string, 1)
+	go pf.readOutput(stdout, ready)
+	go func() {
+		err := cmd.Wait()
+		_ = stdoutWriter.Close()
+		pf.mu.Lock()
+		unexpected := pf.started && !pf.closing
+		pf.mu.Unlock()
+		if unexpected {
+			logger.Error("Port-forward to %s ended, requests to the server will fail: %v %s", target, err, strings.TrimSpace(stderr.String()))
+		}
+		close(pf.done)
+	}()
+
+	select {
+	case addr := <-ready:
+		pf.mu.Lock()
+		pf.started = true
+		pf.mu.Unlock()
+		pf.Addr = addr
+		logger.Info("Forwarding %s to %s port %d", addr, target, port)
+		return pf, nil
+	case <-pf.done:
+		return nil, fmt.Errorf("kubectl port-forward to %s failed: %s", target, orNone(strings.TrimSpace(stderr.String())))
+	case <-time.After(portForwardReadyTimeout):
+		_ = pf.Close()
+		return nil, fmt.Errorf("kubectl port-forward to %s did not start within %s", target, portForwardReadyTimeout)
+	case <-ctx.Done():
+		_ = pf.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// readOutput reports the local address kubectl listens on and logs the
+// connections it forwards
+func (p *PortForward) readOutput(stdout io.Reader, ready chan<- string) {
+	scanner := bufio.NewScanner(stdout)
+	reported := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := forwardingLine.FindStringSubmatch(line); match != nil && !reported {
+			reported = true
+			ready <- match[1]
+			continue
+		}
+		p.logger.Debug("kubectl: %s", line)
+	}
+}
+
+// Close stops the port-forward
+func (p *PortForward) Close() error {
+	p.mu.Lock()
+	p.closing = true
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-p.done
+	return nil
+}
+
+// servicePort looks up the first port of the service
+func servicePort(ctx context.Context, kubectl string, args []string, service string) (int, error) {
+	args = append(args, "get", "service", service, "--output", "jsonpath={.spec.ports[0].port}")
+	cmd := exec.CommandContext(ctx, kubectl, args...) //nolint:gosec // G204: kubectl with arguments from the command line
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up service %s: %v %s", service, err, strings.TrimSpace(stderr.String()))
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("service %s has no port; set it explicitly", service)
+	}
+	return port, nil
+}
+
+// describeKubeContext names the context and namespace for the log
+func describeKubeContext(cfg KubePortForwardConfig) string {
+	var parts []string
+	if cfg.Namespace != "" {
+		parts = append(parts, "namespace "+cfg.Namespace)
+	}
+	if cfg.Context != "" {
+		parts = append(parts, "context "+cfg.Context)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// ForwardedEndpoint points endpoint at the local address of a forward,
+// keeping its scheme and path. https endpoints are rejected: the server's
+// certificate would be verified against the forwarded address instead of
+// its host name.
+func ForwardedEndpoint(endpoint, addr string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	if u.Scheme == "https" {
+		return "", fmt.Errorf("cannot port-forward to %s: its certificate would be checked against %s; use an http endpoint, or --ssh to keep the host name", endpoint, addr)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", fmt.Errorf("invalid forwarded address %q: %w", addr, err)
+	}
+	u.Host = addr
+	return u.String(), nil
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeKubectl writes a kubectl stand-in that records its arguments and
+// behaves like port-forward, or fails with message if it is not empty
+func fakeKubectl(t *testing.T, message string) (kubectl, argsFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake kubectl is a shell script")
+	}
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	script := `#!/bin/sh
+echo "$@" >> ` + argsFile + `
+case "$*" in
+*"get service"*) echo 8080 ;;
+*port-forward*)
+	if [ -n "` + message + `" ]; then echo "` + message + `" >&2; exit 1; fi
+	echo "Forwarding from 127.0.0.1:54321 -> 8080"
+	echo "Forwarding from [::1]:54321 -> 8080"
+	exec sleep 60 ;;
+esac
+`
+	kubectl = filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(kubectl, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return kubectl, argsFile
+}
+
+func TestStartPortForward(t *testing.T) {
+	kubectl, argsFile := fakeKubectl(t, "")
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	pf, err := StartPortForward(context.Background(), KubePortForwardConfig{
+		Context:   "prod",
+		Namespace: "mcp",
+		Service:   "mcp-server",
+		Kubectl:   kubectl,
+	}, logger)
+	if err != nil {
+		t.Fatalf("StartPortForward: %v", err)
+	}
+	if pf.Addr != "127.0.0.1:54321" {
+		t.Errorf("Addr = %s, want 127.0.0.1:54321", pf.Addr)
+	}
+	if err := pf.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		"--context prod --namespace mcp get service mcp-server --output jsonpath={.spec.ports[0].port}",
+		"--context prod --namespace mcp port-forward service/mcp-server :8080 --address 127.0.0.1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("kubectl calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStartPortForwardFailure(t *testing.T) {
+	kubectl, _ := fakeKubectl(t, "error: services missing not found")
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	_, err := StartPortForward(context.Background(), KubePortForwardConfig{Service: "missing", Port: 80, Kubectl: kubectl}, logger)
+	if err == nil || !strings.Contains(err.Error(), "services missing not found") {
+		t.Errorf("expected kubectl's error, got %v", err)
+	}
+}
+
+func TestForwardedEndpoint(t *testing.T) {
+	got, err := ForwardedEndpoint("http://mcp.example.com/api/mcp?debug=1", "127.0.0.1:54321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "http://127.0.0.1:54321/api/mcp?debug=1" {
+		t.Errorf("ForwardedEndpoint = %s", got)
+	}
+	if _, err := ForwardedEndpoint("https://mcp.example.com/api/mcp", "127.0.0.1:54321"); err == nil {
+		t.Error("expected an https endpoint to be rejected")
+	}
+	if _, err := ForwardedEndpoint("not a url", "127.0.0.1:54321"); err == nil {
+		t.Error("expected an invalid endpoint to be rejected")
+	}
+}
@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // ANSI color codes
@@ -20,15 +25,55 @@ const (
 
 // Logger provides formatted logging for the agent
 type Logger struct {
-	verbose     bool
+	level       LogLevel
 	useColor    bool
 	jsonRPCMode bool
 	writer      io.Writer
+
+	// redactor masks credentials in everything written; nil disables it
+	redactor *Redactor
+
+	// tee receives every message as well, filtered by its own level, e.g.
+	// to write a log file at a different verbosity than the terminal
+	tee *Logger
+
+	// prefix labels every line, e.g. with a connection name
+	prefix string
+
+	// format selects text or JSON lines
+	format OutputFormat
+
+	// payload controls the cost of JSON-RPC payload logging
+	payload       PayloadLogOptions
+	payloadSeqNum atomic.Uint64
 }
 
-// SetVerbose sets the verbose mode
+// PayloadLogOptions trades completeness of JSON-RPC payload logging for
+// lower overhead, so that heavy sessions are not dominated by serialization
+type PayloadLogOptions struct {
+	// Compact renders payloads as single-line JSON instead of indented JSON
+	Compact bool
+	// MaxBytes truncates payloads whose compact JSON is longer than this;
+	// zero disables truncation
+	MaxBytes int
+	// SampleRate logs the payload of only every Nth message; payloads of the
+	// others are never serialized. Values <= 1 log every payload.
+	SampleRate int
+}
+
+// SetPayloadOptions configures the JSON-RPC payload logging fast path
+func (l *Logger) SetPayloadOptions(opts PayloadLogOptions) {
+	l.payload = opts
+}
+
+// SetVerbose switches between the debug and info levels
 func (l *Logger) SetVerbose(verbose bool) {
-	l.verbose = verbose
+	switch {
+	case verbose && l.level > LevelDebug:
+		l.level = LevelDebug
+	case !verbose && l.level < LevelInfo:
+		l.level = LevelInfo
+	}
 }
 
 // SetWriter sets a custom writer for the logger
@@ -39,20 +84,41 @@ func (l *Logger) SetWriter(w io.Writer) {
 // NewLogger creates a new logger
 func NewLogger(verbose, useColor, jsonRPCMode bool) *Logger {
 	return &Logger{
-		verbose:     verbose,
+		level:       verboseLevel(verbose),
 		useColor:    useColor,
 		jsonRPCMode: jsonRPCMode,
 		writer:      os.Stdout, // Default to stdout
+		redactor:    defaultRedactor,
 	}
 }
 
+// WithPrefix returns a logger with the same settings and writer that labels
+// every line with prefix
+func (l *Logger) WithPrefix(prefix string) *Logger {
+	prefixed := &Logger{
+		level:       l.level,
+		useColor:    l.useColor,
+		jsonRPCMode: l.jsonRPCMode,
+		writer:      l.writer,
+		redactor:    l.redactor,
+		prefix:      prefix,
+		format:      l.format,
+		payload:     l.payload,
+	}
+	if l.tee != nil {
+		prefixed.tee = l.tee.WithPrefix(prefix)
+	}
+	return prefixed
+}
+
 // NewLoggerWithWriter creates a new logger with a custom writer
 func NewLoggerWithWriter(verbose, useColor, jsonRPCMode bool, writer io.Writer) *Logger {
 	return &Logger{
-		verbose:     verbose,
+		level:       verboseLevel(verbose),
 		useColor:    useColor,
 		jsonRPCMode: jsonRPCMode,
 		writer:      writer,
+		redactor:    defaultRedactor,
 	}
 }
 
@@ -61,6 +127,15 @@ func (l *Logger) timestamp() string {
 	return time.Now().Format("2006-01-02 15:04:05")
 }
 
+// linePrefix returns the bracketed timestamp, followed by the connection name
+// for loggers created with WithPrefix
+func (l *Logger) linePrefix() string {
+	if l.prefix == "" {
+		return "[" + l.timestamp() + "]"
+	}
+	return "[" + l.timestamp() + "] [" + l.prefix + "]"
+}
+
 // colorize applies color to text if colors are enabled
 func (l *Logger) colorize(text, colorCode string) string {
 	if !l.useColor {
@@ -69,186 +144,598 @@ func (l *Logger) colorize(text, colorCode string) string {
 	return fmt.Sprintf("%s%s%s", colorCode, text, colorReset)
 }
 
+// logLine writes a message at the given level, in color for text output
+func (l *Logger) logLine(level, color, msg string) {
+	msg = l.redact(msg)
+	if l.jsonOutput() {
+		l.writeJSONEntry(logEntry{Level: level, Type: entryLog, Message: msg})
+		return
+	}
+	if color != "" {
+		msg = l.colorize(msg, color)
+	}
+	_, _ = fmt.Fprintf(l.writer, "%s %s\n", l.linePrefix(), msg)
+}
+
 // Info logs an informational message
 func (l *Logger) Info(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s\n", l.timestamp(), msg)
+	l.each(func(l *Logger) { l.info(format, args...) })
 }
 
 // Debug logs a debug message (only in verbose mode)
 func (l *Logger) Debug(format string, args ...interface{}) {
-	if !l.verbose {
-		return
-	}
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s\n", l.timestamp(), l.colorize(msg, colorGray))
+	l.each(func(l *Logger) { l.debug(format, args...) })
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s\n", l.timestamp(), l.colorize(msg, colorRed))
+	l.each(func(l *Logger) { l.log(LevelError, "error", colorRed, format, args...) })
 }
 
 // Success logs a success message
 func (l *Logger) Success(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s\n", l.timestamp(), l.colorize(msg, colorGreen))
+	l.each(func(l *Logger) { l.success(format, args...) })
 }
 
 // Warning logs a warning message with yellow highlighting
 func (l *Logger) Warning(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s\n", l.timestamp(), l.colorize(msg, colorYellow))
+	l.each(func(l *Logger) { l.log(LevelWarn, "warning", colorYellow, format, args...) })
 }
 
 // InfoVerbose logs an informational message only in verbose mode
 func (l *Logger) InfoVerbose(format string, args ...interface{}) {
-	if l != nil && l.verbose {
-		l.Info(format, args...)
+	if l == nil {
+		return
 	}
+	l.each(func(l *Logger) {
+		if l.isVerbose() {
+			l.info(format, args...)
+		}
+	})
 }
 
 // WarningVerbose logs a warning message only in verbose mode
 func (l *Logger) WarningVerbose(format string, args ...interface{}) {
-	if l != nil && l.verbose {
-		l.Warning(format, args...)
+	if l == nil {
+		return
+	}
+	l.each(func(l *Logger) {
+		if l.isVerbose() {
+			l.log(LevelWarn, "warning", colorYellow, format, args...)
+		}
+	})
+}
+
+// info, debug and success log a message to this logger only, not its tee
+func (l *Logger) info(format string, args ...interface{}) {
+	l.log(LevelInfo, "info", "", format, args...)
+}
+
+func (l *Logger) debug(format string, args ...interface{}) {
+	l.log(LevelDebug, "debug", colorGray, format, args...)
+}
+
+func (l *Logger) success(format string, args ...interface{}) {
+	l.log(LevelInfo, "success", colorGreen, format, args...)
+}
+
+// log formats and writes a message if level is enabled
+func (l *Logger) log(level LogLevel, name, color, format string, args ...interface{}) {
+	if !l.enabled(level) {
+		return
 	}
+	l.logLine(name, color, fmt.Sprintf(format, args...))
+}
+
+// Correlation links a logged message to the request it belongs to
+type Correlation struct {
+	// ID is the short correlation ID of the request
+	ID string
+	// Method is the method of the request; empty when logging the request
+	// itself
+	Method string
+	// Elapsed is the time since the request was sent
+	Elapsed time.Duration
+}
+
+// suffix renders the correlation at the end of a log line, e.g.
+// " [#3fa9c2 tools/call 120ms]"; empty without an ID
+func (c Correlation) suffix() string {
+	if c.ID == "" {
+		return ""
+	}
+	parts := []string{"#" + c.ID}
+	if c.Method != "" {
+		parts = append(parts, c.Method)
+	}
+	if c.Elapsed > 0 {
+		parts = append(parts, formatElapsed(c.Elapsed))
+	}
+	return " [" + strings.Join(parts, " ") + "]"
+}
+
+// formatElapsed rounds a duration for display
+func formatElapsed(d time.Duration) string {
+	if d >= time.Millisecond {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// correlated returns info, success and debug loggers that end each message
+// with the correlation
+func (l *Logger) correlated(corr Correlation) (info, success, debug func(string, ...interface{})) {
+	sfx := corr.suffix()
+	info = func(format string, args ...interface{}) { l.info("%s%s", fmt.Sprintf(format, args...), sfx) }
+	success = func(format string, args ...interface{}) { l.success("%s%s", fmt.Sprintf(format, args...), sfx) }
+	debug = func(format string, args ...interface{}) { l.debug("%s%s", fmt.Sprintf(format, args...), sfx) }
+	return info, success, debug
 }
 
 // Request logs an outgoing request
 func (l *Logger) Request(method string, params interface{}) {
-	if !l.jsonRPCMode {
+	l.CorrelatedRequest(Correlation{}, method, params)
+}
+
+// CorrelatedRequest logs an outgoing request with its correlation ID
+func (l *Logger) CorrelatedRequest(corr Correlation, method string, params interface{}) {
+	l.each(func(l *Logger) { l.request(corr, method, params) })
+}
+
+func (l *Logger) request(corr Correlation, method string, params interface{}) {
+	info, _, debug := l.correlated(corr)
+	if l.jsonOutput() {
+		l.writeJSONMessage(entryRequest, directionOutgoing, method, params, corr)
+		return
+	}
+	if !l.fullMessages() {
 		// Simple mode - just log what we're doing
 		switch method {
 		case methodInitialize:
-			l.Info("Initializing MCP session...")
+			info("Initializing MCP session...")
 		case "tools/list":
-			l.Info("Listing available tools...")
+			info("Listing available tools...")
 		case "resources/list":
-			l.Info("Listing available resources...")
+			info("Listing available resources...")
 		case "prompts/list":
-			l.Info("Listing available prompts...")
+			info("Listing available prompts...")
+		case methodPing:
+			// Keepalive pings are frequent; only show them in verbose mode
+			debug("Sending ping...")
 		default:
-			l.Info("Sending request: %s", method)
+			info("Sending request: %s", method)
 		}
 		return
 	}
 
+	if !l.enabled(LevelInfo) {
+		return
+	}
+
 	// JSON-RPC mode - full protocol logging
 	arrow := l.colorize("→", colorBlue)
 	methodStr := l.colorize(fmt.Sprintf("REQUEST (%s)", method), colorBlue)
 
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s %s:\n", l.timestamp(), arrow, methodStr)
+	_, _ = fmt.Fprintf(l.writer, "%s %s %s%s:\n", l.linePrefix(), arrow, methodStr, corr.suffix())
 
 	// Pretty print the params
 	if params != nil {
-		jsonStr := l.prettyJSON(params)
-		_, _ = fmt.Fprintln(l.writer, l.colorize(jsonStr, colorBlue))
+		l.writePayload(params, colorBlue)
 	}
 	_, _ = fmt.Fprintln(l.writer)
 }
 
 // Response logs an incoming response
 func (l *Logger) Response(method string, result interface{}) {
-	if !l.jsonRPCMode {
+	l.CorrelatedResponse(Correlation{}, method, result)
+}
+
+// CorrelatedResponse logs an incoming response with the request it answers
+func (l *Logger) CorrelatedResponse(corr Correlation, method string, result interface{}) {
+	l.each(func(l *Logger) { l.response(corr, method, result) })
+}
+
+func (l *Logger) response(corr Correlation, method string, result interface{}) {
+	_, success, debug := l.correlated(corr)
+	if l.jsonOutput() {
+		l.writeJSONMessage(entryResponse, directionIncoming, method, result, corr)
+		return
+	}
+	if !l.fullMessages() {
 		// Simple mode - log meaningful information
 		switch method {
 		case methodInitialize:
 			// Extract protocol version if possible
 			if initResult, ok := result.(map[string]interface{}); ok {
 				if protocolVersion, exists := initResult["protocolVersion"]; exists {
-					l.Success("Session initialized successfully (protocol: %v)", protocolVersion)
+					success("Session initialized successfully (protocol: %v)", protocolVersion)
 				} else {
-					l.Success("Session initialized successfully")
+					success("Session initialized successfully")
 				}
 			} else {
-				l.Success("Session initialized successfully")
+				success("Session initialized successfully")
 			}
 		case "tools/list":
 			// Try to count tools
 			toolCount := l.countTools(result)
 			if toolCount >= 0 {
-				l.Success("Found %d tools", toolCount)
+				success("Found %d tools", toolCount)
 			} else {
-				l.Success("Retrieved tool list")
+				success("Retrieved tool list")
 			}
 		case "resources/list":
 			// Try to count resources
 			resourceCount := l.countResources(result)
 			if resourceCount >= 0 {
-				l.Success("Found %d resources", resourceCount)
+				success("Found %d resources", resourceCount)
 			} else {
-				l.Success("Retrieved resource list")
+				success("Retrieved resource list")
 			}
 		case "prompts/list":
 			// Try to count prompts
 			promptCount := l.countPrompts(result)
 			if promptCount >= 0 {
-				l.Success("Found %d prompts", promptCount)
+				success("Found %d prompts", promptCount)
 			} else {
-				l.Success("Retrieved prompt list")
+				success("Retrieved prompt list")
 			}
+		case methodPing:
+			debug("Received pong")
 		default:
-			l.Success("Received response for: %s", method)
+			success("Received response for: %s", method)
 		}
 		return
 	}
 
+	if !l.enabled(LevelInfo) {
+		return
+	}
+
 	// JSON-RPC mode - full protocol logging
 	arrow := l.colorize("←", colorGreen)
 	methodStr := l.colorize(fmt.Sprintf("RESPONSE (%s)", method), colorGreen)
 
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s %s:\n", l.timestamp(), arrow, methodStr)
+	_, _ = fmt.Fprintf(l.writer, "%s %s %s%s:\n", l.linePrefix(), arrow, methodStr, corr.suffix())
 
 	// Pretty print the result
 	if result != nil {
-		jsonStr := l.prettyJSON(result)
-		_, _ = fmt.Fprintln(l.writer, l.colorize(jsonStr, colorGreen))
+		l.writePayload(result, colorGreen)
 	}
 	_, _ = fmt.Fprintln(l.writer)
 }
 
 // Notification logs an incoming notification
 func (l *Logger) Notification(method string, params interface{}) {
+	l.CorrelatedNotification(Correlation{}, method, params)
+}
+
+// CorrelatedNotification logs an incoming notification with the request it
+// relates to, such as the tool call a progress notification reports on
+func (l *Logger) CorrelatedNotification(corr Correlation, method string, params interface{}) {
+	l.each(func(l *Logger) { l.notification(corr, method, params) })
+}
+
+func (l *Logger) notification(corr Correlation, method string, params interface{}) {
+	info, _, debug := l.correlated(corr)
 	// Skip keepalive notifications unless in verbose mode
-	if method == "$/keepalive" && !l.verbose {
+	if method == "$/keepalive" && !l.isVerbose() {
 		return
 	}
 
-	if !l.jsonRPCMode {
+	if l.jsonOutput() {
+		l.writeJSONMessage(entryNotification, directionIncoming, method, params, corr)
+		return
+	}
+
+	if !l.fullMessages() {
 		// Simple mode - just log the notification type
 		switch method {
 		case notificationToolsListChanged:
-			l.Info("Tools list changed! Fetching updated list...")
+			info("Tools list changed! Fetching updated list...")
 		case notificationResourcesListChanged:
-			l.Info("Resources list changed! Fetching updated list...")
+			info("Resources list changed! Fetching updated list...")
 		case notificationPromptsListChanged:
-			l.Info("Prompts list changed! Fetching updated list...")
+			info("Prompts list changed! Fetching updated list...")
+		case notificationMessage:
+			// Rendered by ServerLog
 		default:
-			if l.verbose {
-				l.Debug("Received notification: %s", method)
-			}
+			debug("Received notification: %s", method)
 		}
 		return
 	}
 
+	if !l.enabled(LevelInfo) {
+		return
+	}
+
 	// JSON-RPC mode - full protocol logging
 	arrow := l.colorize("←", colorYellow)
 	methodStr := l.colorize(fmt.Sprintf("NOTIFICATION (%s)", method), colorYellow)
 
-	_, _ = fmt.Fprintf(l.writer, "[%s] %s %s:\n", l.timestamp(), arrow, methodStr)
+	_, _ = fmt.Fprintf(l.writer, "%s %s %s%s:\n", l.linePrefix(), arrow, methodStr, corr.suffix())
 
 	// Pretty print the params
 	if params != nil {
-		jsonStr := l.prettyJSON(params)
-		_, _ = fmt.Fprintln(l.writer, l.colorize(jsonStr, colorYellow))
+		l.writePayload(params, colorYellow)
+	}
+	_, _ = fmt.Fprintln(l.writer)
+}
+
+// ServerRequest logs a request sent by the server to the client, such as
+// sampling/createMessage
+func (l *Logger) ServerRequest(method string, params interface{}) {
+	l.each(func(l *Logger) { l.serverRequest(method, params) })
+}
+
+func (l *Logger) serverRequest(method string, params interface{}) {
+	if l.jsonOutput() {
+		l.writeJSONMessage(entryRequest, directionIncoming, method, params, Correlation{})
+		return
+	}
+	if !l.fullMessages() {
+		l.info("Received server request: %s", method)
+		return
+	}
+
+	if !l.enabled(LevelInfo) {
+		return
+	}
+
+	arrow := l.colorize("←", colorBlue)
+	methodStr := l.colorize(fmt.Sprintf("SERVER REQUEST (%s)", method), colorBlue)
+
+	_, _ = fmt.Fprintf(l.writer, "%s %s %s:\n", l.linePrefix(), arrow, methodStr)
+	if params != nil {
+		l.writePayload(params, colorBlue)
+	}
+	_, _ = fmt.Fprintln(l.writer)
+}
+
+// ServerRequestReply logs the client's answer to a server request
+func (l *Logger) ServerRequestReply(method string, result interface{}) {
+	l.each(func(l *Logger) { l.serverRequestReply(method, result) })
+}
+
+func (l *Logger) serverRequestReply(method string, result interface{}) {
+	if l.jsonOutput() {
+		l.writeJSONMessage(entryResponse, directionOutgoing, method, result, Correlation{})
+		return
+	}
+	if !l.fullMessages() {
+		l.success("Answered server request: %s", method)
+		return
+	}
+
+	if !l.enabled(LevelInfo) {
+		return
+	}
+
+	arrow := l.colorize("→", colorGreen)
+	methodStr := l.colorize(fmt.Sprintf("REPLY (%s)", method), colorGreen)
+
+	_, _ = fmt.Fprintf(l.writer, "%s %s %s:\n", l.linePrefix(), arrow, methodStr)
+	if result != nil {
+		l.writePayload(result, colorGreen)
 	}
 	_, _ = fmt.Fprintln(l.writer)
 }
 
-// prettyJSON formats JSON for display
-func (l *Logger) prettyJSON(v interface{}) string {
+// ServerLog logs a message received from the server via notifications/message,
+// colored by its severity
+func (l *Logger) ServerLog(level, loggerName, message string) {
+	l.each(func(l *Logger) { l.serverLog(level, loggerName, message) })
+}
+
+func (l *Logger) serverLog(level, loggerName, message string) {
+	if level == "" {
+		level = "info"
+	}
+	if !l.enabled(serverLogLevel(level)) {
+		return
+	}
+	message = l.redact(message)
+	if l.jsonOutput() {
+		l.writeJSONEntry(logEntry{Level: level, Type: entryServerLog, Logger: loggerName, Message: message})
+		return
+	}
+
+	var color string
+	switch level {
+	case "debug":
+		color = colorGray
+	case "info", "notice":
+		color = ""
+	case "warning":
+		color = colorYellow
+	default:
+		// error, critical, alert, emergency and unknown levels
+		color = colorRed
+	}
+
+	prefix := fmt.Sprintf("[server:%s]", strings.ToUpper(level))
+	if loggerName != "" {
+		prefix = fmt.Sprintf("%s (%s)", prefix, loggerName)
+	}
+
+	line := fmt.Sprintf("%s %s", prefix, message)
+	if color != "" {
+		line = l.colorize(line, color)
+	}
+	_, _ = fmt.Fprintf(l.writer, "%s %s\n", l.linePrefix(), line)
+}
+
+// RateLimit logs a rate limit observation on its own channel: lines tagged
+// [rate-limit] in text output and entries of type "rate-limit" in JSON output
+func (l *Logger) RateLimit(level LogLevel, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.each(func(l *Logger) { l.rateLimit(level, fmt.Sprintf(format, args...)) })
+}
+
+func (l *Logger) rateLimit(level LogLevel, message string) {
+	if !l.enabled(level) {
+		return
+	}
+	message = l.redact(message)
+	name := "info"
+	switch {
+	case level >= LevelWarn:
+		name = "warning"
+	case level <= LevelDebug:
+		name = "debug"
+	}
+	if l.jsonOutput() {
+		l.writeJSONEntry(logEntry{Level: name, Type: entryRateLimit, Message: message})
+		return
+	}
+
+	line := "[rate-limit] " + message
+	if level >= LevelWarn {
+		line = l.colorize(line, colorYellow)
+	}
+	_, _ = fmt.Fprintf(l.writer, "%s %s\n", l.linePrefix(), line)
+}
+
+// writePayload renders a JSON-RPC payload, honoring the sampling, compact
+// and truncation settings. Unsampled payloads are skipped before any
+// serialization takes place; of truncated payloads only the part that is
+// written is redacted and indented.
+func (l *Logger) writePayload(v interface{}, color string) {
+	if rate := l.payload.SampleRate; rate > 1 {
+		if (l.payloadSeqNum.Add(1)-1)%uint64(rate) != 0 {
+			_, _ = fmt.Fprintln(l.writer, l.colorize("(payload not sampled)", colorGray))
+			return
+		}
+	}
+
+	raw, err := payloadJSON(v)
+	if err != nil {
+		text := l.redact(fmt.Sprintf("%+v", v))
+		if limit := l.payload.MaxBytes; limit > 0 && len(text) > limit {
+			text = fmt.Sprintf("%s… (truncated, %d bytes total)", truncateUTF8(text, limit), len(text))
+		}
+		_, _ = fmt.Fprintln(l.writer, l.colorize(text, color))
+		return
+	}
+
+	kept, truncated := l.redactedPayload(raw)
+	if !l.payload.Compact {
+		kept = indentJSON(kept)
+	}
+	if truncated {
+		kept = fmt.Sprintf("%s… (truncated, %d bytes total)", kept, len(raw))
+	}
+	_, _ = fmt.Fprintln(l.writer, l.colorize(kept, color))
+}
+
+// redactedPayload returns the redacted compact JSON of a payload, cut to
+// MaxBytes without splitting a character. Only the kept part is redacted, but
+// a string value reaching past the limit is redacted as a whole so no part of
+// a secret is logged.
+func (l *Logger) redactedPayload(raw []byte) (string, bool) {
+	limit := l.payload.MaxBytes
+	if limit <= 0 || len(raw) <= limit {
+		return l.redact(string(raw)), false
+	}
+	kept := l.redact(string(raw[:jsonCutPoint(raw, limit)]))
+	return truncateUTF8(kept, limit), true
+}
+
+// jsonCutPoint returns n, or the end of the JSON string that byte n falls
+// into
+func jsonCutPoint(raw []byte, n int) int {
+	inString, escaped := false, false
+	for i, c := range raw {
+		if i >= n && !inString {
+			return i
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		}
+	}
+	return len(raw)
+}
+
+// truncateUTF8 cuts s to at most n bytes, moving the cut back to the start
+// of a character so no multi-byte character is split
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// indentJSON indents compact JSON like json.Indent with two spaces. Unlike
+// json.Indent it accepts JSON that was cut off.
+func indentJSON(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/4)
+	depth := 0
+	newline := func() {
+		b.WriteByte('\n')
+		for range depth {
+			b.WriteString("  ")
+		}
+	}
+
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			b.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			b.WriteByte(c)
+		case '{', '[':
+			b.WriteByte(c)
+			// Empty objects and arrays stay on one line
+			if i+1 < len(s) && (s[i+1] == '}' || s[i+1] == ']') {
+				b.WriteByte(s[i+1])
+				i++
+				continue
+			}
+			depth++
+			newline()
+		case '}', ']':
+			depth--
+			newline()
+			b.WriteByte(c)
+		case ',':
+			b.WriteByte(c)
+			newline()
+		case ':':
+			b.WriteString(": ")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// payloadJSON returns the compact JSON of a payload, wrapped in a JSON-RPC
+// message structure unless it already is a map
+func payloadJSON(v interface{}) ([]byte, error) {
 	// Create a wrapper that includes the full JSON-RPC structure if needed
 	wrapper := make(map[string]interface{})
 
@@ -278,15 +765,12 @@ func (l *Logger) prettyJSON(v interface{}) string {
 		}
 	}
 
-	b, err := json.MarshalIndent(wrapper, "", "  ")
+	b, err := json.Marshal(wrapper)
 	if err != nil {
 		// Fallback to direct marshaling
-		b, err = json.MarshalIndent(v, "", "  ")
-		if err != nil {
-			return fmt.Sprintf("%+v", v)
-		}
+		return json.Marshal(v)
 	}
-	return string(b)
+	return b, nil
 }
 
 // Write implements io.Writer for compatibility
@@ -299,6 +783,8 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 func (l *Logger) countTools(result interface{}) int {
 	// Try to extract tools array from various response structures
 	switch v := result.(type) {
+	case *mcp.ListToolsResult:
+		return len(v.Tools)
 	case map[string]interface{}:
 		if tools, ok := v["tools"]; ok {
 			if toolsArray, ok := tools.([]interface{}); ok {
@@ -326,6 +812,8 @@ func (l *Logger) countTools(result interface{}) int {
 func (l *Logger) countResources(result interface{}) int {
 	// Try to extract resources array from various response structures
 	switch v := result.(type) {
+	case *mcp.ListResourcesResult:
+		return len(v.Resources)
 	case map[string]interface{}:
 		if resources, ok := v["resources"]; ok {
 			if resourcesArray, ok := resources.([]interface{}); ok {
@@ -353,6 +841,8 @@ func (l *Logger) countResources(result interface{}) int {
 func (l *Logger) countPrompts(result interface{}) int {
 	// Try to extract prompts array from various response structures
 	switch v := result.(type) {
+	case *mcp.ListPromptsResult:
+		return len(v.Prompts)
 	case map[string]interface{}:
 		if prompts, ok := v["prompts"]; ok {
 			if promptsArray, ok := prompts.([]interface{}); ok {
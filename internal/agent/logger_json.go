@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutputFormat selects how the logger renders log lines
+type OutputFormat string
+
+const (
+	// OutputText renders human-readable, optionally colored lines
+	OutputText OutputFormat = "text"
+	// OutputJSON renders every line as a JSON object, one per line, for jq
+	// or log aggregation
+	OutputJSON OutputFormat = "json"
+)
+
+// OutputFormats lists the accepted output format names
+var OutputFormats = []string{
+	string(OutputText),
+	string(OutputJSON),
+}
+
+// ParseOutputFormat validates an output format name
+func ParseOutputFormat(format string) (OutputFormat, error) {
+	normalized := strings.ToLower(strings.TrimSpace(format))
+	for _, f := range OutputFormats {
+		if f == normalized {
+			return OutputFormat(f), nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format: %s (must be one of: %s)", format, strings.Join(OutputFormats, ", "))
+}
+
+// Log entry types of JSON output
+const (
+	entryLog          = "log"
+	entryServerLog    = "server-log"
+	entryRequest      = "request"
+	entryResponse     = "response"
+	entryNotification = "notification"
+	entryRateLimit    = "rate-limit"
+)
+
+// Message directions of JSON output
+const (
+	directionOutgoing = "outgoing"
+	directionIncoming = "incoming"
+)
+
+// logEntry is one line of JSON output
+type logEntry struct {
+	Time       string `json:"time"`
+	Level      string `json:"level"`
+	Type       string `json:"type"`
+	Connection string `json:"connection,omitempty"`
+	Direction  string `json:"direction,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Logger     string `json:"logger,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// CorrelationID, RequestMethod and ElapsedMs link a message to the
+	// request it belongs to
+	CorrelationID string  `json:"correlationId,omitempty"`
+	RequestMethod string  `json:"requestMethod,omitempty"`
+	ElapsedMs     float64 `json:"elapsedMs,omitempty"`
+	// Payload is the JSON-RPC params or result. A payload exceeding the
+	// configured size limit is a truncated string instead.
+	Payload          any  `json:"payload,omitempty"`
+	PayloadTruncated bool `json:"payloadTruncated,omitempty"`
+	// PayloadSkipped is set when payload sampling skipped this message
+	PayloadSkipped bool `json:"payloadSkipped,omitempty"`
+}
+
+// SetOutputFormat switches between text and JSON output
+func (l *Logger) SetOutputFormat(format OutputFormat) {
+	l.format = format
+}
+
+// jsonOutput reports whether lines are rendered as JSON
+func (l *Logger) jsonOutput() bool {
+	return l.format == OutputJSON
+}
+
+// writeJSONMessage writes a JSON-RPC request, response or notification as
+// a JSON entry, honoring the payload sampling and truncation settings
+func (l *Logger) writeJSONMessage(entryType, direction, method string, payload any, corr Correlation) {
+	level := "info"
+	if method == methodPing {
+		if !l.isVerbose() {
+			return
+		}
+		level = "debug"
+	} else if !l.enabled(LevelInfo) {
+		return
+	}
+
+	entry := logEntry{
+		Level:         level,
+		Type:          entryType,
+		Direction:     direction,
+		Method:        method,
+		CorrelationID: corr.ID,
+		RequestMethod: corr.Method,
+		ElapsedMs:     float64(corr.Elapsed.Microseconds()) / 1000,
+	}
+	if payload != nil {
+		l.setJSONPayload(&entry, payload)
+	}
+	l.writeJSONEntry(entry)
+}
+
+// setJSONPayload attaches a payload to an entry; unsampled payloads are
+// skipped before any serialization takes place
+func (l *Logger) setJSONPayload(entry *logEntry, payload any) {
+	if rate := l.payload.SampleRate; rate > 1 {
+		if (l.payloadSeqNum.Add(1)-1)%uint64(rate) != 0 {
+			entry.PayloadSkipped = true
+			return
+		}
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		entry.Payload = l.redact(fmt.Sprintf("%+v", payload))
+		return
+	}
+	kept, truncated := l.redactedPayload(b)
+	if truncated {
+		entry.Payload = kept
+		entry.PayloadTruncated = true
+		return
+	}
+	entry.Payload = json.RawMessage(kept)
+}
+
+// writeJSONEntry stamps an entry and writes it as a single line
+func (l *Logger) writeJSONEntry(entry logEntry) {
+	entry.Time = time.Now().Format(time.RFC3339Nano)
+	entry.Connection = l.prefix
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b, _ = json.Marshal(logEntry{Time: entry.Time, Level: "error", Type: entryLog, Message: fmt.Sprintf("failed to encode log entry: %v", err)})
+	}
+	_, _ = l.writer.Write(append(b, '\n'))
+}
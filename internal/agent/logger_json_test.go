@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// decodeJSONLines parses one JSON object per output line
+func decodeJSONLines(t *testing.T, output string) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line is not JSON: %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestJSONOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, true, false, buf).WithPrefix("staging")
+	logger.SetOutputFormat(OutputJSON)
+
+	logger.Info("Connecting to %s", "server")
+	logger.Error("boom")
+	logger.Request("tools/call", map[string]any{"name": "echo"})
+	logger.Response("tools/call", map[string]any{"content": []any{}})
+	logger.Notification("notifications/tools/list_changed", nil)
+	logger.ServerLog("warning", "db", "slow query")
+	logger.Request(methodPing, nil)
+	logger.Debug("hidden unless verbose")
+
+	entries := decodeJSONLines(t, buf.String())
+	want := []struct {
+		level, entryType, direction, method, message string
+	}{
+		{"info", entryLog, "", "", "Connecting to server"},
+		{"error", entryLog, "", "", "boom"},
+		{"info", entryRequest, directionOutgoing, "tools/call", ""},
+		{"info", entryResponse, directionIncoming, "tools/call", ""},
+		{"info", entryNotification, directionIncoming, "notifications/tools/list_changed", ""},
+		{"warning", entryServerLog, "", "", "slow query"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d:\n%s", len(want), len(entries), buf.String())
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e["level"] != w.level || e["type"] != w.entryType || e["connection"] != "staging" {
+			t.Errorf("entry %d = %v, want level %s type %s", i, e, w.level, w.entryType)
+		}
+		if w.direction != "" && (e["direction"] != w.direction || e["method"] != w.method) {
+			t.Errorf("entry %d = %v, want %s %s", i, e, w.direction, w.method)
+		}
+		if w.message != "" && e["message"] != w.message {
+			t.Errorf("entry %d message = %v, want %q", i, e["message"], w.message)
+		}
+		if _, ok := e["time"].(string); !ok {
+			t.Errorf("entry %d has no timestamp", i)
+		}
+	}
+
+	payload, ok := entries[2]["payload"].(map[string]any)
+	if !ok || payload["name"] != "echo" {
+		t.Errorf("expected request payload to be embedded as JSON, got %v", entries[2]["payload"])
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("expected no color codes in JSON output")
+	}
+}
+
+func TestJSONOutputPayloadOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, buf)
+	logger.SetOutputFormat(OutputJSON)
+	logger.SetPayloadOptions(PayloadLogOptions{MaxBytes: 20, SampleRate: 2})
+
+	params := map[string]any{"payload": strings.Repeat("x", 100)}
+	logger.Request("tools/call", params)
+	logger.Request("tools/call", params)
+
+	entries := decodeJSONLines(t, buf.String())
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0]["payloadTruncated"] != true || len(entries[0]["payload"].(string)) != 20 {
+		t.Errorf("expected a truncated payload, got %v", entries[0])
+	}
+	if entries[1]["payloadSkipped"] != true || entries[1]["payload"] != nil {
+		t.Errorf("expected the second payload to be skipped, got %v", entries[1])
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	if format, err := ParseOutputFormat(" JSON "); err != nil || format != OutputJSON {
+		t.Errorf("ParseOutputFormat(JSON) = %q, %v", format, err)
+	}
+	if _, err := ParseOutputFormat("yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
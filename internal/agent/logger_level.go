@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogLevel is the minimum severity a logger writes
+type LogLevel int
+
+const (
+	// LevelTrace logs every JSON-RPC message with its full payload, as
+	// with --json-rpc, in addition to the debug messages
+	LevelTrace LogLevel = iota
+	// LevelDebug adds keepalive pings and diagnostic details
+	LevelDebug
+	// LevelInfo logs the progress of the session; the default
+	LevelInfo
+	// LevelWarn logs only warnings and errors
+	LevelWarn
+	// LevelError logs only errors
+	LevelError
+)
+
+// LogLevels lists the accepted log level names, from most to least verbose
+var LogLevels = []string{"trace", "debug", "info", "warn", "error"}
+
+// String returns the name of the level
+func (level LogLevel) String() string {
+	if level < LevelTrace || level > LevelError {
+		return fmt.Sprintf("LogLevel(%d)", int(level))
+	}
+	return LogLevels[level]
+}
+
+// ParseLogLevel validates a log level name. "warning" is accepted as an
+// alias of "warn".
+func ParseLogLevel(name string) (LogLevel, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "warning" {
+		normalized = "warn"
+	}
+	for i, level := range LogLevels {
+		if level == normalized {
+			return LogLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level: %s (must be one of: %s)", name, strings.Join(LogLevels, ", "))
+}
+
+// verboseLevel maps the verbose flag to a level
+func verboseLevel(verbose bool) LogLevel {
+	if verbose {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// serverLogLevel maps an RFC 5424 severity of a server log message to a
+// level; unknown severities are treated as errors so they are not lost
+func serverLogLevel(severity string) LogLevel {
+	switch severity {
+	case "debug":
+		return LevelDebug
+	case "info", "notice":
+		return LevelInfo
+	case "warning":
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
+
+// SetLevel sets the minimum severity written by the logger, not its tee
+func (l *Logger) SetLevel(level LogLevel) {
+	l.level = level
+}
+
+// Level returns the minimum severity written by the logger
+func (l *Logger) Level() LogLevel {
+	return l.level
+}
+
+// SetTee makes the logger forward every message to tee, which filters and
+// renders them with its own settings. Use it to write a log file at a
+// different level than the terminal.
+func (l *Logger) SetTee(tee *Logger) {
+	l.tee = tee
+}
+
+// enabled reports whether messages of level are written
+func (l *Logger) enabled(level LogLevel) bool {
+	return level >= l.level
+}
+
+// isVerbose reports whether debug messages are written
+func (l *Logger) isVerbose() bool {
+	return l.enabled(LevelDebug)
+}
+
+// fullMessages reports whether JSON-RPC messages are rendered with their
+// payloads rather than summarized
+func (l *Logger) fullMessages() bool {
+	return l.jsonRPCMode || l.level == LevelTrace
+}
+
+// each calls fn with the logger and its tee, if any
+func (l *Logger) each(fn func(*Logger)) {
+	fn(l)
+	if l.tee != nil {
+		l.tee.each(fn)
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for name, want := range map[string]LogLevel{
+		"trace":   LevelTrace,
+		"Debug":   LevelDebug,
+		" info ":  LevelInfo,
+		"warn":    LevelWarn,
+		"warning": LevelWarn,
+		"error":   LevelError,
+	} {
+		got, err := ParseLogLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		want  []string
+	}{
+		{LevelDebug, []string{"debug message", "info message", "warn message", "error message"}},
+		{LevelInfo, []string{"info message", "warn message", "error message"}},
+		{LevelWarn, []string{"warn message", "error message"}},
+		{LevelError, []string{"error message"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := NewLoggerWithWriter(false, false, false, buf)
+			logger.SetLevel(tt.level)
+
+			logger.Debug("debug message")
+			logger.Info("info message")
+			logger.Warning("warn message")
+			logger.Error("error message")
+			logger.ServerLog("notice", "", "server notice")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if tt.level <= LevelInfo {
+				tt.want = append(tt.want, "server notice")
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tt.want), buf.String())
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("line %d = %q, want %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerTraceLogsFullMessages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, buf)
+	logger.SetLevel(LevelTrace)
+
+	logger.Request("tools/call", map[string]interface{}{"name": "echo"})
+	if !strings.Contains(buf.String(), "REQUEST (tools/call)") || !strings.Contains(buf.String(), `"name": "echo"`) {
+		t.Errorf("expected the full request at trace level, got %q", buf.String())
+	}
+}
+
+func TestLoggerTee(t *testing.T) {
+	console := &bytes.Buffer{}
+	file := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, console)
+	tee := NewLoggerWithWriter(false, false, false, file)
+	tee.SetLevel(LevelTrace)
+	logger.SetTee(tee)
+
+	conn := logger.WithPrefix("conn")
+	conn.Debug("ping details")
+	conn.Request("tools/list", map[string]interface{}{"cursor": "abc"})
+	conn.Response("tools/list", map[string]interface{}{"tools": []interface{}{}})
+
+	if strings.Contains(console.String(), "ping details") || strings.Contains(console.String(), "cursor") {
+		t.Errorf("console logged debug or trace output: %q", console.String())
+	}
+	if !strings.Contains(console.String(), "[conn]") || !strings.Contains(console.String(), "Found 0 tools") {
+		t.Errorf("console missing the summarized response: %q", console.String())
+	}
+	for _, want := range []string{"[conn]", "ping details", `"cursor": "abc"`, "RESPONSE (tools/list)"} {
+		if !strings.Contains(file.String(), want) {
+			t.Errorf("tee output missing %q: %q", want, file.String())
+		}
+	}
+	// Each message is written once per logger
+	if n := strings.Count(file.String(), "REQUEST (tools/list)"); n != 1 {
+		t.Errorf("tee logged the request %d times", n)
+	}
+
+	logger.SetVerbose(true)
+	if logger.Level() != LevelDebug || tee.Level() != LevelTrace {
+		t.Errorf("SetVerbose changed levels to %v and %v", logger.Level(), tee.Level())
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces the values of secrets in logs
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields are the JSON fields, form parameters and headers whose
+// values are always redacted
+var sensitiveFields = map[string]bool{
+	"authorization":             true,
+	"proxy-authorization":       true,
+	"cookie":                    true,
+	"set-cookie":                true,
+	"access_token":              true,
+	"refresh_token":             true,
+	"id_token":                  true,
+	"client_secret":             true,
+	"code_verifier":             true,
+	"device_code":               true,
+	"registration_access_token": true,
+}
+
+// sensitiveFormParams are redacted only in form-encoded bodies and query
+// strings, where "code" is the authorization code and "token" the one sent
+// for revocation or introspection; a JSON "code" field is usually
+// something else, such as a JSON-RPC error code
+var sensitiveFormParams = map[string]bool{
+	"code":  true,
+	"token": true,
+}
+
+var (
+	// jsonStringField matches a JSON field with a string value
+	jsonStringField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// formParam matches a name=value pair of a form body or query string
+	formParam = regexp.MustCompile(`\b([A-Za-z_][\w.-]*)=([^&\s"',]+)`)
+	// credentialScheme matches the credentials of an Authorization header.
+	// Credentials contain a digit or punctuation or are long, so prose such
+	// as "Bearer tokens" is left alone.
+	credentialScheme = regexp.MustCompile(`\b(Bearer|Basic|DPoP) (?:[A-Za-z]*[0-9._~+/=-][A-Za-z0-9._~+/=-]*|[A-Za-z]{16,})`)
+)
+
+// Redactor masks bearer tokens, client secrets, authorization codes and
+// other credentials in log output
+type Redactor struct {
+	// fields match further field names whose values are masked
+	fields []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor that masks the built-in credential fields
+// and any field whose name matches one of the patterns, case-insensitively
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.fields = append(r.fields, re)
+	}
+	return r, nil
+}
+
+// defaultRedactor masks the built-in credential fields
+var defaultRedactor = &Redactor{}
+
+// sensitive reports whether the value of the named field is masked
+func (r *Redactor) sensitive(name string) bool {
+	if sensitiveFields[strings.ToLower(name)] {
+		return true
+	}
+	for _, re := range r.fields {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns s with the values of sensitive JSON fields, form
+// parameters and Authorization credentials masked. Valid JSON stays valid.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	s = credentialScheme.ReplaceAllString(s, "$1 "+redactedValue)
+	s = jsonStringField.ReplaceAllStringFunc(s, func(field string) string {
+		m := jsonStringField.FindStringSubmatch(field)
+		if !r.sensitive(m[1]) {
+			return field
+		}
+		return fmt.Sprintf(`"%s"%s"%s"`, m[1], m[2], redactedValue)
+	})
+	return formParam.ReplaceAllStringFunc(s, func(param string) string {
+		m := formParam.FindStringSubmatch(param)
+		if !r.sensitive(m[1]) && !sensitiveFormParams[strings.ToLower(m[1])] {
+			return param
+		}
+		return m[1] + "=" + redactedValue
+	})
+}
+
+// SetRedactor sets the redactor applied to log output; nil disables
+// redaction
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.redactor = r
+}
+
+// redact masks secrets in s with the logger's redactor
+func (l *Logger) redact(s string) string {
+	return l.redactor.Redact(s)
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactorRedact(t *testing.T) {
+	redactor, err := NewRedactor([]string{"password"})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "token response",
+			in:   `{"access_token":"eyJhbGciOi.x.y","token_type":"Bearer","refresh_token": "r-123"}`,
+			want: `{"access_token":"[REDACTED]","token_type":"Bearer","refresh_token": "[REDACTED]"}`,
+		},
+		{
+			name: "authorization header",
+			in:   "Authorization: Bearer eyJhbGciOi.x.y",
+			want: "Authorization: Bearer [REDACTED]",
+		},
+		{
+			name: "token request",
+			in:   "grant_type=authorization_code&code=abc123&code_verifier=xyz&redirect_uri=http://localhost",
+			want: "grant_type=authorization_code&code=[REDACTED]&code_verifier=[REDACTED]&redirect_uri=http://localhost",
+		},
+		{
+			name: "custom pattern",
+			in:   `{"userPassword":"hunter2","user":"bob"}`,
+			want: `{"userPassword":"[REDACTED]","user":"bob"}`,
+		},
+		{
+			name: "JSON-RPC error code and prose are kept",
+			in:   `{"code":-32601,"message":"Requiring Bearer tokens"}`,
+			want: `{"code":-32601,"message":"Requiring Bearer tokens"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactor.Redact(tt.in); got != tt.want {
+				t.Errorf("Redact() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewRedactor([]string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoggerRedactsPayloads(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, true, buf)
+	logger.Response("tools/call", map[string]interface{}{"client_secret": "s3cr3t"})
+	logger.Info("Calling with Authorization: Bearer abc.def")
+	if strings.Contains(buf.String(), "s3cr3t") || strings.Contains(buf.String(), "abc.def") {
+		t.Errorf("secret logged: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetOutputFormat(OutputJSON)
+	logger.Request("tools/call", map[string]interface{}{"access_token": "t0ken"})
+	var entry logEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("redacted entry is not valid JSON: %v", err)
+	}
+	if strings.Contains(buf.String(), "t0ken") {
+		t.Errorf("secret logged: %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetRedactor(nil)
+	logger.Request("tools/call", map[string]interface{}{"access_token": "t0ken"})
+	if !strings.Contains(buf.String(), "t0ken") {
+		t.Errorf("expected the unredacted payload without a redactor: %s", buf.String())
+	}
+}
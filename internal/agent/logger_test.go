@@ -2,8 +2,10 @@ package agent
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestInfoVerbose(t *testing.T) {
@@ -175,8 +177,8 @@ func TestLoggerConstructors(t *testing.T) {
 		if logger == nil {
 			t.Fatal("expected NewLogger to return non-nil logger")
 		}
-		if !logger.verbose {
-			t.Error("expected verbose to be true")
+		if logger.level != LevelDebug {
+			t.Errorf("expected verbose to select the debug level, got %v", logger.level)
 		}
 		if !logger.useColor {
 			t.Error("expected useColor to be true")
@@ -221,3 +223,158 @@ func TestSetWriter(t *testing.T) {
 		t.Error("expected message to be written to buf2")
 	}
 }
+
+func TestWithPrefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(false, false, false, buf).WithPrefix("staging")
+
+	logger.Info("hello")
+	if !strings.Contains(buf.String(), "] [staging] hello") {
+		t.Errorf("expected connection prefix in %q", buf.String())
+	}
+}
+
+func TestServerLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      string
+		loggerName string
+		message    string
+		useColor   bool
+		want       []string
+		wantColor  string
+	}{
+		{
+			name:    "info without logger name",
+			level:   "info",
+			message: "server started",
+			want:    []string{"[server:INFO]", "server started"},
+		},
+		{
+			name:       "error with logger name",
+			level:      "error",
+			loggerName: "db",
+			message:    "connection lost",
+			useColor:   true,
+			want:       []string{"[server:ERROR] (db)", "connection lost"},
+			wantColor:  colorRed,
+		},
+		{
+			name:      "warning is yellow",
+			level:     "warning",
+			message:   "slow query",
+			useColor:  true,
+			want:      []string{"[server:WARNING]"},
+			wantColor: colorYellow,
+		},
+		{
+			name:    "missing level defaults to info",
+			message: "hello",
+			want:    []string{"[server:INFO]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := NewLoggerWithWriter(false, tt.useColor, false, buf)
+
+			logger.ServerLog(tt.level, tt.loggerName, tt.message)
+
+			output := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got %q", want, output)
+				}
+			}
+			if tt.wantColor != "" && !strings.Contains(output, tt.wantColor) {
+				t.Errorf("expected output to be colored, got %q", output)
+			}
+		})
+	}
+}
+
+func TestIndentJSON(t *testing.T) {
+	v := map[string]interface{}{
+		"empty":  map[string]interface{}{},
+		"list":   []interface{}{1, "a,b", []interface{}{}},
+		"nested": map[string]interface{}{"quote": `say "hi": {x}`},
+	}
+	compact, _ := json.Marshal(v)
+	want, _ := json.MarshalIndent(v, "", "  ")
+	if got := indentJSON(string(compact)); got != string(want) {
+		t.Errorf("indentJSON() = %s, want %s", got, want)
+	}
+
+	// Cut off JSON is indented as far as it goes
+	if got := indentJSON(`{"a":[1,`); got != "{\n  \"a\": [\n    1,\n    " {
+		t.Errorf("indentJSON() of cut off JSON = %q", got)
+	}
+}
+
+func TestPayloadLogOptions(t *testing.T) {
+	params := map[string]interface{}{"name": "echo", "payload": strings.Repeat("x", 200)}
+
+	t.Run("compact and truncated", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewLoggerWithWriter(false, false, true, buf)
+		logger.SetPayloadOptions(PayloadLogOptions{Compact: true, MaxBytes: 50})
+
+		logger.Request("tools/call", params)
+
+		output := buf.String()
+		if strings.Contains(output, "\n  \"") {
+			t.Errorf("expected single-line JSON, got %q", output)
+		}
+		if !strings.Contains(output, "(truncated,") {
+			t.Errorf("expected truncation marker, got %q", output)
+		}
+	})
+
+	t.Run("truncated at a character boundary", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewLoggerWithWriter(false, false, true, buf)
+		// The cut falls into the second byte of an "é"
+		logger.SetPayloadOptions(PayloadLogOptions{Compact: true, MaxBytes: len(`{"a":"`) + 1})
+
+		logger.Request("tools/call", map[string]interface{}{"a": "éé"})
+
+		if output := buf.String(); !utf8.ValidString(output) || !strings.Contains(output, `{"a":"… (truncated,`) {
+			t.Errorf("expected the cut before the split character, got %q", output)
+		}
+	})
+
+	t.Run("secrets reaching past the limit are redacted", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewLoggerWithWriter(false, false, true, buf)
+		logger.SetPayloadOptions(PayloadLogOptions{MaxBytes: 40})
+
+		logger.Request("tools/call", map[string]interface{}{"access_token": strings.Repeat("s", 100)})
+
+		output := buf.String()
+		if strings.Contains(output, "sss") {
+			t.Errorf("expected the cut off secret to be redacted, got %q", output)
+		}
+		if !strings.Contains(output, "\n  \"access_token\": \"[REDAC") {
+			t.Errorf("expected an indented truncated payload, got %q", output)
+		}
+	})
+
+	t.Run("sampled", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewLoggerWithWriter(false, false, true, buf)
+		logger.SetPayloadOptions(PayloadLogOptions{SampleRate: 3})
+
+		for i := 0; i < 6; i++ {
+			logger.Request("tools/call", params)
+		}
+
+		output := buf.String()
+		if got := strings.Count(output, "\"payload\""); got != 2 {
+			t.Errorf("expected 2 sampled payloads, got %d", got)
+		}
+		if got := strings.Count(output, "(payload not sampled)"); got != 4 {
+			t.Errorf("expected 4 skipped payloads, got %d", got)
+		}
+	})
+}
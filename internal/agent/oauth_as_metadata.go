@@ -1,7 +1,8 @@
 // Package agent implements Authorization Server Metadata Discovery per RFC 8414.
 //
 // Multi-Endpoint Discovery:
-// Probes multiple discovery endpoints in priority order based on issuer URL format.
+// Probes multiple discovery endpoints concurrently, resolving them in priority
+// order based on issuer URL format.
 // Supports both OAuth 2.0 Authorization Server Metadata (RFC 8414) and
 // OpenID Connect Discovery 1.0.
 //
@@ -18,7 +19,6 @@ package agent
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -44,6 +44,10 @@ type AuthorizationServerMetadata struct {
 	// RegistrationEndpoint is the URL for Dynamic Client Registration (optional)
 	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
 
+	// DeviceAuthorizationEndpoint is the URL for the Device Authorization
+	// Grant per RFC 8628 (optional)
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
 	// CodeChallengeMethods lists supported PKCE code challenge methods
 	// MCP spec requires this field to be present and include "S256"
 	CodeChallengeMethods []string `json:"code_challenge_methods_supported,omitempty"`
@@ -62,18 +66,16 @@ type AuthorizationServerMetadata struct {
 
 	// TokenEndpointAuthMethodsSupported lists supported token endpoint auth methods
 	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+
+	// JWKSURI is the URL of the JWK Set with the keys the server signs
+	// tokens with (optional)
+	JWKSURI string `json:"jwks_uri,omitempty"`
 }
 
 const (
 	// Maximum size for AS metadata documents (1MB)
 	maxASMetadataSize = 1024 * 1024
 
-	// HTTP timeout for AS metadata requests
-	asMetadataRequestTimeout = 10 * time.Second
-
-	// User agent string for AS metadata requests
-	userAgent = "mcp-debug/1.0"
-
 	// Environment variable to allow insecure operations (testing only)
 	allowInsecureEnvVar = "MCP_DEBUG_ALLOW_INSECURE"
 )
@@ -81,7 +83,8 @@ const (
 // DiscoverAuthorizationServerMetadata discovers authorization server metadata
 // for the given issuer URL per RFC 8414 and OIDC Discovery 1.0.
 //
-// Discovery probes endpoints in this priority order:
+// Discovery probes all endpoints concurrently and picks the result by this
+// priority order:
 //
 // For issuer URLs with path components (e.g., https://auth.example.com/tenant1):
 //  1. OAuth 2.0 with path insertion: https://auth.example.com/.well-known/oauth-authorization-server/tenant1
@@ -92,32 +95,45 @@ const (
 //  1. OAuth 2.0: https://auth.example.com/.well-known/oauth-authorization-server
 //  2. OIDC: https://auth.example.com/.well-known/openid-configuration
 //
-// Returns the first successfully retrieved metadata document.
+// Returns the highest-priority successfully retrieved metadata document;
+// slower lower-priority probes are cancelled once it is known.
 func DiscoverAuthorizationServerMetadata(ctx context.Context, issuerURL string, logger *Logger) (*AuthorizationServerMetadata, error) {
+	metadata, _, err := discoverASMetadata(ctx, issuerURL, logger)
+	return metadata, err
+}
+
+// discoverASMetadata implements DiscoverAuthorizationServerMetadata and
+// also returns the URL the metadata was found at
+func discoverASMetadata(ctx context.Context, issuerURL string, logger *Logger) (*AuthorizationServerMetadata, string, error) {
 	// Build discovery endpoints based on issuer URL format
 	endpoints, err := buildASMetadataEndpoints(issuerURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build AS metadata endpoints: %w", err)
+		return nil, "", fmt.Errorf("failed to build AS metadata endpoints: %w", err)
 	}
 
 	if logger != nil {
 		logger.InfoVerbose("Probing %d AS metadata endpoints for issuer: %s", len(endpoints), issuerURL)
 	}
 
-	// Try each endpoint in order
-	var lastErr error
-	for i, endpoint := range endpoints {
+	// Probe all endpoints concurrently; the first success wins, priority
+	// breaks ties within the grace window
+	metadata, index, err := probeInPriorityOrder(ctx, len(endpoints), func(ctx context.Context, i int) (*AuthorizationServerMetadata, error) {
+		endpoint := endpoints[i]
 		if logger != nil {
 			logger.InfoVerbose("Trying AS metadata endpoint (%d/%d): %s", i+1, len(endpoints), endpoint)
 		}
 
+		start := time.Now()
 		metadata, err := fetchASMetadata(ctx, endpoint)
+		elapsed := time.Since(start).Round(time.Millisecond)
 		if err != nil {
-			if logger != nil {
-				logger.WarningVerbose("Failed to fetch from %s: %v", endpoint, err)
+			if logger != nil && ctx.Err() == nil {
+				logger.WarningVerbose("Failed to fetch from %s after %s: %v", endpoint, elapsed, err)
 			}
-			lastErr = err
-			continue
+			return nil, err
+		}
+		if logger != nil {
+			logger.InfoVerbose("Fetched AS metadata from %s in %s", endpoint, elapsed)
 		}
 
 		// Validate metadata structure
@@ -125,22 +141,22 @@ func DiscoverAuthorizationServerMetadata(ctx context.Context, issuerURL string,
 			if logger != nil {
 				logger.WarningVerbose("Invalid metadata from %s: %v", endpoint, err)
 			}
-			lastErr = err
-			continue
+			return nil, err
 		}
-
-		if logger != nil {
-			logger.Info("Successfully discovered AS metadata from: %s", endpoint)
-		}
-
 		return metadata, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("no valid AS metadata found (last error: %w)", err)
+	}
+	if metadata == nil {
+		return nil, "", fmt.Errorf("no AS metadata found at any discovery endpoint")
 	}
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("no valid AS metadata found (last error: %w)", lastErr)
+	if logger != nil {
+		logger.Info("Successfully discovered AS metadata from: %s", endpoints[index])
 	}
 
-	return nil, fmt.Errorf("no AS metadata found at any discovery endpoint")
+	return metadata, endpoints[index], nil
 }
 
 // normalizePath removes leading and trailing slashes from a URL path.
@@ -215,16 +231,7 @@ func buildASMetadataEndpoints(issuerURL string) ([]string, error) {
 
 // fetchASMetadata fetches and parses authorization server metadata from the specified URL.
 func fetchASMetadata(ctx context.Context, metadataURL string) (*AuthorizationServerMetadata, error) {
-	// Create HTTP client with timeout and secure TLS configuration
-	client := &http.Client{
-		Timeout: asMetadataRequestTimeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12,
-				// InsecureSkipVerify: false (explicit default for security)
-			},
-		},
-	}
+	client := httpClientFor(ctx, metadataRequestTimeout)
 
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
@@ -234,7 +241,6 @@ func fetchASMetadata(ctx context.Context, metadataURL string) (*AuthorizationSer
 
 	// Set appropriate headers
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", userAgent)
 
 	// Execute request
 	resp, err := client.Do(req)
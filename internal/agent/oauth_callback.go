@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// readPastedCallback implements --oauth-manual-code for machines that cannot
+// open a browser or a local port: it prints the authorization URL and reads
+// the URL the browser was redirected to, or just the code, from the user.
+//
+// A bare code carries no state, so the state check is skipped for it with a
+// warning.
+func (c *Client) readPastedCallback(ctx context.Context, authURL, state string) (map[string]string, error) {
+	c.logger.Info("Open this URL in a browser on any machine and authorize mcp-debug:")
+	c.logger.Info("%s", authURL)
+	c.logger.Info("The browser is then redirected to %s, which may fail to load.", c.oauthSettings().RedirectURL)
+	c.logger.Info("Paste the full URL from its address bar, or only the code parameter.")
+
+	readLine := c.oauthLineReader()
+	for {
+		input, err := readLine(ctx, "Redirect URL or code: ")
+		if err != nil {
+			return nil, err
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		params, err := parsePastedCallback(input)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := params["state"]; !ok {
+			c.logger.Warning("No state parameter pasted - CSRF protection is skipped for this code")
+			params["state"] = state
+		}
+		return params, nil
+	}
+}
+
+// parsePastedCallback extracts the callback parameters from a pasted
+// redirect URL, its query string, or a bare authorization code
+func parsePastedCallback(input string) (map[string]string, error) {
+	query := input
+	if parsed, err := url.Parse(input); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		query = parsed.RawQuery
+	} else if !strings.Contains(input, "=") {
+		return map[string]string{"code": input}, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimPrefix(query, "?"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	params := make(map[string]string, len(values))
+	for key, value := range values {
+		if len(value) > 0 {
+			params[key] = value[0]
+		}
+	}
+	if params["error"] != "" {
+		return nil, fmt.Errorf("authorization error: %s - %s", params["error"], params["error_description"])
+	}
+	if params["code"] == "" {
+		return nil, fmt.Errorf("no code parameter in the pasted redirect URL")
+	}
+	return params, nil
+}
+
+// oauthLineReader returns how the manual code is read: the REPL's line
+// editor once it runs, standard input before
+func (c *Client) oauthLineReader() lineReader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.oauthReadLine != nil {
+		return c.oauthReadLine
+	}
+	return stdinLineReader()
+}
+
+// ParsePortRange parses a port range like "8000-8100"; a single port is a
+// range of one
+func ParsePortRange(s string) (int, int, error) {
+	first, last, found := strings.Cut(strings.TrimSpace(s), "-")
+	low, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	high := low
+	if found {
+		if high, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return 0, 0, fmt.Errorf("invalid port range %q: %w", s, err)
+		}
+	}
+	if low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("invalid port range %q: ports must satisfy 1 <= low <= high <= 65535", s)
+	}
+	return low, high, nil
+}
+
+// redirectURLWithFreePort returns redirectURL with its port replaced by the
+// first port of the range that is free on the redirect URL's host
+func redirectURLWithFreePort(redirectURL, portRange string) (string, error) {
+	low, high, err := ParsePortRange(portRange)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(redirectURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid OAuth redirect URL: %w", err)
+	}
+
+	host := parsed.Hostname()
+	for port := low; port <= high; port++ {
+		address := net.JoinHostPort(host, strconv.Itoa(port))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			continue
+		}
+		_ = listener.Close()
+		parsed.Host = address
+		return parsed.String(), nil
+	}
+	return "", fmt.Errorf("no free callback port in range %s on %s", portRange, host)
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestParsePastedCallback(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantCode  string
+		wantState string
+		wantErr   bool
+	}{
+		{input: "http://localhost:8765/callback?code=abc&state=xyz", wantCode: "abc", wantState: "xyz"},
+		{input: "?code=abc&state=xyz", wantCode: "abc", wantState: "xyz"},
+		{input: "code=abc", wantCode: "abc"},
+		{input: "abc-123.def", wantCode: "abc-123.def"},
+		{input: "http://localhost:8765/callback?error=access_denied&error_description=no", wantErr: true},
+		{input: "http://localhost:8765/callback?state=xyz", wantErr: true},
+	}
+	for _, tt := range tests {
+		params, err := parsePastedCallback(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePastedCallback(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (params["code"] != tt.wantCode || params["state"] != tt.wantState) {
+			t.Errorf("parsePastedCallback(%q) = %v, want code %q and state %q", tt.input, params, tt.wantCode, tt.wantState)
+		}
+	}
+}
+
+func TestReadPastedCallback(t *testing.T) {
+	answers := []string{"", "http://localhost:8765/callback?code=abc&state=xyz", "abc"}
+	c := &Client{
+		logger:      NewLoggerWithWriter(false, false, false, io.Discard),
+		oauthConfig: &OAuthConfig{RedirectURL: DefaultRedirectURL},
+		oauthReadLine: func(ctx context.Context, prompt string) (string, error) {
+			answer := answers[0]
+			answers = answers[1:]
+			return answer, nil
+		},
+	}
+
+	params, err := c.readPastedCallback(context.Background(), "https://auth.example.com/authorize", "expected")
+	if err != nil || params["code"] != "abc" || params["state"] != "xyz" {
+		t.Errorf("got %v, %v; want the pasted code and state, skipping the empty line", params, err)
+	}
+
+	// A bare code takes the expected state, so only the state check is skipped
+	params, err = c.readPastedCallback(context.Background(), "https://auth.example.com/authorize", "expected")
+	if err != nil || params["code"] != "abc" || params["state"] != "expected" {
+		t.Errorf("got %v, %v; want the code with the expected state", params, err)
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	if low, high, err := ParsePortRange("8000-8100"); err != nil || low != 8000 || high != 8100 {
+		t.Errorf("ParsePortRange(8000-8100) = %d, %d, %v", low, high, err)
+	}
+	if low, high, err := ParsePortRange("9000"); err != nil || low != 9000 || high != 9000 {
+		t.Errorf("ParsePortRange(9000) = %d, %d, %v", low, high, err)
+	}
+	for _, invalid := range []string{"", "a-b", "8100-8000", "0-10", "65000-70000"} {
+		if _, _, err := ParsePortRange(invalid); err == nil {
+			t.Errorf("ParsePortRange(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestRedirectURLWithFreePort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = busy.Close() }()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	portRange := strconv.Itoa(port) + "-" + strconv.Itoa(port+20)
+	redirectURL, err := redirectURLWithFreePort("http://127.0.0.1:8765/callback", portRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(redirectURL)
+	got, _ := strconv.Atoi(parsed.Port())
+	if got <= port || got > port+20 || parsed.Path != "/callback" {
+		t.Errorf("expected a free port after the busy one %d with the path kept, got %s", port, redirectURL)
+	}
+
+	if _, err := redirectURLWithFreePort("http://127.0.0.1:8765/callback", strconv.Itoa(port)); err == nil {
+		t.Error("expected an error when every port of the range is busy")
+	}
+}
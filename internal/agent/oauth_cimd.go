@@ -12,14 +12,12 @@ package agent
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
 // ClientMetadataDocument represents an OAuth Client ID Metadata Document
@@ -62,9 +60,6 @@ const (
 	// Maximum size for client metadata documents (100KB)
 	// Smaller than other metadata documents as client metadata should be concise
 	maxClientMetadataSize = 100 * 1024
-
-	// HTTP timeout for client metadata requests
-	clientMetadataRequestTimeout = 10 * time.Second
 )
 
 // GenerateClientMetadata generates a Client ID Metadata Document for mcp-debug.
@@ -153,18 +148,8 @@ func fetchClientMetadataWithClient(ctx context.Context, clientIDURL string, http
 		return nil, err
 	}
 
-	// Create HTTP client with timeout and secure TLS configuration
 	if httpClient == nil {
-		httpClient = &http.Client{
-			Timeout: clientMetadataRequestTimeout,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					MinVersion: tls.VersionTLS12,
-					// Use secure defaults: modern cipher suites only
-					// Go's default cipher suite selection is secure for TLS 1.2+
-				},
-			},
-		}
+		httpClient = httpClientFor(ctx, metadataRequestTimeout)
 	}
 
 	// Create request with context
@@ -175,7 +160,6 @@ func fetchClientMetadataWithClient(ctx context.Context, clientIDURL string, http
 
 	// Set appropriate headers
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", userAgent)
 
 	// Execute request
 	resp, err := httpClient.Do(req)
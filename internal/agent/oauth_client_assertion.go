@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// TokenAuthMethodPrivateKeyJWT authenticates the client with a JWT
+	// signed by its private key (RFC 7523 Section 2.2, OpenID Connect Core
+	// Section 9)
+	TokenAuthMethodPrivateKeyJWT = "private_key_jwt"
+
+	// clientAssertionType is the client_assertion_type of JWT client
+	// assertions
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// clientAssertionLifetime bounds how long a client assertion is valid;
+	// each request gets a fresh one
+	clientAssertionLifetime = time.Minute
+)
+
+// clientAssertionRoundTripper makes the OAuth requests of mcp-go's handler
+// authenticate the client with private_key_jwt:
+//   - registration requests ask for private_key_jwt and carry the public key
+//   - token and revocation requests carry a signed client assertion instead
+//     of the client secret
+type clientAssertionRoundTripper struct {
+	base   http.RoundTripper
+	signer *jwtSigner
+	logger *Logger
+}
+
+// newClientAssertionRoundTripper creates a round tripper signing client
+// assertions with signer
+func newClientAssertionRoundTripper(signer *jwtSigner, base http.RoundTripper, logger *Logger) *clientAssertionRoundTripper {
+	return &clientAssertionRoundTripper{base: base, signer: signer, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *clientAssertionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		if body, err = t.rewriteForm(req.URL, body, time.Now()); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(contentType, "application/json"):
+		body = t.rewriteRegistration(body)
+	}
+	return t.base.RoundTrip(withRequestBody(req, body))
+}
+
+// rewriteForm replaces the client secret of a request identifying the
+// client with a client assertion for endpoint
+func (t *clientAssertionRoundTripper) rewriteForm(endpoint *url.URL, body []byte, now time.Time) ([]byte, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil || form.Get("client_id") == "" {
+		return body, nil
+	}
+
+	// The audience is the endpoint the assertion is sent to, without query
+	audience := *endpoint
+	audience.RawQuery = ""
+	audience.Fragment = ""
+	claims, err := clientAssertionClaims(form.Get("client_id"), audience.String(), now)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := t.signer.sign("JWT", claims)
+	if err != nil {
+		return nil, err
+	}
+	if t.logger != nil && t.logger.isVerbose() {
+		t.logger.Info("Authenticating with a client assertion for %s", audience.String())
+	}
+
+	form.Del("client_secret")
+	form.Set("client_assertion_type", clientAssertionType)
+	form.Set("client_assertion", assertion)
+	return []byte(form.Encode()), nil
+}
+
+// clientAssertionClaims are the claims of a client assertion (RFC 7523
+// Section 3): the client is both issuer and subject
+func clientAssertionClaims(clientID, audience string, now time.Time) (map[string]any, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, fmt.Errorf("failed to generate jti: %w", err)
+	}
+	return map[string]any{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(clientAssertionLifetime).Unix(),
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+	}, nil
+}
+
+// rewriteRegistration makes a Dynamic Client Registration request ask for
+// private_key_jwt and register the public key (RFC 7591 Section 2)
+func (t *clientAssertionRoundTripper) rewriteRegistration(body []byte) []byte {
+	var registration map[string]any
+	if err := json.Unmarshal(body, &registration); err != nil {
+		return body
+	}
+	if _, ok := registration["redirect_uris"]; !ok {
+		return body
+	}
+
+	registration["token_endpoint_auth_method"] = TokenAuthMethodPrivateKeyJWT
+	registration["token_endpoint_auth_signing_alg"] = t.signer.alg()
+	registration["jwks"] = map[string]any{"keys": []map[string]string{t.signer.publicJWK()}}
+	rewritten, err := json.Marshal(registration)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
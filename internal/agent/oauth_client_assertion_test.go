@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientAssertionRoundTripper(t *testing.T) {
+	var tokenForm url.Values
+	var registration map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/register" {
+			_ = json.NewDecoder(r.Body).Decode(&registration)
+			return
+		}
+		_ = r.ParseForm()
+		tokenForm = r.PostForm
+	}))
+	defer server.Close()
+
+	signer, err := newEphemeralJWTSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: newClientAssertionRoundTripper(signer, http.DefaultTransport, nil)}
+
+	resp, err := client.PostForm(server.URL+"/token?tenant=a", url.Values{"grant_type": {"authorization_code"}, "client_id": {"abc"}, "client_secret": {"s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if tokenForm.Has("client_secret") || tokenForm.Get("client_id") != "abc" || tokenForm.Get("client_assertion_type") != clientAssertionType {
+		t.Fatalf("unexpected token request %v", tokenForm)
+	}
+
+	jwt, err := parseJWT(tokenForm.Get("client_assertion"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyJWTSignature(jwt, signer.publicKey()); err != nil {
+		t.Errorf("client assertion does not verify: %v", err)
+	}
+	if jwt.claims.Issuer != "abc" || jwt.claims.Subject != "abc" || len(jwt.claims.Audience) != 1 || jwt.claims.Audience[0] != server.URL+"/token" {
+		t.Errorf("unexpected claims %+v", jwt.claims)
+	}
+
+	resp, err = client.Post(server.URL+"/register", "application/json", strings.NewReader(`{"redirect_uris":["http://localhost:8765/callback"],"token_endpoint_auth_method":"none"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if registration["token_endpoint_auth_method"] != TokenAuthMethodPrivateKeyJWT || registration["token_endpoint_auth_signing_alg"] != "ES256" {
+		t.Errorf("unexpected registration request %v", registration)
+	}
+	keys, _ := registration["jwks"].(map[string]any)["keys"].([]any)
+	if len(keys) != 1 || keys[0].(map[string]any)["kid"] != signer.kid {
+		t.Errorf("expected the public key in jwks, got %v", registration["jwks"])
+	}
+}
+
+func TestClientAssertionLeavesOtherFormsAlone(t *testing.T) {
+	signer, _ := newEphemeralJWTSigner()
+	rt := newClientAssertionRoundTripper(signer, nil, nil)
+	endpoint, _ := url.Parse("https://auth.example.com/token")
+
+	body := []byte("grant_type=client_credentials")
+	got, err := rt.rewriteForm(endpoint, body, time.Now())
+	if err != nil || string(got) != string(body) {
+		t.Errorf("expected a request without client_id unchanged, got %s, %v", got, err)
+	}
+}
+
+func TestClientAssertionClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims, err := clientAssertionClaims("abc", "https://auth.example.com/token", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["iat"] != now.Unix() || claims["exp"] != now.Add(clientAssertionLifetime).Unix() || claims["jti"] == "" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	other, _ := clientAssertionClaims("abc", "https://auth.example.com/token", now)
+	if other["jti"] == claims["jti"] {
+		t.Error("expected a fresh jti for every assertion")
+	}
+}
@@ -21,6 +21,12 @@ const (
 	DefaultRedirectURL = "http://localhost:8765/callback"
 	// DefaultClientName is the default OAuth client name
 	DefaultClientName = "mcp-debug"
+	// OAuthFlowAuthorizationCode uses the authorization code flow with a
+	// browser and localhost callback
+	OAuthFlowAuthorizationCode = "authorization-code"
+	// OAuthFlowDevice uses the Device Authorization Grant (RFC 8628) for
+	// headless environments
+	OAuthFlowDevice = "device"
 )
 
 // OAuthConfig contains OAuth 2.1 configuration for authenticating with MCP servers
@@ -28,6 +34,12 @@ type OAuthConfig struct {
 	// Enabled indicates whether OAuth authentication should be used
 	Enabled bool
 
+	// Flow selects how the user authorizes the client
+	// - "authorization-code" (default): Browser redirect to a localhost callback
+	// - "device": Device Authorization Grant (RFC 8628); prints a user code and
+	//   verification URI and polls the token endpoint, no browser or callback needed
+	Flow string
+
 	// ClientID is the OAuth client identifier (optional - will use DCR if not provided)
 	ClientID string
 
@@ -128,16 +140,65 @@ type OAuthConfig struct {
 	// Example: "https://app.example.com/oauth/client-metadata.json"
 	ClientIDMetadataURL string
 
+	// RevokeOnExit revokes the access and refresh tokens at the authorization
+	// server's revocation endpoint (RFC 7009) when mcp-debug exits, so
+	// debugging sessions do not leave live tokens behind
+	RevokeOnExit bool
+
 	// DisableCIMD disables Client ID Metadata Documents support
 	// When true, falls back to Dynamic Client Registration or manual registration
 	// Use this for testing with Authorization Servers that don't support CIMD
 	DisableCIMD bool
+
+	// StepMode pauses before every request of the OAuth flow (discovery,
+	// registration, authorization redirect, code exchange and the first use
+	// of the token), shows it and waits for confirmation
+	StepMode bool
+
+	// ManualCode skips the browser and the callback server: the
+	// authorization URL is printed and the redirect URL or code is pasted
+	// back, for environments that cannot open either
+	ManualCode bool
+
+	// CallbackPortRange is a port range like "8000-8100"; the callback
+	// server listens on its first free port instead of the redirect URL's
+	CallbackPortRange string
+
+	// JAR sends the authorization request parameters as a request object
+	// signed with a generated key (RFC 9101), for authorization servers
+	// that require JWT-secured authorization requests
+	JAR bool
+
+	// PrintRequestObject logs the claims and the compact JWT of each
+	// request object
+	PrintRequestObject bool
+
+	// TokenEndpointAuthMethod selects how the client authenticates at the
+	// token endpoint: "tls_client_auth" or "self_signed_tls_client_auth"
+	// with the --tls-cert client certificate (RFC 8705), or
+	// "private_key_jwt" with a signed client assertion (RFC 7523). Empty
+	// leaves it to the client secret, or none for public clients.
+	TokenEndpointAuthMethod string
+
+	// SigningKeyFile is a PEM private key signing client assertions and
+	// request objects; empty generates a key for the session
+	SigningKeyFile string
+
+	// RefreshBefore refreshes the access token this long before it expires,
+	// so refresh problems surface before a request fails. Zero leaves
+	// refreshing to mcp-go, which refreshes expired tokens on use.
+	RefreshBefore time.Duration
+
+	// TokenLogInterval logs the remaining lifetime of the access token at
+	// this interval; zero disables it
+	TokenLogInterval time.Duration
 }
 
 // DefaultOAuthConfig returns a default OAuth configuration
 func DefaultOAuthConfig() *OAuthConfig {
 	return &OAuthConfig{
 		Enabled:              false,
+		Flow:                 OAuthFlowAuthorizationCode,
 		Scopes:               []string{},
 		ScopeSelectionMode:   ScopeModeAuto, // Secure by default: follow MCP spec
 		RedirectURL:          DefaultRedirectURL,
@@ -164,6 +225,11 @@ func (c *OAuthConfig) WithDefaults() *OAuthConfig {
 		config.RedirectURL = DefaultRedirectURL
 	}
 
+	// Set default flow if not provided
+	if config.Flow == "" {
+		config.Flow = OAuthFlowAuthorizationCode
+	}
+
 	// Set default scope selection mode if not provided
 	if config.ScopeSelectionMode == "" {
 		config.ScopeSelectionMode = ScopeModeAuto
@@ -190,6 +256,11 @@ func (c *OAuthConfig) Validate() error {
 		return fmt.Errorf("invalid scope selection mode: %s (must be 'auto' or 'manual')", c.ScopeSelectionMode)
 	}
 
+	// Validate flow
+	if c.Flow != "" && c.Flow != OAuthFlowAuthorizationCode && c.Flow != OAuthFlowDevice {
+		return fmt.Errorf("invalid OAuth flow: %s (must be '%s' or '%s')", c.Flow, OAuthFlowAuthorizationCode, OAuthFlowDevice)
+	}
+
 	// RedirectURL is required for the callback server
 	if c.RedirectURL == "" {
 		return fmt.Errorf("OAuth redirect URL is required")
@@ -226,6 +297,40 @@ func (c *OAuthConfig) Validate() error {
 		return fmt.Errorf("OAuth authorization timeout is required")
 	}
 
+	if c.ManualCode && c.Flow == OAuthFlowDevice {
+		return fmt.Errorf("manual code entry only applies to the %s flow", OAuthFlowAuthorizationCode)
+	}
+
+	if c.JAR && c.Flow == OAuthFlowDevice {
+		return fmt.Errorf("signed authorization requests only apply to the %s flow", OAuthFlowAuthorizationCode)
+	}
+	if c.PrintRequestObject && !c.JAR {
+		return fmt.Errorf("printing the request object requires signed authorization requests (JAR)")
+	}
+
+	if c.TokenEndpointAuthMethod != "" {
+		if !isMTLSAuthMethod(c.TokenEndpointAuthMethod) && c.TokenEndpointAuthMethod != TokenAuthMethodPrivateKeyJWT {
+			return fmt.Errorf("invalid token endpoint authentication method: %s (must be '%s', '%s' or '%s')", c.TokenEndpointAuthMethod, TokenAuthMethodTLSClient, TokenAuthMethodSelfSignedTLSClient, TokenAuthMethodPrivateKeyJWT)
+		}
+		if c.ClientSecret != "" {
+			return fmt.Errorf("token endpoint authentication with %s does not use a client secret", c.TokenEndpointAuthMethod)
+		}
+	}
+
+	if c.SigningKeyFile != "" && !c.JAR && c.TokenEndpointAuthMethod != TokenAuthMethodPrivateKeyJWT {
+		return fmt.Errorf("a signing key requires signed authorization requests (JAR) or %s", TokenAuthMethodPrivateKeyJWT)
+	}
+
+	if c.RefreshBefore < 0 || c.TokenLogInterval < 0 {
+		return fmt.Errorf("token refresh margin and log interval must not be negative")
+	}
+
+	if c.CallbackPortRange != "" {
+		if _, _, err := ParsePortRange(c.CallbackPortRange); err != nil {
+			return fmt.Errorf("invalid callback port range: %w", err)
+		}
+	}
+
 	// Validate Client ID Metadata URL if provided
 	if c.ClientIDMetadataURL != "" {
 		if err := ValidateClientIDURL(c.ClientIDMetadataURL); err != nil {
@@ -167,6 +167,110 @@ func TestOAuthConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "manual code with the device flow",
+			config: &OAuthConfig{
+				Enabled:              true,
+				Flow:                 OAuthFlowDevice,
+				ManualCode:           true,
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "request object printing without JAR",
+			config: &OAuthConfig{
+				Enabled:              true,
+				PrintRequestObject:   true,
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "JAR with the device flow",
+			config: &OAuthConfig{
+				Enabled:              true,
+				Flow:                 OAuthFlowDevice,
+				JAR:                  true,
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "mTLS token endpoint authentication",
+			config: &OAuthConfig{
+				Enabled:                 true,
+				TokenEndpointAuthMethod: TokenAuthMethodSelfSignedTLSClient,
+				RedirectURL:             "http://localhost:8765/callback",
+				AuthorizationTimeout:    5 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "private_key_jwt with a signing key",
+			config: &OAuthConfig{
+				Enabled:                 true,
+				TokenEndpointAuthMethod: TokenAuthMethodPrivateKeyJWT,
+				SigningKeyFile:          "client-key.pem",
+				RedirectURL:             "http://localhost:8765/callback",
+				AuthorizationTimeout:    5 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "signing key without anything to sign",
+			config: &OAuthConfig{
+				Enabled:              true,
+				SigningKeyFile:       "client-key.pem",
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown token endpoint authentication method",
+			config: &OAuthConfig{
+				Enabled:                 true,
+				TokenEndpointAuthMethod: "client_secret_jwt",
+				RedirectURL:             "http://localhost:8765/callback",
+				AuthorizationTimeout:    5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "mTLS token endpoint authentication with a client secret",
+			config: &OAuthConfig{
+				Enabled:                 true,
+				ClientSecret:            "secret",
+				TokenEndpointAuthMethod: TokenAuthMethodTLSClient,
+				RedirectURL:             "http://localhost:8765/callback",
+				AuthorizationTimeout:    5 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid callback port range",
+			config: &OAuthConfig{
+				Enabled:              true,
+				CallbackPortRange:    "8000-8100",
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid callback port range",
+			config: &OAuthConfig{
+				Enabled:              true,
+				CallbackPortRange:    "8100-8000",
+				RedirectURL:          "http://localhost:8765/callback",
+				AuthorizationTimeout: 5 * time.Minute,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,227 @@
+// Package agent implements the OAuth 2.0 Device Authorization Grant per RFC 8628.
+//
+// The device flow lets mcp-debug authorize on headless machines (e.g. CI)
+// where no browser can reach a localhost callback: the user opens the
+// verification URI on any device and enters the displayed user code while
+// mcp-debug polls the token endpoint.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	// deviceCodeGrantType is the grant_type for polling the token endpoint
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// defaultDevicePollInterval applies when the AS does not specify one
+	// (RFC 8628 Section 3.2)
+	defaultDevicePollInterval = 5 * time.Second
+
+	// deviceSlowDownIncrement is added to the interval on slow_down
+	// (RFC 8628 Section 3.5)
+	deviceSlowDownIncrement = 5 * time.Second
+)
+
+// deviceAuthorizationResponse is the response of the device authorization
+// endpoint (RFC 8628 Section 3.2)
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
+
+// tokenResponse is a successful token endpoint response (RFC 6749 Section 5.1)
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// handleDeviceAuthorizationFlow obtains a token with the Device Authorization
+// Grant and stores it for mcp-go's OAuth handler, which then uses and
+// refreshes it like a token from the authorization code flow
+func (c *Client) handleDeviceAuthorizationFlow(ctx context.Context, oauthHandler *transport.OAuthHandler) error {
+	oauthConfig := c.oauthSettings()
+	c.logger.Info("OAuth authorization required (device flow)")
+
+	if err := c.ensureClientRegistered(ctx, oauthHandler); err != nil {
+		return err
+	}
+
+	serverMetadata, err := oauthHandler.GetServerMetadata(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get authorization server metadata: %w", err)
+	}
+
+	// mcp-go's metadata does not include the device endpoint, so discover the
+	// full RFC 8414 document for the same issuer
+	metadata, err := DiscoverAuthorizationServerMetadata(ctx, serverMetadata.Issuer, c.logger)
+	if err != nil {
+		return fmt.Errorf("failed to discover device authorization endpoint: %w", err)
+	}
+	if metadata.DeviceAuthorizationEndpoint == "" {
+		return fmt.Errorf("authorization server %s does not advertise a device_authorization_endpoint", metadata.Issuer)
+	}
+
+	params := url.Values{}
+	params.Set("client_id", oauthHandler.GetClientID())
+	if secret := oauthHandler.GetClientSecret(); secret != "" {
+		params.Set("client_secret", secret)
+	}
+	if !oauthConfig.SkipResourceParam && c.resourceURI != "" {
+		params.Set("resource", c.resourceURI)
+	}
+
+	authorization, err := requestDeviceAuthorization(ctx, metadata.DeviceAuthorizationEndpoint, params, c.requestedScopes)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("To authorize mcp-debug, visit: %s", authorization.VerificationURI)
+	c.logger.Info("And enter the code: %s", authorization.UserCode)
+	if authorization.VerificationURIComplete != "" {
+		c.logger.Info("Or open this URL directly: %s", authorization.VerificationURIComplete)
+	}
+	c.logger.Info("Waiting for authorization...")
+
+	// The device code expiry bounds polling, and the configured
+	// authorization timeout bounds it further
+	timeout := oauthConfig.AuthorizationTimeout
+	if expiresIn := time.Duration(authorization.ExpiresIn) * time.Second; expiresIn > 0 && (timeout == 0 || expiresIn < timeout) {
+		timeout = expiresIn
+	}
+	pollCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	params.Set("grant_type", deviceCodeGrantType)
+	params.Set("device_code", authorization.DeviceCode)
+
+	token, err := pollDeviceToken(pollCtx, serverMetadata.TokenEndpoint, params, interval)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("device authorization timed out after %v", timeout)
+		}
+		return err
+	}
+
+	if err := c.oauthTokenStore.SaveToken(ctx, token); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+
+	c.logger.Success("Access token obtained successfully!")
+	if token.Scope != "" {
+		c.logger.Info("Granted scopes: %s", token.Scope)
+	}
+	return nil
+}
+
+// requestDeviceAuthorization starts the device flow (RFC 8628 Section 3.1)
+func requestDeviceAuthorization(ctx context.Context, endpoint string, params url.Values, scopes []string) (*deviceAuthorizationResponse, error) {
+	form := url.Values{}
+	for key, values := range params {
+		form[key] = values
+	}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	status, body, err := postOAuthForm(ctx, endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("device authorization request failed: %w", parseOAuthError(status, body))
+	}
+
+	var authorization deviceAuthorizationResponse
+	if err := json.Unmarshal(body, &authorization); err != nil {
+		return nil, fmt.Errorf("failed to parse device authorization response: %w", err)
+	}
+	if authorization.DeviceCode == "" || authorization.UserCode == "" || authorization.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization response is missing device_code, user_code or verification_uri")
+	}
+	return &authorization, nil
+}
+
+// pollDeviceToken polls the token endpoint until the user has approved or
+// denied the request, the device code expires, or ctx is done
+// (RFC 8628 Section 3.4 and 3.5)
+func pollDeviceToken(ctx context.Context, tokenEndpoint string, form url.Values, interval time.Duration) (*client.Token, error) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		status, body, err := postOAuthForm(ctx, tokenEndpoint, form)
+		if err != nil {
+			return nil, fmt.Errorf("token request failed: %w", err)
+		}
+		if status == http.StatusOK {
+			return parseTokenResponse(body)
+		}
+
+		var oauthErr oauthErrorResponse
+		_ = json.Unmarshal(body, &oauthErr)
+		switch oauthErr.Error {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += deviceSlowDownIncrement
+			continue
+		case "access_denied":
+			return nil, fmt.Errorf("authorization was denied by the user")
+		case "expired_token":
+			return nil, fmt.Errorf("device code expired before authorization completed")
+		}
+		return nil, fmt.Errorf("token request failed: %w", parseOAuthError(status, body))
+	}
+}
+
+// parseTokenResponse converts a token endpoint response into an mcp-go token
+func parseTokenResponse(body []byte) (*client.Token, error) {
+	var resp tokenResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("token response is missing access_token")
+	}
+
+	token := &client.Token{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+		Scope:        resp.Scope,
+	}
+	if resp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
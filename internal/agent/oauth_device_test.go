@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestDeviceAuthorization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		if r.Form.Get("client_id") != "cli" || r.Form.Get("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"bad form"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"device_code":"dev","user_code":"ABCD-EFGH","verification_uri":"https://as.example.com/device","expires_in":600,"interval":1}`))
+	}))
+	defer server.Close()
+
+	params := url.Values{"client_id": {"cli"}}
+	auth, err := requestDeviceAuthorization(t.Context(), server.URL, params, []string{"read", "write"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if auth.UserCode != "ABCD-EFGH" || auth.DeviceCode != "dev" || auth.Interval != 1 {
+		t.Errorf("unexpected response: %+v", auth)
+	}
+	if params.Get("scope") != "" {
+		t.Error("expected shared params to be left unmodified")
+	}
+
+	_, err = requestDeviceAuthorization(t.Context(), server.URL, url.Values{"client_id": {"other"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid_request: bad form") {
+		t.Errorf("expected OAuth error, got %v", err)
+	}
+}
+
+func TestPollDeviceToken(t *testing.T) {
+	t.Run("polls until approved", func(t *testing.T) {
+		var polls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			if r.Form.Get("grant_type") != deviceCodeGrantType || r.Form.Get("device_code") != "dev" {
+				t.Errorf("unexpected form: %v", r.Form)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if polls.Add(1) < 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at","token_type":"Bearer","refresh_token":"rt","expires_in":3600,"scope":"read"}`))
+		}))
+		defer server.Close()
+
+		form := url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {"dev"}}
+		token, err := pollDeviceToken(t.Context(), server.URL, form, time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token.AccessToken != "at" || token.RefreshToken != "rt" || token.Scope != "read" || token.ExpiresAt.IsZero() {
+			t.Errorf("unexpected token: %+v", token)
+		}
+		if polls.Load() != 3 {
+			t.Errorf("expected 3 polls, got %d", polls.Load())
+		}
+	})
+
+	t.Run("stops on access_denied", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"access_denied"}`))
+		}))
+		defer server.Close()
+
+		_, err := pollDeviceToken(t.Context(), server.URL, url.Values{}, time.Millisecond)
+		if err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Errorf("expected denial error, got %v", err)
+		}
+	})
+}
+
+func TestOAuthConfigValidateFlow(t *testing.T) {
+	config := DefaultOAuthConfig()
+	config.Enabled = true
+	config.Flow = "implicit"
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for unknown flow")
+	}
+
+	config.Flow = OAuthFlowDevice
+	if err := config.Validate(); err != nil {
+		t.Errorf("unexpected error for device flow: %v", err)
+	}
+}
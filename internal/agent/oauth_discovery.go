@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
 // ProtectedResourceMetadata represents OAuth 2.0 Protected Resource Metadata
@@ -34,27 +33,24 @@ type ProtectedResourceMetadata struct {
 // WWWAuthenticateChallenge represents parsed WWW-Authenticate header information
 type WWWAuthenticateChallenge struct {
 	// Scheme is the authentication scheme (typically "Bearer")
-	Scheme string
+	Scheme string `json:"scheme"`
 
 	// ResourceMetadataURL is the URL to fetch protected resource metadata
-	ResourceMetadataURL string
+	ResourceMetadataURL string `json:"resource_metadata,omitempty"`
 
 	// Scopes are the required scopes for this resource/operation
-	Scopes []string
+	Scopes []string `json:"scope,omitempty"`
 
 	// Error indicates the error type (e.g., "insufficient_scope")
-	Error string
+	Error string `json:"error,omitempty"`
 
 	// ErrorDescription provides human-readable error details
-	ErrorDescription string
+	ErrorDescription string `json:"error_description,omitempty"`
 }
 
 const (
 	// Maximum size for metadata documents (1MB)
 	maxMetadataSize = 1024 * 1024
-
-	// HTTP timeout for metadata requests
-	metadataRequestTimeout = 10 * time.Second
 )
 
 // parseWWWAuthenticate parses a WWW-Authenticate header value and extracts
@@ -231,10 +227,7 @@ func buildWellKnownURIs(endpoint string) ([]string, error) {
 // fetchProtectedResourceMetadata fetches and parses protected resource metadata
 // from the specified URL.
 func fetchProtectedResourceMetadata(ctx context.Context, metadataURL string) (*ProtectedResourceMetadata, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: metadataRequestTimeout,
-	}
+	client := httpClientFor(ctx, metadataRequestTimeout)
 
 	// Create request with context
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
@@ -244,7 +237,6 @@ func fetchProtectedResourceMetadata(ctx context.Context, metadataURL string) (*P
 
 	// Set appropriate headers
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("User-Agent", "mcp-debug/1.0")
 
 	// Execute request
 	resp, err := client.Do(req)
@@ -0,0 +1,438 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Steps of the discovery chain a DiscoveryProbe belongs to
+const (
+	DiscoveryStepChallenge           = "challenge"
+	DiscoveryStepProtectedResource   = "protected-resource"
+	DiscoveryStepAuthorizationServer = "authorization-server"
+)
+
+// discoveryInitializeRequest is the unauthenticated initialize request sent
+// to provoke the 401 challenge of a protected MCP server
+const discoveryInitializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-11-25","capabilities":{},"clientInfo":{"name":"mcp-debug","version":"1.0"}}}`
+
+// DiscoveryProbe is one request of the discovery chain and its outcome
+type DiscoveryProbe struct {
+	// Step is the part of the chain the request belongs to
+	Step string `json:"step"`
+
+	// URL is the probed URL
+	URL string `json:"url"`
+
+	// Status is the HTTP status code, 0 if no response was received
+	Status int `json:"status,omitempty"`
+
+	// Error describes why the probe failed, empty on success
+	Error string `json:"error,omitempty"`
+
+	// Used is set on the probe whose document the configuration is derived from
+	Used bool `json:"used,omitempty"`
+
+	// Duration is the time until the probe completed, failed or was cancelled
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// DiscoveredConfig is the OAuth configuration mcp-debug would derive from
+// the discovered metadata
+type DiscoveredConfig struct {
+	// ResourceURI is the RFC 8707 resource parameter
+	ResourceURI string `json:"resource_uri,omitempty"`
+
+	// AuthorizationServer is the selected authorization server (issuer)
+	AuthorizationServer string `json:"authorization_server,omitempty"`
+
+	AuthorizationEndpoint       string `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint               string `json:"token_endpoint,omitempty"`
+	RegistrationEndpoint        string `json:"registration_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
+	// Scopes are the scopes requested in auto scope selection mode
+	Scopes []string `json:"scopes,omitempty"`
+
+	// PKCEMethods are the advertised code challenge methods
+	PKCEMethods []string `json:"pkce_methods,omitempty"`
+
+	// PKCE is set if the authorization server supports S256
+	PKCE bool `json:"pkce"`
+
+	// DynamicRegistration is set if the authorization server has a
+	// registration endpoint (RFC 7591)
+	DynamicRegistration bool `json:"dynamic_registration"`
+
+	// ClientIDMetadataDocument is set if the authorization server accepts
+	// Client ID Metadata Documents
+	ClientIDMetadataDocument bool `json:"client_id_metadata_document"`
+}
+
+// DiscoveryReport describes every step of OAuth discovery for an MCP
+// endpoint without authorizing
+type DiscoveryReport struct {
+	// Endpoint is the MCP endpoint discovery started from
+	Endpoint string `json:"endpoint"`
+
+	// WWWAuthenticate is the raw challenge header of the unauthenticated request
+	WWWAuthenticate string `json:"www_authenticate,omitempty"`
+
+	// Challenge is the parsed challenge, nil without a parseable header
+	Challenge *WWWAuthenticateChallenge `json:"challenge,omitempty"`
+
+	// Probes lists every request in priority order. Authorization server
+	// metadata endpoints are probed concurrently.
+	Probes []DiscoveryProbe `json:"probes"`
+
+	// ProtectedResource is the RFC 9728 metadata, nil if none was found
+	ProtectedResource *ProtectedResourceMetadata `json:"protected_resource,omitempty"`
+
+	// AuthorizationServer is the RFC 8414 metadata, nil if none was found
+	AuthorizationServer *AuthorizationServerMetadata `json:"authorization_server,omitempty"`
+
+	// Config is the configuration derived from the metadata
+	Config DiscoveredConfig `json:"config"`
+
+	// Problems lists the findings that would make authorization fail or
+	// need extra flags
+	Problems []string `json:"problems,omitempty"`
+}
+
+// DiscoverOAuth runs the OAuth discovery chain for an MCP endpoint the way
+// a connection with --oauth would: the unauthenticated request and its
+// WWW-Authenticate challenge, RFC 9728 Protected Resource Metadata, RFC 8414
+// Authorization Server Metadata and the PKCE, DCR and CIMD support derived
+// from it. Nothing is registered and no authorization is requested.
+//
+// Failed steps are recorded in the report rather than returned; the error
+// is only set if the endpoint URL is invalid.
+func DiscoverOAuth(ctx context.Context, endpoint, preferredAuthServer string) (*DiscoveryReport, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("endpoint URL must include scheme and host")
+	}
+
+	report := &DiscoveryReport{Endpoint: endpoint}
+	report.probeChallenge(ctx)
+	report.probeProtectedResource(ctx)
+
+	// Without protected resource metadata, mcp-go treats the origin of the
+	// endpoint as the authorization server
+	issuer := fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host)
+	if report.ProtectedResource != nil {
+		issuer, err = selectAuthorizationServer(report.ProtectedResource, preferredAuthServer)
+		if err != nil {
+			report.problem("%v", err)
+			issuer = ""
+		}
+	}
+	if issuer != "" {
+		report.Config.AuthorizationServer = issuer
+		report.probeAuthorizationServer(ctx, issuer)
+	}
+
+	report.deriveConfig()
+	return report, nil
+}
+
+// probeChallenge sends an unauthenticated initialize request and records
+// the WWW-Authenticate challenge of the response
+func (r *DiscoveryReport) probeChallenge(ctx context.Context) {
+	probe := DiscoveryProbe{Step: DiscoveryStepChallenge, URL: r.Endpoint}
+	start := time.Now()
+	defer func() {
+		probe.Duration = time.Since(start)
+		r.Probes = append(r.Probes, probe)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Endpoint, strings.NewReader(discoveryInitializeRequest))
+	if err != nil {
+		probe.Error = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := httpClientFor(ctx, metadataRequestTimeout).Do(req)
+	if err != nil {
+		probe.Error = fmt.Sprintf("request failed: %v", err)
+		r.problem("the endpoint could not be reached")
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	probe.Status = resp.StatusCode
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		probe.Error = fmt.Sprintf("expected status %d for an unauthenticated request", http.StatusUnauthorized)
+		r.problem("the endpoint did not require authorization (status %d)", resp.StatusCode)
+	}
+
+	r.WWWAuthenticate = resp.Header.Get("WWW-Authenticate")
+	if r.WWWAuthenticate == "" {
+		if resp.StatusCode == http.StatusUnauthorized {
+			probe.Error = "no WWW-Authenticate header"
+			r.problem("the 401 response has no WWW-Authenticate header")
+		}
+		return
+	}
+	challenge, err := parseWWWAuthenticate(r.WWWAuthenticate)
+	if err != nil {
+		probe.Error = fmt.Sprintf("invalid WWW-Authenticate header: %v", err)
+		return
+	}
+	r.Challenge = challenge
+	probe.Used = true
+}
+
+// probeProtectedResource fetches RFC 9728 metadata from the challenge's
+// resource_metadata URL, or else from the well-known URIs in priority order
+func (r *DiscoveryReport) probeProtectedResource(ctx context.Context) {
+	var uris []string
+	if r.Challenge != nil && r.Challenge.ResourceMetadataURL != "" {
+		uris = []string{r.Challenge.ResourceMetadataURL}
+	} else {
+		var err error
+		if uris, err = buildWellKnownURIs(r.Endpoint); err != nil {
+			r.problem("failed to build well-known URIs: %v", err)
+			return
+		}
+	}
+
+	for _, uri := range uris {
+		var metadata ProtectedResourceMetadata
+		probe := DiscoveryProbe{Step: DiscoveryStepProtectedResource, URL: uri}
+		start := time.Now()
+		status, err := getDiscoveryDocument(ctx, uri, &metadata)
+		probe.Status = status
+		probe.Duration = time.Since(start)
+		if err == nil {
+			err = validateProtectedResourceMetadata(&metadata)
+		}
+		if err != nil {
+			probe.Error = err.Error()
+			r.Probes = append(r.Probes, probe)
+			continue
+		}
+		probe.Used = true
+		r.Probes = append(r.Probes, probe)
+		r.ProtectedResource = &metadata
+		return
+	}
+	r.problem("no protected resource metadata found; the endpoint's origin is used as the authorization server")
+}
+
+// probeAuthorizationServer fetches RFC 8414 or OIDC discovery metadata of
+// the issuer with probeInPriorityOrder: the first endpoint that succeeds is
+// used unless one ahead of it in priority order succeeds within the grace
+// window, and the probes still running are cancelled.
+func (r *DiscoveryReport) probeAuthorizationServer(ctx context.Context, issuer string) {
+	endpoints, err := buildASMetadataEndpoints(issuer)
+	if err != nil {
+		r.problem("failed to build authorization server metadata endpoints: %v", err)
+		return
+	}
+
+	// probeInPriorityOrder returns without waiting for cancelled probes;
+	// wait for them so every probe is recorded
+	probes := make([]DiscoveryProbe, len(endpoints))
+	var wg sync.WaitGroup
+	wg.Add(len(endpoints))
+	metadata, index, _ := probeInPriorityOrder(ctx, len(endpoints), func(probeCtx context.Context, i int) (*AuthorizationServerMetadata, error) {
+		defer wg.Done()
+		probe := DiscoveryProbe{Step: DiscoveryStepAuthorizationServer, URL: endpoints[i]}
+		defer func() { probes[i] = probe }()
+
+		var metadata AuthorizationServerMetadata
+		start := time.Now()
+		status, err := getDiscoveryDocument(probeCtx, endpoints[i], &metadata)
+		probe.Status = status
+		probe.Duration = time.Since(start)
+		if err == nil {
+			err = validateASMetadata(&metadata)
+		}
+		if err != nil {
+			probe.Error = err.Error()
+			if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+				probe.Error = "cancelled: another endpoint succeeded first"
+			}
+			return nil, err
+		}
+		return &metadata, nil
+	})
+	wg.Wait()
+
+	if index >= 0 {
+		probes[index].Used = true
+	}
+	r.Probes = append(r.Probes, probes...)
+	if metadata == nil {
+		r.problem("no authorization server metadata found for %s", issuer)
+		return
+	}
+	r.AuthorizationServer = metadata
+}
+
+// deriveConfig fills in the configuration a connection would use and
+// records the problems it would run into
+func (r *DiscoveryReport) deriveConfig() {
+	derived, _ := deriveResourceURI(r.Endpoint)
+	r.Config.ResourceURI = derived
+	if r.ProtectedResource != nil {
+		r.Config.ResourceURI = r.ProtectedResource.Resource
+		if derived != "" && derived != r.Config.ResourceURI {
+			r.problem("the metadata's resource %s differs from the endpoint's resource URI %s", r.Config.ResourceURI, derived)
+		}
+	}
+
+	r.Config.Scopes = selectScopes(&OAuthConfig{ScopeSelectionMode: ScopeModeAuto}, r.Challenge, r.ProtectedResource, nil)
+
+	as := r.AuthorizationServer
+	if as == nil {
+		return
+	}
+	r.Config.AuthorizationEndpoint = as.AuthorizationEndpoint
+	r.Config.TokenEndpoint = as.TokenEndpoint
+	r.Config.RegistrationEndpoint = as.RegistrationEndpoint
+	r.Config.DeviceAuthorizationEndpoint = as.DeviceAuthorizationEndpoint
+	r.Config.PKCEMethods = as.CodeChallengeMethods
+	r.Config.DynamicRegistration = as.RegistrationEndpoint != ""
+	r.Config.ClientIDMetadataDocument = SupportsClientIDMetadata(as)
+
+	if err := ValidatePKCESupport(as, false, nil); err != nil {
+		r.problem("%v", err)
+	} else {
+		r.Config.PKCE = true
+	}
+	if strings.TrimSuffix(as.Issuer, "/") != strings.TrimSuffix(r.Config.AuthorizationServer, "/") {
+		r.problem("the metadata's issuer %s differs from the authorization server %s", as.Issuer, r.Config.AuthorizationServer)
+	}
+	if !r.Config.DynamicRegistration && !r.Config.ClientIDMetadataDocument {
+		r.problem("neither dynamic client registration nor Client ID Metadata Documents are supported; --oauth-client-id is required")
+	}
+}
+
+// problem records a finding of the report
+func (r *DiscoveryReport) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// Err returns an error if discovery did not yield the metadata needed to
+// authorize, nil otherwise
+func (r *DiscoveryReport) Err() error {
+	if r.AuthorizationServer == nil {
+		return errors.New("OAuth discovery failed: no authorization server metadata found")
+	}
+	return nil
+}
+
+// getDiscoveryDocument fetches a JSON metadata document into v and returns
+// the HTTP status, applying the checks of the metadata fetchers
+func getDiscoveryDocument(ctx context.Context, documentURL string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClientFor(ctx, metadataRequestTimeout).Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(strings.ToLower(contentType), "application/json") {
+		return resp.StatusCode, fmt.Errorf("unexpected Content-Type: %s (expected application/json)", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(body)) >= maxMetadataSize {
+		return resp.StatusCode, fmt.Errorf("response exceeds maximum size of %d bytes", maxMetadataSize)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// WriteDiscoveryReport writes the report as text: the probes, the derived
+// configuration and the problems found
+func WriteDiscoveryReport(w io.Writer, r *DiscoveryReport) {
+	_, _ = fmt.Fprintf(w, "OAuth discovery for %s\n\n", r.Endpoint)
+
+	_, _ = fmt.Fprintln(w, "Probes:")
+	for _, p := range r.Probes {
+		status := "---"
+		if p.Status != 0 {
+			status = fmt.Sprintf("%d", p.Status)
+		}
+		marker := " "
+		if p.Used {
+			marker = "*"
+		}
+		_, _ = fmt.Fprintf(w, "  %s %-21s %s %7s  %s\n", marker, p.Step, status, fmt.Sprintf("%dms", p.Duration.Milliseconds()), p.URL)
+		if p.Error != "" {
+			_, _ = fmt.Fprintf(w, "      %s\n", p.Error)
+		}
+	}
+	if r.WWWAuthenticate != "" {
+		_, _ = fmt.Fprintf(w, "\nWWW-Authenticate: %s\n", r.WWWAuthenticate)
+	}
+
+	c := r.Config
+	_, _ = fmt.Fprintln(w, "\nDerived configuration:")
+	for _, field := range []struct{ name, value string }{
+		{"Resource URI", c.ResourceURI},
+		{"Authorization server", c.AuthorizationServer},
+		{"Authorization endpoint", c.AuthorizationEndpoint},
+		{"Token endpoint", c.TokenEndpoint},
+		{"Registration endpoint", c.RegistrationEndpoint},
+		{"Device endpoint", c.DeviceAuthorizationEndpoint},
+		{"Scopes", strings.Join(c.Scopes, " ")},
+		{"PKCE methods", strings.Join(c.PKCEMethods, ", ")},
+		{"PKCE (S256)", yesNo(c.PKCE)},
+		{"Dynamic registration", yesNo(c.DynamicRegistration)},
+		{"Client ID metadata", yesNo(c.ClientIDMetadataDocument)},
+	} {
+		value := field.value
+		if value == "" {
+			value = "-"
+		}
+		_, _ = fmt.Fprintf(w, "  %-24s%s\n", field.name+":", value)
+	}
+
+	if len(r.Problems) == 0 {
+		_, _ = fmt.Fprintln(w, "\nNo problems found.")
+		return
+	}
+	_, _ = fmt.Fprintln(w, "\nProblems:")
+	for _, problem := range r.Problems {
+		_, _ = fmt.Fprintf(w, "  - %s\n", problem)
+	}
+}
+
+// yesNo formats a supported feature for the text report
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
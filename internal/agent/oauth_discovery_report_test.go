@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDiscoveryServer returns a protected MCP server that serves protected
+// resource metadata at the root well-known URI and OIDC metadata, so the
+// higher-priority probes of both steps fail
+func newDiscoveryServer(t *testing.T, withRegistration bool) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mcp":
+			w.Header().Set("WWW-Authenticate", `Bearer scope="files:read"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/.well-known/oauth-protected-resource":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(ProtectedResourceMetadata{
+				Resource:             server.URL + "/mcp",
+				AuthorizationServers: []string{server.URL},
+				ScopesSupported:      []string{"files:read", "files:write"},
+			})
+		case "/.well-known/openid-configuration":
+			metadata := AuthorizationServerMetadata{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+				CodeChallengeMethods:  []string{"S256"},
+			}
+			if withRegistration {
+				metadata.RegistrationEndpoint = server.URL + "/register"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(metadata)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscoverOAuth(t *testing.T) {
+	server := newDiscoveryServer(t, true)
+
+	report, err := DiscoverOAuth(context.Background(), server.URL+"/mcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatalf("expected discovery to succeed, got %v (problems: %v)", err, report.Problems)
+	}
+
+	want := []DiscoveryProbe{
+		{Step: DiscoveryStepChallenge, URL: server.URL + "/mcp", Status: 401, Used: true},
+		{Step: DiscoveryStepProtectedResource, URL: server.URL + "/.well-known/oauth-protected-resource/mcp", Status: 404, Error: "request failed with status 404"},
+		{Step: DiscoveryStepProtectedResource, URL: server.URL + "/.well-known/oauth-protected-resource", Status: 200, Used: true},
+		{Step: DiscoveryStepAuthorizationServer, URL: server.URL + "/.well-known/oauth-authorization-server", Status: 404, Error: "request failed with status 404"},
+		{Step: DiscoveryStepAuthorizationServer, URL: server.URL + "/.well-known/openid-configuration", Status: 200, Used: true},
+	}
+	if len(report.Probes) != len(want) {
+		t.Fatalf("got %d probes, want %d: %+v", len(report.Probes), len(want), report.Probes)
+	}
+	for i, probe := range report.Probes {
+		if probe.Duration < 0 {
+			t.Errorf("probe %d has negative duration %s", i, probe.Duration)
+		}
+		probe.Duration = 0
+		if probe != want[i] {
+			t.Errorf("probe %d = %+v, want %+v", i, probe, want[i])
+		}
+	}
+
+	config := report.Config
+	if config.ResourceURI != server.URL+"/mcp" || config.AuthorizationServer != server.URL {
+		t.Errorf("unexpected resource or authorization server: %+v", config)
+	}
+	if config.TokenEndpoint != server.URL+"/token" || !config.PKCE || !config.DynamicRegistration || config.ClientIDMetadataDocument {
+		t.Errorf("unexpected derived configuration: %+v", config)
+	}
+	if len(config.Scopes) != 1 || config.Scopes[0] != "files:read" {
+		t.Errorf("expected the challenge's scope to take priority, got %v", config.Scopes)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("expected no problems, got %v", report.Problems)
+	}
+}
+
+func TestDiscoverOAuthReportsProblems(t *testing.T) {
+	server := newDiscoveryServer(t, false)
+
+	report, err := DiscoverOAuth(context.Background(), server.URL+"/mcp", "https://other.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Err() == nil {
+		t.Error("expected an error without authorization server metadata")
+	}
+	if len(report.Problems) == 0 || !strings.Contains(report.Problems[0], "https://other.example.com") {
+		t.Errorf("expected the unknown preferred server to be reported, got %v", report.Problems)
+	}
+
+	report, err = DiscoverOAuth(context.Background(), server.URL+"/mcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "--oauth-client-id") {
+		t.Errorf("expected the missing registration support to be reported, got %v", report.Problems)
+	}
+}
+
+func TestDiscoverOAuthProbesASMetadataConcurrently(t *testing.T) {
+	// newServer serves the OIDC document at once and the RFC 8414 one,
+	// which comes first in priority order, after delay or never
+	newServer := func(delay time.Duration) *httptest.Server {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/.well-known/oauth-authorization-server":
+				if delay == 0 {
+					<-r.Context().Done()
+					return
+				}
+				time.Sleep(delay)
+			case "/.well-known/openid-configuration":
+			default:
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(AuthorizationServerMetadata{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + r.URL.Path + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+				CodeChallengeMethods:  []string{"S256"},
+			})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("hanging endpoint does not delay the result", func(t *testing.T) {
+		server := newServer(0)
+		report := &DiscoveryReport{Endpoint: server.URL + "/mcp"}
+		start := time.Now()
+		report.probeAuthorizationServer(t.Context(), server.URL)
+		if elapsed := time.Since(start); elapsed > probeGraceWindow+testTimeoutNormal/2 {
+			t.Errorf("report took %v, want about the grace window", elapsed)
+		}
+
+		if report.AuthorizationServer == nil || len(report.Probes) != 2 {
+			t.Fatalf("unexpected result: %+v", report.Probes)
+		}
+		first, second := report.Probes[0], report.Probes[1]
+		if first.Used || first.Error != "cancelled: another endpoint succeeded first" || first.Duration < probeGraceWindow {
+			t.Errorf("unexpected first probe %+v", first)
+		}
+		if !second.Used || second.Status != http.StatusOK || second.Duration >= first.Duration {
+			t.Errorf("unexpected second probe %+v", second)
+		}
+	})
+
+	t.Run("higher priority wins within the grace window", func(t *testing.T) {
+		server := newServer(probeGraceWindow / 4)
+		report := &DiscoveryReport{Endpoint: server.URL + "/mcp"}
+		report.probeAuthorizationServer(t.Context(), server.URL)
+
+		if report.AuthorizationServer == nil || len(report.Probes) != 2 || !report.Probes[0].Used || report.Probes[1].Used {
+			t.Fatalf("expected the higher-priority endpoint to be used: %+v", report.Probes)
+		}
+		if !strings.Contains(report.AuthorizationServer.AuthorizationEndpoint, "oauth-authorization-server") {
+			t.Errorf("unexpected metadata %+v", report.AuthorizationServer)
+		}
+	})
+}
+
+func TestDiscoverOAuthCancelsLowerPriorityProbes(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(AuthorizationServerMetadata{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+				CodeChallengeMethods:  []string{"S256"},
+			})
+		default:
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	defer server.Close()
+
+	report := &DiscoveryReport{Endpoint: server.URL + "/mcp"}
+	report.probeAuthorizationServer(t.Context(), server.URL)
+
+	if report.AuthorizationServer == nil || len(report.Probes) != 2 || !report.Probes[0].Used {
+		t.Fatalf("unexpected result: %+v", report.Probes)
+	}
+	if report.Probes[1].Error != "cancelled: another endpoint succeeded first" {
+		t.Errorf("expected the hanging probe to be cancelled, got %+v", report.Probes[1])
+	}
+}
+
+func TestDiscoverOAuthUnprotectedEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	report, err := DiscoverOAuth(context.Background(), server.URL+"/mcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Probes[0].Status != http.StatusNotFound || report.Probes[0].Used {
+		t.Errorf("unexpected challenge probe: %+v", report.Probes[0])
+	}
+	if report.ProtectedResource != nil || report.AuthorizationServer != nil || report.Err() == nil {
+		t.Error("expected discovery to fail without metadata")
+	}
+	// The origin is probed as the authorization server like mcp-go does
+	if report.Config.AuthorizationServer != server.URL {
+		t.Errorf("expected the origin as authorization server, got %q", report.Config.AuthorizationServer)
+	}
+
+	if _, err := DiscoverOAuth(context.Background(), "/mcp", ""); err == nil {
+		t.Error("expected an error for an endpoint without scheme and host")
+	}
+}
+
+func TestWriteDiscoveryReport(t *testing.T) {
+	server := newDiscoveryServer(t, true)
+	report, err := DiscoverOAuth(context.Background(), server.URL+"/mcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range report.Probes {
+		report.Probes[i].Duration = time.Duration(i+1) * 12 * time.Millisecond
+	}
+
+	var buf bytes.Buffer
+	WriteDiscoveryReport(&buf, report)
+	for _, want := range []string{
+		"* challenge             401    12ms  " + server.URL + "/mcp",
+		"  protected-resource    404    24ms  " + server.URL + "/.well-known/oauth-protected-resource/mcp\n      request failed with status 404",
+		"WWW-Authenticate: Bearer scope=\"files:read\"",
+		"Token endpoint:         " + server.URL + "/token",
+		"PKCE (S256):            yes",
+		"Client ID metadata:     no",
+		"No problems found.",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
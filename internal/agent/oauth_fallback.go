@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// errOAuthAuthorizationFailed marks errors of the authorization flow itself,
+// as opposed to the request that needed authorization
+var errOAuthAuthorizationFailed = errors.New("OAuth authorization failed")
+
+// AuthServerAttempt records an authorization server tried while connecting
+type AuthServerAttempt struct {
+	// Server is the issuer URL from the protected resource metadata
+	Server string
+	// Reason is why the server was skipped; empty for the server in use
+	Reason string
+}
+
+// authServerFallback walks the authorization servers of the protected
+// resource metadata: a server whose discovery or token issuance fails is
+// skipped and the next one in the list is tried
+type authServerFallback struct {
+	mu sync.Mutex
+	// attempts lists the servers tried since the connection attempt began
+	attempts []AuthServerAttempt
+	// current is the server the OAuth handler was configured with
+	current string
+}
+
+// reset forgets the servers tried by a previous connection attempt
+func (f *authServerFallback) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts = nil
+	f.current = ""
+}
+
+// tried reports whether server was already skipped
+func (f *authServerFallback) tried(server string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.ContainsFunc(f.attempts, func(a AuthServerAttempt) bool {
+		return a.Server == server && a.Reason != ""
+	})
+}
+
+// skip records that server is not used and why
+func (f *authServerFallback) skip(server, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, attempt := range f.attempts {
+		if attempt.Server == server {
+			f.attempts[i].Reason = reason
+			return
+		}
+	}
+	f.attempts = append(f.attempts, AuthServerAttempt{Server: server, Reason: reason})
+}
+
+// use records that the OAuth handler is configured with server
+func (f *authServerFallback) use(server string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current = server
+	f.attempts = append(f.attempts, AuthServerAttempt{Server: server})
+}
+
+// failCurrent skips the server in use because of err and reports whether
+// there is one to fall back to, given the candidates in order
+func (f *authServerFallback) failCurrent(err error, candidates []string) bool {
+	f.mu.Lock()
+	current := f.current
+	f.current = ""
+	f.mu.Unlock()
+	if current == "" {
+		return false
+	}
+	f.skip(current, fmt.Sprintf("token issuance failed: %v", err))
+	return slices.ContainsFunc(candidates, func(server string) bool { return !f.tried(server) })
+}
+
+// snapshot returns the servers tried so far
+func (f *authServerFallback) snapshot() []AuthServerAttempt {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.attempts)
+}
+
+// AuthServerAttempts returns the authorization servers tried during the last
+// connection attempt, in order, with the reasons the skipped ones failed
+func (c *Client) AuthServerAttempts() []AuthServerAttempt {
+	return c.authServers.snapshot()
+}
+
+// orderAuthServers returns the authorization servers in the order they are
+// tried: the preferred one first, then the others as listed (RFC 9728
+// Section 3 leaves the choice to the client)
+func orderAuthServers(metadata *ProtectedResourceMetadata, preferredServer string) ([]string, error) {
+	first, err := selectAuthorizationServer(metadata, preferredServer)
+	if err != nil {
+		return nil, err
+	}
+	ordered := []string{first}
+	for _, server := range metadata.AuthorizationServers {
+		if !slices.Contains(ordered, server) {
+			ordered = append(ordered, server)
+		}
+	}
+	return ordered, nil
+}
+
+// selectAuthServerWithFallback discovers the metadata of the candidates in
+// order and returns the first server that answers, with its metadata URL.
+// Servers that already failed during this connection attempt are skipped.
+func (c *Client) selectAuthServerWithFallback(ctx context.Context, candidates []string) (string, string, error) {
+	for _, server := range candidates {
+		if c.authServers.tried(server) {
+			continue
+		}
+		_, metadataURL, err := discoverASMetadata(ctx, server, c.logger)
+		if err != nil {
+			c.logger.Warning("Authorization server %s skipped: %v", server, err)
+			c.authServers.skip(server, fmt.Sprintf("discovery failed: %v", err))
+			continue
+		}
+		c.authServers.use(server)
+		c.logAuthServerAttempts()
+		return server, metadataURL, nil
+	}
+	c.logAuthServerAttempts()
+	return "", "", errors.New("no authorization server could be discovered")
+}
+
+// nextAuthServerAfter reports whether a failed authorization should be
+// retried with the next authorization server. Failures caused by the user
+// (cancelling, timing out, aborting a step) are not.
+func (c *Client) nextAuthServerAfter(err error) bool {
+	if err == nil || len(c.authServerCandidates) < 2 {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errStepModeAborted) {
+		return false
+	}
+	if !errors.Is(err, errOAuthAuthorizationFailed) {
+		return false
+	}
+	return c.authServers.failCurrent(err, c.authServerCandidates)
+}
+
+// retryWithNextAuthServer connects again with the next authorization server
+// if err is a failed authorization and one is left; otherwise it returns err
+func (c *Client) retryWithNextAuthServer(ctx context.Context, resume *sessionState, err error) error {
+	if !c.nextAuthServerAfter(err) {
+		if errors.Is(err, errOAuthAuthorizationFailed) {
+			c.logAuthServerAttempts()
+		}
+		return err
+	}
+	c.logger.Warning("Authorization failed, trying the next authorization server: %v", err)
+	if resume != nil && c.sessions != nil {
+		c.closeKeepingSession()
+	} else {
+		_ = c.mcpClient().Close()
+	}
+	return c.connectSession(ctx, resume)
+}
+
+// logAuthServerAttempts logs which authorization servers were tried and
+// why they were skipped, once more than one was tried or none worked
+func (c *Client) logAuthServerAttempts() {
+	attempts := c.authServers.snapshot()
+	if len(attempts) == 0 || (len(attempts) == 1 && attempts[0].Reason == "") {
+		return
+	}
+	c.logger.Info("Authorization servers attempted:")
+	for _, attempt := range attempts {
+		if attempt.Reason == "" {
+			c.logger.Info("  ✓ %s (in use)", attempt.Server)
+		} else {
+			c.logger.Info("  ✗ %s - %s", attempt.Server, attempt.Reason)
+		}
+	}
+}
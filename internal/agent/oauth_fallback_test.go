@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOrderAuthServers(t *testing.T) {
+	metadata := &ProtectedResourceMetadata{AuthorizationServers: []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"}}
+
+	got, err := orderAuthServers(metadata, "https://b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://b.example.com", "https://a.example.com", "https://c.example.com"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := orderAuthServers(metadata, "https://other.example.com"); err == nil {
+		t.Error("expected an error for a preferred server that is not listed")
+	}
+	if _, err := orderAuthServers(&ProtectedResourceMetadata{}, ""); err == nil {
+		t.Error("expected an error without authorization servers")
+	}
+}
+
+func TestSelectAuthServerWithFallback(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"issuer":"https://auth.example.com","authorization_endpoint":"https://auth.example.com/authorize","token_endpoint":"https://auth.example.com/token","code_challenge_methods_supported":["S256"]}`)
+	}))
+	defer up.Close()
+
+	var logs bytes.Buffer
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.logger = NewLoggerWithWriter(false, false, false, &logs)
+
+	server, metadataURL, err := c.selectAuthServerWithFallback(t.Context(), []string{down.URL, up.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server != up.URL || metadataURL != up.URL+"/.well-known/oauth-authorization-server" {
+		t.Errorf("got %s (%s), want the second server", server, metadataURL)
+	}
+
+	attempts := c.AuthServerAttempts()
+	if len(attempts) != 2 || attempts[0].Server != down.URL || !strings.HasPrefix(attempts[0].Reason, "discovery failed") || attempts[1] != (AuthServerAttempt{Server: up.URL}) {
+		t.Errorf("unexpected attempts %+v", attempts)
+	}
+	for _, want := range []string{"Authorization servers attempted:", "✗ " + down.URL + " - discovery failed", "✓ " + up.URL + " (in use)"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("summary missing %q:\n%s", want, logs.String())
+		}
+	}
+
+	if _, _, err := c.selectAuthServerWithFallback(t.Context(), []string{down.URL}); err == nil {
+		t.Error("expected an error once every server failed")
+	}
+}
+
+func TestNextAuthServerAfterTokenFailure(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.authServerCandidates = []string{"https://a.example.com", "https://b.example.com"}
+	c.authServers.use("https://a.example.com")
+
+	for _, err := range []error{
+		errors.New("connection refused"),
+		fmt.Errorf("%w: %w", errOAuthAuthorizationFailed, context.Canceled),
+		fmt.Errorf("%w: %w", errOAuthAuthorizationFailed, errStepModeAborted),
+	} {
+		if c.nextAuthServerAfter(err) {
+			t.Errorf("expected no fallback after %v", err)
+		}
+	}
+
+	if !c.nextAuthServerAfter(fmt.Errorf("%w: invalid_client", errOAuthAuthorizationFailed)) {
+		t.Fatal("expected a fallback after a failed token request")
+	}
+	attempts := c.AuthServerAttempts()
+	if len(attempts) != 1 || !strings.Contains(attempts[0].Reason, "token issuance failed") || !strings.Contains(attempts[0].Reason, "invalid_client") {
+		t.Errorf("unexpected attempts %+v", attempts)
+	}
+
+	c.authServers.use("https://b.example.com")
+	if c.nextAuthServerAfter(fmt.Errorf("%w: invalid_client", errOAuthAuthorizationFailed)) {
+		t.Error("expected no fallback once every server failed")
+	}
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sharedOAuthTransport is the connection-pooled transport used by every OAuth
+// related request (discovery, CIMD, token and registration), so repeated
+// requests to the same authorization server reuse TCP and TLS sessions and
+// all of them honor the same proxy and TLS settings
+var sharedOAuthTransport = newHTTPTransport()
+
+// maxOAuthResponseSize bounds token, device and revocation endpoint responses
+const maxOAuthResponseSize = 1024 * 1024
+
+// oauthErrorResponse is the error body of OAuth endpoints (RFC 6749 Section 5.2)
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// postOAuthForm sends an application/x-www-form-urlencoded POST to an OAuth
+// endpoint and returns the status code and the size-limited response body
+func postOAuthForm(ctx context.Context, endpoint string, form url.Values) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClientFor(ctx, oauthRequestTimeout).Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOAuthResponseSize))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// parseOAuthError extracts the OAuth error code and description from a
+// response body, falling back to the HTTP status
+func parseOAuthError(status int, body []byte) error {
+	var oauthErr oauthErrorResponse
+	if err := json.Unmarshal(body, &oauthErr); err != nil || oauthErr.Error == "" {
+		return fmt.Errorf("request failed with status %d", status)
+	}
+	if oauthErr.ErrorDescription != "" {
+		return fmt.Errorf("%s: %s", oauthErr.Error, oauthErr.ErrorDescription)
+	}
+	return errors.New(oauthErr.Error)
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOAuthHTTPClientReusesConnections(t *testing.T) {
+	var newConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	// Clients with different timeouts still share the pooled transport
+	a := httpClientFor(t.Context(), metadataRequestTimeout)
+	b := httpClientFor(t.Context(), oauthRequestTimeout)
+
+	for _, c := range []*http.Client{a, b, a} {
+		resp, err := c.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	if got := newConns.Load(); got != 1 {
+		t.Errorf("expected 1 connection to be reused, got %d", got)
+	}
+	if sharedOAuthTransport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Error("expected TLS 1.2 minimum on the shared transport")
+	}
+}
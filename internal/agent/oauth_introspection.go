@@ -0,0 +1,103 @@
+// Package agent implements OAuth 2.0 Token Introspection per RFC 7662.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenIntrospection is the introspection response for a token
+// (RFC 7662 Section 2.2)
+type TokenIntrospection struct {
+	// Active reports whether the token is currently valid
+	Active bool `json:"active"`
+	// Scope is the space-separated list of scopes of the token
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	// Exp, Iat and Nbf are Unix timestamps
+	Exp int64  `json:"exp,omitempty"`
+	Iat int64  `json:"iat,omitempty"`
+	Nbf int64  `json:"nbf,omitempty"`
+	Sub string `json:"sub,omitempty"`
+	// Aud is a single audience string or an array of them
+	Aud json.RawMessage `json:"aud,omitempty"`
+	Iss string          `json:"iss,omitempty"`
+}
+
+// Scopes returns the token's scopes as a list
+func (t *TokenIntrospection) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// ExpiresAt returns the expiry time, or the zero time if none was reported
+func (t *TokenIntrospection) ExpiresAt() time.Time {
+	if t.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t.Exp, 0)
+}
+
+// Audiences returns the audiences the token is intended for
+func (t *TokenIntrospection) Audiences() []string {
+	if len(t.Aud) == 0 {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(t.Aud, &single); err == nil {
+		return []string{single}
+	}
+	var multiple []string
+	_ = json.Unmarshal(t.Aud, &multiple)
+	return multiple
+}
+
+// IntrospectToken asks the authorization server's introspection endpoint
+// about the current access token. It tells whether a rejected token is
+// inactive, lacks scopes or was issued for another audience.
+func (c *Client) IntrospectToken(ctx context.Context) (*TokenIntrospection, error) {
+	handler, metadata, err := c.authServerMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.IntrospectionEndpoint == "" {
+		return nil, fmt.Errorf("authorization server does not advertise an introspection_endpoint")
+	}
+
+	token, err := c.oauthTokenStore.GetToken(ctx)
+	if err != nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("no access token to introspect")
+	}
+
+	return introspectToken(ctx, metadata.IntrospectionEndpoint, token.AccessToken, oauthClientAuthParams(handler))
+}
+
+// introspectToken sends an introspection request (RFC 7662 Section 2.1)
+func introspectToken(ctx context.Context, endpoint, token string, clientAuth url.Values) (*TokenIntrospection, error) {
+	form := url.Values{}
+	for key, values := range clientAuth {
+		form[key] = values
+	}
+	form.Set("token", token)
+	form.Set("token_type_hint", tokenTypeHintAccessToken)
+
+	status, body, err := postOAuthForm(ctx, endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("introspection request failed: %w", parseOAuthError(status, body))
+	}
+
+	var introspection TokenIntrospection
+	if err := json.Unmarshal(body, &introspection); err != nil {
+		return nil, fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+	return &introspection, nil
+}
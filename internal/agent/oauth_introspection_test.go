@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestIntrospectToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("token") {
+		case "active":
+			_, _ = w.Write([]byte(`{"active":true,"scope":"read write","client_id":"cli","exp":1700000000,"aud":["https://mcp.example.com","other"]}`))
+		case "single-aud":
+			_, _ = w.Write([]byte(`{"active":true,"aud":"https://mcp.example.com"}`))
+		default:
+			_, _ = w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	defer server.Close()
+
+	clientAuth := url.Values{"client_id": {"cli"}}
+
+	result, err := introspectToken(t.Context(), server.URL, "active", clientAuth)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Active || !slices.Equal(result.Scopes(), []string{"read", "write"}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.ExpiresAt().Unix() != 1700000000 {
+		t.Errorf("unexpected expiry: %v", result.ExpiresAt())
+	}
+	if !slices.Equal(result.Audiences(), []string{"https://mcp.example.com", "other"}) {
+		t.Errorf("unexpected audiences: %v", result.Audiences())
+	}
+
+	result, err = introspectToken(t.Context(), server.URL, "single-aud", clientAuth)
+	if err != nil || !slices.Equal(result.Audiences(), []string{"https://mcp.example.com"}) {
+		t.Errorf("expected single audience, got %v (err %v)", result, err)
+	}
+
+	result, err = introspectToken(t.Context(), server.URL, "revoked", clientAuth)
+	if err != nil || result.Active || !result.ExpiresAt().IsZero() {
+		t.Errorf("expected inactive token, got %+v (err %v)", result, err)
+	}
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	// requestObjectType is the typ header of request objects (RFC 9101
+	// Section 10.8)
+	requestObjectType = "oauth-authz-req+jwt"
+
+	// requestObjectLifetime bounds how long a request object is valid
+	requestObjectLifetime = 5 * time.Minute
+)
+
+// requestObjectOuterParams are the parameters kept outside the request
+// object besides client_id, for authorization servers that require them in
+// the query as OpenID Connect does (RFC 9101 Section 5)
+var requestObjectOuterParams = []string{"response_type", "scope"}
+
+// jarAuthorizationURL moves the parameters of an authorization URL into a
+// signed request object (JAR, RFC 9101) and returns the URL passing it by
+// value in the request parameter
+func (c *Client) jarAuthorizationURL(ctx context.Context, oauthHandler *transport.OAuthHandler, authURL string) (string, error) {
+	metadata, err := oauthHandler.GetServerMetadata(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get authorization server metadata: %w", err)
+	}
+	signer, err := c.oauthSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	signedURL, claims, requestObject, err := signAuthorizationURL(authURL, signer, metadata.Issuer, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if c.oauthSettings().PrintRequestObject {
+		c.logger.Info("Request object claims:\n%s", PrettyJSON(claims))
+		c.logger.Info("Request object: %s", requestObject)
+	}
+	return signedURL, nil
+}
+
+// signAuthorizationURL returns authURL with its parameters replaced by a
+// request object for audience, together with the object's claims and JWT
+func signAuthorizationURL(authURL string, signer *jwtSigner, audience string, now time.Time) (string, map[string]any, string, error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	query := parsed.Query()
+	clientID := query.Get("client_id")
+	claims, err := requestObjectClaims(query, clientID, audience, now)
+	if err != nil {
+		return "", nil, "", err
+	}
+	requestObject, err := signer.sign(requestObjectType, claims)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	outer := url.Values{}
+	outer.Set("client_id", clientID)
+	for _, name := range requestObjectOuterParams {
+		if value := query.Get(name); value != "" {
+			outer.Set(name, value)
+		}
+	}
+	outer.Set("request", requestObject)
+	parsed.RawQuery = outer.Encode()
+	return parsed.String(), claims, requestObject, nil
+}
+
+// requestObjectClaims turns the authorization request parameters into the
+// claims of a request object, adding the JWT claims the authorization
+// server validates it with
+func requestObjectClaims(query url.Values, clientID, audience string, now time.Time) (map[string]any, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, fmt.Errorf("failed to generate jti: %w", err)
+	}
+
+	claims := make(map[string]any, len(query)+6)
+	for name, values := range query {
+		if len(values) > 0 {
+			claims[name] = values[0]
+		}
+	}
+	claims["iss"] = clientID
+	claims["aud"] = audience
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(requestObjectLifetime).Unix()
+	claims["jti"] = base64.RawURLEncoding.EncodeToString(jti)
+	return claims, nil
+}
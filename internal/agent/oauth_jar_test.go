@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignAuthorizationURL(t *testing.T) {
+	signer, err := newEphemeralJWTSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL := "https://auth.example.com/authorize?response_type=code&client_id=abc&redirect_uri=http%3A%2F%2Flocalhost%3A8765%2Fcallback&state=xyz&code_challenge=c&code_challenge_method=S256&resource=https%3A%2F%2Fmcp.example.com%2Fmcp"
+	now := time.Unix(1700000000, 0)
+
+	signedURL, claims, requestObject, err := signAuthorizationURL(authURL, signer, "https://auth.example.com", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(signedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if len(query) != 3 || query.Get("client_id") != "abc" || query.Get("response_type") != "code" || query.Get("request") != requestObject {
+		t.Errorf("expected only client_id, response_type and request outside the object, got %v", query)
+	}
+	if parsed.Host != "auth.example.com" || parsed.Path != "/authorize" {
+		t.Errorf("expected the authorization endpoint kept, got %s", signedURL)
+	}
+
+	for name, want := range map[string]any{
+		"iss": "abc", "aud": "https://auth.example.com", "state": "xyz", "code_challenge": "c",
+		"resource": "https://mcp.example.com/mcp", "redirect_uri": "http://localhost:8765/callback",
+		"iat": now.Unix(), "exp": now.Add(requestObjectLifetime).Unix(),
+	} {
+		if claims[name] != want {
+			t.Errorf("claim %s = %v, want %v", name, claims[name], want)
+		}
+	}
+	if claims["jti"] == "" {
+		t.Error("expected a jti claim")
+	}
+
+	jwt, err := parseJWT(requestObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwt.header.Typ != requestObjectType {
+		t.Errorf("typ = %q, want %q", jwt.header.Typ, requestObjectType)
+	}
+	if err := verifyJWTSignature(jwt, signer.publicKey()); err != nil {
+		t.Errorf("request object signature does not verify: %v", err)
+	}
+}
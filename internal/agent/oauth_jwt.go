@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwtSigner signs the JWTs mcp-debug sends to authorization servers, such
+// as request objects (RFC 9101) and client assertions (RFC 7523)
+type jwtSigner struct {
+	key crypto.Signer
+	kid string
+}
+
+// oauthSigningKey returns the key request objects and client assertions
+// are signed with: the --oauth-signing-key file, or a key generated on first
+// use. A generated key's public JWK is logged once so it can be registered
+// with the authorization server.
+func (c *Client) oauthSigningKey() (*jwtSigner, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.oauthSigner != nil {
+		return c.oauthSigner, nil
+	}
+
+	if path := c.oauthSettings().SigningKeyFile; path != "" {
+		signer, err := loadJWTSigner(path)
+		if err != nil {
+			return nil, err
+		}
+		c.oauthSigner = signer
+		c.logger.Info("Signing OAuth JWTs with %s (%s, kid %s)", path, signer.alg(), signer.kid)
+		return signer, nil
+	}
+
+	signer, err := newEphemeralJWTSigner()
+	if err != nil {
+		return nil, err
+	}
+	c.oauthSigner = signer
+	c.logger.Info("Signing OAuth JWTs with a generated key; register its public JWK with the authorization server:")
+	c.logger.Info("%s", PrettyJSON(map[string]any{"keys": []map[string]string{signer.publicJWK()}}))
+	return signer, nil
+}
+
+// newEphemeralJWTSigner generates a P-256 key that lives as long as the
+// process; authorization servers learn it from the printed public JWK
+func newEphemeralJWTSigner() (*jwtSigner, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return newJWTSigner(key)
+}
+
+// loadJWTSigner reads a PEM private key (PKCS #8, SEC 1 or PKCS #1) from
+// path; RSA and P-256, P-384 and P-521 EC keys are supported
+func loadJWTSigner(path string) (*jwtSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found in %s", path)
+	}
+
+	var key any
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	return newJWTSigner(signer)
+}
+
+// newJWTSigner creates a signer for an RSA or EC key, identified by its
+// thumbprint
+func newJWTSigner(key crypto.Signer) (*jwtSigner, error) {
+	s := &jwtSigner{key: key}
+	if s.alg() == "" {
+		return nil, errors.New("unsupported signing key: use an RSA key or a P-256, P-384 or P-521 EC key")
+	}
+	s.kid = s.thumbprint()
+	return s, nil
+}
+
+// alg is the JWS algorithm of the signer's key, or "" for unsupported keys
+func (s *jwtSigner) alg() string {
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return "ES256"
+		case elliptic.P384():
+			return "ES384"
+		case elliptic.P521():
+			return "ES512"
+		}
+	}
+	return ""
+}
+
+// sign returns the compact JWS of claims with the given typ header
+func (s *jwtSigner) sign(typ string, claims any) (string, error) {
+	alg := s.alg()
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: s.kid, Typ: typ})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := jwtHashes[alg]
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		r, sig, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign JWT: %w", err)
+		}
+		// JWS uses the fixed-size concatenation of r and s (RFC 7518 Section 3.4)
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		sig.FillBytes(signature[size:])
+	default:
+		if signature, err = s.key.Sign(rand.Reader, digest, hash); err != nil {
+			return "", fmt.Errorf("failed to sign JWT: %w", err)
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// publicJWK returns the public key as a JWK for registering it with an
+// authorization server
+func (s *jwtSigner) publicJWK() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := map[string]string{"kid": s.kid, "use": "sig", "alg": s.alg()}
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = encode(key.N.Bytes())
+		jwk["e"] = encode(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = key.Curve.Params().Name
+		jwk["x"] = encode(key.X.FillBytes(make([]byte, size)))
+		jwk["y"] = encode(key.Y.FillBytes(make([]byte, size)))
+	}
+	return jwk
+}
+
+// publicKey returns the key verifying the signer's JWTs
+func (s *jwtSigner) publicKey() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint of the public key, used as kid
+func (s *jwtSigner) thumbprint() string {
+	jwk := s.publicJWK()
+	// The required members in lexicographic order without whitespace
+	var canonical string
+	if jwk["kty"] == "RSA" {
+		canonical = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk["e"], jwk["kty"], jwk["n"])
+	} else {
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk["crv"], jwk["kty"], jwk["x"], jwk["y"])
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
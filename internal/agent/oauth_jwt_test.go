@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJWTSignerSignsVerifiableTokens(t *testing.T) {
+	signer, err := newEphemeralJWTSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signer.sign("JWT", map[string]any{"iss": "client", "aud": "https://auth.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt, err := parseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jwt.header.Alg != "ES256" || jwt.header.Kid != signer.kid || jwt.claims.Issuer != "client" {
+		t.Errorf("unexpected header %+v or claims %+v", jwt.header, jwt.claims)
+	}
+
+	// The printed JWK must verify the signature, as the authorization server does
+	data, _ := json.Marshal(signer.publicJWK())
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.publicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyJWTSignature(jwt, key); err != nil {
+		t.Errorf("signature does not verify with the public JWK: %v", err)
+	}
+}
+
+func TestJWTSignerThumbprint(t *testing.T) {
+	signer, err := newEphemeralJWTSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A base64url SHA-256 digest is 43 characters without padding
+	if len(signer.kid) != 43 || strings.ContainsAny(signer.kid, "+/=") {
+		t.Errorf("kid %q is not a base64url SHA-256 thumbprint", signer.kid)
+	}
+	if signer.thumbprint() != signer.kid {
+		t.Error("the thumbprint must not depend on the kid member")
+	}
+}
+
+// writeKeyFile writes key as a PEM file of the given block type
+func writeKeyFile(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadJWTSigner(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+	pkcs8DER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+
+	tests := []struct {
+		name, blockType string
+		der             []byte
+		alg             string
+	}{
+		{"PKCS #1 RSA key", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), "RS256"},
+		{"PKCS #8 RSA key", "PRIVATE KEY", pkcs8DER, "RS256"},
+		{"SEC 1 P-384 key", "EC PRIVATE KEY", ecDER, "ES384"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := loadJWTSigner(writeKeyFile(t, tt.blockType, tt.der))
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := signer.sign("JWT", map[string]any{"iss": "client"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			jwt, err := parseJWT(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if jwt.header.Alg != tt.alg {
+				t.Errorf("alg = %s, want %s", jwt.header.Alg, tt.alg)
+			}
+			data, _ := json.Marshal(signer.publicJWK())
+			var jwk jsonWebKey
+			_ = json.Unmarshal(data, &jwk)
+			key, err := jwk.publicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifyJWTSignature(jwt, key); err != nil {
+				t.Errorf("signature does not verify with the public JWK: %v", err)
+			}
+		})
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	if _, err := loadJWTSigner(writeKeyFile(t, "PRIVATE KEY", edDER)); err == nil {
+		t.Error("expected Ed25519 keys to be rejected")
+	}
+	if _, err := loadJWTSigner(writeKeyFile(t, "CERTIFICATE", []byte("not a key"))); err == nil {
+		t.Error("expected an error for a file without a private key")
+	}
+}
@@ -34,25 +34,11 @@ type callbackResult struct {
 
 // handleMCPOAuthFlow handles OAuth authorization using mcp-go's built-in OAuth handler
 func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport.OAuthHandler) error {
+	oauthConfig := c.oauthSettings()
 	c.logger.Info("OAuth authorization required")
 
-	// Check if client needs to be registered (Dynamic Client Registration)
-	if oauthHandler.GetClientID() == "" {
-		c.logger.Info("No client ID configured, attempting dynamic client registration...")
-
-		// Use semantic version in client name
-		clientName := DefaultClientName
-		if c.version != "" && c.version != "dev" {
-			clientName = fmt.Sprintf("%s/%s", DefaultClientName, c.version)
-		}
-
-		err := oauthHandler.RegisterClient(ctx, clientName)
-		if err != nil {
-			c.logger.Warning("Dynamic client registration failed: %v", err)
-			c.logger.Info("You may need to manually register a client and provide --oauth-client-id")
-			return fmt.Errorf("client registration failed: %w", err)
-		}
-		c.logger.Success("Client registered successfully with ID: %s", oauthHandler.GetClientID())
+	if err := c.ensureClientRegistered(ctx, oauthHandler); err != nil {
+		return err
 	}
 
 	// Generate PKCE parameters
@@ -70,7 +56,7 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 
 	// Generate nonce for OIDC flows if enabled
 	var nonce string
-	if c.oauthConfig.UseOIDC {
+	if oauthConfig.UseOIDC {
 		nonce, err = client.GenerateState() // Reuse state generation for nonce
 		if err != nil {
 			return fmt.Errorf("failed to generate nonce: %w", err)
@@ -86,7 +72,7 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 
 	// Add RFC 8707 resource parameter to authorization URL
 	// This MUST match the resource parameter sent during token exchange
-	if !c.oauthConfig.SkipResourceParam && c.resourceURI != "" {
+	if !oauthConfig.SkipResourceParam && c.resourceURI != "" {
 		parsedURL, err := url.Parse(authURL)
 		if err == nil {
 			q := parsedURL.Query()
@@ -100,7 +86,7 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 	}
 
 	// Add nonce parameter for OIDC flows
-	if c.oauthConfig.UseOIDC && nonce != "" {
+	if oauthConfig.UseOIDC && nonce != "" {
 		parsedURL, err := url.Parse(authURL)
 		if err == nil {
 			q := parsedURL.Query()
@@ -113,36 +99,22 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 		}
 	}
 
-	// Start callback server
-	callbackConfig := &callbackServerConfig{
-		redirectURL: c.oauthConfig.RedirectURL,
-		logger:      c.logger,
-	}
-	server, resultChan, err := startCallbackServer(callbackConfig)
-	if err != nil {
-		return fmt.Errorf("failed to start callback server: %w", err)
-	}
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			c.logger.Warning("Failed to shutdown callback server: %v", err)
+	// Sign the parameters as a request object last, so it includes all of them
+	if oauthConfig.JAR {
+		authURL, err = c.jarAuthorizationURL(ctx, oauthHandler, authURL)
+		if err != nil {
+			return fmt.Errorf("failed to create request object: %w", err)
 		}
-	}()
-
-	// Open browser
-	c.logger.Info("Opening browser for authorization...")
-	c.logger.Info("Authorization URL: %s", authURL)
-	if err := defaultBrowserOpener(authURL); err != nil {
-		c.logger.Warning("Could not open browser automatically: %v", err)
-		c.logger.Info("Please open this URL in your browser:")
-		c.logger.Info("%s", authURL)
+		c.logger.Info("Authorization request parameters sent as a signed request object (RFC 9101)")
 	}
 
-	// Wait for callback
-	c.logger.Info("Waiting for authorization...")
+	if c.oauthStepper != nil {
+		if err := c.oauthStepper.confirm(ctx, StepPhaseAuthorization, describeAuthorizationURL(authURL)); err != nil {
+			return err
+		}
+	}
 
-	timeout := c.oauthConfig.AuthorizationTimeout
+	timeout := oauthConfig.AuthorizationTimeout
 	if timeout == 0 {
 		timeout = 5 * time.Minute
 	}
@@ -151,23 +123,23 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, timeout)
 	defer cancelTimeout()
 
-	var result callbackResult
-	select {
-	case result = <-resultChan:
-		if result.err != nil {
-			return result.err
-		}
-	case <-timeoutCtx.Done():
+	var params map[string]string
+	if oauthConfig.ManualCode {
+		params, err = c.readPastedCallback(timeoutCtx, authURL, state)
+	} else {
+		params, err = c.awaitCallback(timeoutCtx, authURL)
+	}
+	if err != nil {
 		if errors.Is(ctx.Err(), context.Canceled) {
 			// Parent context was cancelled
 			return fmt.Errorf("authorization cancelled: %w", ctx.Err())
 		}
-		// Timeout occurred
-		return fmt.Errorf("authorization timeout after %v", timeout)
+		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("authorization timeout after %v: %w", timeout, timeoutCtx.Err())
+		}
+		return err
 	}
 
-	params := result.params
-
 	// Verify state
 	if params["state"] != state {
 		return fmt.Errorf("state mismatch (CSRF protection)")
@@ -190,13 +162,13 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 	c.logger.Success("Access token obtained successfully!")
 
 	// Log requested scopes for security audit
-	c.logger.Info("Requested scopes: %v", c.oauthConfig.Scopes)
+	c.logger.Info("Requested scopes: %v", oauthConfig.Scopes)
 	// Note: Granted scopes would need to be exposed by mcp-go library for full validation
 	// For now, we log what was requested. The authorization server may have granted different scopes.
 	c.logger.Info("Token exchange completed - verify granted scopes match your requirements")
 
 	// OIDC nonce validation
-	if c.oauthConfig.UseOIDC && nonce != "" {
+	if oauthConfig.UseOIDC && nonce != "" {
 		c.logger.Info("OIDC nonce validation: nonce='%s'", nonce)
 		c.logger.Warning("Full OIDC ID token validation (including nonce) requires access to the ID token from mcp-go")
 		c.logger.Info("Ensure your MCP server validates the ID token if using OIDC")
@@ -210,6 +182,73 @@ func (c *Client) handleMCPOAuthFlow(ctx context.Context, oauthHandler *transport
 	return nil
 }
 
+// awaitCallback opens the authorization URL in the browser and waits for
+// the redirect to the local callback server, returning its query parameters
+func (c *Client) awaitCallback(ctx context.Context, authURL string) (map[string]string, error) {
+	// Start callback server
+	callbackConfig := &callbackServerConfig{
+		redirectURL: c.oauthSettings().RedirectURL,
+		logger:      c.logger,
+	}
+	server, resultChan, err := startCallbackServer(callbackConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			c.logger.Warning("Failed to shutdown callback server: %v", err)
+		}
+	}()
+
+	// Open browser
+	c.logger.Info("Opening browser for authorization...")
+	c.logger.Info("Authorization URL: %s", authURL)
+	if err := defaultBrowserOpener(authURL); err != nil {
+		c.logger.Warning("Could not open browser automatically: %v", err)
+		c.logger.Info("Please open this URL in your browser:")
+		c.logger.Info("%s", authURL)
+	}
+
+	// Wait for callback
+	c.logger.Info("Waiting for authorization...")
+
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return result.params, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ensureClientRegistered performs Dynamic Client Registration if no client
+// ID is configured
+func (c *Client) ensureClientRegistered(ctx context.Context, oauthHandler *transport.OAuthHandler) error {
+	if oauthHandler.GetClientID() != "" {
+		return nil
+	}
+	c.logger.Info("No client ID configured, attempting dynamic client registration...")
+
+	// Use semantic version in client name
+	clientName := DefaultClientName
+	if c.version != "" && c.version != "dev" {
+		clientName = fmt.Sprintf("%s/%s", DefaultClientName, c.version)
+	}
+
+	err := oauthHandler.RegisterClient(ctx, clientName)
+	if err != nil {
+		c.logger.Warning("Dynamic client registration failed: %v", err)
+		c.logger.Info("You may need to manually register a client and provide --oauth-client-id")
+		return fmt.Errorf("client registration failed: %w", err)
+	}
+	c.logger.Success("Client registered successfully with ID: %s", oauthHandler.GetClientID())
+	return nil
+}
+
 // startCallbackServer starts an HTTP server to receive OAuth callbacks
 func startCallbackServer(config *callbackServerConfig) (*http.Server, <-chan callbackResult, error) {
 	parsedURL, err := url.Parse(config.redirectURL)
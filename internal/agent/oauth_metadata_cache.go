@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetadataCacheTTL is how long discovered metadata is reused when
+// the server sends no shorter Cache-Control max-age
+const DefaultMetadataCacheTTL = 5 * time.Minute
+
+// MetadataCacheConfig configures the cache of protected resource and
+// authorization server metadata
+type MetadataCacheConfig struct {
+	// Disabled fetches the metadata on every connection
+	Disabled bool
+	// TTL is the maximum time a document is reused; a Cache-Control max-age
+	// shortens it
+	TTL time.Duration
+	// File persists the cache across runs; empty keeps it in memory only
+	File string
+}
+
+// metadataCacheEntry is a cached metadata response
+type metadataCacheEntry struct {
+	ContentType string    `json:"content_type"`
+	Body        string    `json:"body"`
+	Expires     time.Time `json:"expires"`
+}
+
+// metadataCache keeps metadata documents keyed by URL, so reconnecting
+// and connecting again do not repeat discovery
+type metadataCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	path    string
+	entries map[string]metadataCacheEntry
+	logger  *Logger
+	now     func() time.Time
+}
+
+// sharedMetadataCache is used by the connections of the process; nil
+// until ConfigureMetadataCache enables it
+var sharedMetadataCache *metadataCache
+
+// ConfigureMetadataCache enables the metadata cache of all connections. It
+// must be called before connecting.
+func ConfigureMetadataCache(config MetadataCacheConfig, logger *Logger) {
+	if config.Disabled || config.TTL <= 0 {
+		sharedMetadataCache = nil
+		return
+	}
+	sharedMetadataCache = newMetadataCache(config.TTL, config.File, logger)
+}
+
+// newMetadataCache returns a cache, loading the unexpired entries of path
+func newMetadataCache(ttl time.Duration, path string, logger *Logger) *metadataCache {
+	c := &metadataCache{
+		ttl:     ttl,
+		path:    path,
+		entries: make(map[string]metadataCacheEntry),
+		logger:  logger,
+		now:     time.Now,
+	}
+	if path == "" {
+		return c
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c
+	}
+	var saved map[string]metadataCacheEntry
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		logger.Warning("Ignoring metadata cache file %s: %v", path, err)
+		return c
+	}
+	for url, entry := range saved {
+		if c.now().Before(entry.Expires) {
+			c.entries[url] = entry
+		}
+	}
+	return c
+}
+
+// get returns the unexpired entry of url
+func (c *metadataCache) get(url string) (metadataCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok {
+		return metadataCacheEntry{}, false
+	}
+	if !c.now().Before(entry.Expires) {
+		delete(c.entries, url)
+		return metadataCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put caches a response for its lifetime and saves the cache file
+func (c *metadataCache) put(url, contentType string, body []byte, lifetime time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = metadataCacheEntry{
+		ContentType: contentType,
+		Body:        string(body),
+		Expires:     c.now().Add(lifetime),
+	}
+	c.saveLocked()
+}
+
+// saveLocked writes the entries to the cache file; the caller holds mu
+func (c *metadataCache) saveLocked() {
+	if c.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err == nil {
+		err = os.WriteFile(c.path, data, 0o600)
+	}
+	if err != nil {
+		c.logger.Warning("Failed to save metadata cache file %s: %v", c.path, err)
+	}
+}
+
+// lifetime returns how long a response may be cached: the TTL, shortened
+// by a Cache-Control max-age, and 0 for no-store, no-cache or max-age=0
+func (c *metadataCache) lifetime(header http.Header) time.Duration {
+	lifetime := c.ttl
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds <= 0 {
+				return 0
+			}
+			lifetime = min(lifetime, time.Duration(seconds)*time.Second)
+		}
+	}
+	return lifetime
+}
+
+// isMetadataRequest reports whether req fetches a well-known metadata
+// document: RFC 9728 protected resource metadata, RFC 8414 authorization
+// server metadata or OIDC discovery. Requests carrying credentials are
+// not cached.
+func isMetadataRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.Header.Get("Authorization") == "" &&
+		strings.Contains(req.URL.Path, "/.well-known/")
+}
+
+// metadataCacheRoundTripper answers metadata requests from the cache and
+// caches successful responses
+type metadataCacheRoundTripper struct {
+	base   http.RoundTripper
+	cache  *metadataCache
+	logger *Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metadataCacheRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isMetadataRequest(req) {
+		return t.base.RoundTrip(req)
+	}
+	url := req.URL.String()
+	if entry, ok := t.cache.get(url); ok {
+		if t.logger != nil {
+			t.logger.InfoVerbose("Using cached metadata for %s (expires in %s)", url, entry.Expires.Sub(t.cache.now()).Round(time.Second))
+		}
+		header := make(http.Header)
+		header.Set("Content-Type", entry.ContentType)
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(entry.Body)),
+			ContentLength: int64(len(entry.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	lifetime := t.cache.lifetime(resp.Header)
+	if lifetime <= 0 {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	// Truncated documents are left for the caller to reject
+	if int64(len(body)) < maxMetadataSize {
+		t.cache.put(url, resp.Header.Get("Content-Type"), body, lifetime)
+	}
+	return resp, nil
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetadataCacheLifetime(t *testing.T) {
+	cache := newMetadataCache(time.Minute, "", NewLogger(false, false, false))
+	for cacheControl, want := range map[string]time.Duration{
+		"":                        time.Minute,
+		"max-age=30":              30 * time.Second,
+		"public, max-age=3600":    time.Minute,
+		"no-store":                0,
+		"max-age=60, no-cache":    0,
+		"max-age=0":               0,
+		"max-age=soon":            0,
+		`private, max-age="10"`:   10 * time.Second,
+		"must-revalidate, public": time.Minute,
+	} {
+		header := http.Header{}
+		if cacheControl != "" {
+			header.Set("Cache-Control", cacheControl)
+		}
+		if got := cache.lifetime(header); got != want {
+			t.Errorf("lifetime(%q) = %s, want %s", cacheControl, got, want)
+		}
+	}
+}
+
+// newMetadataTestServer returns a server counting its requests that serves
+// metadata with the given Cache-Control header
+func newMetadataTestServer(t *testing.T, cacheControl string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		_, _ = io.WriteString(w, `{"resource":"https://mcp.example.com","authorization_servers":["https://auth.example.com"]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestMetadataCacheRoundTripper(t *testing.T) {
+	server, requests := newMetadataTestServer(t, "")
+	cache := newMetadataCache(time.Minute, "", NewLogger(false, false, false))
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	client := &http.Client{Transport: &metadataCacheRoundTripper{base: http.DefaultTransport, cache: cache}}
+
+	get := func(path string, authorization string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected response %d %v", resp.StatusCode, resp.Header)
+		}
+		return string(body)
+	}
+
+	first := get("/.well-known/oauth-protected-resource", "")
+	if second := get("/.well-known/oauth-protected-resource", ""); second != first {
+		t.Errorf("cached body %q differs from %q", second, first)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the second request to be answered from the cache, got %d requests", n)
+	}
+
+	get("/.well-known/oauth-protected-resource", "Bearer token")
+	get("/jwks.json", "")
+	get("/jwks.json", "")
+	if n := requests.Load(); n != 4 {
+		t.Errorf("expected requests with credentials and other documents to bypass the cache, got %d requests", n)
+	}
+
+	now = now.Add(time.Minute)
+	get("/.well-known/oauth-protected-resource", "")
+	if n := requests.Load(); n != 5 {
+		t.Errorf("expected the expired entry to be fetched again, got %d requests", n)
+	}
+}
+
+func TestMetadataCacheHonorsNoStore(t *testing.T) {
+	server, requests := newMetadataTestServer(t, "no-store")
+	cache := newMetadataCache(time.Minute, "", NewLogger(false, false, false))
+	client := &http.Client{Transport: &metadataCacheRoundTripper{base: http.DefaultTransport, cache: cache}}
+
+	for range 2 {
+		resp, err := client.Get(server.URL + "/.well-known/oauth-authorization-server")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected no-store responses not to be cached, got %d requests", n)
+	}
+}
+
+func TestMetadataCacheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata-cache.json")
+	logger := NewLogger(false, false, false)
+
+	cache := newMetadataCache(time.Minute, path, logger)
+	cache.put("https://auth.example.com/.well-known/openid-configuration", "application/json", []byte(`{"issuer":"https://auth.example.com"}`), time.Minute)
+	cache.put("https://old.example.com/.well-known/openid-configuration", "application/json", []byte(`{}`), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	loaded := newMetadataCache(time.Minute, path, logger)
+	entry, ok := loaded.get("https://auth.example.com/.well-known/openid-configuration")
+	if !ok || entry.Body != `{"issuer":"https://auth.example.com"}` || entry.ContentType != "application/json" {
+		t.Errorf("expected the entry to be loaded from the file, got %+v, %v", entry, ok)
+	}
+	if len(loaded.entries) != 1 {
+		t.Errorf("expected expired entries to be dropped on load, got %v", loaded.entries)
+	}
+}
+
+func TestConfigureMetadataCache(t *testing.T) {
+	t.Cleanup(func() { sharedMetadataCache = nil })
+	logger := NewLogger(false, false, false)
+
+	ConfigureMetadataCache(MetadataCacheConfig{TTL: time.Minute}, logger)
+	if sharedMetadataCache == nil {
+		t.Fatal("expected the cache to be enabled")
+	}
+	ConfigureMetadataCache(MetadataCacheConfig{TTL: time.Minute, Disabled: true}, logger)
+	if sharedMetadataCache != nil {
+		t.Error("expected --no-metadata-cache to disable the cache")
+	}
+	ConfigureMetadataCache(MetadataCacheConfig{}, logger)
+	if sharedMetadataCache != nil {
+		t.Error("expected a zero TTL to disable the cache")
+	}
+}
@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Token endpoint authentication methods using the TLS client certificate
+// (RFC 8705 Section 2)
+const (
+	// TokenAuthMethodTLSClient authenticates with a certificate issued by a
+	// CA the authorization server trusts, identified by its subject DN
+	TokenAuthMethodTLSClient = "tls_client_auth"
+	// TokenAuthMethodSelfSignedTLSClient authenticates with a self-signed
+	// certificate registered with the client
+	TokenAuthMethodSelfSignedTLSClient = "self_signed_tls_client_auth"
+)
+
+// isMTLSAuthMethod reports whether method authenticates the client with its
+// TLS certificate
+func isMTLSAuthMethod(method string) bool {
+	return method == TokenAuthMethodTLSClient || method == TokenAuthMethodSelfSignedTLSClient
+}
+
+// oauthClientCertificate returns the client certificate ConfigureTLS set on
+// the OAuth transport, or nil without --tls-cert
+func oauthClientCertificate() *x509.Certificate {
+	tlsConfig := sharedOAuthTransport.TLSClientConfig
+	if tlsConfig == nil || len(tlsConfig.Certificates) == 0 {
+		return nil
+	}
+	cert := tlsConfig.Certificates[0]
+	if cert.Leaf != nil {
+		return cert.Leaf
+	}
+	if len(cert.Certificate) == 0 {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil
+	}
+	return leaf
+}
+
+// mtlsClientAuthRoundTripper makes the OAuth requests of mcp-go's handler
+// authenticate the client with its TLS certificate (RFC 8705):
+//   - registration requests ask for the mTLS method and carry the
+//     certificate's subject DN or public key
+//   - token and revocation requests carry client_id but no client_secret
+//   - requests go to the mtls_endpoint_aliases of the authorization server
+//     metadata, which it learns from the metadata responses
+type mtlsClientAuthRoundTripper struct {
+	base   http.RoundTripper
+	method string
+	cert   *x509.Certificate
+	logger *Logger
+
+	mu sync.Mutex
+	// aliases maps endpoint URLs to their mTLS aliases
+	aliases map[string]string
+}
+
+// newMTLSClientAuthRoundTripper creates a round tripper authenticating with
+// cert using method
+func newMTLSClientAuthRoundTripper(method string, cert *x509.Certificate, base http.RoundTripper, logger *Logger) *mtlsClientAuthRoundTripper {
+	return &mtlsClientAuthRoundTripper{
+		base:    base,
+		method:  method,
+		cert:    cert,
+		logger:  logger,
+		aliases: make(map[string]string),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *mtlsClientAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil {
+			t.observeMetadata(resp)
+		}
+		return resp, err
+	}
+	if req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		body = t.rewriteForm(body)
+	case strings.HasPrefix(contentType, "application/json"):
+		if body, err = t.rewriteRegistration(body); err != nil {
+			return nil, err
+		}
+	}
+
+	rewritten := withRequestBody(req, body)
+	if alias := t.alias(req.URL.String()); alias != "" {
+		aliasURL, err := url.Parse(alias)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mTLS endpoint alias %q: %w", alias, err)
+		}
+		rewritten.URL = aliasURL
+		rewritten.Host = aliasURL.Host
+		if t.logger != nil && t.logger.isVerbose() {
+			t.logger.Info("Using mTLS endpoint alias %s", alias)
+		}
+	}
+	return t.base.RoundTrip(rewritten)
+}
+
+// rewriteForm drops the client secret of token and revocation requests; the
+// client is identified by client_id and authenticated by its certificate
+// (RFC 8705 Section 2)
+func (t *mtlsClientAuthRoundTripper) rewriteForm(body []byte) []byte {
+	form, err := url.ParseQuery(string(body))
+	if err != nil || !form.Has("client_secret") {
+		return body
+	}
+	form.Del("client_secret")
+	return []byte(form.Encode())
+}
+
+// rewriteRegistration sets the token endpoint authentication method of a
+// Dynamic Client Registration request and the metadata the authorization
+// server binds the certificate with (RFC 8705 Section 2.1.2 and 2.2.2)
+func (t *mtlsClientAuthRoundTripper) rewriteRegistration(body []byte) ([]byte, error) {
+	var registration map[string]any
+	if err := json.Unmarshal(body, &registration); err != nil {
+		return body, nil
+	}
+	if _, ok := registration["redirect_uris"]; !ok {
+		return body, nil
+	}
+
+	registration["token_endpoint_auth_method"] = t.method
+	switch t.method {
+	case TokenAuthMethodTLSClient:
+		registration["tls_client_auth_subject_dn"] = t.cert.Subject.String()
+	case TokenAuthMethodSelfSignedTLSClient:
+		jwk, err := certificateJWK(t.cert)
+		if err != nil {
+			return nil, err
+		}
+		registration["jwks"] = map[string]any{"keys": []map[string]any{jwk}}
+	}
+	return json.Marshal(registration)
+}
+
+// observeMetadata records the mTLS endpoint aliases of an authorization
+// server metadata response and warns if the server does not list the
+// configured method
+func (t *mtlsClientAuthRoundTripper) observeMetadata(resp *http.Response) {
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxASMetadataSize))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	var metadata map[string]any
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return
+	}
+	if _, ok := metadata["token_endpoint"].(string); !ok {
+		return
+	}
+
+	if methods, ok := metadata["token_endpoint_auth_methods_supported"].([]any); ok && !slices.Contains(methods, any(t.method)) && t.logger != nil {
+		t.logger.Warning("Authorization server does not list %s in token_endpoint_auth_methods_supported", t.method)
+	}
+
+	aliases, _ := metadata["mtls_endpoint_aliases"].(map[string]any)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, alias := range aliases {
+		endpoint, _ := metadata[name].(string)
+		aliasURL, _ := alias.(string)
+		if endpoint != "" && aliasURL != "" {
+			t.aliases[endpoint] = aliasURL
+		}
+	}
+}
+
+// alias returns the mTLS alias of endpoint, or "" if it has none
+func (t *mtlsClientAuthRoundTripper) alias(endpoint string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.aliases[endpoint]
+}
+
+// certificateJWK returns the public key of cert as a JWK carrying the
+// certificate in x5c, which is how self-signed certificates are registered
+func certificateJWK(cert *x509.Certificate) (map[string]any, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := map[string]any{
+		"use": "sig",
+		"x5c": []string{base64.StdEncoding.EncodeToString(cert.Raw)},
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = encode(key.N.Bytes())
+		jwk["e"] = encode(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = key.Curve.Params().Name
+		jwk["x"] = encode(key.X.FillBytes(make([]byte, size)))
+		jwk["y"] = encode(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = encode(key)
+	default:
+		return nil, fmt.Errorf("unsupported client certificate key type %T", cert.PublicKey)
+	}
+	return jwk, nil
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// selfSignedCertificate returns a self-signed P-256 client certificate
+func selfSignedCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mcp-debug", Organization: []string{"Giant Swarm"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestMTLSClientAuthRoundTripper(t *testing.T) {
+	var tokenPath string
+	var tokenForm url.Values
+	var registration map[string]any
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"issuer":%[1]q,"token_endpoint":"%[1]s/token","registration_endpoint":"%[1]s/register",`+
+				`"token_endpoint_auth_methods_supported":["none"],"mtls_endpoint_aliases":{"token_endpoint":"%[1]s/mtls/token"}}`, server.URL)
+		case "/register":
+			_ = json.NewDecoder(r.Body).Decode(&registration)
+		default:
+			tokenPath = r.URL.Path
+			_ = r.ParseForm()
+			tokenForm = r.PostForm
+		}
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	cert := selfSignedCertificate(t)
+	rt := newMTLSClientAuthRoundTripper(TokenAuthMethodTLSClient, cert, http.DefaultTransport, NewLoggerWithWriter(false, false, false, &logs))
+	client := &http.Client{Transport: rt}
+
+	resp, err := client.Get(server.URL + "/.well-known/oauth-authorization-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), "mtls_endpoint_aliases") {
+		t.Error("expected the metadata response to reach the caller intact")
+	}
+	if !strings.Contains(logs.String(), "does not list tls_client_auth") {
+		t.Errorf("expected a warning about the unsupported method, got %q", logs.String())
+	}
+
+	resp, err = client.Post(server.URL+"/register", "application/json", strings.NewReader(`{"client_name":"mcp-debug","redirect_uris":["http://localhost:8765/callback"],"token_endpoint_auth_method":"none"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if registration["token_endpoint_auth_method"] != TokenAuthMethodTLSClient || registration["tls_client_auth_subject_dn"] != cert.Subject.String() {
+		t.Errorf("unexpected registration request %v", registration)
+	}
+
+	resp, err = client.PostForm(server.URL+"/token", url.Values{"grant_type": {"authorization_code"}, "client_id": {"abc"}, "client_secret": {"s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if tokenPath != "/mtls/token" {
+		t.Errorf("expected the token request at the mTLS alias, got %s", tokenPath)
+	}
+	if tokenForm.Get("client_id") != "abc" || tokenForm.Has("client_secret") {
+		t.Errorf("expected client_id without client_secret, got %v", tokenForm)
+	}
+}
+
+func TestMTLSSelfSignedRegistration(t *testing.T) {
+	cert := selfSignedCertificate(t)
+	rt := newMTLSClientAuthRoundTripper(TokenAuthMethodSelfSignedTLSClient, cert, nil, nil)
+
+	body, err := rt.rewriteRegistration([]byte(`{"redirect_uris":["http://localhost:8765/callback"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registration struct {
+		Method string `json:"token_endpoint_auth_method"`
+		JWKS   struct {
+			Keys []map[string]any `json:"keys"`
+		} `json:"jwks"`
+	}
+	if err := json.Unmarshal(body, &registration); err != nil {
+		t.Fatal(err)
+	}
+	if registration.Method != TokenAuthMethodSelfSignedTLSClient || len(registration.JWKS.Keys) != 1 {
+		t.Fatalf("unexpected registration request %s", body)
+	}
+	key := registration.JWKS.Keys[0]
+	if key["kty"] != "EC" || key["crv"] != "P-256" || key["x"] == "" || len(key["x5c"].([]any)) != 1 {
+		t.Errorf("unexpected JWK %v", key)
+	}
+
+	// Bodies other than registration requests are left alone
+	for _, other := range []string{`{"jsonrpc":"2.0"}`, `not json`} {
+		if got, err := rt.rewriteRegistration([]byte(other)); err != nil || string(got) != other {
+			t.Errorf("rewriteRegistration(%s) = %s, %v", other, got, err)
+		}
+	}
+}
+
+func TestOAuthClientCertificate(t *testing.T) {
+	restoreSharedTransports(t)
+	if oauthClientCertificate() != nil {
+		t.Fatal("expected no client certificate by default")
+	}
+
+	pki := newTestPKI(t)
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	if err := ConfigureTLS(TLSConfig{CertFile: pki.clientCertFile, KeyFile: pki.clientKeyFile}, logger); err != nil {
+		t.Fatal(err)
+	}
+	cert := oauthClientCertificate()
+	if cert == nil || cert.Subject.CommonName != "mcp-debug" {
+		t.Errorf("expected the --tls-cert certificate, got %v", cert)
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// privateNetworks are the loopback, private, shared, link-local (including
+// cloud instance metadata at 169.254.169.254) and unspecified ranges denied
+// by MetadataNetworkPolicy.DenyPrivate
+var privateNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// MetadataNetworkPolicy restricts the addresses that metadata discovery and
+// the OAuth requests following it connect to. The resource_metadata URL and
+// the authorization servers come from the MCP server, so an untrusted server
+// could otherwise point the client at internal services (SSRF). An address
+// is refused if it is denied and not allowed.
+type MetadataNetworkPolicy struct {
+	// DenyPrivate denies the loopback, private and link-local ranges
+	DenyPrivate bool
+	// Deny are further CIDRs or addresses to deny
+	Deny []string
+	// Allow are CIDRs or addresses exempt from the denied ranges, such as
+	// the service network of the cluster an MCP server runs in
+	Allow []string
+}
+
+// enabled reports whether the policy denies anything
+func (p MetadataNetworkPolicy) enabled() bool {
+	return p.DenyPrivate || len(p.Deny) > 0
+}
+
+// networkGuard checks dialed addresses against a parsed policy
+type networkGuard struct {
+	deny  []netip.Prefix
+	allow []netip.Prefix
+}
+
+// newNetworkGuard parses the CIDRs of policy
+func newNetworkGuard(policy MetadataNetworkPolicy) (*networkGuard, error) {
+	guard := &networkGuard{}
+	if policy.DenyPrivate {
+		guard.deny = append(guard.deny, privateNetworks...)
+	}
+	for _, cidr := range policy.Deny {
+		prefix, err := parseNetwork(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid denied network: %w", err)
+		}
+		guard.deny = append(guard.deny, prefix)
+	}
+	for _, cidr := range policy.Allow {
+		prefix, err := parseNetwork(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network: %w", err)
+		}
+		guard.allow = append(guard.allow, prefix)
+	}
+	return guard, nil
+}
+
+// parseNetwork parses a CIDR or a single address
+func parseNetwork(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%q is not a CIDR: %w", s, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is not an address or CIDR: %w", s, err)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// check returns an error if addr may not be connected to
+func (g *networkGuard) check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range g.allow {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, prefix := range g.deny {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("connection to %s refused: in denied network %s (allow it with --metadata-allow-cidr)", addr, prefix)
+		}
+	}
+	return nil
+}
+
+// control is a net.Dialer Control function. It runs after name resolution,
+// so a host name resolving to a denied address is refused too.
+func (g *networkGuard) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("connection to %s refused: cannot check address: %w", address, err)
+	}
+	return g.check(addrPort.Addr())
+}
+
+// ConfigureMetadataNetworks applies policy to metadata discovery, client ID
+// metadata documents and the token and registration requests. With a proxy,
+// the address of the proxy is checked. It must be called before connecting.
+func ConfigureMetadataNetworks(policy MetadataNetworkPolicy, logger *Logger) error {
+	if !policy.enabled() {
+		if len(policy.Allow) > 0 {
+			logger.Warning("--metadata-allow-cidr has no effect without --metadata-deny-private or --metadata-deny-cidr")
+		}
+		return nil
+	}
+	guard, err := newNetworkGuard(policy)
+	if err != nil {
+		return err
+	}
+
+	// The dialer settings of http.DefaultTransport, plus the guard
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guard.control,
+	}
+	sharedOAuthTransport.DialContext = dialer.DialContext
+	sharedOAuthTransport.CloseIdleConnections()
+
+	logger.Info("Metadata discovery restricted: %d denied and %d allowed network(s)", len(guard.deny), len(guard.allow))
+	return nil
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNetworkGuardCheck(t *testing.T) {
+	guard, err := newNetworkGuard(MetadataNetworkPolicy{
+		DenyPrivate: true,
+		Deny:        []string{"203.0.113.0/24"},
+		Allow:       []string{"10.96.0.0/12", "127.0.0.2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, allowed := range map[string]bool{
+		"8.8.8.8":            true,
+		"10.0.0.1":           false,
+		"10.96.0.10":         true,
+		"127.0.0.1":          false,
+		"127.0.0.2":          true,
+		"169.254.169.254":    false,
+		"203.0.113.7":        false,
+		"::1":                false,
+		"::ffff:192.168.1.1": false,
+		"2001:db8::1":        true,
+	} {
+		err := guard.check(netip.MustParseAddr(addr))
+		if (err == nil) != allowed {
+			t.Errorf("%s: got %v, want allowed=%v", addr, err, allowed)
+		}
+	}
+}
+
+func TestNewNetworkGuardRejectsInvalidNetworks(t *testing.T) {
+	for _, policy := range []MetadataNetworkPolicy{
+		{Deny: []string{"10.0.0.0/33"}},
+		{Deny: []string{"example.com"}},
+		{DenyPrivate: true, Allow: []string{"not-an-ip"}},
+	} {
+		if _, err := newNetworkGuard(policy); err == nil {
+			t.Errorf("expected an error for %+v", policy)
+		}
+	}
+}
+
+func TestConfigureMetadataNetworks(t *testing.T) {
+	restoreSharedTransports(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := NewLoggerWithWriter(false, false, false, &logs)
+	if err := ConfigureMetadataNetworks(MetadataNetworkPolicy{Allow: []string{"10.0.0.0/8"}}, logger); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "has no effect") {
+		t.Errorf("expected an allowlist without denied networks to be flagged:\n%s", logs.String())
+	}
+
+	if err := ConfigureMetadataNetworks(MetadataNetworkPolicy{DenyPrivate: true}, logger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetchProtectedResourceMetadata(t.Context(), server.URL); err == nil || !strings.Contains(err.Error(), "denied network 127.0.0.0/8") {
+		t.Errorf("expected the loopback address to be refused, got %v", err)
+	}
+
+	if err := ConfigureMetadataNetworks(MetadataNetworkPolicy{DenyPrivate: true, Allow: []string{"127.0.0.1"}}, logger); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := httpClientFor(t.Context(), oauthRequestTimeout).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected an allowed address to be reachable, got %v", err)
+	}
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// probeGraceWindow is how long higher-priority probes still running may
+// take to finish after a lower-priority candidate succeeded
+const probeGraceWindow = 200 * time.Millisecond
+
+// probeResult is the outcome of probing a single candidate endpoint
+type probeResult[T any] struct {
+	index int
+	value T
+	err   error
+}
+
+// probeInPriorityOrder probes all candidates concurrently and returns the
+// first successful result. Priority only breaks ties: once a candidate
+// succeeds, the higher-priority candidates still running get
+// probeGraceWindow to succeed as well, and the successful result with the
+// lowest index is returned. A higher-priority candidate that hangs thus
+// delays the result by the grace window at most. The remaining probes are
+// cancelled on return.
+//
+// If every candidate fails, the error of the last candidate is returned
+// together with an index of -1.
+func probeInPriorityOrder[T any](ctx context.Context, n int, probe func(ctx context.Context, i int) (T, error)) (T, int, error) {
+	var zero T
+	if n == 0 {
+		return zero, -1, nil
+	}
+
+	probeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so probes still running after an early return never block
+	results := make(chan probeResult[T], n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			value, err := probe(probeCtx, i)
+			results <- probeResult[T]{index: i, value: value, err: err}
+		}(i)
+	}
+
+	done := make([]*probeResult[T], n)
+	best := -1 // lowest index that succeeded so far
+	var grace <-chan time.Time
+	for received := 0; received < n; {
+		select {
+		case res := <-results:
+			received++
+			done[res.index] = &res
+			if res.err == nil && (best < 0 || res.index < best) {
+				best = res.index
+				if grace == nil {
+					timer := time.NewTimer(probeGraceWindow)
+					defer timer.Stop()
+					grace = timer.C
+				}
+			}
+		case <-grace:
+			return done[best].value, best, nil
+		}
+
+		if best >= 0 && decided(done, best) {
+			return done[best].value, best, nil
+		}
+	}
+
+	return zero, -1, done[n-1].err
+}
+
+// decided reports whether every candidate ahead of best has finished, so
+// none of them can still take precedence
+func decided[T any](done []*probeResult[T], best int) bool {
+	for _, res := range done[:best] {
+		if res == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProbeInPriorityOrder(t *testing.T) {
+	t.Run("higher priority wins over faster candidate", func(t *testing.T) {
+		value, index, err := probeInPriorityOrder(t.Context(), 2, func(ctx context.Context, i int) (string, error) {
+			if i == 0 {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return []string{"first", "second"}[i], nil
+		})
+		if err != nil || value != "first" || index != 0 {
+			t.Errorf("got %q, %d, %v; want first, 0, nil", value, index, err)
+		}
+	})
+
+	t.Run("hanging higher priority does not block a success", func(t *testing.T) {
+		start := time.Now()
+		value, index, err := probeInPriorityOrder(t.Context(), 2, func(ctx context.Context, i int) (string, error) {
+			if i == 0 {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			return "second", nil
+		})
+		if err != nil || value != "second" || index != 1 {
+			t.Errorf("got %q, %d, %v; want second, 1, nil", value, index, err)
+		}
+		if elapsed := time.Since(start); elapsed > probeGraceWindow+testTimeoutNormal/2 {
+			t.Errorf("returned after %v, want about the grace window", elapsed)
+		}
+	})
+
+	t.Run("falls through failed candidates", func(t *testing.T) {
+		value, index, err := probeInPriorityOrder(t.Context(), 3, func(ctx context.Context, i int) (int, error) {
+			if i < 2 {
+				return 0, errors.New("not found")
+			}
+			return 42, nil
+		})
+		if err != nil || value != 42 || index != 2 {
+			t.Errorf("got %d, %d, %v; want 42, 2, nil", value, index, err)
+		}
+	})
+
+	t.Run("cancels slower probes after a decisive success", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		start := time.Now()
+		_, index, err := probeInPriorityOrder(t.Context(), 2, func(ctx context.Context, i int) (int, error) {
+			if i == 0 {
+				return 1, nil
+			}
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		})
+		if err != nil || index != 0 {
+			t.Fatalf("got %d, %v", index, err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(testTimeoutNormal):
+			t.Fatal("slow probe was not cancelled")
+		}
+		if time.Since(start) > testTimeoutNormal {
+			t.Error("probe did not return early")
+		}
+	})
+
+	t.Run("returns last error when all fail", func(t *testing.T) {
+		_, index, err := probeInPriorityOrder(t.Context(), 2, func(ctx context.Context, i int) (int, error) {
+			return 0, errors.New([]string{"a", "b"}[i])
+		})
+		if err == nil || err.Error() != "b" || index != -1 {
+			t.Errorf("got %d, %v; want -1, b", index, err)
+		}
+	})
+}
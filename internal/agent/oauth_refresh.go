@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// tokenCheckInterval is how often the token lifecycle monitor looks at the
+// access token, unless the refresh margin or log interval is shorter
+const tokenCheckInterval = 10 * time.Second
+
+// TokenRefresh is the outcome of a refresh grant
+type TokenRefresh struct {
+	// ExpiresAt is when the new access token expires; zero if the
+	// authorization server did not say
+	ExpiresAt time.Time
+	// Scope is the scope of the new access token, if reported
+	Scope string
+	// RefreshTokenRotated is set when a new refresh token was issued
+	RefreshTokenRotated bool
+}
+
+// tokenLifecycle is the state of the proactive refresh monitor
+type tokenLifecycle struct {
+	// mu serializes refreshes, so manual and proactive ones do not race
+	// for a refresh token that is only valid once
+	mu sync.Mutex
+	// lastLogged is when the remaining lifetime was last logged
+	lastLogged time.Time
+	// failedRefreshToken is the refresh token a proactive refresh failed
+	// with; it is not retried until the token changes
+	failedRefreshToken string
+}
+
+// RefreshOAuthToken forces a refresh grant with the current refresh token
+// and logs the exchange with the token endpoint in full, credentials
+// redacted. The new token replaces the current one.
+func (c *Client) RefreshOAuthToken(ctx context.Context) (*TokenRefresh, error) {
+	c.tokenLifecycle.mu.Lock()
+	defer c.tokenLifecycle.mu.Unlock()
+	return c.refreshOAuthToken(withOAuthExchangeLog(ctx))
+}
+
+// refreshOAuthToken performs the refresh grant; callers hold
+// tokenLifecycle.mu
+func (c *Client) refreshOAuthToken(ctx context.Context) (*TokenRefresh, error) {
+	if !c.OAuthEnabled() {
+		return nil, errors.New("OAuth is not enabled")
+	}
+	if c.oauthHandler == nil || c.oauthTokenStore == nil {
+		return nil, errors.New("no OAuth session established")
+	}
+	token, err := c.oauthTokenStore.GetToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no token to refresh: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, errors.New("the authorization server issued no refresh token; authorize again instead")
+	}
+
+	refreshed, err := c.oauthHandler.RefreshToken(ctx, token.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("token refresh failed: %w", err)
+	}
+	return &TokenRefresh{
+		ExpiresAt:           refreshed.ExpiresAt,
+		Scope:               refreshed.Scope,
+		RefreshTokenRotated: refreshed.RefreshToken != token.RefreshToken,
+	}, nil
+}
+
+// startTokenLifecycle logs the remaining lifetime of the access token every
+// TokenLogInterval and refreshes it RefreshBefore its expiry, until ctx is
+// done, so refresh problems surface before a request fails with 401
+func (c *Client) startTokenLifecycle(ctx context.Context) {
+	oauthConfig := c.oauthSettings()
+	if !c.OAuthEnabled() || (oauthConfig.RefreshBefore <= 0 && oauthConfig.TokenLogInterval <= 0) {
+		return
+	}
+
+	interval := tokenCheckInterval
+	if half := oauthConfig.RefreshBefore / 2; half > 0 && half < interval {
+		interval = half
+	}
+	if logInterval := oauthConfig.TokenLogInterval; logInterval > 0 && logInterval < interval {
+		interval = logInterval
+	}
+	interval = max(interval, time.Second)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				c.tokenLifecycleTick(ctx, now)
+			}
+		}
+	}()
+}
+
+// tokenLifecycleTick logs the remaining lifetime if it is due and refreshes
+// the token once it is within RefreshBefore of expiring
+func (c *Client) tokenLifecycleTick(ctx context.Context, now time.Time) {
+	oauthConfig := c.oauthSettings()
+	if c.oauthTokenStore == nil {
+		return
+	}
+	token, err := c.oauthTokenStore.GetToken(ctx)
+	if err != nil || token.AccessToken == "" || token.ExpiresAt.IsZero() {
+		return
+	}
+	remaining := token.ExpiresAt.Sub(now)
+
+	lifecycle := &c.tokenLifecycle
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	if logInterval := oauthConfig.TokenLogInterval; logInterval > 0 && now.Sub(lifecycle.lastLogged) >= logInterval {
+		lifecycle.lastLogged = now
+		if remaining > 0 {
+			c.logger.Info("Access token expires in %s (refresh token: %s)", remaining.Round(time.Second), yesNo(token.RefreshToken != ""))
+		} else {
+			c.logger.Warning("Access token expired %s ago", (-remaining).Round(time.Second))
+		}
+	}
+
+	if oauthConfig.RefreshBefore <= 0 || remaining > oauthConfig.RefreshBefore ||
+		token.RefreshToken == "" || token.RefreshToken == lifecycle.failedRefreshToken {
+		return
+	}
+
+	c.logger.Info("Access token expires in %s, refreshing it", remaining.Round(time.Second))
+	refreshCtx := ctx
+	if c.logger.isVerbose() {
+		refreshCtx = withOAuthExchangeLog(ctx)
+	}
+	refresh, err := c.refreshOAuthToken(refreshCtx)
+	if err != nil {
+		lifecycle.failedRefreshToken = token.RefreshToken
+		c.logger.Warning("Proactive token refresh failed: %v", err)
+		c.logger.Info("Use 'token refresh' to retry; requests fail with 401 once the token expires")
+		return
+	}
+	if refresh.ExpiresAt.IsZero() {
+		c.logger.Success("Access token refreshed")
+	} else {
+		c.logger.Success("Access token refreshed, now expires in %s", refresh.ExpiresAt.Sub(now).Round(time.Second))
+	}
+}
+
+// oauthExchangeLogKey is the context key of OAuth requests whose exchange
+// is logged in full
+type oauthExchangeLogKey struct{}
+
+// withOAuthExchangeLog returns a context whose OAuth requests are logged in
+// full together with their responses
+func withOAuthExchangeLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauthExchangeLogKey{}, true)
+}
+
+// exchangeLogRoundTripper logs the OAuth requests marked with
+// withOAuthExchangeLog and their responses as they go over the wire. The
+// logger redacts the tokens and secrets they carry.
+type exchangeLogRoundTripper struct {
+	base   http.RoundTripper
+	logger *Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *exchangeLogRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if logged, _ := req.Context().Value(oauthExchangeLogKey{}).(bool); !logged {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.Info("OAuth request:\n%s", dumpStepRequest(req, body))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Error("OAuth request failed: %v", err)
+		return nil, err
+	}
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		t.logger.Warning("Failed to render the OAuth response: %v", err)
+		return resp, nil
+	}
+	t.logger.Info("OAuth response:\n%s", dump)
+	return resp, nil
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// newRefreshTestClient returns a client with an OAuth session against an
+// authorization server answering refresh grants with tokenResponse, the
+// buffer its logger writes to and the number of refresh grants received
+func newRefreshTestClient(t *testing.T, tokenStatus int, tokenResponse string) (*Client, *bytes.Buffer, *atomic.Int32) {
+	t.Helper()
+	var refreshes atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			refreshes.Add(1)
+			w.WriteHeader(tokenStatus)
+			_, _ = fmt.Fprint(w, tokenResponse)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"issuer":%[1]q,"authorization_endpoint":"%[1]s/authorize","token_endpoint":"%[1]s/token","response_types_supported":["code"]}`, server.URL)
+	}))
+	t.Cleanup(server.Close)
+
+	var logs bytes.Buffer
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.logger = NewLoggerWithWriter(false, false, false, &logs)
+	c.oauthConfig = &OAuthConfig{Enabled: true}
+	c.oauthTokenStore = client.NewMemoryTokenStore()
+	c.oauthHandler = transport.NewOAuthHandler(transport.OAuthConfig{
+		ClientID:              "cli",
+		AuthServerMetadataURL: server.URL + "/.well-known/oauth-authorization-server",
+		TokenStore:            c.oauthTokenStore,
+		HTTPClient:            &http.Client{Transport: &exchangeLogRoundTripper{base: http.DefaultTransport, logger: c.logger}},
+	})
+	return c, &logs, &refreshes
+}
+
+func TestRefreshOAuthTokenLogsExchange(t *testing.T) {
+	c, logs, _ := newRefreshTestClient(t, http.StatusOK, `{"access_token":"new-access","refresh_token":"new-refresh","token_type":"Bearer","expires_in":300,"scope":"read"}`)
+	_ = c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "old-access", RefreshToken: "old-refresh"})
+
+	refresh, err := c.RefreshOAuthToken(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refresh.RefreshTokenRotated || refresh.Scope != "read" || time.Until(refresh.ExpiresAt) < 4*time.Minute {
+		t.Errorf("unexpected refresh %+v", refresh)
+	}
+	token, _ := c.oauthTokenStore.GetToken(t.Context())
+	if token.AccessToken != "new-access" {
+		t.Errorf("expected the new token to be stored, got %+v", token)
+	}
+
+	for _, want := range []string{"POST /token HTTP/1.1", "grant_type=refresh_token", "HTTP/1.1 200 OK", `"scope":"read"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("exchange log missing %q:\n%s", want, logs.String())
+		}
+	}
+	for _, secret := range []string{"old-refresh", "new-access", "new-refresh"} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("exchange log leaks %s:\n%s", secret, logs.String())
+		}
+	}
+}
+
+func TestRefreshOAuthTokenErrors(t *testing.T) {
+	c, _, refreshes := newRefreshTestClient(t, http.StatusBadRequest, `{"error":"invalid_grant","error_description":"refresh token expired"}`)
+
+	if _, err := c.RefreshOAuthToken(t.Context()); err == nil || !strings.Contains(err.Error(), "no token to refresh") {
+		t.Errorf("expected a missing token error, got %v", err)
+	}
+	_ = c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "access"})
+	if _, err := c.RefreshOAuthToken(t.Context()); err == nil || !strings.Contains(err.Error(), "no refresh token") {
+		t.Errorf("expected a missing refresh token error, got %v", err)
+	}
+	if refreshes.Load() != 0 {
+		t.Error("no refresh grant should be sent without a refresh token")
+	}
+
+	_ = c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "access", RefreshToken: "refresh"})
+	if _, err := c.RefreshOAuthToken(t.Context()); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected the authorization server's error, got %v", err)
+	}
+}
+
+func TestTokenLifecycleTick(t *testing.T) {
+	c, logs, refreshes := newRefreshTestClient(t, http.StatusOK, `{"access_token":"new-access","token_type":"Bearer","expires_in":300}`)
+	c.oauthConfig.RefreshBefore = time.Minute
+	c.oauthConfig.TokenLogInterval = time.Minute
+	now := time.Now()
+	_ = c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: now.Add(10 * time.Minute)})
+
+	c.tokenLifecycleTick(t.Context(), now)
+	c.tokenLifecycleTick(t.Context(), now.Add(30*time.Second))
+	if strings.Count(logs.String(), "Access token expires in 10m0s (refresh token: yes)") != 1 {
+		t.Errorf("expected the lifetime to be logged once per interval:\n%s", logs.String())
+	}
+	if refreshes.Load() != 0 {
+		t.Error("the token should not be refreshed long before it expires")
+	}
+
+	c.tokenLifecycleTick(t.Context(), now.Add(9*time.Minute+30*time.Second))
+	if refreshes.Load() != 1 || !strings.Contains(logs.String(), "Access token refreshed") {
+		t.Errorf("expected a refresh within the margin, got %d:\n%s", refreshes.Load(), logs.String())
+	}
+	token, _ := c.oauthTokenStore.GetToken(t.Context())
+	if token.AccessToken != "new-access" || token.RefreshToken != "refresh" {
+		t.Errorf("expected the refreshed token to keep the refresh token, got %+v", token)
+	}
+}
+
+func TestTokenLifecycleTickStopsRetryingFailedRefresh(t *testing.T) {
+	c, logs, refreshes := newRefreshTestClient(t, http.StatusBadRequest, `{"error":"invalid_grant"}`)
+	c.oauthConfig.RefreshBefore = time.Minute
+	now := time.Now()
+	_ = c.oauthTokenStore.SaveToken(t.Context(), &client.Token{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: now.Add(30 * time.Second)})
+
+	c.tokenLifecycleTick(t.Context(), now)
+	c.tokenLifecycleTick(t.Context(), now.Add(10*time.Second))
+	if refreshes.Load() != 1 {
+		t.Errorf("expected a failed refresh token to be tried once, got %d", refreshes.Load())
+	}
+	if !strings.Contains(logs.String(), "Proactive token refresh failed") {
+		t.Errorf("expected the failure to be logged:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// registrationReadOnlyFields are returned by the authorization server but
+// must not be sent back in an update request (RFC 7592 Section 2.2)
+var registrationReadOnlyFields = []string{
+	"registration_access_token",
+	"registration_client_uri",
+	"client_secret_expires_at",
+	"client_id_issued_at",
+}
+
+// ClientRegistration is a dynamically registered client (RFC 7591) with the
+// credentials to manage it (RFC 7592)
+type ClientRegistration struct {
+	// ClientID is the client identifier issued by the authorization server
+	ClientID string
+	// RegistrationClientURI is the client configuration endpoint; empty if
+	// the authorization server does not support RFC 7592
+	RegistrationClientURI string
+	// RegistrationAccessToken authorizes requests to the client
+	// configuration endpoint
+	RegistrationAccessToken string
+	// Metadata is the client information response as returned
+	Metadata map[string]any
+}
+
+// parseClientRegistration parses a client information response (RFC 7591
+// Section 3.2.1, RFC 7592 Section 3)
+func parseClientRegistration(body []byte) (*ClientRegistration, error) {
+	var metadata map[string]any
+	if err := json.Unmarshal(body, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid client information response: %w", err)
+	}
+	registration := &ClientRegistration{Metadata: metadata}
+	registration.ClientID, _ = metadata["client_id"].(string)
+	registration.RegistrationClientURI, _ = metadata["registration_client_uri"].(string)
+	registration.RegistrationAccessToken, _ = metadata["registration_access_token"].(string)
+	if registration.ClientID == "" {
+		return nil, errors.New("client information response has no client_id")
+	}
+	return registration, nil
+}
+
+// clientRegistrationStore keeps the registration of the current session
+type clientRegistrationStore struct {
+	mu           sync.Mutex
+	registration *ClientRegistration
+}
+
+func (s *clientRegistrationStore) get() *ClientRegistration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.registration
+}
+
+func (s *clientRegistrationStore) set(registration *ClientRegistration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registration = registration
+}
+
+// registrationRecorder keeps the client information response of Dynamic
+// Client Registration, which mcp-go reads only the client ID and secret of,
+// so the client can be managed afterwards
+type registrationRecorder struct {
+	base   http.RoundTripper
+	store  *clientRegistrationStore
+	logger *Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *registrationRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return t.base.RoundTrip(req)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	var registrationRequest map[string]any
+	if json.Unmarshal(body, &registrationRequest) != nil || registrationRequest["redirect_uris"] == nil {
+		return t.base.RoundTrip(withRequestBody(req, body))
+	}
+
+	resp, err := t.base.RoundTrip(withRequestBody(req, body))
+	if err != nil || (resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK) {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxOAuthResponseSize))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registration response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if registration, err := parseClientRegistration(respBody); err == nil {
+		t.store.set(registration)
+		if registration.RegistrationClientURI == "" {
+			t.logger.Info("The authorization server returned no registration_client_uri; the client cannot be managed (RFC 7592)")
+		}
+	}
+	return resp, nil
+}
+
+// ClientRegistration returns the client registered dynamically for this
+// session, as last returned by the authorization server
+func (c *Client) ClientRegistration() (*ClientRegistration, error) {
+	registration := c.registration.get()
+	if registration == nil {
+		return nil, errors.New("no client was registered dynamically in this session")
+	}
+	return registration, nil
+}
+
+// ReadClientRegistration reads the client configuration from the
+// authorization server (RFC 7592 Section 2.1)
+func (c *Client) ReadClientRegistration(ctx context.Context) (*ClientRegistration, error) {
+	current, err := c.managedRegistration()
+	if err != nil {
+		return nil, err
+	}
+	status, body, err := manageClientRegistration(ctx, http.MethodGet, current, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, clientConfigurationError(status, body)
+	}
+	return c.storeManagedRegistration(current, body)
+}
+
+// UpdateClientRegistration replaces the client configuration with the
+// current one plus changes (RFC 7592 Section 2.2). A nil value removes the
+// field. The update carries every field, as the authorization server treats
+// omitted ones as deleted.
+func (c *Client) UpdateClientRegistration(ctx context.Context, changes map[string]any) (*ClientRegistration, error) {
+	current, err := c.managedRegistration()
+	if err != nil {
+		return nil, err
+	}
+	update := maps.Clone(current.Metadata)
+	for _, field := range registrationReadOnlyFields {
+		delete(update, field)
+	}
+	for field, value := range changes {
+		if value == nil {
+			delete(update, field)
+		} else {
+			update[field] = value
+		}
+	}
+	// The client_id must match the one being updated
+	update["client_id"] = current.ClientID
+
+	status, body, err := manageClientRegistration(ctx, http.MethodPut, current, update)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, clientConfigurationError(status, body)
+	}
+	updated, err := c.storeManagedRegistration(current, body)
+	if err != nil {
+		return nil, err
+	}
+	if secret, _ := updated.Metadata["client_secret"].(string); secret != "" && c.oauthHandler != nil && secret != c.oauthHandler.GetClientSecret() {
+		c.logger.Warning("The authorization server issued a new client secret; this session keeps using the old one")
+	}
+	return updated, nil
+}
+
+// DeleteClientRegistration deregisters the client (RFC 7592 Section 2.3)
+func (c *Client) DeleteClientRegistration(ctx context.Context) error {
+	current, err := c.managedRegistration()
+	if err != nil {
+		return err
+	}
+	status, body, err := manageClientRegistration(ctx, http.MethodDelete, current, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		if status == http.StatusOK {
+			c.logger.Warning("Deregistration answered 200 instead of 204 No Content (RFC 7592 Section 2.3)")
+		} else {
+			return clientConfigurationError(status, body)
+		}
+	}
+	c.registration.set(nil)
+	return nil
+}
+
+// managedRegistration returns the registration if it can be managed
+func (c *Client) managedRegistration() (*ClientRegistration, error) {
+	registration, err := c.ClientRegistration()
+	if err != nil {
+		return nil, err
+	}
+	if registration.RegistrationClientURI == "" || registration.RegistrationAccessToken == "" {
+		return nil, errors.New("the authorization server returned no registration_client_uri and registration_access_token; it does not support RFC 7592")
+	}
+	return registration, nil
+}
+
+// storeManagedRegistration parses a client information response of the
+// client configuration endpoint, logs where it departs from RFC 7592 and
+// stores it. Fields the response omits are kept from current, since the
+// server may not return the registration access token again.
+func (c *Client) storeManagedRegistration(current *ClientRegistration, body []byte) (*ClientRegistration, error) {
+	registration, err := parseClientRegistration(body)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range registrationResponseIssues(current, registration) {
+		c.logger.Warning("%s", issue)
+	}
+	if registration.RegistrationClientURI == "" {
+		registration.RegistrationClientURI = current.RegistrationClientURI
+	}
+	if registration.RegistrationAccessToken == "" {
+		registration.RegistrationAccessToken = current.RegistrationAccessToken
+	} else if registration.RegistrationAccessToken != current.RegistrationAccessToken {
+		c.logger.Info("The authorization server rotated the registration access token")
+	}
+	c.registration.set(registration)
+	return registration, nil
+}
+
+// registrationResponseIssues lists where a client information response of
+// the client configuration endpoint departs from RFC 7592 Section 3
+func registrationResponseIssues(current, response *ClientRegistration) []string {
+	var issues []string
+	if response.ClientID != current.ClientID {
+		issues = append(issues, fmt.Sprintf("Response client_id %q does not match the registered client %q", response.ClientID, current.ClientID))
+	}
+	if response.RegistrationClientURI == "" {
+		issues = append(issues, "Response has no registration_client_uri (required by RFC 7592 Section 3)")
+	}
+	if response.RegistrationAccessToken == "" {
+		issues = append(issues, "Response has no registration_access_token (required by RFC 7592 Section 3)")
+	}
+	return issues
+}
+
+// manageClientRegistration sends a request to the client configuration
+// endpoint, authorized with the registration access token, and returns the
+// status code and the size-limited response body
+func manageClientRegistration(ctx context.Context, method string, registration *ClientRegistration, payload map[string]any) (int, []byte, error) {
+	endpoint, err := url.Parse(registration.RegistrationClientURI)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid registration_client_uri: %w", err)
+	}
+	// The registration access token is a bearer credential
+	if endpoint.Scheme != schemeHTTPS && !(endpoint.Scheme == schemeHTTP && isLocalhost(endpoint.Host)) {
+		return 0, nil, fmt.Errorf("security: registration access token can only be sent over HTTPS, refusing to send over %s", endpoint.Scheme)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to encode client metadata: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+registration.RegistrationAccessToken)
+
+	resp, err := httpClientFor(ctx, oauthRequestTimeout).Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxOAuthResponseSize))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// clientConfigurationError explains an error response of the client
+// configuration endpoint (RFC 7592 Section 2)
+func clientConfigurationError(status int, body []byte) error {
+	err := parseOAuthError(status, body)
+	switch status {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("registration access token rejected: %w", err)
+	case http.StatusForbidden, http.StatusNotFound:
+		return fmt.Errorf("client unknown or not manageable (status %d): %w", status, err)
+	}
+	return err
+}
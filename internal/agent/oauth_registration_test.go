@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRegistrationTestServer returns an authorization server with a client
+// configuration endpoint for client "abc" and the last update it received
+func newRegistrationTestServer(t *testing.T) (*httptest.Server, *map[string]any) {
+	t.Helper()
+	var update map[string]any
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/register" {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"client_id":"abc","client_name":"mcp-debug","redirect_uris":["http://localhost:8765/callback"],"registration_client_uri":"%s/register/abc","registration_access_token":"reg-1"}`, server.URL)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer reg-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error":"invalid_token"}`)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = fmt.Fprint(w, `{"client_id":"abc","client_name":"mcp-debug","redirect_uris":["http://localhost:8765/callback"]}`)
+		case http.MethodPut:
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			response := maps.Clone(update)
+			response["registration_client_uri"] = server.URL + "/register/abc"
+			response["registration_access_token"] = "reg-1"
+			_ = json.NewEncoder(w).Encode(response)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &update
+}
+
+// registerTestClient registers a client through the recorder as mcp-go would
+func registerTestClient(t *testing.T, c *Client, server *httptest.Server) {
+	t.Helper()
+	client := &http.Client{Transport: &registrationRecorder{base: http.DefaultTransport, store: &c.registration, logger: c.logger}}
+	resp, err := client.Post(server.URL+"/register", "application/json", strings.NewReader(`{"client_name":"mcp-debug","redirect_uris":["http://localhost:8765/callback"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body["client_id"] != "abc" {
+		t.Fatalf("expected the response to reach mcp-go unchanged, got %v, %v", body, err)
+	}
+}
+
+func TestRegistrationRecorder(t *testing.T) {
+	server, _ := newRegistrationTestServer(t)
+	c := newStubbedClient(t, &stubMCPClient{})
+
+	if _, err := c.ClientRegistration(); err == nil {
+		t.Error("expected an error before registration")
+	}
+	registerTestClient(t, c, server)
+
+	registration, err := c.ClientRegistration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registration.ClientID != "abc" || registration.RegistrationClientURI != server.URL+"/register/abc" || registration.RegistrationAccessToken != "reg-1" {
+		t.Errorf("unexpected registration %+v", registration)
+	}
+}
+
+func TestClientRegistrationLifecycle(t *testing.T) {
+	server, update := newRegistrationTestServer(t)
+	var logs bytes.Buffer
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.logger = NewLoggerWithWriter(false, false, false, &logs)
+	registerTestClient(t, c, server)
+
+	read, err := c.ReadClientRegistration(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.RegistrationAccessToken != "reg-1" || read.RegistrationClientURI != server.URL+"/register/abc" {
+		t.Errorf("expected the management credentials to be kept, got %+v", read)
+	}
+	if !strings.Contains(logs.String(), "Response has no registration_access_token") {
+		t.Errorf("expected the missing registration_access_token to be reported:\n%s", logs.String())
+	}
+
+	updated, err := c.UpdateClientRegistration(t.Context(), map[string]any{"client_name": "renamed", "redirect_uris": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Metadata["client_name"] != "renamed" {
+		t.Errorf("unexpected update response %v", updated.Metadata)
+	}
+	if (*update)["client_id"] != "abc" || (*update)["redirect_uris"] != nil {
+		t.Errorf("unexpected update request %v", *update)
+	}
+	for _, field := range registrationReadOnlyFields {
+		if _, ok := (*update)[field]; ok {
+			t.Errorf("update request carries read-only field %s", field)
+		}
+	}
+
+	if err := c.DeleteClientRegistration(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ClientRegistration(); err == nil {
+		t.Error("expected the registration to be forgotten after deletion")
+	}
+}
+
+func TestClientRegistrationErrors(t *testing.T) {
+	server, _ := newRegistrationTestServer(t)
+	c := newStubbedClient(t, &stubMCPClient{})
+
+	c.registration.set(&ClientRegistration{ClientID: "abc"})
+	if _, err := c.ReadClientRegistration(t.Context()); err == nil || !strings.Contains(err.Error(), "RFC 7592") {
+		t.Errorf("expected an error without a client configuration endpoint, got %v", err)
+	}
+
+	c.registration.set(&ClientRegistration{ClientID: "abc", RegistrationClientURI: server.URL + "/register/abc", RegistrationAccessToken: "wrong"})
+	if _, err := c.ReadClientRegistration(t.Context()); err == nil || !strings.Contains(err.Error(), "registration access token rejected: invalid_token") {
+		t.Errorf("expected a rejected token error, got %v", err)
+	}
+
+	c.registration.set(&ClientRegistration{ClientID: "abc", RegistrationClientURI: "http://auth.example.com/register/abc", RegistrationAccessToken: "reg-1"})
+	if err := c.DeleteClientRegistration(t.Context()); err == nil || !strings.Contains(err.Error(), "HTTPS") {
+		t.Errorf("expected the token to be withheld over plain HTTP, got %v", err)
+	}
+}
+
+func TestRegistrationResponseIssues(t *testing.T) {
+	current := &ClientRegistration{ClientID: "abc", RegistrationClientURI: "https://auth.example.com/register/abc", RegistrationAccessToken: "reg"}
+	if issues := registrationResponseIssues(current, current); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+	issues := registrationResponseIssues(current, &ClientRegistration{ClientID: "xyz"})
+	if len(issues) != 3 || !strings.Contains(issues[0], `"xyz" does not match`) {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
@@ -129,7 +129,7 @@ func (t *resourceRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 			// Continue with original request if adding resource parameter fails
 			return t.base.RoundTrip(req)
 		}
-		if t.logger != nil && t.logger.verbose {
+		if t.logger != nil && t.logger.isVerbose() {
 			t.logger.Info("Added resource parameter to OAuth request: %s", t.resourceURI)
 		}
 	}
@@ -0,0 +1,113 @@
+// Package agent implements OAuth 2.0 Token Revocation per RFC 7009.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// Token type hints for revocation and introspection (RFC 7009 Section 2.1)
+const (
+	tokenTypeHintAccessToken  = "access_token"
+	tokenTypeHintRefreshToken = "refresh_token"
+)
+
+// RevokeTokens revokes the current refresh and access tokens at the
+// authorization server's revocation endpoint and clears them locally, so the
+// next request starts a new authorization. The refresh token is revoked
+// first, since servers may then invalidate the access tokens issued from it.
+func (c *Client) RevokeTokens(ctx context.Context) error {
+	handler, metadata, err := c.authServerMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	if metadata.RevocationEndpoint == "" {
+		return fmt.Errorf("authorization server does not advertise a revocation_endpoint")
+	}
+
+	token, err := c.oauthTokenStore.GetToken(ctx)
+	if err != nil {
+		return fmt.Errorf("no token to revoke: %w", err)
+	}
+
+	clientAuth := oauthClientAuthParams(handler)
+	var errs []error
+	if token.RefreshToken != "" {
+		if err := revokeToken(ctx, metadata.RevocationEndpoint, token.RefreshToken, tokenTypeHintRefreshToken, clientAuth); err != nil {
+			errs = append(errs, fmt.Errorf("refresh token: %w", err))
+		} else {
+			c.logger.Success("Refresh token revoked")
+		}
+	}
+	if token.AccessToken != "" {
+		if err := revokeToken(ctx, metadata.RevocationEndpoint, token.AccessToken, tokenTypeHintAccessToken, clientAuth); err != nil {
+			errs = append(errs, fmt.Errorf("access token: %w", err))
+		} else {
+			c.logger.Success("Access token revoked")
+		}
+	}
+
+	// Drop the tokens even if revocation failed; they must not be reused
+	if err := c.oauthTokenStore.SaveToken(ctx, &client.Token{}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to clear stored token: %w", err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("token revocation failed: %w", err)
+	}
+	return nil
+}
+
+// authServerMetadata returns the OAuth handler of the current session and the
+// metadata of its authorization server
+func (c *Client) authServerMetadata(ctx context.Context) (*transport.OAuthHandler, *transport.AuthServerMetadata, error) {
+	if !c.OAuthEnabled() {
+		return nil, nil, errors.New("OAuth is not enabled")
+	}
+	if c.oauthHandler == nil || c.oauthTokenStore == nil {
+		return nil, nil, errors.New("no OAuth session established")
+	}
+
+	metadata, err := c.oauthHandler.GetServerMetadata(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get authorization server metadata: %w", err)
+	}
+	return c.oauthHandler, metadata, nil
+}
+
+// oauthClientAuthParams returns the client authentication parameters sent in
+// the body of revocation and introspection requests
+func oauthClientAuthParams(handler *transport.OAuthHandler) url.Values {
+	params := url.Values{}
+	params.Set("client_id", handler.GetClientID())
+	if secret := handler.GetClientSecret(); secret != "" {
+		params.Set("client_secret", secret)
+	}
+	return params
+}
+
+// revokeToken sends a revocation request (RFC 7009 Section 2.1). The server
+// answers 200 for both revoked and already invalid tokens.
+func revokeToken(ctx context.Context, endpoint, token, tokenTypeHint string, clientAuth url.Values) error {
+	form := url.Values{}
+	for key, values := range clientAuth {
+		form[key] = values
+	}
+	form.Set("token", token)
+	form.Set("token_type_hint", tokenTypeHint)
+
+	status, body, err := postOAuthForm(ctx, endpoint, form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return parseOAuthError(status, body)
+	}
+	return nil
+}
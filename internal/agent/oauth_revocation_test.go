@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRevokeToken(t *testing.T) {
+	var forms []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		forms = append(forms, r.PostForm)
+		if r.PostForm.Get("token") == "unsupported" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unsupported_token_type"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	clientAuth := url.Values{"client_id": {"cli"}}
+	if err := revokeToken(t.Context(), server.URL, "rt", tokenTypeHintRefreshToken, clientAuth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := forms[0]
+	if got.Get("token") != "rt" || got.Get("token_type_hint") != "refresh_token" || got.Get("client_id") != "cli" {
+		t.Errorf("unexpected form: %v", got)
+	}
+
+	err := revokeToken(t.Context(), server.URL, "unsupported", tokenTypeHintAccessToken, clientAuth)
+	if err == nil || !strings.Contains(err.Error(), "unsupported_token_type") {
+		t.Errorf("expected OAuth error, got %v", err)
+	}
+}
+
+func TestRevokeTokensRequiresSession(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	if err := c.RevokeTokens(t.Context()); err == nil {
+		t.Error("expected error without OAuth")
+	}
+
+	c.oauthConfig = &OAuthConfig{Enabled: true}
+	if err := c.RevokeTokens(t.Context()); err == nil || !strings.Contains(err.Error(), "no OAuth session") {
+		t.Errorf("expected missing session error, got %v", err)
+	}
+}
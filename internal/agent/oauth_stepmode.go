@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Phases of the OAuth flow step mode pauses before
+const (
+	StepPhaseDiscovery     = "discovery"
+	StepPhaseRegistration  = "registration"
+	StepPhaseAuthorization = "authorization redirect"
+	StepPhaseCodeExchange  = "code exchange"
+	StepPhaseTokenUse      = "token use"
+)
+
+// errStepModeAborted is returned when the user declines a step
+var errStepModeAborted = errors.New("aborted in OAuth step mode")
+
+// clientSecretParam matches the client secret in form bodies, which step
+// mode shows redacted
+var clientSecretParam = regexp.MustCompile(`(^|&)client_secret=[^&]*`)
+
+// oauthStepper implements --oauth-step-mode: it shows every OAuth request
+// before it is sent and waits for the user to confirm it, so the parameter
+// an authorization server rejects can be pinpointed
+type oauthStepper struct {
+	mu       sync.Mutex
+	readLine lineReader
+	out      io.Writer
+
+	// continued is set once the user chose to finish without pausing
+	continued bool
+
+	// tokenUsed is set once the first MCP request carrying a token was shown;
+	// later requests are not paused
+	tokenUsed bool
+}
+
+// newOAuthStepper creates a stepper prompting on standard input
+func newOAuthStepper() *oauthStepper {
+	return &oauthStepper{readLine: stdinLineReader(), out: os.Stdout}
+}
+
+// setLineReader replaces how steps are confirmed; the REPL passes its line
+// editor
+func (s *oauthStepper) setLineReader(readLine lineReader, out io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readLine = readLine
+	s.out = out
+}
+
+// confirm shows what the next step will send and waits for the user.
+// Prompts are serialized, so concurrent discovery probes are shown one at a
+// time.
+func (s *oauthStepper) confirm(ctx context.Context, phase, detail string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.continued {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(s.out, "\n=== OAuth step: %s ===\n%s\n\n", phase, strings.TrimRight(detail, "\r\n"))
+	for {
+		answer, err := s.readLine(ctx, "Proceed? [Y]es, [n]o to abort, [c]ontinue without pausing: ")
+		if err != nil {
+			return fmt.Errorf("%w: %v", errStepModeAborted, err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+			return nil
+		case "c", "continue":
+			s.continued = true
+			return nil
+		case "n", "no":
+			return errStepModeAborted
+		}
+	}
+}
+
+// confirmTokenUse reports whether req is the first MCP request carrying a
+// token, which is the only one paused in the token use phase
+func (s *oauthStepper) confirmTokenUse(req *http.Request) bool {
+	if req.Header.Get("Authorization") == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := !s.tokenUsed
+	s.tokenUsed = true
+	return first
+}
+
+// RoundTripper wraps base so OAuth requests are confirmed before they are
+// sent
+func (s *oauthStepper) RoundTripper(base http.RoundTripper) http.RoundTripper {
+	return &stepModeRoundTripper{stepper: s, base: base}
+}
+
+// MCPRoundTripper wraps base so the first MCP request carrying a token is
+// confirmed before it is sent
+func (s *oauthStepper) MCPRoundTripper(base http.RoundTripper) http.RoundTripper {
+	return &stepModeRoundTripper{stepper: s, base: base, mcp: true}
+}
+
+// stepModeRoundTripper pauses requests of the OAuth flow
+type stepModeRoundTripper struct {
+	stepper *oauthStepper
+	base    http.RoundTripper
+
+	// mcp is set on the MCP transport, where only the first request with a
+	// token is paused
+	mcp bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *stepModeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	phase := StepPhaseTokenUse
+	if rt.mcp {
+		if !rt.stepper.confirmTokenUse(req) {
+			return rt.base.RoundTrip(req)
+		}
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if !rt.mcp {
+		phase = oauthRequestPhase(req, body)
+	}
+	if err := rt.stepper.confirm(req.Context(), phase, dumpStepRequest(req, body)); err != nil {
+		return nil, err
+	}
+	return rt.base.RoundTrip(req)
+}
+
+// readRequestBody reads the body of req and replaces it, so the request can
+// still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// withRequestBody returns a copy of req sending body instead
+func withRequestBody(req *http.Request, body []byte) *http.Request {
+	rewritten := req.Clone(req.Context())
+	rewritten.Body = io.NopCloser(bytes.NewReader(body))
+	rewritten.ContentLength = int64(len(body))
+	return rewritten
+}
+
+// oauthRequestPhase names the phase of the flow a request to an
+// authorization server belongs to
+func oauthRequestPhase(req *http.Request, body []byte) string {
+	if req.Method == http.MethodGet {
+		return StepPhaseDiscovery
+	}
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return StepPhaseRegistration
+	}
+	form, _ := url.ParseQuery(string(body))
+	switch form.Get("grant_type") {
+	case "authorization_code", deviceCodeGrantType:
+		return StepPhaseCodeExchange
+	case "refresh_token":
+		return "token refresh"
+	case "":
+		if form.Has("token") {
+			return "token revocation"
+		}
+		return "device authorization"
+	default:
+		return "token request"
+	}
+}
+
+// dumpStepRequest renders req the way it goes over the wire, with
+// credential header values and client secrets redacted
+func dumpStepRequest(req *http.Request, body []byte) string {
+	shown := req.Clone(req.Context())
+	shown.Header = stepRedactHeaders(req.Header)
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		body = clientSecretParam.ReplaceAll(body, []byte("${1}client_secret=[REDACTED]"))
+	}
+	shown.Body = io.NopCloser(bytes.NewReader(body))
+	shown.ContentLength = int64(len(body))
+
+	dump, err := httputil.DumpRequestOut(shown, true)
+	if err != nil {
+		return fmt.Sprintf("%s %s\n(failed to render request: %v)", req.Method, req.URL, err)
+	}
+	return string(dump)
+}
+
+// stepRedactHeaders hides credential header values but keeps the
+// authorization scheme, which step mode is often used to check
+func stepRedactHeaders(header http.Header) http.Header {
+	redacted := redactHeaders(header)
+	if auth := header.Get("Authorization"); auth != "" {
+		if scheme, _, ok := strings.Cut(auth, " "); ok {
+			redacted.Set("Authorization", scheme+" [REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// describeAuthorizationURL lists the parameters of an authorization URL one
+// per line, for confirming the redirect in step mode
+func describeAuthorizationURL(authURL string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return authURL
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "GET %s\n", authURL)
+	query := parsed.Query()
+	if len(query) > 0 {
+		b.WriteString("\nParameters:\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		for _, value := range query[name] {
+			fmt.Fprintf(&b, "  %s = %s\n", name, value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// scriptedStepper returns a stepper answering prompts with answers in
+// order, and the buffer it writes to
+func scriptedStepper(answers ...string) (*oauthStepper, *bytes.Buffer) {
+	var out bytes.Buffer
+	s := &oauthStepper{out: &out}
+	s.readLine = func(ctx context.Context, prompt string) (string, error) {
+		if len(answers) == 0 {
+			return "", errors.New("no more answers")
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+	return s, &out
+}
+
+func TestStepModeShowsRequestsBeforeSending(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		received = append(received, r.Form.Get("grant_type"))
+	}))
+	defer server.Close()
+
+	stepper, out := scriptedStepper("y", "n")
+	client := &http.Client{Transport: stepper.RoundTripper(http.DefaultTransport)}
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "client_secret": {"s3cret"}}
+	resp, err := client.PostForm(server.URL+"/token", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if len(received) != 1 || received[0] != "authorization_code" {
+		t.Fatalf("expected the confirmed request to arrive intact, got %v", received)
+	}
+	for _, want := range []string{"=== OAuth step: code exchange ===", "POST /token HTTP/1.1", "code=abc", "client_secret=[REDACTED]"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("step output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Error("the client secret should be redacted")
+	}
+
+	if _, err := client.Get(server.URL + "/.well-known/oauth-authorization-server"); !errors.Is(err, errStepModeAborted) {
+		t.Errorf("expected the declined request to abort, got %v", err)
+	}
+	if len(received) != 1 {
+		t.Error("the declined request should not be sent")
+	}
+}
+
+func TestStepModeContinueStopsPausing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	stepper, _ := scriptedStepper("maybe", "c")
+	client := &http.Client{Transport: stepper.RoundTripper(http.DefaultTransport)}
+	for range 3 {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+}
+
+func TestStepModePausesFirstTokenUseOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	stepper, out := scriptedStepper("y")
+	client := &http.Client{Transport: stepper.MCPRoundTripper(http.DefaultTransport)}
+	send := func(token string) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/mcp", strings.NewReader(`{}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	send("")
+	send("tok123")
+	send("tok123")
+	if strings.Count(out.String(), "=== OAuth step: token use ===") != 1 {
+		t.Errorf("expected one token use step, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Authorization: Bearer [REDACTED]") || strings.Contains(out.String(), "tok123") {
+		t.Errorf("expected the token to be redacted with its scheme kept:\n%s", out.String())
+	}
+}
+
+func TestOAuthRequestPhase(t *testing.T) {
+	tests := []struct {
+		method, contentType, body, want string
+	}{
+		{http.MethodGet, "", "", StepPhaseDiscovery},
+		{http.MethodPost, "application/json", `{"redirect_uris":[]}`, StepPhaseRegistration},
+		{http.MethodPost, "application/x-www-form-urlencoded", "grant_type=authorization_code&code=x", StepPhaseCodeExchange},
+		{http.MethodPost, "application/x-www-form-urlencoded", "grant_type=refresh_token", "token refresh"},
+		{http.MethodPost, "application/x-www-form-urlencoded", "client_id=x&scope=a", "device authorization"},
+		{http.MethodPost, "application/x-www-form-urlencoded", "token=x", "token revocation"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "https://auth.example.com/", nil)
+		req.Header.Set("Content-Type", tt.contentType)
+		if got := oauthRequestPhase(req, []byte(tt.body)); got != tt.want {
+			t.Errorf("oauthRequestPhase(%s %q) = %q, want %q", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeAuthorizationURL(t *testing.T) {
+	got := describeAuthorizationURL("https://auth.example.com/authorize?response_type=code&client_id=abc&resource=https%3A%2F%2Fmcp.example.com%2Fmcp")
+	want := "Parameters:\n" +
+		"  client_id = abc\n" +
+		"  resource = https://mcp.example.com/mcp\n" +
+		"  response_type = code\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("got:\n%s\nwant suffix:\n%s", got, want)
+	}
+}
+
+func TestHTTPClientForStepMode(t *testing.T) {
+	if withHTTPClients(context.Background(), nil) != context.Background() {
+		t.Error("expected the context unchanged without clients")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	stepper, prompts := scriptedStepper("")
+	ctx := withHTTPClients(context.Background(), &httpClients{stepper: stepper})
+
+	resp, err := httpClientFor(ctx, metadataRequestTimeout).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	for _, want := range []string{"GET / HTTP/1.1", "User-Agent: " + userAgent} {
+		if !strings.Contains(prompts.String(), want) {
+			t.Errorf("expected %q to be shown in step mode:\n%s", want, prompts.String())
+		}
+	}
+}
@@ -62,6 +62,22 @@ func (t *scopeRetryTracker) getAttempts(resource, path, operation string) int {
 	return t.attempts[key]
 }
 
+// stepUpError reports that an insufficient_scope challenge could not be
+// resolved by step-up authorization. classifyError maps it to
+// ErrInsufficientScope.
+type stepUpError struct {
+	err error
+}
+
+func (e *stepUpError) Error() string { return e.err.Error() }
+
+func (e *stepUpError) Unwrap() error { return e.err }
+
+// newStepUpError wraps err as a failed step-up authorization
+func newStepUpError(err error) error {
+	return &stepUpError{err: err}
+}
+
 // detectInsufficientScope checks if an HTTP response indicates an insufficient_scope error
 // per RFC 6750 Section 3 and extracts the challenge information.
 //
@@ -181,8 +197,8 @@ func (rt *stepUpRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		}
 		// Close original response body
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("max step-up authorization retries (%d) exceeded for %s %s (attempts: %d)",
-			rt.config.StepUpMaxRetries, req.Method, req.URL.Path, attempts)
+		return nil, newStepUpError(fmt.Errorf("max step-up authorization retries (%d) exceeded for %s %s (attempts: %d)",
+			rt.config.StepUpMaxRetries, req.Method, req.URL.Path, attempts))
 	}
 
 	attempts := rt.retryTracker.getAttempts(resource, path, operation)
@@ -197,7 +213,7 @@ func (rt *stepUpRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 			rt.logger.Warning("Insufficient_scope error without scope parameter - cannot determine required scopes")
 		}
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("insufficient_scope error without scope parameter")
+		return nil, newStepUpError(fmt.Errorf("insufficient_scope error without scope parameter"))
 	}
 
 	if rt.logger != nil {
@@ -216,7 +232,7 @@ func (rt *stepUpRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 			rt.logger.Warning("Suspicious scope request detected: %v", err)
 		}
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("scope validation failed: %w", err)
+		return nil, newStepUpError(fmt.Errorf("scope validation failed: %w", err))
 	}
 
 	// User prompt if enabled
@@ -226,7 +242,7 @@ func (rt *stepUpRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 				rt.logger.Info("User declined step-up authorization")
 			}
 			_ = resp.Body.Close()
-			return nil, fmt.Errorf("user declined step-up authorization")
+			return nil, newStepUpError(fmt.Errorf("user declined step-up authorization"))
 		}
 	}
 
@@ -238,7 +254,7 @@ func (rt *stepUpRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		rt.logger.Info("Requesting additional permissions...")
 	}
 	if err := rt.reauthorizeFunc(req.Context(), challenge.Scopes); err != nil {
-		return nil, fmt.Errorf("step-up re-authorization failed: %w", err)
+		return nil, newStepUpError(fmt.Errorf("step-up re-authorization failed: %w", err))
 	}
 
 	if rt.logger != nil {
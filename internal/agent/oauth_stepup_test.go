@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -378,6 +379,9 @@ func TestStepUpRoundTripper(t *testing.T) {
 		if !strings.Contains(err.Error(), "max step-up authorization retries") {
 			t.Errorf("expected max retries error, got: %v", err)
 		}
+		if !errors.Is(classifyError(err), ErrInsufficientScope) {
+			t.Errorf("expected the failed step-up to classify as ErrInsufficientScope, got: %v", err)
+		}
 		if reauthorizeCount != 2 {
 			t.Errorf("expected reauthorization count to stay at 2, got %d", reauthorizeCount)
 		}
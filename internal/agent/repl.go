@@ -2,13 +2,16 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/chzyer/readline"
 )
@@ -24,14 +27,32 @@ type REPL struct {
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 	commandHandlers map[string]commandHandler
+	// connections holds the named server connections; client is the
+	// current one
+	connections *ConnectionManager
+	// listenerStops stops the notification listener of each secondary
+	// connection when it is disconnected
+	listenerStops map[string]chan struct{}
+	// script is set while a script runs
+	script *scriptState
+	// display holds the display options and the last result
+	display resultDisplay
+	// jobs are the tool calls started in the background
+	jobs replJobs
+	// historyFile keeps the commands across sessions; commands are the
+	// ones loaded from it and entered since, numbered for !n
+	historyFile string
+	commands    []string
 }
 
 // NewREPL creates a new REPL instance
 func NewREPL(client *Client, logger *Logger) *REPL {
 	r := &REPL{
-		client:   client,
-		logger:   logger,
-		stopChan: make(chan struct{}),
+		client:        client,
+		logger:        logger,
+		stopChan:      make(chan struct{}),
+		connections:   NewConnectionManager(client),
+		listenerStops: make(map[string]chan struct{}),
 	}
 	r.commandHandlers = r.buildCommandHandlers()
 	return r
@@ -41,17 +62,21 @@ func NewREPL(client *Client, logger *Logger) *REPL {
 func (r *REPL) Run(ctx context.Context) error {
 	// Set up readline with tab completion
 	completer := r.createCompleter()
-	historyFile := filepath.Join(os.TempDir(), ".mcp_debug_history")
+	historyFile := r.openHistoryFile()
 
 	config := &readline.Config{
 		Prompt:          "MCP> ",
 		HistoryFile:     historyFile,
+		HistoryLimit:    commandHistoryLimit,
 		AutoComplete:    completer,
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
 
-		HistorySearchFold:   true,
-		FuncFilterInputRune: filterInput,
+		// Commands are saved once history references are expanded, and
+		// answers to prompts are not saved at all
+		DisableAutoSaveHistory: true,
+		HistorySearchFold:      true,
+		FuncFilterInputRune:    filterInput,
 	}
 
 	rl, err := readline.NewEx(config)
@@ -60,10 +85,22 @@ func (r *REPL) Run(ctx context.Context) error {
 	}
 	defer func() { _ = rl.Close() }()
 	r.rl = rl
+	if historyFile != "" {
+		// readline rewrites a long history file without keeping its mode
+		_ = os.Chmod(historyFile, 0o600)
+	}
+	// Secondary connections live only as long as the REPL
+	defer r.connections.CloseAll()
+
+	// Rebuild tab completion whenever a list_changed notification refreshes
+	// the client cache (possibly after a debounce window)
+	r.client.onListRefreshed = r.refreshCompleter
+	r.client.onReconnected = r.showReconnectBanner
+	r.client.setInputReader(r.readLine, r.rl.Stdout())
 
 	// Start notification listener in background
 	r.wg.Add(1)
-	go r.notificationListener(ctx)
+	go r.notificationListener(ctx, r.client, nil)
 
 	// Display welcome message
 	r.logger.Info("MCP REPL started. Type 'help' for available commands. Use TAB for completion.")
@@ -100,9 +137,18 @@ func (r *REPL) Run(ctx context.Context) error {
 		if input == "" {
 			continue
 		}
+		input, expanded, err := r.expandHistory(input)
+		if err != nil {
+			r.logger.Error("Error: %v", err)
+			continue
+		}
+		if expanded {
+			fmt.Println(input)
+		}
+		r.recordCommand(input)
 
 		// Parse and execute command
-		if err := r.executeCommand(ctx, input); err != nil {
+		if err := r.executeCommand(withPager(ctx, true), input); err != nil {
 			if errors.Is(err, errExit) {
 				close(r.stopChan)
 				r.wg.Wait()
@@ -116,127 +162,9 @@ func (r *REPL) Run(ctx context.Context) error {
 	}
 }
 
-// completerCache holds cached completion items for different capabilities
-type completerCache struct {
-	tools     []string
-	resources []string
-	prompts   []string
-}
-
-// getCompletionNames retrieves names for tab completion from the client cache
-func (r *REPL) getCompletionNames() completerCache {
-	r.client.mu.RLock()
-	defer r.client.mu.RUnlock()
-
-	cache := completerCache{}
-
-	if r.client.ServerSupportsTools() {
-		cache.tools = make([]string, len(r.client.toolCache))
-		for i, tool := range r.client.toolCache {
-			cache.tools[i] = tool.Name
-		}
-	}
-
-	if r.client.ServerSupportsResources() {
-		cache.resources = make([]string, len(r.client.resourceCache))
-		for i, resource := range r.client.resourceCache {
-			cache.resources[i] = resource.URI
-		}
-	}
-
-	if r.client.ServerSupportsPrompts() {
-		cache.prompts = make([]string, len(r.client.promptCache))
-		for i, prompt := range r.client.promptCache {
-			cache.prompts[i] = prompt.Name
-		}
-	}
-
-	return cache
-}
-
-// buildPcItems converts a slice of strings to readline completer items
-func buildPcItems(names []string) []readline.PrefixCompleterInterface {
-	items := make([]readline.PrefixCompleterInterface, len(names))
-	for i, name := range names {
-		items[i] = readline.PcItem(name)
-	}
-	return items
-}
-
-// buildListItems creates list command completion items based on server capabilities
-func (r *REPL) buildListItems() []readline.PrefixCompleterInterface {
-	var items []readline.PrefixCompleterInterface
-	if r.client.ServerSupportsTools() {
-		items = append(items, readline.PcItem("tools"))
-	}
-	if r.client.ServerSupportsResources() {
-		items = append(items, readline.PcItem("resources"))
-	}
-	if r.client.ServerSupportsPrompts() {
-		items = append(items, readline.PcItem("prompts"))
-	}
-	return items
-}
-
-// buildDescribeItems creates describe command completion items
-func (r *REPL) buildDescribeItems(toolCompleter, resourceCompleter, promptCompleter []readline.PrefixCompleterInterface) []readline.PrefixCompleterInterface {
-	var items []readline.PrefixCompleterInterface
-	if r.client.ServerSupportsTools() {
-		items = append(items, readline.PcItem("tool", toolCompleter...))
-	}
-	if r.client.ServerSupportsResources() {
-		items = append(items, readline.PcItem("resource", resourceCompleter...))
-	}
-	if r.client.ServerSupportsPrompts() {
-		items = append(items, readline.PcItem("prompt", promptCompleter...))
-	}
-	return items
-}
-
-// buildBaseCompleterItems creates the base command completion items
-func buildBaseCompleterItems() []readline.PrefixCompleterInterface {
-	return []readline.PrefixCompleterInterface{
-		readline.PcItem("help"),
-		readline.PcItem("?"),
-		readline.PcItem("exit"),
-		readline.PcItem("quit"),
-		readline.PcItem("notifications",
-			readline.PcItem("on"),
-			readline.PcItem("off"),
-		),
-	}
-}
-
 // createCompleter creates the tab completion configuration
-func (r *REPL) createCompleter() *readline.PrefixCompleter {
-	cache := r.getCompletionNames()
-
-	toolCompleter := buildPcItems(cache.tools)
-	resourceCompleter := buildPcItems(cache.resources)
-	promptCompleter := buildPcItems(cache.prompts)
-
-	listItems := r.buildListItems()
-	describeItems := r.buildDescribeItems(toolCompleter, resourceCompleter, promptCompleter)
-
-	items := buildBaseCompleterItems()
-
-	if len(listItems) > 0 {
-		items = append(items, readline.PcItem("list", listItems...))
-	}
-	if len(describeItems) > 0 {
-		items = append(items, readline.PcItem("describe", describeItems...))
-	}
-	if r.client.ServerSupportsTools() {
-		items = append(items, readline.PcItem("call", toolCompleter...))
-	}
-	if r.client.ServerSupportsResources() {
-		items = append(items, readline.PcItem("get", resourceCompleter...))
-	}
-	if r.client.ServerSupportsPrompts() {
-		items = append(items, readline.PcItem("prompt", promptCompleter...))
-	}
-
-	return readline.NewPrefixCompleter(items...)
+func (r *REPL) createCompleter() *replCompleter {
+	return &replCompleter{r: r}
 }
 
 // filterInput filters input characters for readline
@@ -249,8 +177,10 @@ func filterInput(r rune) (rune, bool) {
 	return r, true
 }
 
-// notificationListener handles notifications in the background
-func (r *REPL) notificationListener(ctx context.Context) {
+// notificationListener handles the notifications of one connection in the
+// background until the REPL stops or, for secondary connections, stop is
+// closed
+func (r *REPL) notificationListener(ctx context.Context, client *Client, stop <-chan struct{}) {
 	defer r.wg.Done()
 
 	for {
@@ -259,27 +189,19 @@ func (r *REPL) notificationListener(ctx context.Context) {
 			return
 		case <-r.stopChan:
 			return
-		case notification := <-r.client.notificationChan:
+		case <-stop:
+			return
+		case notification := <-client.notificationChan:
 			// Temporarily pause readline
 			if r.rl != nil {
 				_, _ = r.rl.Stdout().Write([]byte("\r\033[K"))
 			}
 
 			// Handle the notification (this will log it)
-			if err := r.client.handleNotification(ctx, notification); err != nil {
+			if err := client.handleNotification(ctx, notification); err != nil {
 				r.logger.Error("Failed to handle notification: %v", err)
 			}
 
-			// Update completer if items changed
-			switch notification.Method {
-			case notificationToolsListChanged,
-				notificationResourcesListChanged,
-				notificationPromptsListChanged:
-				if r.rl != nil {
-					r.rl.Config.AutoComplete = r.createCompleter()
-				}
-			}
-
 			// Refresh readline prompt
 			if r.rl != nil {
 				r.rl.Refresh()
@@ -288,20 +210,81 @@ func (r *REPL) notificationListener(ctx context.Context) {
 	}
 }
 
+// refreshCompleter redraws the prompt after the client cache changed. Tab
+// completion reads the caches lazily, so there is nothing to rebuild.
+func (r *REPL) refreshCompleter(method string) {
+	select {
+	case <-r.stopChan:
+		return
+	default:
+	}
+
+	if r.rl != nil {
+		r.rl.Refresh()
+	}
+}
+
+// showReconnectBanner tells the user that a lost connection was restored
+// automatically, since it may happen while they are typing
+func (r *REPL) showReconnectBanner(report ReconnectReport) {
+	session := "session re-initialized"
+	if report.Resumed {
+		session = "session resumed"
+	}
+	r.printAbovePrompt(func() {
+		r.logger.Success("Connection restored after %d attempt(s) (%v): %s, lists refreshed",
+			report.Attempts, report.Downtime.Round(time.Millisecond), session)
+		if report.Subscriptions > 0 {
+			r.logger.Success("  %d resource subscription(s) re-established", report.Subscriptions)
+		}
+		if len(report.FailedSubscriptions) > 0 {
+			r.logger.Warning("  Not re-subscribed: %s", strings.Join(report.FailedSubscriptions, ", "))
+		}
+	})
+}
+
 // commandHandler defines a REPL command with its handler and argument requirements
 type commandHandler struct {
 	minArgs int
 	usage   string
+	// caches names the caches the command reads; stale or not yet listed
+	// ones are re-listed before the handler runs
+	caches func(parts []string) []string
+	// paged commands only print, so their output can go through the pager
+	paged   bool
 	handler func(ctx context.Context, parts []string) error
 }
 
+// usesCaches returns a caches function for a command that always reads the
+// given caches
+func usesCaches(names ...string) func(parts []string) []string {
+	return func([]string) []string { return names }
+}
+
+// targetCache returns the cache backing the target of list, describe and
+// history
+func targetCache(parts []string) []string {
+	if len(parts) < 2 {
+		return nil
+	}
+	switch strings.ToLower(parts[1]) {
+	case "tools", "tool":
+		return []string{cacheTools}
+	case "resources", "resource", "templates", "template":
+		return []string{cacheResources}
+	case "prompts", "prompt":
+		return []string{cachePrompts}
+	}
+	return nil
+}
+
 // buildCommandHandlers creates the map of command handlers
 func (r *REPL) buildCommandHandlers() map[string]commandHandler {
 	return map[string]commandHandler{
-		"help": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+		"help": {minArgs: 1, paged: true, handler: func(ctx context.Context, parts []string) error {
 			return r.showHelp()
 		}},
-		"?": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+		"?": {minArgs: 1, paged: true, handler: func(ctx context.Context, parts []string) error {
 			return r.showHelp()
 		}},
 		"exit": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
@@ -310,14 +293,37 @@ func (r *REPL) buildCommandHandlers() map[string]commandHandler {
 		"quit": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
 			return errExit
 		}},
+		"server": {minArgs: 1, paged: true, handler: func(ctx context.Context, parts []string) error {
+			return r.showServerInfo()
+		}},
+		"stats": {minArgs: 1, paged: true, handler: func(ctx context.Context, parts []string) error {
+			return r.showStats()
+		}},
+		"ping": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.handlePing(ctx)
+		}},
+		"health": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.showHealth(ctx)
+		}},
+		"capabilities": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.handleCapabilities(ctx, parts[1:])
+		}},
 		"list": {
+			caches:  targetCache,
+			paged:   true,
 			minArgs: 2,
-			usage:   "usage: list <tools|resources|prompts>",
+			usage:   "usage: list <tools|resources|prompts|templates> [--page <n>]",
 			handler: func(ctx context.Context, parts []string) error {
-				return r.handleList(ctx, parts[1])
+				page, err := parseListPage(parts[2:])
+				if err != nil {
+					return err
+				}
+				return r.handleList(ctx, parts[1], page)
 			},
 		},
 		"describe": {
+			caches:  targetCache,
+			paged:   true,
 			minArgs: 3,
 			usage:   "usage: describe <tool|resource|prompt> <name>",
 			handler: func(ctx context.Context, parts []string) error {
@@ -332,24 +338,290 @@ func (r *REPL) buildCommandHandlers() map[string]commandHandler {
 			},
 		},
 		"call": {
+			caches:  usesCaches(cacheTools),
 			minArgs: 2,
-			usage:   "usage: call <tool-name> [args...]",
+			usage:   "usage: call [&] [--timeout <duration>] <tool-name> [args... | --interactive] [> file] [&]",
 			handler: func(ctx context.Context, parts []string) error {
-				return r.handleCallTool(ctx, parts[1], strings.Join(parts[2:], " "))
+				// A leading & runs the call in the background, like a trailing one
+				leading := parts[1] == "&"
+				if leading {
+					parts = append([]string{parts[0]}, parts[2:]...)
+					if len(parts) < 2 {
+						return errors.New("usage: call & <tool-name> [args...]")
+					}
+				}
+				parts, timeout, err := parseCallTimeout(parts)
+				if err != nil {
+					return err
+				}
+				if timeout != nil {
+					ctx = WithCallTimeout(ctx, *timeout)
+				}
+				// A trailing & runs the call in the background
+				background := leading
+				if len(parts) > 2 && parts[len(parts)-1] == "&" {
+					background = true
+					parts = parts[:len(parts)-1]
+				}
+				parts, output, err := parseRedirect(parts)
+				if err != nil {
+					return err
+				}
+				if output != "" {
+					ctx = withOutputFile(ctx, output)
+				}
+				if len(parts) > 2 && (parts[2] == "--interactive" || parts[2] == "-i") {
+					if len(parts) > 3 {
+						return errors.New("--interactive builds the arguments and cannot be combined with JSON arguments")
+					}
+					return r.handleInteractiveCall(ctx, parts[1], background)
+				}
+				return r.handleCallTool(ctx, parts[1], strings.Join(parts[2:], " "), background)
+			},
+		},
+		"requests": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.showInFlightRequests()
+		}},
+		"jobs": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.showJobs()
+		}},
+		"wait": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			if len(parts) > 1 {
+				return r.handleWait(ctx, parts[1])
+			}
+			return r.handleWait(ctx, "")
+		}},
+		"trace": {minArgs: 1, paged: true, handler: func(ctx context.Context, parts []string) error {
+			if len(parts) > 1 {
+				return r.showTrace(parts[1])
+			}
+			return r.showTrace("")
+		}},
+		"show": {
+			minArgs: 2,
+			paged:   true,
+			usage:   "usage: show last [format]",
+			handler: func(ctx context.Context, parts []string) error {
+				if parts[1] != "last" || len(parts) > 3 {
+					return errors.New("usage: show last [format]")
+				}
+				if len(parts) > 2 {
+					return r.showLast(parts[2])
+				}
+				return r.showLast("")
+			},
+		},
+		"expand": {
+			minArgs: 2,
+			paged:   true,
+			usage:   "usage: expand <path> [--all]",
+			handler: func(ctx context.Context, parts []string) error {
+				if len(parts) > 3 || (len(parts) == 3 && parts[2] != "--all") {
+					return errors.New("usage: expand <path> [--all]")
+				}
+				return r.expandJSON(parts[1], len(parts) == 3)
+			},
+		},
+		"display": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.handleDisplay(parts[1:])
+		}},
+		"save": {
+			minArgs: 3,
+			usage:   "usage: save last <file>",
+			handler: func(ctx context.Context, parts []string) error {
+				if parts[1] != "last" {
+					return errors.New("usage: save last <file>")
+				}
+				return r.saveLast(strings.Join(parts[2:], " "))
+			},
+		},
+		"cancel": {
+			minArgs: 2,
+			usage:   "usage: cancel <request-id> [reason]",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleCancel(ctx, parts[1], strings.Join(parts[2:], " "))
 			},
 		},
 		"get": {
+			caches:  usesCaches(cacheResources),
+			minArgs: 2,
+			usage:   "usage: get [--if-changed] <resource-uri> [[>] output-file]",
+			handler: func(ctx context.Context, parts []string) error {
+				parts, target, err := parseRedirect(parts)
+				if err != nil {
+					return err
+				}
+				ifChanged := parts[1] == "--if-changed"
+				if ifChanged {
+					parts = append(parts[:1], parts[2:]...)
+				}
+				switch {
+				case len(parts) < 2:
+					return errors.New("usage: get [--if-changed] <resource-uri> [[>] output-file]")
+				case ifChanged && (len(parts) > 2 || target != ""):
+					return errors.New("get --if-changed prints the resource and takes no output file")
+				case len(parts) > 2:
+					target = strings.Join(parts[2:], " ")
+				}
+				if ifChanged {
+					return r.handleGetResourceIfChanged(ctx, parts[1])
+				}
+				return r.handleGetResource(ctx, parts[1], target)
+			},
+		},
+		"template": {
+			caches:  usesCaches(cacheResources),
 			minArgs: 2,
-			usage:   "usage: get <resource-uri>",
+			usage:   "usage: template <template-name> [> file]",
 			handler: func(ctx context.Context, parts []string) error {
-				return r.handleGetResource(ctx, parts[1])
+				parts, output, err := parseRedirect(parts)
+				if err != nil {
+					return err
+				}
+				if output != "" {
+					ctx = withOutputFile(ctx, output)
+				}
+				return r.handleReadTemplate(ctx, strings.Join(parts[1:], " "))
 			},
 		},
 		"prompt": {
+			caches:  usesCaches(cachePrompts),
+			minArgs: 2,
+			usage:   "usage: prompt <prompt-name> [args...] [--raw] [> file]",
+			handler: func(ctx context.Context, parts []string) error {
+				parts, output, err := parseRedirect(parts)
+				if err != nil {
+					return err
+				}
+				if output != "" {
+					ctx = withOutputFile(ctx, output)
+				}
+				// A trailing --raw prints the result as JSON
+				raw := len(parts) > 2 && parts[len(parts)-1] == "--raw"
+				if raw {
+					parts = parts[:len(parts)-1]
+				}
+				return r.handleGetPrompt(ctx, parts[1], strings.Join(parts[2:], " "), raw)
+			},
+		},
+		"loglevel": {
 			minArgs: 2,
-			usage:   "usage: prompt <prompt-name> [args...]",
+			usage:   "usage: loglevel <" + strings.Join(LoggingLevels, "|") + ">",
 			handler: func(ctx context.Context, parts []string) error {
-				return r.handleGetPrompt(ctx, parts[1], strings.Join(parts[2:], " "))
+				return r.handleLogLevel(ctx, parts[1])
+			},
+		},
+		"subscribe": {
+			minArgs: 2,
+			usage:   "usage: subscribe <resource-uri>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleSubscribe(ctx, parts[1], true)
+			},
+		},
+		"unsubscribe": {
+			minArgs: 2,
+			usage:   "usage: unsubscribe <resource-uri>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleSubscribe(ctx, parts[1], false)
+			},
+		},
+		"auth": {
+			minArgs: 2,
+			usage:   "usage: auth scopes [add <scope...>]",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleAuth(ctx, parts[1:])
+			},
+		},
+		"token": {
+			minArgs: 2,
+			usage:   "usage: token <introspect|refresh>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleToken(ctx, parts[1])
+			},
+		},
+		"scopes": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.showScopeReport(ctx)
+		}},
+		"client": {
+			minArgs: 2,
+			usage:   "usage: client <show|update <json>|delete>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleClientRegistration(ctx, parts[1], strings.Join(parts[2:], " "))
+			},
+		},
+		"logout": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.handleLogout(ctx)
+		}},
+		"connect": {
+			minArgs: 3,
+			usage:   "usage: connect <name> <endpoint>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleConnect(ctx, parts[1], parts[2])
+			},
+		},
+		"use": {
+			minArgs: 2,
+			usage:   "usage: use <name>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleUse(parts[1])
+			},
+		},
+		"disconnect": {
+			minArgs: 2,
+			usage:   "usage: disconnect <name>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleDisconnect(parts[1])
+			},
+		},
+		"source": {
+			minArgs: 2,
+			usage:   "usage: source <file>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.runScript(ctx, strings.Join(parts[1:], " "))
+			},
+		},
+		"assert": {
+			caches:  usesCaches(cacheTools),
+			minArgs: 2,
+			usage:   "usage: assert <expectation-file>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleAssert(ctx, strings.Join(parts[1:], " "))
+			},
+		},
+		"history": {
+			caches:  targetCache,
+			paged:   true,
+			minArgs: 1,
+			usage:   "usage: history [count] | history <tools|resources|prompts> [name]",
+			handler: func(ctx context.Context, parts []string) error {
+				if len(parts) == 1 {
+					return r.showCommandHistory("")
+				}
+				if isCommandHistoryCount(parts[1]) {
+					return r.showCommandHistory(parts[1])
+				}
+				return r.handleHistory(parts[1], strings.Join(parts[2:], " "))
+			},
+		},
+		"connections": {minArgs: 1, handler: func(ctx context.Context, parts []string) error {
+			return r.showConnections()
+		}},
+		"endpoint": {
+			minArgs: 2,
+			usage:   "usage: endpoint <url>",
+			handler: func(ctx context.Context, parts []string) error {
+				return r.handleChangeEndpoint(ctx, parts[1])
+			},
+		},
+		"refresh": {
+			minArgs: 1,
+			usage:   "usage: refresh [tools|resources|prompts]",
+			handler: func(ctx context.Context, parts []string) error {
+				target := ""
+				if len(parts) > 1 {
+					target = parts[1]
+				}
+				return r.handleRefresh(ctx, target)
 			},
 		},
 	}
@@ -373,6 +645,21 @@ func (r *REPL) executeCommand(ctx context.Context, input string) error {
 		return errors.New(handler.usage)
 	}
 
+	if caches := handler.caches; caches != nil && len(caches(parts)) > 0 {
+		// Servers that never send list_changed would otherwise leave stale
+		// data until reconnect; fall back to the cached lists on failure
+		refreshed, err := r.client.refreshStaleCaches(ctx, caches(parts)...)
+		if err != nil {
+			r.logger.Warning("Using cached data: %v", err)
+		}
+		if refreshed {
+			r.refreshCompleter("")
+		}
+	}
+
+	if handler.paged {
+		return r.pageOutput(ctx, func() error { return handler.handler(ctx, parts) })
+	}
 	return handler.handler(ctx, parts)
 }
 
@@ -383,19 +670,76 @@ func (r *REPL) showHelp() error {
 	fmt.Println("  list tools                   - List all available tools")
 	fmt.Println("  list resources               - List all available resources")
 	fmt.Println("  list prompts                 - List all available prompts")
+	fmt.Println("  list templates               - List all available resource templates")
+	fmt.Println("  list <kind> --page <n>       - Fetch a single page of a list from the server")
 	fmt.Println("  describe tool <name>         - Show detailed information about a tool")
 	fmt.Println("  describe resource <uri>      - Show detailed information about a resource")
 	fmt.Println("  describe prompt <name>       - Show detailed information about a prompt")
+	fmt.Println("  describe template <name>     - Show detailed information about a resource template")
 	fmt.Println("  call <tool> {json}           - Execute a tool with JSON arguments")
-	fmt.Println("  get <resource-uri>           - Retrieve a resource")
-	fmt.Println("  prompt <name> {json}         - Get a prompt with JSON arguments")
+	fmt.Println("  call <tool> {json} &         - Execute a tool in the background as a job")
+	fmt.Println("  call & <tool> {json}         - Same as above; start several to overlap calls")
+	fmt.Println("  call <tool> --interactive    - Enter the arguments field by field from the input schema")
+	fmt.Println("  call --timeout <d> <tool> {json}\n                               - Execute a tool with its own deadline, e.g. 30s")
+	fmt.Println("  requests                     - List in-flight tool calls with their progress")
+	fmt.Println("  jobs                         - List background calls and whether they finished")
+	fmt.Println("  wait [job]                   - Wait for a background call, or all of them, and show the result")
+	fmt.Println("  cancel <request-id> [reason] - Cancel an in-flight tool call (notifications/cancelled)")
+	fmt.Println("  trace [id]                   - List the latest requests, or show the request and response with a\n                               correlation ID (the #id in the log) or JSON-RPC id")
+	fmt.Println("  get <resource-uri> [file]    - Retrieve a resource, optionally saving it to a file")
+	fmt.Println("  get <template-name> [file]   - Fill in a resource template's variables and retrieve it")
+	fmt.Println("  get --if-changed <uri>       - Retrieve a resource, printing it only if it changed since the last such get")
+	fmt.Println("  template <name>              - Fill in a resource template's variables and read it")
+	fmt.Println("  prompt <name> {json}         - Get a prompt with JSON arguments, shown as a conversation")
+	fmt.Println("  prompt <name> {json} --raw   - Get a prompt and show the result as JSON")
+	fmt.Println("  show last [format]           - Show the last result in full, optionally in another format")
+	fmt.Println("  expand <path> [--all]        - Show a folded JSON value of the last result or schema, e.g.\n                               expand $.properties; --all unfolds everything in it")
+	fmt.Println("  display [format]             - Show or set how results are rendered (pretty, raw, table, color)")
+	fmt.Println("  display limit <bytes>        - Truncate results longer than this (0 disables truncation)")
+	fmt.Println("  display images <mode>        - Preview images inline: auto, iterm, kitty or off")
+	fmt.Println("  display binary <save|off>    - Save images, audio and blobs in results to temporary files")
+	fmt.Println("  display pager <on|off>       - Show output taller than the terminal through $PAGER or a pager")
+	fmt.Println("  display fold <lines|off>     - Fold nested JSON objects and arrays taller than this")
+	fmt.Println("  <command> > <file>           - Save the result of call, get, template or prompt to a file")
+	fmt.Println("  save last <file>             - Save the last result to a file (binary contents are decoded)")
+	fmt.Println("  server                       - Show server info, capabilities and instructions")
+	fmt.Println("  stats                        - Show notification, ping and per-method latency statistics")
+	fmt.Println("  ping                         - Ping the server and show the round-trip time")
+	fmt.Println("  health                       - Ping the server and show uptime, RTT, reconnects and pending requests")
+	fmt.Println("  capabilities                 - Show the client capabilities announced in initialize")
+	fmt.Println("  capabilities set|add|remove <name...>\n                               - Change them (sampling, roots, elicitation, experimental:<name>)\n                               and start a new session announcing them")
 	fmt.Println("  notifications <on|off>       - Enable/disable notification display")
+	fmt.Println("  loglevel <level>             - Set the server's minimum log level (logging/setLevel)")
+	fmt.Println("  subscribe <resource-uri>     - Receive update notifications for a resource")
+	fmt.Println("  unsubscribe <resource-uri>   - Stop receiving update notifications for a resource")
+	fmt.Println("  refresh [tools|resources|prompts]\n                               - Force re-listing from the server")
+	fmt.Println("  history [count]              - List the commands entered, numbered for !n")
+	fmt.Println("  !n, !!, !prefix             - Run command n of the history, the previous command, or the\n                               latest one starting with prefix")
+	fmt.Println("  history <tools|resources|prompts> [name]\n                               - Show when entries appeared, disappeared or changed")
+	if r.client.OAuthEnabled() {
+		fmt.Println("  auth scopes                  - Show the scopes of the current access token")
+		fmt.Println("  auth scopes add <scope...>   - Re-authorize with additional scopes (step-up)")
+		fmt.Println("  scopes                       - Compare advertised, requested and granted scopes")
+		fmt.Println("  token introspect             - Ask the authorization server about the access token")
+		fmt.Println("  token refresh                - Force a refresh grant and show the exchange")
+		fmt.Println("  logout                       - Revoke the access and refresh tokens")
+		fmt.Println("  client show                  - Read the dynamically registered client (RFC 7592)")
+		fmt.Println("  client update <json>         - Update its metadata; null removes a field")
+		fmt.Println("  client delete                - Deregister the client")
+	}
+	fmt.Println("  source <file>                - Run the commands in a script file")
+	fmt.Println("  assert <file>                - Call tools and check their results against an expectation file")
+	fmt.Println("  connect <name> <endpoint>    - Open an additional connection to another server")
+	fmt.Println("  use <name>                   - Send subsequent commands to the named connection")
+	fmt.Println("  disconnect <name>            - Close an additional connection")
+	fmt.Println("  connections                  - List open connections")
+	fmt.Println("  endpoint <url>               - Connect the current connection to another endpoint, e.g. after\n                               the server moved to another port")
 	fmt.Println("  exit, quit                   - Exit the REPL")
 	fmt.Println()
 	fmt.Println("Keyboard shortcuts:")
 	fmt.Println("  TAB                          - Auto-complete commands and arguments")
 	fmt.Println("  ↑/↓ (arrow keys)             - Navigate command history")
-	fmt.Println("  Ctrl+R                       - Search command history")
+	fmt.Println("  Ctrl+R                       - Search command history, including earlier sessions")
 	fmt.Println("  Ctrl+C                       - Cancel current line")
 	fmt.Println("  Ctrl+D                       - Exit REPL")
 	fmt.Println()
@@ -406,62 +750,111 @@ func (r *REPL) showHelp() error {
 	return nil
 }
 
+// parseListPage parses the optional "--page <n>" of a list command; zero
+// means the cached list
+func parseListPage(args []string) (int, error) {
+	const usage = "usage: list <tools|resources|prompts|templates> [--page <n>]"
+	switch {
+	case len(args) == 0:
+		return 0, nil
+	case len(args) == 2 && args[0] == "--page":
+	default:
+		return 0, errors.New(usage)
+	}
+
+	page, err := strconv.Atoi(args[1])
+	if err != nil || page < 1 {
+		return 0, fmt.Errorf("invalid page %q: pages are numbered from 1", args[1])
+	}
+	return page, nil
+}
+
 // handleList handles list commands
-func (r *REPL) handleList(ctx context.Context, target string) error {
+func (r *REPL) handleList(ctx context.Context, target string, page int) error {
 	switch strings.ToLower(target) {
 	case "tools", "tool":
 		if !r.client.ServerSupportsTools() {
 			fmt.Println("Server does not support tools capability.")
 			return nil
 		}
-		return r.listTools(ctx)
+		return r.listTools(ctx, page)
 	case "resources", "resource":
 		if !r.client.ServerSupportsResources() {
 			fmt.Println("Server does not support resources capability.")
 			return nil
 		}
-		return r.listResources(ctx)
+		return r.listResources(ctx, page)
 	case "prompts", "prompt":
 		if !r.client.ServerSupportsPrompts() {
 			fmt.Println("Server does not support prompts capability.")
 			return nil
 		}
-		return r.listPrompts(ctx)
+		return r.listPrompts(ctx, page)
+	case "templates", "template":
+		if !r.client.ServerSupportsResources() {
+			fmt.Println("Server does not support resources capability.")
+			return nil
+		}
+		return r.listTemplates(ctx, page)
 	default:
-		return fmt.Errorf("unknown list target: %s. Use 'tools', 'resources', or 'prompts'", target)
+		return fmt.Errorf("unknown list target: %s. Use 'tools', 'resources', 'prompts', or 'templates'", target)
 	}
 }
 
-// listTools displays available tools
-func (r *REPL) listTools(ctx context.Context) error {
+// listTools displays the cached tools, or a single page fetched from the
+// server if page is positive
+func (r *REPL) listTools(ctx context.Context, page int) error {
 	r.client.mu.RLock()
 	tools := r.client.toolCache
 	r.client.mu.RUnlock()
 
+	more := false
+	if page > 0 {
+		var err error
+		if tools, more, err = r.client.ListToolsPage(ctx, page); err != nil {
+			return fmt.Errorf("failed to list tools: %w", err)
+		}
+	}
+
 	if len(tools) == 0 {
 		fmt.Println("No tools available.")
 		return nil
 	}
 
-	fmt.Printf("Available tools (%d):\n", len(tools))
+	fmt.Println(listHeading("tools", len(tools), page, more))
 	for i, tool := range tools {
 		fmt.Printf("  %d. %-30s - %s\n", i+1, tool.Name, tool.Description)
 	}
 	return nil
 }
 
-// listResources displays available resources
-func (r *REPL) listResources(ctx context.Context) error {
+// listResources displays the cached resources, or a single page fetched
+// from the server if page is positive
+func (r *REPL) listResources(ctx context.Context, page int) error {
 	r.client.mu.RLock()
 	resources := r.client.resourceCache
 	r.client.mu.RUnlock()
 
+	more := false
+	if page > 0 {
+		var err error
+		if resources, more, err = r.client.ListResourcesPage(ctx, page); err != nil {
+			return fmt.Errorf("failed to list resources: %w", err)
+		}
+	}
+
 	if len(resources) == 0 {
 		fmt.Println("No resources available.")
+		r.client.mu.RLock()
+		templates := len(r.client.templateCache)
+		r.client.mu.RUnlock()
+		if templates > 0 {
+			fmt.Printf("The server has %d resource template(s); see 'list templates' and read them with 'get <template-name>'.\n", templates)
+		}
 		return nil
 	}
 
-	fmt.Printf("Available resources (%d):\n", len(resources))
+	fmt.Println(listHeading("resources", len(resources), page, more))
 	for i, resource := range resources {
 		desc := resource.Description
 		if desc == "" {
@@ -472,24 +865,46 @@ func (r *REPL) listResources(ctx context.Context) error {
 	return nil
 }
 
-// listPrompts displays available prompts
-func (r *REPL) listPrompts(ctx context.Context) error {
+// listPrompts displays the cached prompts, or a single page fetched from
+// the server if page is positive
+func (r *REPL) listPrompts(ctx context.Context, page int) error {
 	r.client.mu.RLock()
 	prompts := r.client.promptCache
 	r.client.mu.RUnlock()
 
+	more := false
+	if page > 0 {
+		var err error
+		if prompts, more, err = r.client.ListPromptsPage(ctx, page); err != nil {
+			return fmt.Errorf("failed to list prompts: %w", err)
+		}
+	}
+
 	if len(prompts) == 0 {
 		fmt.Println("No prompts available.")
 		return nil
 	}
 
-	fmt.Printf("Available prompts (%d):\n", len(prompts))
+	fmt.Println(listHeading("prompts", len(prompts), page, more))
 	for i, prompt := range prompts {
 		fmt.Printf("  %d. %-30s - %s\n", i+1, prompt.Name, prompt.Description)
 	}
 	return nil
 }
 
+// listHeading introduces a listing of count entries of kind; page is zero
+// for the cached list, otherwise the page number and whether more follow
+func listHeading(kind string, count, page int, more bool) string {
+	if page == 0 {
+		return fmt.Sprintf("Available %s (%d):", kind, count)
+	}
+	next := "last page"
+	if more {
+		next = fmt.Sprintf("'--page %d' for more", page+1)
+	}
+	return fmt.Sprintf("Available %s, page %d (%d, %s):", kind, page, count, next)
+}
+
 // handleDescribe handles describe commands
 func (r *REPL) handleDescribe(ctx context.Context, targetType, name string) error {
 	switch strings.ToLower(targetType) {
@@ -508,8 +923,13 @@ func (r *REPL) handleDescribe(ctx context.Context, targetType, name string) erro
 			return fmt.Errorf("server does not support prompts capability")
 		}
 		return r.describePrompt(ctx, name)
+	case "template":
+		if !r.client.ServerSupportsResources() {
+			return fmt.Errorf("server does not support resources capability")
+		}
+		return r.describeTemplate(ctx, name)
 	default:
-		return fmt.Errorf("unknown describe target: %s. Use 'tool', 'resource', or 'prompt'", targetType)
+		return fmt.Errorf("unknown describe target: %s. Use 'tool', 'resource', 'prompt', or 'template'", targetType)
 	}
 }
 
@@ -523,7 +943,7 @@ func (r *REPL) describeTool(ctx context.Context, name string) error {
 			fmt.Printf("Tool: %s\n", tool.Name)
 			fmt.Printf("Description: %s\n", tool.Description)
 			fmt.Println("Input Schema:")
-			fmt.Printf("%s\n", PrettyJSON(tool.InputSchema))
+			r.writeJSONDocument(os.Stdout, tool.InputSchema)
 			return nil
 		}
 	}
@@ -579,17 +999,433 @@ func (r *REPL) describePrompt(ctx context.Context, name string) error {
 	return fmt.Errorf("prompt not found: %s", name)
 }
 
+// showServerInfo displays what the server reported during initialize,
+// including its usage instructions
+func (r *REPL) showServerInfo() error {
+	info := r.client.ServerInfo()
+
+	fmt.Printf("Server: %s\n", info.Name)
+	if info.Version != "" {
+		fmt.Printf("Version: %s\n", info.Version)
+	}
+	if requested := r.client.requestedProtocolVersion(); requested != info.ProtocolVersion {
+		fmt.Printf("Protocol Version: %s (requested %s)\n", info.ProtocolVersion, requested)
+	} else {
+		fmt.Printf("Protocol Version: %s\n", info.ProtocolVersion)
+	}
+
+	var capabilities []string
+	if r.client.ServerSupportsTools() {
+		capabilities = append(capabilities, "tools")
+	}
+	if r.client.ServerSupportsResources() {
+		capabilities = append(capabilities, "resources")
+	}
+	if r.client.ServerSupportsPrompts() {
+		capabilities = append(capabilities, "prompts")
+	}
+	if r.client.ServerSupportsLogging() {
+		capabilities = append(capabilities, "logging")
+	}
+	if len(capabilities) == 0 {
+		capabilities = append(capabilities, "none")
+	}
+	fmt.Printf("Capabilities: %s\n", strings.Join(capabilities, ", "))
+
+	if info.Instructions == "" {
+		fmt.Println("Instructions: (none provided)")
+		return nil
+	}
+	fmt.Println("Instructions:")
+	for _, line := range strings.Split(info.Instructions, "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+	return nil
+}
+
+// showStats displays session statistics collected by the client
+func (r *REPL) showStats() error {
+	notifications := r.client.NotificationStats()
+	fmt.Println("Notifications:")
+	fmt.Printf("  Received:       %d\n", notifications.Received)
+	fmt.Printf("  Dropped:        %d\n", notifications.Dropped)
+	fmt.Printf("  Queued:         %d/%d\n", notifications.Queued, notifications.BufferSize)
+	fmt.Printf("  Overflow:       %s\n", notifications.Policy)
+	fmt.Printf("  Coalesced:      %d list_changed refreshes\n", r.client.SuppressedRefreshes())
+
+	ping := r.client.PingStats()
+	if ping.TotalPings > 0 {
+		fmt.Println("Pings:")
+		fmt.Printf("  Sent:           %d\n", ping.TotalPings)
+		fmt.Printf("  Failed:         %d\n", ping.TotalFailures)
+		fmt.Printf("  Last RTT:       %v\n", ping.LastRTT)
+	}
+
+	if limits := r.client.RateLimitStats(); limits.Observed() {
+		fmt.Println("Rate limits:")
+		fmt.Printf("  Rejected (429): %d\n", limits.Limited)
+		if limits.Limited > 0 {
+			fmt.Printf("  Retry-After:    %v\n", limits.LastRetryAfter)
+		}
+		if limits.Limit >= 0 {
+			fmt.Printf("  Limit:          %d\n", limits.Limit)
+		}
+		if limits.Remaining >= 0 {
+			fmt.Printf("  Remaining:      %d\n", limits.Remaining)
+		}
+		if until := time.Until(limits.Reset); until > 0 {
+			fmt.Printf("  Resets in:      %v\n", until.Round(time.Second))
+		}
+		if limits.Throttled > 0 {
+			fmt.Printf("  Throttled:      %v\n", limits.Throttled.Round(time.Millisecond))
+		}
+	}
+
+	methods := r.client.MethodStats()
+	if len(methods) > 0 {
+		fmt.Println("Requests:")
+		fmt.Printf("  %-26s %7s %7s %10s %10s %10s\n", "Method", "Count", "Error%", "p50", "p95", "p99")
+		for _, m := range methods {
+			fmt.Printf("  %-26s %7d %6.1f%% %8.1fms %8.1fms %8.1fms\n",
+				m.Method, m.Count, m.ErrorRate*100, m.P50Ms, m.P95Ms, m.P99Ms)
+		}
+	}
+	return nil
+}
+
+// handlePing sends one ping and prints its round-trip time
+func (r *REPL) handlePing(ctx context.Context) error {
+	rtt, err := r.client.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	fmt.Printf("Pong in %v\n", rtt.Round(time.Microsecond))
+	return nil
+}
+
+// showHealth pings the server so the RTT is current and displays the
+// connection's health
+func (r *REPL) showHealth(ctx context.Context) error {
+	_, pingErr := r.client.Ping(ctx)
+	health := r.client.Health()
+
+	fmt.Printf("Status:           %s\n", health.Status)
+	fmt.Printf("Uptime:           %v\n", health.Uptime.Round(time.Second))
+	if health.Reconnects > 0 {
+		fmt.Printf("Session age:      %v\n", health.SessionAge.Round(time.Second))
+	}
+	fmt.Printf("Reconnects:       %d\n", health.Reconnects)
+	if health.PingInterval > 0 {
+		fmt.Printf("Keepalive:        every %v\n", health.PingInterval)
+	} else {
+		fmt.Println("Keepalive:        off (--ping-interval)")
+	}
+	if pingErr != nil {
+		fmt.Printf("Last ping:        failed: %v\n", pingErr)
+	} else {
+		fmt.Printf("Last ping RTT:    %v\n", health.Ping.LastRTT.Round(time.Microsecond))
+	}
+	fmt.Printf("Average RTT:      %v\n", health.Ping.AvgRTT.Round(time.Microsecond))
+	fmt.Printf("Pings:            %d sent, %d failed\n", health.Ping.TotalPings, health.Ping.TotalFailures)
+	fmt.Printf("Pending requests: %d (%d queued)\n", health.PendingRequests, health.QueuedRequests)
+	return nil
+}
+
+// handleLogLevel asks the server to change its minimum log level
+func (r *REPL) handleLogLevel(ctx context.Context, level string) error {
+	if !r.client.ServerSupportsLogging() {
+		return fmt.Errorf("server does not support logging capability")
+	}
+
+	if err := r.client.SetLogLevel(ctx, level); err != nil {
+		return fmt.Errorf("failed to set log level: %w", err)
+	}
+
+	fmt.Printf("Server log level set to %s\n", strings.ToLower(level))
+	return nil
+}
+
+// handleSubscribe subscribes to or unsubscribes from resource updates
+func (r *REPL) handleSubscribe(ctx context.Context, uri string, subscribe bool) error {
+	if !r.client.ServerSupportsSubscriptions() {
+		return fmt.Errorf("server does not support resource subscriptions")
+	}
+
+	if subscribe {
+		if err := r.client.SubscribeResource(ctx, uri); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		fmt.Printf("Subscribed to %s\n", uri)
+	} else {
+		if err := r.client.UnsubscribeResource(ctx, uri); err != nil {
+			return fmt.Errorf("failed to unsubscribe: %w", err)
+		}
+		fmt.Printf("Unsubscribed from %s\n", uri)
+	}
+
+	r.refreshCompleter("")
+	return nil
+}
+
+// handleRefresh forces a re-list of one or all caches and rebuilds completion
+func (r *REPL) handleRefresh(ctx context.Context, target string) error {
+	var err error
+	switch strings.ToLower(target) {
+	case "":
+		err = r.client.RefreshCaches(ctx)
+	case "tools", "tool":
+		err = r.client.RefreshCache(ctx, cacheTools)
+	case "resources", "resource":
+		err = r.client.RefreshCache(ctx, cacheResources)
+	case "prompts", "prompt":
+		err = r.client.RefreshCache(ctx, cachePrompts)
+	default:
+		return fmt.Errorf("unknown refresh target: %s. Use 'tools', 'resources', or 'prompts'", target)
+	}
+	if err != nil {
+		return fmt.Errorf("refresh failed: %w", err)
+	}
+
+	r.refreshCompleter("")
+	fmt.Println("Caches refreshed.")
+	return nil
+}
+
+// handleAuth handles auth commands
+func (r *REPL) handleAuth(ctx context.Context, args []string) error {
+	if !r.client.OAuthEnabled() {
+		return fmt.Errorf("OAuth is not enabled (use --oauth)")
+	}
+	if strings.ToLower(args[0]) != "scopes" {
+		return fmt.Errorf("unknown auth command: %s. Use 'auth scopes [add <scope...>]'", args[0])
+	}
+
+	if len(args) == 1 {
+		return r.showTokenScopes(ctx)
+	}
+	if strings.ToLower(args[1]) != "add" || len(args) < 3 {
+		return errors.New("usage: auth scopes add <scope...>")
+	}
+
+	fmt.Println("Before:")
+	if err := r.showTokenScopes(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.client.StepUpScopes(ctx, args[2:]); err != nil {
+		return err
+	}
+	r.refreshCompleter("")
+
+	fmt.Println("After:")
+	return r.showTokenScopes(ctx)
+}
+
+// handleToken handles token commands
+func (r *REPL) handleToken(ctx context.Context, action string) error {
+	if !r.client.OAuthEnabled() {
+		return fmt.Errorf("OAuth is not enabled (use --oauth)")
+	}
+	switch strings.ToLower(action) {
+	case "introspect":
+	case "refresh":
+		return r.handleTokenRefresh(ctx)
+	default:
+		return fmt.Errorf("unknown token command: %s. Use 'token introspect' or 'token refresh'", action)
+	}
+
+	introspection, err := r.client.IntrospectToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Token introspection:")
+	if !introspection.Active {
+		fmt.Println("  Active:     no (expired, revoked or unknown to the authorization server)")
+		return nil
+	}
+	fmt.Println("  Active:     yes")
+	fmt.Printf("  Scopes:     %s\n", formatScopeList(introspection.Scopes()))
+	if expiresAt := introspection.ExpiresAt(); !expiresAt.IsZero() {
+		fmt.Printf("  Expires:    %s (in %s)\n", expiresAt.Format(time.RFC3339), time.Until(expiresAt).Round(time.Second))
+	}
+	if audiences := introspection.Audiences(); len(audiences) > 0 {
+		fmt.Printf("  Audience:   %s\n", strings.Join(audiences, ", "))
+	}
+	for _, field := range []struct{ label, value string }{
+		{"Client ID", introspection.ClientID},
+		{"Subject", introspection.Sub},
+		{"Username", introspection.Username},
+		{"Issuer", introspection.Iss},
+		{"Token type", introspection.TokenType},
+	} {
+		if field.value != "" {
+			fmt.Printf("  %-11s %s\n", field.label+":", field.value)
+		}
+	}
+	return nil
+}
+
+// handleTokenRefresh forces a refresh grant; the client logs the exchange
+func (r *REPL) handleTokenRefresh(ctx context.Context) error {
+	refresh, err := r.client.RefreshOAuthToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Token refreshed:")
+	if !refresh.ExpiresAt.IsZero() {
+		fmt.Printf("  Expires:    %s (in %s)\n", refresh.ExpiresAt.Format(time.RFC3339), time.Until(refresh.ExpiresAt).Round(time.Second))
+	}
+	if refresh.Scope != "" {
+		fmt.Printf("  Scopes:     %s\n", formatScopeList(strings.Fields(refresh.Scope)))
+	}
+	fmt.Printf("  Rotated:    %s\n", yesNo(refresh.RefreshTokenRotated))
+	return nil
+}
+
+// handleClientRegistration manages the dynamically registered client with
+// the client configuration endpoint (RFC 7592)
+func (r *REPL) handleClientRegistration(ctx context.Context, action, args string) error {
+	if !r.client.OAuthEnabled() {
+		return fmt.Errorf("OAuth is not enabled (use --oauth)")
+	}
+
+	switch strings.ToLower(action) {
+	case "show":
+		registration, err := r.client.ClientRegistration()
+		if err != nil {
+			return err
+		}
+		if registration.RegistrationClientURI != "" {
+			if registration, err = r.client.ReadClientRegistration(ctx); err != nil {
+				return err
+			}
+		} else {
+			fmt.Println("The authorization server does not support RFC 7592; showing the registration response")
+		}
+		showClientRegistration(registration)
+	case "update":
+		if args == "" {
+			return errors.New("usage: client update <json>, e.g. client update {\"client_name\": \"test\"}")
+		}
+		var changes map[string]any
+		if err := json.Unmarshal([]byte(args), &changes); err != nil {
+			return fmt.Errorf("invalid JSON metadata: %w", err)
+		}
+		registration, err := r.client.UpdateClientRegistration(ctx, changes)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Client updated.")
+		showClientRegistration(registration)
+	case "delete":
+		if err := r.client.DeleteClientRegistration(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Client deregistered. Its tokens and client ID may stop working.")
+	default:
+		return fmt.Errorf("unknown client command: %s. Use 'client show', 'client update <json>' or 'client delete'", action)
+	}
+	return nil
+}
+
+// showClientRegistration displays a client registration with its
+// credentials masked
+func showClientRegistration(registration *ClientRegistration) {
+	fmt.Println("Client registration:")
+	fmt.Printf("  Client ID:  %s\n", registration.ClientID)
+	fmt.Printf("  Endpoint:   %s\n", orNone(registration.RegistrationClientURI))
+	metadata := maps.Clone(registration.Metadata)
+	for _, field := range []string{"client_secret", "registration_access_token"} {
+		if _, ok := metadata[field]; ok {
+			metadata[field] = redactedValue
+		}
+	}
+	fmt.Printf("  Metadata:\n    %s\n", strings.ReplaceAll(PrettyJSON(metadata), "\n", "\n    "))
+}
+
+// handleLogout revokes the OAuth tokens of the session
+func (r *REPL) handleLogout(ctx context.Context) error {
+	if !r.client.OAuthEnabled() {
+		return fmt.Errorf("OAuth is not enabled (use --oauth)")
+	}
+	if err := r.client.RevokeTokens(ctx); err != nil {
+		return err
+	}
+	fmt.Println("Logged out. The next request will start a new authorization.")
+	return nil
+}
+
+// showTokenScopes displays the scopes of the current access token
+func (r *REPL) showTokenScopes(ctx context.Context) error {
+	scopes, granted, err := r.client.TokenScopes(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Scopes: %s\n", formatScopeList(scopes))
+	if !granted {
+		fmt.Println("  (requested scopes; the authorization server did not report the granted scopes)")
+	}
+	return nil
+}
+
+// showScopeReport displays the scopes advertised by the resource and the
+// authorization server next to the requested and granted ones
+func (r *REPL) showScopeReport(ctx context.Context) error {
+	if !r.client.OAuthEnabled() {
+		return fmt.Errorf("OAuth is not enabled (use --oauth)")
+	}
+	report, err := r.client.ScopeReport(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Resource metadata:     %s\n", formatScopeList(report.Resource))
+	if report.AuthServerErr != nil {
+		fmt.Printf("Authorization server:  unavailable: %v\n", report.AuthServerErr)
+	} else {
+		fmt.Printf("Authorization server:  %s\n", formatScopeList(report.AuthServer))
+	}
+	fmt.Printf("Requested:             %s\n", formatScopeList(report.Requested))
+	if report.GrantedReported {
+		fmt.Printf("Granted:               %s\n", formatScopeList(report.Granted))
+	} else {
+		fmt.Println("Granted:               (not reported in the token response)")
+	}
+
+	if len(report.Mismatches) == 0 {
+		fmt.Println("\nNo mismatches.")
+		return nil
+	}
+	fmt.Println("\nMismatches:")
+	for _, mismatch := range report.Mismatches {
+		fmt.Printf("  ⚠ %s\n", mismatch)
+	}
+	return nil
+}
+
 // handleNotifications enables or disables notification display
 func (r *REPL) handleNotifications(setting string) error {
 	switch strings.ToLower(setting) {
 	case "on":
-		r.logger.SetVerbose(true)
+		r.setVerbose(true)
 		fmt.Println("Notifications enabled")
 	case "off":
-		r.logger.SetVerbose(false)
+		r.setVerbose(false)
 		fmt.Println("Notifications disabled")
 	default:
 		return fmt.Errorf("invalid setting: %s. Use 'on' or 'off'", setting)
 	}
 	return nil
 }
+
+// setVerbose toggles notification display for the REPL and every connection
+func (r *REPL) setVerbose(verbose bool) {
+	r.logger.SetVerbose(verbose)
+	for _, name := range r.connections.Names() {
+		if client := r.connections.Get(name); client != nil && client.logger != nil {
+			client.logger.SetVerbose(verbose)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolArgument is a property of a tool's input schema. Objects carry their
+// own properties and arrays the schema of their items.
+type toolArgument struct {
+	Name        string
+	Description string
+	Type        string
+	Enum        []any
+	Default     any
+	Required    bool
+	Properties  []toolArgument
+	Items       *toolArgument
+}
+
+// toolArguments extracts the properties of a tool's input schema, sorted by
+// name with required arguments first
+func toolArguments(tool mcp.Tool) []toolArgument {
+	return schemaArguments(tool.InputSchema.Properties, anyList(tool.InputSchema.Required))
+}
+
+// schemaArguments parses the properties of an object schema, sorted by name
+// with required properties first
+func schemaArguments(properties map[string]any, required []any) []toolArgument {
+	args := make([]toolArgument, 0, len(properties))
+	for name, raw := range properties {
+		prop, _ := raw.(map[string]any)
+		arg := schemaArgument(name, prop)
+		arg.Required = slices.Contains(required, any(name))
+		args = append(args, arg)
+	}
+	sort.Slice(args, func(i, j int) bool {
+		if args[i].Required != args[j].Required {
+			return args[i].Required
+		}
+		return args[i].Name < args[j].Name
+	})
+	return args
+}
+
+// schemaArgument parses the schema of a single property
+func schemaArgument(name string, prop map[string]any) toolArgument {
+	arg := toolArgument{
+		Name:    name,
+		Type:    schemaType(prop["type"]),
+		Enum:    anyList(prop["enum"]),
+		Default: prop["default"],
+	}
+	arg.Description, _ = prop["description"].(string)
+	if properties, ok := prop["properties"].(map[string]any); ok {
+		arg.Properties = schemaArguments(properties, anyList(prop["required"]))
+	}
+	if items, ok := prop["items"].(map[string]any); ok {
+		item := schemaArgument(name, items)
+		arg.Items = &item
+	}
+	return arg
+}
+
+// anyList converts a schema list to []any; schemas decoded from JSON hold
+// []any while schemas built with mcp-go options hold []string
+func anyList(value any) []any {
+	switch list := value.(type) {
+	case []any:
+		return list
+	case []string:
+		values := make([]any, len(list))
+		for i, entry := range list {
+			values[i] = entry
+		}
+		return values
+	}
+	return nil
+}
+
+// schemaType returns the JSON schema type of a property, taking the first
+// non-null type of a type union
+func schemaType(value any) string {
+	switch t := value.(type) {
+	case string:
+		return t
+	case []any:
+		for _, entry := range t {
+			if name, ok := entry.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// placeholder returns the value an argument template starts with: the
+// default, the first enum value, or an empty value of the argument's type
+func (a toolArgument) placeholder() any {
+	if a.Default != nil {
+		return a.Default
+	}
+	if len(a.Enum) > 0 {
+		return a.Enum[0]
+	}
+	switch a.Type {
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []any{}
+	case "object":
+		return map[string]any{}
+	case "string":
+		return "<string>"
+	}
+	return nil
+}
+
+// toolArgumentTemplates returns JSON argument templates for a tool: one with
+// the required arguments and, if the tool has optional arguments, one with
+// all of them. Keys keep the order of toolArguments and the JSON is compact
+// so a template stays a single word on the command line.
+func toolArgumentTemplates(tool mcp.Tool) []string {
+	args := toolArguments(tool)
+	if len(args) == 0 {
+		return nil
+	}
+
+	var templates []string
+	required := 0
+	for required < len(args) && args[required].Required {
+		required++
+	}
+	if required > 0 {
+		templates = append(templates, argumentTemplate(args[:required]))
+	}
+	if required < len(args) {
+		templates = append(templates, argumentTemplate(args))
+	}
+	return templates
+}
+
+// argumentTemplate renders arguments as a compact JSON object in order
+func argumentTemplate(args []toolArgument) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(compactJSON(arg.Name))
+		b.WriteByte(':')
+		b.WriteString(compactJSON(arg.placeholder()))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// compactJSON encodes value without HTML escaping, so placeholders such as
+// "<string>" stay readable
+func compactJSON(value any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestToolArgumentTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tool mcp.Tool
+		want []string
+	}{
+		{"no arguments", mcp.NewTool("ping"), nil},
+		{
+			"required only",
+			mcp.NewTool("echo", mcp.WithString("message", mcp.Required()), mcp.WithNumber("count", mcp.Required())),
+			[]string{`{"count":0,"message":"<string>"}`},
+		},
+		{
+			"optional only",
+			mcp.NewTool("search", mcp.WithBoolean("verbose"), mcp.WithArray("tags")),
+			[]string{`{"tags":[],"verbose":false}`},
+		},
+		{
+			"required first",
+			mcp.NewTool("deploy",
+				mcp.WithString("env", mcp.Required(), mcp.Enum("staging", "production")),
+				mcp.WithNumber("replicas", mcp.DefaultNumber(3)),
+				mcp.WithObject("labels"),
+				mcp.WithString("app", mcp.Required()),
+			),
+			[]string{`{"app":"<string>","env":"staging"}`, `{"app":"<string>","env":"staging","labels":{},"replicas":3}`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := toolArgumentTemplates(tc.tool); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("toolArgumentTemplates = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSchemaType(t *testing.T) {
+	if got := schemaType([]any{"null", "integer"}); got != "integer" {
+		t.Errorf("schemaType(union) = %q, want integer", got)
+	}
+	if got := schemaType(nil); got != "" {
+		t.Errorf("schemaType(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// ImagePreview selects how images in results are previewed inline
+type ImagePreview string
+
+const (
+	// PreviewAuto uses the protocol of the detected terminal, if any; the
+	// default
+	PreviewAuto ImagePreview = "auto"
+	// PreviewITerm uses the iTerm2 inline images protocol, also understood
+	// by WezTerm and others
+	PreviewITerm ImagePreview = "iterm"
+	// PreviewKitty uses the kitty graphics protocol; it shows PNG images only
+	PreviewKitty ImagePreview = "kitty"
+	// PreviewOff never previews images
+	PreviewOff ImagePreview = "off"
+)
+
+// ImagePreviews lists the accepted image preview modes
+var ImagePreviews = []string{
+	string(PreviewAuto),
+	string(PreviewITerm),
+	string(PreviewKitty),
+	string(PreviewOff),
+}
+
+// ParseImagePreview validates an image preview mode
+func ParseImagePreview(mode string) (ImagePreview, error) {
+	normalized := strings.ToLower(strings.TrimSpace(mode))
+	for _, m := range ImagePreviews {
+		if m == normalized {
+			return ImagePreview(m), nil
+		}
+	}
+	return "", fmt.Errorf("invalid image preview: %s (must be one of: %s)", mode, strings.Join(ImagePreviews, ", "))
+}
+
+// Escape sequences wrapping inline images. writeTruncated does not count
+// them towards the display limit.
+const (
+	itermImageStart = "\033]1337;File="
+	itermImageEnd   = "\a"
+	kittyImageStart = "\033_G"
+	kittyImageEnd   = "\033\\"
+	// kittyChunkSize is the maximum base64 payload of one kitty escape
+	kittyChunkSize = 4096
+)
+
+// resolve maps auto to the protocol of the terminal on stdout, or off if
+// stdout is not a terminal supporting inline images
+func (p ImagePreview) resolve() ImagePreview {
+	if p != PreviewAuto && p != "" {
+		return p
+	}
+	if !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return PreviewOff
+	}
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty":
+		return PreviewKitty
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm":
+		return PreviewITerm
+	}
+	return PreviewOff
+}
+
+// writeBinary describes base64 encoded binary content by its MIME type and
+// decoded size instead of printing it. Depending on the options it saves
+// the data to a temporary file and previews images inline.
+func writeBinary(w io.Writer, prefix, label, mimeType, data string, opts DisplayOptions) {
+	if mimeType == "" {
+		mimeType = "unknown type"
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		fmt.Fprintf(w, "%s[%s: %s, %d bytes of invalid base64: %v]\n", prefix, label, mimeType, len(data), err)
+		return
+	}
+
+	line := fmt.Sprintf("%s[%s: %s, %d bytes]", prefix, label, mimeType, len(decoded))
+	if opts.SaveBinary {
+		if path, err := saveBinaryTempFile(decoded, mimeType); err != nil {
+			line += fmt.Sprintf(" (not saved: %v)", err)
+		} else {
+			line += " saved to " + path
+		}
+	}
+	fmt.Fprintln(w, line)
+	writeImagePreview(w, decoded, mimeType, opts.ImagePreview.resolve())
+}
+
+// saveBinaryTempFile writes data to a temporary file named after its digest
+// and MIME type, so showing the same result again reuses the file
+func saveBinaryTempFile(data []byte, mimeType string) (string, error) {
+	digest := sha256.Sum256(data)
+	path := filepath.Join(os.TempDir(), "mcp-debug-"+hex.EncodeToString(digest[:6])+extensionFor(mimeType))
+
+	// Exclusive creation does not follow a symlink planted at the path
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		if info, statErr := os.Lstat(path); statErr == nil && info.Mode().IsRegular() && info.Size() == int64(len(data)) {
+			return path, nil
+		}
+		return "", fmt.Errorf("%s exists", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// extensionFor returns a file extension for a MIME type, or .bin
+func extensionFor(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/svg+xml":
+		return ".svg"
+	}
+	if extensions, err := mime.ExtensionsByType(mimeType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
+
+// writeImagePreview writes an image as an inline image escape sequence of
+// the protocol. Images the protocol cannot show are skipped.
+func writeImagePreview(w io.Writer, data []byte, mimeType string, protocol ImagePreview) {
+	if !strings.HasPrefix(mimeType, "image/") || mimeType == "image/svg+xml" {
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch protocol {
+	case PreviewITerm:
+		fmt.Fprintf(w, "%sinline=1;size=%d;preserveAspectRatio=1:%s%s\n", itermImageStart, len(data), encoded, itermImageEnd)
+	case PreviewKitty:
+		if mimeType != "image/png" {
+			return
+		}
+		for first := true; ; first = false {
+			chunk := encoded[:min(kittyChunkSize, len(encoded))]
+			encoded = encoded[len(chunk):]
+			more := 0
+			if encoded != "" {
+				more = 1
+			}
+			control := fmt.Sprintf("m=%d", more)
+			if first {
+				control = "f=100,a=T," + control
+			}
+			fmt.Fprintf(w, "%s%s;%s%s", kittyImageStart, control, chunk, kittyImageEnd)
+			if more == 0 {
+				break
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
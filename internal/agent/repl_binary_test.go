@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseImagePreview(t *testing.T) {
+	for _, mode := range ImagePreviews {
+		if got, err := ParseImagePreview(strings.ToUpper(mode)); err != nil || string(got) != mode {
+			t.Errorf("ParseImagePreview(%q) = %v, %v", mode, got, err)
+		}
+	}
+	if _, err := ParseImagePreview("sixel"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestDisplayBinaryContent(t *testing.T) {
+	data := bytes.Repeat([]byte{0xff, 0x00}, 500)
+	encoded := base64.StdEncoding.EncodeToString(data)
+	opts := DisplayOptions{ImagePreview: PreviewOff}
+
+	var buf bytes.Buffer
+	displayToolResult(&buf, &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewImageContent(encoded, "image/png"),
+		mcp.NewAudioContent(encoded, "audio/wav"),
+		mcp.NewEmbeddedResource(mcp.BlobResourceContents{URI: "file:///a.bin", MIMEType: "application/octet-stream", Blob: encoded}),
+	}}, opts)
+	want := "Result:\n" +
+		"[Image: image/png, 1000 bytes]\n" +
+		"[Audio: audio/wav, 1000 bytes]\n" +
+		"[Embedded Resource file:///a.bin: application/octet-stream, 1000 bytes]\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	displayResourceContents(&buf, &mcp.ReadResourceResult{Contents: []mcp.ResourceContents{
+		mcp.BlobResourceContents{URI: "file:///b", Blob: "not base64!"},
+	}}, "", opts)
+	if !strings.Contains(buf.String(), "[Binary data: unknown type, 11 bytes of invalid base64") {
+		t.Errorf("expected invalid base64 to be reported, got %q", buf.String())
+	}
+}
+
+func TestSaveBinaryTempFile(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	data := []byte("\x89PNG binary")
+
+	var buf bytes.Buffer
+	opts := DisplayOptions{SaveBinary: true, ImagePreview: PreviewOff}
+	writeBinary(&buf, "", "Image", "image/png", base64.StdEncoding.EncodeToString(data), opts)
+	_, path, ok := strings.Cut(strings.TrimSpace(buf.String()), " saved to ")
+	if !ok || !strings.HasSuffix(path, ".png") {
+		t.Fatalf("expected the path of a .png file, got %q", buf.String())
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("temporary file holds %q, %v; want the decoded data", got, err)
+	}
+
+	// Showing the result again reuses the file
+	again, err := saveBinaryTempFile(data, "image/png")
+	if err != nil || again != path {
+		t.Errorf("saveBinaryTempFile = %q, %v; want %q", again, err, path)
+	}
+}
+
+func TestWriteImagePreview(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 4000)
+
+	var buf bytes.Buffer
+	writeImagePreview(&buf, data, "image/png", PreviewITerm)
+	if !strings.HasPrefix(buf.String(), itermImageStart+"inline=1;size=4000;") || !strings.HasSuffix(buf.String(), itermImageEnd+"\n") {
+		t.Errorf("unexpected iTerm2 sequence: %.60q", buf.String())
+	}
+
+	buf.Reset()
+	writeImagePreview(&buf, data, "image/png", PreviewKitty)
+	chunks := strings.Split(strings.TrimSuffix(buf.String(), "\n"), kittyImageEnd)
+	if len(chunks) != 3 || !strings.HasPrefix(chunks[0], kittyImageStart+"f=100,a=T,m=1;") ||
+		!strings.HasPrefix(chunks[1], kittyImageStart+"m=0;") {
+		t.Errorf("expected two kitty chunks, got %d: %.80q", len(chunks)-1, buf.String())
+	}
+
+	buf.Reset()
+	writeImagePreview(&buf, data, "image/jpeg", PreviewKitty)
+	writeImagePreview(&buf, data, "application/pdf", PreviewITerm)
+	if buf.Len() != 0 {
+		t.Errorf("expected no preview for unsupported types, got %.60q", buf.String())
+	}
+}
+
+func TestWriteTruncatedSkipsImages(t *testing.T) {
+	var image bytes.Buffer
+	writeImagePreview(&image, bytes.Repeat([]byte("x"), 1000), "image/png", PreviewITerm)
+	output := "[Image: image/png, 1000 bytes]\n" + image.String() + strings.Repeat("line\n", 4)
+
+	var buf bytes.Buffer
+	writeTruncated(&buf, []byte(output), 100)
+	if buf.String() != output {
+		t.Errorf("images should not count towards the limit, got %q", buf.String())
+	}
+
+	buf.Reset()
+	writeTruncated(&buf, []byte(output), 40)
+	if !strings.Contains(buf.String(), image.String()) || !strings.Contains(buf.String(), "showing 37 of 52 bytes") {
+		t.Errorf("expected the cut after the image with visible byte counts, got %q", buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// capabilitiesUsage is the usage of the capabilities command
+const capabilitiesUsage = "usage: capabilities [set <name,...|none> | add <name...> | remove <name...>]"
+
+// handleCapabilities shows the announced client capabilities, or changes
+// them and starts a new session announcing the new set
+func (r *REPL) handleCapabilities(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return r.showClientCapabilities()
+	}
+
+	names := SplitClientCapabilities(args[1:])
+
+	current := r.client.AnnouncedCapabilities()
+	var announced []string
+	switch args[0] {
+	case "set":
+		if len(names) == 0 {
+			return errors.New(capabilitiesUsage)
+		}
+		if len(names) != 1 || names[0] != "none" {
+			announced = names
+		}
+	case "add":
+		if len(names) == 0 {
+			return errors.New(capabilitiesUsage)
+		}
+		// Adding a capability again replaces it, e.g. to change the
+		// settings of an experimental capability
+		announced = current
+		for _, name := range names {
+			i := slices.IndexFunc(announced, func(entry string) bool {
+				return clientCapabilityName(entry) == clientCapabilityName(name)
+			})
+			if i >= 0 {
+				announced[i] = name
+			} else {
+				announced = append(announced, name)
+			}
+		}
+	case "remove":
+		if len(names) == 0 {
+			return errors.New(capabilitiesUsage)
+		}
+		// Experimental capabilities are removed by name, whatever their
+		// settings
+		removed := make(map[string]bool, len(names))
+		for _, name := range names {
+			removed[clientCapabilityName(name)] = true
+		}
+		for _, entry := range current {
+			if !removed[clientCapabilityName(entry)] {
+				announced = append(announced, entry)
+			}
+		}
+	default:
+		return errors.New(capabilitiesUsage)
+	}
+
+	if err := r.client.SetAnnouncedCapabilities(ctx, announced); err != nil {
+		return err
+	}
+	return r.showClientCapabilities()
+}
+
+// showClientCapabilities lists the capabilities announced in initialize and
+// whether mcp-debug answers the requests they allow the server to send
+func (r *REPL) showClientCapabilities() error {
+	statuses, err := r.client.ClientCapabilityStatuses()
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No client capabilities announced.")
+		return nil
+	}
+
+	fmt.Println("Announced client capabilities:")
+	for _, status := range statuses {
+		switch {
+		case status.Settings != nil:
+			settings, _ := json.Marshal(status.Settings)
+			fmt.Printf("  %-14s %s\n", status.Name, settings)
+		case strings.HasPrefix(status.Name, capabilityExperimentalPrefix):
+			fmt.Printf("  %s\n", status.Name)
+		case status.Answered:
+			fmt.Printf("  %-14s requests are answered\n", status.Name)
+		default:
+			fmt.Printf("  %-14s announced only; requests from the server fail\n", status.Name)
+		}
+	}
+	return nil
+}
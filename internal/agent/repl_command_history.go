@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// commandHistoryLimit is the number of commands kept in the history file,
+// which Ctrl+R searches
+const commandHistoryLimit = 1000
+
+// historyFileUnsafe matches the characters not used in history file names
+var historyFileUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// DefaultHistoryFile returns the REPL command history file of a profile
+// or, without one, of an endpoint, in the history directory next to the
+// default config file. Empty if there is no home directory.
+func DefaultHistoryFile(profile, endpoint string) string {
+	config := DefaultConfigPath()
+	if config == "" {
+		return ""
+	}
+	name := "profile-" + profile
+	if profile == "" {
+		name = "endpoint-" + endpoint
+	}
+	name = strings.Trim(historyFileUnsafe.ReplaceAllString(name, "_"), "_")
+	return filepath.Join(filepath.Dir(config), "history", name)
+}
+
+// SetHistoryFile sets the file the commands are saved to and loaded from;
+// empty uses a file in the temporary directory shared by all endpoints
+func (r *REPL) SetHistoryFile(path string) {
+	r.historyFile = path
+}
+
+// openHistoryFile creates the history file, readable only by the user as
+// commands may carry credentials, and loads the commands of earlier
+// sessions. The history works without a file for the session.
+func (r *REPL) openHistoryFile() string {
+	path := r.historyFile
+	if path == "" {
+		path = filepath.Join(os.TempDir(), ".mcp_debug_history")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		r.logger.Warning("Command history is not saved: %v", err)
+		return ""
+	}
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600) //nolint:gosec // G304: history file of the user
+	if err != nil {
+		r.logger.Warning("Command history is not saved: %v", err)
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+	r.commands = readCommandHistory(file)
+	return path
+}
+
+// readCommandHistory reads the latest commands of a history file, one per
+// line as readline writes them
+func readCommandHistory(reader io.Reader) []string {
+	var commands []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	if len(commands) > commandHistoryLimit {
+		commands = commands[len(commands)-commandHistoryLimit:]
+	}
+	return commands
+}
+
+// recordCommand adds a command to the history and its file
+func (r *REPL) recordCommand(input string) {
+	r.commands = append(r.commands, input)
+	if len(r.commands) > commandHistoryLimit {
+		r.commands = r.commands[len(r.commands)-commandHistoryLimit:]
+	}
+	if r.rl != nil {
+		if err := r.rl.SaveHistory(input); err != nil {
+			r.logger.Debug("Failed to save command history: %v", err)
+		}
+	}
+}
+
+// expandHistory replaces a line starting with ! by the command it refers
+// to: !n is the command the history command numbers n, !-n the nth latest,
+// !! the previous command and !prefix the latest one starting with prefix
+func (r *REPL) expandHistory(input string) (string, bool, error) {
+	if !strings.HasPrefix(input, "!") || input == "!" {
+		return input, false, nil
+	}
+	ref := input[1:]
+	if len(r.commands) == 0 {
+		return "", false, fmt.Errorf("%s: the command history is empty", input)
+	}
+	if ref == "!" {
+		return r.commands[len(r.commands)-1], true, nil
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 0 {
+			n += len(r.commands) + 1
+		}
+		if n < 1 || n > len(r.commands) {
+			return "", false, fmt.Errorf("%s: no such command in the history (1-%d)", input, len(r.commands))
+		}
+		return r.commands[n-1], true, nil
+	}
+	for i := len(r.commands) - 1; i >= 0; i-- {
+		if strings.HasPrefix(r.commands[i], ref) {
+			return r.commands[i], true, nil
+		}
+	}
+	return "", false, fmt.Errorf("%s: no command in the history starts with %q", input, ref)
+}
+
+// showCommandHistory lists the latest count commands with the numbers !n
+// re-executes, or all of them if count is 0
+func (r *REPL) showCommandHistory(count string) error {
+	n := 0
+	if count != "" {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q", count)
+		}
+	}
+	writeCommandHistory(os.Stdout, r.commands, n)
+	return nil
+}
+
+// writeCommandHistory prints the latest count commands, numbered from the
+// oldest one kept
+func writeCommandHistory(w io.Writer, commands []string, count int) {
+	if len(commands) == 0 {
+		_, _ = fmt.Fprintln(w, "The command history is empty.")
+		return
+	}
+	first := 0
+	if count > 0 && count < len(commands) {
+		first = len(commands) - count
+	}
+	width := len(strconv.Itoa(len(commands)))
+	for i := first; i < len(commands); i++ {
+		_, _ = fmt.Fprintf(w, "  %*d  %s\n", width, i+1, commands[i])
+	}
+}
+
+// isCommandHistoryCount tells "history 20" from the capability history of
+// a list
+func isCommandHistoryCount(arg string) bool {
+	_, err := strconv.Atoi(arg)
+	return err == nil
+}
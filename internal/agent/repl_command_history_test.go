@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandHistory(t *testing.T) {
+	r := &REPL{commands: []string{"list tools", "call echo {\"text\": \"hi\"}", "describe tool echo"}}
+
+	tests := []struct {
+		input    string
+		want     string
+		expanded bool
+		wantErr  bool
+	}{
+		{input: "list prompts", want: "list prompts"},
+		{input: "call echo {\"text\": \"!1\"}", want: "call echo {\"text\": \"!1\"}"},
+		{input: "!", want: "!"},
+		{input: "!!", want: "describe tool echo", expanded: true},
+		{input: "!2", want: "call echo {\"text\": \"hi\"}", expanded: true},
+		{input: "!-3", want: "list tools", expanded: true},
+		{input: "!call", want: "call echo {\"text\": \"hi\"}", expanded: true},
+		{input: "!4", wantErr: true},
+		{input: "!0", wantErr: true},
+		{input: "!ping", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, expanded, err := r.expandHistory(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("expandHistory(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || expanded != tt.expanded {
+			t.Errorf("expandHistory(%q) = %q, %v; want %q, %v", tt.input, got, expanded, tt.want, tt.expanded)
+		}
+	}
+
+	empty := &REPL{}
+	if _, _, err := empty.expandHistory("!!"); err == nil {
+		t.Error("expected an error for an empty history")
+	}
+}
+
+func TestWriteCommandHistory(t *testing.T) {
+	commands := make([]string, 12)
+	for i := range commands {
+		commands[i] = fmt.Sprintf("command %d", i+1)
+	}
+
+	var all bytes.Buffer
+	writeCommandHistory(&all, commands, 0)
+	if lines := strings.Split(strings.TrimSuffix(all.String(), "\n"), "\n"); len(lines) != 12 || lines[0] != "   1  command 1" {
+		t.Errorf("unexpected history:\n%s", all.String())
+	}
+
+	var latest bytes.Buffer
+	writeCommandHistory(&latest, commands, 2)
+	if want := "  11  command 11\n  12  command 12\n"; latest.String() != want {
+		t.Errorf("latest commands:\n%s\nwant:\n%s", latest.String(), want)
+	}
+
+	var empty bytes.Buffer
+	writeCommandHistory(&empty, nil, 0)
+	if !strings.Contains(empty.String(), "empty") {
+		t.Errorf("unexpected output for an empty history: %q", empty.String())
+	}
+}
+
+func TestOpenHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "endpoint-test")
+	r := &REPL{logger: NewLoggerWithWriter(false, false, false, io.Discard)}
+	r.SetHistoryFile(path)
+
+	if got := r.openHistoryFile(); got != path {
+		t.Fatalf("openHistoryFile = %q, want %q", got, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("history file mode = %o, want 600", mode)
+	}
+
+	var lines strings.Builder
+	for i := range commandHistoryLimit + 5 {
+		_, _ = fmt.Fprintf(&lines, "command %d\n\n", i+1)
+	}
+	if err := os.WriteFile(path, []byte(lines.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r.openHistoryFile()
+	if len(r.commands) != commandHistoryLimit || r.commands[0] != "command 6" {
+		t.Errorf("loaded %d commands starting with %q, want %d starting with command 6", len(r.commands), r.commands[0], commandHistoryLimit)
+	}
+}
+
+func TestDefaultHistoryFile(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	tests := []struct {
+		profile  string
+		endpoint string
+		want     string
+	}{
+		{profile: "staging", endpoint: "http://localhost:8090/mcp", want: "profile-staging"},
+		{endpoint: "http://localhost:8090/mcp", want: "endpoint-http_localhost_8090_mcp"},
+		{endpoint: "https://mcp.example.com/", want: "endpoint-https_mcp.example.com"},
+	}
+	for _, tt := range tests {
+		want := filepath.Join(config, "mcp-debug", "history", tt.want)
+		if got := DefaultHistoryFile(tt.profile, tt.endpoint); got != want {
+			t.Errorf("DefaultHistoryFile(%q, %q) = %s, want %s", tt.profile, tt.endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package agent
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxCompletionCandidates caps the number of names offered per TAB press, so
+// servers with thousands of tools do not flood the terminal
+const maxCompletionCandidates = 100
+
+// replCompleter is a lazy readline.AutoCompleter. Candidates are computed from
+// the client caches on each TAB press and only names matching the typed
+// prefix are considered, so list_changed refreshes never rebuild a completion
+// tree and the cost of a TAB press does not depend on unrelated categories.
+type replCompleter struct {
+	r *REPL
+}
+
+// completionSource yields candidate names for an argument position; it stops
+// early once yield returns false
+type completionSource func(yield func(name string) bool)
+
+// Do implements readline.AutoCompleter
+func (c *replCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	text := string(line[:pos])
+	words := strings.Fields(text)
+
+	// The word being completed is empty if the cursor follows whitespace
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\t") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	source := c.sourceFor(words)
+	if source == nil {
+		return nil, 0
+	}
+
+	var suggestions [][]rune
+	source(func(name string) bool {
+		if strings.HasPrefix(name, current) {
+			suggestions = append(suggestions, []rune(name[len(current):]+" "))
+		}
+		return len(suggestions) < maxCompletionCandidates
+	})
+	return suggestions, len([]rune(current))
+}
+
+// sourceFor returns the candidates for the argument following the given
+// complete words, or nil if there is nothing to complete
+func (c *replCompleter) sourceFor(words []string) completionSource {
+	client := c.r.client
+
+	if len(words) == 0 {
+		return staticSource(c.commandNames()...)
+	}
+
+	command := strings.ToLower(words[0])
+	if command == "call" && len(words) > 1 && words[1] == "&" {
+		// A leading & does not change what follows
+		words = append([]string{words[0]}, words[2:]...)
+	}
+	if command == "auth" {
+		switch len(words) {
+		case 1:
+			return staticSource("scopes")
+		case 2:
+			return staticSource("add")
+		}
+		return nil
+	}
+	if command == "describe" {
+		switch len(words) {
+		case 1:
+			return staticSource(c.describeTargets()...)
+		case 2:
+			return c.describeSource(words[1])
+		}
+		return nil
+	}
+	if command == "history" && len(words) == 2 {
+		return c.historySource(words[1])
+	}
+	if command == "capabilities" && len(words) > 1 {
+		return staticSource(capabilitySampling, capabilityRoots, capabilityElicitation)
+	}
+	if command == "list" && len(words) == 2 {
+		return staticSource("--page")
+	}
+	if command == "expand" && len(words) == 2 {
+		return staticSource("--all")
+	}
+	if command == "show" && len(words) == 2 && words[1] == "last" {
+		return staticSource(DisplayFormats...)
+	}
+	if command == "display" && len(words) == 2 {
+		switch words[1] {
+		case "images":
+			return staticSource(ImagePreviews...)
+		case "binary":
+			return staticSource("save", "off")
+		case "pager":
+			return staticSource("on", "off")
+		case "fold":
+			return staticSource("off")
+		}
+		return nil
+	}
+	if command == "call" && len(words) > 1 && strings.HasPrefix(words[1], "--timeout") {
+		// The tool name follows the --timeout option
+		optionWords := 2
+		if words[1] == "--timeout" {
+			optionWords = 3
+		}
+		switch len(words) {
+		case optionWords:
+			return c.toolSource()
+		case optionWords + 1:
+			return c.argumentSource(words[optionWords])
+		}
+		return nil
+	}
+	if command == "call" && len(words) == 2 {
+		return c.argumentSource(words[1])
+	}
+	if command == "get" && len(words) == 2 && words[1] == "--if-changed" {
+		return c.resourceSource()
+	}
+	if len(words) != 1 {
+		return nil
+	}
+
+	switch command {
+	case "list":
+		return staticSource(c.listTargets()...)
+	case "refresh":
+		return staticSource("tools", "resources", "prompts")
+	case "history":
+		return staticSource(c.historyTargets()...)
+	case "notifications":
+		return staticSource("on", "off")
+	case "capabilities":
+		return staticSource("set", "add", "remove")
+	case "show", "save":
+		return staticSource("last")
+	case "expand":
+		return staticSource(c.r.foldedPaths()...)
+	case "display":
+		return staticSource(append([]string{"limit", "images", "binary", "pager", "fold"}, DisplayFormats...)...)
+	case "token":
+		return staticSource("introspect", "refresh")
+	case "client":
+		return staticSource("show", "update", "delete")
+	case "use":
+		return staticSource(c.r.connections.Names()...)
+	case "disconnect":
+		return staticSource(c.secondaryConnections()...)
+	case "call":
+		return c.toolSource()
+	case "cancel":
+		return c.inFlightSource()
+	case "wait":
+		return c.jobSource()
+	case "get":
+		return chainSources(c.resourceSource(), c.templateSource())
+	case "subscribe":
+		return c.resourceSource()
+	case "template":
+		return c.templateSource()
+	case "prompt":
+		return c.promptSource()
+	case "loglevel":
+		return staticSource(LoggingLevels...)
+	case "unsubscribe":
+		return staticSource(client.Subscriptions()...)
+	}
+	return nil
+}
+
+// commandNames lists the commands available for the server's capabilities
+func (c *replCompleter) commandNames() []string {
+	client := c.r.client
+
+	names := []string{"help", "?", "exit", "quit", "server", "stats", "ping", "health", "capabilities", "trace", "show", "expand", "display", "save", "notifications", "refresh", "source", "connect", "connections", "endpoint"}
+	if len(c.r.connections.Names()) > 1 {
+		names = append(names, "use", "disconnect")
+	}
+	if len(c.listTargets()) > 0 {
+		names = append(names, "list", "describe", "history")
+	}
+	if client.ServerSupportsTools() {
+		names = append(names, "call", "requests", "jobs", "assert")
+	}
+	if len(client.InFlightRequests()) > 0 {
+		names = append(names, "cancel")
+	}
+	if len(c.r.jobs.list()) > 0 {
+		names = append(names, "wait")
+	}
+	if client.ServerSupportsResources() {
+		names = append(names, "get", "template")
+	}
+	if client.ServerSupportsPrompts() {
+		names = append(names, "prompt")
+	}
+	if client.ServerSupportsLogging() {
+		names = append(names, "loglevel")
+	}
+	if client.ServerSupportsSubscriptions() {
+		names = append(names, "subscribe", "unsubscribe")
+	}
+	if client.OAuthEnabled() {
+		names = append(names, "auth", "scopes", "token", "logout", "client")
+	}
+	return names
+}
+
+// inFlightSource yields the ids of the in-flight requests
+func (c *replCompleter) inFlightSource() completionSource {
+	var ids []string
+	for _, req := range c.r.client.InFlightRequests() {
+		if req.ID != "" {
+			ids = append(ids, req.ID)
+		}
+	}
+	return staticSource(ids...)
+}
+
+// jobSource yields the numbers of the background jobs
+func (c *replCompleter) jobSource() completionSource {
+	var ids []string
+	for _, job := range c.r.jobs.list() {
+		ids = append(ids, strconv.Itoa(job.id))
+	}
+	return staticSource(ids...)
+}
+
+// secondaryConnections lists the connections that can be disconnected
+func (c *replCompleter) secondaryConnections() []string {
+	var names []string
+	for _, name := range c.r.connections.Names() {
+		if name != DefaultConnectionName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// listTargets lists the list command targets for the server's capabilities
+func (c *replCompleter) listTargets() []string {
+	var targets []string
+	if c.r.client.ServerSupportsTools() {
+		targets = append(targets, "tools")
+	}
+	if c.r.client.ServerSupportsResources() {
+		targets = append(targets, "resources", "templates")
+	}
+	if c.r.client.ServerSupportsPrompts() {
+		targets = append(targets, "prompts")
+	}
+	return targets
+}
+
+// historyTargets lists the history command targets for the server's capabilities
+func (c *replCompleter) historyTargets() []string {
+	var targets []string
+	for _, target := range c.listTargets() {
+		if target != "templates" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// historySource returns the names for a history target
+func (c *replCompleter) historySource(target string) completionSource {
+	switch strings.ToLower(target) {
+	case "tools":
+		return c.toolSource()
+	case "resources":
+		return c.resourceSource()
+	case "prompts":
+		return c.promptSource()
+	}
+	return nil
+}
+
+// describeTargets lists the describe command targets for the server's capabilities
+func (c *replCompleter) describeTargets() []string {
+	var targets []string
+	if c.r.client.ServerSupportsTools() {
+		targets = append(targets, "tool")
+	}
+	if c.r.client.ServerSupportsResources() {
+		targets = append(targets, "resource", "template")
+	}
+	if c.r.client.ServerSupportsPrompts() {
+		targets = append(targets, "prompt")
+	}
+	return targets
+}
+
+// describeSource returns the names for a describe target
+func (c *replCompleter) describeSource(target string) completionSource {
+	switch strings.ToLower(target) {
+	case "tool":
+		return c.toolSource()
+	case "resource":
+		return c.resourceSource()
+	case "template":
+		return c.templateSource()
+	case "prompt":
+		return c.promptSource()
+	}
+	return nil
+}
+
+// toolSource yields cached tool names
+func (c *replCompleter) toolSource() completionSource {
+	if !c.r.client.ServerSupportsTools() {
+		return nil
+	}
+	return func(yield func(string) bool) {
+		c.r.client.mu.RLock()
+		defer c.r.client.mu.RUnlock()
+		for _, tool := range c.r.client.toolCache {
+			if !yield(tool.Name) {
+				return
+			}
+		}
+	}
+}
+
+// argumentSource yields the --interactive option and JSON argument
+// templates derived from the input schema of a cached tool with arguments
+func (c *replCompleter) argumentSource(toolName string) completionSource {
+	tool := c.r.findTool(toolName)
+	if tool == nil {
+		return nil
+	}
+	templates := toolArgumentTemplates(*tool)
+	if len(templates) == 0 {
+		return nil
+	}
+	return staticSource(append([]string{"--interactive"}, templates...)...)
+}
+
+// resourceSource yields cached resource URIs
+func (c *replCompleter) resourceSource() completionSource {
+	if !c.r.client.ServerSupportsResources() {
+		return nil
+	}
+	return func(yield func(string) bool) {
+		c.r.client.mu.RLock()
+		defer c.r.client.mu.RUnlock()
+		for _, resource := range c.r.client.resourceCache {
+			if !yield(resource.URI) {
+				return
+			}
+		}
+	}
+}
+
+// templateSource yields cached resource template names
+func (c *replCompleter) templateSource() completionSource {
+	if !c.r.client.ServerSupportsResources() {
+		return nil
+	}
+	return func(yield func(string) bool) {
+		c.r.client.mu.RLock()
+		defer c.r.client.mu.RUnlock()
+		for _, tmpl := range c.r.client.templateCache {
+			if !yield(tmpl.Name) {
+				return
+			}
+		}
+	}
+}
+
+// chainSources yields the candidates of each non-nil source in turn
+func chainSources(sources ...completionSource) completionSource {
+	return func(yield func(string) bool) {
+		more := true
+		for _, source := range sources {
+			if source == nil || !more {
+				continue
+			}
+			source(func(name string) bool {
+				more = yield(name)
+				return more
+			})
+		}
+	}
+}
+
+// promptSource yields cached prompt names
+func (c *replCompleter) promptSource() completionSource {
+	if !c.r.client.ServerSupportsPrompts() {
+		return nil
+	}
+	return func(yield func(string) bool) {
+		c.r.client.mu.RLock()
+		defer c.r.client.mu.RUnlock()
+		for _, prompt := range c.r.client.promptCache {
+			if !yield(prompt.Name) {
+				return
+			}
+		}
+	}
+}
+
+// staticSource yields a fixed list of names
+func staticSource(names ...string) completionSource {
+	return func(yield func(string) bool) {
+		for _, name := range names {
+			if !yield(name) {
+				return
+			}
+		}
+	}
+}
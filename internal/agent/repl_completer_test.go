@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func completions(c *replCompleter, line string) []string {
+	suggestions, _ := c.Do([]rune(line), len([]rune(line)))
+	names := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		names[i] = string(s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestREPLCompleter(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	c.serverCapabilities = &mcp.ServerCapabilities{
+		Tools:   &struct{ ListChanged bool `json:"listChanged,omitempty"` }{},
+		Prompts: &struct{ ListChanged bool `json:"listChanged,omitempty"` }{},
+	}
+	for i := 0; i < 5000; i++ {
+		c.toolCache = append(c.toolCache, mcp.Tool{Name: fmt.Sprintf("tool_%04d", i)})
+	}
+	c.toolCache = append(c.toolCache, mcp.NewTool("echo", mcp.WithString("message", mcp.Required()), mcp.WithBoolean("loud")))
+	c.promptCache = []mcp.Prompt{{Name: "greeting"}, {Name: "summary"}}
+	completer := NewREPL(c, c.logger).createCompleter()
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "ca", want: []string{"ll ", "pabilities "}},
+		{line: "capabilities add ro", want: []string{"ots "}},
+		{line: "call tool_499", want: []string{"0 ", "1 ", "2 ", "3 ", "4 ", "5 ", "6 ", "7 ", "8 ", "9 "}},
+		{line: "describe pr", want: []string{"ompt "}},
+		{line: "describe prompt s", want: []string{"ummary "}},
+		{line: "list ", want: []string{"prompts ", "tools "}},
+		{line: "get ", want: nil},
+		{line: "call tool_0001 ", want: nil},
+		{line: "call echo ", want: []string{"--interactive ", `{"message":"<string>","loud":false} `, `{"message":"<string>"} `}},
+		{line: "call echo --", want: []string{"interactive "}},
+		{line: "call --timeout 5s echo {\"message\":\"<string>\",", want: []string{`"loud":false} `}},
+		{line: "call echo {} ", want: nil},
+		{line: "call & ech", want: []string{"o "}},
+		{line: "call & echo --", want: []string{"interactive "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := completions(completer, tt.line)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("completions(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	if got := len(completions(completer, "call ")); got != maxCompletionCandidates {
+		t.Errorf("expected candidates to be capped at %d, got %d", maxCompletionCandidates, got)
+	}
+}
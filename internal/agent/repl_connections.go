@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+)
+
+// handleConnect opens an additional named connection. It gets its own
+// caches, notification listener and log prefix; the current connection
+// stays unchanged until 'use'.
+func (r *REPL) handleConnect(ctx context.Context, name, endpoint string) error {
+	client, err := r.connections.Connect(ctx, name, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to connect %s: %w", name, err)
+	}
+	client.onListRefreshed = r.refreshCompleter
+	client.onReconnected = r.showReconnectBanner
+	if r.rl != nil {
+		client.setInputReader(r.readLine, r.rl.Stdout())
+	}
+
+	stop := make(chan struct{})
+	r.listenerStops[name] = stop
+	r.wg.Add(1)
+	go r.notificationListener(ctx, client, stop)
+
+	r.updatePrompt()
+	fmt.Printf("Connected %s to %s. Type 'use %s' to switch to it.\n", name, endpoint, name)
+	return nil
+}
+
+// handleUse switches the connection that subsequent commands go to
+func (r *REPL) handleUse(name string) error {
+	client, err := r.connections.Use(name)
+	if err != nil {
+		return err
+	}
+	r.client = client
+	r.updatePrompt()
+	r.refreshCompleter("")
+	fmt.Printf("Using %s (%s)\n", name, client.currentEndpoint())
+	return nil
+}
+
+// handleDisconnect closes an additional connection. Closing the current one
+// switches back to the default connection.
+func (r *REPL) handleDisconnect(name string) error {
+	if err := r.connections.Disconnect(name); err != nil {
+		return err
+	}
+	if stop, ok := r.listenerStops[name]; ok {
+		close(stop)
+		delete(r.listenerStops, name)
+	}
+
+	current, client := r.connections.Current()
+	if client != r.client {
+		r.client = client
+		fmt.Printf("Switched to %s\n", current)
+	}
+	r.updatePrompt()
+	fmt.Printf("Disconnected %s\n", name)
+	return nil
+}
+
+// handleChangeEndpoint connects the current connection to another endpoint
+// without restarting, running the OAuth flow again if OAuth is enabled
+func (r *REPL) handleChangeEndpoint(ctx context.Context, endpoint string) error {
+	if err := r.client.ChangeEndpoint(ctx, endpoint); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	server := r.client.ServerInfo().Name
+	if server == "" {
+		server = "(unknown server)"
+	}
+	fmt.Printf("Connected to %s - %s\n", endpoint, server)
+	return nil
+}
+
+// showConnections lists the open connections, marking the current one
+func (r *REPL) showConnections() error {
+	current, _ := r.connections.Current()
+	fmt.Println("Connections:")
+	for _, name := range r.connections.Names() {
+		client := r.connections.Get(name)
+		if client == nil {
+			continue
+		}
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		server := client.ServerInfo().Name
+		if server == "" {
+			server = "(unknown server)"
+		}
+		fmt.Printf("  %s %-15s %s - %s\n", marker, name, client.currentEndpoint(), server)
+	}
+	return nil
+}
+
+// updatePrompt shows the current connection's name in the prompt once more
+// than one connection is open
+func (r *REPL) updatePrompt() {
+	if r.rl == nil {
+		return
+	}
+	current, _ := r.connections.Current()
+	if len(r.connections.Names()) > 1 {
+		r.rl.SetPrompt(fmt.Sprintf("MCP[%s]> ", current))
+	} else {
+		r.rl.SetPrompt("MCP> ")
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// conversationIndent indents the content of prompt messages below their role
+const conversationIndent = "  "
+
+// roleColors are the colors of the roles in prompt results; other roles are
+// yellow
+var roleColors = map[mcp.Role]string{
+	mcp.RoleUser:      colorBlue,
+	mcp.RoleAssistant: colorGreen,
+}
+
+// displayPromptResult displays the messages of a prompt like a
+// conversation, each under its role, or with raw set as the JSON the server
+// returned
+func displayPromptResult(w io.Writer, result *mcp.GetPromptResult, raw bool, opts DisplayOptions) {
+	if raw {
+		fmt.Fprintln(w, PrettyJSON(result))
+		return
+	}
+
+	if result.Description != "" {
+		fmt.Fprintln(w, result.Description)
+		fmt.Fprintln(w)
+	}
+	if len(result.Messages) == 0 {
+		fmt.Fprintln(w, "No messages.")
+		return
+	}
+	for i, msg := range result.Messages {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, roleLabel(msg.Role, opts))
+		writeMessageContent(w, msg.Content, opts)
+	}
+}
+
+// roleLabel returns the heading of a message of role, colored unless
+// colors are disabled
+func roleLabel(role mcp.Role, opts DisplayOptions) string {
+	label := string(role)
+	if label == "" {
+		label = "(no role)"
+	}
+	label += ":"
+	if opts.NoColor {
+		return label
+	}
+	color, ok := roleColors[role]
+	if !ok {
+		color = colorYellow
+	}
+	return color + label + colorReset
+}
+
+// writeMessageContent writes the content of a prompt message indented below
+// its role: text as is, binary content by type and size, resources with
+// their URI
+func writeMessageContent(w io.Writer, content mcp.Content, opts DisplayOptions) {
+	if textContent, ok := mcp.AsTextContent(content); ok {
+		writeIndented(w, textContent.Text)
+		return
+	}
+	if imageContent, ok := mcp.AsImageContent(content); ok {
+		writeBinary(w, conversationIndent, "Image", imageContent.MIMEType, imageContent.Data, opts)
+		return
+	}
+	if audioContent, ok := mcp.AsAudioContent(content); ok {
+		writeBinary(w, conversationIndent, "Audio", audioContent.MIMEType, audioContent.Data, opts)
+		return
+	}
+	if resource, ok := mcp.AsEmbeddedResource(content); ok {
+		if textContent, ok := mcp.AsTextResourceContents(resource.Resource); ok {
+			fmt.Fprintf(w, "%s[Resource: %s]\n", conversationIndent, textContent.URI)
+			writeIndented(w, textContent.Text)
+		} else if blobContent, ok := mcp.AsBlobResourceContents(resource.Resource); ok {
+			writeBinary(w, conversationIndent, "Resource "+blobContent.URI, blobContent.MIMEType, blobContent.Blob, opts)
+		}
+		return
+	}
+	if link, ok := asResourceLink(content); ok {
+		fmt.Fprintf(w, "%s[Resource link: %s]\n", conversationIndent, link.URI)
+		return
+	}
+	writeIndented(w, PrettyJSON(content))
+}
+
+// asResourceLink returns content as a resource link, if it is one
+func asResourceLink(content mcp.Content) (*mcp.ResourceLink, bool) {
+	switch link := content.(type) {
+	case mcp.ResourceLink:
+		return &link, true
+	case *mcp.ResourceLink:
+		return link, link != nil
+	}
+	return nil, false
+}
+
+// writeIndented writes text with every line indented below its role
+func writeIndented(w io.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintln(w, conversationIndent+line)
+	}
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestDisplayPromptResultAsConversation(t *testing.T) {
+	result := &mcp.GetPromptResult{
+		Description: "Review a change",
+		Messages: []mcp.PromptMessage{
+			{Role: mcp.RoleUser, Content: mcp.NewTextContent("Review this diff:\n\n+added")},
+			{Role: mcp.RoleUser, Content: mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///main.go", Text: "package main"})},
+			{Role: mcp.RoleAssistant, Content: mcp.NewImageContent(base64.StdEncoding.EncodeToString([]byte("png")), "image/png")},
+			{Role: mcp.RoleAssistant, Content: mcp.NewResourceLink("file:///doc.md", "doc", "", "text/markdown")},
+		},
+	}
+
+	var buf bytes.Buffer
+	displayPromptResult(&buf, result, false, DisplayOptions{NoColor: true, ImagePreview: PreviewOff})
+	want := `Review a change
+
+user:
+  Review this diff:
+
+  +added
+
+user:
+  [Resource: file:///main.go]
+  package main
+
+assistant:
+  [Image: image/png, 3 bytes]
+
+assistant:
+  [Resource link: file:///doc.md]
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestDisplayPromptResultColorsRoles(t *testing.T) {
+	result := &mcp.GetPromptResult{Messages: []mcp.PromptMessage{
+		{Role: mcp.RoleUser, Content: mcp.NewTextContent("hi")},
+		{Role: mcp.RoleAssistant, Content: mcp.NewTextContent("hello")},
+	}}
+
+	var buf bytes.Buffer
+	displayPromptResult(&buf, result, false, DisplayOptions{})
+	for _, want := range []string{colorBlue + "user:" + colorReset, colorGreen + "assistant:" + colorReset} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in %q", want, buf.String())
+		}
+	}
+}
+
+func TestDisplayPromptResultRaw(t *testing.T) {
+	result := &mcp.GetPromptResult{Messages: []mcp.PromptMessage{
+		{Role: mcp.RoleUser, Content: mcp.NewTextContent("hi")},
+	}}
+
+	var buf bytes.Buffer
+	displayPromptResult(&buf, result, true, DisplayOptions{})
+	if !strings.Contains(buf.String(), `"role": "user"`) || !strings.Contains(buf.String(), `"text": "hi"`) {
+		t.Errorf("expected the result as JSON, got %s", buf.String())
+	}
+}
@@ -0,0 +1,461 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// DefaultMaxDisplayBytes is the size above which the REPL truncates
+// results, so a huge result does not flood the terminal
+const DefaultMaxDisplayBytes = 64 << 10
+
+// tableCellWidth bounds the width of a table cell holding nested JSON
+const tableCellWidth = 40
+
+// DisplayFormat selects how the REPL renders JSON in results
+type DisplayFormat string
+
+const (
+	// DisplayPretty indents JSON text; the default
+	DisplayPretty DisplayFormat = "pretty"
+	// DisplayRaw prints text exactly as the server sent it
+	DisplayRaw DisplayFormat = "raw"
+	// DisplayTable renders JSON arrays of objects and objects as tables
+	DisplayTable DisplayFormat = "table"
+	// DisplayColor indents JSON text and highlights its syntax
+	DisplayColor DisplayFormat = "color"
+)
+
+// DisplayFormats lists the accepted display format names
+var DisplayFormats = []string{
+	string(DisplayPretty),
+	string(DisplayRaw),
+	string(DisplayTable),
+	string(DisplayColor),
+}
+
+// ParseDisplayFormat validates a display format name
+func ParseDisplayFormat(format string) (DisplayFormat, error) {
+	normalized := strings.ToLower(strings.TrimSpace(format))
+	for _, f := range DisplayFormats {
+		if f == normalized {
+			return DisplayFormat(f), nil
+		}
+	}
+	return "", fmt.Errorf("invalid display format: %s (must be one of: %s)", format, strings.Join(DisplayFormats, ", "))
+}
+
+// DisplayOptions configures how the REPL prints tool, resource and prompt
+// results
+type DisplayOptions struct {
+	// MaxBytes truncates rendered results longer than this; zero disables
+	// truncation. 'show last' prints the full result.
+	MaxBytes int
+	Format   DisplayFormat
+	// SaveBinary writes images, audio and blobs to temporary files
+	SaveBinary bool
+	// ImagePreview selects the inline image protocol; empty means auto
+	ImagePreview ImagePreview
+	// NoColor disables the role colors of prompt results
+	NoColor bool
+	// NoPager prints long output directly instead of through a pager
+	NoPager bool
+	// FoldLines folds nested JSON objects and arrays taller than this many
+	// lines; zero disables folding. 'show last' prints them unfolded.
+	FoldLines int
+
+	// folds collects the JSON values folded while rendering a result
+	folds *jsonFolds
+}
+
+// renderFunc writes a result with the given options
+type renderFunc func(w io.Writer, opts DisplayOptions)
+
+// shownResult is a tool, resource or prompt result the REPL printed or saved
+type shownResult struct {
+	render renderFunc
+	// save writes the result as file contents; see repl_save.go
+	save func(w io.Writer) error
+}
+
+// resultDisplay prints results within the display limits and keeps the
+// last one for 'show last' and 'save last' and the JSON folded last for
+// expand; safe for concurrent use, since background calls print their
+// results when they arrive
+type resultDisplay struct {
+	mu      sync.Mutex
+	options DisplayOptions
+	last    shownResult
+	folds   *jsonFolds
+}
+
+// SetDisplayOptions configures the rendering and truncation of results
+func (r *REPL) SetDisplayOptions(opts DisplayOptions) {
+	if opts.Format == "" {
+		opts.Format = DisplayPretty
+	}
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	r.display.options = opts
+}
+
+// displayOptions returns the current display options
+func (r *REPL) displayOptions() DisplayOptions {
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	opts := r.display.options
+	if opts.Format == "" {
+		opts.Format = DisplayPretty
+	}
+	return opts
+}
+
+// showResult prints a result in the current format, truncated to the
+// display limit, or saves it if the command redirected its output to a
+// file. The result is remembered for 'show last' and 'save last'.
+func (r *REPL) showResult(ctx context.Context, result shownResult) error {
+	r.rememberResult(result)
+
+	if path := outputFileFromContext(ctx); path != "" {
+		return saveResult(result, path)
+	}
+
+	opts := r.displayOptions()
+	opts.folds = r.jsonFoldsFor(opts)
+	var buf bytes.Buffer
+	result.render(&buf, opts)
+	r.keepFolds(opts.folds)
+	if r.canPage(ctx) {
+		var truncated bytes.Buffer
+		writeTruncated(&truncated, buf.Bytes(), opts.MaxBytes)
+		r.page(truncated.Bytes())
+		return nil
+	}
+	writeTruncated(os.Stdout, buf.Bytes(), opts.MaxBytes)
+	return nil
+}
+
+// rememberResult keeps result for 'show last' and 'save last'
+func (r *REPL) rememberResult(result shownResult) {
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	r.display.last = result
+}
+
+// writeTruncated writes output, cutting it at maxBytes (at a line break if
+// one is near) and telling how to see the rest. Inline images do not count
+// towards the limit.
+func writeTruncated(w io.Writer, output []byte, maxBytes int) {
+	total := visibleLen(output)
+	if maxBytes <= 0 || total <= maxBytes {
+		_, _ = w.Write(output)
+		return
+	}
+
+	cut := visibleOffset(output, maxBytes)
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	if nl := bytes.LastIndexByte(output[:cut], '\n'); nl >= cut/2 {
+		cut = nl + 1
+	}
+	_, _ = w.Write(output[:cut])
+	if cut > 0 && output[cut-1] != '\n' {
+		_, _ = io.WriteString(w, "\n")
+	}
+	// Color sequences may have been cut off before their reset
+	_, _ = fmt.Fprintf(w, "%s… output truncated: showing %d of %d bytes. Use 'show last' to see the full result.\n",
+		colorReset, visibleLen(output[:cut]), total)
+}
+
+// visibleLen returns the number of bytes of output outside inline images
+func visibleLen(output []byte) int {
+	n := 0
+	for i := 0; i < len(output); {
+		if end := imageSequenceEnd(output[i:]); end > 0 {
+			i += end
+			continue
+		}
+		n++
+		i++
+	}
+	return n
+}
+
+// visibleOffset returns the offset of the n-th byte of output outside
+// inline images, or len(output) if there are fewer
+func visibleOffset(output []byte, n int) int {
+	for i := 0; i < len(output); {
+		if end := imageSequenceEnd(output[i:]); end > 0 {
+			i += end
+			continue
+		}
+		if n == 0 {
+			return i
+		}
+		n--
+		i++
+	}
+	return len(output)
+}
+
+// imageSequenceEnd returns the length of the inline image escape sequence
+// starting b, or 0 if b does not start with one
+func imageSequenceEnd(b []byte) int {
+	for _, seq := range [][2]string{{itermImageStart, itermImageEnd}, {kittyImageStart, kittyImageEnd}} {
+		if bytes.HasPrefix(b, []byte(seq[0])) {
+			if end := bytes.Index(b, []byte(seq[1])); end > 0 {
+				return end + len(seq[1])
+			}
+		}
+	}
+	return 0
+}
+
+// showLast prints the last result in full, in the given format or the
+// current one
+func (r *REPL) showLast(format string) error {
+	r.display.mu.Lock()
+	render := r.display.last.render
+	r.display.mu.Unlock()
+	if render == nil {
+		return fmt.Errorf("no result to show yet")
+	}
+
+	opts := r.displayOptions()
+	if format != "" {
+		parsed, err := ParseDisplayFormat(format)
+		if err != nil {
+			return err
+		}
+		opts.Format = parsed
+	}
+	render(os.Stdout, opts)
+	return nil
+}
+
+// handleDisplay shows or changes the display options: 'display',
+// 'display <format>', 'display limit <bytes>', 'display images <mode>',
+// 'display binary <save|off>', 'display pager <on|off>' or
+// 'display fold <lines|off>'
+func (r *REPL) handleDisplay(args []string) error {
+	opts := r.displayOptions()
+	switch {
+	case len(args) == 0:
+	case len(args) == 2 && args[0] == "limit":
+		var limit int
+		if _, err := fmt.Sscan(args[1], &limit); err != nil || limit < 0 {
+			return fmt.Errorf("invalid limit %q: use a number of bytes (0 disables truncation)", args[1])
+		}
+		opts.MaxBytes = limit
+	case len(args) == 2 && args[0] == "images":
+		preview, err := ParseImagePreview(args[1])
+		if err != nil {
+			return err
+		}
+		opts.ImagePreview = preview
+	case len(args) == 2 && args[0] == "pager":
+		switch args[1] {
+		case "on":
+			opts.NoPager = false
+		case "off":
+			opts.NoPager = true
+		default:
+			return fmt.Errorf("usage: display pager <on|off>")
+		}
+	case len(args) == 2 && args[0] == "fold":
+		if args[1] == "off" {
+			opts.FoldLines = 0
+			break
+		}
+		var lines int
+		if _, err := fmt.Sscan(args[1], &lines); err != nil || lines < 1 {
+			return fmt.Errorf("invalid fold height %q: use a number of lines or off", args[1])
+		}
+		opts.FoldLines = lines
+	case len(args) == 2 && args[0] == "binary":
+		switch args[1] {
+		case "save":
+			opts.SaveBinary = true
+		case "off":
+			opts.SaveBinary = false
+		default:
+			return fmt.Errorf("usage: display binary <save|off>")
+		}
+	case len(args) == 1:
+		format, err := ParseDisplayFormat(args[0])
+		if err != nil {
+			return err
+		}
+		opts.Format = format
+	default:
+		return fmt.Errorf("usage: display [%s] | display limit <bytes> | display images <%s> | display binary <save|off> | display pager <on|off> | display fold <lines|off>",
+			strings.Join(DisplayFormats, "|"), strings.Join(ImagePreviews, "|"))
+	}
+	r.SetDisplayOptions(opts)
+
+	limit := "off"
+	if opts.MaxBytes > 0 {
+		limit = fmt.Sprintf("%d bytes", opts.MaxBytes)
+	}
+	preview := opts.ImagePreview
+	if preview == "" {
+		preview = PreviewAuto
+	}
+	binary := "off"
+	if opts.SaveBinary {
+		binary = "save to temporary files"
+	}
+	pager := "on"
+	if opts.NoPager {
+		pager = "off"
+	}
+	fold := "off"
+	if opts.FoldLines > 0 {
+		fold = fmt.Sprintf("above %d lines", opts.FoldLines)
+	}
+	fmt.Printf("Display format: %s, truncation: %s, image preview: %s, binary content: %s, pager: %s, folding: %s\n", opts.Format, limit, preview, binary, pager, fold)
+	return nil
+}
+
+// writeJSONText writes text holding JSON in the display format; other text
+// is written as is
+func writeJSONText(w io.Writer, text string, opts DisplayOptions) {
+	if opts.Format == DisplayRaw {
+		_, _ = fmt.Fprintln(w, text)
+		return
+	}
+	var data any
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		_, _ = fmt.Fprintln(w, text)
+		return
+	}
+	writeJSONValue(w, data, opts)
+}
+
+// writeJSONValue writes decoded JSON in the display format, folding large
+// nested values if opts collects folds
+func writeJSONValue(w io.Writer, data any, opts DisplayOptions) {
+	if opts.Format == DisplayTable && writeTable(w, data) {
+		return
+	}
+	view := jsonView{color: opts.Format == DisplayColor, foldLines: opts.FoldLines, folds: opts.folds}
+	_, _ = fmt.Fprintln(w, view.render(data, "$"))
+}
+
+// writeTable renders an array of objects as rows with a column per key, an
+// object as key/value rows and an array of scalars as one column. It
+// reports false for other values, which are not tabular.
+func writeTable(w io.Writer, data any) bool {
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	switch v := data.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		_, _ = fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\n", key, tableCell(v[key]))
+		}
+	case []any:
+		if len(v) == 0 {
+			return false
+		}
+		columns, ok := tableColumns(v)
+		if !ok {
+			_, _ = fmt.Fprintln(tw, "VALUE")
+			for _, item := range v {
+				_, _ = fmt.Fprintln(tw, tableCell(item))
+			}
+			break
+		}
+		_, _ = fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, item := range v {
+			row := item.(map[string]any)
+			cells := make([]string, len(columns))
+			for i, column := range columns {
+				if value, ok := row[column]; ok {
+					cells[i] = tableCell(value)
+				}
+			}
+			_, _ = fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+	default:
+		return false
+	}
+	_ = tw.Flush()
+	// Drop the padding of empty cells at the end of rows
+	for _, line := range strings.SplitAfter(table.String(), "\n") {
+		if line != "" {
+			_, _ = fmt.Fprintln(w, strings.TrimRight(line, " \n"))
+		}
+	}
+	return true
+}
+
+// tableColumns returns the keys of an array of objects in order of first
+// appearance, or false if an element is not an object
+func tableColumns(items []any) ([]string, bool) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		row, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		keys := make([]string, 0, len(row))
+		for key := range row {
+			if !seen[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			seen[key] = true
+			columns = append(columns, key)
+		}
+	}
+	return columns, true
+}
+
+// tableCell renders a value on one line, shortening nested JSON
+func tableCell(value any) string {
+	var cell string
+	switch v := value.(type) {
+	case string:
+		cell = v
+	case nil:
+		return ""
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		cell = string(b)
+	}
+	cell = strings.NewReplacer("\n", `\n`, "\t", " ").Replace(cell)
+	if utf8.RuneCountInString(cell) > tableCellWidth {
+		cell = string([]rune(cell)[:tableCellWidth-1]) + "…"
+	}
+	return cell
+}
+
+// ANSI colors of highlighted JSON
+const (
+	colorJSONKey     = colorBlue
+	colorJSONString  = colorGreen
+	colorJSONNumber  = colorYellow
+	colorJSONLiteral = "\033[35m"
+)
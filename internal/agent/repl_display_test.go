@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseDisplayFormat(t *testing.T) {
+	for name, want := range map[string]DisplayFormat{
+		"pretty":  DisplayPretty,
+		"RAW":     DisplayRaw,
+		" table ": DisplayTable,
+		"color":   DisplayColor,
+	} {
+		got, err := ParseDisplayFormat(name)
+		if err != nil || got != want {
+			t.Errorf("ParseDisplayFormat(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseDisplayFormat("yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteTruncated(t *testing.T) {
+	output := []byte(strings.Repeat("0123456789\n", 10))
+
+	var buf bytes.Buffer
+	writeTruncated(&buf, output, 0)
+	if buf.String() != string(output) {
+		t.Error("output should not be truncated without a limit")
+	}
+
+	buf.Reset()
+	writeTruncated(&buf, output, 50)
+	got := buf.String()
+	if !strings.HasPrefix(got, strings.Repeat("0123456789\n", 4)+colorReset+"…") {
+		t.Errorf("output should be cut at the last line break before the limit, got %q", got)
+	}
+	if !strings.Contains(got, "showing 44 of 110 bytes") || !strings.Contains(got, "show last") {
+		t.Errorf("truncation note missing sizes or hint: %q", got)
+	}
+}
+
+func TestWriteTruncatedKeepsRunes(t *testing.T) {
+	var buf bytes.Buffer
+	writeTruncated(&buf, []byte("ääää"), 3)
+	if got := strings.SplitN(buf.String(), "\n", 2)[0]; got != "ä" {
+		t.Errorf("expected the cut to fall between runes, got %q", got)
+	}
+}
+
+func TestWriteJSONText(t *testing.T) {
+	text := `{"b":[1,2],"a":"x"}`
+
+	var buf bytes.Buffer
+	writeJSONText(&buf, text, DisplayOptions{Format: DisplayRaw})
+	if buf.String() != text+"\n" {
+		t.Errorf("raw format should keep the text, got %q", buf.String())
+	}
+
+	buf.Reset()
+	writeJSONText(&buf, text, DisplayOptions{Format: DisplayPretty})
+	if !strings.Contains(buf.String(), "\n  \"a\": \"x\"") {
+		t.Errorf("pretty format should indent, got %q", buf.String())
+	}
+
+	buf.Reset()
+	writeJSONText(&buf, "not json", DisplayOptions{Format: DisplayTable})
+	if buf.String() != "not json\n" {
+		t.Errorf("non-JSON text should be written as is, got %q", buf.String())
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSONText(&buf, `[{"name":"api-0","restarts":0},{"name":"api-1","phase":"Pending"}]`, DisplayOptions{Format: DisplayTable})
+	want := "NAME   RESTARTS  PHASE\n" +
+		"api-0  0\n" +
+		"api-1            Pending\n"
+	if buf.String() != want {
+		t.Errorf("array of objects:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeJSONText(&buf, `{"status":"ok","nested":{"a":1}}`, DisplayOptions{Format: DisplayTable})
+	want = "KEY     VALUE\n" +
+		"nested  {\"a\":1}\n" +
+		"status  ok\n"
+	if buf.String() != want {
+		t.Errorf("object:\ngot:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeJSONText(&buf, `"just a string"`, DisplayOptions{Format: DisplayTable})
+	if buf.String() != "\"just a string\"\n" {
+		t.Errorf("scalars should fall back to pretty JSON, got %q", buf.String())
+	}
+}
+
+func TestTableCellShortensLongValues(t *testing.T) {
+	cell := tableCell(strings.Repeat("x", 100))
+	if len([]rune(cell)) != tableCellWidth || !strings.HasSuffix(cell, "…") {
+		t.Errorf("expected a cell of %d runes ending in an ellipsis, got %q", tableCellWidth, cell)
+	}
+	if got := tableCell("a\nb"); got != `a\nb` {
+		t.Errorf("line breaks should be escaped, got %q", got)
+	}
+}
+
+func TestDisplayToolResultFormats(t *testing.T) {
+	result := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(`{"a":1}`)}}
+
+	var buf bytes.Buffer
+	displayToolResult(&buf, result, DisplayOptions{Format: DisplayRaw})
+	if buf.String() != "Result:\n{\"a\":1}\n" {
+		t.Errorf("unexpected raw result: %q", buf.String())
+	}
+}
+
+func TestHandleDisplay(t *testing.T) {
+	r := &REPL{}
+	if got := r.displayOptions().Format; got != DisplayPretty {
+		t.Errorf("default format = %s, want pretty", got)
+	}
+	if err := r.handleDisplay([]string{"table"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.handleDisplay([]string{"limit", "1024"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.displayOptions(); got.Format != DisplayTable || got.MaxBytes != 1024 {
+		t.Errorf("options = %+v, want table with a 1024 byte limit", got)
+	}
+	if err := r.handleDisplay([]string{"limit", "-1"}); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+	if err := r.handleDisplay([]string{"yaml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := r.showLast(""); err == nil {
+		t.Error("expected an error before any result was shown")
+	}
+}
@@ -3,7 +3,12 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -36,69 +41,89 @@ func parseToolArgs(argsStr string, toolName string) (map[string]interface{}, err
 	return args, nil
 }
 
-// displayContent displays a single content item with an optional prefix.
-//
-// Prefix behavior:
-//   - Empty string (""):  Text content is passed to displayTextContent() for JSON
-//     pretty-printing. This is used for tool results where JSON responses are common.
-//   - Non-empty prefix (e.g., "Content: "): Text is printed as-is with the prefix.
-//     This is used for prompt messages where the raw text should be displayed.
-func displayContent(content mcp.Content, prefix string) {
+// displayContent displays a single content item of a tool result. Text is
+// rendered as JSON in the display format if it parses as JSON.
+func displayContent(w io.Writer, content mcp.Content, opts DisplayOptions) {
 	if textContent, ok := mcp.AsTextContent(content); ok {
-		if prefix == "" {
-			// No prefix: attempt JSON pretty-printing for tool results
-			displayTextContent(textContent.Text)
-		} else {
-			// With prefix: display raw text (used for prompt messages)
-			fmt.Printf("%s%s\n", prefix, textContent.Text)
-		}
+		writeJSONText(w, textContent.Text, opts)
 		return
 	}
 	if imageContent, ok := mcp.AsImageContent(content); ok {
-		fmt.Printf("%s[Image: MIME type %s, %d bytes]\n", prefix, imageContent.MIMEType, len(imageContent.Data))
+		writeBinary(w, "", "Image", imageContent.MIMEType, imageContent.Data, opts)
 		return
 	}
 	if audioContent, ok := mcp.AsAudioContent(content); ok {
-		fmt.Printf("%s[Audio: MIME type %s, %d bytes]\n", prefix, audioContent.MIMEType, len(audioContent.Data))
+		writeBinary(w, "", "Audio", audioContent.MIMEType, audioContent.Data, opts)
 		return
 	}
 	if resource, ok := mcp.AsEmbeddedResource(content); ok {
-		fmt.Printf("%s[Embedded Resource: %v]\n", prefix, resource.Resource)
+		if textContent, ok := mcp.AsTextResourceContents(resource.Resource); ok {
+			fmt.Fprintf(w, "[Embedded Resource: %s]\n", textContent.URI)
+			writeJSONText(w, textContent.Text, opts)
+		} else if blobContent, ok := mcp.AsBlobResourceContents(resource.Resource); ok {
+			writeBinary(w, "", "Embedded Resource "+blobContent.URI, blobContent.MIMEType, blobContent.Blob, opts)
+		} else {
+			fmt.Fprintf(w, "[Embedded Resource: %v]\n", resource.Resource)
+		}
 		return
 	}
-	fmt.Printf("%s%+v\n", prefix, content)
-}
-
-// displayTextContent displays text content, pretty-printing JSON if possible
-func displayTextContent(text string) {
-	var jsonData interface{}
-	if err := json.Unmarshal([]byte(text), &jsonData); err == nil {
-		fmt.Println(PrettyJSON(jsonData))
-	} else {
-		fmt.Println(text)
-	}
+	fmt.Fprintf(w, "%+v\n", content)
 }
 
 // displayToolResult displays the result of a tool call
-func displayToolResult(result *mcp.CallToolResult) {
+func displayToolResult(w io.Writer, result *mcp.CallToolResult, opts DisplayOptions) {
 	if result.IsError {
-		fmt.Println("Tool returned an error:")
+		fmt.Fprintln(w, "Tool returned an error:")
 		for _, content := range result.Content {
 			if textContent, ok := mcp.AsTextContent(content); ok {
-				fmt.Printf("  %s\n", textContent.Text)
+				fmt.Fprintf(w, "  %s\n", textContent.Text)
 			}
 		}
 		return
 	}
 
-	fmt.Println("Result:")
+	fmt.Fprintln(w, "Result:")
 	for _, content := range result.Content {
-		displayContent(content, "")
+		displayContent(w, content, opts)
 	}
 }
 
-// handleCallTool executes a tool with the given arguments
-func (r *REPL) handleCallTool(ctx context.Context, toolName string, argsStr string) error {
+// parseCallTimeout removes a leading --timeout option from the words of a
+// call command and returns the remaining words with the parsed timeout, or a
+// nil timeout if the option is absent
+func parseCallTimeout(parts []string) ([]string, *time.Duration, error) {
+	if len(parts) < 2 {
+		return parts, nil, nil
+	}
+
+	var value string
+	var consumed int
+	switch {
+	case parts[1] == "--timeout":
+		if len(parts) < 3 {
+			return nil, nil, errors.New("usage: call --timeout <duration> <tool-name> [args...]")
+		}
+		value, consumed = parts[2], 2
+	case strings.HasPrefix(parts[1], "--timeout="):
+		value, consumed = strings.TrimPrefix(parts[1], "--timeout="), 1
+	default:
+		return parts, nil, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return nil, nil, fmt.Errorf("invalid timeout %q: use a duration such as 30s or 2m (0 disables the deadline)", value)
+	}
+	rest := append([]string{parts[0]}, parts[1+consumed:]...)
+	if len(rest) < 2 {
+		return nil, nil, errors.New("usage: call --timeout <duration> <tool-name> [args...]")
+	}
+	return rest, &timeout, nil
+}
+
+// handleCallTool executes a tool with the given arguments. A background call
+// returns immediately; its result is printed when it arrives.
+func (r *REPL) handleCallTool(ctx context.Context, toolName string, argsStr string, background bool) error {
 	if !r.client.ServerSupportsTools() {
 		return fmt.Errorf("server does not support tools capability")
 	}
@@ -111,15 +136,49 @@ func (r *REPL) handleCallTool(ctx context.Context, toolName string, argsStr stri
 	if err != nil {
 		return err
 	}
+	return r.callTool(ctx, toolName, args, background)
+}
+
+// handleInteractiveCall asks for the arguments of a tool with the argument
+// wizard and calls it once the user confirms them
+func (r *REPL) handleInteractiveCall(ctx context.Context, toolName string, background bool) error {
+	if !r.client.ServerSupportsTools() {
+		return fmt.Errorf("server does not support tools capability")
+	}
+
+	tool := r.findTool(toolName)
+	if tool == nil {
+		return fmt.Errorf("tool not found: %s", toolName)
+	}
+
+	wizard := &argumentWizard{readLine: r.readLine, out: r.rl.Stdout()}
+	args, err := wizard.run(ctx, toolName, toolArguments(*tool))
+	if errors.Is(err, errInputInterrupted) {
+		return errArgumentsDeclined
+	}
+	if err != nil {
+		return err
+	}
+	return r.callTool(ctx, toolName, args, background)
+}
+
+// callTool executes a tool with parsed arguments, in the background or
+// while showing its progress
+func (r *REPL) callTool(ctx context.Context, toolName string, args map[string]interface{}, background bool) error {
+	if background {
+		r.startBackgroundCall(ctx, toolName, args)
+		return nil
+	}
 
 	fmt.Printf("Executing tool: %s...\n", toolName)
-	result, err := r.client.CallTool(ctx, toolName, args)
+	progress := newToolProgressDisplay()
+	result, err := r.client.CallToolStreaming(ctx, toolName, args, progress.update)
+	progress.finish()
 	if err != nil {
 		return fmt.Errorf("tool execution failed: %w", err)
 	}
 
-	displayToolResult(result)
-	return nil
+	return r.showResult(ctx, toolResult(result))
 }
 
 // findResource finds a resource by URI in the cache
@@ -135,45 +194,183 @@ func (r *REPL) findResource(uri string) *mcp.Resource {
 	return nil
 }
 
-// handleGetResource retrieves and displays a resource
-func (r *REPL) handleGetResource(ctx context.Context, uri string) error {
+// handleGetResource retrieves and displays a resource. If target is set, or
+// the resource exceeds the client's memory limit, the contents are written to
+// a file instead of being printed.
+func (r *REPL) handleGetResource(ctx context.Context, uri, target string) error {
 	if !r.client.ServerSupportsResources() {
 		return fmt.Errorf("server does not support resources capability")
 	}
 
-	resource := r.findResource(uri)
-	if resource == nil {
-		return fmt.Errorf("resource not found: %s", uri)
+	mimeType, uri, err := r.resolveResource(ctx, uri)
+	if err != nil {
+		return err
 	}
 
 	// Retrieve the resource
 	fmt.Printf("Retrieving resource: %s...\n", uri)
-	result, err := r.client.GetResource(ctx, uri)
+	progress := newDownloadProgress()
+	if target != "" {
+		written, err := r.client.SaveResource(ctx, uri, target, progress.update)
+		progress.finish()
+		if err != nil {
+			return fmt.Errorf("resource retrieval failed: %w", err)
+		}
+		r.rememberResult(savedResourceResult(target, written))
+		fmt.Printf("Saved %d bytes to %s\n", written, target)
+		return nil
+	}
+
+	download, err := r.client.DownloadResource(ctx, uri, progress.update)
+	progress.finish()
+	if err != nil {
+		return fmt.Errorf("resource retrieval failed: %w", err)
+	}
+	if download.Result != nil {
+		return r.showResult(ctx, resourceResult(download.Result, mimeType))
+	}
+	r.rememberResult(savedResourceResult(download.Path, download.Size))
+	fmt.Printf("Resource is %d bytes (limit %d), saved to %s instead of printing\n", download.Size, r.client.ResourceMemoryLimit(), download.Path)
+	return nil
+}
+
+// handleGetResourceIfChanged re-reads a resource and displays it only if it
+// changed since the previous 'get --if-changed' of it
+func (r *REPL) handleGetResourceIfChanged(ctx context.Context, uri string) error {
+	if !r.client.ServerSupportsResources() {
+		return fmt.Errorf("server does not support resources capability")
+	}
+
+	mimeType, uri, err := r.resolveResource(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Retrieving resource: %s...\n", uri)
+	result, changed, err := r.client.ReadResourceIfChanged(ctx, uri)
 	if err != nil {
 		return fmt.Errorf("resource retrieval failed: %w", err)
 	}
+	if !changed {
+		version, _ := r.client.ResourceVersion(uri)
+		fmt.Printf("Resource unchanged since the last 'get --if-changed' (%d bytes, sha256 %s); use 'get %s' to show it\n", version.Size, version.ShortDigest(), uri)
+		return nil
+	}
+	return r.displayOrSaveResource(ctx, result, mimeType)
+}
+
+// resolveResource maps the argument of get to the URI to read and its MIME
+// type. Besides listed resources it accepts a resource template, given by name
+// or URI template, whose variables are prompted for, and URIs produced by a
+// template.
+func (r *REPL) resolveResource(ctx context.Context, arg string) (mimeType, uri string, err error) {
+	if resource := r.findResource(arg); resource != nil {
+		return resource.MIMEType, resource.URI, nil
+	}
+
+	if tmpl := r.findTemplate(arg); tmpl != nil {
+		uri, err := r.expandTemplate(ctx, tmpl)
+		return tmpl.MIMEType, uri, err
+	}
+
+	if tmpl := r.client.MatchResourceTemplate(arg); tmpl != nil {
+		return tmpl.MIMEType, arg, nil
+	}
+	return "", "", fmt.Errorf("resource not found: %s", arg)
+}
+
+// displayOrSaveResource prints a resource, or saves it to a temporary file if
+// it exceeds the configured memory limit
+func (r *REPL) displayOrSaveResource(ctx context.Context, result *mcp.ReadResourceResult, mimeType string) error {
+	size := ResourceContentsSize(result.Contents)
+	limit := r.client.ResourceMemoryLimit()
+	if limit <= 0 || size <= limit {
+		return r.showResult(ctx, resourceResult(result, mimeType))
+	}
+
+	r.rememberResult(resourceResult(result, mimeType))
+	f, err := os.CreateTemp("", "mcp-debug-resource-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	target := f.Name()
+	_ = f.Close()
+	fmt.Printf("Resource is %d bytes (limit %d), saving to a file instead of printing\n", size, limit)
+	return saveResource(result, target)
+}
+
+// saveResource writes a resource to target
+func saveResource(result *mcp.ReadResourceResult, target string) error {
+	written, err := SaveResourceContents(result, target)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d bytes to %s\n", written, target)
+	return nil
+}
+
+// Download progress is shown once a transfer takes longer than
+// downloadProgressDelay, and then updated every downloadProgressInterval
+const (
+	downloadProgressDelay    = 500 * time.Millisecond
+	downloadProgressInterval = 200 * time.Millisecond
+)
+
+// downloadProgress renders an in-place line with the bytes received while a
+// resource is streamed
+type downloadProgress struct {
+	start time.Time
+	last  time.Time
+	shown bool
+}
+
+// newDownloadProgress starts timing a transfer
+func newDownloadProgress() *downloadProgress {
+	return &downloadProgress{start: time.Now()}
+}
+
+// update is the DownloadProgressHandler of the transfer
+func (p *downloadProgress) update(received, total int64) {
+	now := time.Now()
+	if now.Sub(p.start) < downloadProgressDelay || now.Sub(p.last) < downloadProgressInterval {
+		return
+	}
+	p.last, p.shown = now, true
+	if total > 0 {
+		fmt.Printf("\r  %d/%d bytes received (%d%%)", received, total, received*100/total)
+	} else {
+		fmt.Printf("\r  %d bytes received", received)
+	}
+}
+
+// finish ends the progress line, if one was shown
+func (p *downloadProgress) finish() {
+	if p.shown {
+		fmt.Println()
+	}
+}
 
-	// Display contents
-	fmt.Println("Contents:")
+// displayResourceContents displays the contents of a resource read,
+// rendering JSON in the display format when the resource declares an
+// application/json MIME type
+func displayResourceContents(w io.Writer, result *mcp.ReadResourceResult, mimeType string, opts DisplayOptions) {
+	fmt.Fprintln(w, "Contents:")
 	for _, content := range result.Contents {
 		if textContent, ok := mcp.AsTextResourceContents(content); ok {
 			// Check MIME type for appropriate display
-			if resource.MIMEType == "application/json" {
-				var jsonData interface{}
-				if err := json.Unmarshal([]byte(textContent.Text), &jsonData); err == nil {
-					fmt.Println(PrettyJSON(jsonData))
-				} else {
-					fmt.Println(textContent.Text)
-				}
+			if mimeType == "application/json" {
+				writeJSONText(w, textContent.Text, opts)
 			} else {
-				fmt.Println(textContent.Text)
+				fmt.Fprintln(w, textContent.Text)
 			}
 		} else if blobContent, ok := mcp.AsBlobResourceContents(content); ok {
-			fmt.Printf("[Binary data: %d bytes]\n", len(blobContent.Blob))
+			blobType := blobContent.MIMEType
+			if blobType == "" {
+				blobType = mimeType
+			}
+			writeBinary(w, "", "Binary data", blobType, blobContent.Blob, opts)
 		}
 	}
-
-	return nil
 }
 
 // findPrompt finds a prompt by name in the cache
@@ -232,17 +429,9 @@ func parsePromptArgs(argsStr string, prompt *mcp.Prompt) (map[string]string, err
 	return args, nil
 }
 
-// displayPromptResult displays the result of a prompt retrieval
-func displayPromptResult(result *mcp.GetPromptResult) {
-	fmt.Println("Messages:")
-	for i, msg := range result.Messages {
-		fmt.Printf("\n[%d] Role: %s\n", i+1, msg.Role)
-		displayContent(msg.Content, "Content: ")
-	}
-}
-
-// handleGetPrompt retrieves and displays a prompt with arguments
-func (r *REPL) handleGetPrompt(ctx context.Context, promptName string, argsStr string) error {
+// handleGetPrompt retrieves and displays a prompt with arguments, as a
+// conversation or with raw set as JSON
+func (r *REPL) handleGetPrompt(ctx context.Context, promptName string, argsStr string, raw bool) error {
 	if !r.client.ServerSupportsPrompts() {
 		return fmt.Errorf("server does not support prompts capability")
 	}
@@ -263,6 +452,22 @@ func (r *REPL) handleGetPrompt(ctx context.Context, promptName string, argsStr s
 		return fmt.Errorf("prompt retrieval failed: %w", err)
 	}
 
-	displayPromptResult(result)
-	return nil
+	return r.showResult(ctx, promptResult(result, raw))
+}
+
+// handleAssert runs the cases of an expectation file against the current
+// connection and fails if any of them does not pass
+func (r *REPL) handleAssert(ctx context.Context, path string) error {
+	if !r.client.ServerSupportsTools() {
+		return fmt.Errorf("server does not support tools capability")
+	}
+
+	suite, err := LoadAssertionSuite(path)
+	if err != nil {
+		return err
+	}
+
+	results := r.client.RunAssertions(ctx, suite.Cases)
+	WriteAssertionResults(os.Stdout, results)
+	return AssertionsError(results, len(suite.Cases))
 }
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// historyTimeFormat is how the history command shows when a change was seen
+const historyTimeFormat = "2006-01-02 15:04:05"
+
+// handleHistory shows when the tools, resources or prompts appeared,
+// disappeared or changed, optionally only for the entry called name
+func (r *REPL) handleHistory(target, name string) error {
+	var kind string
+	switch strings.ToLower(target) {
+	case "tools", "tool":
+		kind = historyTools
+	case "resources", "resource":
+		kind = historyResources
+	case "prompts", "prompt":
+		kind = historyPrompts
+	default:
+		return fmt.Errorf("unknown history target: %s. Use 'tools', 'resources', or 'prompts'", target)
+	}
+
+	snapshots, since, events := r.client.CapabilityHistory(kind)
+	writeHistory(os.Stdout, kind, name, snapshots, since, events)
+	return nil
+}
+
+// writeHistory prints the history events of a list kind, one line per change
+func writeHistory(w io.Writer, kind, name string, snapshots int, since time.Time, events []HistoryEvent) {
+	if snapshots == 0 {
+		_, _ = fmt.Fprintf(w, "No %s have been listed yet.\n", kind)
+		return
+	}
+
+	if name != "" {
+		var matching []HistoryEvent
+		for _, event := range events {
+			if event.Name == name {
+				matching = append(matching, event)
+			}
+		}
+		events = matching
+	}
+
+	subject := kind
+	if name != "" {
+		subject = name
+	}
+	if len(events) == 0 {
+		_, _ = fmt.Fprintf(w, "No changes to %s since %s (%d snapshot(s)).\n", subject, since.Local().Format(historyTimeFormat), snapshots)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "Changes to %s since %s (%d snapshot(s)):\n", subject, since.Local().Format(historyTimeFormat), snapshots)
+	for _, event := range events {
+		marker := "~"
+		switch event.Change {
+		case HistoryAppeared:
+			marker = "+"
+		case HistoryDisappeared:
+			marker = "-"
+		}
+		line := fmt.Sprintf("  %s  %s %s %s", event.Time.Local().Format(historyTimeFormat), marker, event.Name, event.Change)
+		if len(event.Fields) > 0 {
+			line += ": " + strings.Join(event.Fields, ", ")
+		}
+		_, _ = fmt.Fprintln(w, line)
+		for _, detail := range event.Details {
+			_, _ = fmt.Fprintf(w, "      %s\n", detail)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/chzyer/readline"
+)
+
+// readLine reads the answer to a server request (sampling, elicitation)
+// with the REPL's line editor. Such requests arrive while a command is
+// waiting for the server, so the editor is not reading commands meanwhile.
+func (r *REPL) readLine(ctx context.Context, prompt string) (string, error) {
+	r.rl.SetPrompt(prompt)
+	defer r.updatePrompt()
+
+	line, err := r.rl.Readline()
+	if err == readline.ErrInterrupt {
+		return "", errInputInterrupted
+	}
+	return line, err
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// replJob is a tool call started in the background with 'call &'
+type replJob struct {
+	id         int
+	connection string
+	call       *ToolCallHandle
+	// ctx is the context of the command that started the job, carrying its
+	// output redirection
+	ctx context.Context
+}
+
+// status describes the state of the job for 'jobs'
+func (job *replJob) status() string {
+	if !job.call.Finished() {
+		return "running"
+	}
+	if _, err := job.call.Wait(context.Background()); err != nil {
+		return "failed"
+	}
+	return "done"
+}
+
+// replJobs numbers the background calls of the REPL. Jobs are kept until
+// their result was collected with 'wait'; safe for concurrent use.
+type replJobs struct {
+	mu   sync.Mutex
+	next int
+	jobs []*replJob
+}
+
+// add registers a background call and returns its job
+func (j *replJobs) add(ctx context.Context, connection string, call *ToolCallHandle) *replJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.next++
+	job := &replJob{id: j.next, connection: connection, call: call, ctx: ctx}
+	j.jobs = append(j.jobs, job)
+	return job
+}
+
+// list returns the jobs in the order they were started
+func (j *replJobs) list() []*replJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*replJob(nil), j.jobs...)
+}
+
+// find returns the job with the given number
+func (j *replJobs) find(id int) *replJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, job := range j.jobs {
+		if job.id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// remove forgets a job whose result was collected
+func (j *replJobs) remove(job *replJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, other := range j.jobs {
+		if other == job {
+			j.jobs = append(j.jobs[:i], j.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// startBackgroundCall runs a tool call as a job without waiting for its
+// result, so the REPL stays usable and several calls can overlap. The job
+// can be followed with 'jobs' and 'requests', collected with 'wait' and
+// aborted with 'cancel'; its result is also printed above the prompt when it
+// arrives.
+func (r *REPL) startBackgroundCall(ctx context.Context, toolName string, args map[string]interface{}) {
+	connection, client := r.connections.Current()
+	// Requesting progress lets 'requests' show how far the call is
+	call := client.CallToolAsync(ctx, toolName, args, func(ToolProgress) {})
+	job := r.jobs.add(ctx, connection, call)
+	go func() {
+		result, err := call.Wait(context.Background())
+		r.printAbovePrompt(func() {
+			if err != nil {
+				r.logger.Error("[%d] Background call of %s failed after %s: %v", job.id, toolName, roundElapsed(call.Elapsed()), err)
+				return
+			}
+			fmt.Printf("[%d] Background call of %s finished in %s.\n", job.id, toolName, roundElapsed(call.Elapsed()))
+			if err := r.showResult(withPager(ctx, false), toolResult(result)); err != nil {
+				r.logger.Error("[%d] Background call of %s: %v", job.id, toolName, err)
+			}
+		})
+	}()
+	fmt.Printf("[%d] Started %s in the background; use 'jobs' to follow it, 'wait %d' for its result and 'cancel <request-id>' to abort it\n", job.id, toolName, job.id)
+}
+
+// roundElapsed rounds a call duration for display
+func roundElapsed(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// showJobs lists the background calls whose result was not yet collected
+func (r *REPL) showJobs() error {
+	jobs := r.jobs.list()
+	if len(jobs) == 0 {
+		fmt.Println("No background jobs.")
+		return nil
+	}
+
+	multiple := len(r.connections.Names()) > 1
+	fmt.Printf("Jobs (%d):\n", len(jobs))
+	for _, job := range jobs {
+		line := fmt.Sprintf("  [%d] %-8s %-24s %8s", job.id, job.status(), job.call.Tool, job.call.Elapsed().Round(100*time.Millisecond))
+		if multiple {
+			line += "  on " + job.connection
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// handleWait waits for the background job with the given number, or for all
+// jobs if id is empty, and shows the results
+func (r *REPL) handleWait(ctx context.Context, id string) error {
+	if id == "" {
+		jobs := r.jobs.list()
+		if len(jobs) == 0 {
+			fmt.Println("No background jobs.")
+			return nil
+		}
+		for _, job := range jobs {
+			if err := r.waitJob(ctx, job); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				r.logger.Error("%v", err)
+			}
+		}
+		return nil
+	}
+
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid job %q: use the number shown by 'jobs'", id)
+	}
+	job := r.jobs.find(n)
+	if job == nil {
+		return fmt.Errorf("no job %d", n)
+	}
+	return r.waitJob(ctx, job)
+}
+
+// waitJob waits for a job, shows its result and forgets it
+func (r *REPL) waitJob(ctx context.Context, job *replJob) error {
+	result, err := job.call.Wait(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.jobs.remove(job)
+	if err != nil {
+		return fmt.Errorf("[%d] %s failed after %s: %w", job.id, job.call.Tool, roundElapsed(job.call.Elapsed()), err)
+	}
+
+	fmt.Printf("[%d] %s finished in %s:\n", job.id, job.call.Tool, roundElapsed(job.call.Elapsed()))
+	return r.showResult(job.ctx, toolResult(result))
+}
@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestREPLWaitJobs(t *testing.T) {
+	c := newStubbedClient(t, newOverlappingStub(2))
+	c.serverCapabilities = &mcp.ServerCapabilities{Tools: &struct {
+		ListChanged bool `json:"listChanged,omitempty"`
+	}{}}
+	r := NewREPL(c, c.logger)
+	ctx := context.Background()
+	r.jobs.add(ctx, DefaultConnectionName, c.CallToolAsync(ctx, "first", nil, nil))
+	r.jobs.add(ctx, DefaultConnectionName, c.CallToolAsync(ctx, "fail", nil, nil))
+
+	output, err := captureStdout(func() error { return r.showJobs() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Jobs (2):", "[1]", "first", "[2]", "fail"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("jobs output missing %q:\n%s", want, output)
+		}
+	}
+
+	if err := r.handleWait(ctx, "3"); err == nil || err.Error() != "no job 3" {
+		t.Errorf("expected an unknown job to be rejected, got %v", err)
+	}
+	output, err = captureStdout(func() error { return r.handleWait(ctx, "1") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "[1] first finished in") || !strings.Contains(output, "result of first") {
+		t.Errorf("unexpected wait output:\n%s", output)
+	}
+	if err := r.handleWait(ctx, "2"); err == nil || !strings.Contains(err.Error(), "[2] fail failed after") || !strings.Contains(err.Error(), "tool exploded") {
+		t.Errorf("expected the failure of job 2, got %v", err)
+	}
+	if jobs := r.jobs.list(); len(jobs) != 0 {
+		t.Errorf("expected waited jobs to be forgotten, got %d", len(jobs))
+	}
+}
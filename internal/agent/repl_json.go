@@ -0,0 +1,315 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultFoldLines is the height above which nested JSON objects and
+// arrays in results and tool schemas are folded
+const DefaultFoldLines = 40
+
+// jsonFolds keeps the JSON documents the REPL showed and the values it
+// folded in them by path, for expand
+type jsonFolds struct {
+	docs  []any
+	nodes map[string]any
+}
+
+// jsonView renders decoded JSON indented like PrettyJSON, optionally with
+// highlighted syntax. If folds is set, nested objects and arrays taller
+// than foldLines are folded into one line naming the path expanding them.
+type jsonView struct {
+	color     bool
+	foldLines int
+	folds     *jsonFolds
+}
+
+// render returns data, the value at path, without a trailing newline
+func (v *jsonView) render(data any, path string) string {
+	if v.folds != nil {
+		v.folds.docs = append(v.folds.docs, data)
+	}
+	var b strings.Builder
+	v.write(&b, data, path, "", true)
+	return b.String()
+}
+
+// write renders a value at the indentation of its line; the root is never
+// folded
+func (v *jsonView) write(b *strings.Builder, data any, path, indent string, root bool) {
+	switch value := data.(type) {
+	case map[string]any:
+		if len(value) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		if !root && v.fold(value) {
+			v.writeFolded(b, value, path, "{", fmt.Sprintf("%d keys", len(value)), "}")
+			return
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteString("{\n")
+		for i, key := range keys {
+			b.WriteString(indent + "  ")
+			b.WriteString(v.paint(colorJSONKey, jsonScalar(key)))
+			b.WriteString(": ")
+			v.write(b, value[key], jsonKeyPath(path, key), indent+"  ", false)
+			if i < len(keys)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case []any:
+		if len(value) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		if !root && v.fold(value) {
+			v.writeFolded(b, value, path, "[", fmt.Sprintf("%d items", len(value)), "]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range value {
+			b.WriteString(indent + "  ")
+			v.write(b, item, jsonIndexPath(path, i), indent+"  ", false)
+			if i < len(value)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	case string:
+		b.WriteString(v.paint(colorJSONString, jsonScalar(value)))
+	case bool, nil:
+		b.WriteString(v.paint(colorJSONLiteral, jsonScalar(value)))
+	default:
+		b.WriteString(v.paint(colorJSONNumber, jsonScalar(value)))
+	}
+}
+
+// fold reports whether a nested value is folded
+func (v *jsonView) fold(data any) bool {
+	return v.folds != nil && v.foldLines > 0 && jsonLines(data) > v.foldLines
+}
+
+// writeFolded writes a folded value as {… 12 keys, expand .path} and keeps
+// it for expand
+func (v *jsonView) writeFolded(b *strings.Builder, data any, path, open, size, close string) {
+	if v.folds.nodes == nil {
+		v.folds.nodes = make(map[string]any)
+	}
+	v.folds.nodes[path] = data
+	b.WriteString(open + v.paint(colorGray, "… "+size+", expand "+path) + close)
+}
+
+// paint colors text if the view highlights syntax
+func (v *jsonView) paint(color, text string) string {
+	if !v.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// jsonScalar encodes a string, number or literal without escaping HTML
+func jsonScalar(value any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonLines returns the number of lines of a value's unfolded rendering
+func jsonLines(data any) int {
+	n := 2
+	switch value := data.(type) {
+	case map[string]any:
+		if len(value) == 0 {
+			return 1
+		}
+		for _, child := range value {
+			n += jsonLines(child)
+		}
+	case []any:
+		if len(value) == 0 {
+			return 1
+		}
+		for _, child := range value {
+			n += jsonLines(child)
+		}
+	default:
+		return 1
+	}
+	return n
+}
+
+// jsonKeyPath returns the JSONPath of an object's member, e.g. $.a.b or
+// $.a['b c'], as assertions accept it
+func jsonKeyPath(parent, key string) string {
+	if key != "" && !strings.ContainsAny(key, ".[]'* ") {
+		return parent + "." + key
+	}
+	return parent + "['" + key + "']"
+}
+
+// jsonIndexPath returns the JSONPath of an array's element, e.g. $.items[2]
+func jsonIndexPath(parent string, index int) string {
+	return fmt.Sprintf("%s[%d]", parent, index)
+}
+
+// formatJSONPath writes the steps of a path without wildcards back the
+// way folded values are named
+func formatJSONPath(steps []pathStep) string {
+	path := "$"
+	for _, step := range steps {
+		if step.isIndex {
+			path = jsonIndexPath(path, step.index)
+		} else {
+			path = jsonKeyPath(path, step.name)
+		}
+	}
+	return path
+}
+
+// parseExpandPath parses the path of expand: a JSONPath whose $ may be left
+// out, selecting one value
+func parseExpandPath(path string) ([]pathStep, error) {
+	switch {
+	case path == "" || path == ".":
+		path = "$"
+	case strings.HasPrefix(path, ".") || strings.HasPrefix(path, "["):
+		path = "$" + path
+	case !strings.HasPrefix(path, "$"):
+		path = "$." + path
+	}
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if step.wildcard || (step.isIndex && step.index < 0) {
+			return nil, fmt.Errorf("invalid path %q: expand shows a single value", path)
+		}
+	}
+	return steps, nil
+}
+
+// jsonFoldsFor returns the folds to keep for output rendered with opts, or
+// nil if nothing is folded: with folding off and in scripts, which assert
+// on the full output
+func (r *REPL) jsonFoldsFor(opts DisplayOptions) *jsonFolds {
+	if opts.FoldLines <= 0 || r.script != nil {
+		return nil
+	}
+	return &jsonFolds{}
+}
+
+// keepFolds makes the JSON shown with folds the one expand refers to
+func (r *REPL) keepFolds(folds *jsonFolds) {
+	if folds == nil || len(folds.docs) == 0 {
+		return
+	}
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	r.display.folds = folds
+}
+
+// writeJSONDocument writes v, any value encoding to JSON, indented in the
+// current display format and folded like results
+func (r *REPL) writeJSONDocument(w io.Writer, v any) {
+	var data any
+	encoded, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(encoded, &data)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(w, PrettyJSON(v))
+		return
+	}
+	opts := r.displayOptions()
+	view := jsonView{color: opts.Format == DisplayColor, foldLines: opts.FoldLines, folds: r.jsonFoldsFor(opts)}
+	_, _ = fmt.Fprintln(w, view.render(data, "$"))
+	r.keepFolds(view.folds)
+}
+
+// expandJSON shows the value at path in the JSON shown last, folding its
+// own large values again unless all is set
+func (r *REPL) expandJSON(path string, all bool) error {
+	steps, err := parseExpandPath(path)
+	if err != nil {
+		return err
+	}
+	path = formatJSONPath(steps)
+
+	r.display.mu.Lock()
+	folds := r.display.folds
+	var data any
+	found := false
+	if folds != nil {
+		data, found = folds.nodes[path]
+	}
+	r.display.mu.Unlock()
+	if folds == nil {
+		return errors.New("no folded JSON shown yet")
+	}
+	if !found {
+		// Values shown unfolded can be expanded too; the latest document
+		// having the path wins
+		for i := len(folds.docs) - 1; i >= 0 && !found; i-- {
+			if values := evaluateJSONPath(folds.docs[i], steps); len(values) == 1 {
+				data, found = values[0], true
+			}
+		}
+		if !found {
+			return fmt.Errorf("no value at %s in the JSON shown last", path)
+		}
+	}
+
+	opts := r.displayOptions()
+	view := jsonView{color: opts.Format == DisplayColor, foldLines: opts.FoldLines, folds: &jsonFolds{}}
+	if all {
+		view.foldLines = 0
+	}
+	fmt.Println(view.render(data, path))
+
+	// Values folded within this one can be expanded next
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	if r.display.folds == folds {
+		if folds.nodes == nil {
+			folds.nodes = make(map[string]any)
+		}
+		for nested, value := range view.folds.nodes {
+			folds.nodes[nested] = value
+		}
+	}
+	return nil
+}
+
+// foldedPaths lists the paths of the values folded in the JSON shown last
+func (r *REPL) foldedPaths() []string {
+	r.display.mu.Lock()
+	defer r.display.mu.Unlock()
+	if r.display.folds == nil {
+		return nil
+	}
+	paths := make([]string, 0, len(r.display.folds.nodes))
+	for path := range r.display.folds.nodes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestJSONViewHighlights(t *testing.T) {
+	view := jsonView{color: true}
+	got := view.render(map[string]any{"key": "va\"l", "n": -1.5, "ok": true, "none": nil, "html": "<b>"}, "$")
+	for _, want := range []string{
+		colorJSONKey + `"key"` + colorReset,
+		colorJSONString + `"va\"l"` + colorReset,
+		colorJSONString + `"<b>"` + colorReset,
+		colorJSONNumber + "-1.5" + colorReset,
+		colorJSONLiteral + "true" + colorReset,
+		colorJSONLiteral + "null" + colorReset,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("highlighted JSON missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestJSONViewMatchesPrettyJSON(t *testing.T) {
+	var data any
+	text := `{"b":[1,2.5,{"c":null}],"a":"x","e":{},"f":[],"g":{"h":[true,false]}}`
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		t.Fatal(err)
+	}
+	view := jsonView{foldLines: 3}
+	if got, want := view.render(data, "$"), PrettyJSON(data); got != want {
+		t.Errorf("unfolded rendering:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestJSONViewFolds(t *testing.T) {
+	var data any
+	text := `{"name":"echo","schema":{"type":"object","properties":{"text":{"type":"string"},"odd key":{"type":"number"}}},"tags":["a","b","c","d"]}`
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	folds := &jsonFolds{}
+	view := jsonView{foldLines: 5, folds: folds}
+	got := view.render(data, "$")
+	want := "{\n" +
+		"  \"name\": \"echo\",\n" +
+		"  \"schema\": {… 2 keys, expand $.schema},\n" +
+		"  \"tags\": [… 4 items, expand $.tags]\n" +
+		"}"
+	if got != want {
+		t.Errorf("folded rendering:\n%s\nwant:\n%s", got, want)
+	}
+	if len(folds.nodes) != 2 || len(folds.docs) != 1 {
+		t.Errorf("folded %d values of %d documents, want 2 of 1", len(folds.nodes), len(folds.docs))
+	}
+
+	// Expanding a value folds its own large values again, naming them by
+	// their full path
+	folds = &jsonFolds{}
+	view = jsonView{foldLines: 4, folds: folds}
+	got = view.render(data.(map[string]any)["schema"], "$.schema")
+	if !strings.Contains(got, `"properties": {… 2 keys, expand $.schema.properties}`) {
+		t.Errorf("expanded rendering:\n%s", got)
+	}
+	if _, ok := folds.nodes["$.schema.properties"]; !ok {
+		t.Errorf("nested fold not kept: %v", folds.nodes)
+	}
+}
+
+func TestParseExpandPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: ".", want: "$"},
+		{path: "$.schema.properties", want: "$.schema.properties"},
+		{path: ".schema.properties", want: "$.schema.properties"},
+		{path: "schema", want: "$.schema"},
+		{path: "tags[2]", want: "$.tags[2]"},
+		{path: "$['odd key']", want: "$['odd key']"},
+		{path: `[0]["a.b"]`, want: "$[0]['a.b']"},
+		{path: "$.tags[*]", wantErr: true},
+		{path: "$.tags[-1]", wantErr: true},
+		{path: "$.tags[", wantErr: true},
+	}
+	for _, tt := range tests {
+		steps, err := parseExpandPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseExpandPath(%q) error = %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil && formatJSONPath(steps) != tt.want {
+			t.Errorf("parseExpandPath(%q) = %s, want %s", tt.path, formatJSONPath(steps), tt.want)
+		}
+	}
+}
+
+func TestExpandJSON(t *testing.T) {
+	r := &REPL{}
+	if err := r.expandJSON("$.schema", false); err == nil {
+		t.Error("expected an error before any JSON was shown")
+	}
+
+	r.SetDisplayOptions(DisplayOptions{FoldLines: 3})
+	result := shownResult{render: func(w io.Writer, opts DisplayOptions) {
+		writeJSONText(w, `{"schema":{"properties":{"text":{"type":"string"}}},"tags":["a"]}`, opts)
+	}}
+	output, err := captureStdout(func() error { return r.showResult(context.Background(), result) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "expand $.schema}") {
+		t.Fatalf("expected a folded schema:\n%s", output)
+	}
+	if paths := r.foldedPaths(); len(paths) != 1 || paths[0] != "$.schema" {
+		t.Errorf("folded paths = %v, want [$.schema]", paths)
+	}
+
+	output, err = captureStdout(func() error { return r.expandJSON("schema", false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "expand $.schema.properties}") {
+		t.Errorf("expected the properties folded again:\n%s", output)
+	}
+	if paths := r.foldedPaths(); len(paths) != 2 {
+		t.Errorf("folded paths = %v, want the nested properties added", paths)
+	}
+
+	output, err = captureStdout(func() error { return r.expandJSON("$.schema", true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"type": "string"`) || strings.Contains(output, "expand") {
+		t.Errorf("expected the schema in full:\n%s", output)
+	}
+
+	// Values shown unfolded can be expanded as well
+	output, err = captureStdout(func() error { return r.expandJSON("$.tags[0]", false) })
+	if err != nil || strings.TrimSpace(output) != `"a"` {
+		t.Errorf("expand $.tags[0] = %q, %v", output, err)
+	}
+	if err := r.expandJSON("$.missing", false); err == nil {
+		t.Error("expected an error for a path not in the JSON")
+	}
+
+	// show last prints the result unfolded
+	output, err = captureStdout(func() error { return r.showLast("") })
+	if err != nil || strings.Contains(output, "expand") {
+		t.Errorf("show last should not fold:\n%s", output)
+	}
+}
@@ -0,0 +1,408 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+)
+
+// pagerTabWidth is the distance of tab stops when wrapping output into rows
+const pagerTabWidth = 8
+
+// ansiSequence matches the color and cursor sequences in rendered output
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// pagerKey is the context key of whether a command's output may be paged
+type pagerKey struct{}
+
+// withPager returns a context whose command output is paged if it does not
+// fit on the screen, or, if enabled is false, never paged. Only commands the
+// user waits for are paged; results of background calls are not.
+func withPager(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, pagerKey{}, enabled)
+}
+
+// pagerFromContext returns whether the output of ctx's command may be paged
+func pagerFromContext(ctx context.Context) bool {
+	enabled, _ := ctx.Value(pagerKey{}).(bool)
+	return enabled
+}
+
+// canPage returns whether the output of ctx's command goes through the
+// pager: not in scripts, with --no-pager or when stdout is not a terminal
+func (r *REPL) canPage(ctx context.Context) bool {
+	if !pagerFromContext(ctx) || r.script != nil || r.displayOptions().NoPager {
+		return false
+	}
+	return readline.IsTerminal(int(os.Stdin.Fd())) && readline.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// pageOutput runs fn, a command printing to stdout, and shows what it
+// printed through the pager if it does not fit on the screen
+func (r *REPL) pageOutput(ctx context.Context, fn func() error) error {
+	if !r.canPage(ctx) {
+		return fn()
+	}
+	var buf bytes.Buffer
+	err := redirectStdout(&buf, fn)
+	r.page(buf.Bytes())
+	return err
+}
+
+// page writes output to stdout, through $PAGER or the internal pager if it
+// is taller than the terminal. Output with inline images is not paged, as
+// pagers cannot show them.
+func (r *REPL) page(output []byte) {
+	width, height, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 2 || visibleLen(output) != len(output) {
+		_, _ = os.Stdout.Write(output)
+		return
+	}
+	rows := wrapRows(string(output), width)
+	if len(rows) < height {
+		_, _ = os.Stdout.Write(output)
+		return
+	}
+
+	if command := strings.Fields(os.Getenv("PAGER")); len(command) > 0 {
+		err := runExternalPager(command, output)
+		if err == nil {
+			return
+		}
+		r.logger.Warning("$PAGER failed, using the internal pager: %v", err)
+	}
+	if err := runInternalPager(rows, height); err != nil {
+		r.logger.Warning("Pager failed: %v", err)
+		_, _ = os.Stdout.Write(output)
+	}
+}
+
+// runExternalPager pipes output through the user's pager. less is told to
+// keep colors and the output on the screen, unless $LESS says otherwise.
+func runExternalPager(command []string, output []byte) error {
+	cmd := exec.Command(command[0], command[1:]...) //nolint:gosec // G204: the user's $PAGER
+	cmd.Stdin = bytes.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	return cmd.Run()
+}
+
+// runInternalPager shows rows on the alternate screen until the user quits,
+// then leaves the last screen they saw in the terminal
+func runInternalPager(rows []string, height int) error {
+	fd := int(os.Stdin.Fd())
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	p := &pager{rows: rows, height: height - 1}
+	_, _ = io.WriteString(os.Stdout, "\033[?1049h")
+	err = p.run(bufio.NewReader(os.Stdin), os.Stdout)
+	_, _ = io.WriteString(os.Stdout, "\033[?1049l")
+	_ = readline.Restore(fd, state)
+
+	for _, row := range p.visible() {
+		_, _ = fmt.Fprintln(os.Stdout, row)
+	}
+	_, _ = io.WriteString(os.Stdout, colorReset)
+	return err
+}
+
+// pager is the internal pager, navigated with the keys of less
+type pager struct {
+	rows []string
+	// height is the number of rows shown above the status line
+	height int
+	top    int
+	// query is the latest search and match the row it was last found in;
+	// message replaces the position in the status line until the next key
+	query   string
+	match   int
+	message string
+}
+
+// pagerHelp lists the keys in the status line
+const pagerHelp = "space/b page, j/k line, g/G top/end, /? search, n/N next/previous, q quit"
+
+// run handles keys until the user quits or the input ends
+func (p *pager) run(in *bufio.Reader, out io.Writer) error {
+	for {
+		p.render(out)
+		key, err := readPagerKey(in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		p.message = ""
+
+		switch key {
+		case "q", "Q", "ctrl-c":
+			return nil
+		case "/", "?":
+			query, ok := p.readQuery(in, out, key)
+			if !ok {
+				continue
+			}
+			if query != "" {
+				p.query = query
+			}
+			p.search(key == "/")
+		default:
+			p.handleKey(key)
+		}
+	}
+}
+
+// handleKey moves through the rows
+func (p *pager) handleKey(key string) {
+	switch key {
+	case "j", "down", "enter", "ctrl-n", "e":
+		p.scroll(1)
+	case "k", "up", "ctrl-p", "y":
+		p.scroll(-1)
+	case "space", "f", "pgdn", "ctrl-f", "ctrl-v":
+		p.scroll(p.height)
+	case "b", "pgup", "ctrl-b":
+		p.scroll(-p.height)
+	case "d", "ctrl-d":
+		p.scroll(p.height / 2)
+	case "u", "ctrl-u":
+		p.scroll(-p.height / 2)
+	case "g", "<", "home":
+		p.top = 0
+	case "G", ">", "end":
+		p.top = p.lastTop()
+	case "n":
+		p.search(true)
+	case "N":
+		p.search(false)
+	case "h":
+		p.message = pagerHelp
+	}
+}
+
+// scroll moves the view by n rows, staying within the output
+func (p *pager) scroll(n int) {
+	p.top = min(max(p.top+n, 0), p.lastTop())
+}
+
+// lastTop is the top row of the last screen
+func (p *pager) lastTop() int {
+	return max(len(p.rows)-p.height, 0)
+}
+
+// search moves to the next row matching the query, forward or backward of
+// the top row. The query ignores case unless it has upper case letters.
+func (p *pager) search(forward bool) {
+	if p.query == "" {
+		p.message = "No previous search"
+		return
+	}
+	query := p.query
+	fold := strings.ToLower(query) == query
+	if fold {
+		query = strings.ToLower(query)
+	}
+	matches := func(row string) bool {
+		plain := ansiSequence.ReplaceAllString(row, "")
+		if fold {
+			plain = strings.ToLower(plain)
+		}
+		return strings.Contains(plain, query)
+	}
+
+	step := 1
+	if !forward {
+		step = -1
+	}
+	// On the last screen a match is not the top row; continue after it
+	from := p.top
+	if p.match > p.top && p.match < p.top+p.height {
+		from = p.match
+	}
+	for i := from + step; i >= 0 && i < len(p.rows); i += step {
+		if matches(p.rows[i]) {
+			p.top = min(i, p.lastTop())
+			p.match = i
+			return
+		}
+	}
+	p.message = fmt.Sprintf("Pattern not found: %s", p.query)
+}
+
+// readQuery reads a search query after / or ? in the status line; ok is
+// false if the user cancelled it with Escape or Ctrl+C
+func (p *pager) readQuery(in *bufio.Reader, out io.Writer, prompt string) (string, bool) {
+	var query []rune
+	for {
+		_, _ = fmt.Fprintf(out, "\033[%d;1H\033[K%s%s", p.height+1, prompt, string(query))
+		key, err := readPagerKey(in)
+		if err != nil {
+			return "", false
+		}
+		switch {
+		case key == "enter":
+			return string(query), true
+		case key == "esc", key == "ctrl-c":
+			return "", false
+		case key == "backspace":
+			if len(query) == 0 {
+				return "", false
+			}
+			query = query[:len(query)-1]
+		case key == "space":
+			query = append(query, ' ')
+		case utf8.RuneCountInString(key) == 1:
+			query = append(query, []rune(key)...)
+		}
+	}
+}
+
+// visible returns the rows on the screen
+func (p *pager) visible() []string {
+	return p.rows[p.top:min(p.top+p.height, len(p.rows))]
+}
+
+// render draws the visible rows and the status line
+func (p *pager) render(out io.Writer) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	for _, row := range p.visible() {
+		b.WriteString(row)
+		b.WriteString(colorReset + "\r\n")
+	}
+	for range p.height - len(p.visible()) {
+		b.WriteString("~\r\n")
+	}
+
+	status := p.message
+	if status == "" {
+		last := min(p.top+p.height, len(p.rows))
+		status = fmt.Sprintf("rows %d-%d of %d (%d%%)", p.top+1, last, len(p.rows), last*100/len(p.rows))
+		if last == len(p.rows) {
+			status += " (END)"
+		}
+		status += " - h for help, q to quit"
+	}
+	b.WriteString("\033[7m" + status + colorReset)
+	_, _ = io.WriteString(out, b.String())
+}
+
+// readPagerKey reads a key press: a character or the name of a special key
+func readPagerKey(in *bufio.Reader) (string, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case '\r', '\n':
+		return "enter", nil
+	case ' ':
+		return "space", nil
+	case 0x7f, 0x08:
+		return "backspace", nil
+	case 0x1b:
+		return readEscapeKey(in)
+	}
+	if r < 0x20 {
+		// Ctrl+A is 1, Ctrl+B 2 and so on
+		return "ctrl-" + string(rune('a'+r-1)), nil
+	}
+	return string(r), nil
+}
+
+// readEscapeKey reads the rest of the sequence of an arrow, page or
+// home/end key. A lone Escape is not followed by more input.
+func readEscapeKey(in *bufio.Reader) (string, error) {
+	if in.Buffered() == 0 {
+		return "esc", nil
+	}
+	introducer, err := in.ReadByte()
+	if err != nil || (introducer != '[' && introducer != 'O') {
+		return "esc", err
+	}
+	var params []byte
+	for {
+		c, err := in.ReadByte()
+		if err != nil {
+			return "esc", err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			switch string(params) + string(c) {
+			case "A":
+				return "up", nil
+			case "B":
+				return "down", nil
+			case "H", "1~", "7~":
+				return "home", nil
+			case "F", "4~", "8~":
+				return "end", nil
+			case "5~":
+				return "pgup", nil
+			case "6~":
+				return "pgdn", nil
+			}
+			return "", nil
+		}
+		params = append(params, c)
+	}
+}
+
+// wrapRows splits output into the rows it takes on a terminal width columns
+// wide, keeping color sequences with the text they color
+func wrapRows(output string, width int) []string {
+	if width < 1 {
+		width = 80
+	}
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	var rows []string
+	for _, line := range lines {
+		var row strings.Builder
+		column := 0
+		for i := 0; i < len(line); {
+			if loc := ansiSequence.FindStringIndex(line[i:]); loc != nil && loc[0] == 0 {
+				row.WriteString(line[i : i+loc[1]])
+				i += loc[1]
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(line[i:])
+			i += size
+			text := string(r)
+			advance := 1
+			if r == '\t' {
+				advance = pagerTabWidth - column%pagerTabWidth
+				text = strings.Repeat(" ", advance)
+			}
+			if r == '\r' {
+				continue
+			}
+			if column+advance > width {
+				rows = append(rows, row.String())
+				row.Reset()
+				column = 0
+				if r == '\t' {
+					continue
+				}
+			}
+			row.WriteString(text)
+			column += advance
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
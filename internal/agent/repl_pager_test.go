@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// pagerRows returns n rows reading "row 1", "row 2" and so on
+func pagerRows(n int) []string {
+	rows := make([]string, n)
+	for i := range rows {
+		rows[i] = fmt.Sprintf("row %d", i+1)
+	}
+	return rows
+}
+
+func TestPagerNavigation(t *testing.T) {
+	tests := []struct {
+		keys    string
+		wantTop int
+	}{
+		{keys: "q", wantTop: 0},
+		{keys: "jjjq", wantTop: 3},
+		{keys: "jjkq", wantTop: 1},
+		{keys: "\x1b[B\x1b[Bq", wantTop: 2},
+		{keys: " q", wantTop: 10},
+		{keys: "  bq", wantTop: 10},
+		{keys: "dq", wantTop: 5},
+		{keys: "Gq", wantTop: 90},
+		{keys: "G\x1b[Aq", wantTop: 89},
+		{keys: "G\x1b[5~q", wantTop: 80},
+		{keys: "Ggq", wantTop: 0},
+		{keys: "kkq", wantTop: 0},
+		{keys: "          q", wantTop: 90},
+		{keys: "jj\x03", wantTop: 2},
+	}
+	for _, tt := range tests {
+		p := &pager{rows: pagerRows(100), height: 10}
+		if err := p.run(bufio.NewReader(strings.NewReader(tt.keys)), io.Discard); err != nil {
+			t.Fatalf("run(%q): %v", tt.keys, err)
+		}
+		if p.top != tt.wantTop {
+			t.Errorf("after %q top = %d, want %d", tt.keys, p.top, tt.wantTop)
+		}
+	}
+}
+
+func TestPagerSearch(t *testing.T) {
+	rows := pagerRows(100)
+	rows[40] = "\x1b[32m\"Needle\"\x1b[0m: 1"
+	rows[60] = "a needle in row 61"
+	rows[95] = "needle near the end"
+	rows[98] = "last needle"
+
+	tests := []struct {
+		keys        string
+		wantTop     int
+		wantMessage string
+	}{
+		// Lower case queries ignore case and skip color sequences
+		{keys: "/needle\r", wantTop: 40},
+		{keys: "/needle\rn", wantTop: 60},
+		{keys: "/needle\rnN", wantTop: 40},
+		{keys: "/Needle\rn", wantTop: 40, wantMessage: "Pattern not found: Needle"},
+		// Matches on the last screen do not move it, but n continues after them
+		{keys: "/needle\rnn", wantTop: 90},
+		{keys: "/needle\rnnn", wantTop: 90},
+		{keys: "/needle\rnnnn", wantTop: 90, wantMessage: "Pattern not found: needle"},
+		{keys: "G?needle\r", wantTop: 60},
+		// An empty query repeats the previous one
+		{keys: "/needle\r/\r", wantTop: 60},
+		{keys: "/needle\x1b", wantTop: 0},
+		{keys: "/neex\x7fdle\r", wantTop: 40},
+		{keys: "/missing\r", wantTop: 0, wantMessage: "Pattern not found: missing"},
+		{keys: "n", wantTop: 0, wantMessage: "No previous search"},
+	}
+	for _, tt := range tests {
+		p := &pager{rows: rows, height: 10}
+		// Without a closing q the message of the last key is kept
+		if err := p.run(bufio.NewReader(strings.NewReader(tt.keys)), io.Discard); err != nil {
+			t.Fatalf("run(%q): %v", tt.keys, err)
+		}
+		if p.top != tt.wantTop {
+			t.Errorf("after %q top = %d, want %d", tt.keys, p.top, tt.wantTop)
+		}
+		if p.message != tt.wantMessage {
+			t.Errorf("after %q message = %q, want %q", tt.keys, p.message, tt.wantMessage)
+		}
+	}
+}
+
+func TestPagerRender(t *testing.T) {
+	p := &pager{rows: pagerRows(3), height: 5}
+	var out strings.Builder
+	p.render(&out)
+	screen := out.String()
+	for _, want := range []string{"row 1" + colorReset + "\r\n", "row 3", "~\r\n", "rows 1-3 of 3 (100%) (END)"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen lacks %q:\n%q", want, screen)
+		}
+	}
+
+	p = &pager{rows: pagerRows(100), height: 10, top: 20}
+	out.Reset()
+	p.render(&out)
+	if !strings.Contains(out.String(), "rows 21-30 of 100 (30%)") || strings.Contains(out.String(), "row 31") {
+		t.Errorf("unexpected screen:\n%q", out.String())
+	}
+}
+
+func TestWrapRows(t *testing.T) {
+	tests := []struct {
+		output string
+		width  int
+		want   []string
+	}{
+		{output: "short\nlines\n", width: 10, want: []string{"short", "lines"}},
+		{output: "abcdefghij", width: 4, want: []string{"abcd", "efgh", "ij"}},
+		{output: "\x1b[32mabcdef\x1b[0m", width: 4, want: []string{"\x1b[32mabcd", "ef\x1b[0m"}},
+		{output: "a\tb", width: 20, want: []string{"a       b"}},
+		{output: "äöüß", width: 2, want: []string{"äö", "üß"}},
+		{output: "\n\nx", width: 5, want: []string{"", "", "x"}},
+	}
+	for _, tt := range tests {
+		got := wrapRows(tt.output, tt.width)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrapRows(%q, %d) = %q, want %q", tt.output, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestPagerContext(t *testing.T) {
+	ctx := context.Background()
+	if pagerFromContext(ctx) {
+		t.Error("commands outside the REPL loop must not be paged")
+	}
+	if !pagerFromContext(withPager(ctx, true)) {
+		t.Error("expected paging for interactive commands")
+	}
+	if pagerFromContext(withPager(withPager(ctx, true), false)) {
+		t.Error("background results must not be paged")
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of cells of a tool progress bar
+const progressBarWidth = 30
+
+// toolProgressDisplay renders the progress of a tool call the REPL waits for.
+// Updates with a known total redraw a progress bar in place; other updates
+// carry partial output and are printed as lines.
+type toolProgressDisplay struct {
+	mu  sync.Mutex
+	out io.Writer
+	// barShown is set while the current line holds the progress bar
+	barShown bool
+}
+
+// newToolProgressDisplay creates a display writing to stdout
+func newToolProgressDisplay() *toolProgressDisplay {
+	return &toolProgressDisplay{out: os.Stdout}
+}
+
+// update implements ProgressHandler
+func (d *toolProgressDisplay) update(update ToolProgress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if update.Total > 0 {
+		_, _ = fmt.Fprintf(d.out, "\r\033[K  %s", formatToolProgress(update))
+		d.barShown = true
+		return
+	}
+	if d.barShown {
+		_, _ = fmt.Fprint(d.out, "\r\033[K")
+		d.barShown = false
+	}
+	_, _ = fmt.Fprintf(d.out, "  … %s\n", formatToolProgress(update))
+}
+
+// finish ends the progress bar line so the result starts on a fresh line
+func (d *toolProgressDisplay) finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.barShown {
+		_, _ = fmt.Fprintln(d.out)
+		d.barShown = false
+	}
+}
+
+// formatToolProgress renders an update as a bar with percentage if the total
+// is known, otherwise as its message or raw progress value
+func formatToolProgress(update ToolProgress) string {
+	if update.Total <= 0 {
+		if update.Message != "" {
+			return update.Message
+		}
+		return fmt.Sprintf("progress %g", update.Progress)
+	}
+
+	ratio := min(max(update.Progress/update.Total, 0), 1)
+	filled := int(ratio * progressBarWidth)
+	bar := fmt.Sprintf("[%s%s] %3.0f%% (%g/%g)",
+		strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		ratio*100, update.Progress, update.Total)
+	if update.Message != "" {
+		bar += " " + update.Message
+	}
+	return bar
+}
+
+// printAbovePrompt prints output produced while the user may be typing,
+// then redraws the prompt
+func (r *REPL) printAbovePrompt(print func()) {
+	if r.rl != nil {
+		_, _ = r.rl.Stdout().Write([]byte("\r\033[K"))
+	}
+	print()
+	if r.rl != nil {
+		r.rl.Refresh()
+	}
+}
+
+// showInFlightRequests lists the tool calls awaiting their response
+func (r *REPL) showInFlightRequests() error {
+	requests := r.client.InFlightRequests()
+	if len(requests) == 0 {
+		fmt.Println("No requests in flight.")
+		return nil
+	}
+
+	fmt.Printf("In-flight requests (%d):\n", len(requests))
+	for _, req := range requests {
+		id := req.ID
+		if id == "" {
+			id = "-"
+		}
+		line := fmt.Sprintf("  %-6s %s %-24s %8s", id, req.Method, req.Target, time.Since(req.Started).Round(100*time.Millisecond))
+		if req.Progress != nil {
+			line += "  " + formatToolProgress(*req.Progress)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// handleCancel sends notifications/cancelled for an in-flight request
+func (r *REPL) handleCancel(ctx context.Context, id, reason string) error {
+	if reason == "" {
+		reason = "cancelled by user"
+	}
+	if err := r.client.CancelRequest(ctx, id, reason); err != nil {
+		return err
+	}
+	fmt.Printf("Cancelled request %s\n", id)
+	return nil
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// outputFileKey is the context key of the file a command's result is
+// redirected to
+type outputFileKey struct{}
+
+// withOutputFile returns a context whose result is saved to path instead of
+// being printed
+func withOutputFile(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, outputFileKey{}, path)
+}
+
+// outputFileFromContext returns the file the result of ctx's command is
+// redirected to, if any
+func outputFileFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(outputFileKey{}).(string)
+	return path
+}
+
+// parseRedirect strips a trailing '> file' (or '>file') from a command,
+// returning the remaining words and the file. A '>' inside a JSON string
+// argument is not a redirection.
+func parseRedirect(parts []string) ([]string, string, error) {
+	n := len(parts)
+	var rest []string
+	var path string
+	switch {
+	case n > 2 && parts[n-2] == ">":
+		rest, path = parts[:n-2], parts[n-1]
+	case n > 1 && parts[n-1] == ">":
+		return nil, "", errors.New("missing file name after >")
+	case n > 2 && strings.HasPrefix(parts[n-1], ">"):
+		rest, path = parts[:n-1], strings.TrimPrefix(parts[n-1], ">")
+	default:
+		return parts, "", nil
+	}
+	if insideJSONString(strings.Join(rest, " ")) {
+		return parts, "", nil
+	}
+	return rest, path, nil
+}
+
+// insideJSONString reports whether s ends within an unterminated JSON string
+func insideJSONString(s string) bool {
+	inside := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inside:
+			i++
+		case s[i] == '"':
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// toolResult is a tool call result for display and saving. A result with a
+// single content item is saved as that item: text as is, images and audio
+// decoded from base64, embedded resources as their contents. Other results
+// are saved as JSON.
+func toolResult(result *mcp.CallToolResult) shownResult {
+	return shownResult{
+		render: func(w io.Writer, opts DisplayOptions) {
+			displayToolResult(w, result, opts)
+		},
+		save: func(w io.Writer) error {
+			if len(result.Content) == 1 && !result.IsError {
+				return writeContent(w, result.Content[0])
+			}
+			_, err := fmt.Fprintln(w, PrettyJSON(result))
+			return err
+		},
+	}
+}
+
+// writeContent writes the decoded data of a content item
+func writeContent(w io.Writer, content mcp.Content) error {
+	if textContent, ok := mcp.AsTextContent(content); ok {
+		_, err := io.WriteString(w, textContent.Text)
+		return err
+	}
+	if imageContent, ok := mcp.AsImageContent(content); ok {
+		return writeBase64(w, imageContent.Data)
+	}
+	if audioContent, ok := mcp.AsAudioContent(content); ok {
+		return writeBase64(w, audioContent.Data)
+	}
+	if resource, ok := mcp.AsEmbeddedResource(content); ok {
+		_, err := writeResourceContents(w, []mcp.ResourceContents{resource.Resource})
+		return err
+	}
+	_, err := fmt.Fprintln(w, PrettyJSON(content))
+	return err
+}
+
+// writeBase64 writes the data a base64 string decodes to
+func writeBase64(w io.Writer, data string) error {
+	_, err := io.Copy(w, base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+	return err
+}
+
+// resourceResult is a resource read result for display and saving; it is
+// saved as its decoded contents
+func resourceResult(result *mcp.ReadResourceResult, mimeType string) shownResult {
+	return shownResult{
+		render: func(w io.Writer, opts DisplayOptions) {
+			displayResourceContents(w, result, mimeType, opts)
+		},
+		save: func(w io.Writer) error {
+			_, err := writeResourceContents(w, result.Contents)
+			return err
+		},
+	}
+}
+
+// savedResourceResult is a resource that was streamed to the file at path
+// rather than read into memory; it is saved by copying that file
+func savedResourceResult(path string, size int64) shownResult {
+	return shownResult{
+		render: func(w io.Writer, opts DisplayOptions) {
+			_, _ = fmt.Fprintf(w, "Resource of %d bytes saved to %s\n", size, path)
+		},
+		save: func(w io.Writer) error {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			_, err = io.Copy(w, f)
+			return err
+		},
+	}
+}
+
+// promptResult is a prompt result for display, as JSON if raw is set, and
+// saving; it is saved as JSON
+func promptResult(result *mcp.GetPromptResult, raw bool) shownResult {
+	return shownResult{
+		render: func(w io.Writer, opts DisplayOptions) {
+			displayPromptResult(w, result, raw, opts)
+		},
+		save: func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, PrettyJSON(result))
+			return err
+		},
+	}
+}
+
+// saveResult writes a result to path. A partially written file is removed
+// on failure.
+func saveResult(result shownResult, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	cw := &countingWriter{w: f}
+	err = result.save(cw)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Saved %d bytes to %s\n", cw.written, path)
+	return nil
+}
+
+// saveLast writes the last result to path
+func (r *REPL) saveLast(path string) error {
+	r.display.mu.Lock()
+	last := r.display.last
+	r.display.mu.Unlock()
+	if last.save == nil {
+		return fmt.Errorf("no result to save yet")
+	}
+	return saveResult(last, path)
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseRedirect(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantRest string
+		wantPath string
+	}{
+		{`call echo {"a":1} > out.json`, `call echo {"a":1}`, "out.json"},
+		{`call echo {"a":1} >out.json`, `call echo {"a":1}`, "out.json"},
+		{`call echo > out.json`, `call echo`, "out.json"},
+		{`call echo {"a":1}`, `call echo {"a":1}`, ""},
+		{`call echo {"q": "a > b"}`, `call echo {"q": "a > b"}`, ""},
+		{`call echo {"q": "a \" > b"}`, `call echo {"q": "a \" > b"}`, ""},
+		{`get >file`, `get >file`, ""},
+	}
+	for _, tt := range tests {
+		rest, path, err := parseRedirect(strings.Fields(tt.input))
+		if err != nil {
+			t.Errorf("parseRedirect(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(rest, strings.Fields(tt.wantRest)) || path != tt.wantPath {
+			t.Errorf("parseRedirect(%q) = %q, %q; want %q, %q", tt.input, rest, path, tt.wantRest, tt.wantPath)
+		}
+	}
+	if _, _, err := parseRedirect(strings.Fields(`call echo {} >`)); err == nil {
+		t.Error("expected an error for a missing file name")
+	}
+}
+
+func TestSaveToolResult(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G', 0, 1, 2}
+	tests := []struct {
+		name   string
+		result *mcp.CallToolResult
+		want   string
+	}{
+		{
+			name:   "text",
+			result: &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(`{"a":1}`)}},
+			want:   `{"a":1}`,
+		},
+		{
+			name: "image",
+			result: &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewImageContent(base64.StdEncoding.EncodeToString(png), "image/png"),
+			}},
+			want: string(png),
+		},
+		{
+			name: "embedded blob",
+			result: &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewEmbeddedResource(mcp.BlobResourceContents{URI: "file:///x", Blob: base64.StdEncoding.EncodeToString(png)}),
+			}},
+			want: string(png),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out")
+			if err := saveResult(toolResult(tt.result), path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("saved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveToolResultWithSeveralContentsAsJSON(t *testing.T) {
+	result := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent("one"), mcp.NewTextContent("two")}}
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := saveResult(toolResult(result), path); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !strings.Contains(string(got), `"text": "one"`) || !strings.Contains(string(got), `"text": "two"`) {
+		t.Errorf("expected the result as JSON, got %s", got)
+	}
+}
+
+func TestShowResultRedirectsAndSavesLast(t *testing.T) {
+	r := &REPL{}
+	if err := r.saveLast(filepath.Join(t.TempDir(), "none")); err == nil {
+		t.Error("expected an error before any result was shown")
+	}
+
+	dir := t.TempDir()
+	result := &mcp.ReadResourceResult{Contents: []mcp.ResourceContents{
+		mcp.BlobResourceContents{URI: "file:///x", Blob: base64.StdEncoding.EncodeToString([]byte("binary\x00data"))},
+	}}
+	redirected := filepath.Join(dir, "redirected")
+	if err := r.showResult(withOutputFile(context.Background(), redirected), resourceResult(result, "")); err != nil {
+		t.Fatal(err)
+	}
+	last := filepath.Join(dir, "last")
+	if err := r.saveLast(last); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{redirected, last} {
+		if got, _ := os.ReadFile(path); string(got) != "binary\x00data" {
+			t.Errorf("%s: got %q, want the decoded blob", filepath.Base(path), got)
+		}
+	}
+}
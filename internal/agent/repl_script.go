@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxScriptDepth bounds nested 'source' commands so a script cannot source
+// itself forever
+const maxScriptDepth = 8
+
+// scriptVarPattern matches ${name} references in script lines
+var scriptVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// scriptState is the state shared by a script and the scripts it sources
+type scriptState struct {
+	vars  map[string]string
+	depth int
+	// output and err are the captured output and error of the last command,
+	// which assertions check
+	output string
+	err    error
+}
+
+// scriptLine is a non-empty, non-comment line of a script
+type scriptLine struct {
+	number int
+	text   string
+}
+
+// RunScript executes the REPL commands in the file at path without user
+// interaction and stops at the first failing command or assertion. It is the
+// entry point for --script; interactive sessions use the 'source' command.
+func (r *REPL) RunScript(ctx context.Context, path string) error {
+	r.client.onListRefreshed = r.refreshCompleter
+	r.wg.Add(1)
+	go r.notificationListener(ctx, r.client, nil)
+	defer func() {
+		close(r.stopChan)
+		r.wg.Wait()
+		r.connections.CloseAll()
+	}()
+
+	if err := r.runScript(ctx, path); err != nil && !errors.Is(err, errExit) {
+		return err
+	}
+	return nil
+}
+
+// runScript executes a script file, sharing variables with the enclosing
+// script if it is sourced from one
+func (r *REPL) runScript(ctx context.Context, path string) error {
+	if r.script == nil {
+		r.script = &scriptState{vars: make(map[string]string)}
+		defer func() { r.script = nil }()
+	}
+	if r.script.depth >= maxScriptDepth {
+		return fmt.Errorf("scripts nested more than %d levels deep", maxScriptDepth)
+	}
+	r.script.depth++
+	defer func() { r.script.depth-- }()
+
+	lines, err := readScript(path)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.runScriptLine(ctx, line.text); err != nil {
+			if errors.Is(err, errExit) {
+				return err
+			}
+			// A failure is expected if the next line asserts it
+			if r.script.err != nil && i+1 < len(lines) && isErrorAssertion(lines[i+1].text) {
+				continue
+			}
+			return fmt.Errorf("%s:%d: %w", path, line.number, err)
+		}
+	}
+	return nil
+}
+
+// readScript returns the commands of a script, skipping blank lines and
+// '#' comments
+func readScript(path string) ([]scriptLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []scriptLine
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		lines = append(lines, scriptLine{number: number, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return lines, nil
+}
+
+// runScriptLine expands variables in a line and executes it as a 'set' or
+// 'assert' directive or a REPL command
+func (r *REPL) runScriptLine(ctx context.Context, line string) error {
+	line, err := r.expandScriptVars(line)
+	if err != nil {
+		return err
+	}
+
+	directive, rest, _ := strings.Cut(line, " ")
+	switch strings.ToLower(directive) {
+	case "set":
+		return r.setScriptVar(strings.TrimSpace(rest))
+	case "assert":
+		// 'assert <file>' is the REPL command running an expectation file
+		if isScriptAssertion(line) {
+			return r.script.assert(strings.TrimSpace(rest))
+		}
+	}
+
+	fmt.Printf("> %s\n", line)
+	output, err := captureStdout(func() error {
+		return r.executeCommand(ctx, line)
+	})
+	r.script.output, r.script.err = output, err
+	if err != nil && !errors.Is(err, errExit) {
+		r.logger.Error("Error: %v", err)
+	}
+	return err
+}
+
+// expandScriptVars replaces ${name} with the script variable of that name,
+// falling back to the environment
+func (r *REPL) expandScriptVars(line string) (string, error) {
+	var missing []string
+	expanded := scriptVarPattern.ReplaceAllStringFunc(line, func(ref string) string {
+		name := scriptVarPattern.FindStringSubmatch(ref)[1]
+		if value, ok := r.script.vars[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		missing = append(missing, name)
+		return ref
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// setScriptVar handles 'set <name> <value>'; a quoted value is unquoted
+func (r *REPL) setScriptVar(args string) error {
+	name, value, _ := strings.Cut(args, " ")
+	if !scriptVarPattern.MatchString("${" + name + "}") {
+		return fmt.Errorf("usage: set <name> <value>")
+	}
+	value, err := scriptArgument(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+	r.script.vars[name] = value
+	return nil
+}
+
+// assert checks the output or outcome of the previous command:
+//
+//	assert ok                    the command succeeded
+//	assert error ["text"]        the command failed (with an error containing text)
+//	assert contains "text"       the output contains text
+//	assert not-contains "text"   the output does not contain text
+//	assert matches "regexp"      the output matches the regular expression
+func (s *scriptState) assert(args string) error {
+	kind, rest, _ := strings.Cut(args, " ")
+	expected, err := scriptArgument(strings.TrimSpace(rest))
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(kind) {
+	case "ok":
+		if s.err != nil {
+			return fmt.Errorf("assertion failed: expected success, got error: %v", s.err)
+		}
+	case "error":
+		if s.err == nil {
+			return errors.New("assertion failed: expected the command to fail")
+		}
+		if !strings.Contains(s.err.Error(), expected) {
+			return fmt.Errorf("assertion failed: error %q does not contain %q", s.err.Error(), expected)
+		}
+		// The failure was expected; later assertions must not see it again
+		s.err = nil
+		return nil
+	case "contains":
+		if !strings.Contains(s.output, expected) {
+			return fmt.Errorf("assertion failed: output does not contain %q", expected)
+		}
+	case "not-contains":
+		if strings.Contains(s.output, expected) {
+			return fmt.Errorf("assertion failed: output contains %q", expected)
+		}
+	case "matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		if !re.MatchString(s.output) {
+			return fmt.Errorf("assertion failed: output does not match %q", expected)
+		}
+	default:
+		return fmt.Errorf("unknown assertion: %s. Use 'ok', 'error', 'contains', 'not-contains' or 'matches'", kind)
+	}
+	return nil
+}
+
+// isErrorAssertion reports whether a script line is 'assert error'
+func isErrorAssertion(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) >= 2 && strings.EqualFold(fields[0], "assert") && strings.EqualFold(fields[1], "error")
+}
+
+// isScriptAssertion reports whether an 'assert' line checks the previous
+// command, as opposed to running an expectation file
+func isScriptAssertion(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return true
+	}
+	switch strings.ToLower(fields[1]) {
+	case "ok", "error", "contains", "not-contains", "matches":
+		return true
+	}
+	return false
+}
+
+// scriptArgument unquotes a double-quoted argument; other arguments are
+// returned as written
+func scriptArgument(arg string) (string, error) {
+	if !strings.HasPrefix(arg, `"`) {
+		return arg, nil
+	}
+	unquoted, err := strconv.Unquote(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s: %w", arg, err)
+	}
+	return unquoted, nil
+}
+
+// captureStdout runs fn while copying everything it prints to stdout into the
+// returned string. The output is still shown as it is printed.
+func captureStdout(fn func() error) (string, error) {
+	var buf bytes.Buffer
+	err := redirectStdout(io.MultiWriter(os.Stdout, &buf), fn)
+	return buf.String(), err
+}
+
+// redirectStdout runs fn while sending everything it prints to stdout to w
+// instead
+func redirectStdout(w io.Writer, fn func() error) error {
+	original := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fn()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(w, reader)
+		close(done)
+	}()
+
+	os.Stdout = writer
+	err = fn()
+	os.Stdout = original
+
+	_ = writer.Close()
+	<-done
+	_ = reader.Close()
+	return err
+}
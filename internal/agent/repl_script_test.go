@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mcp")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestRunScript(t *testing.T) {
+	t.Setenv("MCP_SCRIPT_TEST_LEVEL", "info")
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{
+			name: "variables and output assertions",
+			script: `# smoke test
+set name "test-server"
+server
+assert ok
+assert contains "Server: ${name}"
+assert not-contains "Instructions: Use"
+assert matches "Protocol Version: \\S+"
+`,
+		},
+		{
+			name: "expected failure",
+			script: `describe tool missing
+assert error "does not support tools"
+assert ok
+`,
+		},
+		{
+			name:   "environment variables",
+			script: "loglevel ${MCP_SCRIPT_TEST_LEVEL}\nassert error \"does not support logging\"\n",
+		},
+		{
+			name:    "failed assertion",
+			script:  "server\n\nassert contains \"nope\"\n",
+			wantErr: "test.mcp:3: assertion failed: output does not contain \"nope\"",
+		},
+		{
+			name:    "failed command",
+			script:  "bogus\nserver\n",
+			wantErr: "test.mcp:1: unknown command: bogus",
+		},
+		{
+			name:    "undefined variable",
+			script:  "get ${UNDEFINED_MCP_SCRIPT_VAR}\n",
+			wantErr: "undefined variable: UNDEFINED_MCP_SCRIPT_VAR",
+		},
+		{
+			name:    "assert runs an expectation file",
+			script:  "assert expectations.json\n",
+			wantErr: "test.mcp:1: server does not support tools capability",
+		},
+		{
+			name:   "exit stops the script",
+			script: "exit\nbogus\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newStubbedClient(t, &stubMCPClient{})
+			c.serverInfo.Name = "test-server"
+			c.serverInfo.ProtocolVersion = "2025-06-18"
+
+			err := NewREPL(c, c.logger).RunScript(context.Background(), writeScript(t, tt.script))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSourceNestingLimit(t *testing.T) {
+	c := newStubbedClient(t, &stubMCPClient{})
+	path := filepath.Join(t.TempDir(), "loop.mcp")
+	if err := os.WriteFile(path, []byte("source "+path+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	err := NewREPL(c, c.logger).RunScript(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "nested more than") {
+		t.Fatalf("expected nesting error, got %v", err)
+	}
+}
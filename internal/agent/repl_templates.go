@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// findTemplate finds a resource template by name or raw URI template
+func (r *REPL) findTemplate(name string) *mcp.ResourceTemplate {
+	r.client.mu.RLock()
+	defer r.client.mu.RUnlock()
+
+	for _, tmpl := range r.client.templateCache {
+		if tmpl.Name == name || (name != "" && templateURI(&tmpl) == name) {
+			return &tmpl
+		}
+	}
+	return nil
+}
+
+// listTemplates displays available resource templates
+func (r *REPL) listTemplates(ctx context.Context, page int) error {
+	r.client.mu.RLock()
+	templates := r.client.templateCache
+	r.client.mu.RUnlock()
+
+	more := false
+	if page > 0 {
+		var err error
+		if templates, more, err = r.client.ListResourceTemplatesPage(ctx, page); err != nil {
+			return fmt.Errorf("failed to list resource templates: %w", err)
+		}
+	}
+
+	if len(templates) == 0 {
+		fmt.Println("No resource templates available.")
+		return nil
+	}
+
+	fmt.Println(listHeading("resource templates", len(templates), page, more))
+	for i, tmpl := range templates {
+		desc := tmpl.Description
+		if desc == "" {
+			desc = tmpl.Name
+		}
+		uri := templateURI(&tmpl)
+		if uri == "" {
+			uri = "(no valid URI template)"
+		}
+		fmt.Printf("  %d. %-40s - %s\n", i+1, uri, desc)
+	}
+	return nil
+}
+
+// describeTemplate shows detailed information about a resource template
+func (r *REPL) describeTemplate(ctx context.Context, name string) error {
+	tmpl := r.findTemplate(name)
+	if tmpl == nil {
+		return fmt.Errorf("resource template not found: %s", name)
+	}
+
+	fmt.Printf("Resource Template: %s\n", tmpl.Name)
+	if uri := templateURI(tmpl); uri != "" {
+		fmt.Printf("URI Template: %s\n", uri)
+	} else {
+		fmt.Println("URI Template: (missing or invalid)")
+	}
+	if tmpl.Description != "" {
+		fmt.Printf("Description: %s\n", tmpl.Description)
+	}
+	if tmpl.MIMEType != "" {
+		fmt.Printf("MIME Type: %s\n", tmpl.MIMEType)
+	}
+	if vars := TemplateVariables(tmpl); len(vars) > 0 {
+		fmt.Printf("Variables: %s\n", strings.Join(vars, ", "))
+	}
+	return nil
+}
+
+// handleReadTemplate prompts for each variable of a resource template, expands
+// it and reads the resulting resource
+func (r *REPL) handleReadTemplate(ctx context.Context, name string) error {
+	if !r.client.ServerSupportsResources() {
+		return fmt.Errorf("server does not support resources capability")
+	}
+
+	tmpl := r.findTemplate(name)
+	if tmpl == nil {
+		return fmt.Errorf("resource template not found: %s", name)
+	}
+
+	uri, err := r.expandTemplate(ctx, tmpl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Retrieving resource: %s...\n", uri)
+	result, err := r.client.GetResource(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("resource retrieval failed: %w", err)
+	}
+
+	return r.showResult(ctx, resourceResult(result, tmpl.MIMEType))
+}
+
+// expandTemplate prompts for the variables of a resource template and
+// returns the expanded URI
+func (r *REPL) expandTemplate(ctx context.Context, tmpl *mcp.ResourceTemplate) (string, error) {
+	values, err := r.promptTemplateVariables(ctx, tmpl)
+	if err != nil {
+		return "", err
+	}
+	return ExpandResourceTemplate(tmpl, values)
+}
+
+// promptTemplateVariables asks for a value for each template variable, with
+// TAB completion backed by the server's completions capability
+func (r *REPL) promptTemplateVariables(ctx context.Context, tmpl *mcp.ResourceTemplate) (map[string]string, error) {
+	values := make(map[string]string)
+	vars := TemplateVariables(tmpl)
+	if len(vars) == 0 || r.rl == nil {
+		return values, nil
+	}
+
+	// Restore the main prompt and completer afterwards
+	defer func() {
+		r.rl.SetPrompt("MCP> ")
+		r.rl.Config.AutoComplete = r.createCompleter()
+	}()
+
+	for _, name := range vars {
+		r.rl.SetPrompt(fmt.Sprintf("  %s: ", name))
+		r.rl.Config.AutoComplete = &templateVarCompleter{
+			ctx:      ctx,
+			client:   r.client,
+			tmpl:     tmpl,
+			name:     name,
+			resolved: values,
+		}
+
+		line, err := r.rl.Readline()
+		if err != nil {
+			if errors.Is(err, readline.ErrInterrupt) || errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("template expansion cancelled")
+			}
+			return nil, err
+		}
+		if value := strings.TrimSpace(line); value != "" {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// templateVarCompleter completes a single template variable by asking the
+// server via completion/complete
+type templateVarCompleter struct {
+	ctx      context.Context
+	client   *Client
+	tmpl     *mcp.ResourceTemplate
+	name     string
+	resolved map[string]string
+}
+
+// Do implements readline.AutoCompleter
+func (t *templateVarCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	prefix := string(line[:pos])
+
+	candidates, err := t.client.CompleteTemplateArgument(t.ctx, t.tmpl, t.name, prefix, t.resolved)
+	if err != nil {
+		return nil, 0
+	}
+
+	var suggestions [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			suggestions = append(suggestions, []rune(candidate[len(prefix):]))
+		}
+	}
+	return suggestions, len([]rune(prefix))
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// traceListSize is the number of exchanges listed by trace without an id
+const traceListSize = 20
+
+// showTrace lists the latest requests, or dumps the request and response
+// with the given correlation or JSON-RPC id
+func (r *REPL) showTrace(id string) error {
+	if id == "" {
+		return r.listExchanges()
+	}
+
+	ex, ok := r.client.FindExchange(id)
+	if !ok {
+		return fmt.Errorf("no request with id %s (the latest %d are kept)", id, maxExchanges)
+	}
+	fmt.Printf("#%s %s (JSON-RPC id %s)\n", ex.ID, ex.Method, ex.RequestID)
+	fmt.Printf("Sent:     %s\n", ex.Sent.Format("2006-01-02 15:04:05.000"))
+	if ex.Done {
+		fmt.Printf("Elapsed:  %s\n", formatElapsed(ex.Elapsed))
+	} else {
+		fmt.Printf("Elapsed:  %s (awaiting response)\n", formatElapsed(time.Since(ex.Sent)))
+	}
+	fmt.Println("\nRequest:")
+	fmt.Println(r.traceJSON(ex.Request))
+	switch {
+	case ex.Err != "":
+		fmt.Printf("\nFailed: %s\n", r.logger.redact(ex.Err))
+	case ex.Done:
+		fmt.Println("\nResponse:")
+		fmt.Println(r.traceJSON(ex.Response))
+	}
+	return nil
+}
+
+// listExchanges prints the latest requests with their correlation IDs
+func (r *REPL) listExchanges() error {
+	exchanges := r.client.RecentExchanges(traceListSize)
+	if len(exchanges) == 0 {
+		fmt.Println("No requests sent yet.")
+		return nil
+	}
+
+	fmt.Printf("Latest requests (%d):\n", len(exchanges))
+	for _, ex := range exchanges {
+		status := formatElapsed(ex.Elapsed)
+		switch {
+		case ex.Err != "":
+			status = "failed"
+		case !ex.Done:
+			status = "pending"
+		}
+		fmt.Printf("  #%s  %-6s %-28s %8s  %s\n", ex.ID, ex.RequestID, ex.Method, status, ex.Sent.Format("15:04:05"))
+	}
+	fmt.Println("Use 'trace <id>' to show a request and its response.")
+	return nil
+}
+
+// traceJSON indents a recorded message and masks its credentials
+func (r *REPL) traceJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return r.logger.redact(string(raw))
+	}
+	return r.logger.redact(buf.String())
+}
@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxArgumentAttempts bounds how often an invalid argument value is asked
+// for again before the wizard gives up
+const maxArgumentAttempts = 3
+
+// errArgumentsDeclined is returned when the user does not confirm the
+// arguments built by the wizard
+var errArgumentsDeclined = errors.New("tool call cancelled")
+
+// argumentWizard builds tool arguments by asking for each property of the
+// input schema in turn
+type argumentWizard struct {
+	readLine lineReader
+	out      io.Writer
+}
+
+// run asks for the arguments of a tool, shows the resulting JSON and asks
+// for confirmation before returning it
+func (w *argumentWizard) run(ctx context.Context, toolName string, args []toolArgument) (map[string]any, error) {
+	_, _ = fmt.Fprintf(w.out, "Arguments for %s (empty input skips optional arguments, Ctrl+C aborts):\n", toolName)
+	values, err := w.askObject(ctx, "", args)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _ = fmt.Fprintf(w.out, "%s\n", PrettyJSON(values))
+	ok, err := w.confirm(ctx, fmt.Sprintf("Call %s with these arguments? [Y/n] ", toolName), true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errArgumentsDeclined
+	}
+	return values, nil
+}
+
+// askObject asks for each property of an object; path prefixes the names of
+// nested properties
+func (w *argumentWizard) askObject(ctx context.Context, path string, args []toolArgument) (map[string]any, error) {
+	values := make(map[string]any)
+	for _, arg := range args {
+		name := arg.Name
+		if path != "" {
+			name = path + "." + arg.Name
+		}
+		if arg.Description != "" {
+			_, _ = fmt.Fprintf(w.out, "  %s\n", arg.Description)
+		}
+
+		value, ok, err := w.askArgument(ctx, name, arg)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			values[arg.Name] = value
+		}
+	}
+	return values, nil
+}
+
+// askArgument asks for one value; ok is false when an optional argument is
+// left out
+func (w *argumentWizard) askArgument(ctx context.Context, name string, arg toolArgument) (value any, ok bool, err error) {
+	switch {
+	case arg.Type == "object" && len(arg.Properties) > 0:
+		if !arg.Required {
+			set, err := w.confirm(ctx, fmt.Sprintf("%s: set this object? [y/N] ", argumentLabel(name, arg)), false)
+			if err != nil || !set {
+				return nil, false, err
+			}
+		}
+		value, err := w.askObject(ctx, name, arg.Properties)
+		return value, err == nil, err
+	case arg.Type == "array" && arg.Items != nil && isScalarType(arg.Items.Type):
+		items, err := w.askScalarItems(ctx, name, *arg.Items)
+		if err != nil || (len(items) == 0 && !arg.Required) {
+			return nil, false, err
+		}
+		return items, true, nil
+	case arg.Type == "array" && arg.Items != nil && len(arg.Items.Properties) > 0:
+		items, err := w.askObjectItems(ctx, name, *arg.Items)
+		if err != nil || (len(items) == 0 && !arg.Required) {
+			return nil, false, err
+		}
+		return items, true, nil
+	}
+	return w.askValue(ctx, argumentLabel(name, arg), arg)
+}
+
+// askScalarItems reads array items one per prompt until an empty input
+func (w *argumentWizard) askScalarItems(ctx context.Context, name string, item toolArgument) ([]any, error) {
+	// An empty input always ends the list, even if items declare a default
+	item.Default, item.Required = nil, false
+	items := []any{}
+	for {
+		label := fmt.Sprintf("%s[%d] (%s, empty to finish)", name, len(items), argumentType(item))
+		value, ok, err := w.askValue(ctx, label, item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return items, nil
+		}
+		items = append(items, value)
+	}
+}
+
+// askObjectItems asks for the properties of array items for as long as the
+// user wants to add another one
+func (w *argumentWizard) askObjectItems(ctx context.Context, name string, item toolArgument) ([]any, error) {
+	items := []any{}
+	for {
+		more, err := w.confirm(ctx, fmt.Sprintf("Add an item to %s? [y/N] ", name), false)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return items, nil
+		}
+		value, err := w.askObject(ctx, fmt.Sprintf("%s[%d]", name, len(items)), item.Properties)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+}
+
+// askValue reads a single value. An empty input takes the default, or leaves
+// out an optional value without one; ok is false when left out.
+func (w *argumentWizard) askValue(ctx context.Context, label string, arg toolArgument) (value any, ok bool, err error) {
+	for attempt := 0; attempt < maxArgumentAttempts; attempt++ {
+		input, err := w.readLine(ctx, label+": ")
+		if err != nil {
+			return nil, false, err
+		}
+		input = strings.TrimSpace(input)
+
+		if input == "" {
+			if arg.Default != nil {
+				return arg.Default, true, nil
+			}
+			if !arg.Required {
+				return nil, false, nil
+			}
+			_, _ = fmt.Fprintf(w.out, "  %s is required\n", arg.Name)
+			continue
+		}
+
+		value, err := arg.parseValue(input)
+		if err == nil {
+			return value, true, nil
+		}
+		_, _ = fmt.Fprintf(w.out, "  invalid value: %v\n", err)
+	}
+	return nil, false, fmt.Errorf("no valid value for %s after %d attempts", arg.Name, maxArgumentAttempts)
+}
+
+// confirm asks a yes/no question; an empty answer takes def
+func (w *argumentWizard) confirm(ctx context.Context, prompt string, def bool) (bool, error) {
+	for attempt := 0; attempt < maxArgumentAttempts; attempt++ {
+		input, err := w.readLine(ctx, prompt)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// parseValue converts user input into a value of the argument's type.
+// Arguments without a scalar type take JSON, and untyped ones fall back to
+// the raw string.
+func (a toolArgument) parseValue(input string) (any, error) {
+	if len(a.Enum) > 0 {
+		var names []string
+		for _, value := range a.Enum {
+			if fmt.Sprint(value) == input {
+				return value, nil
+			}
+			names = append(names, fmt.Sprint(value))
+		}
+		return nil, fmt.Errorf("must be one of: %s", strings.Join(names, ", "))
+	}
+
+	switch a.Type {
+	case "string":
+		return input, nil
+	case "number":
+		v, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return v, nil
+	case "integer":
+		v, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return v, nil
+	case "boolean":
+		switch strings.ToLower(input) {
+		case "y", "yes", "true", "1":
+			return true, nil
+		case "n", "no", "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be yes or no")
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		if a.Type == "" {
+			return input, nil
+		}
+		return nil, fmt.Errorf("must be a JSON %s", a.Type)
+	}
+	return value, nil
+}
+
+// isScalarType reports whether values of a schema type are entered on a
+// single prompt without JSON
+func isScalarType(schemaType string) bool {
+	switch schemaType {
+	case "string", "number", "integer", "boolean":
+		return true
+	}
+	return false
+}
+
+// argumentType describes the accepted values of an argument, e.g. "a|b" for
+// an enum or "object as JSON" for values without a scalar type
+func argumentType(arg toolArgument) string {
+	if len(arg.Enum) > 0 {
+		names := make([]string, len(arg.Enum))
+		for i, value := range arg.Enum {
+			names[i] = fmt.Sprint(value)
+		}
+		return strings.Join(names, "|")
+	}
+	if arg.Type == "" || isScalarType(arg.Type) {
+		return cmp.Or(arg.Type, "any")
+	}
+	return arg.Type + " as JSON"
+}
+
+// argumentLabel renders the prompt of an argument, e.g.
+// "replicas (integer, required, default 3)"
+func argumentLabel(name string, arg toolArgument) string {
+	details := []string{argumentType(arg)}
+	if arg.Required {
+		details = append(details, "required")
+	}
+	if arg.Default != nil {
+		details = append(details, fmt.Sprintf("default %s", compactJSON(arg.Default)))
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
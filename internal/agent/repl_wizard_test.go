@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func deployTool() mcp.Tool {
+	return mcp.NewTool("deploy",
+		mcp.WithString("app", mcp.Required()),
+		mcp.WithString("env", mcp.Required(), mcp.Enum("staging", "production")),
+		mcp.WithNumber("replicas", mcp.DefaultNumber(3)),
+		mcp.WithArray("tags", mcp.WithStringItems()),
+		mcp.WithObject("limits", mcp.Properties(map[string]any{
+			"cpu":    map[string]any{"type": "string"},
+			"memory": map[string]any{"type": "integer"},
+		}), mcp.Required()),
+		mcp.WithArray("ports", mcp.Items(map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"port": map[string]any{"type": "integer"}},
+			"required":   []any{"port"},
+		})),
+		mcp.WithBoolean("dry_run"),
+	)
+}
+
+func TestArgumentWizard(t *testing.T) {
+	var shown strings.Builder
+	wizard := &argumentWizard{
+		readLine: scriptedInput(t,
+			"web",               // app
+			"dev", "production", // env: invalid enum value, then valid
+			"",            // limits.cpu skipped
+			"lots", "512", // limits.memory: invalid integer, then valid
+			"",               // dry_run skipped
+			"y", "8080", "n", // ports: one item
+			"",                   // replicas takes the default
+			"blue", "canary", "", // tags
+			"", // confirm
+		),
+		out: &shown,
+	}
+
+	args, err := wizard.run(context.Background(), "deploy", toolArguments(deployTool()))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := `{"app":"web","env":"production","limits":{"memory":512},"ports":[{"port":8080}],"replicas":3,"tags":["blue","canary"]}`
+	if got := compactJSON(args); got != want {
+		t.Errorf("arguments = %s\nwant %s", got, want)
+	}
+	if !strings.Contains(shown.String(), "must be one of: staging, production") || !strings.Contains(shown.String(), "must be an integer") {
+		t.Errorf("validation errors not shown:\n%s", shown.String())
+	}
+}
+
+func TestArgumentWizardAborts(t *testing.T) {
+	echo := mcp.NewTool("echo", mcp.WithString("message", mcp.Required()))
+
+	wizard := &argumentWizard{readLine: scriptedInput(t, "hi", "n"), out: io.Discard}
+	if _, err := wizard.run(context.Background(), "echo", toolArguments(echo)); !errors.Is(err, errArgumentsDeclined) {
+		t.Errorf("declined confirmation: err = %v", err)
+	}
+
+	wizard.readLine = scriptedInput(t, "", "", "")
+	if _, err := wizard.run(context.Background(), "echo", toolArguments(echo)); err == nil {
+		t.Error("expected error when a required argument is never given")
+	}
+
+	wizard.readLine = func(ctx context.Context, prompt string) (string, error) {
+		return "", errInputInterrupted
+	}
+	if _, err := wizard.run(context.Background(), "echo", toolArguments(echo)); !errors.Is(err, errInputInterrupted) {
+		t.Errorf("interrupted: err = %v", err)
+	}
+}
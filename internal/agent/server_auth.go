@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// tokenLeeway tolerates clock skew between the server and the issuer
+const tokenLeeway = time.Minute
+
+// ServerAuthConfig makes the streamable-http transport of the MCP server
+// mode a protected resource: requests need a bearer token issued by Issuer,
+// and RFC 9728 protected resource metadata tells clients where to get one.
+type ServerAuthConfig struct {
+	// Issuer is the authorization server whose tokens are accepted. Its
+	// signing keys are found through its metadata (jwks_uri).
+	Issuer string
+
+	// Resource is the canonical URI of the MCP endpoint (RFC 8707). Tokens
+	// must name it in their audience.
+	Resource string
+
+	// Scopes must all be granted by a token. They are also advertised as
+	// scopes_supported and in the WWW-Authenticate challenge.
+	Scopes []string
+}
+
+// validate checks that the issuer and resource are usable
+func (c ServerAuthConfig) validate() error {
+	if _, err := buildASMetadataEndpoints(c.Issuer); err != nil {
+		return fmt.Errorf("invalid authorization server issuer: %w", err)
+	}
+	resource, err := url.Parse(c.Resource)
+	if err != nil || !resource.IsAbs() || resource.Host == "" {
+		return fmt.Errorf("invalid resource URI %q: must be an absolute URL", c.Resource)
+	}
+	if resource.Fragment != "" {
+		return fmt.Errorf("invalid resource URI %q: must not contain a fragment", c.Resource)
+	}
+	return nil
+}
+
+// RequireBearerTokens protects the streamable-http transport with OAuth
+// bearer tokens. It must be called before Start.
+func (m *MCPServer) RequireBearerTokens(cfg ServerAuthConfig) error {
+	if m.serverTransport != "streamable-http" {
+		return fmt.Errorf("bearer token authentication requires the streamable-http server transport")
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	m.auth = &serverAuth{
+		config: cfg,
+		logger: m.logger,
+		validator: &tokenValidator{
+			issuer:   cfg.Issuer,
+			resource: cfg.Resource,
+			scopes:   cfg.Scopes,
+			keys:     &jwksCache{issuer: cfg.Issuer, logger: m.logger},
+			leeway:   tokenLeeway,
+			now:      time.Now,
+		},
+	}
+	return nil
+}
+
+// serverAuth checks the bearer tokens of incoming requests
+type serverAuth struct {
+	config    ServerAuthConfig
+	logger    *Logger
+	validator *tokenValidator
+}
+
+// metadata returns the protected resource metadata of the endpoint
+func (a *serverAuth) metadata() server.ProtectedResourceMetadataConfig {
+	return server.ProtectedResourceMetadataConfig{
+		Resource:               a.config.Resource,
+		AuthorizationServers:   []string{a.config.Issuer},
+		ScopesSupported:        a.config.Scopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "mcp-debug",
+	}
+}
+
+// metadataURL returns where the protected resource metadata is served
+func (a *serverAuth) metadataURL() string {
+	resource, _ := url.Parse(a.config.Resource)
+	return resource.Scheme + "://" + resource.Host + server.ProtectedResourceMetadataPath(a.config.Resource)
+}
+
+// handler serves the protected resource metadata and requires a valid
+// bearer token for the MCP endpoint
+func (a *serverAuth) handler(endpointPath string, next http.Handler) http.Handler {
+	metadata := server.NewProtectedResourceMetadataHandler(a.metadata())
+
+	mux := http.NewServeMux()
+	mux.Handle(server.WellKnownProtectedResourcePath, metadata)
+	mux.Handle(server.ProtectedResourceMetadataPath(a.config.Resource), metadata)
+	mux.Handle(endpointPath, a.requireToken(next))
+	return mux
+}
+
+// requireToken rejects requests without a valid bearer token
+func (a *serverAuth) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			a.logger.Warning("Rejected %s %s: no bearer token", r.Method, r.URL.Path)
+			a.challenge(w, nil)
+			return
+		}
+
+		claims, err := a.validator.validate(r.Context(), token)
+		if err != nil {
+			var berr *bearerError
+			if !errors.As(err, &berr) {
+				berr = invalidToken("%v", err)
+			}
+			a.logger.Warning("Rejected %s %s: %v", r.Method, r.URL.Path, berr)
+			a.challenge(w, berr)
+			return
+		}
+
+		a.logger.InfoVerbose("Authenticated %s %s (sub %q, client %q)", r.Method, r.URL.Path, claims.Subject, claims.ClientID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// challenge writes an RFC 6750 WWW-Authenticate challenge pointing at the
+// protected resource metadata (RFC 9728 Section 5.1). A request without a
+// token gets no error code.
+func (a *serverAuth) challenge(w http.ResponseWriter, berr *bearerError) {
+	params := []string{fmt.Sprintf("resource_metadata=%q", a.metadataURL())}
+	if len(a.config.Scopes) > 0 {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(a.config.Scopes, " ")))
+	}
+	status := http.StatusUnauthorized
+	if berr != nil {
+		status = berr.status
+		params = append(params,
+			fmt.Sprintf("error=%q", berr.code),
+			fmt.Sprintf("error_description=%q", strings.ReplaceAll(berr.description, `"`, "'")),
+		)
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	http.Error(w, http.StatusText(status), status)
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
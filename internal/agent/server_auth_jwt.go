@@ -0,0 +1,406 @@
+package agent
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for RS256, PS256 and ES256
+	_ "crypto/sha512" // registers SHA-384 and SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval is the minimum time between JWKS fetches, so
+	// tokens with unknown key IDs cannot make the server hammer the issuer
+	jwksRefreshInterval = 30 * time.Second
+
+	// maxJWKSSize bounds the JWK Set document
+	maxJWKSSize = 1024 * 1024
+)
+
+// jwtHashes maps the supported JWS algorithms to their hash. Symmetric
+// algorithms and "none" are deliberately absent: a resource server never
+// shares a secret with the issuer.
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// jwtHeader is the JOSE header of a signed token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// tokenClaims are the access token claims checked by the server (RFC 9068)
+type tokenClaims struct {
+	Issuer    string        `json:"iss"`
+	Subject   string        `json:"sub"`
+	Audience  audienceClaim `json:"aud"`
+	ExpiresAt float64       `json:"exp"`
+	NotBefore float64       `json:"nbf"`
+	ClientID  string        `json:"client_id"`
+	// Scope is the space-separated scope claim; some issuers use scp instead
+	Scope string          `json:"scope"`
+	Scp   json.RawMessage `json:"scp"`
+}
+
+// scopes returns the granted scopes from scope or scp
+func (c tokenClaims) scopes() []string {
+	if c.Scope != "" {
+		return strings.Fields(c.Scope)
+	}
+	var list []string
+	if json.Unmarshal(c.Scp, &list) == nil {
+		return list
+	}
+	var single string
+	if json.Unmarshal(c.Scp, &single) == nil {
+		return strings.Fields(single)
+	}
+	return nil
+}
+
+// audienceClaim is the aud claim, which may be a string or a list
+type audienceClaim []string
+
+// UnmarshalJSON accepts both forms of the aud claim
+func (a *audienceClaim) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audienceClaim{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// parsedJWT is a token split into its parts
+type parsedJWT struct {
+	header       jwtHeader
+	claims       tokenClaims
+	signingInput string
+	signature    []byte
+}
+
+// parseJWT decodes a compact JWS without verifying it
+func parseJWT(token string) (*parsedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	var jwt parsedJWT
+	if err := decodeJWTPart(parts[0], &jwt.header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if err := decodeJWTPart(parts[1], &jwt.claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	jwt.signature = signature
+	jwt.signingInput = parts[0] + "." + parts[1]
+	return &jwt, nil
+}
+
+// decodeJWTPart decodes a base64url JSON part of a token
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifyJWTSignature checks the signature of a token with a public key
+func verifyJWTSignature(jwt *parsedJWT, key crypto.PublicKey) error {
+	hash, ok := jwtHashes[jwt.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm %q", jwt.header.Alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(jwt.signingInput))
+	digest := hasher.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch jwt.header.Alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hash, digest, jwt.signature)
+		case "PS":
+			return rsa.VerifyPSS(k, hash, digest, jwt.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		if jwt.header.Alg[:2] != "ES" {
+			break
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(jwt.signature) != 2*size {
+			return errors.New("invalid ECDSA signature length")
+		}
+		r := new(big.Int).SetBytes(jwt.signature[:size])
+		s := new(big.Int).SetBytes(jwt.signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("key type does not match algorithm %s", jwt.header.Alg)
+}
+
+// jsonWebKey is a public key of a JWK Set (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// jwksCache holds the signing keys of an issuer, discovered from its
+// metadata and refreshed when a token names an unknown key
+type jwksCache struct {
+	issuer string
+	logger *Logger
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      []jsonWebKey
+	fetchedAt time.Time
+}
+
+// key returns the public key a token header refers to
+func (c *jwksCache) key(ctx context.Context, header jwtHeader) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key := c.find(header); key != nil {
+		return key.publicKey()
+	}
+	// Keys rotate: re-fetch, but not more often than jwksRefreshInterval
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("no signing key %q in the issuer's JWKS", header.Kid)
+	}
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key := c.find(header); key != nil {
+		return key.publicKey()
+	}
+	return nil, fmt.Errorf("no signing key %q in the issuer's JWKS", header.Kid)
+}
+
+// find returns the key matching the header's kid, or the only suitable key
+// if the token names none
+func (c *jwksCache) find(header jwtHeader) *jsonWebKey {
+	var candidates []*jsonWebKey
+	for i, key := range c.keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if key.Alg != "" && key.Alg != header.Alg {
+			continue
+		}
+		if header.Kid != "" && key.Kid == header.Kid {
+			return &c.keys[i]
+		}
+		candidates = append(candidates, &c.keys[i])
+	}
+	if header.Kid == "" && len(candidates) == 1 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// refresh fetches the JWK Set, discovering its URL from the issuer's
+// metadata on first use
+func (c *jwksCache) refresh(ctx context.Context) error {
+	c.fetchedAt = time.Now()
+
+	if c.jwksURI == "" {
+		metadata, err := DiscoverAuthorizationServerMetadata(ctx, c.issuer, c.logger)
+		if err != nil {
+			return fmt.Errorf("failed to discover issuer metadata: %w", err)
+		}
+		if metadata.JWKSURI == "" {
+			return fmt.Errorf("issuer %s does not publish a jwks_uri", c.issuer)
+		}
+		c.jwksURI = metadata.JWKSURI
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.jwksURI, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClientFor(ctx, metadataRequestTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	c.keys = set.Keys
+	if c.logger != nil {
+		c.logger.InfoVerbose("Loaded %d signing key(s) from %s", len(set.Keys), c.jwksURI)
+	}
+	return nil
+}
+
+// bearerError is a rejected bearer token, rendered as an RFC 6750 challenge
+type bearerError struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *bearerError) Error() string {
+	return e.code + ": " + e.description
+}
+
+// invalidToken returns an invalid_token rejection
+func invalidToken(format string, args ...any) *bearerError {
+	return &bearerError{status: http.StatusUnauthorized, code: "invalid_token", description: fmt.Sprintf(format, args...)}
+}
+
+// tokenValidator checks access tokens issued for the server
+type tokenValidator struct {
+	issuer   string
+	resource string
+	scopes   []string
+	keys     *jwksCache
+	// leeway tolerates clock skew in exp and nbf
+	leeway time.Duration
+	now    func() time.Time
+}
+
+// validate verifies a token's signature, issuer, audience, lifetime and
+// scopes and returns its claims
+func (v *tokenValidator) validate(ctx context.Context, token string) (*tokenClaims, error) {
+	jwt, err := parseJWT(token)
+	if err != nil {
+		return nil, invalidToken("%v", err)
+	}
+	if _, ok := jwtHashes[jwt.header.Alg]; !ok {
+		return nil, invalidToken("unsupported signing algorithm %q", jwt.header.Alg)
+	}
+
+	key, err := v.keys.key(ctx, jwt.header)
+	if err != nil {
+		return nil, invalidToken("%v", err)
+	}
+	if err := verifyJWTSignature(jwt, key); err != nil {
+		return nil, invalidToken("signature verification failed: %v", err)
+	}
+
+	claims := jwt.claims
+	now := v.now()
+	switch {
+	case claims.Issuer != v.issuer:
+		return nil, invalidToken("issuer %q is not %q", claims.Issuer, v.issuer)
+	case !slices.Contains(claims.Audience, v.resource):
+		return nil, invalidToken("audience %v does not include %s", []string(claims.Audience), v.resource)
+	case claims.ExpiresAt == 0:
+		return nil, invalidToken("token has no expiry")
+	case now.After(unixTime(claims.ExpiresAt).Add(v.leeway)):
+		return nil, invalidToken("token expired at %s", unixTime(claims.ExpiresAt).Format(time.RFC3339))
+	case claims.NotBefore != 0 && now.Add(v.leeway).Before(unixTime(claims.NotBefore)):
+		return nil, invalidToken("token is not valid before %s", unixTime(claims.NotBefore).Format(time.RFC3339))
+	}
+
+	granted := claims.scopes()
+	var missing []string
+	for _, scope := range v.scopes {
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return &claims, &bearerError{
+			status:      http.StatusForbidden,
+			code:        "insufficient_scope",
+			description: "missing scope(s): " + strings.Join(missing, " "),
+		}
+	}
+	return &claims, nil
+}
+
+// unixTime converts a NumericDate claim
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
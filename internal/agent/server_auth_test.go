@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIssuer is an authorization server publishing an RSA and an EC
+// signing key
+type testIssuer struct {
+	url    string
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 issuer.url,
+			"authorization_endpoint": issuer.url + "/authorize",
+			"token_endpoint":         issuer.url + "/token",
+			"jwks_uri":               issuer.url + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	issuer.url = ts.URL
+	return issuer
+}
+
+// sign issues a token with the given algorithm and key ID
+func (i *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "at+jwt"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, i.rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		signature = []byte("unsigned")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(signature)
+}
+
+// claims returns valid claims for resource, overridden by extra
+func (i *testIssuer) claims(resource string, extra map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss":   i.url,
+		"sub":   "alice",
+		"aud":   resource,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "mcp:read mcp:write",
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func TestTokenValidator(t *testing.T) {
+	issuer := newTestIssuer(t)
+	const resource = "http://localhost:8899/mcp"
+	validator := &tokenValidator{
+		issuer:   issuer.url,
+		resource: resource,
+		scopes:   []string{"mcp:read"},
+		keys:     &jwksCache{issuer: issuer.url},
+		leeway:   time.Minute,
+		now:      time.Now,
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode string
+	}{
+		{"RS256", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, nil)), ""},
+		{"PS256", issuer.sign(t, "PS256", "rsa", issuer.claims(resource, nil)), ""},
+		{"ES256", issuer.sign(t, "ES256", "ec", issuer.claims(resource, nil)), ""},
+		{"audience list", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"aud": []string{"other", resource}})), ""},
+		{"scp claim", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"scope": nil, "scp": []string{"mcp:read"}})), ""},
+		{"wrong audience", issuer.sign(t, "RS256", "rsa", issuer.claims("http://other/mcp", nil)), "invalid_token"},
+		{"wrong issuer", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"iss": "https://evil.example"})), "invalid_token"},
+		{"expired", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})), "invalid_token"},
+		{"no expiry", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"exp": nil})), "invalid_token"},
+		{"not yet valid", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})), "invalid_token"},
+		{"unknown key", issuer.sign(t, "RS256", "rotated", issuer.claims(resource, nil)), "invalid_token"},
+		{"key of another type", issuer.sign(t, "RS256", "ec", issuer.claims(resource, nil)), "invalid_token"},
+		{"alg none", issuer.sign(t, "none", "rsa", issuer.claims(resource, nil)), "invalid_token"},
+		{"HS256", issuer.sign(t, "HS256", "rsa", issuer.claims(resource, nil)), "invalid_token"},
+		{"opaque", "not-a-jwt", "invalid_token"},
+		{"missing scope", issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"scope": "mcp:write"})), "insufficient_scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.validate(t.Context(), tt.token)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("expected a valid token, got %v", err)
+				}
+				return
+			}
+			berr, ok := err.(*bearerError)
+			if !ok || berr.code != tt.wantCode {
+				t.Fatalf("expected %s, got %v", tt.wantCode, err)
+			}
+		})
+	}
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token := issuer.sign(t, "RS256", "rsa", issuer.claims(resource, nil))
+		parts := strings.Split(token, ".")
+		forged, _ := json.Marshal(issuer.claims(resource, map[string]any{"sub": "mallory"}))
+		parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+		if _, err := validator.validate(t.Context(), strings.Join(parts, ".")); err == nil {
+			t.Fatal("expected the forged token to be rejected")
+		}
+	})
+}
+
+func TestServerAuthHandler(t *testing.T) {
+	issuer := newTestIssuer(t)
+	const resource = "http://localhost:8899/mcp"
+
+	ms := &MCPServer{serverTransport: "streamable-http", logger: NewLoggerWithWriter(false, false, false, io.Discard)}
+	if err := ms.RequireBearerTokens(ServerAuthConfig{Issuer: issuer.url, Resource: resource, Scopes: []string{"mcp:read"}}); err != nil {
+		t.Fatalf("RequireBearerTokens: %v", err)
+	}
+	mcpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+	ts := httptest.NewServer(ms.auth.handler("/mcp", mcpHandler))
+	defer ts.Close()
+
+	t.Run("protected resource metadata", func(t *testing.T) {
+		for _, path := range []string{"/.well-known/oauth-protected-resource/mcp", "/.well-known/oauth-protected-resource"} {
+			resp, err := http.Get(ts.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var metadata ProtectedResourceMetadata
+			err = json.NewDecoder(resp.Body).Decode(&metadata)
+			_ = resp.Body.Close()
+			if err != nil || metadata.Resource != resource || len(metadata.AuthorizationServers) != 1 || metadata.AuthorizationServers[0] != issuer.url {
+				t.Errorf("%s: unexpected metadata %+v (%v)", path, metadata, err)
+			}
+		}
+	})
+
+	request := func(token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader("{}"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		resp := request("")
+		challenge, err := parseWWWAuthenticate(resp.Header.Get("WWW-Authenticate"))
+		if resp.StatusCode != http.StatusUnauthorized || err != nil {
+			t.Fatalf("expected 401 with a challenge, got %d %q", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+		}
+		if challenge.ResourceMetadataURL != "http://localhost:8899/.well-known/oauth-protected-resource/mcp" || challenge.Error != "" {
+			t.Errorf("unexpected challenge %+v", challenge)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		resp := request(issuer.sign(t, "RS256", "rsa", issuer.claims("http://other/mcp", nil)))
+		if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`) {
+			t.Errorf("expected invalid_token, got %d %q", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("insufficient scope", func(t *testing.T) {
+		resp := request(issuer.sign(t, "RS256", "rsa", issuer.claims(resource, map[string]any{"scope": "other"})))
+		challenge, _ := parseWWWAuthenticate(resp.Header.Get("WWW-Authenticate"))
+		if resp.StatusCode != http.StatusForbidden || challenge == nil || challenge.Error != "insufficient_scope" {
+			t.Errorf("expected 403 insufficient_scope, got %d %q", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		if resp := request(issuer.sign(t, "ES256", "ec", issuer.claims(resource, nil))); resp.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestRequireBearerTokensValidation(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	stdio := &MCPServer{serverTransport: "stdio", logger: logger}
+	if err := stdio.RequireBearerTokens(ServerAuthConfig{Issuer: "https://auth.example.com", Resource: "http://localhost:8899/mcp"}); err == nil {
+		t.Error("expected an error for the stdio transport")
+	}
+
+	ms := &MCPServer{serverTransport: "streamable-http", logger: logger}
+	for _, cfg := range []ServerAuthConfig{
+		{Issuer: "http://auth.example.com", Resource: "http://localhost:8899/mcp"},
+		{Issuer: "https://auth.example.com", Resource: "/mcp"},
+		{Issuer: "https://auth.example.com", Resource: "http://localhost:8899/mcp#frag"},
+	} {
+		if err := ms.RequireBearerTokens(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// faultToolName is the tool that changes the injected faults; its calls are
+// never faulted, so the faults can always be turned off again
+const faultToolName = "configure_faults"
+
+// defaultFaultStatuses are the statuses of injected HTTP error bursts
+var defaultFaultStatuses = []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError}
+
+// FaultConfig makes the streamable-http transport of the MCP server mode
+// misbehave, to verify how clients cope with a flaky server. Rates are
+// probabilities between 0 and 1, rolled per request or, for notifications,
+// per message.
+type FaultConfig struct {
+	// Latency is the maximum random delay added before a request is handled
+	Latency time.Duration
+
+	// DropNotifications is the rate of notifications that are not sent
+	DropNotifications float64
+
+	// Malformed is the rate of JSON-RPC responses that are corrupted: cut
+	// off, answered with a wrong ID or with a wrong protocol version
+	Malformed float64
+
+	// Truncate is the rate of responses whose body or SSE stream ends
+	// halfway through the JSON-RPC response
+	Truncate float64
+
+	// HTTPErrors is the rate of requests that start a burst of HTTP errors
+	HTTPErrors float64
+
+	// ErrorBurst is the number of consecutive requests a burst fails
+	ErrorBurst int
+
+	// ErrorStatuses are the statuses a burst picks from (default 401, 403
+	// and 500)
+	ErrorStatuses []int
+
+	// ConnectionReset is the rate of requests whose connection is reset
+	// without a response
+	ConnectionReset float64
+
+	// Seed makes the faults reproducible for the same sequence of requests;
+	// 0 picks a random seed
+	Seed int64
+}
+
+// validate checks that rates are probabilities and statuses are errors
+func (c FaultConfig) validate() error {
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"drop notifications", c.DropNotifications},
+		{"malformed", c.Malformed},
+		{"truncate", c.Truncate},
+		{"HTTP errors", c.HTTPErrors},
+		{"connection reset", c.ConnectionReset},
+	}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("%s rate %v must be between 0 and 1", r.name, r.rate)
+		}
+	}
+	if c.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if c.ErrorBurst < 0 {
+		return fmt.Errorf("error burst must not be negative")
+	}
+	for _, status := range c.ErrorStatuses {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("error status %d is not an HTTP error status", status)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the burst length and statuses
+func (c FaultConfig) withDefaults() FaultConfig {
+	if c.ErrorBurst == 0 {
+		c.ErrorBurst = 1
+	}
+	if len(c.ErrorStatuses) == 0 {
+		c.ErrorStatuses = defaultFaultStatuses
+	}
+	return c
+}
+
+// InjectFaults makes the streamable-http transport misbehave as configured
+// and registers the configure_faults tool to change the faults while the
+// server runs. It must be called before Start.
+func (m *MCPServer) InjectFaults(cfg FaultConfig) error {
+	if m.serverTransport != "streamable-http" {
+		return fmt.Errorf("fault injection requires the streamable-http server transport")
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	m.faults = newFaultInjector(cfg.withDefaults(), m.logger)
+	m.registerFaultTool()
+	m.logger.Warning("Injecting faults into the MCP server (seed %d)", m.faults.config.Seed)
+	return nil
+}
+
+// Fault kinds, as counted and reported by configure_faults
+const (
+	faultLatency             = "latency"
+	faultDroppedNotification = "droppedNotifications"
+	faultMalformed           = "malformed"
+	faultTruncated           = "truncated"
+	faultHTTPError           = "httpErrors"
+	faultConnectionReset     = "connectionResets"
+)
+
+// faultInjector decides which faults hit a request and applies them
+type faultInjector struct {
+	logger *Logger
+
+	mu     sync.Mutex
+	config FaultConfig
+	rand   *rand.Rand
+	// burstLeft is the number of requests the current error burst still
+	// fails with burstStatus
+	burstLeft   int
+	burstStatus int
+	injected    map[string]int
+}
+
+func newFaultInjector(cfg FaultConfig, logger *Logger) *faultInjector {
+	f := &faultInjector{logger: logger, injected: make(map[string]int)}
+	f.configure(cfg)
+	return f
+}
+
+// configure replaces the faults, reseeding if the seed changed
+func (f *faultInjector) configure(cfg FaultConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cfg.Seed == 0 {
+		cfg.Seed = f.config.Seed
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = rand.Int64()
+	}
+	if f.rand == nil || cfg.Seed != f.config.Seed {
+		f.rand = rand.New(rand.NewPCG(uint64(cfg.Seed), 0))
+	}
+	f.config = cfg
+	f.burstLeft = 0
+}
+
+// snapshot returns the faults and how often each was injected
+func (f *faultInjector) snapshot() (FaultConfig, map[string]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	injected := make(map[string]int, len(f.injected))
+	for kind, n := range f.injected {
+		injected[kind] = n
+	}
+	return f.config, injected
+}
+
+// faultPlan is what happens to one request
+type faultPlan struct {
+	delay     time.Duration
+	status    int
+	reset     bool
+	malformed bool
+	truncate  bool
+}
+
+// plan rolls the faults of a request
+func (f *faultInjector) plan() faultPlan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var p faultPlan
+	if f.config.Latency > 0 {
+		p.delay = time.Duration(f.rand.Int64N(int64(f.config.Latency) + 1))
+	}
+	if f.burstLeft == 0 && f.chanceLocked(f.config.HTTPErrors) {
+		f.burstLeft = f.config.ErrorBurst
+		f.burstStatus = f.config.ErrorStatuses[f.rand.IntN(len(f.config.ErrorStatuses))]
+	}
+	if f.burstLeft > 0 {
+		f.burstLeft--
+		p.status = f.burstStatus
+		return p
+	}
+	if f.chanceLocked(f.config.ConnectionReset) {
+		p.reset = true
+		return p
+	}
+	p.malformed = f.chanceLocked(f.config.Malformed)
+	p.truncate = f.chanceLocked(f.config.Truncate)
+	return p
+}
+
+// dropNotification rolls whether a notification is dropped
+func (f *faultInjector) dropNotification() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.chanceLocked(f.config.DropNotifications)
+}
+
+// chanceLocked rolls rate; the caller holds mu
+func (f *faultInjector) chanceLocked(rate float64) bool {
+	return rate > 0 && f.rand.Float64() < rate
+}
+
+// intN returns a random number in [0, n)
+func (f *faultInjector) intN(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.IntN(n)
+}
+
+// record counts an injected fault and logs it
+func (f *faultInjector) record(kind, format string, args ...any) {
+	f.mu.Lock()
+	f.injected[kind]++
+	f.mu.Unlock()
+
+	if kind == faultLatency {
+		f.logger.InfoVerbose("Injected fault: "+format, args...)
+		return
+	}
+	f.logger.Warning("Injected fault: "+format, args...)
+}
+
+// handler injects faults into the requests to next, except for calls of
+// the configure_faults tool
+func (f *faultInjector) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isFaultToolCall(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		plan := f.plan()
+		if plan.delay > 0 {
+			f.record(faultLatency, "delaying %s %s by %s", r.Method, r.URL.Path, plan.delay)
+			timer := time.NewTimer(plan.delay)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+
+		switch {
+		case plan.status != 0:
+			f.record(faultHTTPError, "answering %s %s with %d", r.Method, r.URL.Path, plan.status)
+			switch plan.status {
+			case http.StatusUnauthorized:
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="injected fault"`)
+			case http.StatusForbidden:
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", error_description="injected fault"`)
+			}
+			http.Error(w, http.StatusText(plan.status), plan.status)
+		case plan.reset:
+			f.record(faultConnectionReset, "resetting the connection of %s %s", r.Method, r.URL.Path)
+			resetConnection(w)
+		default:
+			next.ServeHTTP(&faultWriter{ResponseWriter: w, faults: f, plan: plan}, r)
+		}
+	})
+}
+
+// isFaultToolCall reports whether r calls the configure_faults tool; the
+// body is restored for the next handler
+func isFaultToolCall(r *http.Request) bool {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var message struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(body, &message) != nil {
+		return false
+	}
+	return message.Method == "tools/call" && message.Params.Name == faultToolName
+}
+
+// resetConnection closes the connection without a response, with a TCP
+// reset where possible
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		// HTTP/2 cannot be hijacked; aborting the handler resets the stream
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// faultWriter applies the faults of a request to its response: a JSON
+// body is corrupted or cut off as a whole, an SSE stream event by event
+type faultWriter struct {
+	http.ResponseWriter
+	faults *faultInjector
+	plan   faultPlan
+
+	mu sync.Mutex
+	// pending holds the start of an SSE event not yet written completely
+	pending []byte
+	// done is set once a body fault was applied; truncated also discards
+	// everything written afterwards
+	done      bool
+	truncated bool
+}
+
+// Write applies the faults to application/json and text/event-stream
+// bodies and passes any other body through
+func (w *faultWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return len(p), w.writeJSON(p)
+	case "text/event-stream":
+		w.pending = append(w.pending, p...)
+		for {
+			end := bytes.Index(w.pending, []byte("\n\n"))
+			if end < 0 {
+				return len(p), nil
+			}
+			event := w.pending[:end+2]
+			w.pending = w.pending[end+2:]
+			if err := w.writeEvent(event); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		return w.ResponseWriter.Write(p)
+	}
+}
+
+// Flush keeps the writer streaming for mcp-go
+func (w *faultWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the connection
+func (w *faultWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// writeJSON writes a JSON-RPC response body
+func (w *faultWriter) writeJSON(p []byte) error {
+	if w.truncated {
+		return nil
+	}
+	if !w.done {
+		p = w.corruptResponse(p)
+	}
+	_, err := w.ResponseWriter.Write(p)
+	return err
+}
+
+// writeEvent writes one SSE event, dropping notifications and corrupting or
+// cutting off the JSON-RPC response
+func (w *faultWriter) writeEvent(event []byte) error {
+	if w.truncated {
+		return nil
+	}
+
+	var data []string
+	for _, line := range strings.Split(strings.TrimRight(string(event), "\n"), "\n") {
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	payload := []byte(strings.Join(data, "\n"))
+
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	_ = json.Unmarshal(payload, &message)
+
+	switch {
+	case message.Method != "" && message.ID == nil:
+		if w.faults.dropNotification() {
+			w.faults.record(faultDroppedNotification, "dropping %s", message.Method)
+			return nil
+		}
+	case message.Method == "" && message.ID != nil && !w.done:
+		corrupted := w.corruptResponse(payload)
+		switch {
+		case w.truncated:
+			// The stream ends in the middle of the event
+			event = event[:len(event)/2]
+		case !bytes.Equal(corrupted, payload):
+			event = fmt.Appendf(nil, "event: message\ndata: %s\n\n", corrupted)
+		}
+	}
+	_, err := w.ResponseWriter.Write(event)
+	return err
+}
+
+// corruptResponse applies the planned body faults to a JSON-RPC response
+func (w *faultWriter) corruptResponse(payload []byte) []byte {
+	switch {
+	case w.plan.truncate:
+		w.done, w.truncated = true, true
+		w.faults.record(faultTruncated, "cutting off the response halfway")
+		return payload[:len(payload)/2]
+	case w.plan.malformed:
+		w.done = true
+		corrupted, how := w.malform(payload)
+		w.faults.record(faultMalformed, "%s", how)
+		return corrupted
+	}
+	return payload
+}
+
+// malform corrupts a JSON-RPC response in one of a few ways clients must
+// reject
+func (w *faultWriter) malform(payload []byte) ([]byte, string) {
+	var message map[string]any
+	if json.Unmarshal(payload, &message) != nil {
+		return []byte("not json"), "replacing the response with invalid JSON"
+	}
+	switch w.faults.intN(3) {
+	case 0:
+		message["id"] = "chaos"
+		corrupted, _ := json.Marshal(message)
+		return corrupted, "changing the response ID"
+	case 1:
+		message["jsonrpc"] = "1.0"
+		corrupted, _ := json.Marshal(message)
+		return corrupted, "changing the response's JSON-RPC version"
+	default:
+		end := bytes.LastIndexByte(payload, '}')
+		if end < 0 {
+			end = len(payload)
+		}
+		return append(bytes.Clone(payload[:end]), ',', '}'), "making the response invalid JSON"
+	}
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestFaultInjectorBursts(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	faults := newFaultInjector(FaultConfig{HTTPErrors: 1, ErrorBurst: 3, ErrorStatuses: []int{503}, Seed: 1}, logger)
+
+	if plan := faults.plan(); plan.status != 503 {
+		t.Fatalf("expected the burst to start, got %+v", plan)
+	}
+	faults.config.HTTPErrors = 0
+	for i := range 2 {
+		if plan := faults.plan(); plan.status != 503 {
+			t.Fatalf("request %d of the burst: got %+v", i+2, plan)
+		}
+	}
+	if plan := faults.plan(); plan.status != 0 {
+		t.Fatalf("expected the burst to be over, got %+v", plan)
+	}
+}
+
+func TestFaultWriter(t *testing.T) {
+	const (
+		notification = `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`
+		response     = `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`
+	)
+	sseEvent := func(data string) string { return "event: message\ndata: " + data + "\n\n" }
+
+	write := func(t *testing.T, cfg FaultConfig, plan faultPlan, contentType string, chunks ...string) string {
+		t.Helper()
+		faults := newFaultInjector(cfg.withDefaults(), NewLoggerWithWriter(false, false, false, io.Discard))
+		rec := httptest.NewRecorder()
+		w := &faultWriter{ResponseWriter: rec, faults: faults, plan: plan}
+		w.Header().Set("Content-Type", contentType)
+		for _, chunk := range chunks {
+			if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+				t.Fatalf("Write = %d, %v", n, err)
+			}
+		}
+		return rec.Body.String()
+	}
+
+	t.Run("untouched", func(t *testing.T) {
+		// Events split across writes are reassembled
+		stream := sseEvent(notification) + sseEvent(response)
+		if got := write(t, FaultConfig{}, faultPlan{}, "text/event-stream", stream[:10], stream[10:]); got != stream {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("dropped notification", func(t *testing.T) {
+		got := write(t, FaultConfig{DropNotifications: 1}, faultPlan{}, "text/event-stream", sseEvent(notification), sseEvent(response))
+		if got != sseEvent(response) {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("truncated stream", func(t *testing.T) {
+		got := write(t, FaultConfig{}, faultPlan{truncate: true}, "text/event-stream", sseEvent(notification), sseEvent(response), sseEvent(notification))
+		if !strings.HasPrefix(got, sseEvent(notification)) || strings.Contains(got, response) || strings.HasSuffix(got, "\n\n") {
+			t.Errorf("expected the stream to end inside the response, got %q", got)
+		}
+	})
+
+	t.Run("malformed event", func(t *testing.T) {
+		got := write(t, FaultConfig{}, faultPlan{malformed: true}, "text/event-stream", sseEvent(response))
+		data, ok := strings.CutPrefix(strings.TrimSuffix(got, "\n\n"), "event: message\ndata: ")
+		if !ok || data == response {
+			t.Errorf("expected a corrupted response event, got %q", got)
+		}
+	})
+
+	t.Run("malformed JSON", func(t *testing.T) {
+		if got := write(t, FaultConfig{}, faultPlan{malformed: true}, "application/json", response+"\n"); got == response+"\n" {
+			t.Errorf("expected a corrupted body, got %q", got)
+		}
+	})
+
+	t.Run("truncated JSON", func(t *testing.T) {
+		got := write(t, FaultConfig{}, faultPlan{truncate: true}, "application/json", response)
+		if got != response[:len(response)/2] {
+			t.Errorf("got %q", got)
+		}
+	})
+}
+
+func TestConfigureFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	ms, err := NewMCPServer(nil, "streamable-http", NewLoggerWithWriter(false, false, false, io.Discard), nil, false)
+	if err != nil {
+		t.Fatalf("NewMCPServer: %v", err)
+	}
+	if err := ms.InjectFaults(FaultConfig{Seed: 42}); err != nil {
+		t.Fatalf("InjectFaults: %v", err)
+	}
+	ts := httptest.NewServer(ms.faults.handler(server.NewStreamableHTTPServer(ms.mcpServer)))
+	defer ts.Close()
+
+	downstream, err := client.NewStreamableHttpClient(ts.URL + "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = downstream.Close() }()
+	if _, err := downstream.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	configure := func(args map[string]any) faultsOutput {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Name = faultToolName
+		req.Params.Arguments = args
+		result, err := downstream.CallTool(ctx, req)
+		if err != nil || result.IsError {
+			t.Fatalf("configure_faults: %v %+v", err, result)
+		}
+		var out faultsOutput
+		data, _ := json.Marshal(result.StructuredContent)
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// Faults hit every request but configure_faults, so they can be lifted
+	out := configure(map[string]any{"http_errors": 1, "error_statuses": []int{http.StatusServiceUnavailable}})
+	if out.HTTPErrors != 1 || out.Seed != 42 || len(out.ErrorStatuses) != 1 {
+		t.Errorf("unexpected configuration %+v", out)
+	}
+	if _, err := downstream.ListTools(ctx, mcp.ListToolsRequest{}); err == nil {
+		t.Error("expected ListTools to fail with an injected HTTP error")
+	}
+
+	configure(map[string]any{"http_errors": 0, "connection_reset": 1})
+	if _, err := downstream.ListTools(ctx, mcp.ListToolsRequest{}); err == nil {
+		t.Error("expected ListTools to fail with a reset connection")
+	}
+
+	out = configure(map[string]any{"connection_reset": 0})
+	if _, err := downstream.ListTools(ctx, mcp.ListToolsRequest{}); err != nil {
+		t.Errorf("ListTools after lifting the faults: %v", err)
+	}
+	if out.Injected.HTTPErrors != 1 || out.Injected.ConnectionResets != 1 {
+		t.Errorf("unexpected fault counts %+v", out.Injected)
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = faultToolName
+	req.Params.Arguments = map[string]any{"malformed": 2}
+	if result, err := downstream.CallTool(ctx, req); err != nil || !result.IsError {
+		t.Errorf("expected an invalid rate to be rejected, got %v %+v", err, result)
+	}
+}
+
+func TestInjectFaultsValidation(t *testing.T) {
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+
+	stdio, _ := NewMCPServer(nil, "stdio", logger, nil, false)
+	if err := stdio.InjectFaults(FaultConfig{}); err == nil {
+		t.Error("expected an error for the stdio transport")
+	}
+
+	ms, _ := NewMCPServer(nil, "streamable-http", logger, nil, false)
+	for _, cfg := range []FaultConfig{
+		{DropNotifications: -0.1},
+		{Truncate: 1.5},
+		{Latency: -1},
+		{ErrorStatuses: []int{200}},
+	} {
+		if err := ms.InjectFaults(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -15,16 +19,35 @@ type MCPServer struct {
 	mcpServer       *server.MCPServer
 	notifyClients   bool
 	serverTransport string
+	// sessionLog holds the log lines served by get_session_log; nil if the
+	// log is not recorded
+	sessionLog *SessionLog
+	// auth requires bearer tokens on the streamable-http transport; nil
+	// leaves it open
+	auth *serverAuth
+	// faults injects faults into the streamable-http transport; nil if
+	// none are injected
+	faults *faultInjector
+	// upstreams holds the connection to the server given on the command
+	// line and those opened with connect_upstream
+	upstreams *ConnectionManager
+
+	// changesMu guards changes, the number of capability changes served as
+	// resources of their own
+	changesMu sync.Mutex
+	changes   int
 }
 
-// NewMCPServer creates a new MCP server that exposes agent functionality
-func NewMCPServer(client *Client, serverTransport string, logger *Logger, notifyClients bool) (*MCPServer, error) {
+// NewMCPServer creates a new MCP server that exposes agent functionality.
+// sessionLog should be a writer of logger so get_session_log can serve the
+// log; it may be nil.
+func NewMCPServer(client *Client, serverTransport string, logger *Logger, sessionLog *SessionLog, notifyClients bool) (*MCPServer, error) {
 	// Create MCP server
 	mcpServer := server.NewMCPServer(
 		"mcp-debug-agent",
 		"1.0.0",
 		server.WithToolCapabilities(notifyClients),
-		server.WithResourceCapabilities(false, false),
+		server.WithResourceCapabilities(false, true),
 		server.WithPromptCapabilities(false),
 	)
 
@@ -34,21 +57,35 @@ func NewMCPServer(client *Client, serverTransport string, logger *Logger, notify
 		mcpServer:       mcpServer,
 		notifyClients:   notifyClients,
 		serverTransport: serverTransport,
+		sessionLog:      sessionLog,
+	}
+	if client != nil {
+		ms.upstreams = NewConnectionManager(client)
 	}
 
-	// Register all tools
+	// Register all tools, resources and prompts
 	ms.registerTools()
+	ms.registerUpstreamTools()
+	ms.registerResources()
+	ms.registerPrompts()
 
 	return ms, nil
 }
 
 // Start starts the MCP server using stdio or streamable-http transport
 func (m *MCPServer) Start(ctx context.Context, listenAddr string) error {
+	if m.upstreams != nil {
+		defer m.upstreams.CloseAll()
+	}
+
 	// Start the server with the specified transport
 	switch m.serverTransport {
 	case "stdio":
 		return server.ServeStdio(m.mcpServer)
 	case "streamable-http":
+		if m.auth != nil || m.faults != nil {
+			return m.serveHTTP(ctx, listenAddr)
+		}
 		httpServer := server.NewStreamableHTTPServer(
 			m.mcpServer,
 			server.WithEndpointPath("/mcp"),
@@ -64,18 +101,24 @@ func (m *MCPServer) registerTools() {
 	// List tools
 	listToolsTool := mcp.NewTool("list_tools",
 		mcp.WithDescription("List all available tools from connected MCP servers"),
+		withUpstream(),
+		mcp.WithOutputSchema[toolsOutput](),
 	)
 	m.mcpServer.AddTool(listToolsTool, m.handleListTools)
 
 	// List resources
 	listResourcesTool := mcp.NewTool("list_resources",
 		mcp.WithDescription("List all available resources from connected MCP servers"),
+		withUpstream(),
+		mcp.WithOutputSchema[resourcesOutput](),
 	)
 	m.mcpServer.AddTool(listResourcesTool, m.handleListResources)
 
 	// List prompts
 	listPromptsTool := mcp.NewTool("list_prompts",
 		mcp.WithDescription("List all available prompts from connected MCP servers"),
+		withUpstream(),
+		mcp.WithOutputSchema[promptsOutput](),
 	)
 	m.mcpServer.AddTool(listPromptsTool, m.handleListPrompts)
 
@@ -86,6 +129,8 @@ func (m *MCPServer) registerTools() {
 			mcp.Required(),
 			mcp.Description("Name of the tool to describe"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[toolOutput](),
 	)
 	m.mcpServer.AddTool(describeToolTool, m.handleDescribeTool)
 
@@ -96,6 +141,8 @@ func (m *MCPServer) registerTools() {
 			mcp.Required(),
 			mcp.Description("URI of the resource to describe"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[resourceOutput](),
 	)
 	m.mcpServer.AddTool(describeResourceTool, m.handleDescribeResource)
 
@@ -106,6 +153,8 @@ func (m *MCPServer) registerTools() {
 			mcp.Required(),
 			mcp.Description("Name of the prompt to describe"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[promptOutput](),
 	)
 	m.mcpServer.AddTool(describePromptTool, m.handleDescribePrompt)
 
@@ -119,6 +168,8 @@ func (m *MCPServer) registerTools() {
 		mcp.WithObject("arguments",
 			mcp.Description("Arguments to pass to the tool (as JSON object)"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[callToolOutput](),
 	)
 	m.mcpServer.AddTool(callToolTool, m.handleCallTool)
 
@@ -129,6 +180,8 @@ func (m *MCPServer) registerTools() {
 			mcp.Required(),
 			mcp.Description("URI of the resource to retrieve"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[resourceContentsOutput](),
 	)
 	m.mcpServer.AddTool(getResourceTool, m.handleGetResource)
 
@@ -142,6 +195,143 @@ func (m *MCPServer) registerTools() {
 		mcp.WithObject("arguments",
 			mcp.Description("Arguments to pass to the prompt (as JSON object with string values)"),
 		),
+		withUpstream(),
+		mcp.WithOutputSchema[promptResultOutput](),
 	)
 	m.mcpServer.AddTool(getPromptTool, m.handleGetPrompt)
+
+	// Get statistics
+	getStatisticsTool := mcp.NewTool("get_statistics",
+		mcp.WithDescription("Report request count, error rate and p50/p95/p99 latency per JSON-RPC method since connecting, and notification and ping statistics"),
+		withUpstream(),
+		mcp.WithOutputSchema[statisticsOutput](),
+	)
+	m.mcpServer.AddTool(getStatisticsTool, m.handleGetStatistics)
+
+	// Connection health
+	connectionHealthTool := mcp.NewTool("connection_health",
+		mcp.WithDescription("Report the health of the connection to the MCP server: status, uptime, last and average ping round-trip time, reconnect count and pending requests"),
+		mcp.WithBoolean("ping",
+			mcp.Description("Ping the server first so the round-trip time is current (default true)"),
+		),
+		withUpstream(),
+		mcp.WithOutputSchema[connectionHealthOutput](),
+	)
+	m.mcpServer.AddTool(connectionHealthTool, m.handleConnectionHealth)
+
+	// Get session log
+	getSessionLogTool := mcp.NewTool("get_session_log",
+		mcp.WithDescription("Return the latest lines of the debugging session's log, including the JSON-RPC traffic with the connected server when --verbose is set"),
+		mcp.WithNumber("lines",
+			mcp.Description("Maximum number of lines to return, newest last (default 100, 0 for all kept lines)"),
+		),
+		mcp.WithString("contains",
+			mcp.Description("Only return lines containing this text"),
+		),
+		mcp.WithOutputSchema[sessionLogOutput](),
+	)
+	m.mcpServer.AddTool(getSessionLogTool, m.handleGetSessionLog)
+
+	// Reconnect
+	reconnectTool := mcp.NewTool("reconnect",
+		mcp.WithDescription("Close the connection to the MCP server, start a new session and report what changed in its tools, resources and prompts"),
+		withUpstream(),
+		mcp.WithOutputSchema[reconnectOutput](),
+	)
+	m.mcpServer.AddTool(reconnectTool, m.handleReconnect)
+
+	// Change endpoint
+	changeEndpointTool := mcp.NewTool("change_endpoint",
+		mcp.WithDescription("Disconnect from the MCP server and connect to another endpoint instead, with a fresh OAuth flow if OAuth is enabled, and report what changed in its tools, resources and prompts. Useful when the server under development moves to another port."),
+		mcp.WithString("endpoint",
+			mcp.Required(),
+			mcp.Description("http or https URL of the MCP server to connect to"),
+		),
+		withUpstream(),
+		mcp.WithOutputSchema[changeEndpointOutput](),
+	)
+	m.mcpServer.AddTool(changeEndpointTool, m.handleChangeEndpoint)
+}
+
+// registerFaultTool registers the tool that changes the injected faults
+func (m *MCPServer) registerFaultTool() {
+	configureFaultsTool := mcp.NewTool(faultToolName,
+		mcp.WithDescription("Change the faults injected into this server's streamable-http transport and report how often each was injected. Omitted arguments keep their current value; call without arguments to only report."),
+		mcp.WithString("latency",
+			mcp.Description("Maximum random delay added before a request is handled, e.g. 500ms"),
+		),
+		mcp.WithNumber("drop_notifications",
+			mcp.Description("Rate (0-1) of notifications that are not sent"),
+		),
+		mcp.WithNumber("malformed",
+			mcp.Description("Rate (0-1) of JSON-RPC responses that are corrupted"),
+		),
+		mcp.WithNumber("truncate",
+			mcp.Description("Rate (0-1) of responses cut off halfway"),
+		),
+		mcp.WithNumber("http_errors",
+			mcp.Description("Rate (0-1) of requests that start a burst of HTTP errors"),
+		),
+		mcp.WithNumber("error_burst",
+			mcp.Description("Number of consecutive requests an HTTP error burst fails"),
+		),
+		mcp.WithArray("error_statuses",
+			mcp.Description("HTTP statuses an error burst picks from"),
+			mcp.WithNumberItems(),
+		),
+		mcp.WithNumber("connection_reset",
+			mcp.Description("Rate (0-1) of requests whose connection is reset"),
+		),
+		mcp.WithNumber("seed",
+			mcp.Description("Seed for the random faults, to reproduce a run"),
+		),
+		mcp.WithOutputSchema[faultsOutput](),
+	)
+	m.mcpServer.AddTool(configureFaultsTool, m.handleConfigureFaults)
+}
+
+// registerResources registers the resources describing the upstream server
+// and the debugging session
+func (m *MCPServer) registerResources() {
+	instructionsResource := mcp.NewResource(serverInstructionsURI, "Server instructions",
+		mcp.WithResourceDescription("Usage instructions the connected MCP server returned during initialize"),
+		mcp.WithMIMEType("text/plain"),
+	)
+	m.mcpServer.AddResource(instructionsResource, m.handleReadInstructions)
+	m.registerHistoryResources()
+}
+
+// serveHTTP runs the streamable-http transport behind the bearer token
+// check and fault injection until ctx is cancelled
+func (m *MCPServer) serveHTTP(ctx context.Context, listenAddr string) error {
+	const endpointPath = "/mcp"
+	var handler http.Handler = server.NewStreamableHTTPServer(m.mcpServer, server.WithEndpointPath(endpointPath))
+	if m.faults != nil {
+		handler = m.faults.handler(handler)
+	}
+	if m.auth != nil {
+		m.logger.Info("Requiring bearer tokens from %s for %s", m.auth.config.Issuer, m.auth.config.Resource)
+		handler = m.auth.handler(endpointPath, handler)
+	} else {
+		mux := http.NewServeMux()
+		mux.Handle(endpointPath, handler)
+		handler = mux
+	}
+
+	httpServer := &http.Server{
+		Addr:              listenAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
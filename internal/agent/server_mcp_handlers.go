@@ -2,59 +2,223 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
+// refreshStaleCaches re-lists expired or invalidated caches before they are
+// served; on failure the cached data is used as-is
+func (m *MCPServer) refreshStaleCaches(ctx context.Context, client *Client) {
+	if _, err := client.refreshStaleCaches(ctx); err != nil {
+		m.logger.Warning("Using cached data: %v", err)
+	}
+}
+
 // handleListTools handles the list_tools tool request
 func (m *MCPServer) handleListTools(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	m.client.mu.RLock()
-	tools := m.client.toolCache
-	m.client.mu.RUnlock()
-
-	// Convert to JSON
-	data, err := json.Marshal(tools)
+	client, err := m.upstream(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal tools: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	return mcp.NewToolResultText(string(data)), nil
+	m.refreshStaleCaches(ctx, client)
+
+	client.mu.RLock()
+	tools := client.toolCache
+	client.mu.RUnlock()
+
+	// Convert to JSON
+	out := toolsOutput{Tools: make([]toolOutput, 0, len(tools))}
+	for _, tool := range tools {
+		out.Tools = append(out.Tools, newToolOutput(tool))
+	}
+	return structuredResult(out, tools), nil
 }
 
 // handleListResources handles the list_resources tool request
 func (m *MCPServer) handleListResources(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	m.client.mu.RLock()
-	resources := m.client.resourceCache
-	m.client.mu.RUnlock()
-
-	// Convert to JSON
-	data, err := json.Marshal(resources)
+	client, err := m.upstream(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal resources: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	return mcp.NewToolResultText(string(data)), nil
+	m.refreshStaleCaches(ctx, client)
+
+	client.mu.RLock()
+	resources := client.resourceCache
+	client.mu.RUnlock()
+
+	// Convert to JSON
+	out := resourcesOutput{Resources: make([]resourceOutput, 0, len(resources))}
+	for _, resource := range resources {
+		out.Resources = append(out.Resources, newResourceOutput(resource))
+	}
+	return structuredResult(out, resources), nil
 }
 
 // handleListPrompts handles the list_prompts tool request
 func (m *MCPServer) handleListPrompts(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	m.client.mu.RLock()
-	prompts := m.client.promptCache
-	m.client.mu.RUnlock()
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	m.refreshStaleCaches(ctx, client)
+
+	client.mu.RLock()
+	prompts := client.promptCache
+	client.mu.RUnlock()
 
 	// Convert to JSON
-	data, err := json.Marshal(prompts)
+	out := promptsOutput{Prompts: make([]promptOutput, 0, len(prompts))}
+	for _, prompt := range prompts {
+		out.Prompts = append(out.Prompts, newPromptOutput(prompt))
+	}
+	return structuredResult(out, prompts), nil
+}
+
+// handleGetStatistics handles the get_statistics tool request
+func (m *MCPServer) handleGetStatistics(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	methods := client.MethodStats()
+	notifications := client.NotificationStats()
+	ping := client.PingStats()
+	limits := client.RateLimitStats()
+
+	out := statisticsOutput{
+		Methods: methods,
+		Notifications: notificationStatsOutput{
+			Received:           notifications.Received,
+			Dropped:            notifications.Dropped,
+			Queued:             notifications.Queued,
+			BufferSize:         notifications.BufferSize,
+			Policy:             string(notifications.Policy),
+			CoalescedRefreshes: client.SuppressedRefreshes(),
+		},
+		Pings: pingStatsOutput{
+			Sent:                ping.TotalPings,
+			Failed:              ping.TotalFailures,
+			ConsecutiveFailures: ping.ConsecutiveFailures,
+			LastRTTMs:           float64(ping.LastRTT) / float64(time.Millisecond),
+		},
+		RateLimits: rateLimitStatsOutput{
+			Limited:          limits.Limited,
+			LastRetryAfterMs: float64(limits.LastRetryAfter) / float64(time.Millisecond),
+			Limit:            limits.Limit,
+			Remaining:        limits.Remaining,
+			ThrottledMs:      float64(limits.Throttled) / float64(time.Millisecond),
+		},
+	}
+	if reset := time.Until(limits.Reset); reset > 0 {
+		out.RateLimits.ResetInSeconds = reset.Seconds()
+	}
+	if out.Methods == nil {
+		out.Methods = []MethodStats{}
+	}
+	if ping.LastError != nil {
+		out.Pings.LastError = ping.LastError.Error()
+	}
+	return structuredResult(out, methods), nil
+}
+
+// handleConnectionHealth handles the connection_health tool request. A
+// failed ping is reported in the result rather than as an error.
+func (m *MCPServer) handleConnectionHealth(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if request.GetBool("ping", true) {
+		_, _ = client.Ping(ctx)
+	}
+	out := newConnectionHealthOutput(client.Health())
+	return structuredResult(out, out), nil
+}
+
+// handleGetSessionLog handles the get_session_log tool request
+func (m *MCPServer) handleGetSessionLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if m.sessionLog == nil {
+		return mcp.NewToolResultError("the session log is not recorded"), nil
+	}
+
+	lines := request.GetInt("lines", 100)
+	if lines < 0 {
+		return mcp.NewToolResultError("'lines' must not be negative"), nil
+	}
+	out := sessionLogOutput{}
+	out.Lines, out.Total = m.sessionLog.Tail(lines, request.GetString("contains", ""))
+	return structuredResult(out, out), nil
+}
+
+// handleReconnect handles the reconnect tool request
+func (m *MCPServer) handleReconnect(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	before := client.snapshotSurface()
+	if err := client.Reconnect(ctx); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("reconnect failed: %v", err)), nil
+	}
+	after := client.snapshotSurface()
+
+	out := reconnectOutput{
+		Server:              client.ServerInfo(),
+		InstructionsChanged: before.instructions != after.instructions,
+	}
+	for _, diff := range diffSurfaces(before, after) {
+		out.Changes = append(out.Changes, newChangesOutput(diff))
+	}
+	return structuredResult(out, out), nil
+}
+
+// handleChangeEndpoint handles the change_endpoint tool request
+func (m *MCPServer) handleChangeEndpoint(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal prompts: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	endpoint := request.GetString("endpoint", "")
+	if endpoint == "" {
+		return mcp.NewToolResultError("missing or invalid 'endpoint' argument"), nil
+	}
+
+	previous := client.currentEndpoint()
+	before := client.snapshotSurface()
+	if err := client.ChangeEndpoint(ctx, endpoint); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to connect to %s: %v", endpoint, err)), nil
 	}
+	after := client.snapshotSurface()
 
-	return mcp.NewToolResultText(string(data)), nil
+	out := changeEndpointOutput{
+		PreviousEndpoint:    previous,
+		Endpoint:            endpoint,
+		Server:              client.ServerInfo(),
+		InstructionsChanged: before.instructions != after.instructions,
+	}
+	for _, diff := range diffSurfaces(before, after) {
+		out.Changes = append(out.Changes, newChangesOutput(diff))
+	}
+	return structuredResult(out, out), nil
 }
 
 // handleDescribeTool handles the describe_tool request
 func (m *MCPServer) handleDescribeTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get tool name from arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -66,32 +230,33 @@ func (m *MCPServer) handleDescribeTool(ctx context.Context, request mcp.CallTool
 		return mcp.NewToolResultError("missing or invalid 'name' argument"), nil
 	}
 
+	m.refreshStaleCaches(ctx, client)
+
 	// Find the tool
-	m.client.mu.RLock()
+	client.mu.RLock()
 	var tool *mcp.Tool
-	for _, t := range m.client.toolCache {
+	for _, t := range client.toolCache {
 		if t.Name == name {
 			tool = &t
 			break
 		}
 	}
-	m.client.mu.RUnlock()
+	client.mu.RUnlock()
 
 	if tool == nil {
 		return mcp.NewToolResultError(fmt.Sprintf("tool not found: %s", name)), nil
 	}
 
-	// Convert to JSON
-	data, err := json.Marshal(tool)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal tool: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(newToolOutput(*tool), tool), nil
 }
 
 // handleDescribeResource handles the describe_resource request
 func (m *MCPServer) handleDescribeResource(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get resource URI from arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -103,32 +268,33 @@ func (m *MCPServer) handleDescribeResource(ctx context.Context, request mcp.Call
 		return mcp.NewToolResultError("missing or invalid 'uri' argument"), nil
 	}
 
+	m.refreshStaleCaches(ctx, client)
+
 	// Find the resource
-	m.client.mu.RLock()
+	client.mu.RLock()
 	var resource *mcp.Resource
-	for _, r := range m.client.resourceCache {
+	for _, r := range client.resourceCache {
 		if r.URI == uri {
 			resource = &r
 			break
 		}
 	}
-	m.client.mu.RUnlock()
+	client.mu.RUnlock()
 
 	if resource == nil {
 		return mcp.NewToolResultError(fmt.Sprintf("resource not found: %s", uri)), nil
 	}
 
-	// Convert to JSON
-	data, err := json.Marshal(resource)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal resource: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(newResourceOutput(*resource), resource), nil
 }
 
 // handleDescribePrompt handles the describe_prompt request
 func (m *MCPServer) handleDescribePrompt(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get prompt name from arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -140,32 +306,33 @@ func (m *MCPServer) handleDescribePrompt(ctx context.Context, request mcp.CallTo
 		return mcp.NewToolResultError("missing or invalid 'name' argument"), nil
 	}
 
+	m.refreshStaleCaches(ctx, client)
+
 	// Find the prompt
-	m.client.mu.RLock()
+	client.mu.RLock()
 	var prompt *mcp.Prompt
-	for _, p := range m.client.promptCache {
+	for _, p := range client.promptCache {
 		if p.Name == name {
 			prompt = &p
 			break
 		}
 	}
-	m.client.mu.RUnlock()
+	client.mu.RUnlock()
 
 	if prompt == nil {
 		return mcp.NewToolResultError(fmt.Sprintf("prompt not found: %s", name)), nil
 	}
 
-	// Convert to JSON
-	data, err := json.Marshal(prompt)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal prompt: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(newPromptOutput(*prompt), prompt), nil
 }
 
 // handleCallTool handles the call_tool request
 func (m *MCPServer) handleCallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -183,23 +350,56 @@ func (m *MCPServer) handleCallTool(ctx context.Context, request mcp.CallToolRequ
 		toolArgs, _ = argValue.(map[string]interface{})
 	}
 
-	// Call the tool
-	result, err := m.client.CallTool(ctx, toolName, toolArgs)
+	// Call the tool, forwarding partial results if the caller asked for progress
+	result, err := client.CallToolStreaming(ctx, toolName, toolArgs, m.progressForwarder(ctx, request))
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("tool call failed: %v", err)), nil
 	}
 
-	// Convert result to JSON
-	data, err := json.Marshal(result)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	out := callToolOutput{
+		IsError:           result.IsError,
+		Content:           jsonObjects(result.Content),
+		StructuredContent: result.StructuredContent,
 	}
+	return structuredResult(out, result), nil
+}
 
-	return mcp.NewToolResultText(string(data)), nil
+// progressForwarder returns a handler that relays upstream progress to the
+// downstream client, or nil if the downstream request carried no progress token
+func (m *MCPServer) progressForwarder(ctx context.Context, request mcp.CallToolRequest) ProgressHandler {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+
+	token := request.Params.Meta.ProgressToken
+	return func(update ToolProgress) {
+		params := map[string]any{
+			"progressToken": token,
+			"progress":      update.Progress,
+		}
+		if update.Total > 0 {
+			params["total"] = update.Total
+		}
+		if update.Message != "" {
+			params["message"] = update.Message
+		}
+		if err := srv.SendNotificationToClient(ctx, string(mcp.MethodNotificationProgress), params); err != nil {
+			m.logger.Debug("Failed to forward progress: %v", err)
+		}
+	}
 }
 
 // handleGetResource handles the get_resource request
 func (m *MCPServer) handleGetResource(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get resource URI from arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -212,22 +412,21 @@ func (m *MCPServer) handleGetResource(ctx context.Context, request mcp.CallToolR
 	}
 
 	// Get the resource
-	result, err := m.client.GetResource(ctx, uri)
+	result, err := client.GetResource(ctx, uri)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("resource retrieval failed: %v", err)), nil
 	}
 
-	// Convert result to JSON
-	data, err := json.Marshal(result)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(data)), nil
+	return structuredResult(resourceContentsOutput{Contents: jsonObjects(result.Contents)}, result), nil
 }
 
 // handleGetPrompt handles the get_prompt request
 func (m *MCPServer) handleGetPrompt(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	client, err := m.upstream(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Get arguments
 	args, ok := request.Params.Arguments.(map[string]interface{})
 	if !ok {
@@ -250,16 +449,72 @@ func (m *MCPServer) handleGetPrompt(ctx context.Context, request mcp.CallToolReq
 	}
 
 	// Get the prompt
-	result, err := m.client.GetPrompt(ctx, promptName, promptArgs)
+	result, err := client.GetPrompt(ctx, promptName, promptArgs)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("prompt retrieval failed: %v", err)), nil
 	}
 
-	// Convert result to JSON
-	data, err := json.Marshal(result)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err)), nil
+	out := promptResultOutput{
+		Description: result.Description,
+		Messages:    jsonObjects(result.Messages),
+	}
+	return structuredResult(out, result), nil
+}
+
+// handleReadInstructions returns the upstream server's instructions
+func (m *MCPServer) handleReadInstructions(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      serverInstructionsURI,
+			MIMEType: "text/plain",
+			Text:     m.client.ServerInstructions(),
+		},
+	}, nil
+}
+
+// handleConfigureFaults changes the injected faults and reports them
+func (m *MCPServer) handleConfigureFaults(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, _ := m.faults.snapshot()
+	args := request.GetArguments()
+
+	if _, ok := args["latency"]; ok {
+		latency, err := time.ParseDuration(request.GetString("latency", ""))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid latency: %v", err)), nil
+		}
+		cfg.Latency = latency
+	}
+	rates := map[string]*float64{
+		"drop_notifications": &cfg.DropNotifications,
+		"malformed":          &cfg.Malformed,
+		"truncate":           &cfg.Truncate,
+		"http_errors":        &cfg.HTTPErrors,
+		"connection_reset":   &cfg.ConnectionReset,
+	}
+	for name, rate := range rates {
+		if _, ok := args[name]; ok {
+			*rate = request.GetFloat(name, *rate)
+		}
+	}
+	if _, ok := args["error_burst"]; ok {
+		cfg.ErrorBurst = request.GetInt("error_burst", cfg.ErrorBurst)
+	}
+	if _, ok := args["error_statuses"]; ok {
+		cfg.ErrorStatuses = request.GetIntSlice("error_statuses", cfg.ErrorStatuses)
+	}
+	if _, ok := args["seed"]; ok {
+		cfg.Seed = int64(request.GetInt("seed", int(cfg.Seed)))
+	}
+
+	if err := cfg.validate(); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(args) > 0 {
+		m.faults.configure(cfg.withDefaults())
+		m.logger.Warning("Fault injection reconfigured")
 	}
 
-	return mcp.NewToolResultText(string(data)), nil
+	cfg, injected := m.faults.snapshot()
+	out := newFaultsOutput(cfg, injected)
+	return structuredResult(out, out), nil
 }
@@ -0,0 +1,397 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The structured results of the server mode tools. Their output schemas are
+// derived from these types, so every field that may be absent is omitempty
+// and lists are never nil.
+
+// toolsOutput is the structured result of list_tools
+type toolsOutput struct {
+	Tools []toolOutput `json:"tools"`
+}
+
+// toolOutput describes one upstream tool
+type toolOutput struct {
+	Name         string         `json:"name"`
+	Title        string         `json:"title,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+	Annotations  map[string]any `json:"annotations,omitempty"`
+}
+
+// resourcesOutput is the structured result of list_resources
+type resourcesOutput struct {
+	Resources []resourceOutput `json:"resources"`
+}
+
+// resourceOutput describes one upstream resource
+type resourceOutput struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// promptsOutput is the structured result of list_prompts
+type promptsOutput struct {
+	Prompts []promptOutput `json:"prompts"`
+}
+
+// promptOutput describes one upstream prompt
+type promptOutput struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Arguments   []promptArgumentOutput `json:"arguments"`
+}
+
+// promptArgumentOutput describes one argument of a prompt
+type promptArgumentOutput struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// callToolOutput is the structured result of call_tool: the upstream
+// result, including tool errors, which are not failures of call_tool itself
+type callToolOutput struct {
+	IsError           bool             `json:"isError"`
+	Content           []map[string]any `json:"content"`
+	StructuredContent any              `json:"structuredContent,omitempty"`
+}
+
+// resourceContentsOutput is the structured result of get_resource
+type resourceContentsOutput struct {
+	Contents []map[string]any `json:"contents"`
+}
+
+// promptResultOutput is the structured result of get_prompt
+type promptResultOutput struct {
+	Description string           `json:"description,omitempty"`
+	Messages    []map[string]any `json:"messages"`
+}
+
+// statisticsOutput is the structured result of get_statistics
+type statisticsOutput struct {
+	Methods       []MethodStats           `json:"methods"`
+	Notifications notificationStatsOutput `json:"notifications"`
+	Pings         pingStatsOutput         `json:"pings"`
+	RateLimits    rateLimitStatsOutput    `json:"rateLimits"`
+}
+
+// notificationStatsOutput reports the notification buffer
+type notificationStatsOutput struct {
+	Received   int64  `json:"received"`
+	Dropped    int64  `json:"dropped"`
+	Queued     int    `json:"queued"`
+	BufferSize int    `json:"bufferSize"`
+	Policy     string `json:"policy"`
+	// CoalescedRefreshes counts list_changed refreshes saved by debouncing
+	CoalescedRefreshes int64 `json:"coalescedRefreshes"`
+}
+
+// pingStatsOutput reports the keepalive pings
+type pingStatsOutput struct {
+	Sent                int     `json:"sent"`
+	Failed              int     `json:"failed"`
+	ConsecutiveFailures int     `json:"consecutiveFailures"`
+	LastRTTMs           float64 `json:"lastRttMs"`
+	LastError           string  `json:"lastError,omitempty"`
+}
+
+// rateLimitStatsOutput reports the rate limiting announced by the server;
+// limit and remaining are -1 when it never sent them
+type rateLimitStatsOutput struct {
+	Limited          int     `json:"limited"`
+	LastRetryAfterMs float64 `json:"lastRetryAfterMs"`
+	Limit            int     `json:"limit"`
+	Remaining        int     `json:"remaining"`
+	ResetInSeconds   float64 `json:"resetInSeconds"`
+	ThrottledMs      float64 `json:"throttledMs"`
+}
+
+// connectionHealthOutput is the structured result of connection_health
+type connectionHealthOutput struct {
+	Status          string  `json:"status"`
+	UptimeSeconds   float64 `json:"uptimeSeconds"`
+	SessionSeconds  float64 `json:"sessionAgeSeconds"`
+	Reconnects      int     `json:"reconnects"`
+	PingIntervalMs  float64 `json:"pingIntervalMs"`
+	LastRTTMs       float64 `json:"lastPingRttMs"`
+	AvgRTTMs        float64 `json:"avgPingRttMs"`
+	LastPingError   string  `json:"lastPingError,omitempty"`
+	PingsSent       int     `json:"pingsSent"`
+	PingsFailed     int     `json:"pingsFailed"`
+	PendingRequests int     `json:"pendingRequests"`
+	QueuedRequests  int     `json:"queuedRequests"`
+}
+
+// newConnectionHealthOutput converts a health snapshot
+func newConnectionHealthOutput(health ConnectionHealth) connectionHealthOutput {
+	out := connectionHealthOutput{
+		Status:          string(health.Status),
+		UptimeSeconds:   health.Uptime.Seconds(),
+		SessionSeconds:  health.SessionAge.Seconds(),
+		Reconnects:      health.Reconnects,
+		PingIntervalMs:  float64(health.PingInterval) / float64(time.Millisecond),
+		LastRTTMs:       float64(health.Ping.LastRTT) / float64(time.Millisecond),
+		AvgRTTMs:        float64(health.Ping.AvgRTT) / float64(time.Millisecond),
+		PingsSent:       health.Ping.TotalPings,
+		PingsFailed:     health.Ping.TotalFailures,
+		PendingRequests: health.PendingRequests,
+		QueuedRequests:  health.QueuedRequests,
+	}
+	if health.Ping.LastError != nil {
+		out.LastPingError = health.Ping.LastError.Error()
+	}
+	return out
+}
+
+// upstreamsOutput is the structured result of list_upstreams and
+// disconnect_upstream
+type upstreamsOutput struct {
+	Upstreams []upstreamOutput `json:"upstreams"`
+}
+
+// upstreamOutput describes one upstream connection
+type upstreamOutput struct {
+	Name      string     `json:"name"`
+	Endpoint  string     `json:"endpoint"`
+	Transport string     `json:"transport"`
+	Server    ServerInfo `json:"server"`
+	Status    string     `json:"status"`
+}
+
+// newUpstreamOutput describes the named upstream connection
+func newUpstreamOutput(name string, client *Client) upstreamOutput {
+	return upstreamOutput{
+		Name:      name,
+		Endpoint:  client.currentEndpoint(),
+		Transport: client.transport,
+		Server:    client.ServerInfo(),
+		Status:    string(client.Health().Status),
+	}
+}
+
+// sessionLogOutput is the structured result of get_session_log
+type sessionLogOutput struct {
+	Lines []string `json:"lines"`
+	// Total is the number of lines logged since the server started,
+	// including those no longer kept
+	Total int `json:"total"`
+}
+
+// exchangeOutput is one request of the JSON-RPC log resource
+type exchangeOutput struct {
+	ID        string          `json:"id"`
+	RequestID string          `json:"requestId,omitempty"`
+	Method    string          `json:"method"`
+	Sent      time.Time       `json:"sent,omitzero"`
+	ElapsedMs float64         `json:"elapsedMs,omitempty"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// newExchangeOutput converts an exchange
+func newExchangeOutput(ex Exchange) exchangeOutput {
+	out := exchangeOutput{
+		ID:        ex.ID,
+		RequestID: ex.RequestID,
+		Method:    ex.Method,
+		Sent:      ex.Sent,
+		Request:   ex.Request,
+		Response:  ex.Response,
+		Error:     ex.Err,
+	}
+	if ex.Done {
+		out.ElapsedMs = float64(ex.Elapsed.Microseconds()) / 1000
+	}
+	return out
+}
+
+// historyEventOutput is one entry of the capability change resources
+type historyEventOutput struct {
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Name    string    `json:"name"`
+	Change  string    `json:"change"`
+	Fields  []string  `json:"fields,omitempty"`
+	Details []string  `json:"details,omitempty"`
+}
+
+// newHistoryEventOutput converts a capability history event
+func newHistoryEventOutput(kind string, event HistoryEvent) historyEventOutput {
+	return historyEventOutput{
+		Kind:    kind,
+		Time:    event.Time,
+		Name:    event.Name,
+		Change:  string(event.Change),
+		Fields:  event.Fields,
+		Details: event.Details,
+	}
+}
+
+// reconnectOutput is the structured result of reconnect
+type reconnectOutput struct {
+	Server              ServerInfo      `json:"server"`
+	InstructionsChanged bool            `json:"instructionsChanged"`
+	Changes             []changesOutput `json:"changes"`
+}
+
+// changeEndpointOutput is the structured result of change_endpoint
+type changeEndpointOutput struct {
+	PreviousEndpoint    string          `json:"previousEndpoint"`
+	Endpoint            string          `json:"endpoint"`
+	Server              ServerInfo      `json:"server"`
+	InstructionsChanged bool            `json:"instructionsChanged"`
+	Changes             []changesOutput `json:"changes"`
+}
+
+// changesOutput lists what changed for one kind of capability across a
+// reconnect
+type changesOutput struct {
+	Kind    string   `json:"kind"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// faultsOutput is the structured result of configure_faults
+type faultsOutput struct {
+	Latency           string  `json:"latency"`
+	DropNotifications float64 `json:"dropNotifications"`
+	Malformed         float64 `json:"malformed"`
+	Truncate          float64 `json:"truncate"`
+	HTTPErrors        float64 `json:"httpErrors"`
+	ErrorBurst        int     `json:"errorBurst"`
+	ErrorStatuses     []int   `json:"errorStatuses"`
+	ConnectionReset   float64 `json:"connectionReset"`
+	Seed              int64   `json:"seed"`
+	// Injected counts the injected faults by kind since the server started
+	Injected faultCountsOutput `json:"injected"`
+}
+
+// faultCountsOutput counts the injected faults
+type faultCountsOutput struct {
+	Latency              int `json:"latency"`
+	DroppedNotifications int `json:"droppedNotifications"`
+	Malformed            int `json:"malformed"`
+	Truncated            int `json:"truncated"`
+	HTTPErrors           int `json:"httpErrors"`
+	ConnectionResets     int `json:"connectionResets"`
+}
+
+// structuredResult returns a result carrying structured content, with text
+// as the JSON encoding of legacy: the plain-text result of earlier versions
+// for existing tools, so clients that parse the text keep working
+func structuredResult(structured, legacy any) *mcp.CallToolResult {
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal result: %v", err))
+	}
+	return mcp.NewToolResultStructured(structured, string(data))
+}
+
+// jsonObjects re-encodes a list of MCP values, e.g. content items, as
+// generic JSON objects; never nil
+func jsonObjects(v any) []map[string]any {
+	objects := []map[string]any{}
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &objects)
+	}
+	if objects == nil {
+		objects = []map[string]any{}
+	}
+	return objects
+}
+
+// newToolOutput describes a tool, with the raw schemas it was listed with
+func newToolOutput(tool mcp.Tool) toolOutput {
+	definition := toolDefinition(tool)
+	out := toolOutput{
+		Name:         tool.Name,
+		Description:  tool.Description,
+		InputSchema:  schemaObject(definition["inputSchema"]),
+		OutputSchema: schemaObject(definition["outputSchema"]),
+		Annotations:  schemaObject(definition["annotations"]),
+	}
+	out.Title, _ = definition["title"].(string)
+	if out.InputSchema == nil {
+		out.InputSchema = map[string]any{"type": "object"}
+	}
+	return out
+}
+
+// newResourceOutput describes a resource
+func newResourceOutput(resource mcp.Resource) resourceOutput {
+	return resourceOutput{
+		URI:         resource.URI,
+		Name:        resource.Name,
+		Description: resource.Description,
+		MIMEType:    resource.MIMEType,
+	}
+}
+
+// newPromptOutput describes a prompt
+func newPromptOutput(prompt mcp.Prompt) promptOutput {
+	out := promptOutput{
+		Name:        prompt.Name,
+		Description: prompt.Description,
+		Arguments:   make([]promptArgumentOutput, 0, len(prompt.Arguments)),
+	}
+	for _, arg := range prompt.Arguments {
+		out.Arguments = append(out.Arguments, promptArgumentOutput{
+			Name:        arg.Name,
+			Description: arg.Description,
+			Required:    arg.Required,
+		})
+	}
+	return out
+}
+
+// newChangesOutput converts a reconnect diff
+func newChangesOutput(diff surfaceDiff) changesOutput {
+	nonNil := func(names []string) []string {
+		if names == nil {
+			return []string{}
+		}
+		return names
+	}
+	return changesOutput{
+		Kind:    diff.kind,
+		Added:   nonNil(diff.added),
+		Removed: nonNil(diff.removed),
+		Changed: nonNil(diff.changed),
+	}
+}
+
+// newFaultsOutput reports the injected faults
+func newFaultsOutput(cfg FaultConfig, injected map[string]int) faultsOutput {
+	return faultsOutput{
+		Latency:           cfg.Latency.String(),
+		DropNotifications: cfg.DropNotifications,
+		Malformed:         cfg.Malformed,
+		Truncate:          cfg.Truncate,
+		HTTPErrors:        cfg.HTTPErrors,
+		ErrorBurst:        cfg.ErrorBurst,
+		ErrorStatuses:     append([]int{}, cfg.ErrorStatuses...),
+		ConnectionReset:   cfg.ConnectionReset,
+		Seed:              cfg.Seed,
+		Injected: faultCountsOutput{
+			Latency:              injected[faultLatency],
+			DroppedNotifications: injected[faultDroppedNotification],
+			Malformed:            injected[faultMalformed],
+			Truncated:            injected[faultTruncated],
+			HTTPErrors:           injected[faultHTTPError],
+			ConnectionResets:     injected[faultConnectionReset],
+		},
+	}
+}
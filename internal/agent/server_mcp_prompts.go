@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Limits of the context the prompts pre-fill
+const (
+	// maxPromptLogLines is the number of log lines a prompt includes
+	maxPromptLogLines = 50
+	// maxPromptExchanges is the number of exchanges a prompt includes
+	maxPromptExchanges = 10
+	// maxPromptPayloadBytes truncates each request and response
+	maxPromptPayloadBytes = 2000
+)
+
+// registerPrompts registers the prompts guiding an assistant through
+// common debugging workflows, each pre-filled with the captured context
+func (m *MCPServer) registerPrompts() {
+	m.mcpServer.AddPrompt(mcp.NewPrompt("diagnose-auth-failure",
+		mcp.WithPromptDescription("Diagnose why authenticating with the connected MCP server fails, given the OAuth log and the failed requests"),
+		mcp.WithArgument("symptom",
+			mcp.ArgumentDescription("What went wrong, e.g. the error message or the status code seen"),
+		),
+	), m.handleDiagnoseAuthFailure)
+
+	m.mcpServer.AddPrompt(mcp.NewPrompt("summarize-tool-changes",
+		mcp.WithPromptDescription("Summarize how the connected server's tools changed during the session and whether the changes break callers"),
+	), m.handleSummarizeToolChanges)
+
+	m.mcpServer.AddPrompt(mcp.NewPrompt("investigate-tool-error",
+		mcp.WithPromptDescription("Investigate failed tool calls, given their requests and responses and the tool's definition"),
+		mcp.WithArgument("tool",
+			mcp.ArgumentDescription("Name of the tool whose calls failed; all tools if omitted"),
+		),
+	), m.handleInvestigateToolError)
+}
+
+// handleDiagnoseAuthFailure pre-fills the OAuth configuration, the OAuth log
+// lines and the failed requests
+func (m *MCPServer) handleDiagnoseAuthFailure(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var b strings.Builder
+	b.WriteString("Diagnose why authentication with an MCP server fails. Work through the MCP authorization flow in order: protected resource metadata (RFC 9728), authorization server metadata (RFC 8414), client registration, the authorization request with PKCE and the resource parameter (RFC 8707), the token exchange, and finally the bearer token on MCP requests. Name the first step that fails, explain the likely cause and suggest a fix, citing the log lines and responses below.\n")
+	if symptom := request.Params.Arguments["symptom"]; symptom != "" {
+		fmt.Fprintf(&b, "\nReported symptom: %s\n", symptom)
+	}
+	m.writeConnectionContext(&b)
+	fmt.Fprintf(&b, "OAuth enabled: %t\n", m.client.OAuthEnabled())
+
+	m.writeLogContext(&b, "OAuth log", func(line string) bool { return oauthLogLine.MatchString(line) })
+	writeExchangeContext(&b, "Failed requests", m.client.RecentExchanges(maxExchanges), exchangeFailed)
+	return workflowPrompt("Diagnose an authentication failure", b.String()), nil
+}
+
+// handleSummarizeToolChanges pre-fills the recorded tool changes and the
+// current tools
+func (m *MCPServer) handleSummarizeToolChanges(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var b strings.Builder
+	b.WriteString("Summarize how the tools of an MCP server changed during this debugging session. Group the changes into breaking changes (removed tools, new required arguments, changed argument types) and compatible ones, and for each breaking change say what a caller has to adapt. If nothing changed, say so.\n")
+	m.writeConnectionContext(&b)
+
+	snapshots, since, events := m.client.CapabilityHistory(historyTools)
+	fmt.Fprintf(&b, "\n## Tool changes\n\n")
+	if snapshots == 0 {
+		b.WriteString("The tool list has not been recorded yet.\n")
+	} else {
+		fmt.Fprintf(&b, "%d distinct tool list(s) recorded since %s.\n\n", snapshots, since.Format("2006-01-02 15:04:05"))
+		if len(events) == 0 {
+			b.WriteString("No changes.\n")
+		}
+		for _, event := range events {
+			fmt.Fprintf(&b, "- %s %s %s", event.Time.Format("15:04:05"), event.Name, event.Change)
+			if len(event.Fields) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(event.Fields, ", "))
+			}
+			b.WriteString("\n")
+			for _, detail := range event.Details {
+				fmt.Fprintf(&b, "  %s\n", detail)
+			}
+		}
+	}
+
+	m.client.mu.RLock()
+	names := make([]string, 0, len(m.client.toolCache))
+	for _, tool := range m.client.toolCache {
+		names = append(names, tool.Name)
+	}
+	m.client.mu.RUnlock()
+	slices.Sort(names)
+	fmt.Fprintf(&b, "\n## Current tools\n\n%s\n", orNone(strings.Join(names, ", ")))
+	return workflowPrompt("Summarize the tool changes", b.String()), nil
+}
+
+// handleInvestigateToolError pre-fills the failed tool calls and the
+// definition of the tool
+func (m *MCPServer) handleInvestigateToolError(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	tool := request.Params.Arguments["tool"]
+
+	var b strings.Builder
+	b.WriteString("Investigate why calls to an MCP tool fail. For each failed call below, decide whether the arguments violate the tool's input schema, the tool reported an error in its result (isError), or the server returned a JSON-RPC or transport error. Explain the cause and suggest corrected arguments or a server-side fix.\n")
+	m.writeConnectionContext(&b)
+
+	if tool != "" {
+		b.WriteString("\n## Tool definition\n\n")
+		m.client.mu.RLock()
+		index := slices.IndexFunc(m.client.toolCache, func(t mcp.Tool) bool { return t.Name == tool })
+		var definition string
+		if index >= 0 {
+			definition = PrettyJSON(toolDefinition(m.client.toolCache[index]))
+		}
+		m.client.mu.RUnlock()
+		if definition == "" {
+			fmt.Fprintf(&b, "The server does not list a tool named %s.\n", tool)
+		} else {
+			fmt.Fprintf(&b, "```json\n%s\n```\n", definition)
+		}
+	}
+
+	writeExchangeContext(&b, "Failed tool calls", m.client.RecentExchanges(maxExchanges), func(ex Exchange) bool {
+		return ex.Method == "tools/call" && (tool == "" || exchangeToolName(ex) == tool) && (exchangeFailed(ex) || toolCallFailed(ex))
+	})
+	return workflowPrompt("Investigate failed tool calls", b.String()), nil
+}
+
+// writeConnectionContext writes the endpoint and identity of the server
+func (m *MCPServer) writeConnectionContext(b *strings.Builder) {
+	info := m.client.ServerInfo()
+	fmt.Fprintf(b, "\n## Connection\n\nEndpoint: %s\nTransport: %s\nServer: %s %s (protocol %s)\n",
+		m.client.currentEndpoint(), m.client.transport, orNone(info.Name), info.Version, orNone(info.ProtocolVersion))
+}
+
+// writeLogContext writes the latest session log lines that match
+func (m *MCPServer) writeLogContext(b *strings.Builder, title string, match func(string) bool) {
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	if m.sessionLog == nil {
+		b.WriteString("The session log is not recorded.\n")
+		return
+	}
+	lines, _ := m.sessionLog.Tail(0, "")
+	lines = slices.DeleteFunc(lines, func(line string) bool { return !match(line) })
+	if len(lines) > maxPromptLogLines {
+		lines = lines[len(lines)-maxPromptLogLines:]
+	}
+	if len(lines) == 0 {
+		b.WriteString("No matching lines.\n")
+		return
+	}
+	fmt.Fprintf(b, "```\n%s\n```\n", strings.Join(lines, "\n"))
+}
+
+// writeExchangeContext writes the latest exchanges that match with their
+// requests and responses
+func writeExchangeContext(b *strings.Builder, title string, exchanges []Exchange, match func(Exchange) bool) {
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	exchanges = slices.DeleteFunc(exchanges, func(ex Exchange) bool { return !match(ex) })
+	if len(exchanges) > maxPromptExchanges {
+		exchanges = exchanges[len(exchanges)-maxPromptExchanges:]
+	}
+	if len(exchanges) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	for _, ex := range exchanges {
+		fmt.Fprintf(b, "### %s #%s (%s)\n\n", ex.Method, ex.ID, ex.Sent.Format("15:04:05"))
+		if len(ex.Request) > 0 {
+			fmt.Fprintf(b, "Request:\n```json\n%s\n```\n", truncatePayload(ex.Request))
+		}
+		if len(ex.Response) > 0 {
+			fmt.Fprintf(b, "Response:\n```json\n%s\n```\n", truncatePayload(ex.Response))
+		}
+		if ex.Err != "" {
+			fmt.Fprintf(b, "Transport error: %s\n", ex.Err)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// exchangeFailed reports whether a request failed in transport or with a
+// JSON-RPC error
+func exchangeFailed(ex Exchange) bool {
+	if ex.Err != "" {
+		return true
+	}
+	var response struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal(ex.Response, &response) == nil && len(response.Error) > 0
+}
+
+// toolCallFailed reports whether a tools/call response is a result with
+// isError set
+func toolCallFailed(ex Exchange) bool {
+	var response struct {
+		Result struct {
+			IsError bool `json:"isError"`
+		} `json:"result"`
+	}
+	return json.Unmarshal(ex.Response, &response) == nil && response.Result.IsError
+}
+
+// exchangeToolName returns the tool a tools/call request called
+func exchangeToolName(ex Exchange) string {
+	var request struct {
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	_ = json.Unmarshal(ex.Request, &request)
+	return request.Params.Name
+}
+
+// truncatePayload shortens a request or response to maxPromptPayloadBytes
+func truncatePayload(payload json.RawMessage) string {
+	if len(payload) <= maxPromptPayloadBytes {
+		return string(payload)
+	}
+	return fmt.Sprintf("%s… (truncated, %d bytes total)", payload[:maxPromptPayloadBytes], len(payload))
+}
+
+// workflowPrompt returns a prompt result with a single user message
+func workflowPrompt(description, text string) *mcp.GetPromptResult {
+	return mcp.NewGetPromptResult(description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	})
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestMCPServerPrompts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	upstreamServer := server.NewMCPServer("upstream", "1.0.0", server.WithToolCapabilities(false))
+	upstreamServer.AddTool(mcp.NewTool("deploy", mcp.WithString("target", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("unknown target " + req.GetString("target", "")), nil
+		})
+	ts := server.NewTestStreamableHTTPServer(upstreamServer)
+	defer ts.Close()
+
+	sessionLog := NewSessionLog(100)
+	logger := NewLoggerWithWriter(false, false, false, sessionLog)
+	upstream := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := upstream.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = upstream.Close() }()
+	if _, err := upstream.CallTool(ctx, "deploy", map[string]any{"target": "moon"}); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	logger.Warning("OAuth token refresh failed: invalid_grant")
+
+	ms, err := NewMCPServer(upstream, "stdio", logger, sessionLog, false)
+	if err != nil {
+		t.Fatalf("NewMCPServer: %v", err)
+	}
+	downstream, err := client.NewInProcessClient(ms.mcpServer)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer func() { _ = downstream.Close() }()
+	if _, err := downstream.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	listed, err := downstream.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(listed.Prompts) != 3 {
+		t.Errorf("expected 3 prompts, got %+v", listed.Prompts)
+	}
+
+	for _, tt := range []struct {
+		prompt string
+		args   map[string]string
+		want   []string
+	}{
+		{"diagnose-auth-failure", map[string]string{"symptom": "401 on tools/list"}, []string{
+			"Reported symptom: 401 on tools/list", "OAuth enabled: false", "OAuth token refresh failed: invalid_grant",
+		}},
+		{"summarize-tool-changes", nil, []string{"1 distinct tool list(s) recorded", "No changes.", "## Current tools\n\ndeploy"}},
+		{"investigate-tool-error", map[string]string{"tool": "deploy"}, []string{
+			`"name": "deploy"`, "### tools/call #", `unknown target moon`,
+		}},
+		{"investigate-tool-error", map[string]string{"tool": "missing"}, []string{
+			"does not list a tool named missing", "## Failed tool calls\n\nNone.",
+		}},
+	} {
+		req := mcp.GetPromptRequest{}
+		req.Params.Name = tt.prompt
+		req.Params.Arguments = tt.args
+		result, err := downstream.GetPrompt(ctx, req)
+		if err != nil {
+			t.Fatalf("GetPrompt %s: %v", tt.prompt, err)
+		}
+		text := result.Messages[0].Content.(mcp.TextContent).Text
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s %v lacks %q:\n%s", tt.prompt, tt.args, want, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// oauthLogLine matches the session log lines about OAuth: discovery,
+// authorization, tokens and scopes
+var oauthLogLine = regexp.MustCompile(`(?i)oauth|authoriz|token|scope|pkce|resource metadata`)
+
+// registerHistoryResources registers the resources serving the session
+// log, the JSON-RPC traffic, the OAuth events and the capability changes.
+// Each capability change recorded from now on is added as a resource of its
+// own, which notifies clients with resources/list_changed.
+func (m *MCPServer) registerHistoryResources() {
+	if m.sessionLog != nil {
+		m.mcpServer.AddResource(mcp.NewResource(sessionLogURI, "Session log",
+			mcp.WithResourceDescription("The latest lines of the debugging session's log, oldest first; includes the JSON-RPC traffic when --verbose is set"),
+			mcp.WithMIMEType("text/plain"),
+		), m.handleReadSessionLog)
+		m.mcpServer.AddResource(mcp.NewResource(oauthLogURI, "OAuth events",
+			mcp.WithResourceDescription("The session log lines about OAuth discovery, authorization, tokens and scopes"),
+			mcp.WithMIMEType("text/plain"),
+		), m.handleReadSessionLog)
+	}
+	m.mcpServer.AddResource(mcp.NewResource(jsonRPCLogURI, "JSON-RPC log",
+		mcp.WithResourceDescription("The latest JSON-RPC requests sent to the connected server with their raw responses, oldest first"),
+		mcp.WithMIMEType("application/json"),
+	), m.handleReadJSONRPCLog)
+	m.mcpServer.AddResource(mcp.NewResource(capabilityChangesURI, "Capability changes",
+		mcp.WithResourceDescription("Every tool, resource and prompt of the connected server that appeared, disappeared or changed, oldest first"),
+		mcp.WithMIMEType("application/json"),
+	), m.handleReadCapabilityChanges)
+
+	if m.client != nil && m.client.history != nil {
+		m.client.history.setOnChange(m.addCapabilityChange)
+	}
+}
+
+// handleReadSessionLog returns the kept lines of the session log, or only
+// those about OAuth
+func (m *MCPServer) handleReadSessionLog(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	lines, _ := m.sessionLog.Tail(0, "")
+	if request.Params.URI == oauthLogURI {
+		lines = slices.DeleteFunc(lines, func(line string) bool { return !oauthLogLine.MatchString(line) })
+	}
+	text := strings.Join(lines, "\n")
+	if text != "" {
+		text += "\n"
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/plain", Text: text},
+	}, nil
+}
+
+// handleReadJSONRPCLog returns the latest exchanges with the connected
+// server
+func (m *MCPServer) handleReadJSONRPCLog(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	exchanges := m.client.RecentExchanges(maxExchanges)
+	out := make([]exchangeOutput, 0, len(exchanges))
+	for _, ex := range exchanges {
+		out = append(out, newExchangeOutput(ex))
+	}
+	return jsonResourceContents(jsonRPCLogURI, out)
+}
+
+// handleReadCapabilityChanges returns every recorded capability change
+func (m *MCPServer) handleReadCapabilityChanges(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	out := []historyEventOutput{}
+	for _, kind := range []string{historyTools, historyResources, historyPrompts} {
+		_, _, events := m.client.CapabilityHistory(kind)
+		for _, event := range events {
+			out = append(out, newHistoryEventOutput(kind, event))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b historyEventOutput) int { return a.Time.Compare(b.Time) })
+	return jsonResourceContents(capabilityChangesURI, out)
+}
+
+// addCapabilityChange serves the events of one capability change under a
+// new resource, dropping the oldest beyond maxHistorySnapshots
+func (m *MCPServer) addCapabilityChange(kind string, events []HistoryEvent) {
+	if len(events) == 0 {
+		return
+	}
+	out := make([]historyEventOutput, 0, len(events))
+	for _, event := range events {
+		out = append(out, newHistoryEventOutput(kind, event))
+	}
+
+	m.changesMu.Lock()
+	m.changes++
+	uri := fmt.Sprintf("%s/%d", capabilityChangesURI, m.changes)
+	// Keep as many changes as the history keeps snapshots
+	if dropped := m.changes - maxHistorySnapshots; dropped > 0 {
+		m.mcpServer.DeleteResources(fmt.Sprintf("%s/%d", capabilityChangesURI, dropped))
+	}
+	m.changesMu.Unlock()
+
+	resource := mcp.NewResource(uri, fmt.Sprintf("Capability change: %d %s", len(events), kind),
+		mcp.WithResourceDescription(fmt.Sprintf("The %s of the connected server that changed at %s", kind, events[0].Time.Format(time.RFC3339))),
+		mcp.WithMIMEType("application/json"),
+	)
+	m.mcpServer.AddResource(resource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return jsonResourceContents(uri, out)
+	})
+}
+
+// jsonResourceContents encodes v as the JSON contents of a resource
+func jsonResourceContents(uri string, v any) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", uri, err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// readTextResource reads a resource and returns its text
+func readTextResource(t *testing.T, ctx context.Context, c *client.Client, uri string) string {
+	t.Helper()
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uri
+	result, err := c.ReadResource(ctx, req)
+	if err != nil {
+		t.Fatalf("ReadResource %s: %v", uri, err)
+	}
+	text, ok := result.Contents[0].(mcp.TextResourceContents)
+	if !ok {
+		t.Fatalf("%s has no text contents: %+v", uri, result.Contents)
+	}
+	return text.Text
+}
+
+func TestMCPServerHistoryResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	upstreamServer := server.NewMCPServer("upstream", "1.0.0", server.WithToolCapabilities(false))
+	upstreamServer.AddTool(mcp.NewTool("echo"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(upstreamServer)
+	defer ts.Close()
+
+	sessionLog := NewSessionLog(100)
+	logger := NewLoggerWithWriter(false, false, false, sessionLog)
+	upstream := NewClient(ClientConfig{
+		Endpoint:  ts.URL + "/mcp",
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := upstream.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = upstream.Close() }()
+	logger.Info("OAuth authentication enabled")
+
+	ms, err := NewMCPServer(upstream, "stdio", logger, sessionLog, false)
+	if err != nil {
+		t.Fatalf("NewMCPServer: %v", err)
+	}
+	// The in-process transport does not deliver notifications
+	downstreamServer := server.NewTestStreamableHTTPServer(ms.mcpServer)
+	defer downstreamServer.Close()
+	downstream, err := client.NewStreamableHttpClient(downstreamServer.URL+"/mcp", transport.WithContinuousListening())
+	if err != nil {
+		t.Fatalf("NewStreamableHttpClient: %v", err)
+	}
+	defer func() { _ = downstream.Close() }()
+	listChanged := make(chan struct{}, 1)
+	downstream.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationResourcesListChanged {
+			select {
+			case listChanged <- struct{}{}:
+			default:
+			}
+		}
+	})
+	if err := downstream.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := downstream.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	if text := readTextResource(t, ctx, downstream, sessionLogURI); !strings.Contains(text, "Connecting to MCP server") {
+		t.Errorf("session log lacks the connection:\n%s", text)
+	}
+	if text := readTextResource(t, ctx, downstream, oauthLogURI); strings.TrimSpace(text) == "" || strings.Contains(text, "Connecting to MCP server") {
+		t.Errorf("OAuth log should only hold the OAuth line:\n%s", text)
+	}
+	if text := readTextResource(t, ctx, downstream, jsonRPCLogURI); !strings.Contains(text, `"method": "tools/list"`) || !strings.Contains(text, `"response"`) {
+		t.Errorf("JSON-RPC log lacks tools/list:\n%s", text)
+	}
+	if text := readTextResource(t, ctx, downstream, capabilityChangesURI); text != "[]" {
+		t.Errorf("expected no capability changes yet, got %s", text)
+	}
+
+	upstreamServer.AddTool(mcp.NewTool("deploy"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if err := upstream.RefreshCache(ctx, cacheTools); err != nil {
+		t.Fatalf("RefreshCache: %v", err)
+	}
+	select {
+	case <-listChanged:
+	case <-time.After(testTimeoutLong):
+		t.Fatal("no resources/list_changed notification")
+	}
+
+	listed, err := downstream.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	found := false
+	for _, resource := range listed.Resources {
+		found = found || resource.URI == capabilityChangesURI+"/1"
+	}
+	if !found {
+		t.Fatalf("change resource not listed: %+v", listed.Resources)
+	}
+	for _, uri := range []string{capabilityChangesURI + "/1", capabilityChangesURI} {
+		if text := readTextResource(t, ctx, downstream, uri); !strings.Contains(text, `"name": "deploy"`) || !strings.Contains(text, `"change": "appeared"`) {
+			t.Errorf("%s lacks the new tool:\n%s", uri, text)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newUpstreamServer starts a server with a tool, a resource and a prompt
+func newUpstreamServer(t *testing.T) string {
+	t.Helper()
+
+	upstream := server.NewMCPServer("upstream", "1.2.3",
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+	)
+	upstream.AddTool(mcp.NewTool("echo", mcp.WithString("message", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(req.GetString("message", "")), nil
+		})
+	upstream.AddResource(mcp.NewResource("docs://readme", "readme", mcp.WithMIMEType("text/plain")),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: "docs://readme", MIMEType: "text/plain", Text: "hello"}}, nil
+		})
+	upstream.AddPrompt(mcp.NewPrompt("greet", mcp.WithArgument("name", mcp.RequiredArgument())),
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult("greeting", []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Hello "+req.Params.Arguments["name"])),
+			}), nil
+		})
+
+	ts := server.NewTestStreamableHTTPServer(upstream)
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func TestMCPServerStructuredOutput(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	sessionLog := NewSessionLog(10)
+	logger := NewLoggerWithWriter(false, false, false, sessionLog)
+	upstream := NewClient(ClientConfig{
+		Endpoint:  newUpstreamServer(t),
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := upstream.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = upstream.Close() }()
+
+	ms, err := NewMCPServer(upstream, "stdio", logger, sessionLog, false)
+	if err != nil {
+		t.Fatalf("NewMCPServer: %v", err)
+	}
+	downstream, err := client.NewInProcessClient(ms.mcpServer)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer func() { _ = downstream.Close() }()
+	if _, err := downstream.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	listed, err := downstream.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	tools := make(map[string]mcp.Tool)
+	for _, tool := range listed.Tools {
+		tools[tool.Name] = tool
+	}
+
+	calls := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"list_tools", nil, `"name":"echo"`},
+		{"list_resources", nil, `"uri":"docs://readme"`},
+		{"list_prompts", nil, `"required":true`},
+		{"describe_tool", map[string]any{"name": "echo"}, `"required":["message"]`},
+		{"describe_resource", map[string]any{"uri": "docs://readme"}, `"mimeType":"text/plain"`},
+		{"describe_prompt", map[string]any{"name": "greet"}, `"name":"greet"`},
+		{"call_tool", map[string]any{"name": "echo", "arguments": map[string]any{"message": "hi"}}, `"text":"hi"`},
+		{"get_resource", map[string]any{"uri": "docs://readme"}, `"text":"hello"`},
+		{"get_prompt", map[string]any{"name": "greet", "arguments": map[string]any{"name": "Ada"}}, `Hello Ada`},
+		{"get_statistics", nil, `"method":"tools/call"`},
+		{"get_session_log", map[string]any{"lines": 1}, `"lines":[`},
+		{"connection_health", nil, `"status":"healthy"`},
+		{"reconnect", nil, `"name":"upstream"`},
+	}
+
+	var validator schemaValidator
+	for _, call := range calls {
+		t.Run(call.tool, func(t *testing.T) {
+			tool, ok := tools[call.tool]
+			if !ok {
+				t.Fatalf("tool %s is not listed", call.tool)
+			}
+			schemaJSON, ok := outputSchemaJSON(tool)
+			if !ok {
+				t.Fatalf("tool %s declares no output schema", call.tool)
+			}
+
+			req := mcp.CallToolRequest{}
+			req.Params.Name = call.tool
+			req.Params.Arguments = call.args
+			result, err := downstream.CallTool(ctx, req)
+			if err != nil {
+				t.Fatalf("CallTool: %v", err)
+			}
+			if result.IsError || result.StructuredContent == nil {
+				t.Fatalf("expected a structured result, got %+v", result)
+			}
+
+			violations, err := validator.validate(schemaJSON, result.StructuredContent)
+			if err != nil || len(violations) > 0 {
+				t.Errorf("structured content does not match the output schema: %v %v", violations, err)
+			}
+			data, _ := json.Marshal(result.StructuredContent)
+			if !strings.Contains(string(data), call.want) {
+				t.Errorf("expected %s in %s", call.want, data)
+			}
+		})
+	}
+}
+
+func TestSessionLog(t *testing.T) {
+	log := NewSessionLog(3)
+	_, _ = log.Write([]byte("one\n\x1b[31mtwo\x1b[0m\nthr"))
+	_, _ = log.Write([]byte("ee\nfour\n"))
+
+	lines, total := log.Tail(0, "")
+	if total != 4 || strings.Join(lines, ",") != "two,three,four" {
+		t.Errorf("Tail = %q (total %d)", lines, total)
+	}
+	if lines, _ := log.Tail(2, ""); strings.Join(lines, ",") != "three,four" {
+		t.Errorf("Tail(2) = %q", lines)
+	}
+	if lines, _ := log.Tail(0, "o"); strings.Join(lines, ",") != "two,four" {
+		t.Errorf("Tail with filter = %q", lines)
+	}
+}
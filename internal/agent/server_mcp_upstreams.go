@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// upstreamArgument is the optional argument naming the upstream connection
+// a tool works on
+const upstreamArgument = "upstream"
+
+// withUpstream adds the upstream argument to a tool that works on one
+// upstream server
+func withUpstream() mcp.ToolOption {
+	return mcp.WithString(upstreamArgument,
+		mcp.Description(fmt.Sprintf("Name of the upstream connection to use, see list_upstreams (default %q, the server mcp-debug was started with)", DefaultConnectionName)),
+	)
+}
+
+// registerUpstreamTools registers the tools that open, list and close
+// additional upstream connections
+func (m *MCPServer) registerUpstreamTools() {
+	connectUpstreamTool := mcp.NewTool("connect_upstream",
+		mcp.WithDescription("Connect to another MCP server under a name, with the settings mcp-debug was started with. Pass the name as 'upstream' to the other tools to work on it."),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the new upstream connection"),
+		),
+		mcp.WithString("endpoint",
+			mcp.Required(),
+			mcp.Description("http or https URL of the MCP server"),
+		),
+		mcp.WithOutputSchema[upstreamOutput](),
+	)
+	m.mcpServer.AddTool(connectUpstreamTool, m.handleConnectUpstream)
+
+	listUpstreamsTool := mcp.NewTool("list_upstreams",
+		mcp.WithDescription("List the upstream connections with their endpoint, server and health"),
+		mcp.WithOutputSchema[upstreamsOutput](),
+	)
+	m.mcpServer.AddTool(listUpstreamsTool, m.handleListUpstreams)
+
+	disconnectUpstreamTool := mcp.NewTool("disconnect_upstream",
+		mcp.WithDescription("Close an upstream connection opened with connect_upstream"),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name of the upstream connection to close"),
+		),
+		mcp.WithOutputSchema[upstreamsOutput](),
+	)
+	m.mcpServer.AddTool(disconnectUpstreamTool, m.handleDisconnectUpstream)
+}
+
+// upstream returns the client of the upstream connection a tool request
+// names, or the primary client if it names none
+func (m *MCPServer) upstream(request mcp.CallToolRequest) (*Client, error) {
+	name := request.GetString(upstreamArgument, "")
+	if name == "" || name == DefaultConnectionName {
+		return m.client, nil
+	}
+	if m.upstreams != nil {
+		if client := m.upstreams.Get(name); client != nil {
+			return client, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown upstream: %s", name)
+}
+
+// handleConnectUpstream handles the connect_upstream tool request. The
+// connection outlives the request and is closed by disconnect_upstream or
+// when the server stops.
+func (m *MCPServer) handleConnectUpstream(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := request.GetString("name", "")
+	if name == "" {
+		return mcp.NewToolResultError("missing or invalid 'name' argument"), nil
+	}
+	endpoint := request.GetString("endpoint", "")
+	if endpoint == "" {
+		return mcp.NewToolResultError("missing or invalid 'endpoint' argument"), nil
+	}
+	if m.upstreams == nil {
+		return mcp.NewToolResultError("no upstream connections are managed"), nil
+	}
+
+	client, err := m.upstreams.Connect(context.WithoutCancel(ctx), name, endpoint)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to connect %s: %v", name, err)), nil
+	}
+	m.logger.Info("Connected upstream %s to %s", name, endpoint)
+
+	out := newUpstreamOutput(name, client)
+	return structuredResult(out, out), nil
+}
+
+// handleListUpstreams handles the list_upstreams tool request
+func (m *MCPServer) handleListUpstreams(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	out := m.upstreamsOutput()
+	return structuredResult(out, out), nil
+}
+
+// handleDisconnectUpstream handles the disconnect_upstream tool request and
+// reports the remaining connections
+func (m *MCPServer) handleDisconnectUpstream(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := request.GetString("name", "")
+	if name == "" {
+		return mcp.NewToolResultError("missing or invalid 'name' argument"), nil
+	}
+	if m.upstreams == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown upstream: %s", name)), nil
+	}
+	if err := m.upstreams.Disconnect(name); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	m.logger.Info("Disconnected upstream %s", name)
+
+	out := m.upstreamsOutput()
+	return structuredResult(out, out), nil
+}
+
+// upstreamsOutput describes the established upstream connections, sorted
+// by name
+func (m *MCPServer) upstreamsOutput() upstreamsOutput {
+	out := upstreamsOutput{Upstreams: []upstreamOutput{}}
+	if m.upstreams == nil {
+		return out
+	}
+	for _, name := range m.upstreams.Names() {
+		if client := m.upstreams.Get(name); client != nil {
+			out.Upstreams = append(out.Upstreams, newUpstreamOutput(name, client))
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// callTool calls a tool of the MCP server mode and returns its text result
+func callTool(t *testing.T, ctx context.Context, c *client.Client, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	result, err := c.CallTool(ctx, req)
+	if err != nil {
+		t.Fatalf("CallTool %s: %v", name, err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestMCPServerUpstreams(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	newUpstream := func(name string) string {
+		s := server.NewMCPServer(name, "1.0.0")
+		s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+		ts := server.NewTestStreamableHTTPServer(s)
+		t.Cleanup(ts.Close)
+		return ts.URL + "/mcp"
+	}
+	primaryURL, secondURL := newUpstream("primary"), newUpstream("second")
+
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	primary := NewClient(ClientConfig{
+		Endpoint:  primaryURL,
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := primary.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = primary.Close() }()
+
+	ms, err := NewMCPServer(primary, "stdio", logger, nil, false)
+	if err != nil {
+		t.Fatalf("NewMCPServer: %v", err)
+	}
+	defer ms.upstreams.CloseAll()
+	downstream, err := client.NewInProcessClient(ms.mcpServer)
+	if err != nil {
+		t.Fatalf("NewInProcessClient: %v", err)
+	}
+	defer func() { _ = downstream.Close() }()
+	if _, err := downstream.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	if text, isError := callTool(t, ctx, downstream, "connect_upstream", map[string]any{"name": "second", "endpoint": secondURL}); isError || !strings.Contains(text, `"name":"second"`) {
+		t.Fatalf("connect_upstream failed: %s", text)
+	}
+	if text, isError := callTool(t, ctx, downstream, "connect_upstream", map[string]any{"name": "second", "endpoint": secondURL}); !isError {
+		t.Errorf("connecting a name twice should fail: %s", text)
+	}
+
+	text, _ := callTool(t, ctx, downstream, "list_upstreams", nil)
+	var listed upstreamsOutput
+	if err := json.Unmarshal([]byte(text), &listed); err != nil {
+		t.Fatalf("list_upstreams: %v", err)
+	}
+	if len(listed.Upstreams) != 2 || listed.Upstreams[0].Name != DefaultConnectionName || listed.Upstreams[1].Server.Name != "second" {
+		t.Errorf("unexpected upstreams %+v", listed.Upstreams)
+	}
+
+	for _, tt := range []struct {
+		upstream string
+		want     string
+	}{
+		{"", "primary"},
+		{DefaultConnectionName, "primary"},
+		{"second", "second"},
+	} {
+		text, isError := callTool(t, ctx, downstream, "call_tool", map[string]any{"name": "whoami", "upstream": tt.upstream})
+		if isError || !strings.Contains(text, tt.want) {
+			t.Errorf("call_tool on %q = %s, want %s", tt.upstream, text, tt.want)
+		}
+	}
+	if text, isError := callTool(t, ctx, downstream, "list_tools", map[string]any{"upstream": "missing"}); !isError || !strings.Contains(text, "unknown upstream: missing") {
+		t.Errorf("unknown upstream should fail: %s", text)
+	}
+
+	if text, isError := callTool(t, ctx, downstream, "change_endpoint", map[string]any{"endpoint": secondURL, "upstream": "second"}); isError || !strings.Contains(text, `"previousEndpoint":"`+secondURL+`"`) {
+		t.Errorf("change_endpoint on second failed: %s", text)
+	}
+	if text, isError := callTool(t, ctx, downstream, "change_endpoint", map[string]any{"endpoint": "not a url"}); !isError || !strings.Contains(text, "invalid endpoint") {
+		t.Errorf("an invalid endpoint should be rejected: %s", text)
+	}
+
+	if text, isError := callTool(t, ctx, downstream, "disconnect_upstream", map[string]any{"name": DefaultConnectionName}); !isError {
+		t.Errorf("the default upstream should not be closed: %s", text)
+	}
+	if text, isError := callTool(t, ctx, downstream, "disconnect_upstream", map[string]any{"name": "second"}); isError || strings.Contains(text, "second") {
+		t.Errorf("disconnect_upstream failed: %s", text)
+	}
+	if _, isError := callTool(t, ctx, downstream, "call_tool", map[string]any{"name": "whoami", "upstream": "second"}); !isError {
+		t.Error("a closed upstream should no longer be usable")
+	}
+}
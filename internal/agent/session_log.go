@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultSessionLogLines is the number of log lines a SessionLog keeps
+const DefaultSessionLogLines = 1000
+
+// ansiEscape matches the color codes of text log output
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// SessionLog keeps the latest lines written to it, without colors, so the
+// log of a running session can be queried, e.g. by the get_session_log tool
+// in MCP server mode. Use it as a Logger writer, next to the terminal.
+type SessionLog struct {
+	mu       sync.Mutex
+	lines    []string
+	next     int
+	total    int
+	capacity int
+	// partial holds a line whose newline has not been written yet
+	partial []byte
+	// followers receive every line as it is added
+	followers map[chan string]struct{}
+}
+
+// NewSessionLog creates a log keeping the latest capacity lines
+// (DefaultSessionLogLines if not positive)
+func NewSessionLog(capacity int) *SessionLog {
+	if capacity <= 0 {
+		capacity = DefaultSessionLogLines
+	}
+	return &SessionLog{capacity: capacity}
+}
+
+// Write implements io.Writer, splitting the output into lines
+func (s *SessionLog) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.partial = append(s.partial, p...)
+	for {
+		end := bytes.IndexByte(s.partial, '\n')
+		if end < 0 {
+			break
+		}
+		s.add(ansiEscape.ReplaceAllString(string(s.partial[:end]), ""))
+		s.partial = s.partial[end+1:]
+	}
+	// Do not keep the backing array of a large payload alive
+	if len(s.partial) == 0 {
+		s.partial = nil
+	}
+	return len(p), nil
+}
+
+// add appends a line to the ring buffer and passes it on to the followers
+func (s *SessionLog) add(line string) {
+	for follower := range s.followers {
+		// A follower that cannot keep up misses lines rather than
+		// blocking the logger
+		select {
+		case follower <- line:
+		default:
+		}
+	}
+
+	s.total++
+	if len(s.lines) < s.capacity {
+		s.lines = append(s.lines, line)
+		return
+	}
+	s.lines[s.next] = line
+	s.next = (s.next + 1) % s.capacity
+}
+
+// Tail returns the last n kept lines, oldest first, and the number of lines
+// written in total. A non-empty contains keeps only the lines containing it;
+// n <= 0 returns every kept line.
+func (s *SessionLog) Tail(n int, contains string) ([]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ordered := append(append([]string{}, s.lines[s.next:]...), s.lines[:s.next]...)
+	lines := []string{}
+	for _, line := range ordered {
+		if contains == "" || strings.Contains(line, contains) {
+			lines = append(lines, line)
+		}
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, s.total
+}
+
+// Follow returns the last n kept lines (every kept line if n <= 0) and a
+// channel receiving each line written from then on. Lines are dropped while
+// the channel's buffer of buffer lines is full. Call stop to close the
+// channel.
+func (s *SessionLog) Follow(n, buffer int) (backlog []string, lines <-chan string, stop func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ordered := append(append([]string{}, s.lines[s.next:]...), s.lines[:s.next]...)
+	if n > 0 && len(ordered) > n {
+		ordered = ordered[len(ordered)-n:]
+	}
+
+	ch := make(chan string, buffer)
+	if s.followers == nil {
+		s.followers = make(map[chan string]struct{})
+	}
+	s.followers[ch] = struct{}{}
+
+	var once sync.Once
+	return ordered, ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.followers, ch)
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// snapshotIgnored replaces the values of ignored paths in recorded and
+// verified results, so a path that disappears is still noticed
+const snapshotIgnored = "<ignored>"
+
+// maxSnapshotDifferences limits the differences reported per case
+const maxSnapshotDifferences = 20
+
+// SnapshotSuite is the file of `snapshot record` and `snapshot verify`:
+// tool calls with the results recorded for them
+type SnapshotSuite struct {
+	// Ignore are JSONPaths masked in every result, e.g. timestamps
+	Ignore []string       `json:"ignore,omitempty"`
+	Cases  []SnapshotCase `json:"cases"`
+}
+
+// SnapshotCase calls one tool and holds its recorded result
+type SnapshotCase struct {
+	// Name identifies the case in the report; defaults to the tool name and
+	// position
+	Name string `json:"name,omitempty"`
+	// Tool is the name of the tool to call
+	Tool string `json:"tool"`
+	// Arguments are passed to the tool
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// Ignore are JSONPaths masked in this case's result, in addition to
+	// those of the suite
+	Ignore []string `json:"ignore,omitempty"`
+	// Result is the recorded result with the ignored paths masked; empty
+	// until the case is recorded
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// label returns the name of the case, or the tool and position of the i-th
+// case if it has none
+func (sc SnapshotCase) label(i int) string {
+	if sc.Name != "" {
+		return sc.Name
+	}
+	return fmt.Sprintf("%s #%d", sc.Tool, i+1)
+}
+
+// LoadSnapshotSuite reads and validates a snapshot file
+func LoadSnapshotSuite(path string) (*SnapshotSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	var suite SnapshotSuite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot file %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("snapshot file %s has no cases", path)
+	}
+	for _, ignore := range suite.Ignore {
+		if _, err := parseJSONPath(ignore); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for i, sc := range suite.Cases {
+		if sc.Tool == "" {
+			return nil, fmt.Errorf("%s: case %d has no tool", path, i+1)
+		}
+		for _, ignore := range sc.Ignore {
+			if _, err := parseJSONPath(ignore); err != nil {
+				return nil, fmt.Errorf("%s: case %q: %w", path, sc.label(i), err)
+			}
+		}
+	}
+	return &suite, nil
+}
+
+// SaveSnapshotSuite writes a snapshot file
+func SaveSnapshotSuite(path string, suite *SnapshotSuite) error {
+	// Without HTML escaping, so masked values stay readable as <ignored>
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return fmt.Errorf("failed to encode snapshot file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	return nil
+}
+
+// snapshotResult calls the case's tool and returns its result with the
+// ignored paths masked
+func (c *Client) snapshotResult(ctx context.Context, suite *SnapshotSuite, sc SnapshotCase) (any, error) {
+	result, err := c.CallTool(ctx, sc.Tool, sc.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("call failed: %w", err)
+	}
+	doc, err := decodeToolResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode the result: %w", err)
+	}
+	for _, ignore := range slices.Concat(suite.Ignore, sc.Ignore) {
+		steps, err := parseJSONPath(ignore)
+		if err != nil {
+			return nil, err
+		}
+		doc = replaceJSONPath(doc, steps, snapshotIgnored)
+	}
+	return doc, nil
+}
+
+// RecordSnapshots calls the tool of each case in order and stores its result
+// in the suite. Tool errors are recorded like any other result; a failed
+// call stops the recording, leaving the suite partly updated.
+func (c *Client) RecordSnapshots(ctx context.Context, suite *SnapshotSuite) error {
+	for i := range suite.Cases {
+		sc := &suite.Cases[i]
+		doc, err := c.snapshotResult(ctx, suite, *sc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sc.label(i), err)
+		}
+		sc.Result = json.RawMessage(compactJSON(doc))
+	}
+	return nil
+}
+
+// SnapshotResult is the outcome of verifying one snapshot case
+type SnapshotResult struct {
+	Name string
+	// Differences between the recorded and the new result, each prefixed
+	// with its path
+	Differences []string
+	Duration    time.Duration
+}
+
+// Passed reports whether the result matched the recorded one
+func (r SnapshotResult) Passed() bool {
+	return len(r.Differences) == 0
+}
+
+// VerifySnapshots calls the tool of each case in order and compares its
+// result with the recorded one. A failed call fails its case without
+// stopping the run, unless ctx ends.
+func (c *Client) VerifySnapshots(ctx context.Context, suite *SnapshotSuite) []SnapshotResult {
+	results := make([]SnapshotResult, 0, len(suite.Cases))
+	for i, sc := range suite.Cases {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		differences := c.verifySnapshot(ctx, suite, sc)
+		results = append(results, SnapshotResult{Name: sc.label(i), Differences: differences, Duration: time.Since(start)})
+	}
+	return results
+}
+
+// verifySnapshot calls the case's tool and returns the differences from the
+// recorded result
+func (c *Client) verifySnapshot(ctx context.Context, suite *SnapshotSuite, sc SnapshotCase) []string {
+	if len(sc.Result) == 0 {
+		return []string{"no result recorded; run snapshot record first"}
+	}
+	want, err := decodeJSONValue(sc.Result)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot decode the recorded result: %v", err)}
+	}
+	got, err := c.snapshotResult(ctx, suite, sc)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	return limitDifferences(diffJSONValues("$", want, got))
+}
+
+// limitDifferences shortens a list of differences to maxSnapshotDifferences
+func limitDifferences(differences []string) []string {
+	if len(differences) <= maxSnapshotDifferences {
+		return differences
+	}
+	more := len(differences) - maxSnapshotDifferences
+	return append(differences[:maxSnapshotDifferences], fmt.Sprintf("... and %d more", more))
+}
+
+// identifierPattern matches member names that need no brackets in a path
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// memberPath appends a member name to a path
+func memberPath(path, name string) string {
+	if identifierPattern.MatchString(name) {
+		return path + "." + name
+	}
+	return fmt.Sprintf("%s['%s']", path, name)
+}
+
+// diffJSONValues returns the differences between two decoded JSON values,
+// each prefixed with the path where it occurs
+func diffJSONValues(path string, want, got any) []string {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			break
+		}
+		var differences []string
+		keys := slices.Sorted(maps.Keys(w))
+		for _, key := range slices.Sorted(maps.Keys(g)) {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			wantMember, inWant := w[key]
+			gotMember, inGot := g[key]
+			switch {
+			case !inGot:
+				differences = append(differences, fmt.Sprintf("%s: missing, want %s", memberPath(path, key), compactJSON(wantMember)))
+			case !inWant:
+				differences = append(differences, fmt.Sprintf("%s: unexpected %s", memberPath(path, key), compactJSON(gotMember)))
+			default:
+				differences = append(differences, diffJSONValues(memberPath(path, key), wantMember, gotMember)...)
+			}
+		}
+		return differences
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			break
+		}
+		var differences []string
+		for i := range max(len(w), len(g)) {
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(g):
+				differences = append(differences, fmt.Sprintf("%s: missing, want %s", elementPath, compactJSON(w[i])))
+			case i >= len(w):
+				differences = append(differences, fmt.Sprintf("%s: unexpected %s", elementPath, compactJSON(g[i])))
+			default:
+				differences = append(differences, diffJSONValues(elementPath, w[i], g[i])...)
+			}
+		}
+		return differences
+	}
+	if reflect.DeepEqual(want, got) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: got %s, want %s", path, compactJSON(got), compactJSON(want))}
+}
+
+// WriteSnapshotResults prints one line per case, the differences of failed
+// cases and a summary
+func WriteSnapshotResults(w io.Writer, results []SnapshotResult) {
+	passed := 0
+	for _, r := range results {
+		status := "FAIL"
+		if r.Passed() {
+			status = "PASS"
+			passed++
+		}
+		_, _ = fmt.Fprintf(w, "%s  %s (%s)\n", status, r.Name, r.Duration.Round(time.Millisecond))
+		for _, difference := range r.Differences {
+			_, _ = fmt.Fprintf(w, "      %s\n", difference)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d passed, %d failed\n", passed, len(results)-passed)
+}
+
+// SnapshotsError returns an ErrAssertionFailed error if any case differs
+// from its snapshot or did not run, nil otherwise
+func SnapshotsError(results []SnapshotResult, total int) error {
+	failed := total - len(results)
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return withKind(ErrAssertionFailed, fmt.Errorf("%d of %d snapshot(s) differ", failed, total))
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestReplaceJSONPath(t *testing.T) {
+	doc, _ := decodeJSONValue([]byte(`{"items":[{"id":1,"at":"a"},{"id":2,"at":"b"}],"at":"c"}`))
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"$.items[*].at", `{"at":"c","items":[{"at":"x","id":1},{"at":"x","id":2}]}`},
+		{"$.items[-1].id", `{"at":"c","items":[{"at":"a","id":1},{"at":"b","id":"x"}]}`},
+		{"$.missing.at", `{"at":"c","items":[{"at":"a","id":1},{"at":"b","id":2}]}`},
+		{"$", `"x"`},
+	} {
+		steps, _ := parseJSONPath(tc.path)
+		if got := compactJSON(replaceJSONPath(doc, steps, "x")); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.path, got, tc.want)
+		}
+	}
+	if got := compactJSON(doc); got != `{"at":"c","items":[{"at":"a","id":1},{"at":"b","id":2}]}` {
+		t.Errorf("document was modified: %s", got)
+	}
+}
+
+func TestDiffJSONValues(t *testing.T) {
+	want, _ := decodeJSONValue([]byte(`{"a":1,"list":[1,2,3],"odd key":true,"gone":"x","obj":{"n":null}}`))
+	got, _ := decodeJSONValue([]byte(`{"a":2,"list":[1,3],"odd key":true,"new":"y","obj":[]}`))
+	differences := diffJSONValues("$", want, got)
+	expected := []string{
+		`$.a: got 2, want 1`,
+		`$.gone: missing, want "x"`,
+		`$.list[1]: got 3, want 2`,
+		`$.list[2]: missing, want 3`,
+		`$.obj: got [], want {"n":null}`,
+		`$.new: unexpected "y"`,
+	}
+	if strings.Join(differences, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got differences:\n%s\nwant:\n%s", strings.Join(differences, "\n"), strings.Join(expected, "\n"))
+	}
+	if differences := diffJSONValues("$", want, want); len(differences) != 0 {
+		t.Errorf("expected no differences, got %v", differences)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	var calls, version atomic.Int32
+	s := server.NewMCPServer("snapshots", "1.0.0")
+	s.AddTool(mcp.NewTool("status"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n := calls.Add(1)
+		return mcp.NewToolResultStructuredOnly(map[string]any{
+			"requestId": fmt.Sprintf("req-%d", n),
+			"version":   version.Load(),
+			"healthy":   true,
+		}), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	client := NewClient(ClientConfig{
+		Endpoint:      ts.URL + "/mcp",
+		Transport:     "streamable-http",
+		Logger:        NewLoggerWithWriter(false, false, false, io.Discard),
+		NoInitialList: true,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	if err := os.WriteFile(path, []byte(`{
+		"ignore": ["$.structuredContent.requestId"],
+		"cases": [
+			{"tool": "status", "ignore": ["$.content[*].text"]},
+			{"name": "unmasked", "tool": "status"}
+		]
+	}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadSnapshotSuite(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshotSuite: %v", err)
+	}
+	if results := client.VerifySnapshots(ctx, suite); results[0].Passed() || !strings.Contains(results[0].Differences[0], "run snapshot record first") {
+		t.Errorf("expected unrecorded cases to fail, got %+v", results[0])
+	}
+
+	if err := client.RecordSnapshots(ctx, suite); err != nil {
+		t.Fatalf("RecordSnapshots: %v", err)
+	}
+	if err := SaveSnapshotSuite(path, suite); err != nil {
+		t.Fatalf("SaveSnapshotSuite: %v", err)
+	}
+	if suite, err = LoadSnapshotSuite(path); err != nil {
+		t.Fatalf("LoadSnapshotSuite: %v", err)
+	}
+	recordedDoc, _ := decodeJSONValue(suite.Cases[0].Result)
+	if recorded := compactJSON(recordedDoc); !strings.Contains(recorded, `"requestId":"<ignored>"`) || !strings.Contains(recorded, `"text":"<ignored>"`) {
+		t.Errorf("ignored paths not masked: %s", recorded)
+	}
+
+	// The request ids change but are ignored; the second case still sees the
+	// changing text content
+	results := client.VerifySnapshots(ctx, suite)
+	if !results[0].Passed() || results[0].Name != "status #1" {
+		t.Errorf("expected the first case to pass, got %+v", results[0])
+	}
+	if results[1].Passed() || !strings.HasPrefix(results[1].Differences[0], "$.content[0].text: got ") {
+		t.Errorf("expected the text content to differ, got %+v", results[1])
+	}
+
+	version.Store(2)
+	results = client.VerifySnapshots(ctx, suite)
+	if len(results[0].Differences) != 1 || results[0].Differences[0] != "$.structuredContent.version: got 2, want 0" {
+		t.Errorf("unexpected differences %v", results[0].Differences)
+	}
+	if err := SnapshotsError(results, len(suite.Cases)); !errors.Is(err, ErrAssertionFailed) || err.Error() != "2 of 2 snapshot(s) differ" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
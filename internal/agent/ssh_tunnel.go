@@ -0,0 +1,351 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshDialTimeout bounds connecting to and authenticating with the jump host
+const sshDialTimeout = 30 * time.Second
+
+// defaultIdentityFiles are the keys in ~/.ssh tried when no identity file
+// is given, in the order OpenSSH tries them
+var defaultIdentityFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// SSHTunnelConfig configures the SSH jump host an MCP server is reached
+// through
+type SSHTunnelConfig struct {
+	// Destination is the jump host as [user@]host[:port]
+	Destination string
+	// IdentityFile is a private key tried after the keys of the SSH agent;
+	// empty tries the default keys in ~/.ssh
+	IdentityFile string
+	// KnownHostsFile holds the accepted host keys; empty uses
+	// ~/.ssh/known_hosts
+	KnownHostsFile string
+}
+
+// SSHTunnel carries the connections to an MCP server's host over SSH. A
+// lost SSH connection is re-established on the next request.
+type SSHTunnel struct {
+	// target is the host:port the tunnel carries connections to
+	target string
+	// addr is the address of the jump host
+	addr   string
+	config *ssh.ClientConfig
+	logger *Logger
+	// agentConn is the connection to the SSH agent; nil without one
+	agentConn net.Conn
+
+	// reconnectMu lets only one dial at a time replace a lost connection
+	reconnectMu sync.Mutex
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+}
+
+// ConfigureSSHTunnel connects to the jump host and routes the connections
+// to the host of endpoint through it: the MCP requests as well as the
+// discovery of its protected resource metadata. Requests to other hosts,
+// like the authorization server, are sent directly. It must be called
+// before connecting; Close ends the tunnel.
+func ConfigureSSHTunnel(ctx context.Context, cfg SSHTunnelConfig, endpoint string, logger *Logger) (*SSHTunnel, error) {
+	target, err := endpointAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	username, addr, err := parseSSHDestination(cfg.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &SSHTunnel{target: target, addr: addr, logger: logger}
+	auth, err := t.authMethods(cfg.IdentityFile)
+	if err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	hostKeys, err := hostKeyCallback(cfg.KnownHostsFile)
+	if err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	t.config = &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sshDialTimeout,
+	}
+
+	logger.Info("Opening SSH tunnel to %s through %s@%s...", target, username, addr)
+	if _, err := t.connect(ctx); err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	logger.Info("Connections to %s go through the SSH tunnel", target)
+
+	for _, transport := range sharedTransports() {
+		t.route(transport)
+	}
+	return t, nil
+}
+
+// route sends the connections of transport to the target through the
+// tunnel, bypassing any proxy for them
+func (t *SSHTunnel) route(transport *http.Transport) {
+	proxy := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if addr, err := endpointAddress(req.URL.String()); err == nil && addr == t.target {
+			return nil, nil
+		}
+		if proxy == nil {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		// The dialer settings of http.DefaultTransport
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address != t.target {
+			return dial(ctx, network, address)
+		}
+		return t.dial(ctx, network, address)
+	}
+	transport.CloseIdleConnections()
+}
+
+// dial opens a connection to address on the far side of the tunnel,
+// reconnecting to the jump host if the SSH connection was lost
+func (t *SSHTunnel) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	client, err := t.currentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("SSH tunnel to %s: %w", address, err)
+	}
+	return conn, nil
+}
+
+// currentClient returns the SSH connection to the jump host, reconnecting if
+// it was lost. Concurrent callers wait for the reconnect under way instead
+// of opening connections of their own.
+func (t *SSHTunnel) currentClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	client := t.client
+	t.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	t.reconnectMu.Lock()
+	defer t.reconnectMu.Unlock()
+
+	t.mu.Lock()
+	client = t.client
+	t.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+	t.logger.Warning("SSH connection to %s lost, reconnecting...", t.addr)
+	return t.connect(ctx)
+}
+
+// connect establishes the SSH connection to the jump host
+func (t *SSHTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	dialer := &net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server %s: %w", t.addr, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed: %w", t.addr, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		_ = client.Close()
+		return nil, errors.New("SSH tunnel closed")
+	}
+	t.client = client
+	t.mu.Unlock()
+
+	// Forget the client once its connection ends, so the next request
+	// reconnects
+	go func() {
+		_ = client.Wait()
+		t.mu.Lock()
+		if t.client == client {
+			t.client = nil
+		}
+		t.mu.Unlock()
+	}()
+	return client, nil
+}
+
+// Close ends the SSH connection. Requests to the target fail afterwards.
+func (t *SSHTunnel) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	client := t.client
+	t.client = nil
+	t.mu.Unlock()
+
+	if t.agentConn != nil {
+		_ = t.agentConn.Close()
+	}
+	if client != nil {
+		return client.Close()
+	}
+	return nil
+}
+
+// authMethods offers the keys of the SSH agent, then the identity file or
+// the default keys. Keys protected by a passphrase have to be added to the
+// agent.
+func (t *SSHTunnel) authMethods(identityFile string) ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			t.logger.Warning("Not using the SSH agent: %v", err)
+		} else {
+			t.agentConn = conn
+			agentSigners, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				t.logger.Warning("Not using the SSH agent: %v", err)
+			}
+			signers = append(signers, agentSigners...)
+		}
+	}
+
+	files := []string{identityFile}
+	if identityFile == "" {
+		files = nil
+		if home, err := os.UserHomeDir(); err == nil {
+			for _, name := range defaultIdentityFiles {
+				files = append(files, filepath.Join(home, ".ssh", name))
+			}
+		}
+	}
+	for _, file := range files {
+		signer, err := loadIdentity(file)
+		switch {
+		case err == nil:
+			signers = append(signers, signer)
+		case identityFile != "":
+			return nil, err
+		case !errors.Is(err, os.ErrNotExist):
+			t.logger.Debug("Skipping SSH key: %v", err)
+		}
+	}
+
+	if len(signers) == 0 {
+		return nil, errors.New("no SSH key to authenticate with: start an SSH agent or give --ssh-identity")
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, nil
+}
+
+// loadIdentity reads an unencrypted private key
+func loadIdentity(file string) (ssh.Signer, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, fmt.Errorf("SSH key %s is protected by a passphrase; add it to the SSH agent with ssh-add", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", file, err)
+	}
+	return signer, nil
+}
+
+// hostKeyCallback verifies the jump host against the known hosts file
+func hostKeyCallback(file string) (ssh.HostKeyCallback, error) {
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate known_hosts: %w", err)
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %w", err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return fmt.Errorf("host key of %s is not in %s; connect once with ssh to verify and add it", hostname, file)
+		}
+		return err
+	}, nil
+}
+
+// parseSSHDestination splits [user@]host[:port] into the user, defaulting
+// to the current one, and the address, defaulting to port 22
+func parseSSHDestination(destination string) (username, addr string, err error) {
+	if destination == "" {
+		return "", "", errors.New("no SSH destination given")
+	}
+	username, host, found := strings.Cut(destination, "@")
+	if !found {
+		host = destination
+		current, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("cannot determine the SSH user, give it as user@host: %w", err)
+		}
+		username = current.Username
+	}
+	if host == "" || username == "" {
+		return "", "", fmt.Errorf("invalid SSH destination %q (expected [user@]host[:port])", destination)
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	return username, host, nil
+}
+
+// endpointAddress returns the host:port an endpoint URL connects to
+func endpointAddress(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is a jump host accepting one client key and forwarding
+// direct-tcpip channels
+type testSSHServer struct {
+	addr string
+	// connections counts the SSH connections and forwarded the forwarded
+	// connections
+	connections atomic.Int32
+	forwarded   atomic.Int32
+	// knownHosts and identity are the client's known_hosts and key files
+	knownHosts string
+	identity   string
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	dir := t.TempDir()
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientKey, _ := ed25519.GenerateKey(rand.Reader)
+	clientSigner, _ := ssh.NewSignerFromKey(clientKey)
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &testSSHServer{
+		addr:       listener.Addr().String(),
+		knownHosts: filepath.Join(dir, "known_hosts"),
+		identity:   filepath.Join(dir, "id_ed25519"),
+	}
+	_ = os.WriteFile(s.identity, pem.EncodeToMemory(block), 0o600)
+	_ = os.WriteFile(s.knownHosts, []byte(knownhosts.Line([]string{s.addr}, hostSigner.PublicKey())+"\n"), 0o600)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "debugger" && string(key.Marshal()) == string(clientSigner.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.serve(conn, config)
+			}()
+		}
+	}()
+	return s
+}
+
+// serve forwards the direct-tcpip channels of one SSH connection
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	s.connections.Add(1)
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+		// RFC 4254 7.2: host to connect, port, originator address and port
+		data := newChannel.ExtraData()
+		hostLen := binary.BigEndian.Uint32(data)
+		host := string(data[4 : 4+hostLen])
+		port := binary.BigEndian.Uint32(data[4+hostLen:])
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+		s.forwarded.Add(1)
+		go ssh.DiscardRequests(requests)
+		go func() {
+			_, _ = io.Copy(channel, target)
+			_ = channel.CloseWrite()
+		}()
+		go func() {
+			_, _ = io.Copy(target, channel)
+			_ = target.Close()
+		}()
+	}
+}
+
+func TestSSHTunnel(t *testing.T) {
+	restoreSharedTransports(t)
+	jump := newTestSSHServer(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "through the tunnel")
+	}))
+	defer target.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "direct")
+	}))
+	defer other.Close()
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	logger := NewLoggerWithWriter(false, false, false, io.Discard)
+	tunnel, err := ConfigureSSHTunnel(context.Background(), SSHTunnelConfig{
+		Destination:    "debugger@" + jump.addr,
+		IdentityFile:   jump.identity,
+		KnownHostsFile: jump.knownHosts,
+	}, target.URL+"/mcp", logger)
+	if err != nil {
+		t.Fatalf("ConfigureSSHTunnel: %v", err)
+	}
+	defer func() { _ = tunnel.Close() }()
+
+	httpClient := &http.Client{Transport: sharedMCPTransport}
+	get := func(url string) string {
+		t.Helper()
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if body := get(target.URL + "/mcp"); body != "through the tunnel" || jump.forwarded.Load() != 1 {
+		t.Errorf("got %q with %d forwarded connections", body, jump.forwarded.Load())
+	}
+	if body := get(other.URL); body != "direct" || jump.forwarded.Load() != 1 {
+		t.Errorf("other hosts should be reached directly: %q, %d forwarded", body, jump.forwarded.Load())
+	}
+
+	// A lost SSH connection is re-established by the next request
+	tunnel.mu.Lock()
+	_ = tunnel.client.Close()
+	tunnel.mu.Unlock()
+	sharedMCPTransport.CloseIdleConnections()
+	deadline := time.Now().Add(testTimeoutLong)
+	for {
+		tunnel.mu.Lock()
+		lost := tunnel.client == nil
+		tunnel.mu.Unlock()
+		if lost || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Concurrent requests share one new SSH connection
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := tunnel.dial(context.Background(), "tcp", tunnel.target)
+			if err != nil {
+				t.Errorf("dial after losing the connection: %v", err)
+				return
+			}
+			_ = conn.Close()
+		}()
+	}
+	wg.Wait()
+	if n := jump.connections.Load(); n != 2 {
+		t.Errorf("expected one reconnect, got %d SSH connections", n)
+	}
+	if body := get(target.URL + "/mcp"); body != "through the tunnel" || jump.forwarded.Load() != 7 {
+		t.Errorf("after reconnecting got %q with %d forwarded connections", body, jump.forwarded.Load())
+	}
+}
+
+func TestParseSSHDestination(t *testing.T) {
+	for destination, want := range map[string][2]string{
+		"ops@bastion.example.com":      {"ops", "bastion.example.com:22"},
+		"ops@bastion.example.com:2222": {"ops", "bastion.example.com:2222"},
+		"ops@[2001:db8::1]:2222":       {"ops", "[2001:db8::1]:2222"},
+		"ops@2001:db8::1":              {"ops", "[2001:db8::1]:22"},
+	} {
+		username, addr, err := parseSSHDestination(destination)
+		if err != nil || username != want[0] || addr != want[1] {
+			t.Errorf("parseSSHDestination(%q) = %q, %q, %v; want %q, %q", destination, username, addr, err, want[0], want[1])
+		}
+	}
+	for _, destination := range []string{"", "ops@", "@bastion"} {
+		if _, _, err := parseSSHDestination(destination); err == nil {
+			t.Errorf("parseSSHDestination(%q) should fail", destination)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,6 +12,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // Test timeout constants
@@ -545,3 +550,101 @@ func (mms *MockMCPServer) GetRequests() []*http.Request {
 	defer mms.mu.Unlock()
 	return append([]*http.Request{}, mms.requests...)
 }
+
+// stubMCPClient is a minimal client.MCPClient for exercising Client logic
+// without a live server. Methods not overridden panic via the nil embedded
+// interface, which makes unexpected calls obvious in tests.
+type stubMCPClient struct {
+	client.MCPClient
+
+	mu        sync.Mutex
+	pingErr   error
+	pingCalls int
+
+	tools          []mcp.Tool
+	listToolsCalls int
+
+	subscribeErr error
+	subscribed   []string
+
+	completions     []string
+	lastCompleteReq mcp.CompleteRequest
+
+	initResult *mcp.InitializeResult
+
+	// readResult answers resources/read; readReqs records each request
+	readResult func(req mcp.ReadResourceRequest) *mcp.ReadResourceResult
+	readReqs   []mcp.ReadResourceRequest
+
+	// callTool answers tools/call; it is invoked without holding mu
+	callTool func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
+}
+
+func (s *stubMCPClient) CallTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return s.callTool(ctx, req)
+}
+
+func (s *stubMCPClient) ReadResource(ctx context.Context, req mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readReqs = append(s.readReqs, req)
+	return s.readResult(req), nil
+}
+
+func (s *stubMCPClient) Initialize(ctx context.Context, req mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.initResult != nil {
+		return s.initResult, nil
+	}
+	return &mcp.InitializeResult{ProtocolVersion: req.Params.ProtocolVersion}, nil
+}
+
+func (s *stubMCPClient) Complete(ctx context.Context, req mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCompleteReq = req
+	return &mcp.CompleteResult{Completion: mcp.Completion{Values: s.completions}}, nil
+}
+
+func (s *stubMCPClient) Close() error {
+	return nil
+}
+
+func (s *stubMCPClient) Subscribe(ctx context.Context, req mcp.SubscribeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribeErr != nil {
+		return s.subscribeErr
+	}
+	s.subscribed = append(s.subscribed, req.Params.URI)
+	return nil
+}
+
+func (s *stubMCPClient) ListToolsByPage(ctx context.Context, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listToolsCalls++
+	return &mcp.ListToolsResult{Tools: s.tools}, nil
+}
+
+func (s *stubMCPClient) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pingCalls++
+	return s.pingErr
+}
+
+// newStubbedClient creates a Client backed by the given stub
+func newStubbedClient(t *testing.T, stub client.MCPClient) *Client {
+	t.Helper()
+
+	c := NewClient(ClientConfig{
+		Endpoint:  "test://endpoint",
+		Transport: "streamable-http",
+		Logger:    NewLoggerWithWriter(false, false, false, io.Discard),
+		Version:   "test",
+	})
+	c.setMCPClient(stub)
+	return c
+}
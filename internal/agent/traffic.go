@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecordedBodySize bounds the request and response body kept per HTTP
+// exchange; long-lived SSE streams would otherwise grow without limit
+const maxRecordedBodySize = 1024 * 1024
+
+// redactedHeaders are recorded without their values, since exported traffic
+// is meant to be shared
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// TrafficRecorder captures the HTTP exchanges of a session, including the
+// JSON-RPC messages they carry, for export as HAR or OTLP spans
+type TrafficRecorder struct {
+	mu        sync.Mutex
+	exchanges []*HTTPExchange
+	// traceID groups all spans of the session into one trace
+	traceID [16]byte
+	// redactor masks credentials in the URLs and bodies handed out, such
+	// as the tokens of OAuth responses; nil hands them out unmasked
+	redactor *Redactor
+}
+
+// HTTPExchange is one recorded HTTP request and its response
+type HTTPExchange struct {
+	Started time.Time
+	// Wait is the time until the response headers arrived
+	Wait time.Duration
+	// Duration is the time until the response body was fully read or
+	// closed; zero while the response is still streaming
+	Duration time.Duration
+
+	Method          string
+	URL             string
+	RequestHeaders  http.Header
+	RequestBody     []byte
+	Status          int
+	Proto           string
+	ResponseHeaders http.Header
+	ResponseBody    []byte
+	// BodyTruncated is set when a body exceeded maxRecordedBodySize
+	BodyTruncated bool
+	// Err is the transport error, if the request failed without a response
+	Err string
+
+	// RPCMethod is the JSON-RPC method of the request body, if any
+	RPCMethod string
+
+	spanID [8]byte
+}
+
+// NewTrafficRecorder creates an empty recorder
+func NewTrafficRecorder() *TrafficRecorder {
+	r := &TrafficRecorder{redactor: defaultRedactor}
+	_, _ = rand.Read(r.traceID[:])
+	return r
+}
+
+// SetRedactor sets the redactor applied to the URLs and bodies of the
+// exchanges, normally that of the log; nil disables redaction
+func (r *TrafficRecorder) SetRedactor(redactor *Redactor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactor = redactor
+}
+
+// Exchanges returns a snapshot of the recorded exchanges, with credentials
+// in URLs and bodies redacted. Exchanges whose response is still streaming
+// report the time elapsed so far.
+func (r *TrafficRecorder) Exchanges() []HTTPExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exchanges := make([]HTTPExchange, len(r.exchanges))
+	for i, ex := range r.exchanges {
+		exchanges[i] = *ex
+		exchanges[i].URL = r.redactor.Redact(ex.URL)
+		exchanges[i].RequestBody = r.redactBody(ex.RequestBody)
+		exchanges[i].ResponseBody = r.redactBody(ex.ResponseBody)
+		if exchanges[i].Duration == 0 {
+			exchanges[i].Duration = time.Since(ex.Started)
+		}
+	}
+	return exchanges
+}
+
+// RoundTripper wraps next so that every exchange through it is recorded
+func (r *TrafficRecorder) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingRoundTripper{recorder: r, next: next}
+}
+
+// recordingRoundTripper records exchanges into a TrafficRecorder
+type recordingRoundTripper struct {
+	recorder *TrafficRecorder
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ex := &HTTPExchange{
+		Started:        time.Now(),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeaders: redactHeaders(req.Header),
+	}
+	_, _ = rand.Read(ex.spanID[:])
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// Hand the transport a fresh copy of the body
+		clone := req.Clone(req.Context())
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		req = clone
+
+		ex.RequestBody, ex.BodyTruncated = limitBody(body)
+		ex.RPCMethod = jsonRPCMethod(body)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		ex.Err = err.Error()
+		ex.Duration = time.Since(ex.Started)
+		t.recorder.add(ex)
+		return nil, err
+	}
+
+	ex.Wait = time.Since(ex.Started)
+	ex.Status = resp.StatusCode
+	ex.Proto = resp.Proto
+	ex.ResponseHeaders = redactHeaders(resp.Header)
+	t.recorder.add(ex)
+
+	resp.Body = &recordingBody{ReadCloser: resp.Body, recorder: t.recorder, exchange: ex}
+	return resp, nil
+}
+
+// add appends an exchange
+func (r *TrafficRecorder) add(ex *HTTPExchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, ex)
+}
+
+// recordingBody copies a response body into its exchange as it is read, so
+// streamed SSE responses are recorded without being buffered up front
+type recordingBody struct {
+	io.ReadCloser
+	recorder *TrafficRecorder
+	exchange *HTTPExchange
+}
+
+// Read implements io.Reader
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.recorder.mu.Lock()
+	if n > 0 {
+		room := maxRecordedBodySize - len(b.exchange.ResponseBody)
+		if n > room {
+			b.exchange.BodyTruncated = true
+		}
+		b.exchange.ResponseBody = append(b.exchange.ResponseBody, p[:min(n, max(room, 0))]...)
+	}
+	if err == io.EOF {
+		b.finishLocked()
+	}
+	b.recorder.mu.Unlock()
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *recordingBody) Close() error {
+	b.recorder.mu.Lock()
+	b.finishLocked()
+	b.recorder.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+// finishLocked records the end of the exchange once
+func (b *recordingBody) finishLocked() {
+	if b.exchange.Duration == 0 {
+		b.exchange.Duration = time.Since(b.exchange.Started)
+	}
+}
+
+// redactBody returns a redacted copy of a recorded body. Bodies are kept
+// whole and redacted when handed out, since a streamed response may split
+// a field across reads.
+func (r *TrafficRecorder) redactBody(body []byte) []byte {
+	if body == nil {
+		return nil
+	}
+	return []byte(r.redactor.Redact(string(body)))
+}
+
+// redactHeaders copies headers, hiding credential values
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			redacted[name] = []string{"[REDACTED]"}
+		}
+	}
+	return redacted
+}
+
+// limitBody truncates a body to maxRecordedBodySize
+func limitBody(body []byte) ([]byte, bool) {
+	if len(body) > maxRecordedBodySize {
+		return bytes.Clone(body[:maxRecordedBodySize]), true
+	}
+	return body, false
+}
+
+// jsonRPCMessage holds the fields of a JSON-RPC message the exporters use
+type jsonRPCMessage struct {
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// jsonRPCMethod returns the method of a JSON-RPC request body, or of the
+// first message of a batch
+func jsonRPCMethod(body []byte) string {
+	var msg jsonRPCMessage
+	if json.Unmarshal(body, &msg) == nil {
+		return msg.Method
+	}
+	var batch []jsonRPCMessage
+	if json.Unmarshal(body, &batch) == nil && len(batch) > 0 {
+		return batch[0].Method
+	}
+	return ""
+}
+
+// jsonRPCError returns the error message of a JSON-RPC error response, which
+// is either a JSON body or the data lines of an SSE stream
+func jsonRPCError(contentType string, body []byte) string {
+	payloads := [][]byte{body}
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		payloads = nil
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxRecordedBodySize)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				payloads = append(payloads, []byte(strings.TrimSpace(data)))
+			}
+		}
+	}
+
+	for _, payload := range payloads {
+		var msg jsonRPCMessage
+		if json.Unmarshal(payload, &msg) == nil && msg.Error != nil {
+			return msg.Error.Message
+		}
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+// HAR 1.2 document types (http://www.softwareishard.com/blog/har-12-spec/)
+type (
+	harDocument struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Pages   []struct{} `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string       `json:"method"`
+		URL         string       `json:"url"`
+		HTTPVersion string       `json:"httpVersion"`
+		Headers     []harNameVal `json:"headers"`
+		QueryString []harNameVal `json:"queryString"`
+		Cookies     []harNameVal `json:"cookies"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+		PostData    *harPostData `json:"postData,omitempty"`
+	}
+
+	harResponse struct {
+		Status      int          `json:"status"`
+		StatusText  string       `json:"statusText"`
+		HTTPVersion string       `json:"httpVersion"`
+		Headers     []harNameVal `json:"headers"`
+		Cookies     []harNameVal `json:"cookies"`
+		Content     harContent   `json:"content"`
+		RedirectURL string       `json:"redirectURL"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+	}
+
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+
+	harNameVal struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// WriteHAR writes the recorded exchanges to path as a HAR 1.2 file, which
+// browser developer tools and HAR viewers can open
+func (r *TrafficRecorder) WriteHAR(path, version string) error {
+	data, err := json.MarshalIndent(r.har(version), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	return nil
+}
+
+// har returns the recorded exchanges as a HAR document
+func (r *TrafficRecorder) har(version string) harDocument {
+	exchanges := r.Exchanges()
+	entries := make([]harEntry, 0, len(exchanges))
+	for _, ex := range exchanges {
+		entries = append(entries, harEntryFor(ex))
+	}
+
+	return harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "mcp-debug", Version: version},
+		Entries: entries,
+		Pages:   []struct{}{},
+	}}
+}
+
+// harEntryFor converts one exchange; the JSON-RPC method becomes the comment
+func harEntryFor(ex HTTPExchange) harEntry {
+	entry := harEntry{
+		StartedDateTime: ex.Started.Format(time.RFC3339Nano),
+		Time:            milliseconds(ex.Duration),
+		Request: harRequest{
+			Method:      ex.Method,
+			URL:         ex.URL,
+			HTTPVersion: harHTTPVersion(ex.Proto),
+			Headers:     harHeaders(ex.RequestHeaders),
+			QueryString: harQueryString(ex.URL),
+			Cookies:     []harNameVal{},
+			HeadersSize: -1,
+			BodySize:    len(ex.RequestBody),
+		},
+		Response: harResponse{
+			Status:      ex.Status,
+			StatusText:  http.StatusText(ex.Status),
+			HTTPVersion: harHTTPVersion(ex.Proto),
+			Headers:     harHeaders(ex.ResponseHeaders),
+			Cookies:     []harNameVal{},
+			Content: harContent{
+				Size:     len(ex.ResponseBody),
+				MimeType: ex.ResponseHeaders.Get("Content-Type"),
+				Text:     string(ex.ResponseBody),
+			},
+			HeadersSize: -1,
+			BodySize:    len(ex.ResponseBody),
+		},
+		Timings: harTimings{
+			Wait:    milliseconds(ex.Wait),
+			Receive: milliseconds(ex.Duration - ex.Wait),
+		},
+		Comment: ex.RPCMethod,
+	}
+	if len(ex.RequestBody) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: ex.RequestHeaders.Get("Content-Type"),
+			Text:     string(ex.RequestBody),
+		}
+	}
+	if ex.Err != "" {
+		entry.Comment = fmt.Sprintf("%s (error: %s)", ex.RPCMethod, ex.Err)
+	}
+	if ex.Duration < ex.Wait {
+		entry.Timings.Receive = 0
+	}
+	return entry
+}
+
+// harHeaders flattens headers into sorted name/value pairs
+func harHeaders(header http.Header) []harNameVal {
+	pairs := []harNameVal{}
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, harNameVal{Name: name, Value: value})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+// harQueryString lists the query parameters of a URL
+func harQueryString(rawURL string) []harNameVal {
+	pairs := []harNameVal{}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return pairs
+	}
+	for name, values := range parsed.Query() {
+		for _, value := range values {
+			pairs = append(pairs, harNameVal{Name: name, Value: value})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+// harHTTPVersion defaults the protocol of failed requests
+func harHTTPVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpTracesPath is appended to the collector endpoint (OTLP/HTTP)
+const otlpTracesPath = "/v1/traces"
+
+// OTLP span kind and status codes (opentelemetry-proto trace.proto)
+const (
+	otlpSpanKindClient = 3
+	otlpStatusOK       = 1
+	otlpStatusError    = 2
+)
+
+const (
+	// otlpExportTimeout bounds the export request to the collector
+	otlpExportTimeout = 10 * time.Second
+	// otlpMaxResponseSize bounds the collector error body that is reported
+	otlpMaxResponseSize = 64 * 1024
+)
+
+// OTLP/HTTP JSON types of an ExportTraceServiceRequest
+type (
+	otlpExportRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpAttribute struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+
+	otlpAnyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// ExportOTLP sends the recorded exchanges as spans of one trace to an
+// OpenTelemetry collector's OTLP/HTTP endpoint (e.g. http://localhost:4318).
+// Each HTTP exchange becomes a client span named after its JSON-RPC method.
+func (r *TrafficRecorder) ExportOTLP(ctx context.Context, endpoint, version string) error {
+	body, err := json.Marshal(r.otlpRequest(version))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, otlpExportTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, otlpMaxResponseSize))
+		return fmt.Errorf("failed to export spans: collector returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// otlpRequest converts the recorded exchanges into an export request
+func (r *TrafficRecorder) otlpRequest(version string) otlpExportRequest {
+	exchanges := r.Exchanges()
+	spans := make([]otlpSpan, 0, len(exchanges))
+	for _, ex := range exchanges {
+		spans = append(spans, r.otlpSpanFor(ex))
+	}
+
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			otlpString("service.name", "mcp-debug"),
+			otlpString("service.version", version),
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/giantswarm/mcp-debug", Version: version},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// otlpSpanFor converts one exchange into a span following the RPC and HTTP
+// semantic conventions
+func (r *TrafficRecorder) otlpSpanFor(ex HTTPExchange) otlpSpan {
+	name := ex.RPCMethod
+	if name == "" {
+		name = ex.Method
+	}
+
+	attributes := []otlpAttribute{
+		otlpString("http.request.method", ex.Method),
+		otlpString("url.full", ex.URL),
+	}
+	if ex.RPCMethod != "" {
+		attributes = append(attributes,
+			otlpString("rpc.system", "jsonrpc"),
+			otlpString("rpc.method", ex.RPCMethod),
+		)
+	}
+	if ex.Status != 0 {
+		attributes = append(attributes, otlpInt("http.response.status_code", int64(ex.Status)))
+	}
+	if session := ex.ResponseHeaders.Get("Mcp-Session-Id"); session != "" {
+		attributes = append(attributes, otlpString("mcp.session.id", session))
+	}
+
+	status := otlpStatus{Code: otlpStatusOK}
+	switch {
+	case ex.Err != "":
+		status = otlpStatus{Code: otlpStatusError, Message: ex.Err}
+	case ex.Status >= 400:
+		status = otlpStatus{Code: otlpStatusError, Message: http.StatusText(ex.Status)}
+	default:
+		if message := jsonRPCError(ex.ResponseHeaders.Get("Content-Type"), ex.ResponseBody); message != "" {
+			status = otlpStatus{Code: otlpStatusError, Message: message}
+		}
+	}
+
+	return otlpSpan{
+		TraceID:           hex.EncodeToString(r.traceID[:]),
+		SpanID:            hex.EncodeToString(ex.spanID[:]),
+		Name:              name,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: strconv.FormatInt(ex.Started.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(ex.Started.Add(ex.Duration).UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	}
+}
+
+// otlpString builds a string attribute
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+// otlpInt builds an integer attribute; OTLP/JSON encodes int64 as a string
+func otlpInt(key string, value int64) otlpAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: &s}}
+}
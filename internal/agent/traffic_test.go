@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordTraffic sends a JSON response and an SSE error response through a
+// recorder
+func recordTraffic(t *testing.T) *TrafficRecorder {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		if strings.Contains(string(body), "tools/call") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":2,\"error\":{\"code\":-32602,\"message\":\"unknown tool\"}}\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := NewTrafficRecorder()
+	client := &http.Client{Transport: recorder.RoundTripper(nil)}
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing"}}`,
+	} {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+	return recorder
+}
+
+func TestTrafficRecorderHAR(t *testing.T) {
+	recorder := recordTraffic(t)
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err := recorder.WriteHAR(path, "test"); err != nil {
+		t.Fatalf("WriteHAR failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read HAR: %v", err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Error("expected the Authorization header to be redacted")
+	}
+
+	var doc harDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 2 {
+		t.Fatalf("unexpected HAR log: %+v", doc.Log)
+	}
+
+	entry := doc.Log.Entries[0]
+	if entry.Comment != "tools/list" || entry.Request.Method != http.MethodPost || entry.Response.Status != http.StatusOK {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Request.PostData == nil || !strings.Contains(entry.Request.PostData.Text, `"tools/list"`) {
+		t.Errorf("expected the request body, got %+v", entry.Request.PostData)
+	}
+	if !strings.Contains(entry.Response.Content.Text, `"tools":[]`) {
+		t.Errorf("expected the response body, got %q", entry.Response.Content.Text)
+	}
+}
+
+func TestTrafficRecorderRedactsBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"access-123","refresh_token":"refresh-456","client_secret":"secret-789"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	record := func(recorder *TrafficRecorder) HTTPExchange {
+		client := &http.Client{Transport: recorder.RoundTripper(nil)}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/revoke?code=code-000", strings.NewReader("token=revoked-111&token_type_hint=refresh_token"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return recorder.Exchanges()[0]
+	}
+
+	ex := record(NewTrafficRecorder())
+	recorded := ex.URL + string(ex.RequestBody) + string(ex.ResponseBody)
+	for _, secret := range []string{"code-000", "revoked-111", "access-123", "refresh-456", "secret-789"} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("expected %s to be redacted, got %s", secret, recorded)
+		}
+	}
+	if !strings.Contains(string(ex.RequestBody), "token_type_hint=refresh_token") {
+		t.Errorf("expected other form parameters to be kept, got %s", ex.RequestBody)
+	}
+
+	unredacted := NewTrafficRecorder()
+	unredacted.SetRedactor(nil)
+	if ex := record(unredacted); !strings.Contains(string(ex.ResponseBody), "access-123") {
+		t.Errorf("expected no redaction without a redactor, got %s", ex.ResponseBody)
+	}
+}
+
+func TestTrafficRecorderOTLP(t *testing.T) {
+	recorder := recordTraffic(t)
+
+	var export otlpExportRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer collector.Close()
+
+	if err := recorder.ExportOTLP(context.Background(), collector.URL+"/", "test"); err != nil {
+		t.Fatalf("ExportOTLP failed: %v", err)
+	}
+
+	spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].TraceID != spans[1].TraceID || len(spans[0].TraceID) != 32 || spans[0].SpanID == spans[1].SpanID {
+		t.Errorf("expected one trace with distinct spans, got %+v", spans)
+	}
+	if spans[0].Name != "tools/list" || spans[0].Status.Code != otlpStatusOK || spans[0].Kind != otlpSpanKindClient {
+		t.Errorf("unexpected span: %+v", spans[0])
+	}
+	if spans[1].Name != "tools/call" || spans[1].Status.Code != otlpStatusError || spans[1].Status.Message != "unknown tool" {
+		t.Errorf("expected the JSON-RPC error in the span status, got %+v", spans[1])
+	}
+
+	collector.Close()
+	if err := recorder.ExportOTLP(context.Background(), collector.URL, "test"); err == nil {
+		t.Error("expected an error when the collector is unreachable")
+	}
+}
@@ -0,0 +1,268 @@
+package agent
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webUIPage is the single-page inspector served at /
+//
+//go:embed web/index.html
+var webUIPage []byte
+
+const (
+	// webLogBacklog is the number of earlier log lines a browser receives
+	// when it connects
+	webLogBacklog = 500
+	// webLogBuffer is the number of lines queued for a slow browser before
+	// further lines are dropped
+	webLogBuffer = 1024
+	// webMaxRequestBody bounds the tool call requests of the browser
+	webMaxRequestBody = 1 << 20
+)
+
+// WebUI serves a browser inspector for the connected server: the live
+// message log over a websocket, the capability lists and a form to call
+// tools
+type WebUI struct {
+	client *Client
+	logger *Logger
+	// sessionLog must be a writer of logger; its lines are streamed to
+	// the browser
+	sessionLog *SessionLog
+	// listenHost is the host of the address passed to Start, which
+	// requests may name besides loopback addresses
+	listenHost string
+}
+
+// capabilitiesOutput is the response of /api/capabilities
+type capabilitiesOutput struct {
+	Endpoint  string           `json:"endpoint"`
+	Server    ServerInfo       `json:"server"`
+	Tools     []toolOutput     `json:"tools"`
+	Resources []resourceOutput `json:"resources"`
+	Prompts   []promptOutput   `json:"prompts"`
+}
+
+// webCallRequest is the body of /api/tools/call
+type webCallRequest struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// webLogMessage is a websocket message carrying a log line
+type webLogMessage struct {
+	Type string `json:"type"`
+	Line string `json:"line"`
+}
+
+// NewWebUI creates the inspector. sessionLog should be a writer of logger
+// so the browser sees the log.
+func NewWebUI(client *Client, logger *Logger, sessionLog *SessionLog) *WebUI {
+	return &WebUI{client: client, logger: logger, sessionLog: sessionLog}
+}
+
+// Handler returns the HTTP handler of the inspector. Every route is
+// guarded against other sites' pages, see allowedRequest.
+func (w *WebUI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", w.handleIndex)
+	mux.HandleFunc("GET /api/capabilities", w.handleCapabilities)
+	mux.HandleFunc("POST /api/tools/call", w.handleCallTool)
+	mux.HandleFunc("GET /api/log", w.handleLog)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if err := w.allowedRequest(r); err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+// Start serves the inspector on addr until ctx is cancelled
+func (w *WebUI) Start(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	w.listenHost, _, _ = net.SplitHostPort(addr)
+	w.logger.Success("Web UI available at http://%s/", webDisplayAddr(listener.Addr()))
+
+	httpServer := &http.Server{
+		Handler:           w.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Requests, including the hijacked websocket streams, end with ctx
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// webDisplayAddr returns the address to open in a browser, replacing an
+// unspecified host with localhost
+func webDisplayAddr(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return fmt.Sprintf("localhost:%d", tcp.Port)
+}
+
+// allowedRequest rejects requests a page of another site could make
+// through the user's browser. The Host must be a loopback address,
+// localhost or the host the inspector listens on, which defeats DNS
+// rebinding: a hostile name resolving to 127.0.0.1 still arrives with that
+// name as Host. When listening on all interfaces any IP address is
+// accepted, since rebinding needs a name. A present Origin must match the
+// Host.
+func (w *WebUI) allowedRequest(r *http.Request) error {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	listenAll := w.listenHost == "" || net.ParseIP(w.listenHost).IsUnspecified()
+	switch {
+	case host == "localhost" || strings.EqualFold(host, w.listenHost):
+	case ip != nil && (ip.IsLoopback() || listenAll):
+	default:
+		return fmt.Errorf("host %q is not allowed", r.Host)
+	}
+
+	// Requests without an Origin header are not sent by other sites' pages
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+		return errors.New("cross-origin requests are not allowed")
+	}
+	return nil
+}
+
+// handleIndex serves the inspector page
+func (w *WebUI) handleIndex(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = rw.Write(webUIPage)
+}
+
+// handleCapabilities returns the server info and capability lists,
+// re-listing stale caches first
+func (w *WebUI) handleCapabilities(rw http.ResponseWriter, r *http.Request) {
+	if _, err := w.client.refreshStaleCaches(r.Context()); err != nil {
+		w.logger.Warning("Using cached data: %v", err)
+	}
+
+	w.client.mu.RLock()
+	out := capabilitiesOutput{
+		Endpoint:  w.client.endpoint,
+		Server:    w.client.serverInfo,
+		Tools:     make([]toolOutput, 0, len(w.client.toolCache)),
+		Resources: make([]resourceOutput, 0, len(w.client.resourceCache)),
+		Prompts:   make([]promptOutput, 0, len(w.client.promptCache)),
+	}
+	for _, tool := range w.client.toolCache {
+		out.Tools = append(out.Tools, newToolOutput(tool))
+	}
+	for _, resource := range w.client.resourceCache {
+		out.Resources = append(out.Resources, newResourceOutput(resource))
+	}
+	for _, prompt := range w.client.promptCache {
+		out.Prompts = append(out.Prompts, newPromptOutput(prompt))
+	}
+	w.client.mu.RUnlock()
+
+	writeJSON(rw, http.StatusOK, out)
+}
+
+// handleCallTool calls a tool with the arguments entered in the form
+func (w *WebUI) handleCallTool(rw http.ResponseWriter, r *http.Request) {
+	var req webCallRequest
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, webMaxRequestBody)).Decode(&req); err != nil {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	if req.Name == "" {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": "missing tool name"})
+		return
+	}
+
+	result, err := w.client.CallTool(r.Context(), req.Name, req.Arguments)
+	if err != nil {
+		writeJSON(rw, http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("tool call failed: %v", err)})
+		return
+	}
+	writeJSON(rw, http.StatusOK, callToolOutput{
+		IsError:           result.IsError,
+		Content:           jsonObjects(result.Content),
+		StructuredContent: result.StructuredContent,
+	})
+}
+
+// handleLog streams the session log over a websocket, starting with the
+// latest lines
+func (w *WebUI) handleLog(rw http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(rw, r)
+	if err != nil {
+		w.logger.Debug("Web UI log stream: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		_ = ws.readLoop()
+		cancel()
+	}()
+
+	backlog, lines, stop := w.sessionLog.Follow(webLogBacklog, webLogBuffer)
+	defer stop()
+
+	send := func(line string) error {
+		data, err := json.Marshal(webLogMessage{Type: "log", Line: line})
+		if err != nil {
+			return err
+		}
+		return ws.WriteText(string(data))
+	}
+	for _, line := range backlog {
+		if err := send(line); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-lines:
+			if err := send(line); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newWebUITest(t *testing.T) (*httptest.Server, *Logger) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeoutLong)
+	defer cancel()
+
+	sessionLog := NewSessionLog(100)
+	logger := NewLoggerWithWriter(false, false, false, sessionLog)
+	upstream := NewClient(ClientConfig{
+		Endpoint:  newUpstreamServer(t),
+		Transport: "streamable-http",
+		Logger:    logger,
+	})
+	if err := upstream.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	t.Cleanup(func() { _ = upstream.Close() })
+
+	ts := httptest.NewServer(NewWebUI(upstream, logger, sessionLog).Handler())
+	t.Cleanup(ts.Close)
+	return ts, logger
+}
+
+func TestWebUIAPI(t *testing.T) {
+	ts, _ := newWebUITest(t)
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "<title>mcp-debug</title>") {
+		t.Errorf("GET / = %d, want the inspector page", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/capabilities")
+	if err != nil {
+		t.Fatalf("GET /api/capabilities: %v", err)
+	}
+	var caps capabilitiesOutput
+	err = json.NewDecoder(resp.Body).Decode(&caps)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to decode capabilities: %v", err)
+	}
+	if caps.Server.Name != "upstream" || len(caps.Tools) != 1 || caps.Tools[0].Name != "echo" ||
+		len(caps.Resources) != 1 || caps.Resources[0].URI != "docs://readme" ||
+		len(caps.Prompts) != 1 || caps.Prompts[0].Name != "greet" {
+		t.Errorf("unexpected capabilities: %+v", caps)
+	}
+
+	call := func(origin, body string) (int, string) {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/tools/call", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /api/tools/call: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	if status, body := call(ts.URL, `{"name":"echo","arguments":{"message":"hi"}}`); status != http.StatusOK || !strings.Contains(body, `"text":"hi"`) {
+		t.Errorf("call = %d %s, want the echoed text", status, body)
+	}
+	if status, _ := call("https://evil.example.com", `{"name":"echo","arguments":{"message":"hi"}}`); status != http.StatusForbidden {
+		t.Errorf("cross-origin call = %d, want 403", status)
+	}
+	if status, _ := call("", `{"arguments":{}}`); status != http.StatusBadRequest {
+		t.Errorf("call without a name = %d, want 400", status)
+	}
+	if status, body := call("", `{"name":"missing"}`); status != http.StatusBadGateway || !strings.Contains(body, "tool call failed") {
+		t.Errorf("call of a missing tool = %d %s, want 502", status, body)
+	}
+}
+
+// dialWebSocket performs the websocket handshake with the log stream
+func dialWebSocket(t *testing.T, serverURL string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(testTimeoutLong))
+
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	_, err = io.WriteString(conn, "GET /api/log HTTP/1.1\r\nHost: "+strings.TrimPrefix(serverURL, "http://")+
+		"\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+	if err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("handshake response: %v", err)
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Fatalf("handshake = %d %v", resp.StatusCode, resp.Header)
+	}
+	return conn, reader
+}
+
+// readWebSocketFrame reads an unmasked server frame
+func readWebSocketFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, _ = io.ReadFull(reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, _ = io.ReadFull(reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// writeMaskedFrame sends a client frame, which must be masked
+func writeMaskedFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+}
+
+func TestWebUILogStream(t *testing.T) {
+	ts, logger := newWebUITest(t)
+	logger.Info("before the browser connected")
+
+	conn, reader := dialWebSocket(t, ts.URL)
+
+	readUntil := func(want string) {
+		t.Helper()
+		for {
+			opcode, payload := readWebSocketFrame(t, reader)
+			if opcode != wsOpText {
+				t.Fatalf("unexpected opcode %d", opcode)
+			}
+			var msg webLogMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				t.Fatalf("invalid message %s: %v", payload, err)
+			}
+			if msg.Type == "log" && strings.Contains(msg.Line, want) {
+				return
+			}
+		}
+	}
+	readUntil("before the browser connected")
+
+	logger.Info("after the browser connected")
+	readUntil("after the browser connected")
+
+	writeMaskedFrame(t, conn, wsOpPing, []byte("ping"))
+	for {
+		opcode, payload := readWebSocketFrame(t, reader)
+		if opcode == wsOpPong {
+			if string(payload) != "ping" {
+				t.Errorf("pong payload = %q", payload)
+			}
+			break
+		}
+	}
+
+	writeMaskedFrame(t, conn, wsOpClose, []byte{0x03, 0xE8})
+	for {
+		if opcode, _ := readWebSocketFrame(t, reader); opcode == wsOpClose {
+			break
+		}
+	}
+}
+
+func TestWebUILogStreamRejectsCrossOrigin(t *testing.T) {
+	ts, _ := newWebUITest(t)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/log", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/log: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin log stream = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestWebUIRejectsForeignHost(t *testing.T) {
+	ts, _ := newWebUITest(t)
+
+	get := func(host, origin string) int {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/capabilities", nil)
+		req.Host = host
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/capabilities: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A rebound name resolving to 127.0.0.1 sends a matching Origin
+	if status := get("rebind.example.com", "http://rebind.example.com"); status != http.StatusForbidden {
+		t.Errorf("rebound host = %d, want 403", status)
+	}
+	if status := get("", "https://evil.example.com"); status != http.StatusForbidden {
+		t.Errorf("cross-origin capabilities = %d, want 403", status)
+	}
+	if status := get("localhost", ""); status != http.StatusOK {
+		t.Errorf("localhost = %d, want 200", status)
+	}
+}
+
+func TestWebUIAllowedRequest(t *testing.T) {
+	tests := []struct {
+		listenHost string
+		host       string
+		want       bool
+	}{
+		{"localhost", "localhost:8080", true},
+		{"localhost", "127.0.0.1:8080", true},
+		{"localhost", "[::1]:8080", true},
+		{"localhost", "attacker.example:8080", false},
+		{"localhost", "192.168.1.5:8080", false},
+		{"inspector.lan", "inspector.lan:8080", true},
+		{"", "192.168.1.5:8080", true},
+		{"0.0.0.0", "192.168.1.5:8080", true},
+		{"0.0.0.0", "attacker.example:8080", false},
+	}
+	for _, tt := range tests {
+		w := &WebUI{listenHost: tt.listenHost}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		if got := w.allowedRequest(req) == nil; got != tt.want {
+			t.Errorf("listening on %q, Host %q allowed = %v, want %v", tt.listenHost, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSessionLogFollow(t *testing.T) {
+	log := NewSessionLog(10)
+	_, _ = io.WriteString(log, "one\ntwo\nthree\n")
+
+	backlog, lines, stop := log.Follow(2, 1)
+	if strings.Join(backlog, ",") != "two,three" {
+		t.Errorf("backlog = %v, want the last 2 lines", backlog)
+	}
+
+	// The second line does not fit in the buffer and is dropped
+	_, _ = io.WriteString(log, "four\nfive\n")
+	if line := <-lines; line != "four" {
+		t.Errorf("followed line = %q, want four", line)
+	}
+	select {
+	case line := <-lines:
+		t.Errorf("unexpected line %q", line)
+	default:
+	}
+
+	stop()
+	stop()
+	if _, ok := <-lines; ok {
+		t.Error("expected the channel to be closed")
+	}
+	_, _ = io.WriteString(log, "six\n")
+	if tail, total := log.Tail(1, ""); tail[0] != "six" || total != 6 {
+		t.Errorf("Tail() = %v, %d", tail, total)
+	}
+}